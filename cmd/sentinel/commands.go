@@ -0,0 +1,956 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/config"
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/internal/orchestrator"
+	"gh-sentinel/internal/report"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/workflow"
+)
+
+// command is a CLI subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// commands lists the subcommands in help order. fix is the default when no
+// subcommand is given.
+var commands = []command{
+	{"scan", "List failed workflow runs without changing anything", runScan},
+	{"watch", "Poll the default branch and react to new failures", runWatch},
+	{"serve", "Watch the default branch behind a local HTTP API", runServe},
+	{"fix", "Diagnose a failed run and apply a fix (default)", runFix},
+	{"explain", "Explain why a run failed, without generating a fix", runExplain},
+	{"apply", "Apply a fix saved by fix --dry-run", runApply},
+	{"rollback", "Restore a workflow file from its backup", runRollback},
+	{"history", "List applied fixes and their backups", runHistory},
+	{"secrets", "List env vars, secrets and variables each workflow uses", runSecrets},
+	{"audit", "Check workflow expressions, conditions and job graph offline", runAudit},
+	{"harden", "Pin actions and reusable workflows to verified commit SHAs", runHarden},
+	{"security", "Scan workflows for script injection and permission issues", runSecurity},
+	{"why-not", "Explain whether a workflow runs for an event", runWhyNot},
+	{"cancel", "Cancel a run, or every active run on a branch", runCancel},
+	{"disable", "Disable a chronically broken workflow", runDisable},
+	{"enable", "Re-enable a disabled workflow", runEnable},
+	{"doctor", "Check that the environment is ready", runDoctor},
+	{"clean", "Remove old temp and cache files", runClean},
+	{"telemetry", "Show or change the opt-in usage counters", runTelemetry},
+	{"config", "Show, export, import or sync the shareable settings", runConfig},
+	{"eval", "Compare deterministic and AI fixes over a replay corpus", runEval},
+	{"tutorial", "Walk through a session on a demo failure, stage by stage", runTutorial},
+}
+
+// findCommand returns the subcommand with the given name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Diagnostic log flags, which every command accepts
+var (
+	logLevel  string
+	debug     bool
+	logFormat string
+	logFile   bool
+)
+
+// operation is the name of the command being run, as the log entries give it
+var operation string
+
+// repository is the repository --repo names, empty for the working
+// directory's
+var repository string
+
+// newFlagSet creates a flag set whose usage points back at the help text
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of gh sentinel %s:\n", name)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&logLevel, "log-level", "", "diagnostic log level on stderr: "+strings.Join(logger.LevelNames, ", ")+" (default info)")
+	fs.BoolVar(&debug, "debug", false, "show debug logs, like --log-level debug")
+	fs.StringVar(&logFormat, "log-format", "", "diagnostic log format: "+strings.Join(logger.Formats, ", ")+" (default text)")
+	fs.BoolVar(&logFile, "log-file", false, "also log the session, at every level, to a JSON file in the cache directory")
+	return fs
+}
+
+// addRepoFlag registers --repo on commands that only need the GitHub API
+func addRepoFlag(fs *flag.FlagSet) {
+	fs.StringVar(&repository, "repo", "", "work on this repository (owner/name) instead of the current directory's")
+}
+
+// aiFlags are shared by commands that may call the AI
+type aiFlags struct {
+	provider   *string
+	model      *string
+	rulesOnly  *bool
+	lang       *string
+	maxLogSize *int
+}
+
+func addAIFlags(fs *flag.FlagSet) aiFlags {
+	return aiFlags{
+		provider:   addProviderFlag(fs),
+		model:      fs.String("model", "", "AI model to use for this invocation"),
+		rulesOnly:  fs.Bool("rules-only", false, "only apply deterministic fixers, never call the AI"),
+		lang:       fs.String("lang", "", "language of AI explanations and Markdown reports: "+strings.Join(config.LanguageCodes(), ", ")+" (default en)"),
+		maxLogSize: fs.Int("max-log-size", 0, "characters of logs sent to the AI (default 6000)"),
+	}
+}
+
+// addProviderFlag registers --ai-provider
+func addProviderFlag(fs *flag.FlagSet) *string {
+	return fs.String("ai-provider", "", "AI provider: "+strings.Join(config.AIProviders, ", ")+" (default copilot)")
+}
+
+// apply copies the flag values onto the configuration. The provider is set
+// first so --model applies to it.
+func (f aiFlags) apply(cfg *config.Config) {
+	if *f.provider != "" {
+		cfg.AI.Provider = *f.provider
+	}
+	if *f.model != "" {
+		cfg.SetModel(*f.model)
+	}
+	if *f.rulesOnly {
+		cfg.RulesOnly = true
+	}
+	if *f.lang != "" {
+		cfg.Language = strings.ToLower(*f.lang)
+	}
+	if *f.maxLogSize != 0 {
+		cfg.MaxLogSize = *f.maxLogSize
+	}
+}
+
+// addOutputFlag registers --output, text or json and the formats in more,
+// and returns a validator for it
+func addOutputFlag(fs *flag.FlagSet, more ...string) func() (string, error) {
+	formats := append([]string{orchestrator.OutputText, orchestrator.OutputJSON}, more...)
+	names := strings.Join(formats[:len(formats)-1], ", ") + " or " + formats[len(formats)-1]
+	output := fs.String("output", orchestrator.OutputText, "output format: "+names)
+	return func() (string, error) {
+		if !slices.Contains(formats, *output) {
+			return "", fmt.Errorf("unknown output format %q (expected %s)", *output, names)
+		}
+		return *output, nil
+	}
+}
+
+// addReportFlag registers the --output of fix, which accepts every report
+// format and defaults to the configured one
+func addReportFlag(fs *flag.FlagSet) func() (string, error) {
+	output := fs.String("output", "", "report format: "+strings.Join(report.Names(), ", "))
+	return func() (string, error) {
+		if *output == "" {
+			return "", nil
+		}
+		if _, err := report.Lookup(*output); err != nil {
+			return "", err
+		}
+		return *output, nil
+	}
+}
+
+// addCategoryFlag registers --category, which selects failures by their
+// category in the failure taxonomy
+func addCategoryFlag(fs *flag.FlagSet) func() ([]string, error) {
+	category := fs.String("category", "", "comma-separated failure categories to select: "+strings.Join(analyzer.Tags, ", "))
+	return func() ([]string, error) {
+		return analyzer.ParseTags(*category)
+	}
+}
+
+// addRunFilterFlags registers the flags that narrow down the runs searched
+// for failures
+func addRunFilterFlags(fs *flag.FlagSet) func() (github.RunFilter, error) {
+	branch := fs.String("branch", "", "only look at runs on this branch")
+	workflowFile := fs.String("workflow", "", "only look at runs of this workflow file, e.g. ci.yml")
+	event := fs.String("event", "", "only look at runs triggered by this event, e.g. push or pull_request")
+	actor := fs.String("actor", "", "only look at runs triggered by this user")
+	commits := fs.Int("commits", 1, "look at the failed runs of the last N commits instead of only the newest")
+	return func() (github.RunFilter, error) {
+		if *commits < 1 {
+			return github.RunFilter{}, fmt.Errorf("--commits must be at least 1")
+		}
+		return github.RunFilter{
+			Branch:   *branch,
+			Workflow: *workflowFile,
+			Event:    *event,
+			Actor:    *actor,
+			Commits:  *commits,
+		}, nil
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDigest reads how often watch sends a digest: daily, weekly or a
+// duration of at least MinDigestInterval. Empty means never.
+func parseDigest(digest string) (time.Duration, error) {
+	switch digest {
+	case "":
+		return 0, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+	every, err := time.ParseDuration(digest)
+	if err != nil || every < orchestrator.MinDigestInterval {
+		return 0, fmt.Errorf("--digest must be daily, weekly or a duration of at least %s", orchestrator.MinDigestInterval)
+	}
+	return every, nil
+}
+
+// newOrchestrator builds an orchestrator from the default configuration
+// after applying overrides. Cancelling ctx interrupts its session.
+func newOrchestrator(ctx context.Context, overrides ...func(*config.Config)) (*orchestrator.Orchestrator, error) {
+	cfg := config.Default()
+	cfg.TelemetryEndpoint = telemetryEndpoint
+	cfg.Repository = repository
+	if err := orchestrator.LoadSettings(ctx, cfg, os.Getenv("SENTINEL_SHARED_CONFIG")); err != nil {
+		return nil, err
+	}
+	if theme := os.Getenv("SENTINEL_THEME"); theme != "" {
+		cfg.Theme = theme
+	}
+	if lang := os.Getenv("SENTINEL_LANG"); lang != "" {
+		cfg.Language = strings.ToLower(lang)
+	}
+	if critical := splitList(os.Getenv("SENTINEL_CRITICAL_WORKFLOWS")); critical != nil {
+		cfg.CriticalWorkflows = critical
+	}
+	if reviewers := splitList(os.Getenv("SENTINEL_REVIEWERS")); reviewers != nil {
+		cfg.Reviewers = reviewers
+	}
+	if auth := os.Getenv("SENTINEL_AUTH"); auth != "" {
+		cfg.Auth = strings.ToLower(auth)
+	}
+	// gh's own variable naming the host of commands outside a clone
+	if host := os.Getenv("GH_HOST"); host != "" {
+		cfg.GitHubHost = strings.ToLower(host)
+	}
+	cfg.NotifyWebhook = os.Getenv("SENTINEL_NOTIFY_WEBHOOK")
+	if provider := os.Getenv("SENTINEL_AI_PROVIDER"); provider != "" {
+		cfg.AI.Provider = provider
+	}
+	if size := os.Getenv("SENTINEL_MAX_LOG_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("SENTINEL_MAX_LOG_SIZE must be a number of characters, got %q", size)
+		}
+		cfg.MaxLogSize = n
+	}
+	if confidence := os.Getenv("SENTINEL_MIN_CONFIDENCE"); confidence != "" {
+		cfg.MinConfidence = strings.ToUpper(confidence)
+	}
+	if confidence := os.Getenv("SENTINEL_AUTO_APPLY"); confidence != "" {
+		cfg.AutoApply = strings.ToUpper(confidence)
+	}
+	if ignored := splitList(os.Getenv("SENTINEL_IGNORE_WORKFLOWS")); ignored != nil {
+		cfg.IgnoreWorkflows = ignored
+	}
+	if level := os.Getenv("SENTINEL_LOG_LEVEL"); level != "" {
+		cfg.LogLevel = strings.ToLower(level)
+	}
+	if logLevel != "" {
+		cfg.LogLevel = strings.ToLower(logLevel)
+	}
+	if debug {
+		cfg.LogLevel = "debug"
+	}
+	if format := os.Getenv("SENTINEL_LOG_FORMAT"); format != "" {
+		cfg.LogFormat = strings.ToLower(format)
+	}
+	if logFormat != "" {
+		cfg.LogFormat = strings.ToLower(logFormat)
+	}
+	if logFile {
+		cfg.LogFile = true
+	}
+	for _, override := range overrides {
+		override(cfg)
+	}
+	orch, err := orchestrator.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	orch.SetContext(ctx)
+	orch.SetOperation(operation)
+	return orch, nil
+}
+
+func runScan(ctx context.Context, args []string) error {
+	fs := newFlagSet("scan")
+	addRepoFlag(fs)
+	output := addOutputFlag(fs, orchestrator.OutputSARIF)
+	category := addCategoryFlag(fs)
+	runFilter := addRunFilterFlags(fs)
+	org := fs.String("org", "", "scan the default branch of every repository of an organization")
+	topic := fs.String("topic", "", "with --org, only repositories with these comma-separated topics")
+	name := fs.String("name", "", "with --org, only repositories whose name matches this glob, e.g. api-*")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+	categories, err := category()
+	if err != nil {
+		return err
+	}
+	runs, err := runFilter()
+	if err != nil {
+		return err
+	}
+
+	if *org == "" {
+		if *topic != "" || *name != "" {
+			return fmt.Errorf("--topic and --name only apply to --org")
+		}
+		orch, err := newOrchestrator(ctx)
+		if err != nil {
+			return err
+		}
+		return orch.Scan(orchestrator.Options{Output: format, Categories: categories, Runs: runs})
+	}
+	switch {
+	case repository != "":
+		return fmt.Errorf("--org and --repo cannot be combined")
+	case format == orchestrator.OutputSARIF:
+		return fmt.Errorf("--org does not support --output sarif")
+	case categories != nil:
+		return fmt.Errorf("--org cannot be combined with --category")
+	case runs != (github.RunFilter{Commits: 1}):
+		return fmt.Errorf("--org looks at the latest commit of each default branch; it cannot be combined with --branch, --workflow, --event, --actor or --commits")
+	}
+	if _, err := path.Match(*name, ""); err != nil {
+		return fmt.Errorf("invalid --name pattern %q", *name)
+	}
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.ScanOrg(orchestrator.Options{
+		Output: format,
+		Org:    *org,
+		Repos:  github.RepoFilter{Topics: splitList(*topic), Name: *name},
+	})
+}
+
+func runWatch(ctx context.Context, args []string) error {
+	fs := newFlagSet("watch")
+	addRepoFlag(fs)
+	ai := addAIFlags(fs)
+	interval := fs.Duration("interval", 2*time.Minute, "how often to poll for new failures")
+	diagnose := fs.Bool("diagnose", false, "diagnose each new failure and propose a fix (never applied)")
+	digest := fs.String("digest", "", "send a CI health digest: daily, weekly or every <duration>")
+	notify := fs.String("notify", "", "chat webhook URL digests are posted to (default: printed)")
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics on this address, e.g. 127.0.0.1:9464")
+	category := addCategoryFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	categories, err := category()
+	if err != nil {
+		return err
+	}
+	if *interval < orchestrator.MinMonitorInterval {
+		return fmt.Errorf("--interval must be at least %s", orchestrator.MinMonitorInterval)
+	}
+	every, err := parseDigest(*digest)
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx, ai.apply, func(cfg *config.Config) {
+		if every > 0 {
+			cfg.Digest = every
+		}
+		if *notify != "" {
+			cfg.NotifyWebhook = *notify
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return orch.Monitor(orchestrator.Options{Categories: categories, MetricsAddr: *metricsAddr}, *interval, *diagnose)
+}
+
+func runServe(ctx context.Context, args []string) error {
+	fs := newFlagSet("serve")
+	addRepoFlag(fs)
+	ai := addAIFlags(fs)
+	addr := fs.String("addr", "127.0.0.1:8787", "address the status API listens on")
+	interval := fs.Duration("interval", 2*time.Minute, "how often to poll for new failures")
+	diagnose := fs.Bool("diagnose", false, "diagnose each new failure and propose a fix (never applied)")
+	notify := fs.String("notify", "", "chat webhook URL a summary of each diagnosis is posted to")
+	category := addCategoryFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	categories, err := category()
+	if err != nil {
+		return err
+	}
+	if *interval < orchestrator.MinMonitorInterval {
+		return fmt.Errorf("--interval must be at least %s", orchestrator.MinMonitorInterval)
+	}
+
+	orch, err := newOrchestrator(ctx, ai.apply, func(cfg *config.Config) {
+		if *notify != "" {
+			cfg.NotifyWebhook = *notify
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return orch.Serve(orchestrator.Options{Categories: categories}, *addr, *interval, *diagnose)
+}
+
+func runFix(ctx context.Context, args []string) error {
+	fs := newFlagSet("fix")
+	addRepoFlag(fs)
+	ai := addAIFlags(fs)
+	runID := fs.Int64("run-id", 0, "analyze this workflow run without showing the selector")
+	yes := fs.Bool("yes", false, "never prompt: auto-select the latest failure and apply the fix if it is confident enough (auto_apply)")
+	all := fs.Bool("all", false, "fix every failed run of the latest commit, reviewing one combined diff")
+	noLint := fs.Bool("no-lint", false, "skip actionlint verification of the fix")
+	noFlaky := fs.Bool("no-flaky", false, "diagnose without comparing the failure with recent runs to label flaky steps")
+	runHooks := fs.Bool("hooks", false, "run the repository's pre-commit or lint-staged hooks on the patched file")
+	noCache := fs.Bool("no-cache", false, "ask the AI again even when the run's diagnosis is cached, and look the repository up again")
+	createBranch := fs.Bool("create-branch", false, "commit an applied fix to sentinel/fix-<run-id> and push it")
+	watch := fs.Bool("watch", false, "re-run the workflow after patching and watch the result")
+	dryRun := fs.Bool("dry-run", false, "write the fixed file and its diff under ~/.gh-sentinel/proposals instead of applying the fix")
+	comment := fs.Bool("comment", false, "post the diagnosis on the run's pull request, or commit of a push, without asking")
+	issue := fs.Bool("issue", false, "file an issue for a failure with no fix or only a LOW confidence one, without asking")
+	critical := fs.String("critical", "", "comma-separated workflows (paths or globs) whose fixes are opened as pull requests for a second reviewer")
+	reviewers := fs.String("reviewers", "", "comma-separated reviewers of critical fixes, users or org/team (default: CODEOWNERS)")
+	output := addReportFlag(fs)
+	category := addCategoryFlag(fs)
+	runFilter := addRunFilterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+	categories, err := category()
+	if err != nil {
+		return err
+	}
+	runs, err := runFilter()
+	if err != nil {
+		return err
+	}
+	if *all && *runID != 0 {
+		return fmt.Errorf("--all and --run-id cannot be combined")
+	}
+	if *runID != 0 && runs != (github.RunFilter{Commits: 1}) {
+		return fmt.Errorf("--run-id cannot be combined with --branch, --workflow, --event, --actor or --commits")
+	}
+	if *dryRun && (*createBranch || *watch) {
+		return fmt.Errorf("--dry-run cannot be combined with --create-branch or --watch")
+	}
+
+	orch, err := newOrchestrator(ctx, ai.apply, func(cfg *config.Config) {
+		cfg.Lint = !*noLint
+		if *noFlaky {
+			cfg.FlakyHistory = 0
+		}
+		if *runHooks {
+			cfg.RunHooks = true
+		}
+		if *critical != "" {
+			cfg.CriticalWorkflows = splitList(*critical)
+		}
+		if *reviewers != "" {
+			cfg.Reviewers = splitList(*reviewers)
+		}
+		if *createBranch {
+			cfg.CreateBranch = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return orch.Fix(orchestrator.Options{
+		RunID:        *runID,
+		Yes:          *yes,
+		All:          *all,
+		Output:       format,
+		CreateBranch: *createBranch,
+		Watch:        *watch,
+		DryRun:       *dryRun,
+		NoCache:      *noCache,
+		Comment:      *comment,
+		Issue:        *issue,
+		Categories:   categories,
+		Runs:         runs,
+	})
+}
+
+func runExplain(ctx context.Context, args []string) error {
+	fs := newFlagSet("explain")
+	addRepoFlag(fs)
+	ai := addAIFlags(fs)
+	runID := fs.Int64("run-id", 0, "explain this workflow run instead of the most recent failure")
+	output := addOutputFlag(fs)
+	runFilter := addRunFilterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+	runs, err := runFilter()
+	if err != nil {
+		return err
+	}
+	if *runID != 0 && runs != (github.RunFilter{Commits: 1}) {
+		return fmt.Errorf("--run-id cannot be combined with --branch, --workflow, --event, --actor or --commits")
+	}
+
+	orch, err := newOrchestrator(ctx, ai.apply)
+	if err != nil {
+		return err
+	}
+	return orch.Explain(orchestrator.Options{RunID: *runID, Output: format, Runs: runs})
+}
+
+func runApply(ctx context.Context, args []string) error {
+	fs := newFlagSet("apply")
+	yes := fs.Bool("yes", false, "apply without asking for confirmation, unless the file changed since the proposal was made")
+	remove := fs.Bool("delete", false, "delete the proposal once it is applied")
+	output := addReportFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("apply expects one proposal, a run ID or a directory, e.g. gh sentinel apply 1234567890")
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Apply(orchestrator.Options{Yes: *yes, Output: format}, fs.Arg(0), *remove)
+}
+
+func runRollback(ctx context.Context, args []string) error {
+	fs := newFlagSet("rollback")
+	backup := fs.String("backup", "", "backup file to restore (picked in a terminal, otherwise the most recent one)")
+	yes := fs.Bool("yes", false, "restore without asking for confirmation")
+	remove := fs.Bool("delete", false, "delete the backup once it is restored")
+	single := fs.Bool("single", false, "restore only this file of a fix that changed several files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("rollback expects at most one workflow file, e.g. gh sentinel rollback .github/workflows/ci.yml")
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Rollback(orchestrator.Options{Yes: *yes}, fs.Arg(0), *backup, *remove, *single)
+}
+
+func runHistory(ctx context.Context, args []string) error {
+	fs := newFlagSet("history")
+	output := addOutputFlag(fs)
+	category := addCategoryFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+	categories, err := category()
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.History(orchestrator.Options{Output: format, Categories: categories})
+}
+
+func runSecrets(ctx context.Context, args []string) error {
+	fs := newFlagSet("secrets")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Secrets(orchestrator.Options{Output: format})
+}
+
+func runAudit(ctx context.Context, args []string) error {
+	fs := newFlagSet("audit")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Audit(orchestrator.Options{Output: format}, fs.Args())
+}
+
+func runHarden(ctx context.Context, args []string) error {
+	fs := newFlagSet("harden")
+	output := addOutputFlag(fs)
+	yes := fs.Bool("yes", false, "pin without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Harden(orchestrator.Options{Output: format, Yes: *yes}, fs.Args())
+}
+
+func runSecurity(ctx context.Context, args []string) error {
+	fs := newFlagSet("security")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Security(orchestrator.Options{Output: format}, fs.Args())
+}
+
+func runWhyNot(ctx context.Context, args []string) error {
+	fs := newFlagSet("why-not")
+	event := fs.String("event", "push", "event name, e.g. push or pull_request")
+	branch := fs.String("branch", "", "pushed branch, or the base branch of a pull request")
+	tag := fs.String("tag", "", "pushed tag")
+	eventType := fs.String("type", "", "activity type, e.g. opened or labeled")
+	paths := fs.String("paths", "", "comma-separated changed files")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(reorderArgs(fs, args)); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("why-not expects exactly one workflow, e.g. gh sentinel why-not ci.yml --event push --branch main")
+	}
+	if *branch != "" && *tag != "" {
+		return fmt.Errorf("--branch and --tag cannot be combined")
+	}
+
+	ev := workflow.TriggerEvent{Name: *event, Branch: *branch, Tag: *tag, Type: *eventType}
+	if *paths != "" {
+		for _, p := range strings.Split(*paths, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				ev.Paths = append(ev.Paths, p)
+			}
+		}
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.WhyNot(orchestrator.Options{Output: format}, fs.Arg(0), ev)
+}
+
+// reorderArgs moves flags ahead of positional arguments, so a command can
+// be written as "why-not ci.yml --event push"
+func reorderArgs(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		// Non-boolean flags take the next argument as their value
+		if f := fs.Lookup(name); f != nil && i+1 < len(args) {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++
+				flags = append(flags, args[i])
+			}
+		}
+	}
+	return append(flags, positional...)
+}
+
+func runCancel(ctx context.Context, args []string) error {
+	fs := newFlagSet("cancel")
+	addRepoFlag(fs)
+	branch := fs.String("branch", "", "cancel every queued and in-progress run on this branch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var runID int64
+	switch {
+	case *branch != "" && fs.NArg() == 0:
+	case *branch == "" && fs.NArg() == 1:
+		id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid run ID %q", fs.Arg(0))
+		}
+		runID = id
+	default:
+		return fmt.Errorf("cancel expects a run ID or --branch, e.g. gh sentinel cancel --branch main")
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Cancel(orchestrator.Options{}, runID, *branch)
+}
+
+func runDisable(ctx context.Context, args []string) error {
+	fs := newFlagSet("disable")
+	addRepoFlag(fs)
+	yes := fs.Bool("yes", false, "disable without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("disable expects exactly one workflow, e.g. gh sentinel disable .github/workflows/ci.yml")
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Disable(orchestrator.Options{Yes: *yes}, fs.Arg(0))
+}
+
+func runEnable(ctx context.Context, args []string) error {
+	fs := newFlagSet("enable")
+	addRepoFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("enable expects exactly one workflow, e.g. gh sentinel enable .github/workflows/ci.yml")
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Enable(orchestrator.Options{}, fs.Arg(0))
+}
+
+func runDoctor(ctx context.Context, args []string) error {
+	fs := newFlagSet("doctor")
+	provider := addProviderFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx, func(cfg *config.Config) {
+		if *provider != "" {
+			cfg.AI.Provider = *provider
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return orch.Doctor()
+}
+
+func runClean(ctx context.Context, args []string) error {
+	fs := newFlagSet("clean")
+	all := fs.Bool("all", false, "remove every temp and cache file, not just expired ones")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Clean(orchestrator.Options{Output: format}, *all)
+}
+
+func runTelemetry(ctx context.Context, args []string) error {
+	fs := newFlagSet("telemetry")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(reorderArgs(fs, args)); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("telemetry expects at most one action: show, enable or disable")
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Telemetry(orchestrator.Options{Output: format}, fs.Arg(0))
+}
+
+func runConfig(ctx context.Context, args []string) error {
+	fs := newFlagSet("config")
+	output := addOutputFlag(fs)
+	repo := fs.Bool("repo", false, "set, unset or edit the repository's .sentinel.yml instead of your own settings")
+	yes := fs.Bool("yes", false, "import settings that choose where credentials go or run code without asking for confirmation")
+	if err := fs.Parse(reorderArgs(fs, args)); err != nil {
+		return err
+	}
+	format, err := output()
+	if err != nil {
+		return err
+	}
+	action := fs.Arg(0)
+	switch {
+	case (action == "export" || action == "import") && fs.NArg() != 2:
+		return fmt.Errorf("config %s expects a bundle path, e.g. gh sentinel config %s sentinel.tgz", action, action)
+	case action == "set" && fs.NArg() != 3:
+		return fmt.Errorf("config set expects a key and a value, e.g. gh sentinel config set min_confidence MEDIUM")
+	case action == "unset" && fs.NArg() != 2:
+		return fmt.Errorf("config unset expects a key, e.g. gh sentinel config unset min_confidence")
+	case (action == "" || action == "show" || action == "edit" || action == "sync") && fs.NArg() > 1:
+		return fmt.Errorf("config expects one action: show, set <key> <value>, unset <key>, edit, export <bundle>, import <bundle> or sync")
+	}
+
+	orch, err := newOrchestrator(ctx)
+	if err != nil {
+		return err
+	}
+	return orch.Settings(orchestrator.Options{Output: format, Yes: *yes}, action, fs.Args()[min(1, fs.NArg()):], *repo)
+}
+
+func runEval(ctx context.Context, args []string) error {
+	fs := newFlagSet("eval")
+	ai := addAIFlags(fs)
+	category := addCategoryFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	categories, err := category()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("eval expects a corpus directory, e.g. gh sentinel eval ./corpus")
+	}
+
+	orch, err := newOrchestrator(ctx, ai.apply)
+	if err != nil {
+		return err
+	}
+	return orch.Evaluate(orchestrator.Options{Categories: categories}, fs.Arg(0))
+}
+
+func runTutorial(ctx context.Context, args []string) error {
+	fs := newFlagSet("tutorial")
+	useAI := fs.Bool("ai", false, "let the AI diagnose the failure when no recipe fixes it (sends its logs to the provider)")
+	provider := addProviderFlag(fs)
+	yes := fs.Bool("yes", false, "run every stage without pausing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("tutorial expects at most one case directory, e.g. gh sentinel tutorial ./corpus/node-engine")
+	}
+
+	orch, err := newOrchestrator(ctx, func(cfg *config.Config) {
+		cfg.RulesOnly = !*useAI
+		if *provider != "" {
+			cfg.AI.Provider = *provider
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return orch.Tutorial(orchestrator.Options{Yes: *yes}, fs.Arg(0))
+}
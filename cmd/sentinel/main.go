@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"gh-sentinel/internal/orchestrator"
+	sentinelErrors "gh-sentinel/internal/errors"
 	"gh-sentinel/internal/ui"
 )
 
@@ -13,16 +19,51 @@ const (
 )
 
 func main() {
-	// Create and run orchestrator
-	orch, err := orchestrator.New()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, ui.FormatError(fmt.Sprintf("Initialization failed: %v", err)))
+	args := os.Args[1:]
+
+	// Without a subcommand, flags belong to fix
+	name := "fix"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
 		printHelp()
-		os.Exit(1)
+		return
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintln(os.Stderr, ui.FormatError(fmt.Sprintf("Unknown command %q", name)))
+		printHelp()
+		os.Exit(2)
 	}
 
-	if err := orch.Run(); err != nil {
+	// Ctrl-C or SIGTERM cancels the session: requests and commands in flight
+	// are aborted and the command winds down with a partial summary. A
+	// second signal exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	operation = name
+	err := cmd.run(ctx, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	recordUsage(ctx, name, err)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, ui.FormatWarning("Interrupted"))
+			os.Exit(130)
+		}
 		fmt.Fprintln(os.Stderr, ui.FormatError(fmt.Sprintf("Error: %v", err)))
+		if sentinelErrors.IsRetryable(err) {
+			fmt.Fprintln(os.Stderr, ui.FormatDim("  This looks transient (a timeout, an outage or a rate limit): try again in a few minutes"))
+		}
 		os.Exit(1)
 	}
 }
@@ -33,16 +74,220 @@ func printHelp() {
 
 PREREQUISITES:
   • gh CLI must be installed and authenticated
-  • gh copilot extension must be installed
+  • gh copilot extension must be installed, unless another AI provider
+    or --rules-only is used
   • Must be run from a git repository
 
 USAGE:
-  gh sentinel
+  gh sentinel [command] [flags]
+
+COMMANDS:
+%s
+FIX FLAGS:
+  --ai-provider <p> AI backend: copilot (default, Copilot API with the gh
+                    token, falling back to gh copilot), openai
+                    (OPENAI_API_KEY), anthropic (ANTHROPIC_API_KEY) or ollama
+                    (local server at http://localhost:11434)
+  --model <name>    AI model to use (defaults to the provider's default)
+  --lang <code>     Language of AI explanations and the Markdown report: en
+                    (default), fr, de, es, pt or it
+  --max-log-size <n>
+                    Characters of logs sent to the AI (default 6000)
+  --repo <owner/name>
+                    Work on another repository than the current directory's
+                    (also for scan, watch, cancel, disable and enable); fixes
+                    are committed through the API to sentinel/fix-<run-id>
+                    and opened as pull requests
+  --run-id <id>     Analyze a specific workflow run, skipping the selector
+  --yes             Never prompt: auto-select the most recent failure and
+                    apply the fix if its confidence reaches auto_apply (HIGH
+                    by default; prompts are also skipped without a TTY)
+  --all             Diagnose every failed run of the latest commit and review
+                    the fixes as one combined diff (also a in the selector)
+  --output <fmt>    Report format: text (default), json, markdown, sarif or
+                    job-summary; json, markdown and sarif print the report to
+                    stdout and never prompt, job-summary also appends Markdown
+                    to $GITHUB_STEP_SUMMARY in GitHub Actions
+  --rules-only      Only apply deterministic offline fixers, never call the AI
+  --no-lint         Skip verifying the fix with actionlint
+  --no-flaky        Diagnose without first checking whether the failed steps
+                    also fail intermittently in recent runs (flaky_history
+                    runs of the workflow, 10 by default)
+  --hooks           Run the repository's pre-commit or lint-staged hooks on
+                    the patched file and report the ones it fails (they run
+                    the repository's own code, so they are opt-in)
+  --no-cache        Ask the AI again even if the run was diagnosed before
+                    (AI diagnoses are cached by run, logs and workflow file
+                    in ~/.gh-sentinel/cache for 7 days), and look up the
+                    repository instead of starting from the last session's
+  --category <list> Only diagnose failures of these categories: infra,
+                    dependency, syntax, test, permission, resource, flaky,
+                    external-service (ignore_categories skips some always)
+  --branch <name>   Only look for failures in runs on this branch
+  --workflow <file> Only look for failures in runs of this workflow, e.g. ci.yml
+  --event <name>    Only look for failures in runs triggered by this event,
+                    e.g. push, pull_request or schedule
+  --actor <user>    Only look for failures in runs triggered by this user
+  --commits <n>     Look at the failed runs of the last n commits with runs
+                    instead of only the newest (default 1)
+  --create-branch   Commit an applied fix to sentinel/fix-<run-id> and push
+                    it using your gh credentials
+  --watch           After patching, re-run the workflow (or wait for the run
+                    the pushed fix triggers) and stream its progress
+  --dry-run         Do the whole analysis but write the fixed file, the file
+                    it was made from and their diff to
+                    ~/.gh-sentinel/proposals/<owner>/<repo>/<run-id>/
+                    instead of touching the working tree
+  --comment         Post the diagnosis and the fix's diff on the run's pull
+                    request, or on the commit of a push, without asking (in a
+                    terminal you are asked); posting again updates the comment
+  --issue           File an issue for a failure with no actionable fix, or
+                    only a LOW confidence one, without asking (in a terminal
+                    you are asked); labels and assignees come from the
+                    issue_labels and issue_assignees settings
+  --critical <list> Comma-separated workflows (paths or globs, e.g.
+                    release.yml,deploy-*.yml) that are never patched directly:
+                    their fixes are opened as pull requests for a second reviewer
+  --reviewers <list>
+                    Reviewers of critical fixes, users or org/team (default:
+                    the file's owners in CODEOWNERS)
+
+FLAGS OF EVERY COMMAND:
+  --log-level <l>   Diagnostic log on stderr: debug, info (default), warn or
+                    error
+  --debug           Same as --log-level debug
+  --log-format <f>  Diagnostic log format: text (default) or json (one object
+                    per line with time, level, msg, run_id, repo, operation)
+  --log-file        Also log the session at every level, in JSON, to
+                    ~/.gh-sentinel/cache/logs/session-<time>.log (the newest
+                    20 are kept)
+
+OTHER COMMANDS:
+  scan [--output json|sarif]             List failed runs of the latest commit
+       [--category <list>]               (only those of these failure categories);
+                                         sarif adds their log patterns and the
+                                         security findings of the workflows
+       [--branch <name>] [--workflow <file>] [--event <name>] [--actor <user>]
+       [--commits <n>]                   (only runs matching these, as for fix)
+       [--org <org>] [--topic <list>] [--name <glob>]
+                                         Check the default branch of every repository
+                                         of an organization (with these topics, or a
+                                         matching name) and pick a failure to diagnose
+  watch [--interval 2m] [--diagnose]     Keep polling the default branch and announce
+        [--category <list>]              (or diagnose) new failures until Ctrl-C
+        [--digest daily|weekly] [--notify <url>]
+                                         Also send a CI health digest (success rate,
+                                         failures, fixes, top recurring failures) to
+                                         a chat webhook, or print it
+        [--metrics <addr>]               Serve Prometheus metrics on <addr>/metrics
+  serve [--addr 127.0.0.1:8787] [--interval 2m] [--diagnose] [--category <list>]
+        [--notify <url>]                 (posting a summary of each diagnosis)
+                                         Watch as above behind a local HTTP API:
+                                         GET /api/status, /api/failures and
+                                         /api/reports/<run-id>, POST
+                                         /api/runs/<run-id>/diagnose
+                                         (and POST /webhook for workflow_run
+                                         webhooks with SENTINEL_WEBHOOK_SECRET)
+                                         and GET /metrics for Prometheus
+  explain [--run-id <id>] [--output json] [--rules-only]
+                                         Explain why the latest failed run (or
+                                         <id>) failed: failed steps, log patterns,
+                                         suggestions and the AI's root cause,
+                                         without generating or applying a fix
+  apply [--yes] [--delete] [--output <fmt>] <run-id|dir>
+                                         Apply a fix saved by fix --dry-run: check
+                                         the file for changes since, preview the
+                                         diff, confirm; --delete removes the proposal
+  rollback [--backup <path>] [--yes] [--delete] [--single] [file]
+                                         Restore a workflow from a backup: pick one
+                                         (all files without <file>), preview the diff,
+                                         confirm; --delete removes the backup after.
+                                         A fix of several files is restored as a
+                                         whole unless --single is given
+  history [--output json] [--category <list>]
+                                         List applied fixes, newest first; in a
+                                         terminal, browse their diffs and roll back
+  secrets [--output json]                Flag references to secrets/vars that do not exist
+  audit [--output json] [file...]        Check ${{ }} expressions, if: conditions and
+                                         the needs: graph (fails on errors)
+  harden [--yes] [--output json] [file...]
+                                         Pin uses: tags and branches to the commit
+                                         SHA they resolve to (# tag kept), verify
+                                         existing pins, and show the diff
+  security [--output json] [file...]     Flag script injection, missing permissions:,
+                                         pull_request_target checkouts of the PR and
+                                         secrets in if:; in a terminal, have the AI
+                                         fix each finding
+  why-not <workflow> [--event push] [--branch <name> | --tag <name>]
+          [--paths a,b] [--type <type>]  Explain whether the workflow's on: filters
+                                         let it run for that event
+  cancel <run-id> | --branch <name>      Stop doomed runs (also x / X in the selector)
+  disable [--yes] <workflow>             Stop a broken workflow from running
+  enable <workflow>                      Turn a disabled workflow back on
+  doctor [--ai-provider <p>]             Check gh, auth, the AI provider and directories
+  clean [--all] [--output json]          Remove expired temp and cache files (done at
+                                         every start; --all empties both directories)
+  telemetry [show | enable | disable]    Opt-in anonymous usage counters (off by default);
+                                         show prints exactly what would be sent
+  config [show | set <key> <value> | unset <key> | edit] [--repo]
+                                         Show the settings in effect and where they
+                                         come from, or change ~/.gh-sentinel/config.yml
+                                         (--repo: the repository's .sentinel.yml)
+  config [export <bundle> | import [--yes] <bundle> | sync]
+                                         Share settings, recipes and error patterns:
+                                         a .tgz bundle, or a git repository to follow
+  eval [--model <name>] [--rules-only] [--category <list>] <dir>
+                                         Replay a corpus (one directory per case:
+                                         logs.txt, workflow.yml, expected.yml)
+  tutorial [--ai] [--yes] [case-dir]     Walk through a session stage by stage on a
+                                         demo failure (or one case of a corpus),
+                                         patching only a scratch copy
+
+ENVIRONMENT:
+  SENTINEL_THEME    Colors: auto (default, follows the terminal background),
+                    dark, light, high-contrast (blue/orange, safe for
+                    red-green color blindness), monochrome or plain;
+                    NO_COLOR disables colors too
+  SENTINEL_LANG     Default of --lang
+  SENTINEL_CRITICAL_WORKFLOWS, SENTINEL_REVIEWERS
+                    Defaults of --critical and --reviewers
+  SENTINEL_NOTIFY_WEBHOOK
+                    Default of watch --notify and serve --notify
+  SENTINEL_SERVE_TOKEN
+                    Bearer token of the serve API (default: generated into
+                    ~/.gh-sentinel/serve.token)
+  SENTINEL_WEBHOOK_SECRET
+                    Secret of the GitHub webhook whose workflow_run events
+                    serve receives on /webhook
+  SENTINEL_AI_PROVIDER, SENTINEL_MAX_LOG_SIZE, SENTINEL_MIN_CONFIDENCE,
+  SENTINEL_AUTO_APPLY, SENTINEL_IGNORE_WORKFLOWS
+                    Override ai_provider, max_log_size, min_confidence,
+                    auto_apply and ignore_workflows (comma-separated) of the
+                    settings
+  SENTINEL_LOG_LEVEL
+                    Default of --log-level (log_level: in config.yml)
+  SENTINEL_LOG_FORMAT
+                    Default of --log-format (log_format: in config.yml;
+                    log_file: true turns on --log-file)
+  SENTINEL_SHARED_CONFIG
+                    Git URL of shared settings (overrides shared: in config.yml)
+  SENTINEL_AUTH     How to authenticate with GitHub: gh (default, the gh
+                    CLI's login), token or app (overrides auth: in config.yml)
+  SENTINEL_GITHUB_TOKEN
+                    Token of auth: token, e.g. a fine-grained one
+  SENTINEL_APP_PRIVATE_KEY, SENTINEL_APP_PRIVATE_KEY_FILE
+                    Private key (PEM, or a file holding it) of the GitHub App
+                    of auth: app, whose app_id is in config.yml
+  GH_HOST           GitHub Enterprise Server host of --repo and of commands
+                    outside a clone (overrides github_host: in config.yml)
 
 SETUP:
   1. Install gh CLI: https://cli.github.com
-  2. Authenticate: gh auth login
+  2. Authenticate: gh auth login (--hostname <host> for GitHub Enterprise
+     Server)
   3. Install Copilot: gh extension install github/gh-copilot
+     (or set OPENAI_API_KEY / ANTHROPIC_API_KEY, or run Ollama, and pass
+     --ai-provider)
   4. Install Sentinel: gh extension install .
 
 FEATURES:
@@ -51,11 +296,17 @@ FEATURES:
   ✓ Surgical YAML fixes with backup
   ✓ Interactive TUI for workflow selection
   ✓ Pattern-based error detection
+  ✓ Deterministic offline fixes for common failures
   ✓ Diff preview before applying changes
+  ✓ actionlint verification of every fix
 
 VERSION: %s
 
 LEARN MORE: https://github.com/YOUR_USERNAME/gh-sentinel
 `
-	fmt.Printf(help, version)
+	var list strings.Builder
+	for _, cmd := range commands {
+		fmt.Fprintf(&list, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Printf(help, list.String(), version)
 }
\ No newline at end of file
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+
+	"gh-sentinel/internal/config"
+	"gh-sentinel/internal/telemetry"
+)
+
+// telemetryEndpoint receives the usage counters of users who opted in.
+// Release builds set it with -ldflags "-X main.telemetryEndpoint=<url>";
+// other builds keep the counters local.
+var telemetryEndpoint string
+
+// recordUsage counts the command and the category of its error, then sends
+// the counters when a report is due. It does nothing unless the user
+// enabled telemetry, and its failures are never shown.
+func recordUsage(ctx context.Context, name string, err error) {
+	cfg := config.Default()
+	telemetry.Record(cfg.TelemetryFile, cfg.Version, func(c *telemetry.Counters) {
+		c.Commands[name]++
+		if category := telemetry.ErrorCategory(err); category != "" {
+			c.Errors[category]++
+		}
+	})
+	if ctx.Err() == nil {
+		telemetry.Flush(ctx, cfg.TelemetryFile, telemetryEndpoint, cfg.UserAgent)
+	}
+}
@@ -0,0 +1,119 @@
+package cleanup
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// Policy bounds what a directory may keep. Zero values disable a limit.
+type Policy struct {
+	MaxAge  time.Duration // Files not modified for longer are removed
+	MaxSize int64         // Oldest files are removed until the rest fit
+	All     bool          // Remove everything
+}
+
+// Result is what a collection removed and kept
+type Result struct {
+	Dir          string `json:"dir"`
+	FilesRemoved int    `json:"files_removed"`
+	BytesFreed   int64  `json:"bytes_freed"`
+	FilesKept    int    `json:"files_kept"`
+	BytesKept    int64  `json:"bytes_kept"`
+}
+
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Collect removes files from dir that the policy does not allow to keep,
+// oldest first, then prunes directories left empty. dir itself is kept. A
+// missing directory is empty.
+func Collect(dir string, policy Policy) (*Result, error) {
+	result := &Result{Dir: dir}
+
+	var files []entry
+	var dirs []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != dir {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // Removed while walking
+		}
+		files = append(files, entry{path, info.Size(), info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.FilesystemError("collect", dir, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	cutoff := time.Now().Add(-policy.MaxAge)
+	var firstErr error
+	for _, f := range files {
+		expired := policy.MaxAge > 0 && f.modTime.Before(cutoff)
+		oversized := policy.MaxSize > 0 && total > policy.MaxSize
+		if !policy.All && !expired && !oversized {
+			result.FilesKept++
+			result.BytesKept += f.size
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			if firstErr == nil {
+				firstErr = errors.FilesystemError("collect", f.path, err)
+			}
+			result.FilesKept++
+			result.BytesKept += f.size
+			continue
+		}
+		total -= f.size
+		result.FilesRemoved++
+		result.BytesFreed += f.size
+	}
+
+	// Deepest directories first, so parents empty out in turn. Removing a
+	// directory that still has files fails and is ignored.
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, d := range dirs {
+		os.Remove(d)
+	}
+
+	return result, firstErr
+}
+
+// FormatSize formats a byte count for humans
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
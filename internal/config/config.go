@@ -3,8 +3,17 @@ package config
 import (
 	"fmt"
 	"os"
+	"net/url"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"time"
+
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/analyzer"
 )
 
 // Config holds all application configuration
@@ -12,13 +21,143 @@ type Config struct {
 	Version       string
 	UserAgent     string
 	MaxLogSize    int
+	MaxRawLogSize int  // Upper bound on fetched logs before prompt compression
+	CompressLogs  bool // Summarize oversized logs instead of tail-truncating
 	RequestTimeout time.Duration
+	APIRetries    int           // Extra attempts of a GitHub API request failing with a transient error; 0 never retries
+	APIRetryDelay time.Duration // Wait before the first retry, doubled before each next one, with jitter
 	BackupEnabled bool
 	BackupSuffix  string
+	BackupDir     string        // Backups go under <repo>/<file>/ here; empty keeps them next to the file
+	BackupMaxCount int          // Backups kept per file, newest first; 0 keeps all
+	BackupMaxAge  time.Duration // Backups older than this are removed; 0 keeps them
 	TempDir       string
 	CacheDir      string
+	TempTTL       time.Duration // Files in TempDir older than this are removed at startup
+	CacheTTL      time.Duration // Files in CacheDir older than this are removed at startup
+	MaxDirSize    int64         // Cap in bytes on each of TempDir and CacheDir; oldest files go first
+	RulesOnly     bool   // Never call the AI; only apply deterministic fixers
+	RecipesFile   string // User-contributed fix recipes
+	PatternsFile  string // User-defined error patterns of the log analyzer
+	HistoryFile   string // Session recaps, one JSON object per line
+	PatchesFile   string // Applied patches with their diffs, one JSON object per line
+	TelemetryFile string // Opt-in setting and pending usage counters
+	SettingsFile  string // Shareable settings, see Settings
+	ServeTokenFile string // Token of serve's status API, when generated
+	ProposalsDir  string // Fixes written by --dry-run, under <owner>/<name>/<run-id>/
+	Repository    string // owner/name to work on instead of the working directory's repository (--repo)
+	GitHubHost    string // Host of a GitHub Enterprise Server, e.g. github.example.com; empty uses the repository's (github.com by default)
+	GitHubAPIURL  string // Root of the REST API when it is not https://<host>/api/v3/
+	GitHubUploadURL string // Root of the uploads API when it is not https://<host>/api/uploads/
+	Auth          string // How to authenticate with GitHub: gh (default), token or app, see AuthModes
+	AppID         int64  // GitHub App of auth: app
+	AppInstallationID int64 // Installation of that app; 0 uses its only one
+	RepoSettingsFile string // The repository's .sentinel.yml, when it has one
+	SharedDir     string // Clone of the team's shared settings repository
+	Shared        string // Git URL of the team's shared settings; empty shares nothing
+	TelemetryEndpoint string // Where opted-in counters are sent; empty keeps them local
+	Lint          bool   // Verify fixes with actionlint before and after patching
+	FixRetries    int    // How often an AI fix failing validation is sent back for correction
+	FlakyHistory  int    // Recent runs of a workflow compared to label flaky failures; 0 compares none
+	RunHooks      bool   // Run the repository's pre-commit or lint-staged hooks on patched files
+	IgnoreCategories []string // Failure categories that are neither diagnosed nor announced
+	IgnoreWorkflows  []string // Workflow paths or globs whose failures are neither diagnosed nor announced
+	MinConfidence string      // Lowest confidence of an AI fix that is offered to apply: LOW, MEDIUM (default) or HIGH; lower ones are only explained
+	AutoApply     string      // Lowest confidence of a fix --yes applies: LOW, MEDIUM or HIGH (default); lower ones need a confirmation
+	CreateBranch  bool        // Commit applied fixes to sentinel/fix-<run-id> and push them, as --create-branch does
+	OpenPR        bool        // Open a pull request for each fix branch pushed
+	IssueLabels    []string   // Labels of the issues filed for failures sentinel cannot fix
+	IssueAssignees []string   // Users those issues are assigned to
+	Language      string // Language of AI explanations and Markdown reports, e.g. "fr"
+	Output        string // Report format of fix: text, json, markdown, sarif or job-summary
+	Theme         string // Terminal colors: auto, dark, light, high-contrast, monochrome or plain
+	LogLevel      string // Lowest level of the diagnostic log on stderr: debug, info, warn or error
+	LogFormat     string // Diagnostic log format: text, or json with the run, repository and command
+	LogFile       bool   // Also keep a JSON log of each session, at every level, in CacheDir/logs
+	CriticalWorkflows []string // Workflow paths or globs whose fixes need a second reviewer's approval
+	Reviewers         []string // Reviewers of critical fixes (users or org/team); empty uses CODEOWNERS
+	NotifyWebhook string        // Chat webhook watch posts digests to; empty prints them
+	Digest        time.Duration // How often watch sends a CI health digest; 0 sends none
+	RedactPatterns []string     // Regular expressions of secrets to mask in logs, besides the built-in ones
+	AI            AIConfig
+}
+
+// AIConfig selects the AI provider and holds per-provider model parameters
+type AIConfig struct {
+	Provider  string
+	Providers map[string]ModelSettings
+}
+
+// ModelSettings holds the model parameters for a single AI provider.
+// Zero values mean "use the provider's default".
+type ModelSettings struct {
+	Model           string  `yaml:"model,omitempty" json:"model,omitempty"`
+	Temperature     float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	MaxTokens       int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	ReasoningEffort string  `yaml:"reasoning_effort,omitempty" json:"reasoning_effort,omitempty"` // low, medium or high
+	Endpoint        string  `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`                 // Base URL of the provider's API (HTTP providers)
+}
+
+// AIProviders lists the supported AI providers
+var AIProviders = []string{"copilot", "openai", "anthropic", "ollama"}
+
+// Authentication modes: the gh CLI's token, a token given in
+// SENTINEL_GITHUB_TOKEN (e.g. a fine-grained one), or the installation
+// tokens of a GitHub App
+const (
+	AuthGH    = "gh"
+	AuthToken = "token"
+	AuthApp   = "app"
+)
+
+// AuthModes lists the supported authentication modes
+var AuthModes = []string{AuthGH, AuthToken, AuthApp}
+
+// Languages maps the languages AI explanations and reports can be written in
+// to their English names
+var Languages = map[string]string{
+	"en": "English",
+	"fr": "French",
+	"de": "German",
+	"es": "Spanish",
+	"pt": "Portuguese",
+	"it": "Italian",
+}
+
+// LanguageCodes lists the codes of Languages, sorted
+func LanguageCodes() []string {
+	codes := make([]string, 0, len(Languages))
+	for code := range Languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// repositoryRe matches a repository given as owner/name
+var repositoryRe = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+// ConfidenceLevels are the confidences of AI fixes, lowest first
+var ConfidenceLevels = []string{"LOW", "MEDIUM", "HIGH"}
+
+// ConfidenceRank orders a confidence among ConfidenceLevels. Unknown
+// confidences rank lowest.
+func ConfidenceRank(confidence string) int {
+	for i, level := range ConfidenceLevels {
+		if strings.EqualFold(level, confidence) {
+			return i
+		}
+	}
+	return -1
 }
 
+// MaxAPIRetries bounds APIRetries, so an outage is reported within minutes
+const MaxAPIRetries = 10
+
+// MaxFlakyHistory bounds FlakyHistory, since every run compared costs an
+// API request
+const MaxFlakyHistory = 50
+
 // Default returns a production-ready configuration
 func Default() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -29,12 +168,66 @@ func Default() *Config {
 		Version:       "1.0.0",
 		UserAgent:     "gh-sentinel/1.0.0",
 		MaxLogSize:    6000, // Characters (Windows cmd buffer safety)
+		MaxRawLogSize: 500000,
+		CompressLogs:  true,
 		RequestTimeout: 30 * time.Second,
+		APIRetries:    3,
+		APIRetryDelay: time.Second,
 		BackupEnabled: true,
 		BackupSuffix:  ".sentinel.bak",
+		BackupDir:     filepath.Join(homeDir, ".gh-sentinel", "backups"),
+		BackupMaxCount: 10,
+		BackupMaxAge:  90 * 24 * time.Hour,
 		TempDir:       tempDir,
 		CacheDir:      cacheDir,
+		TempTTL:       24 * time.Hour,
+		CacheTTL:      7 * 24 * time.Hour,
+		MaxDirSize:    256 << 20,
+		RecipesFile:   filepath.Join(homeDir, ".gh-sentinel", "recipes.yml"),
+		PatternsFile:  filepath.Join(homeDir, ".gh-sentinel", "patterns.yml"),
+		HistoryFile:   filepath.Join(homeDir, ".gh-sentinel", "history.jsonl"),
+		PatchesFile:   filepath.Join(homeDir, ".gh-sentinel", "patches.jsonl"),
+		TelemetryFile: filepath.Join(homeDir, ".gh-sentinel", "telemetry.json"),
+		SettingsFile:  filepath.Join(homeDir, ".gh-sentinel", "config.yml"),
+		ServeTokenFile: filepath.Join(homeDir, ".gh-sentinel", "serve.token"),
+		ProposalsDir:  filepath.Join(homeDir, ".gh-sentinel", "proposals"),
+		SharedDir:     filepath.Join(homeDir, ".gh-sentinel", "shared"),
+		Lint:          true,
+		FixRetries:    2,
+		FlakyHistory:  10,
+		Output:        "text",
+		Auth:          AuthGH,
+		MinConfidence: "MEDIUM",
+		AutoApply:     "HIGH",
+		Theme:         "auto",
+		LogLevel:      "info",
+		LogFormat:     "text",
+		Language:      "en",
+		AI: AIConfig{
+			Provider: "copilot",
+			Providers: map[string]ModelSettings{
+				"copilot":   {},
+				"openai":    {},
+				"anthropic": {},
+				"ollama":    {},
+			},
+		},
+	}
+}
+
+// Model returns the model settings of the active AI provider
+func (c *Config) Model() ModelSettings {
+	return c.AI.Providers[c.AI.Provider]
+}
+
+// SetModel overrides the model name of the active AI provider
+func (c *Config) SetModel(model string) {
+	if c.AI.Providers == nil {
+		c.AI.Providers = make(map[string]ModelSettings)
 	}
+	settings := c.AI.Providers[c.AI.Provider]
+	settings.Model = model
+	c.AI.Providers[c.AI.Provider] = settings
 }
 
 // EnsureDirectories creates required directories if they don't exist
@@ -53,8 +246,115 @@ func (c *Config) Validate() error {
 	if c.MaxLogSize <= 0 {
 		return fmt.Errorf("MaxLogSize must be positive")
 	}
+	if c.MaxRawLogSize < c.MaxLogSize {
+		return fmt.Errorf("MaxRawLogSize must be at least MaxLogSize")
+	}
 	if c.RequestTimeout <= 0 {
 		return fmt.Errorf("RequestTimeout must be positive")
 	}
+	if c.FixRetries < 0 {
+		return fmt.Errorf("FixRetries must not be negative")
+	}
+	if c.APIRetries < 0 || c.APIRetries > MaxAPIRetries {
+		return fmt.Errorf("API retries must be between 0 and %d", MaxAPIRetries)
+	}
+	if c.APIRetryDelay <= 0 {
+		return fmt.Errorf("APIRetryDelay must be positive")
+	}
+	if c.FlakyHistory < 0 || c.FlakyHistory > MaxFlakyHistory {
+		return fmt.Errorf("flaky history must be between 0 and %d runs", MaxFlakyHistory)
+	}
+	if c.TempTTL < 0 || c.CacheTTL < 0 || c.MaxDirSize < 0 {
+		return fmt.Errorf("TempTTL, CacheTTL and MaxDirSize must not be negative")
+	}
+	if c.Digest < 0 {
+		return fmt.Errorf("Digest must not be negative")
+	}
+	if c.BackupMaxCount < 0 || c.BackupMaxAge < 0 {
+		return fmt.Errorf("backup max count and max age must not be negative")
+	}
+	if c.BackupDir != "" && !filepath.IsAbs(c.BackupDir) {
+		return fmt.Errorf("backup directory %q must be an absolute path", c.BackupDir)
+	}
+	if c.NotifyWebhook != "" {
+		if u, err := url.Parse(c.NotifyWebhook); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("notify webhook must be an http(s) URL")
+		}
+	}
+	for _, pattern := range c.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redact pattern %q: %v", pattern, err)
+		}
+	}
+	for _, tag := range c.IgnoreCategories {
+		if !analyzer.ValidTag(tag) {
+			return fmt.Errorf("unknown failure category %q in ignore_categories (expected %s)", tag, strings.Join(analyzer.Tags, ", "))
+		}
+	}
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if !slices.Contains(logger.Formats, c.LogFormat) {
+		return fmt.Errorf("unknown log format %q (expected %s)", c.LogFormat, strings.Join(logger.Formats, ", "))
+	}
+	if _, ok := Languages[c.Language]; !ok {
+		return fmt.Errorf("unsupported language %q (expected %s)", c.Language, strings.Join(LanguageCodes(), ", "))
+	}
+	if !slices.Contains(AuthModes, c.Auth) {
+		return fmt.Errorf("unknown auth mode %q (expected %s)", c.Auth, strings.Join(AuthModes, ", "))
+	}
+	if c.Auth == AuthApp && c.AppID <= 0 {
+		return fmt.Errorf("auth: app needs the app_id of the GitHub App")
+	}
+	if c.GitHubHost != "" && strings.ContainsAny(c.GitHubHost, "/:@ ") {
+		return fmt.Errorf("github_host %q must be a host name, e.g. github.example.com", c.GitHubHost)
+	}
+	for _, api := range []string{c.GitHubAPIURL, c.GitHubUploadURL} {
+		if u, err := url.Parse(api); api != "" && (err != nil || u.Scheme != "https" || u.Host == "") {
+			return fmt.Errorf("GitHub API URL %q must be an https URL", api)
+		}
+	}
+	if c.Repository != "" && !repositoryRe.MatchString(c.Repository) {
+		return fmt.Errorf("repository %q is not owner/name", c.Repository)
+	}
+	for _, pattern := range c.CriticalWorkflows {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid critical workflow pattern %q", pattern)
+		}
+	}
+	for _, pattern := range c.IgnoreWorkflows {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignored workflow pattern %q", pattern)
+		}
+	}
+	if c.MinConfidence != "" && ConfidenceRank(c.MinConfidence) < 0 {
+		return fmt.Errorf("min confidence must be %s", strings.Join(ConfidenceLevels, ", "))
+	}
+	if ConfidenceRank(c.AutoApply) < 0 {
+		return fmt.Errorf("auto apply confidence must be %s", strings.Join(ConfidenceLevels, ", "))
+	}
+	if c.AI.Provider == "" {
+		return fmt.Errorf("AI provider must be set")
+	}
+	known := false
+	for _, name := range AIProviders {
+		known = known || name == c.AI.Provider
+	}
+	if !known {
+		return fmt.Errorf("unknown AI provider %q (expected %s)", c.AI.Provider, strings.Join(AIProviders, ", "))
+	}
+	for name, settings := range c.AI.Providers {
+		if settings.Temperature < 0 || settings.Temperature > 2 {
+			return fmt.Errorf("%s: temperature must be between 0 and 2", name)
+		}
+		if settings.MaxTokens < 0 {
+			return fmt.Errorf("%s: max tokens must not be negative", name)
+		}
+		switch settings.ReasoningEffort {
+		case "", "low", "medium", "high":
+		default:
+			return fmt.Errorf("%s: reasoning effort must be low, medium or high", name)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,478 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are the shareable part of the configuration, as stored in a
+// settings file. Unset keys leave the configuration alone. Credentials are
+// never part of it: API keys and tokens come from the environment.
+type Settings struct {
+	AIProvider        string                   `yaml:"ai_provider,omitempty" json:"ai_provider,omitempty"`
+	Models            map[string]ModelSettings `yaml:"models,omitempty" json:"models,omitempty"`
+	MaxLogSize        *int                     `yaml:"max_log_size,omitempty" json:"max_log_size,omitempty"` // Characters of logs sent to the AI
+	RulesOnly         *bool                    `yaml:"rules_only,omitempty" json:"rules_only,omitempty"`
+	Lint              *bool                    `yaml:"lint,omitempty" json:"lint,omitempty"`
+	FixRetries        *int                     `yaml:"fix_retries,omitempty" json:"fix_retries,omitempty"`
+	FlakyHistory      *int                     `yaml:"flaky_history,omitempty" json:"flaky_history,omitempty"`
+	APIRetries        *int                     `yaml:"api_retries,omitempty" json:"api_retries,omitempty"` // Extra attempts of failing GitHub API requests
+	RunHooks          *bool                    `yaml:"run_hooks,omitempty" json:"run_hooks,omitempty"`
+	Output            string                   `yaml:"output,omitempty" json:"output,omitempty"`
+	Theme             string                   `yaml:"theme,omitempty" json:"theme,omitempty"`
+	LogLevel          string                   `yaml:"log_level,omitempty" json:"log_level,omitempty"`   // debug, info, warn or error
+	LogFormat         string                   `yaml:"log_format,omitempty" json:"log_format,omitempty"` // text or json
+	LogFile           *bool                    `yaml:"log_file,omitempty" json:"log_file,omitempty"`     // Keep a JSON log of each session
+	Language          string                   `yaml:"language,omitempty" json:"language,omitempty"`     // Language of AI explanations and reports
+	CriticalWorkflows []string                 `yaml:"critical_workflows,omitempty" json:"critical_workflows,omitempty"`
+	Reviewers         []string                 `yaml:"reviewers,omitempty" json:"reviewers,omitempty"`
+	BackupDir         string                   `yaml:"backup_dir,omitempty" json:"backup_dir,omitempty"` // ~/ is the home directory
+	BackupMaxCount    *int                     `yaml:"backup_max_count,omitempty" json:"backup_max_count,omitempty"`
+	BackupMaxDays     *int                     `yaml:"backup_max_days,omitempty" json:"backup_max_days,omitempty"`
+	RedactPatterns    []string                 `yaml:"redact_patterns,omitempty" json:"redact_patterns,omitempty"`
+	IgnoreCategories  []string                 `yaml:"ignore_categories,omitempty" json:"ignore_categories,omitempty"` // Failure categories never diagnosed or announced
+	IgnoreWorkflows   []string                 `yaml:"ignore_workflows,omitempty" json:"ignore_workflows,omitempty"`   // Workflows whose failures are never diagnosed or announced
+	MinConfidence     string                   `yaml:"min_confidence,omitempty" json:"min_confidence,omitempty"`       // AI fixes below it are only shown
+	AutoApply         string                   `yaml:"auto_apply,omitempty" json:"auto_apply,omitempty"`               // Fixes below it are not applied by --yes
+	CreateBranch      *bool                    `yaml:"create_branch,omitempty" json:"create_branch,omitempty"`
+	OpenPR            *bool                    `yaml:"open_pr,omitempty" json:"open_pr,omitempty"`
+	IssueLabels       []string                 `yaml:"issue_labels,omitempty" json:"issue_labels,omitempty"`
+	IssueAssignees    []string                 `yaml:"issue_assignees,omitempty" json:"issue_assignees,omitempty"`
+	Shared            string                   `yaml:"shared,omitempty" json:"shared,omitempty"`           // Git URL of the team's shared settings
+	GitHubHost        string                   `yaml:"github_host,omitempty" json:"github_host,omitempty"` // GitHub Enterprise Server host
+	GitHubAPIURL      string                   `yaml:"github_api_url,omitempty" json:"github_api_url,omitempty"`
+	GitHubUploadURL   string                   `yaml:"github_upload_url,omitempty" json:"github_upload_url,omitempty"`
+	Auth              string                   `yaml:"auth,omitempty" json:"auth,omitempty"` // gh, token or app
+	AppID             *int64                   `yaml:"app_id,omitempty" json:"app_id,omitempty"`
+	AppInstallationID *int64                   `yaml:"app_installation_id,omitempty" json:"app_installation_id,omitempty"`
+}
+
+// RepoSettingsName is the settings file a repository can commit at its root
+const RepoSettingsName = ".sentinel.yml"
+
+// userOnlyKeys are the settings a repository's settings file cannot set:
+// they choose where credentials and logs are sent, run code, or write
+// outside the repository
+var userOnlyKeys = []string{"ai_provider", "models", "run_hooks", "backup_dir", "shared", "github_host", "github_api_url", "github_upload_url", "auth", "app_id", "app_installation_id"}
+
+// LoadSettings reads a settings file. It returns empty settings if the file
+// does not exist. Unknown keys are errors, so typos do not go unnoticed.
+func LoadSettings(path string) (*Settings, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Settings{}, nil
+	}
+	if err != nil {
+		return nil, errors.FilesystemError("load_settings", path, err)
+	}
+	return ParseSettings(data, path)
+}
+
+// ParseSettings reads settings from YAML; path is only used in errors
+func ParseSettings(data []byte, path string) (*Settings, error) {
+	settings := &Settings{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(settings); err != nil && err != io.EOF {
+		return nil, errors.ValidationError("load_settings", fmt.Sprintf("invalid settings file: %v", err)).WithPath(path)
+	}
+	return settings, nil
+}
+
+// Save writes the settings as YAML
+func (s *Settings) Save(path string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.FilesystemError("save_settings", path, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return errors.FilesystemError("save_settings", path, err)
+	}
+	return nil
+}
+
+// Apply overrides the configuration with the keys the settings set
+func (s *Settings) Apply(c *Config) {
+	if s.AIProvider != "" {
+		c.AI.Provider = s.AIProvider
+	}
+	for name, model := range s.Models {
+		if c.AI.Providers == nil {
+			c.AI.Providers = make(map[string]ModelSettings)
+		}
+		c.AI.Providers[name] = model
+	}
+	if s.MaxLogSize != nil {
+		c.MaxLogSize = *s.MaxLogSize
+	}
+	if s.RulesOnly != nil {
+		c.RulesOnly = *s.RulesOnly
+	}
+	if s.Lint != nil {
+		c.Lint = *s.Lint
+	}
+	if s.FixRetries != nil {
+		c.FixRetries = *s.FixRetries
+	}
+	if s.FlakyHistory != nil {
+		c.FlakyHistory = *s.FlakyHistory
+	}
+	if s.APIRetries != nil {
+		c.APIRetries = *s.APIRetries
+	}
+	if s.RunHooks != nil {
+		c.RunHooks = *s.RunHooks
+	}
+	if s.Output != "" {
+		c.Output = s.Output
+	}
+	if s.Theme != "" {
+		c.Theme = s.Theme
+	}
+	if s.LogLevel != "" {
+		c.LogLevel = strings.ToLower(s.LogLevel)
+	}
+	if s.LogFormat != "" {
+		c.LogFormat = strings.ToLower(s.LogFormat)
+	}
+	if s.LogFile != nil {
+		c.LogFile = *s.LogFile
+	}
+	if s.Language != "" {
+		c.Language = s.Language
+	}
+	if s.CriticalWorkflows != nil {
+		c.CriticalWorkflows = s.CriticalWorkflows
+	}
+	if s.Reviewers != nil {
+		c.Reviewers = s.Reviewers
+	}
+	if s.BackupDir != "" {
+		c.BackupDir = expandHome(s.BackupDir)
+	}
+	if s.BackupMaxCount != nil {
+		c.BackupMaxCount = *s.BackupMaxCount
+	}
+	if s.BackupMaxDays != nil {
+		c.BackupMaxAge = time.Duration(*s.BackupMaxDays) * 24 * time.Hour
+	}
+	if s.RedactPatterns != nil {
+		c.RedactPatterns = s.RedactPatterns
+	}
+	if s.IgnoreCategories != nil {
+		c.IgnoreCategories = s.IgnoreCategories
+	}
+	if s.IgnoreWorkflows != nil {
+		c.IgnoreWorkflows = s.IgnoreWorkflows
+	}
+	if s.MinConfidence != "" {
+		c.MinConfidence = strings.ToUpper(s.MinConfidence)
+	}
+	if s.AutoApply != "" {
+		c.AutoApply = strings.ToUpper(s.AutoApply)
+	}
+	if s.CreateBranch != nil {
+		c.CreateBranch = *s.CreateBranch
+	}
+	if s.OpenPR != nil {
+		c.OpenPR = *s.OpenPR
+	}
+	if s.IssueLabels != nil {
+		c.IssueLabels = s.IssueLabels
+	}
+	if s.IssueAssignees != nil {
+		c.IssueAssignees = s.IssueAssignees
+	}
+	if s.Shared != "" {
+		c.Shared = s.Shared
+	}
+	if s.GitHubHost != "" {
+		c.GitHubHost = strings.ToLower(s.GitHubHost)
+	}
+	if s.GitHubAPIURL != "" {
+		c.GitHubAPIURL = s.GitHubAPIURL
+	}
+	if s.GitHubUploadURL != "" {
+		c.GitHubUploadURL = s.GitHubUploadURL
+	}
+	if s.Auth != "" {
+		c.Auth = strings.ToLower(s.Auth)
+	}
+	if s.AppID != nil {
+		c.AppID = *s.AppID
+	}
+	if s.AppInstallationID != nil {
+		c.AppInstallationID = *s.AppInstallationID
+	}
+}
+
+// SettingsOf returns the settings in which a configuration differs from the
+// defaults, without credentials embedded in URLs
+func SettingsOf(c *Config) *Settings {
+	d := Default()
+	s := &Settings{}
+	if c.AI.Provider != d.AI.Provider {
+		s.AIProvider = c.AI.Provider
+	}
+	for name, model := range c.AI.Providers {
+		if model != d.AI.Providers[name] {
+			if s.Models == nil {
+				s.Models = make(map[string]ModelSettings)
+			}
+			model.Endpoint = withoutCredentials(model.Endpoint)
+			s.Models[name] = model
+		}
+	}
+	if c.MaxLogSize != d.MaxLogSize {
+		s.MaxLogSize = &c.MaxLogSize
+	}
+	if c.RulesOnly != d.RulesOnly {
+		s.RulesOnly = &c.RulesOnly
+	}
+	if c.Lint != d.Lint {
+		s.Lint = &c.Lint
+	}
+	if c.FixRetries != d.FixRetries {
+		s.FixRetries = &c.FixRetries
+	}
+	if c.FlakyHistory != d.FlakyHistory {
+		s.FlakyHistory = &c.FlakyHistory
+	}
+	if c.APIRetries != d.APIRetries {
+		s.APIRetries = &c.APIRetries
+	}
+	if c.RunHooks != d.RunHooks {
+		s.RunHooks = &c.RunHooks
+	}
+	if c.Output != d.Output {
+		s.Output = c.Output
+	}
+	if c.Theme != d.Theme {
+		s.Theme = c.Theme
+	}
+	if c.LogLevel != d.LogLevel {
+		s.LogLevel = c.LogLevel
+	}
+	if c.LogFormat != d.LogFormat {
+		s.LogFormat = c.LogFormat
+	}
+	if c.LogFile != d.LogFile {
+		s.LogFile = &c.LogFile
+	}
+	if c.Language != d.Language {
+		s.Language = c.Language
+	}
+	if !reflect.DeepEqual(c.CriticalWorkflows, d.CriticalWorkflows) {
+		s.CriticalWorkflows = c.CriticalWorkflows
+	}
+	if !reflect.DeepEqual(c.Reviewers, d.Reviewers) {
+		s.Reviewers = c.Reviewers
+	}
+	if c.BackupDir != d.BackupDir {
+		s.BackupDir = c.BackupDir
+	}
+	if c.BackupMaxCount != d.BackupMaxCount {
+		s.BackupMaxCount = &c.BackupMaxCount
+	}
+	if c.BackupMaxAge != d.BackupMaxAge {
+		days := int(c.BackupMaxAge / (24 * time.Hour))
+		s.BackupMaxDays = &days
+	}
+	if !reflect.DeepEqual(c.RedactPatterns, d.RedactPatterns) {
+		s.RedactPatterns = c.RedactPatterns
+	}
+	if !reflect.DeepEqual(c.IgnoreCategories, d.IgnoreCategories) {
+		s.IgnoreCategories = c.IgnoreCategories
+	}
+	if !reflect.DeepEqual(c.IgnoreWorkflows, d.IgnoreWorkflows) {
+		s.IgnoreWorkflows = c.IgnoreWorkflows
+	}
+	if c.MinConfidence != d.MinConfidence {
+		s.MinConfidence = c.MinConfidence
+	}
+	if c.AutoApply != d.AutoApply {
+		s.AutoApply = c.AutoApply
+	}
+	if c.CreateBranch != d.CreateBranch {
+		s.CreateBranch = &c.CreateBranch
+	}
+	if c.OpenPR != d.OpenPR {
+		s.OpenPR = &c.OpenPR
+	}
+	if !reflect.DeepEqual(c.IssueLabels, d.IssueLabels) {
+		s.IssueLabels = c.IssueLabels
+	}
+	if !reflect.DeepEqual(c.IssueAssignees, d.IssueAssignees) {
+		s.IssueAssignees = c.IssueAssignees
+	}
+	s.Shared = withoutCredentials(c.Shared)
+	s.GitHubHost = c.GitHubHost
+	s.GitHubAPIURL = c.GitHubAPIURL
+	s.GitHubUploadURL = c.GitHubUploadURL
+	if c.Auth != d.Auth {
+		s.Auth = c.Auth
+	}
+	if c.AppID != d.AppID {
+		s.AppID = &c.AppID
+	}
+	if c.AppInstallationID != d.AppInstallationID {
+		s.AppInstallationID = &c.AppInstallationID
+	}
+	return s
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// withoutCredentials drops the user and password of a URL
+func withoutCredentials(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
+
+// Empty reports whether the settings set nothing
+func (s *Settings) Empty() bool {
+	return reflect.DeepEqual(s, &Settings{})
+}
+
+// CheckRepository fails if settings read from a repository set a key that
+// only the user's own settings may set
+func (s *Settings) CheckRepository(path string) error {
+	if keys := s.UserOnlyKeys(); len(keys) > 0 {
+		return errors.ValidationError("load_settings", fmt.Sprintf("%s can only be set in your own settings, not in a repository's %s", keys[0], RepoSettingsName)).WithPath(path)
+	}
+	return nil
+}
+
+// UserOnlyKeys lists the keys the settings set that only the user's own
+// settings may set, in file order
+func (s *Settings) UserOnlyKeys() []string {
+	var keys []string
+	for _, key := range s.Keys() {
+		if slices.Contains(userOnlyKeys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Only returns a copy of the settings with just the given keys
+func (s *Settings) Only(keys []string) *Settings {
+	return s.filter(func(key string) bool { return slices.Contains(keys, key) })
+}
+
+// Without returns a copy of the settings without the given keys
+func (s *Settings) Without(keys []string) *Settings {
+	return s.filter(func(key string) bool { return !slices.Contains(keys, key) })
+}
+
+// filter returns a copy of the settings with the keys keep accepts
+func (s *Settings) filter(keep func(key string) bool) *Settings {
+	out := &Settings{}
+	src, dst := reflect.ValueOf(s).Elem(), reflect.ValueOf(out).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if keep(strings.Split(src.Type().Field(i).Tag.Get("yaml"), ",")[0]) {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return out
+}
+
+// SettingsKeys lists every settings key, in file order
+func SettingsKeys() []string {
+	t := reflect.TypeOf(Settings{})
+	keys := make([]string, t.NumField())
+	for i := range keys {
+		keys[i] = strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+	}
+	return keys
+}
+
+// SetSetting returns settings file data with key set to value, read as
+// YAML, or removed when value is nil. The other keys and their comments are
+// kept.
+func SetSetting(data []byte, key string, value *string) ([]byte, error) {
+	if !slices.Contains(SettingsKeys(), key) {
+		return nil, errors.ValidationError("set_setting", fmt.Sprintf("unknown setting %q (expected %s)", key, strings.Join(SettingsKeys(), ", ")))
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.ValidationError("set_setting", fmt.Sprintf("invalid settings file: %v", err))
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.ValidationError("set_setting", "invalid settings file: not a mapping of keys")
+	}
+
+	var node *yaml.Node
+	if value != nil {
+		var parsed yaml.Node
+		if err := yaml.Unmarshal([]byte(*value), &parsed); err != nil {
+			return nil, errors.ValidationError("set_setting", fmt.Sprintf("invalid value for %s: %v", key, err))
+		}
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+		if len(parsed.Content) > 0 {
+			node = parsed.Content[0]
+		}
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != key {
+			continue
+		}
+		if node == nil {
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		} else {
+			root.Content[i+1] = node
+		}
+		return yaml.Marshal(&doc)
+	}
+	if node != nil {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+	}
+	return yaml.Marshal(&doc)
+}
+
+// Keys lists the keys the settings set, in file order
+func (s *Settings) Keys() []string {
+	var keys []string
+	v := reflect.ValueOf(s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Field(i).IsZero() {
+			keys = append(keys, strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0])
+		}
+	}
+	return keys
+}
@@ -1,8 +1,10 @@
 package context
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os/exec"
 	"strings"
 
@@ -16,8 +18,12 @@ type RepoContext struct {
 	FullName      string
 	DefaultBranch string
 	IsPrivate     bool
+	Host          string // github.com, or the host of a GitHub Enterprise Server
 }
 
+// DefaultHost is the host of GitHub.com
+const DefaultHost = "github.com"
+
 // ghRepoResponse matches the structure returned by gh repo view --json
 type ghRepoResponse struct {
 	Owner struct {
@@ -28,20 +34,50 @@ type ghRepoResponse struct {
 	DefaultBranchRef struct {
 		Name string `json:"name"`
 	} `json:"defaultBranchRef"`
-	IsPrivate bool `json:"isPrivate"`
+	IsPrivate bool   `json:"isPrivate"`
+	URL       string `json:"url"`
+}
+
+// DetectRepository uses gh CLI to detect current repository context. The
+// host is the one of the git remote, so a clone of a GitHub Enterprise
+// Server repository is detected as such.
+func DetectRepository(ctx context.Context) (*RepoContext, error) {
+	return viewRepository(ctx, "")
 }
 
-// DetectRepository uses gh CLI to detect current repository context
-func DetectRepository() (*RepoContext, error) {
+// LookupRepository uses gh CLI to get the context of a repository given as
+// owner/name, wherever the working directory is. A non-empty host looks it
+// up on that GitHub Enterprise Server.
+func LookupRepository(ctx context.Context, host, fullName string) (*RepoContext, error) {
+	if host != "" {
+		fullName = host + "/" + fullName
+	}
+	return viewRepository(ctx, fullName)
+}
+
+// viewRepository asks gh CLI about a repository, the working directory's
+// when fullName is empty
+func viewRepository(ctx context.Context, fullName string) (*RepoContext, error) {
 	// Check if gh CLI is available
 	if _, err := exec.LookPath("gh"); err != nil {
 		return nil, errors.AuthError("detect_repository", fmt.Errorf("gh CLI not found in PATH"))
 	}
 
 	// Get repository information
-	cmd := exec.Command("gh", "repo", "view", "--json", "owner,name,nameWithOwner,defaultBranchRef,isPrivate")
+	args := []string{"repo", "view"}
+	if fullName != "" {
+		args = append(args, fullName)
+	}
+	args = append(args, "--json", "owner,name,nameWithOwner,defaultBranchRef,isPrivate,url")
+	cmd := exec.CommandContext(ctx, "gh", args...)
 	output, err := cmd.Output()
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if fullName != "" {
+			return nil, errors.GitHubAPIError("detect_repository", fmt.Errorf("repository %s not found or gh not authenticated", fullName))
+		}
 		return nil, errors.GitHubAPIError("detect_repository", fmt.Errorf("not in a git repository or gh not authenticated"))
 	}
 
@@ -56,27 +92,35 @@ func DetectRepository() (*RepoContext, error) {
 		return nil, errors.ValidationError("detect_repository", "missing required repository information")
 	}
 
-	ctx := &RepoContext{
+	repo := &RepoContext{
 		Owner:         response.Owner.Login,
 		Name:          response.Name,
 		FullName:      response.NameWithOwner,
 		DefaultBranch: response.DefaultBranchRef.Name,
 		IsPrivate:     response.IsPrivate,
+		Host:          DefaultHost,
+	}
+	if u, err := url.Parse(response.URL); err == nil && u.Host != "" {
+		repo.Host = strings.ToLower(u.Host)
 	}
 
 	// Fallback for FullName if not provided
-	if ctx.FullName == "" {
-		ctx.FullName = fmt.Sprintf("%s/%s", ctx.Owner, ctx.Name)
+	if repo.FullName == "" {
+		repo.FullName = fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
 	}
 
-	return ctx, nil
+	return repo, nil
 }
 
-// GetAuthToken retrieves the GitHub authentication token from gh CLI
-func GetAuthToken() (string, error) {
-	cmd := exec.Command("gh", "auth", "token")
+// GetAuthToken retrieves the GitHub authentication token from gh CLI, the
+// one of host when it is not empty
+func GetAuthToken(ctx context.Context, host string) (string, error) {
+	cmd := exec.CommandContext(ctx, "gh", hostArgs([]string{"auth", "token"}, host)...)
 	output, err := cmd.Output()
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		return "", errors.AuthError("get_auth_token", err)
 	}
 	
@@ -88,11 +132,31 @@ func GetAuthToken() (string, error) {
 	return token, nil
 }
 
-// CheckAuthentication verifies that gh CLI is authenticated
-func CheckAuthentication() error {
-	cmd := exec.Command("gh", "auth", "status")
+// CheckAuthentication verifies that gh CLI is authenticated, with host when
+// it is not empty
+func CheckAuthentication(ctx context.Context, host string) error {
+	cmd := exec.CommandContext(ctx, "gh", hostArgs([]string{"auth", "status"}, host)...)
 	if err := cmd.Run(); err != nil {
-		return errors.AuthError("check_authentication", fmt.Errorf("not authenticated with GitHub - run 'gh auth login'"))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errors.AuthError("check_authentication", fmt.Errorf("not authenticated with GitHub - run 'gh auth login%s'", hostFlag(host)))
 	}
 	return nil
 }
+
+// hostArgs adds the --hostname of host to gh auth arguments
+func hostArgs(args []string, host string) []string {
+	if host == "" {
+		return args
+	}
+	return append(args, "--hostname", host)
+}
+
+// hostFlag is the --hostname option naming host in a hint, "" for none
+func hostFlag(host string) string {
+	if host == "" || host == DefaultHost {
+		return ""
+	}
+	return " --hostname " + host
+}
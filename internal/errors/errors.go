@@ -1,7 +1,14 @@
 package errors
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
 )
 
 // ErrorType represents different categories of errors
@@ -10,26 +17,49 @@ type ErrorType int
 const (
 	ErrTypeUnknown ErrorType = iota
 	ErrTypeGitHub           // GitHub API errors
-	ErrTypeCopilot          // Copilot CLI errors
+	ErrTypeCopilot          // Copilot CLI and AI provider errors
 	ErrTypeFilesystem       // File operations errors
 	ErrTypeValidation       // Data validation errors
 	ErrTypeNetwork          // Network connectivity errors
 	ErrTypeAuth             // Authentication errors
 )
 
+// String returns the category name of the error type
+func (t ErrorType) String() string {
+	switch t {
+	case ErrTypeGitHub:
+		return "github"
+	case ErrTypeCopilot:
+		return "ai"
+	case ErrTypeFilesystem:
+		return "filesystem"
+	case ErrTypeValidation:
+		return "validation"
+	case ErrTypeNetwork:
+		return "network"
+	case ErrTypeAuth:
+		return "auth"
+	}
+	return "unknown"
+}
+
 // SentinelError is a custom error with additional context
 type SentinelError struct {
-	Type    ErrorType
-	Op      string // Operation being performed
-	Path    string // File path if applicable
-	Err     error  // Underlying error
-	Message string // User-friendly message
+	Type      ErrorType
+	Op        string // Operation being performed
+	Path      string // File path if applicable
+	Err       error  // Underlying error
+	Message   string // User-friendly message
+	Retryable bool   // The operation may succeed if tried again later
 }
 
 func (e *SentinelError) Error() string {
-	if e.Path != "" {
+	if e.Path != "" && e.Err != nil {
 		return fmt.Sprintf("%s: %s (%s): %v", e.Op, e.Message, e.Path, e.Err)
 	}
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Op, e.Message, e.Path)
+	}
 	if e.Err != nil {
 		return fmt.Sprintf("%s: %s: %v", e.Op, e.Message, e.Err)
 	}
@@ -56,15 +86,80 @@ func (e *SentinelError) WithPath(path string) *SentinelError {
 	return e
 }
 
+// IsRetryable reports whether an error is transient, so trying again later
+// may succeed: a timeout, a server error or a rate limit, as opposed to e.g.
+// bad credentials or a missing resource
+func IsRetryable(err error) bool {
+	var sentinelErr *SentinelError
+	if stderrors.As(err, &sentinelErr) && sentinelErr.Retryable {
+		return true
+	}
+	if stderrors.Is(err, context.Canceled) {
+		return false
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if stderrors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if stderrors.As(err, &rateErr) || stderrors.As(err, &abuseErr) {
+		return true
+	}
+	status := statusCode(err)
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// statusCode returns the HTTP status of a GitHub API error, or 0
+func statusCode(err error) int {
+	var resp *github.ErrorResponse
+	if stderrors.As(err, &resp) && resp.Response != nil {
+		return resp.Response.StatusCode
+	}
+	return 0
+}
+
+// missingPermissions describes what a token refused with 403 would need,
+// from the permissions GitHub lists for fine-grained tokens and GitHub Apps
+// or the scopes it lists for classic tokens, or returns ""
+func missingPermissions(err error) string {
+	var resp *github.ErrorResponse
+	if !stderrors.As(err, &resp) || resp.Response == nil || resp.Response.StatusCode != http.StatusForbidden {
+		return ""
+	}
+	if permissions := resp.Response.Header.Get("X-Accepted-GitHub-Permissions"); permissions != "" {
+		return "grant the fine-grained token or GitHub App " + permissions
+	}
+	if scopes := resp.Response.Header.Get("X-Accepted-OAuth-Scopes"); scopes != "" && strings.Contains(resp.Message, "not accessible") {
+		return "add the scopes " + scopes + " to the token"
+	}
+	return ""
+}
+
 // Predefined error constructors for common scenarios
 func GitHubAPIError(op string, err error) *SentinelError {
-	return New(ErrTypeGitHub, op, "GitHub API request failed", err)
+	if statusCode(err) == http.StatusUnauthorized {
+		return New(ErrTypeAuth, op, "GitHub rejected the credentials (run `gh auth login`, or renew the token or GitHub App key sentinel was given)", err)
+	}
+	if missing := missingPermissions(err); missing != "" {
+		return New(ErrTypeAuth, op, "the token lacks a permission this needs: "+missing, err)
+	}
+	e := New(ErrTypeGitHub, op, "GitHub API request failed", err)
+	e.Retryable = IsRetryable(err)
+	return e
 }
 
 func CopilotError(op string, err error) *SentinelError {
 	return New(ErrTypeCopilot, op, "GitHub Copilot CLI failed", err)
 }
 
+func AIProviderError(op, provider string, err error) *SentinelError {
+	return New(ErrTypeCopilot, op, provider+" request failed", err)
+}
+
 func FilesystemError(op, path string, err error) *SentinelError {
 	return New(ErrTypeFilesystem, op, "filesystem operation failed", err).WithPath(path)
 }
@@ -74,7 +169,9 @@ func ValidationError(op, message string) *SentinelError {
 }
 
 func NetworkError(op string, err error) *SentinelError {
-	return New(ErrTypeNetwork, op, "network request failed", err)
+	e := New(ErrTypeNetwork, op, "network request failed", err)
+	e.Retryable = !stderrors.Is(err, context.Canceled)
+	return e
 }
 
 func AuthError(op string, err error) *SentinelError {
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// sessionLogPattern matches the session logs OpenSessionLog creates
+const sessionLogPattern = "session-*.log"
+
+// OpenSessionLog starts writing every entry, whatever the level, as JSON to
+// dir/session-<timestamp>.log, and removes the oldest session logs beyond
+// keep. It returns the path of the new log.
+func (l *Logger) OpenSessionLog(dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("session-%s.log", time.Now().Format("20060102-150405")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file = file
+	l.mu.Unlock()
+
+	if err := pruneSessionLogs(dir, keep); err != nil {
+		l.Debug("Could not remove old session logs: %v", err)
+	}
+	return path, nil
+}
+
+// Close stops writing the session log
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// pruneSessionLogs removes the oldest session logs of dir beyond keep. Their
+// names sort by the time they were started.
+func pruneSessionLogs(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	logs, err := filepath.Glob(filepath.Join(dir, sessionLogPattern))
+	if err != nil || len(logs) <= keep {
+		return err
+	}
+	sort.Strings(logs)
+	for _, path := range logs[:len(logs)-keep] {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
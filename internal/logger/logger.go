@@ -1,9 +1,12 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,11 +20,45 @@ const (
 	LevelError
 )
 
-// Logger provides structured logging
+// LevelNames lists the names of the levels, from the most verbose
+var LevelNames = []string{"debug", "info", "warn", "error"}
+
+// String returns the name of the level, e.g. "debug"
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return LevelNames[l]
+}
+
+// ParseLevel returns the level of a name in LevelNames, in any case
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range LevelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (expected %s)", name, strings.Join(LevelNames, ", "))
+}
+
+// Output formats
+const (
+	FormatText = "text" // [15:04:05] INFO: message
+	FormatJSON = "json" // One JSON object per line, with the fields
+)
+
+// Formats lists the output formats
+var Formats = []string{FormatText, FormatJSON}
+
+// Logger provides structured logging. It is safe for concurrent use.
 type Logger struct {
+	mu     sync.Mutex
 	level  Level
 	output io.Writer
 	prefix string
+	json   bool
+	file   *os.File // Session log, written at every level in JSON
+	fields map[string]interface{}
 }
 
 // New creates a new logger
@@ -33,6 +70,7 @@ func New(level Level, output io.Writer) *Logger {
 		level:  level,
 		output: output,
 		prefix: "sentinel",
+		fields: make(map[string]interface{}),
 	}
 }
 
@@ -41,26 +79,70 @@ func Default() *Logger {
 	return New(LevelInfo, os.Stderr)
 }
 
-func (l *Logger) log(level Level, format string, args ...interface{}) {
-	if level < l.level {
+// SetFormat sets the format of the output: FormatText or FormatJSON
+func (l *Logger) SetFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q (expected %s)", format, strings.Join(Formats, ", "))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.json = format == FormatJSON
+	return nil
+}
+
+// SetField adds a field to every later entry of the JSON output and the
+// session log, e.g. the run being analyzed. A nil value removes it.
+func (l *Logger) SetField(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if value == nil {
+		delete(l.fields, key)
 		return
 	}
+	l.fields[key] = value
+}
 
-	var levelStr string
-	switch level {
-	case LevelDebug:
-		levelStr = "DEBUG"
-	case LevelInfo:
-		levelStr = "INFO"
-	case LevelWarn:
-		levelStr = "WARN"
-	case LevelError:
-		levelStr = "ERROR"
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level && l.file == nil {
+		return
 	}
 
-	timestamp := time.Now().Format("15:04:05")
+	now := time.Now()
 	message := fmt.Sprintf(format, args...)
-	fmt.Fprintf(l.output, "[%s] %s: %s\n", timestamp, levelStr, message)
+	var entry []byte
+	if l.json || l.file != nil {
+		entry = l.entry(now, level, message)
+	}
+	if level >= l.level {
+		if l.json {
+			l.output.Write(entry)
+		} else {
+			fmt.Fprintf(l.output, "[%s] %s: %s\n", now.Format("15:04:05"), strings.ToUpper(level.String()), message)
+		}
+	}
+	if l.file != nil {
+		l.file.Write(entry)
+	}
+}
+
+// entry renders a JSON log entry with the fields
+func (l *Logger) entry(at time.Time, level Level, message string) []byte {
+	record := make(map[string]interface{}, len(l.fields)+3)
+	for key, value := range l.fields {
+		record[key] = value
+	}
+	record["time"] = at.Format(time.RFC3339Nano)
+	record["level"] = level.String()
+	record["msg"] = message
+	data, err := json.Marshal(record)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"time": at.Format(time.RFC3339Nano), "level": level.String(), "msg": message})
+	}
+	return append(data, '\n')
 }
 
 // Debug logs debug messages
@@ -85,5 +167,7 @@ func (l *Logger) Error(format string, args ...interface{}) {
 
 // SetLevel changes the logging level
 func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.level = level
 }
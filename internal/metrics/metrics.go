@@ -0,0 +1,209 @@
+// Package metrics counts what sentinel does, for the Prometheus endpoint of
+// serve and watch
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics of sentinel, in the order /metrics lists them
+var (
+	FailuresDetected = NewCounter("sentinel_failures_detected_total",
+		"Failed runs announced by watch or serve, by failure category", "category")
+	Diagnoses = NewCounter("sentinel_diagnoses_total",
+		"Runs diagnosed, by source of the diagnosis (rules, ai or none) and outcome", "source", "status")
+	FixesApplied = NewCounter("sentinel_fixes_applied_total",
+		"Fixes written to a workflow file")
+	FixReruns = NewCounter("sentinel_fix_reruns_total",
+		"Runs watched after a fix, by conclusion", "conclusion")
+	AIRequestDuration = NewHistogram("sentinel_ai_request_duration_seconds",
+		"Time the AI provider took to diagnose a failure, by provider and outcome (ok or error)",
+		[]float64{1, 2.5, 5, 10, 20, 30, 60, 120}, "provider", "outcome")
+	GitHubRequests = NewCounter("sentinel_github_api_requests_total",
+		"Requests to the GitHub API, retries included, by HTTP status code (error when none came back)", "code")
+)
+
+// registry holds every metric, in the order they were created
+var registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func register(m metric) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.metrics = append(registry.metrics, m)
+}
+
+// Counter is a count that only goes up, with one value per combination of
+// its labels
+type Counter struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64 // By the label values, joined by labelSeparator
+}
+
+// NewCounter creates and registers a counter
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc adds one to the counter of the label values, given in the order of
+// the labels
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds n to the counter of the label values
+func (c *Counter) Add(n float64, values ...string) {
+	key := joinValues(c.labels, values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += n
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	if len(c.labels) == 0 {
+		fmt.Fprintf(w, "%s %s\n", c.name, formatValue(c.values[""]))
+		return
+	}
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelPairs(c.labels, key, ""), formatValue(c.values[key]))
+	}
+}
+
+// Histogram counts observations in buckets of increasing upper bounds, with
+// their sum, per combination of its labels
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates and registers a histogram with the given bucket
+// upper bounds, in increasing order
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+// Observe records a value for the label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := joinValues(h.labels, values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, key, formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, key, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelPairs(h.labels, key, ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelPairs(h.labels, key, ""), s.count)
+	}
+}
+
+// Handler serves every metric in the Prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// Write writes every metric in the Prometheus text format
+func Write(w io.Writer) {
+	registry.mu.Lock()
+	metrics := append([]metric(nil), registry.metrics...)
+	registry.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// labelSeparator joins label values into a key; it cannot appear in text
+const labelSeparator = "\x00"
+
+// joinValues keys label values, padding missing ones with empty values
+func joinValues(labels, values []string) string {
+	padded := make([]string, len(labels))
+	copy(padded, values)
+	return strings.Join(padded, labelSeparator)
+}
+
+// labelPairs renders the labels of a key, with le when it is not empty
+func labelPairs(labels []string, key, le string) string {
+	var pairs []string
+	if len(labels) > 0 {
+		for i, value := range strings.Split(key, labelSeparator) {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], value))
+		}
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=%q", le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,49 @@
+// Package notify delivers messages to a chat webhook
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// sendTimeout bounds a notification, which must never stall a watch
+const sendTimeout = 15 * time.Second
+
+// Webhook posts messages to an incoming webhook accepting {"text": ...}, as
+// Slack, Mattermost, Rocket.Chat and Microsoft Teams workflows do
+type Webhook struct {
+	URL       string
+	UserAgent string
+}
+
+// Send posts a message in Markdown
+func (w *Webhook) Send(ctx context.Context, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.ValidationError("send_notification", fmt.Sprintf("invalid webhook URL: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", w.UserAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.NetworkError("send_notification", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.NetworkError("send_notification", fmt.Errorf("webhook answered %s", resp.Status))
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/patcher"
+)
+
+// maxLabelLength bounds how much of a finding is quoted next to a hunk
+const maxLabelLength = 70
+
+// finding is something a run revealed that a fix may address
+type finding struct {
+	label string          // How the finding is cited, e.g. "SC2086: Double quote..."
+	line  int             // Line of the analyzed workflow it points at; 0 if none
+	terms map[string]bool // Words that tie a changed line to it
+	all   bool            // Addresses every hunk, like the recipe that wrote the fix
+}
+
+// termPattern matches the words compared between findings and changed lines:
+// identifiers, keys, action references and versions
+var termPattern = regexp.MustCompile(`[A-Za-z0-9_.-]{3,}`)
+
+// sentencePattern splits an explanation into sentences
+var sentencePattern = regexp.MustCompile(`[^.!?\n]+(?:[.!?]+(?:\s|$)|$)`)
+
+// stopTerms are words too common in workflows and explanations to tie a
+// change to a finding
+var stopTerms = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true,
+	"from": true, "into": true, "not": true, "are": true, "was": true, "were": true,
+	"has": true, "have": true, "been": true, "will": true, "which": true, "when": true,
+	"should": true, "because": true, "but": true, "its": true, "than": true, "then": true,
+	"can": true, "does": true, "instead": true, "also": true, "only": true, "use": true,
+	"uses": true, "run": true, "runs": true, "name": true, "step": true, "steps": true,
+	"job": true, "jobs": true, "workflow": true, "workflows": true, "file": true,
+	"line": true, "error": true, "errors": true, "failed": true, "fails": true,
+	"failure": true, "fix": true, "true": true, "false": true, "echo": true, "env": true,
+	"github": true, "actions": true, "runs-on": true, "ubuntu-latest": true,
+}
+
+// categoryTerms are the workflow words a log pattern category is usually
+// fixed with
+var categoryTerms = map[string][]string{
+	"dependency":       {"npm", "yarn", "pnpm", "pip", "install", "cache", "package-lock.json", "requirements.txt", "go.sum"},
+	"deprecation":      {"set-output", "save-state", "github_output", "github_state", "node12", "node16"},
+	"runtime_version":  {"node-version", "python-version", "go-version", "java-version", "setup-node", "setup-python", "setup-go", "setup-java"},
+	"permissions":      {"permissions", "contents", "id-token", "packages", "pull-requests", "write"},
+	"cloud_auth":       {"id-token", "role-to-assume", "credentials", "audience", "workload_identity_provider", "aws-region"},
+	"checkout":         {"checkout", "fetch-depth", "ref", "token"},
+	"lfs":              {"lfs"},
+	"submodules":       {"submodules"},
+	"docker":           {"docker", "image", "container", "services"},
+	"exit_code":        {"continue-on-error", "exit"},
+	"shell":            {"shell", "bash", "pwsh"},
+	"line_endings":     {"autocrlf", "crlf", "eol"},
+	"paths":            {"working-directory", "path", "paths"},
+	"xcode":            {"xcode-select", "xcode", "developer_dir"},
+	"case_sensitivity": {"path", "paths", "working-directory"},
+}
+
+// terms extracts the comparable words of a text
+func terms(text string) map[string]bool {
+	found := make(map[string]bool)
+	for _, term := range termPattern.FindAllString(text, -1) {
+		term = strings.ToLower(strings.Trim(term, ".-"))
+		if len(term) >= 3 && !stopTerms[term] {
+			found[term] = true
+		}
+	}
+	return found
+}
+
+// runFindings collects the findings of a run and its diagnosis
+func runFindings(run *Report) []finding {
+	var findings []finding
+	for _, s := range run.Scripts {
+		label := s.Message
+		if s.Code != "" {
+			label = s.Code + ": " + label
+		}
+		findings = append(findings, finding{label: "script " + label, line: s.Line, terms: terms(s.Message)})
+	}
+	for _, s := range run.Skipped {
+		findings = append(findings, finding{label: "if: " + s.Condition, line: s.Line, terms: terms(s.Condition)})
+	}
+	for _, d := range run.Detected {
+		findings = append(findings, finding{label: fmt.Sprintf("log %s: %s", d.Pattern, strings.TrimSpace(d.Message)), terms: terms(d.Message)})
+	}
+	if run.Category != "" {
+		category := finding{label: run.Category + " failure", terms: make(map[string]bool)}
+		for _, term := range categoryTerms[run.Category] {
+			category.terms[term] = true
+		}
+		findings = append(findings, category)
+	}
+	if d := run.Diagnosis; d != nil {
+		for _, recipe := range d.Recipes {
+			findings = append(findings, finding{label: "recipe " + recipe, all: true})
+		}
+		for _, sentence := range sentencePattern.FindAllString(d.Explanation, -1) {
+			sentence = strings.TrimSpace(sentence)
+			if sentence != "" {
+				findings = append(findings, finding{label: "diagnosis: " + sentence, terms: terms(sentence)})
+			}
+		}
+	}
+	return findings
+}
+
+// annotateHunks ties each hunk to the findings it addresses: those pointing
+// at a line the hunk changes, and those sharing a word with its changed lines
+func annotateHunks(hunks []patcher.Hunk, findings []finding) []ReportChange {
+	changes := make([]ReportChange, len(hunks))
+	for i, hunk := range hunks {
+		changes[i].Hunk = hunk.Header()
+
+		// Old-side lines the hunk changes, and the words of its changes
+		changed := make(map[int]bool)
+		words := make(map[string]bool)
+		line := hunk.OldStart
+		for _, l := range hunk.Lines {
+			switch l.Op {
+			case patcher.OpContext:
+				line++
+			case patcher.OpRemove:
+				changed[line] = true
+				line++
+			case patcher.OpAdd:
+				changed[line] = true
+				changed[line-1] = true
+			}
+			if l.Op != patcher.OpContext {
+				for term := range terms(l.Text) {
+					words[term] = true
+				}
+			}
+		}
+
+		seen := make(map[string]bool)
+		for _, f := range findings {
+			if seen[f.label] || !addresses(f, changed, words) {
+				continue
+			}
+			seen[f.label] = true
+			changes[i].Addresses = append(changes[i].Addresses, truncateLabel(f.label))
+		}
+	}
+	return changes
+}
+
+// addresses reports whether a finding explains a change
+func addresses(f finding, changed map[int]bool, words map[string]bool) bool {
+	if f.all || (f.line > 0 && changed[f.line]) {
+		return true
+	}
+	for term := range f.terms {
+		if words[term] {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateLabel shortens a finding to one line
+func truncateLabel(label string) string {
+	label = strings.Join(strings.Fields(label), " ")
+	if len([]rune(label)) > maxLabelLength {
+		label = string([]rune(label)[:maxLabelLength-1]) + "…"
+	}
+	return label
+}
+
+// annotateFix records what each hunk of a fix addresses, judged from the
+// findings of the runs it fixes
+func (o *Orchestrator) annotateFix(req *patcher.PatchRequest, runs []*Report) []ReportChange {
+	hunks, err := o.patcher.Hunks(req)
+	if err != nil || len(hunks) == 0 {
+		return nil
+	}
+	var findings []finding
+	for _, run := range runs {
+		findings = append(findings, runFindings(run)...)
+	}
+	return annotateHunks(hunks, findings)
+}
+
+// printChanges lists what each hunk of a fix addresses, flagging the hunks
+// no finding explains
+func (o *Orchestrator) printChanges(changes []ReportChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintln(o.out, ui.FormatHeader("What each change addresses:"))
+	for _, change := range changes {
+		fmt.Fprintf(o.out, "  %s\n", ui.FormatDiffLine(change.Hunk))
+		for _, note := range changeNotes(change) {
+			fmt.Fprintf(o.out, "    %s\n", note)
+		}
+	}
+	fmt.Fprintln(o.out)
+}
+
+// changeNotes renders the findings a hunk addresses
+func changeNotes(change ReportChange) []string {
+	if len(change.Addresses) == 0 {
+		return []string{ui.FormatWarning("No finding explains this change; check that it is intended")}
+	}
+	var notes []string
+	for _, address := range change.Addresses {
+		notes = append(notes, ui.FormatDim("↳ "+address))
+	}
+	return notes
+}
@@ -0,0 +1,296 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+)
+
+// Apply applies a fix that fix --dry-run saved as a proposal. ref is the run
+// ID of a proposal of the current repository, or the path of a proposal
+// directory. The workflow file is first compared with the one the fix was
+// made from: a file that changed since gets the fix merged onto it, which
+// is only applied after a confirmation in a terminal. With remove the
+// proposal is deleted once applied.
+func (o *Orchestrator) Apply(opts Options, ref string, remove bool) (err error) {
+	o.opts = opts
+	format, err := o.format()
+	if err != nil {
+		return err
+	}
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+	repo := o.github.GetRepository()
+	dir, err := o.resolveProposal(repo.FullName, ref)
+	if err != nil {
+		return err
+	}
+	p, fixed, base, err := loadProposal(dir)
+	if err != nil {
+		return err
+	}
+	if p.Repository != repo.FullName {
+		return fmt.Errorf("the proposal in %s is for %s, but this is a checkout of %s", dir, p.Repository, repo.FullName)
+	}
+
+	o.report = &Report{
+		Repository: repo.FullName,
+		RunID:      p.RunID,
+		Workflow:   p.Workflow,
+		Status:     StatusProposed,
+		Proposal:   dir,
+		Diagnosis: &ReportDiagnosis{
+			Source:       p.Source,
+			Target:       p.Target,
+			Confidence:   p.Confidence,
+			Explanation:  p.Explanation,
+			SchemaIssues: p.SchemaIssues,
+		},
+	}
+	o.session = append(o.session, o.report)
+	if format.Stdout {
+		o.out = os.Stderr
+	}
+	started := time.Now()
+	defer func() {
+		if reportErr := o.finishSession(started, format, err); reportErr != nil && err == nil {
+			err = fmt.Errorf("failed to write report: %w", reportErr)
+		}
+	}()
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Proposal for run #%d (%s), made %s", p.RunID, ui.FormatHighlight(p.Target), ago(p.CreatedAt))))
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Confidence: %s", p.Confidence)))
+	fmt.Fprintln(o.out, wrapText(p.Explanation, 80))
+	fmt.Fprintln(o.out)
+
+	target := filepath.FromSlash(p.Target)
+	diagnosis := &copilot.DiagnosisResult{
+		Explanation:  p.Explanation,
+		FixedContent: fixed,
+		TargetFile:   target,
+		Confidence:   p.Confidence,
+		BaseContent:  base,
+	}
+	drift, err := proposalDrift(p, target, fixed)
+	if err != nil {
+		return err
+	}
+	if drift == driftApplied {
+		o.report.Status = StatusApplied
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s already has this fix; nothing to apply", target)))
+		return nil
+	}
+
+	// A file that changed since gets the fix merged onto it
+	req := patchRequest(diagnosis)
+	diff, err := o.patcher.PreviewDiff(req)
+	if err != nil {
+		if drift == driftChanged {
+			return fmt.Errorf("%s changed since the proposal was made and the fix no longer applies; diagnose the run again with: gh sentinel fix --run-id %d", target, p.RunID)
+		}
+		return err
+	}
+	fmt.Fprintln(o.out, ui.FormatHeader("━━━━━━━━━━━━━━ PROPOSED FIX ━━━━━━━━━━━━━━\n"))
+	if drift == driftChanged {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s changed since the proposal was made; this is the fix merged onto the current file", target)))
+	}
+	o.printDiff(diff, 0)
+
+	issues := patcher.ValidateSchema(target, fixed)
+	if len(issues) > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The fixed file has %d schema issues:", len(issues))))
+		for _, issue := range issues {
+			fmt.Fprintf(o.out, "  • %s\n", issue)
+		}
+		fmt.Fprintln(o.out)
+	}
+
+	critical := o.critical(target)
+	action, status, reason := o.applyDecision(applyGate{
+		confidences: []string{p.Confidence},
+		invalid:     len(issues) > 0,
+		disputed:    disputed(o.report),
+	})
+	switch {
+	case drift == driftChanged && !o.interactive():
+		return fmt.Errorf("refusing to apply a proposal whose file changed since it was made without review; apply it in a terminal, or diagnose the run again with: gh sentinel fix --run-id %d", p.RunID)
+	case action == applyHeld:
+		o.report.Status = status
+		fmt.Fprintln(o.out, ui.FormatWarning(reason))
+		return nil
+	case action == applyAuto && critical:
+		fmt.Fprintln(o.out, ui.FormatInfo("Opening the fix for review (--yes)"))
+	case action == applyAuto:
+		fmt.Fprintln(o.out, ui.FormatInfo("Applying the proposal (--yes)"))
+	case action == applyProposed:
+		return fmt.Errorf("refusing to apply the proposal without confirmation; pass --yes")
+	default:
+		details := "A backup will be created automatically"
+		if critical {
+			details = "This workflow is critical: the fix is committed to its own branch and opened as a pull request for a second reviewer"
+		}
+		if drift == driftChanged {
+			details = "The file changed since the proposal was made. " + details
+		}
+		if len(issues) > 0 {
+			details = fmt.Sprintf("Warning: %d schema issues found. %s", len(issues), details)
+		}
+		confirmed, err := o.confirmPatch(req, details, nil)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+			return nil
+		}
+	}
+
+	if critical {
+		err = o.requestReview(req, diagnosis)
+	} else {
+		err = o.applyProposal(req)
+	}
+	if err != nil {
+		return err
+	}
+	if remove && o.report.Status != StatusDeclined {
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not delete the proposal: %v", err)))
+		} else {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Deleted the proposal %s", dir)))
+		}
+	}
+	return nil
+}
+
+// applyProposal patches the working tree with an approved proposal
+func (o *Orchestrator) applyProposal(req *patcher.PatchRequest) error {
+	fmt.Fprintln(o.out, ui.FormatInfo("Applying patch..."))
+	var result *patcher.PatchResult
+	ok, err := o.applyMerging([]*patcher.PatchRequest{req}, func() (err error) {
+		result, err = o.patcher.Apply(req)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	if !ok {
+		o.report.Status = StatusDeclined
+		fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+		return nil
+	}
+	o.report.Status = StatusApplied
+	metrics.FixesApplied.Inc()
+	o.report.Patch = &ReportPatch{
+		BackupPath:   result.BackupPath,
+		LinesAdded:   result.LinesAdded,
+		LinesRemoved: result.LinesRemoved,
+		HunksSkipped: result.HunksSkipped,
+		Merged:       result.Merged,
+		Conflicts:    len(req.Resolutions),
+	}
+
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s patched successfully!", req.FilePath)))
+	if result.BackupPath != "" {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Backup: %s", result.BackupPath)))
+	}
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Changes: +%d -%d lines", result.LinesAdded, result.LinesRemoved)))
+	o.printMerged(req, result)
+	fmt.Fprintln(o.out)
+	o.verifyPatched(req.FilePath)
+	o.report.Patch.Hooks = o.runHooks(req.FilePath)
+	return nil
+}
+
+// resolveProposal returns the directory of the proposal ref names: a run ID
+// of the repository, or a proposal directory or its proposal.json
+func (o *Orchestrator) resolveProposal(repository, ref string) (string, error) {
+	if runID, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		dir := o.proposalDir(repository, runID)
+		if _, err := os.Stat(filepath.Join(dir, proposalFile)); err != nil {
+			return "", fmt.Errorf("no proposal for run %d of %s in %s; make one with: gh sentinel fix --run-id %d --dry-run", runID, repository, o.config.ProposalsDir, runID)
+		}
+		return dir, nil
+	}
+	if filepath.Base(ref) == proposalFile {
+		return filepath.Dir(ref), nil
+	}
+	return ref, nil
+}
+
+// loadProposal reads a proposal directory: the proposal, the fixed file and
+// the file it was made from, which is empty when the fix creates it
+func loadProposal(dir string) (*proposal, string, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, proposalFile))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("cannot read the proposal: %w", err)
+	}
+	var p proposal
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, "", "", fmt.Errorf("corrupt proposal %s: %w", filepath.Join(dir, proposalFile), err)
+	}
+	// Proposals can come from someone else; they only patch files of the
+	// repository
+	if p.Target == "" || !filepath.IsLocal(filepath.FromSlash(p.Target)) {
+		return nil, "", "", fmt.Errorf("the proposal in %s targets %q, which is not a file of the repository", dir, p.Target)
+	}
+
+	name := filepath.Base(p.Target)
+	fixed, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("cannot read the fixed file of the proposal: %w", err)
+	}
+	if p.BaseSHA256 == "" {
+		return &p, string(fixed), "", nil
+	}
+	base, err := os.ReadFile(filepath.Join(dir, name+proposalBaseExt))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("cannot read the original file of the proposal: %w", err)
+	}
+	if contentSHA256(string(base)) != p.BaseSHA256 {
+		return nil, "", "", fmt.Errorf("%s does not match the SHA-256 of the proposal", filepath.Join(dir, name+proposalBaseExt))
+	}
+	return &p, string(fixed), string(base), nil
+}
+
+// drift is how a workflow file compares with the one a proposal was made
+// from
+type drift int
+
+const (
+	driftNone    drift = iota // Unchanged
+	driftChanged              // Changed since; the fix has to be merged onto it
+	driftApplied              // Already the fixed file
+)
+
+// proposalDrift compares the workflow file with the one the proposal was
+// made from. A file created or deleted since cannot take the fix.
+func proposalDrift(p *proposal, target, fixed string) (drift, error) {
+	current, err := os.ReadFile(target)
+	if err != nil && !os.IsNotExist(err) {
+		return driftNone, fmt.Errorf("cannot read %s: %w", target, err)
+	}
+	exists := err == nil
+	switch {
+	case exists && string(current) == fixed:
+		return driftApplied, nil
+	case p.BaseSHA256 == "" && exists:
+		return driftNone, fmt.Errorf("the proposal creates %s, which exists now; diagnose the run again with: gh sentinel fix --run-id %d", target, p.RunID)
+	case p.BaseSHA256 != "" && !exists:
+		return driftNone, fmt.Errorf("%s was deleted since the proposal was made", target)
+	case exists && contentSHA256(string(current)) != p.BaseSHA256:
+		return driftChanged, nil
+	}
+	return driftNone, nil
+}
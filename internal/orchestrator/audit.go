@@ -0,0 +1,166 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/expr"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// auditFinding is a problem an audit rule found in a workflow file
+type auditFinding struct {
+	File     string `json:"file"`
+	Rule     string `json:"rule"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+}
+
+// auditRule checks one workflow file
+type auditRule struct {
+	name  string
+	check func(path, content string) ([]auditFinding, error)
+}
+
+// auditRules run in order on every audited file
+var auditRules = []auditRule{
+	{"expressions", auditExpressions},
+	{"needs", auditNeeds},
+}
+
+// Audit statically checks local workflow files without contacting GitHub.
+// Without paths every workflow in the workflows directory is audited. It
+// fails when any error-level problem is found.
+func (o *Orchestrator) Audit(opts Options, paths []string) error {
+	o.opts = opts
+
+	if len(paths) == 0 {
+		files, err := filepath.Glob(filepath.Join(workflow.Dir, "*"))
+		if err != nil {
+			return fmt.Errorf("failed to list workflow files: %w", err)
+		}
+		for _, file := range files {
+			ext := strings.ToLower(filepath.Ext(file))
+			if ext == ".yml" || ext == ".yaml" {
+				paths = append(paths, file)
+			}
+		}
+	}
+
+	findings := []auditFinding{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for _, rule := range auditRules {
+			found, err := rule.check(filepath.ToSlash(path), string(data))
+			if err != nil {
+				return err
+			}
+			findings = append(findings, found...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+
+	errorCount := 0
+	for _, f := range findings {
+		if f.Severity == expr.SeverityError {
+			errorCount++
+		}
+	}
+
+	if opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		o.printAudit(paths, findings, errorCount)
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("audit found %d errors", errorCount)
+	}
+	return nil
+}
+
+// auditExpressions checks ${{ }} expressions and if: conditions
+func auditExpressions(path, content string) ([]auditFinding, error) {
+	found, err := expr.Audit(path, content)
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]auditFinding, 0, len(found))
+	for _, f := range found {
+		findings = append(findings, auditFinding{
+			File:     path,
+			Rule:     "expressions",
+			Line:     f.Line,
+			Severity: f.Severity,
+			Message:  f.Message,
+		})
+	}
+	return findings, nil
+}
+
+// auditNeeds checks the needs: graph for missing jobs and cycles
+func auditNeeds(path, content string) ([]auditFinding, error) {
+	var findings []auditFinding
+	for _, issue := range patcher.ValidateNeeds(content) {
+		findings = append(findings, auditFinding{
+			File:     path,
+			Rule:     "needs",
+			Line:     issue.Line,
+			Severity: expr.SeverityError,
+			Message:  issue.Message,
+		})
+	}
+	return findings, nil
+}
+
+// printAudit prints the findings grouped by file
+func (o *Orchestrator) printAudit(paths []string, findings []auditFinding, errorCount int) {
+	if len(paths) == 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo("No workflow files found"))
+		return
+	}
+
+	file := ""
+	for _, f := range findings {
+		if f.File != file {
+			if file != "" {
+				fmt.Fprintln(o.out)
+			}
+			file = f.File
+			fmt.Fprintln(o.out, ui.FormatHeader(file))
+		}
+		label := fmt.Sprintf("line %d: %s", f.Line, f.Message)
+		fmt.Fprintf(o.out, "  %s\n", ui.Format(ui.LevelSeverity(f.Severity), label))
+	}
+	if len(findings) > 0 {
+		fmt.Fprintln(o.out)
+	}
+
+	switch {
+	case errorCount > 0:
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("%d errors, %d warnings in %d workflow files", errorCount, len(findings)-errorCount, len(paths))))
+	case len(findings) > 0:
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%d warnings in %d workflow files", len(findings), len(paths))))
+	default:
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("No problems found in %d workflow files", len(paths))))
+	}
+}
@@ -0,0 +1,303 @@
+package orchestrator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+)
+
+// batchFix is the combined fix for one file in a batch
+type batchFix struct {
+	diagnosis *copilot.DiagnosisResult
+	runs      []*Report // Runs whose failures the fix addresses
+	issues    []patcher.SchemaIssue
+}
+
+// fixAll diagnoses every run, merges fixes that land on the same file and
+// applies them together after a single review of the combined diff
+func (o *Orchestrator) fixAll(items []ui.WorkflowItem, workflowFiles []string) error {
+	if o.config.Repository != "" {
+		return fmt.Errorf("--all patches a local checkout; with --repo, fix the runs one at a time")
+	}
+	batch := o.report
+
+	var fixes []*batchFix
+	byTarget := make(map[string]*batchFix)
+	pending := make(map[string]string) // Target file -> fixed content so far
+	for i := range items {
+		selected := &items[i]
+		o.report = &Report{Repository: batch.Repository}
+		batch.Runs = append(batch.Runs, o.report)
+
+		based := pending[selected.Path] != "" // Diagnosed from an earlier fix
+		diagnosis, err := o.analyzeRun(selected, workflowFiles, pending)
+		if err != nil && o.ctx.Err() != nil {
+			o.report = batch
+			return err
+		}
+		if err != nil {
+			o.report.Status = StatusError
+			o.report.Error = err.Error()
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not diagnose run #%d: %v", selected.ID, err)))
+			continue
+		}
+		if diagnosis == nil {
+			continue
+		}
+		if len(diagnosis.Patches) > 0 {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Skipping this fix: it spans %d files, which are applied together; fix run #%d on its own", 1+len(diagnosis.Patches), selected.ID)))
+			continue
+		}
+
+		o.report.Status = StatusProposed
+		target := diagnosis.TargetFile
+		fix, seen := byTarget[target]
+		switch {
+		case !seen:
+			fix = &batchFix{diagnosis: diagnosis}
+			byTarget[target] = fix
+			fixes = append(fixes, fix)
+		case diagnosis.FixedContent == fix.diagnosis.FixedContent:
+			fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Same fix as run #%d, merged", fix.runs[0].RunID)))
+		case based && target == selected.Path:
+			// The new fix was made on top of the earlier one and replaces it
+			fix.diagnosis = &copilot.DiagnosisResult{
+				Explanation:  fix.diagnosis.Explanation + "\n\n" + diagnosis.Explanation,
+				FixedContent: diagnosis.FixedContent,
+				TargetFile:   target,
+				Confidence:   diagnosis.Confidence,
+				BaseContent:  fix.diagnosis.BaseContent,
+			}
+		default:
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Skipping this fix: it conflicts with the fix for run #%d in %s; run sentinel again after applying", fix.runs[0].RunID, target)))
+			continue
+		}
+		fix.runs = append(fix.runs, o.report)
+		pending[target] = fix.diagnosis.FixedContent
+	}
+	o.report = batch
+
+	if len(fixes) == 0 {
+		batch.Status = StatusNoFix
+		fmt.Fprintln(o.out, ui.FormatInfo("No fixes to apply"))
+		return nil
+	}
+	return o.applyBatch(fixes)
+}
+
+// applyBatch shows the combined diff and applies every fix once approved
+func (o *Orchestrator) applyBatch(fixes []*batchFix) error {
+	// Critical workflows only change through a reviewed pull request,
+	// which a batch does not open
+	var batchable []*batchFix
+	for _, fix := range fixes {
+		if !o.critical(fix.diagnosis.TargetFile) || o.opts.DryRun {
+			batchable = append(batchable, fix)
+			continue
+		}
+		for _, run := range fix.runs {
+			run.Status = StatusProposed
+		}
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s is critical and needs a second reviewer; open its fix for review with: gh sentinel fix --run-id %d",
+			fix.diagnosis.TargetFile, fix.runs[0].RunID)))
+	}
+	if len(batchable) == 0 {
+		o.report.Status = StatusProposed
+		return nil
+	}
+	fixes = batchable
+
+	fmt.Fprintln(o.out, "\n"+ui.FormatHeader(fmt.Sprintf("━━━━━━━━━━ PROPOSED FIXES (%d files) ━━━━━━━━━━\n", len(fixes))))
+
+	invalid := false
+	for _, fix := range fixes {
+		var ids []string
+		for _, run := range fix.runs {
+			ids = append(ids, fmt.Sprintf("#%d", run.RunID))
+		}
+		fmt.Fprintln(o.out, ui.FormatHighlight(fmt.Sprintf("%s (runs %s)", fix.diagnosis.TargetFile, strings.Join(ids, ", "))))
+
+		diff, err := o.patcher.PreviewDiff(patchRequest(fix.diagnosis))
+		if err != nil {
+			o.logger.Warn("Could not generate diff preview: %v", err)
+		} else {
+			o.printDiff(diff, 0)
+		}
+		changes := o.annotateFix(patchRequest(fix.diagnosis), fix.runs)
+		for _, run := range fix.runs {
+			if run.Diagnosis != nil {
+				run.Diagnosis.Changes = changes
+			}
+		}
+		o.printChanges(changes)
+
+		fix.issues = patcher.ValidateSchema(fix.diagnosis.TargetFile, fix.diagnosis.FixedContent)
+		if len(fix.issues) > 0 {
+			invalid = true
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The fixed file has %d schema issues:", len(fix.issues))))
+			for _, issue := range fix.issues {
+				fmt.Fprintf(o.out, "  • %s\n", issue)
+			}
+			fmt.Fprintln(o.out)
+		}
+	}
+
+	if o.opts.CreateBranch || o.opts.Watch {
+		fmt.Fprintln(o.out, ui.FormatDim("--create-branch and --watch apply to single fixes; commit and push the batch manually"))
+	}
+
+	gate := applyGate{invalid: invalid}
+	for _, fix := range fixes {
+		gate.confidences = append(gate.confidences, fix.diagnosis.Confidence)
+		gate.disputed = gate.disputed || slices.ContainsFunc(fix.runs, disputed)
+	}
+	action, status, reason := o.applyDecision(gate)
+	switch {
+	case o.opts.DryRun:
+		return o.proposeBatch(fixes)
+	case action == applyHeld:
+		o.setBatchStatus(fixes, status)
+		fmt.Fprintln(o.out, ui.FormatWarning(reason))
+		return nil
+	case action == applyAuto:
+		fmt.Fprintln(o.out, ui.FormatInfo("Auto-applying fixes (--yes)"))
+	case action == applyProposed:
+		o.setBatchStatus(fixes, status)
+		for _, fix := range fixes {
+			fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Proposed content for %s (not applied, pass --yes to apply):", fix.diagnosis.TargetFile)))
+			fmt.Fprintln(o.out, fix.diagnosis.FixedContent)
+		}
+		return nil
+	default:
+		details := "A backup of each file will be created automatically"
+		if invalid {
+			details = "Warning: some fixes have schema issues. " + details
+		}
+		confirmed, err := ui.ShowConfirmation(fmt.Sprintf("Apply fixes to %d files?", len(fixes)), details)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			o.setBatchStatus(fixes, StatusDeclined)
+			fmt.Fprintln(o.out, ui.FormatDim("Patches cancelled by user"))
+			return nil
+		}
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo("Applying patches..."))
+	o.report.Status = StatusProposed
+	var applied []*batchFix
+	var reqs []*patcher.PatchRequest
+	for _, fix := range fixes {
+		// A file changed upstream meanwhile needs a fresh diagnosis
+		if change := o.changedUpstream(fix.diagnosis.TargetFile); change != "" {
+			for _, run := range fix.runs {
+				run.Status = StatusProposed
+			}
+			fmt.Fprintln(o.out, ui.FormatWarning(change+"; skipped its fix, run sentinel again to diagnose the new version"))
+			continue
+		}
+		applied = append(applied, fix)
+		reqs = append(reqs, patchRequest(fix.diagnosis))
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	// The working tree may not be ready for the fixes
+	var files []copilot.FilePatch
+	for _, fix := range applied {
+		files = append(files, fix.diagnosis.Files()...)
+	}
+	if ok, err := o.guardPatch(files, applied[0].runs[0].Branch); err != nil || !ok {
+		if err == nil {
+			o.setBatchStatus(applied, StatusDeclined)
+			fmt.Fprintln(o.out, ui.FormatDim("Patches cancelled by user"))
+		}
+		return err
+	}
+
+	// The fixes are written together: if one cannot be, none is
+	var results []*patcher.PatchResult
+	var group string
+	ok, err := o.applyMerging(reqs, func() (err error) {
+		results, group, err = o.patcher.ApplyGroup(reqs)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply the patches, no file was changed: %w", err)
+	}
+	if !ok {
+		o.setBatchStatus(applied, StatusDeclined)
+		fmt.Fprintln(o.out, ui.FormatDim("Patches cancelled by user"))
+		return nil
+	}
+	if len(results) < 2 {
+		group = ""
+	}
+	for i, fix := range applied {
+		result := results[i]
+		metrics.FixesApplied.Inc()
+		for _, run := range fix.runs {
+			run.Status = StatusApplied
+			run.Patch = &ReportPatch{
+				BackupPath:   result.BackupPath,
+				LinesAdded:   result.LinesAdded,
+				LinesRemoved: result.LinesRemoved,
+				Group:        group,
+				Merged:       result.Merged,
+				Conflicts:    len(reqs[i].Resolutions),
+			}
+		}
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s patched (+%d -%d lines)", fix.diagnosis.TargetFile, result.LinesAdded, result.LinesRemoved)))
+		if result.BackupPath != "" {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Backup: %s", result.BackupPath)))
+		}
+		o.printMerged(reqs[i], result)
+		o.verifyPatched(fix.diagnosis.TargetFile)
+		hookResults := o.runHooks(fix.diagnosis.TargetFile)
+		for _, run := range fix.runs {
+			run.Patch.Hooks = hookResults
+		}
+	}
+	o.report.Status = StatusApplied
+	return nil
+}
+
+// proposeBatch writes each fix of a batch to the proposal directory of its
+// first run, which the other runs it fixes point to
+func (o *Orchestrator) proposeBatch(fixes []*batchFix) error {
+	for _, fix := range fixes {
+		base, created, err := localBase(fix.diagnosis)
+		if err != nil {
+			return err
+		}
+		first := fix.runs[0]
+		if err := o.saveProposal(first, fix.diagnosis, base, created, fix.issues); err != nil {
+			return err
+		}
+		for _, run := range fix.runs[1:] {
+			run.Status = StatusProposed
+			run.Proposal = first.Proposal
+		}
+	}
+	o.report.Status = StatusProposed
+	return nil
+}
+
+// setBatchStatus sets the status of the batch and every run with a fix
+func (o *Orchestrator) setBatchStatus(fixes []*batchFix, status string) {
+	o.report.Status = status
+	for _, fix := range fixes {
+		for _, run := range fix.runs {
+			run.Status = status
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+)
+
+// fixBranchPrefix namespaces branches created by --create-branch
+const fixBranchPrefix = "sentinel/fix-"
+
+// publishFix commits the patched files on a new branch and pushes them
+// using the gh CLI's credentials. With open_pr a pull request against the
+// checked-out branch is opened for it.
+func (o *Orchestrator) publishFix(diagnosis *copilot.DiagnosisResult) error {
+	if o.report.RunID == 0 {
+		return fmt.Errorf("cannot name the fix branch without a run ID")
+	}
+
+	repo, err := o.openRepo()
+	if err != nil {
+		return err
+	}
+
+	branch := fmt.Sprintf("%s%d", fixBranchPrefix, o.report.RunID)
+	if repo.BranchExists(branch) {
+		return fmt.Errorf("branch %s already exists; delete it or commit the fix manually", branch)
+	}
+
+	base, err := repo.CurrentBranch()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Creating branch %s...", branch)))
+	if err := repo.CreateBranch(branch); err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, patch := range diagnosis.Files() {
+		paths = append(paths, patch.TargetFile)
+	}
+	sha, err := repo.Commit(o.commitMessage(diagnosis), paths...)
+	if err != nil {
+		return err
+	}
+	o.report.Patch.Branch = branch
+	o.report.Patch.Commit = sha
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Pushing %s to origin...", branch)))
+	if err := repo.Push("origin", branch); err != nil {
+		return err
+	}
+	o.report.Patch.Pushed = true
+
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Fix committed (%s) and pushed to %s", shortSHA(sha), branch)))
+	if !o.config.OpenPR {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Open a pull request with: gh pr create --head %s", branch)))
+		return nil
+	}
+
+	pr, err := o.github.CreatePullRequest(branch, base, fmt.Sprintf("ci: fix %s (run %d)", diagnosis.TargetFile, o.report.RunID), o.pullBody(diagnosis))
+	if err != nil {
+		return fmt.Errorf("could not open a pull request for %s: %w", branch, err)
+	}
+	o.report.Patch.PullRequest = pr.Number
+	o.report.Patch.PullURL = pr.URL
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Opened pull request #%d: %s", pr.Number, pr.URL)))
+	return nil
+}
+
+// commitMessage describes the fix, carrying the AI explanation in the body
+func (o *Orchestrator) commitMessage(diagnosis *copilot.DiagnosisResult) string {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "ci: fix %s (run %d)\n\n", diagnosis.TargetFile, o.report.RunID)
+	if explanation := strings.TrimSpace(diagnosis.Explanation); explanation != "" {
+		msg.WriteString(wrapText(explanation, 72))
+		msg.WriteString("\n\n")
+	}
+	fmt.Fprintf(&msg, "Generated by gh-sentinel from failed run %d", o.report.RunID)
+	if o.report.Workflow != "" {
+		fmt.Fprintf(&msg, " of %s", o.report.Workflow)
+	}
+	msg.WriteString(".\n")
+	return msg.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
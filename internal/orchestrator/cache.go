@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gh-sentinel/pkg/copilot"
+)
+
+// diagnosisCacheDir is the subdirectory of the cache directory holding AI
+// diagnoses
+const diagnosisCacheDir = "diagnoses"
+
+// cachedDiagnosis is an AI diagnosis stored in the cache directory
+type cachedDiagnosis struct {
+	CreatedAt  time.Time               `json:"created_at"`
+	Repository string                  `json:"repository"`
+	RunID      int64                   `json:"run_id"`
+	Diagnosis  copilot.DiagnosisResult `json:"diagnosis"`
+}
+
+// diagnosisKey identifies the AI diagnosis of a run by its repository, its
+// ID and what the AI is given: the logs, the workflow file, the actions and
+// workflows it runs, the source files the logs point to, and the language of
+// the explanation
+func (o *Orchestrator) diagnosisKey(runID int64, logs, fileContent string, related, sources map[string]string) string {
+	content := sha256.New()
+	content.Write([]byte(logs))
+	content.Write([]byte{0})
+	content.Write([]byte(fileContent))
+	for _, files := range []map[string]string{related, sources} {
+		for _, path := range sortedPaths(files) {
+			fmt.Fprintf(content, "\x00%s\x00%s", path, files[path])
+		}
+	}
+
+	key := sha256.New()
+	fmt.Fprintf(key, "%s\x00%d\x00%x\x00%s", o.report.Repository, runID, content.Sum(nil), o.config.Language)
+	return hex.EncodeToString(key.Sum(nil))
+}
+
+func (o *Orchestrator) diagnosisCachePath(key string) string {
+	return filepath.Join(o.config.CacheDir, diagnosisCacheDir, key+".json")
+}
+
+// cachedDiagnosisFor returns the cached diagnosis of a key, or nil if there
+// is none younger than the cache TTL or --no-cache was given
+func (o *Orchestrator) cachedDiagnosisFor(key string) *cachedDiagnosis {
+	if o.opts.NoCache {
+		return nil
+	}
+	data, err := os.ReadFile(o.diagnosisCachePath(key))
+	if err != nil {
+		return nil
+	}
+	var cached cachedDiagnosis
+	if err := json.Unmarshal(data, &cached); err != nil {
+		o.logger.Debug("Ignoring corrupt cached diagnosis %s: %v", key, err)
+		return nil
+	}
+	if o.config.CacheTTL > 0 && time.Since(cached.CreatedAt) > o.config.CacheTTL {
+		return nil
+	}
+	return &cached
+}
+
+// cacheDiagnosis stores an AI diagnosis so analyzing the same run again does
+// not ask the AI again. Failing to store it only costs a later request.
+func (o *Orchestrator) cacheDiagnosis(key string, runID int64, diagnosis *copilot.DiagnosisResult) {
+	stored := *diagnosis
+	stored.BaseContent = "" // Set from the fetched file on every analysis
+	if d := o.report.Diagnosis; d != nil && d.Disagreement != nil {
+		stored.Confidence = d.Disagreement.AIConfidence // Lowered again on every analysis
+	}
+	data, err := json.Marshal(cachedDiagnosis{
+		CreatedAt:  time.Now(),
+		Repository: o.report.Repository,
+		RunID:      runID,
+		Diagnosis:  stored,
+	})
+	if err != nil {
+		return
+	}
+	path := o.diagnosisCachePath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		o.logger.Debug("Could not cache the diagnosis: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		o.logger.Debug("Could not cache the diagnosis: %v", err)
+	}
+}
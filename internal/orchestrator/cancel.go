@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"gh-sentinel/internal/ui"
+)
+
+// Cancel stops a single run, or every queued and in-progress run on a
+// branch, so a broken push stops spawning doomed runs
+func (o *Orchestrator) Cancel(opts Options, runID int64, branch string) error {
+	o.opts = opts
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+
+	if branch != "" {
+		return o.cancelBranch(branch)
+	}
+
+	run, err := o.github.GetWorkflowRun(runID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow run %d: %w", runID, err)
+	}
+	return o.cancelRun(run.ID, run.Status)
+}
+
+// cancelRun cancels one run unless it already finished
+func (o *Orchestrator) cancelRun(runID int64, status string) error {
+	if status == "completed" {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Run %d has already completed", runID)))
+		return nil
+	}
+
+	if err := o.github.CancelWorkflowRun(runID); err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Cancellation requested for run %d", runID)))
+	return nil
+}
+
+// cancelBranch cancels every queued and in-progress run on branch
+func (o *Orchestrator) cancelBranch(branch string) error {
+	if branch == "" {
+		return fmt.Errorf("the run has no branch to cancel")
+	}
+
+	runs, err := o.github.ListActiveRuns(branch)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("No active runs on %s", branch)))
+		return nil
+	}
+
+	cancelled := 0
+	for _, run := range runs {
+		if err := o.github.CancelWorkflowRun(run.ID); err != nil {
+			o.logger.Warn("Could not cancel run %d: %v", run.ID, err)
+			continue
+		}
+		cancelled++
+	}
+
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Cancellation requested for %d of %d active runs on %s", cancelled, len(runs), branch)))
+	if cancelled < len(runs) {
+		return fmt.Errorf("%d runs on %s could not be cancelled", len(runs)-cancelled, branch)
+	}
+	return nil
+}
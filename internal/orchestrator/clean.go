@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gh-sentinel/internal/cleanup"
+	"gh-sentinel/internal/ui"
+)
+
+// policies returns the retention policy of each working directory
+func (o *Orchestrator) policies(all bool) map[string]cleanup.Policy {
+	return map[string]cleanup.Policy{
+		o.config.TempDir:  {MaxAge: o.config.TempTTL, MaxSize: o.config.MaxDirSize, All: all},
+		o.config.CacheDir: {MaxAge: o.config.CacheTTL, MaxSize: o.config.MaxDirSize, All: all},
+	}
+}
+
+// collectGarbage enforces the retention limits of the working directories
+// at startup. Failures only cost disk space, so they are logged and ignored.
+func (o *Orchestrator) collectGarbage() {
+	o.collected = make(map[string]*cleanup.Result)
+	for dir, policy := range o.policies(false) {
+		result, err := cleanup.Collect(dir, policy)
+		if err != nil {
+			o.logger.Debug("Cleaning %s: %v", dir, err)
+		}
+		if result != nil && result.FilesRemoved > 0 {
+			o.collected[dir] = result
+			o.logger.Debug("Removed %d files (%s) from %s", result.FilesRemoved, cleanup.FormatSize(result.BytesFreed), dir)
+		}
+	}
+	if removed, err := o.patcher.PruneBackups(); err != nil {
+		o.logger.Debug("Pruning backups: %v", err)
+	} else if removed > 0 {
+		o.logger.Debug("Removed %d old backups from %s", removed, o.config.BackupDir)
+	}
+}
+
+// Clean enforces the retention limits of the temp and cache directories,
+// or empties them when all is set
+func (o *Orchestrator) Clean(opts Options, all bool) error {
+	o.opts = opts
+
+	var results []*cleanup.Result
+	var firstErr error
+	for _, dir := range []string{o.config.TempDir, o.config.CacheDir} {
+		result, err := cleanup.Collect(dir, o.policies(all)[dir])
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if result == nil {
+			continue
+		}
+		// Count what the startup collection already removed
+		if startup := o.collected[dir]; startup != nil {
+			result.FilesRemoved += startup.FilesRemoved
+			result.BytesFreed += startup.BytesFreed
+		}
+		results = append(results, result)
+	}
+
+	if opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+		return firstErr
+	}
+
+	for _, result := range results {
+		line := fmt.Sprintf("%s: removed %d files (%s), kept %d (%s)",
+			result.Dir, result.FilesRemoved, cleanup.FormatSize(result.BytesFreed),
+			result.FilesKept, cleanup.FormatSize(result.BytesKept))
+		fmt.Fprintln(o.out, ui.FormatSuccess(line))
+	}
+	if !all {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Limits: temp files %.0fh, cache files %.0fh, %s per directory; pass --all to empty both",
+			o.config.TempTTL.Hours(), o.config.CacheTTL.Hours(), cleanup.FormatSize(o.config.MaxDirSize))))
+	}
+	if o.config.BackupDir != "" {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Backups in %s keep the %d newest per file for %.0f days (0 means no limit); --all leaves them alone",
+			o.config.BackupDir, o.config.BackupMaxCount, o.config.BackupMaxAge.Hours()/24)))
+	}
+	return firstErr
+}
@@ -0,0 +1,150 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/report"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/patcher"
+)
+
+// maxCommentDiffLines bounds the diff of a fix in a comment
+const maxCommentDiffLines = 150
+
+// commentMarker tags the comments of the diagnoses of a workflow, so that
+// diagnosing it again edits the comment instead of adding one
+func commentMarker(workflow string) string {
+	return fmt.Sprintf("<!-- gh-sentinel diagnosis: %s -->", workflow)
+}
+
+// share posts the diagnosis of a run where its authors see it, and files
+// an issue when sentinel could not fix the failure
+func (o *Orchestrator) share(runID int64, diff string) error {
+	if err := o.offerComment(runID, diff); err != nil {
+		return err
+	}
+	return o.offerIssue(runID)
+}
+
+// offerComment posts the diagnosis of the run, and the diff of its fix, as
+// a comment on the pull request the run belongs to, or on the commit of a
+// push. In a terminal the user is asked first; otherwise it is only posted
+// with --comment. An earlier comment of sentinel about the same workflow
+// is updated rather than repeated.
+func (o *Orchestrator) offerComment(runID int64, diff string) error {
+	d := o.report.Diagnosis
+	if d == nil || (!o.opts.Comment && !o.interactive()) {
+		return nil
+	}
+	run, err := o.github.GetWorkflowRun(runID)
+	if err != nil {
+		o.logger.Warn("Cannot tell where run #%d comes from: %v", runID, err)
+		return nil
+	}
+	number, err := o.github.RunPullRequest(run)
+	if err != nil {
+		o.logger.Warn("Cannot find the pull request of run #%d: %v", runID, err)
+		return nil
+	}
+
+	where := fmt.Sprintf("pull request #%d", number)
+	if number == 0 {
+		if run.Event != "push" {
+			o.logger.Debug("Not commenting on run #%d of a %s event", runID, run.Event)
+			return nil
+		}
+		where = "commit " + shortSHA(run.HeadSHA)
+	}
+	if !o.opts.Comment {
+		confirmed, err := ui.ShowConfirmation(
+			fmt.Sprintf("Post the diagnosis on %s?", where),
+			"Diagnosing this workflow again updates the same comment",
+		)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	marker := commentMarker(o.report.Workflow)
+	body := o.commentBody(marker, diff)
+	var comment *github.Comment
+	if number != 0 {
+		comment, err = o.github.CommentOnPullRequest(number, marker, body)
+	} else {
+		comment, err = o.github.CommentOnCommit(run.HeadSHA, marker, body)
+	}
+	if err != nil {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not comment on %s: %v", where, err)))
+		return nil
+	}
+	o.report.CommentURL = comment.URL
+	verb := "Posted"
+	if comment.Updated {
+		verb = "Updated"
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s the diagnosis on %s", verb, where)))
+	fmt.Fprintln(o.out, ui.FormatDim("  "+comment.URL))
+	return nil
+}
+
+// commentBody renders the diagnosis of the report as Markdown: the failed
+// steps, the root cause and the diff of the fix, if there is one
+func (o *Orchestrator) commentBody(marker, diff string) string {
+	d := o.report.Diagnosis
+	var b strings.Builder
+	b.WriteString(marker + "\n")
+	fmt.Fprintf(&b, "### 🛡️ gh-sentinel diagnosis of `%s`\n\n", o.report.Workflow)
+	fmt.Fprintf(&b, "[Run %d](%s) failed", o.report.RunID, o.github.RunURL(o.report.RunID))
+	var steps []string
+	for _, f := range o.report.Failed {
+		steps = append(steps, fmt.Sprintf("%s › `%s`", f.Job, f.Step))
+	}
+	if len(steps) > 0 {
+		b.WriteString(" at " + strings.Join(steps, ", "))
+	}
+	b.WriteString(".\n\n")
+
+	source := d.Source
+	if d.Confidence != "" {
+		source += ", " + strings.ToLower(d.Confidence) + " confidence"
+	}
+	fmt.Fprintf(&b, "**Root cause** (%s)\n\n", source)
+	if explanation := strings.TrimSpace(d.Explanation); explanation != "" {
+		b.WriteString(explanation + "\n\n")
+	}
+
+	if diff = strings.TrimRight(diff, "\n"); diff != "" {
+		fmt.Fprintf(&b, "**Proposed fix** of `%s` (%s)\n\n", d.Target, report.Describe(o.report.Status))
+		lines := strings.Split(diff, "\n")
+		if len(lines) > maxCommentDiffLines {
+			lines = append(lines[:maxCommentDiffLines], fmt.Sprintf("... (%d more lines)", len(lines)-maxCommentDiffLines))
+		}
+		b.WriteString("```diff\n" + strings.Join(lines, "\n") + "\n```\n\n")
+	}
+	b.WriteString("<sub>Posted by gh-sentinel; diagnosing the workflow again updates this comment.</sub>\n")
+	return b.String()
+}
+
+// commentDiff is the diff of a fix as the comment shows it, taken before
+// the fix is applied
+func (o *Orchestrator) commentDiff(diagnosis *copilot.DiagnosisResult) string {
+	if diagnosis.FixedContent == "" || diagnosis.Confidence == "HEALTHY" {
+		return ""
+	}
+	// With --repo the fix is made from the default branch's version
+	if o.config.Repository != "" {
+		return patcher.Unified(diagnosis.TargetFile, patcher.Diff(diagnosis.BaseContent, diagnosis.FixedContent), diagnosis.BaseContent == "")
+	}
+	diff, err := o.patcher.PreviewDiff(patchRequest(diagnosis))
+	if err != nil {
+		o.logger.Debug("No diff of the fix for the comment: %v", err)
+		return ""
+	}
+	return diff
+}
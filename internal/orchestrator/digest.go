@@ -0,0 +1,219 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/notify"
+	"gh-sentinel/internal/report"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/github"
+)
+
+// maxDigestRuns bounds how many completed runs a digest counts
+const maxDigestRuns = 1000
+
+// maxDigestFingerprints bounds how many failed runs a digest fetches jobs
+// for, one API call each
+const maxDigestFingerprints = 30
+
+// topFingerprints is how many recurring failures a digest lists
+const topFingerprints = 5
+
+// ciDigest summarizes the CI health of a repository over a period
+type ciDigest struct {
+	Repository     string
+	Branch         string
+	Since, Until   time.Time
+	Runs           int // Runs that succeeded or failed; cancelled and skipped runs are left out
+	Failures       int
+	Applied        int    // Fixes applied by sentinel sessions
+	AwaitingReview int    // Fixes of critical workflows opened for review
+	Tags           string // Failure categories of the runs sessions analyzed, e.g. "test 3 • dependency 1"
+	Fingerprints   []fingerprintCount
+	Truncated      bool // More runs completed than were counted
+}
+
+// fingerprintCount is how often a failure recurred
+type fingerprintCount struct {
+	Fingerprint string
+	Count       int
+}
+
+// failedConclusions are the conclusions counted as failures
+var failedConclusions = map[string]bool{"failure": true, "timed_out": true, "startup_failure": true}
+
+// collectDigest gathers the runs of the default branch completed in a
+// period and the fixes sentinel sessions made to the repository meanwhile
+func (o *Orchestrator) collectDigest(since, until time.Time) (*ciDigest, error) {
+	repo := o.github.GetRepository()
+	d := &ciDigest{Repository: repo.FullName, Branch: repo.DefaultBranch, Since: since, Until: until}
+
+	runs, err := o.github.ListCompletedRuns(repo.DefaultBranch, since, maxDigestRuns)
+	if err != nil {
+		return nil, err
+	}
+	d.Truncated = len(runs) == maxDigestRuns
+
+	counts := make(map[string]int)
+	fetched := 0
+	for _, run := range runs {
+		if run.CreatedAt.After(until) {
+			continue
+		}
+		switch {
+		case run.Conclusion == "success":
+			d.Runs++
+		case failedConclusions[run.Conclusion]:
+			d.Runs++
+			d.Failures++
+			if fetched < maxDigestFingerprints {
+				fetched++
+				counts[o.fingerprint(run)]++
+			}
+		}
+	}
+	for fingerprint, count := range counts {
+		d.Fingerprints = append(d.Fingerprints, fingerprintCount{fingerprint, count})
+	}
+	sort.Slice(d.Fingerprints, func(i, j int) bool {
+		a, b := d.Fingerprints[i], d.Fingerprints[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+	if len(d.Fingerprints) > topFingerprints {
+		d.Fingerprints = d.Fingerprints[:topFingerprints]
+	}
+
+	recaps, err := readRecaps(o.config.HistoryFile, maxReviewSessions)
+	if err != nil {
+		o.logger.Warn("Could not read the session history: %v", err)
+	}
+	var analyzed []sessionRun
+	for _, recap := range recaps {
+		if recap.Repository != d.Repository || recap.FinishedAt.Before(since) || recap.FinishedAt.After(until) {
+			continue
+		}
+		analyzed = append(analyzed, recap.Runs...)
+		for _, run := range recap.Runs {
+			switch run.Status {
+			case StatusApplied:
+				d.Applied++
+			case StatusAwaitingReview:
+				d.AwaitingReview++
+			}
+		}
+	}
+	d.Tags = report.TagCounts(analyzed)
+	return d, nil
+}
+
+// fingerprint identifies a failure by where it happened: the workflow, and
+// the first failed job and step when they can be retrieved
+func (o *Orchestrator) fingerprint(run *github.WorkflowRun) string {
+	parts := []string{run.WorkflowPath}
+	if run.WorkflowPath == "" {
+		parts[0] = run.Name
+	}
+	jobs, err := o.github.ListRunJobs(run.ID)
+	if err != nil {
+		o.logger.Debug("Could not list the jobs of run #%d: %v", run.ID, err)
+		return parts[0]
+	}
+	for _, job := range jobs {
+		if !failedConclusions[job.Conclusion] {
+			continue
+		}
+		parts = append(parts, job.Name)
+		for _, step := range job.Steps {
+			if failedConclusions[step.Conclusion] {
+				parts = append(parts, step.Name)
+				break
+			}
+		}
+		break
+	}
+	return strings.Join(parts, " › ")
+}
+
+// title names the repository and period of a digest
+func (d *ciDigest) title() string {
+	return fmt.Sprintf("CI health of %s (%s), %s – %s", d.Repository, d.Branch,
+		d.Since.Format("Jan 02 15:04"), d.Until.Format("Jan 02 15:04"))
+}
+
+// lines renders the figures of a digest
+func (d *ciDigest) lines() []string {
+	var lines []string
+	if d.Runs == 0 {
+		lines = append(lines, "No run succeeded or failed")
+	} else {
+		more := ""
+		if d.Truncated {
+			more = fmt.Sprintf(", first %d completed runs only", maxDigestRuns)
+		}
+		lines = append(lines, fmt.Sprintf("Success rate: %d%% (%d of %d runs%s)",
+			(d.Runs-d.Failures)*100/d.Runs, d.Runs-d.Failures, d.Runs, more))
+	}
+	lines = append(lines, fmt.Sprintf("Failures seen: %d", d.Failures))
+	applied := fmt.Sprintf("Fixes applied: %d", d.Applied)
+	if d.AwaitingReview > 0 {
+		applied += fmt.Sprintf(", %d awaiting review", d.AwaitingReview)
+	}
+	lines = append(lines, applied)
+	if d.Tags != "" {
+		lines = append(lines, "Analyzed failures by category: "+d.Tags)
+	}
+	if len(d.Fingerprints) > 0 {
+		lines = append(lines, "Top recurring failures:")
+		for _, f := range d.Fingerprints {
+			lines = append(lines, fmt.Sprintf("  %d× %s", f.Count, f.Fingerprint))
+		}
+	}
+	return lines
+}
+
+// markdown renders a digest for a chat webhook
+func (d *ciDigest) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n", d.title())
+	for _, line := range d.lines() {
+		if strings.HasPrefix(line, "  ") {
+			fmt.Fprintf(&b, "    • %s\n", strings.TrimSpace(line))
+		} else {
+			fmt.Fprintf(&b, "• %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// sendDigest summarizes a period of CI health and posts it to the webhook,
+// or prints it when there is none. Errors are reported and watching goes on.
+func (o *Orchestrator) sendDigest(since, until time.Time) {
+	d, err := o.collectDigest(since, until)
+	if err != nil {
+		if o.ctx.Err() == nil {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not collect the CI health digest: %v", err)))
+		}
+		return
+	}
+
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, ui.FormatHeader(d.title()))
+	for _, line := range d.lines() {
+		fmt.Fprintf(o.out, "  %s\n", line)
+	}
+	if o.config.NotifyWebhook != "" {
+		webhook := &notify.Webhook{URL: o.config.NotifyWebhook, UserAgent: o.config.UserAgent}
+		if err := webhook.Send(o.ctx, d.markdown()); err != nil {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not send the digest: %v", err)))
+		} else {
+			fmt.Fprintln(o.out, ui.FormatDim("  Digest sent to the notify webhook"))
+		}
+	}
+	fmt.Fprintln(o.out)
+}
@@ -0,0 +1,138 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"gh-sentinel/internal/config"
+	sentinelContext "gh-sentinel/internal/context"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/workflow"
+)
+
+// check is a single prerequisite verified by Doctor
+type check struct {
+	name string
+	hint string
+	run  func() error
+}
+
+// Doctor verifies every prerequisite and reports what needs fixing
+func (o *Orchestrator) Doctor() error {
+	checks := []check{
+		{
+			name: "gh CLI installed",
+			hint: "Install it from https://cli.github.com",
+			run: func() error {
+				_, err := exec.LookPath("gh")
+				return err
+			},
+		},
+		{
+			name: authCheckName(o.config.Auth),
+			hint: authHint(o.config.Auth),
+			run: func() error {
+				return github.CheckCredentials(o.ctx, o.config, o.logger)
+			},
+		},
+		{
+			name: "GitHub repository detected",
+			hint: "Run sentinel from inside a clone of a GitHub repository",
+			run: func() error {
+				_, err := sentinelContext.DetectRepository(o.ctx)
+				return err
+			},
+		},
+		{
+			name: "Local workflows directory",
+			hint: fmt.Sprintf("Fixes are written to %s; run from the repository root", workflow.Dir),
+			run: func() error {
+				_, err := os.Stat(workflow.Dir)
+				return err
+			},
+		},
+		{
+			name: fmt.Sprintf("AI provider %s available", o.config.AI.Provider),
+			hint: providerHint(o.config.AI.Provider),
+			run: func() error {
+				_, err := copilot.NewClient(o.ctx, o.config, o.logger)
+				return err
+			},
+		},
+		{
+			name: "Working directories writable",
+			hint: fmt.Sprintf("Check permissions on %s and %s", o.config.TempDir, o.config.CacheDir),
+			run: func() error {
+				for _, dir := range []string{o.config.TempDir, o.config.CacheDir} {
+					probe := filepath.Join(dir, ".doctor")
+					if err := os.WriteFile(probe, nil, 0644); err != nil {
+						return err
+					}
+					os.Remove(probe)
+				}
+				return nil
+			},
+		},
+	}
+
+	fmt.Fprintln(o.out, ui.FormatHeader("Sentinel doctor"))
+	fmt.Fprintln(o.out)
+
+	failed := 0
+	for _, c := range checks {
+		if err := c.run(); err != nil {
+			failed++
+			fmt.Fprintln(o.out, ui.FormatError(c.name))
+			fmt.Fprintf(o.out, "    %s\n", ui.FormatDim(c.hint))
+			o.logger.Debug("%s: %v", c.name, err)
+			continue
+		}
+		fmt.Fprintln(o.out, ui.FormatSuccess(c.name))
+	}
+	fmt.Fprintln(o.out)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess("All checks passed"))
+	return nil
+}
+
+// providerHint explains how to set up an AI provider
+func providerHint(provider string) string {
+	switch provider {
+	case "openai":
+		return "Set OPENAI_API_KEY (or use --rules-only)"
+	case "anthropic":
+		return "Set ANTHROPIC_API_KEY (or use --rules-only)"
+	case "ollama":
+		return "Start the server with: ollama serve (or use --rules-only)"
+	}
+	return "Run gh auth login with a Copilot subscription, or: gh extension install github/gh-copilot (or use --rules-only)"
+}
+
+// authCheckName names the check of the configured GitHub authentication
+func authCheckName(auth string) string {
+	switch auth {
+	case config.AuthToken:
+		return "GitHub token in " + github.TokenEnv
+	case config.AuthApp:
+		return "GitHub App installation token"
+	}
+	return "gh authenticated"
+}
+
+// authHint explains how to set up the configured GitHub authentication
+func authHint(auth string) string {
+	switch auth {
+	case config.AuthToken:
+		return fmt.Sprintf("Set %s to a token with access to the repository", github.TokenEnv)
+	case config.AuthApp:
+		return fmt.Sprintf("Set app_id, and the app's private key in %s or %s", github.AppKeyEnv, github.AppKeyFileEnv)
+	}
+	return "Run: gh auth login"
+}
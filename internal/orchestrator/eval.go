@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/eval"
+)
+
+// Evaluate runs the deterministic fixer and the AI over a replay corpus and
+// prints validity and accuracy rates per category and failure category.
+// With --category only the cases of those failure categories are replayed.
+// It needs no GitHub access.
+func (o *Orchestrator) Evaluate(opts Options, corpusDir string) error {
+	o.opts = opts
+	cases, err := eval.LoadCorpus(corpusDir)
+	if err != nil {
+		return err
+	}
+	if len(opts.Categories) > 0 {
+		var selected []eval.Case
+		for _, c := range cases {
+			if o.selectedTag(o.analyzer.AnalyzeLogs(c.Logs).Tag) {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) == 0 {
+			return fmt.Errorf("no case of %s has failures of category %s", corpusDir, strings.Join(opts.Categories, " or "))
+		}
+		cases = selected
+	}
+
+	if err := o.connectAI(); err != nil {
+		return err
+	}
+
+	// Scratch copies are only needed while the harness runs
+	scratchDir := filepath.Join(o.config.TempDir, "eval")
+	defer os.RemoveAll(scratchDir)
+	harness := eval.NewHarness(
+		o.analyzer,
+		o.fixer,
+		o.copilot,
+		o.patcher,
+		o.logger,
+		scratchDir,
+	)
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Evaluating %d cases from %s", len(cases), corpusDir)))
+	report := harness.Run(cases)
+	if err := o.ctx.Err(); err != nil {
+		return err
+	}
+	o.printEvalReport(report, o.copilot != nil)
+	return nil
+}
+
+// printEvalReport prints per-case outcomes and per-category rates
+func (o *Orchestrator) printEvalReport(report *eval.Report, withAI bool) {
+	fmt.Fprintln(o.out, "\n"+ui.FormatHeader("━━━━━━━━━━━━━━ EVALUATION REPORT ━━━━━━━━━━━━━━\n"))
+
+	for _, r := range report.Results {
+		line := fmt.Sprintf("%-30s %-16s %-16s rules: %s", r.Case, r.Category, r.Tag, outcomeLabel(r.Rules))
+		if withAI {
+			line += fmt.Sprintf("  ai: %s", outcomeLabel(r.AI))
+		}
+		fmt.Fprintln(o.out, line)
+	}
+
+	fmt.Fprintln(o.out)
+	o.printEvalStats("Per category (produced / valid / accurate):", report.Categories, withAI)
+	o.printEvalStats("Per failure category (produced / valid / accurate):", report.Tags, withAI)
+}
+
+// printEvalStats prints the rates of groups of cases
+func (o *Orchestrator) printEvalStats(title string, groups []eval.CategoryStats, withAI bool) {
+	fmt.Fprintln(o.out, ui.FormatHeader(title))
+	for _, c := range groups {
+		line := fmt.Sprintf("  %-16s %2d cases  rules: %s", c.Category, c.Cases, statsLabel(c.Rules, c.Cases, c.Labeled))
+		if withAI {
+			line += fmt.Sprintf("  ai: %s", statsLabel(c.AI, c.Cases, c.Labeled))
+		}
+		fmt.Fprintln(o.out, line)
+	}
+	fmt.Fprintln(o.out)
+}
+
+func outcomeLabel(o eval.Outcome) string {
+	switch {
+	case !o.Produced && o.Err != "":
+		return ui.FormatError("error")
+	case !o.Produced:
+		return ui.FormatDim("no fix")
+	case !o.Valid:
+		return ui.FormatError("invalid")
+	case o.Accurate:
+		return ui.FormatSuccess("accurate")
+	default:
+		return ui.FormatWarning("valid")
+	}
+}
+
+func statsLabel(s eval.Stats, cases, labeled int) string {
+	accuracy := "n/a"
+	if labeled > 0 {
+		accuracy = fmt.Sprintf("%d%%", s.Accurate*100/labeled)
+	}
+	return fmt.Sprintf("%d%% / %d%% / %s", s.Produced*100/cases, s.Valid*100/cases, accuracy)
+}
@@ -0,0 +1,135 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/github"
+)
+
+// explanation is the JSON output of explain
+type explanation struct {
+	Repository  string             `json:"repository"`
+	RunID       int64              `json:"run_id"`
+	Workflow    string             `json:"workflow"`
+	Category    string             `json:"category,omitempty"`
+	Tag         string             `json:"tag,omitempty"`
+	Failed      []ReportFailedStep `json:"failed_steps,omitempty"`
+	Detected    []ReportDetected   `json:"detected,omitempty"`
+	Suggestions []string           `json:"suggestions,omitempty"`
+	RootCause   string             `json:"root_cause,omitempty"`  // APPLICATION_CODE or INFRASTRUCTURE when the failure category points there
+	Implicated  []string           `json:"implicated,omitempty"`  // Tests and source files the logs point to
+	Explanation string             `json:"explanation,omitempty"` // From the AI; empty in rules-only mode
+}
+
+// Explain explains why a run failed without fixing it: the failed steps,
+// the log patterns matched with their suggestions, the tests and source
+// files the logs point to, and the root cause the AI reads in the logs.
+// The workflow file is never read and no fix is generated, for failures
+// that are fixed in the application code. Without a run ID the most recent
+// failed run is explained.
+func (o *Orchestrator) Explain(opts Options) error {
+	o.opts = opts
+	// JSON goes to stdout; everything meant for humans moves to stderr
+	if opts.Output == OutputJSON {
+		o.out = os.Stderr
+	}
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+	if err := o.connectAI(); err != nil {
+		return err
+	}
+	repo := o.github.GetRepository()
+	o.report = &Report{Repository: repo.FullName}
+
+	var run *github.WorkflowRun
+	if opts.RunID != 0 {
+		var err error
+		if run, err = o.github.GetWorkflowRun(opts.RunID); err != nil {
+			return fmt.Errorf("failed to get workflow run %d: %w", opts.RunID, err)
+		}
+	} else {
+		runs, err := o.github.GetFailedWorkflowRuns(10, opts.Runs)
+		if err != nil {
+			return fmt.Errorf("failed to get workflow runs: %w", err)
+		}
+		runs = o.withoutIgnoredWorkflows(runs)
+		if len(runs) == 0 {
+			fmt.Fprintln(o.out, ui.FormatSuccess("System Clean. No failures detected! ✨"))
+			return nil
+		}
+		run = runs[0]
+	}
+	item := o.convertToUIItems([]*github.WorkflowRun{run})[0]
+	o.report.RunID, o.report.Workflow = item.ID, item.Path
+	o.logger.SetField("run_id", item.ID)
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Explaining run #%d: %s", item.ID, ui.FormatHighlight(item.TitleText))))
+
+	jobLogs, analysis, err := o.fetchJobLogs(item.ID)
+	if err != nil {
+		return fmt.Errorf("cannot explain run %d without its logs: %w", item.ID, err)
+	}
+	logs := github.FormatJobLogs(jobLogs, o.config.MaxRawLogSize)
+	if redacted, n := o.redactor.Redact(logs); n > 0 {
+		logs = redacted
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Redacted %d secrets from the logs", n)))
+	}
+	o.printFailedSteps(jobLogs)
+
+	var keyLines []string
+	if analysis != nil {
+		o.printAnalysis(analysis)
+		keyLines = analysis.MatchedLines()
+	}
+	implicated := analyzer.Implicated(logs, maxCulprits)
+	if len(implicated) > 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo("The logs point to:"))
+		for _, name := range implicated {
+			fmt.Fprintf(o.out, "  • %s\n", ui.FormatHighlight(name))
+		}
+		fmt.Fprintln(o.out)
+	}
+
+	var rootCause, explained string
+	if analysis != nil {
+		rootCause = analyzer.RootCauseOf(analysis.Tag)
+	}
+	if o.copilot == nil {
+		fmt.Fprintln(o.out, ui.FormatDim("Rules-only mode: the AI is not asked for the root cause"))
+	} else {
+		fmt.Fprintln(o.out, ui.FormatInfo("Asking the AI for the root cause..."))
+		explained, err = o.copilot.QuickDiagnose(logs, keyLines)
+		if err != nil {
+			// The patterns and suggestions still stand on their own
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not get the root cause from the AI: %v", err)))
+		} else {
+			fmt.Fprintln(o.out, ui.FormatHeader("\nRoot Cause:"))
+			fmt.Fprintln(o.out, wrapText(explained, 80))
+			fmt.Fprintln(o.out)
+		}
+	}
+
+	if opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explanation{
+			Repository:  o.report.Repository,
+			RunID:       o.report.RunID,
+			Workflow:    o.report.Workflow,
+			Category:    o.report.Category,
+			Tag:         o.report.Tag,
+			Failed:      o.report.Failed,
+			Detected:    o.report.Detected,
+			Suggestions: o.report.Suggestions,
+			RootCause:   rootCause,
+			Implicated:  implicated,
+			Explanation: explained,
+		})
+	}
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("If the workflow itself is at fault, propose a fix with: gh sentinel fix --run-id %d", item.ID)))
+	return nil
+}
@@ -0,0 +1,995 @@
+package orchestrator
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/config"
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/fixer"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// remoteUnavailable stands in for the workflow content when it cannot be
+// fetched
+const remoteUnavailable = "[Remote file not accessible]"
+
+// Fix runs the interactive repair flow: find failed runs, diagnose the
+// selected one and apply the fix
+func (o *Orchestrator) Fix(opts Options) (err error) {
+	o.opts = opts
+	o.opts.CreateBranch = opts.CreateBranch || o.config.CreateBranch
+	format, err := o.format()
+	if err != nil {
+		return err
+	}
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+	if err := o.connectAI(); err != nil {
+		return err
+	}
+
+	repo := o.github.GetRepository()
+	o.report = &Report{Repository: repo.FullName}
+	o.session = append(o.session, o.report)
+
+	// Machine-readable reports go to stdout; everything meant for humans
+	// moves to stderr
+	if format.Stdout {
+		o.out = os.Stderr
+	}
+
+	// End with the report of everything the session did
+	started := time.Now()
+	defer func() {
+		if reportErr := o.finishSession(started, format, err); reportErr != nil && err == nil {
+			err = fmt.Errorf("failed to write report: %w", reportErr)
+		}
+	}()
+
+	// Display banner
+	ui.PrintBanner(o.out)
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Repository: %s", ui.FormatHighlight(repo.FullName))))
+	fmt.Fprintln(o.out, ui.FormatDim("Scanning for failed workflows...\n"))
+
+	// Step 1: Get workflow files list, from the last session while the
+	// selector is shown
+	workflowFiles, err := o.listWorkflowFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list workflow files: %w", err)
+	}
+	o.logger.Debug("Found workflow files: %v", workflowFiles)
+	if len(workflowFiles) == 0 {
+		fmt.Fprintln(o.out, ui.FormatDim("No workflow files in .github/workflows on the default branch; paths are taken from the runs\n"))
+	}
+
+	// A pre-selected run skips discovery and the interactive selector
+	if opts.RunID != 0 {
+		run, err := o.github.GetWorkflowRun(opts.RunID)
+		if err != nil {
+			return fmt.Errorf("failed to get workflow run %d: %w", opts.RunID, err)
+		}
+		if workflowFiles, err = o.freshWorkflowFiles(workflowFiles); err != nil {
+			return err
+		}
+		selected := o.convertToUIItems([]*github.WorkflowRun{run})[0]
+		return o.analyzeAndFix(&selected, workflowFiles)
+	}
+
+	// Step 2: Get failed workflow runs
+	runs, err := o.github.GetFailedWorkflowRuns(10, opts.Runs)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow runs: %w", err)
+	}
+
+	if kept := o.withoutIgnoredWorkflows(runs); len(kept) < len(runs) {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Ignoring %d failed runs of workflows in ignore_workflows", len(runs)-len(kept))))
+		runs = kept
+	}
+	if len(runs) == 0 {
+		o.report.Status = StatusClean
+		fmt.Fprintln(o.out, ui.FormatSuccess("System Clean. No failures detected! ✨"))
+		return nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Found %d failed workflow runs", len(runs))))
+
+	// Step 3: User selects a workflow to analyze
+	items := o.convertToUIItems(runs)
+	if opts.All || !o.interactive() {
+		if workflowFiles, err = o.freshWorkflowFiles(workflowFiles); err != nil {
+			return err
+		}
+	}
+	if opts.All {
+		return o.fixAll(items, workflowFiles)
+	}
+	if !o.interactive() {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Auto-selected most recent failed run: %s", items[0].TitleText)))
+		return o.analyzeAndFix(&items[0], workflowFiles)
+	}
+
+	for {
+		selected, action, err := ui.ShowWorkflowSelector(items)
+		if err != nil {
+			return fmt.Errorf("failed to show selector: %w", err)
+		}
+
+		// Cancelling doomed runs returns to the selector
+		switch action {
+		case ui.ActionCancel:
+			fmt.Fprintln(o.out, ui.FormatDim("Operation cancelled"))
+			return nil
+		case ui.ActionCancelRun:
+			if err := o.cancelRun(selected[0].ID, selected[0].Status); err != nil {
+				return err
+			}
+			continue
+		case ui.ActionCancelBranch:
+			if err := o.cancelBranch(selected[0].Branch); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The refresh is usually done by the time a run is picked
+		if workflowFiles, err = o.freshWorkflowFiles(workflowFiles); err != nil {
+			return err
+		}
+		if action == ui.ActionFixAll {
+			return o.fixAll(items, workflowFiles)
+		}
+
+		// Step 4: Analyze the selected runs
+		return o.analyzeSelected(selected, workflowFiles)
+	}
+}
+
+// belowMinConfidence reports whether an AI fix is less confident than
+// min_confidence, so it is only shown. Deterministic fixes always qualify.
+func (o *Orchestrator) belowMinConfidence(diagnosis *copilot.DiagnosisResult) bool {
+	if o.config.MinConfidence == "" || diagnosis.Confidence == "HEALTHY" {
+		return false
+	}
+	if d := o.report.Diagnosis; d != nil && d.Source != "ai" {
+		return false
+	}
+	return config.ConfidenceRank(diagnosis.Confidence) < config.ConfidenceRank(o.config.MinConfidence)
+}
+
+// belowAutoApply reports whether a fix is less confident than auto_apply,
+// so --yes only proposes it: applying it needs someone's confirmation
+func (o *Orchestrator) belowAutoApply(confidence string) bool {
+	return config.ConfidenceRank(confidence) < config.ConfidenceRank(o.config.AutoApply)
+}
+
+// autoApplyWarning explains why --yes held back a fix
+func (o *Orchestrator) autoApplyWarning(confidence string) string {
+	return fmt.Sprintf("Not auto-applying a fix of %s confidence: auto_apply is %s, so run without --yes to review it", confidence, o.config.AutoApply)
+}
+
+// applyAction is how a fix about to be applied goes on
+type applyAction int
+
+const (
+	applyConfirm  applyAction = iota // The user is asked to confirm it
+	applyProposed                    // There is no one to ask, so it is only proposed
+	applyHeld                        // --yes holds it back, for a reason
+	applyAuto                        // --yes applies it
+)
+
+// applyGate is what the apply policy weighs about a fix, or about the fixes
+// of a batch as a whole
+type applyGate struct {
+	confidences []string // Confidence of each fix
+	invalid     bool     // Some fix fails schema validation
+	disputed    bool     // Some fix blames another failure than the log patterns
+}
+
+// applyDecision decides whether a fix is applied on its own, confirmed by
+// the user or only proposed. Every apply path goes through it, so the
+// auto-apply policy lives in one place. A fix --yes holds back comes with
+// the report status to set and the reason to show.
+func (o *Orchestrator) applyDecision(gate applyGate) (applyAction, string, string) {
+	plural := len(gate.confidences) > 1
+	switch {
+	case o.interactive():
+		return applyConfirm, "", ""
+	case !o.opts.Yes:
+		return applyProposed, StatusProposed, ""
+	case gate.invalid && plural:
+		return applyHeld, StatusInvalid, "Not auto-applying fixes that fail schema validation"
+	case gate.invalid:
+		return applyHeld, StatusInvalid, "Not auto-applying a fix that fails schema validation"
+	case gate.disputed && plural:
+		return applyHeld, StatusProposed, "Not auto-applying fixes that blame another failure than the log patterns"
+	case gate.disputed:
+		return applyHeld, StatusProposed, "Not auto-applying a fix that blames another failure than the log patterns"
+	}
+	for _, confidence := range gate.confidences {
+		if !o.belowAutoApply(confidence) {
+			continue
+		}
+		if plural {
+			return applyHeld, StatusProposed, fmt.Sprintf("Not auto-applying the fixes: some are less confident than auto_apply (%s), so run without --yes to review them", o.config.AutoApply)
+		}
+		return applyHeld, StatusProposed, o.autoApplyWarning(confidence)
+	}
+	return applyAuto, "", ""
+}
+
+// analyzeSelected analyzes and fixes runs one after another. A failure of
+// one run is reported and the next run is still processed.
+func (o *Orchestrator) analyzeSelected(selected []ui.WorkflowItem, workflowFiles []string) error {
+	if len(selected) == 1 {
+		return o.analyzeAndFix(&selected[0], workflowFiles)
+	}
+
+	failed := 0
+	for i := range selected {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Run %d of %d", i+1, len(selected))))
+		o.report = &Report{Repository: o.report.Repository}
+		o.session = append(o.session, o.report)
+		if err := o.analyzeAndFix(&selected[i], workflowFiles); err != nil {
+			if o.ctx.Err() != nil {
+				return err
+			}
+			failed++
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Run #%d: %v", selected[i].ID, err)))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d selected runs could not be fixed", failed, len(selected))
+	}
+	return nil
+}
+
+// analyzeAndFix performs the full analysis and fix workflow
+func (o *Orchestrator) analyzeAndFix(selected *ui.WorkflowItem, workflowFiles []string) (err error) {
+	// Failures that stay broken can be parked by disabling the workflow
+	defer func() {
+		if err == nil {
+			err = o.offerDisable(selected.Path)
+		}
+	}()
+
+	var local map[string]string // Local content the run is diagnosed from instead
+	for rediagnosed := 0; ; rediagnosed++ {
+		diagnosis, err := o.analyzeRun(selected, workflowFiles, local)
+		if err != nil {
+			return err
+		}
+		if diagnosis == nil {
+			return o.share(selected.ID, "")
+		}
+		diff := o.commentDiff(diagnosis)
+		err = o.applyFix(diagnosis)
+		var rebase *localContentError
+		if stderrors.As(err, &rebase) {
+			local = rebase.files
+			fmt.Fprintln(o.out, ui.FormatInfo("Diagnosing again with the local content..."))
+			*o.report = Report{Repository: o.report.Repository}
+			continue
+		}
+		if err != errRediagnose {
+			if err != nil {
+				return err
+			}
+			return o.share(selected.ID, diff)
+		}
+		if rediagnosed == maxRediagnoses {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatWarning("The workflow file keeps changing upstream; pull the latest changes and run sentinel again"))
+			return nil
+		}
+		fmt.Fprintln(o.out, ui.FormatInfo("Refetching the workflow file and diagnosing again..."))
+		*o.report = Report{Repository: o.report.Repository}
+	}
+}
+
+// analyzeRun diagnoses a run and returns the fix to apply, checked and
+// lint-gated, or nil with the report status set when there is nothing to
+// apply. Content in pending replaces the remote workflow file, so that a
+// batch builds on fixes already proposed for the same file.
+func (o *Orchestrator) analyzeRun(selected *ui.WorkflowItem, workflowFiles []string, pending map[string]string) (*copilot.DiagnosisResult, error) {
+	fmt.Fprintln(o.out, "\n"+ui.FormatHeader("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Fprintln(o.out, ui.FormatHeader(fmt.Sprintf("🔍 Analyzing Run #%d", selected.ID)))
+	fmt.Fprintln(o.out, ui.FormatHeader("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"))
+
+	// Match a path guessed from the workflow name to the real file name
+	if resolved, ok := workflow.Resolve(selected.Path, workflowFiles); ok && resolved != selected.Path {
+		o.logger.Debug("Resolved workflow path %s to %s", selected.Path, resolved)
+		selected.Path = resolved
+	}
+	o.report.RunID = selected.ID
+	o.report.Workflow = selected.Path
+	o.report.Branch = selected.Branch
+	o.logger.SetField("run_id", selected.ID)
+
+	// Step 1: Fetch logs (if available), analyzing each job's as it
+	// arrives, and the workflow file meanwhile
+	fetching := o.fetchWorkflowContent(selected.Path, pending)
+	fmt.Fprintln(o.out, ui.FormatInfo("Fetching and analyzing job logs..."))
+	jobLogs, analysis, err := o.fetchJobLogs(selected.ID)
+
+	// If no job logs, this might be a configuration error
+	// Continue anyway and let Copilot analyze the workflow file
+	var logs string
+	if err != nil {
+		o.logger.Warn("Could not retrieve job logs: %v", err)
+		fmt.Fprintln(o.out, ui.FormatWarning("No job logs available (possible workflow configuration error)"))
+		logs = "[No job execution logs available - workflow may have configuration error]"
+		fmt.Fprintln(o.out, ui.FormatInfo("Proceeding with workflow file analysis...\n"))
+	} else {
+		logs = github.FormatJobLogs(jobLogs, o.config.MaxRawLogSize)
+		o.logger.Debug("Retrieved %d chars of logs", len(logs))
+		// Secrets are masked before the logs reach the AI or any report
+		var n int
+		if logs, n = o.redactor.Redact(logs); n > 0 {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Redacted %d secrets from the logs", n)))
+		}
+		o.printFailedSteps(jobLogs)
+	}
+
+	// Step 2: Quick pattern analysis (skip if no real logs)
+	if analysis != nil {
+		o.printAnalysis(analysis)
+	}
+
+	// An intermittent failure needs a re-run, not a workflow fix
+	if skip, err := o.checkFlaky(selected.ID); err != nil || skip {
+		return nil, err
+	}
+	if o.skipIgnored() {
+		return nil, nil
+	}
+
+	// Step 3: Get file content, fetched along with the logs
+	fileContent := o.workflowContent(selected.Path, fetching)
+
+	// A job failing inside a reusable workflow of another repository is
+	// fixed there, so that workflow is diagnosed instead
+	diagnosed := selected
+	remote, isRemote := o.remoteWorkflow(selected.Path, fileContent)
+	if isRemote {
+		var content string
+		if content, isRemote = o.remoteContent(remote); isRemote {
+			fileContent = content
+			diagnosed = &ui.WorkflowItem{ID: selected.ID, Path: remote.String()}
+		}
+	}
+
+	// The failure may be inside a local action or a reusable workflow the
+	// failed job runs, which the fix can then target
+	var related map[string]string
+	if !isRemote {
+		related = o.relatedFiles(fileContent, workflowFiles, pending)
+		workflowFiles = withRelated(workflowFiles, related)
+	}
+
+	// A failure of the application may be fixed in a manifest, a Dockerfile
+	// or a source file the logs point to
+	var sources map[string]string
+	if !isRemote && !o.config.RulesOnly {
+		sources = o.sourceFiles(logs, pending)
+	}
+
+	// Cloud auth failures are mostly fixed on the cloud side
+	if analysis != nil {
+		o.printCloudGuidance(analysis, fileContent, selected)
+	}
+
+	// Combinations of a matrix that fail alike point at the axis to fix
+	o.analyzeMatrix(selected.ID, jobLogs, analysis, fileContent)
+
+	// Many workflow failures are shell bugs inside run steps
+	o.checkScripts(selected.Path, fileContent, logs)
+
+	// A job that never ran is often an if: that is false, not a failure
+	o.explainSkipped(selected.ID, selected.Path, fileContent)
+
+	// Step 4: Deterministic recipes first, AI diagnosis as fallback
+	diagnosis, err := o.diagnose(diagnosed, analysis, logs, fileContent, workflowFiles, related, sources)
+	if err != nil {
+		return nil, err
+	}
+	if diagnosis == nil {
+		o.report.Status = StatusNoFix
+		fmt.Fprintln(o.out, ui.FormatInfo("No deterministic recipe matched this failure"))
+		if analysis != nil {
+			if cause := analyzer.RootCauseOf(analysis.Tag); cause != "" {
+				o.reportRootCause(cause, "", logs)
+			}
+		}
+		return nil, nil
+	}
+
+	// Display results
+	o.checkDisagreement(diagnosis)
+	o.displayDiagnosisResults(diagnosis, diagnosed.Path)
+
+	// A failure of the application code or of a service is not fixed by
+	// rewriting the workflow, only in one of the source files
+	if diagnosis.RootCause != "" && diagnosis.RootCause != analyzer.RootCauseWorkflow {
+		if diagnosis.FixedContent == "" {
+			o.reportRootCause(diagnosis.RootCause, diagnosis.Culprit, logs)
+			return nil, nil
+		}
+		o.report.RootCause = diagnosis.RootCause
+		o.report.Culprits = []string{diagnosis.TargetFile}
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("The root cause is in %s: the fix is for %s, not the workflow", rootCauseWhere(diagnosis.RootCause), ui.FormatHighlight(diagnosis.TargetFile))))
+	}
+
+	// Step 5: Apply fix if available
+	if diagnosis.FixedContent != "" && o.belowMinConfidence(diagnosis) {
+		o.report.Status = StatusProposed
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Not offering to apply the fix: its confidence (%s) is below min_confidence (%s)", diagnosis.Confidence, o.config.MinConfidence)))
+		return nil, nil
+	}
+	if isRemote && diagnosis.FixedContent != "" && diagnosis.Confidence != "HEALTHY" {
+		if len(diagnosis.Patches) > 0 {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Only the fix of %s is proposed; the changes to the %d further files are left out", diagnosis.TargetFile, len(diagnosis.Patches))))
+			diagnosis.Patches = nil
+		}
+		return nil, o.proposeRemote(remote, fileContent, diagnosis)
+	}
+	if diagnosis.FixedContent != "" && diagnosis.Confidence != "HEALTHY" {
+		target, err := o.reconcileTarget(diagnosis.TargetFile, workflowFiles, sources)
+		if err != nil {
+			return nil, err
+		}
+		if target == "" {
+			if o.report.Status == "" {
+				o.report.Status = StatusDeclined
+			}
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled"))
+			return nil, nil
+		}
+		diagnosis.TargetFile = target
+		o.report.Diagnosis.Target = target
+
+		// The fix only touches what it changed in the analyzed content
+		if target == selected.Path && fileContent != remoteUnavailable {
+			diagnosis.BaseContent = fileContent
+		} else if content, ok := related[target]; ok {
+			diagnosis.BaseContent = content
+		} else if content, ok := sources[target]; ok {
+			diagnosis.BaseContent = content
+		}
+		if len(diagnosis.Patches) > 0 {
+			analyzed := make(map[string]string)
+			for file, content := range related {
+				analyzed[file] = content
+			}
+			for file, content := range sources {
+				analyzed[file] = content
+			}
+			if fileContent != remoteUnavailable {
+				analyzed[selected.Path] = fileContent
+			}
+			o.reconcilePatches(diagnosis, workflowFiles, analyzed)
+		}
+
+		fixed, err := o.lintGate(diagnosis)
+		// A corrected fix replaces the cached one, so the corrections are
+		// not billed again either
+		if fixed != nil && fixed.Attempt > 1 && !o.report.Diagnosis.Cached {
+			o.cacheDiagnosis(o.diagnosisKey(selected.ID, logs, fileContent, related, sources), selected.ID, fixed)
+		}
+		return fixed, err
+	}
+
+	o.report.Status = StatusNoFix
+	fmt.Fprintln(o.out, ui.FormatInfo("No actionable fix required"))
+	return nil, nil
+}
+
+// printAnalysis prints the log patterns matched in a run and their top
+// suggestions, and records them in the report
+func (o *Orchestrator) printAnalysis(analysis *analyzer.Analysis) {
+	o.report.Category = analysis.Category
+	o.report.Tag = analysis.Tag
+	for _, detected := range analysis.Errors {
+		o.report.Detected = append(o.report.Detected, ReportDetected{
+			Pattern:  detected.Pattern,
+			Category: detected.Category,
+			Tag:      detected.Tag,
+			Severity: detected.Severity,
+			Message:  detected.Message,
+			Step:     detected.Step,
+		})
+	}
+
+	if len(analysis.Errors) > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("\nDetected %d potential issues:", len(analysis.Errors))))
+		for i, err := range analysis.Errors {
+			if i >= 3 {
+				break // Show top 3
+			}
+			fmt.Fprintf(o.out, "  %d. %s %s: %s\n", i+1, ui.Mark(ui.LevelSeverity(err.Severity)), ui.FormatHighlight(err.Pattern), err.Message[:min(80, len(err.Message))])
+			if err.Step != "" {
+				fmt.Fprintf(o.out, "     %s\n", ui.FormatDim("in "+err.Step))
+			}
+		}
+	}
+
+	suggestions := o.analyzer.GetTopSuggestions(analysis, 3)
+	o.report.Suggestions = suggestions
+	if len(suggestions) > 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo("\n💡 Quick Suggestions:"))
+		for i, suggestion := range suggestions {
+			fmt.Fprintf(o.out, "  %d. %s\n", i+1, suggestion)
+		}
+	}
+	fmt.Fprintln(o.out)
+}
+
+// printFailedSteps records and prints the step each job failed at
+func (o *Orchestrator) printFailedSteps(jobs []*github.JobLogs) {
+	o.report.Failed = nil
+	for _, job := range jobs {
+		step := job.FailedStep()
+		if step == nil {
+			continue
+		}
+		failed := ReportFailedStep{Job: job.Job.Name, Step: step.Name, Number: step.Number}
+		if d := step.Duration(); d > 0 {
+			failed.Duration = d.Round(time.Second).String()
+		}
+		o.report.Failed = append(o.report.Failed, failed)
+		fmt.Fprintln(o.out, ui.Format(ui.SeverityError, fmt.Sprintf("%s failed at %s", ui.FormatHighlight(job.Job.Name), step.Header())))
+	}
+}
+
+// failedSteps returns the recorded failed steps for the AI prompt
+func (o *Orchestrator) failedSteps() []string {
+	var steps []string
+	for _, f := range o.report.Failed {
+		steps = append(steps, fmt.Sprintf("%s › %s", f.Job, f.Step))
+	}
+	return steps
+}
+
+// printCloudGuidance prints cloud-side steps for OIDC and credential failures
+func (o *Orchestrator) printCloudGuidance(analysis *analyzer.Analysis, fileContent string, selected *ui.WorkflowItem) {
+	ctx := analyzer.CloudContext{
+		Repository: o.report.Repository,
+		Branch:     selected.Branch,
+	}
+	if inv, err := workflow.Inspect(selected.Path, fileContent); err == nil {
+		ctx.Environments = inv.Environments
+	}
+
+	steps := analyzer.CloudGuidance(analysis, fileContent, ctx)
+	if len(steps) == 0 {
+		return
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo("☁️  Cloud authentication guidance:"))
+	for i, step := range steps {
+		fmt.Fprintf(o.out, "  %d. %s\n", i+1, step)
+	}
+	fmt.Fprintln(o.out)
+
+	// The id-token recipe only edits existing permissions blocks
+	for _, e := range analysis.Errors {
+		if e.Pattern != "Missing OIDC Token Permission" {
+			continue
+		}
+		if jobs := fixer.MissingIDToken(fileContent); len(jobs) > 0 {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Jobs %s have no permissions block, so `id-token: write` is not granted automatically: a block drops every scope it does not list, so add one with `id-token: write` and the other scopes they need", strings.Join(jobs, ", "))))
+			fmt.Fprintln(o.out)
+		}
+		break
+	}
+}
+
+// diagnose produces a diagnosis from the deterministic recipes when one
+// applies, falling back to the AI. It returns nil in rules-only mode when no
+// recipe matches.
+func (o *Orchestrator) diagnose(selected *ui.WorkflowItem, analysis *analyzer.Analysis, logs, fileContent string, workflowFiles []string, related, sources map[string]string) (*copilot.DiagnosisResult, error) {
+	if fix := o.fixer.Fix(analysis, logs, fileContent); fix != nil {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Matched deterministic recipe: %s", strings.Join(fix.Recipes, ", "))))
+		diagnosis := &copilot.DiagnosisResult{
+			Explanation:  fix.Explanation,
+			FixedContent: fix.FixedContent,
+			TargetFile:   selected.Path,
+			Confidence:   fix.Confidence,
+		}
+		o.recordDiagnosis(diagnosis, "rules", fix.Recipes)
+		return diagnosis, nil
+	}
+
+	if o.config.RulesOnly {
+		return nil, nil
+	}
+
+	// The same run with the same logs and file gets the same diagnosis, so
+	// it is not billed again
+	key := o.diagnosisKey(selected.ID, logs, fileContent, related, sources)
+	if cached := o.cachedDiagnosisFor(key); cached != nil {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Using the cached diagnosis from %s (--no-cache asks the AI again)", ago(cached.CreatedAt))))
+		diagnosis := &cached.Diagnosis
+		o.recordDiagnosis(diagnosis, "ai", nil)
+		o.report.Diagnosis.Cached = true
+		return diagnosis, nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo("Consulting AI for diagnosis..."))
+	diagnosisReq := &copilot.DiagnosisRequest{
+		ErrorLogs:      logs,
+		CurrentFile:    selected.Path,
+		FileContent:    fileContent,
+		AvailableFiles: workflowFiles,
+		WorkflowPath:   selected.Path,
+		RunnerOS:       analyzer.DetectRunnerOS(logs),
+		ScriptFindings: o.scriptFindings(),
+		FailedSteps:    o.failedSteps(),
+		Matrix:         o.report.Matrix,
+		RelatedFiles:   related,
+		Implicated:     analyzer.Implicated(logs, maxCulprits),
+		SourceFiles:    sources,
+	}
+	if analysis != nil {
+		diagnosisReq.KeyLines = analysis.MatchedLines()
+	}
+
+	diagnosis, err := o.copilot.DiagnoseAndFix(diagnosisReq)
+	if err != nil {
+		return nil, fmt.Errorf("AI diagnosis failed: %w", err)
+	}
+	o.recordDiagnosis(diagnosis, "ai", nil)
+	o.cacheDiagnosis(key, selected.ID, diagnosis)
+	return diagnosis, nil
+}
+
+// reconcileTarget checks that the fix target exists. When it doesn't, the
+// user confirms the closest existing workflow or explicitly chooses to create
+// a new file. A file other than a workflow is only fixed when it is one of
+// the source files the AI was given. It returns "" if the user cancels.
+func (o *Orchestrator) reconcileTarget(target string, workflowFiles []string, sources map[string]string) (string, error) {
+	if !inCheckout(target) {
+		o.report.Status = StatusTargetNotFound
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("%s is outside the repository; only files of the repository are fixed", target)))
+		return "", nil
+	}
+	if !workflow.IsWorkflowFile(target) {
+		if _, ok := sources[target]; ok {
+			return target, nil
+		}
+		o.report.Status = StatusTargetNotFound
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("%s is not among the files the logs point to; only those are fixed besides workflows", target)))
+		return "", nil
+	}
+	if resolved, ok := workflow.Resolve(target, workflowFiles); ok {
+		return resolved, nil
+	}
+	// Without workflow files on the default branch, the path of the run's
+	// workflow is the only one known to exist
+	if len(workflowFiles) == 0 && target == o.report.Workflow {
+		return target, nil
+	}
+
+	closest, distance := workflow.Closest(target, workflowFiles)
+	o.logger.Debug("Fix target %s not found; closest is %s (distance %d)", target, closest, distance)
+
+	// Without a user to ask, only take near-certain matches
+	if !o.interactive() {
+		if closest != "" && distance <= maxAutoTargetDistance {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s does not exist; using %s", target, closest)))
+			return closest, nil
+		}
+		o.report.Status = StatusTargetNotFound
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("%s does not exist and no similar workflow was found", target)))
+		return "", nil
+	}
+
+	var options []string
+	if closest != "" {
+		options = append(options, fmt.Sprintf("Patch %s instead", closest))
+	}
+	options = append(options, fmt.Sprintf("Create new file %s", target))
+
+	choice, err := ui.ShowChoice(
+		fmt.Sprintf("%s does not exist in the repository", target),
+		"The AI may have misnamed the workflow file",
+		options,
+	)
+	if err != nil {
+		return "", fmt.Errorf("target selection dialog failed: %w", err)
+	}
+
+	switch {
+	case choice < 0:
+		return "", nil
+	case closest != "" && choice == 0:
+		return closest, nil
+	default:
+		return target, nil
+	}
+}
+
+// displayDiagnosisResults shows the diagnosis results
+func (o *Orchestrator) displayDiagnosisResults(diagnosis *copilot.DiagnosisResult, originalPath string) {
+	fmt.Fprintln(o.out, "\n"+ui.FormatHeader("━━━━━━━━━━━━━━ DIAGNOSIS REPORT ━━━━━━━━━━━━━━\n"))
+
+	if diagnosis.Attempt > 1 {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Attempt %d of %d", diagnosis.Attempt, 1+o.config.FixRetries)))
+		fmt.Fprintln(o.out)
+	}
+
+	// Target redirection?
+	if diagnosis.TargetFile != originalPath {
+		fmt.Fprintln(o.out, ui.FormatWarning("🎯 Target Redirection Detected"))
+		fmt.Fprintf(o.out, "   User Selected: %s\n", ui.FormatDim(originalPath))
+		fmt.Fprintf(o.out, "   AI Identified: %s\n", ui.FormatHighlight(diagnosis.TargetFile))
+		fmt.Fprintln(o.out)
+	}
+	if len(diagnosis.Patches) > 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("The fix spans %d files:", 1+len(diagnosis.Patches))))
+		for _, patch := range diagnosis.Files() {
+			fmt.Fprintf(o.out, "   • %s\n", ui.FormatHighlight(patch.TargetFile))
+		}
+		fmt.Fprintln(o.out)
+	}
+
+	// Confidence
+	fmt.Fprintf(o.out, "Confidence: %s\n\n", ui.Format(ui.ConfidenceSeverity(diagnosis.Confidence), diagnosis.Confidence))
+
+	// The AI may blame something the log evidence does not point to
+	if d := o.report.Diagnosis; d != nil && d.Disagreement != nil {
+		disagreement := d.Disagreement.String()
+		fmt.Fprintln(o.out, ui.FormatWarning("⚖️  "+strings.ToUpper(disagreement[:1])+disagreement[1:]))
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("   Confidence lowered from %s; --yes will not apply this fix", d.Disagreement.AIConfidence)))
+		fmt.Fprintln(o.out)
+	}
+
+	// Explanation
+	fmt.Fprintln(o.out, ui.FormatHeader("Root Cause:"))
+	fmt.Fprintln(o.out, wrapText(diagnosis.Explanation, 80))
+	fmt.Fprintln(o.out)
+}
+
+// printDiff prints a diff in color, truncated to maxLines when positive
+func (o *Orchestrator) printDiff(diff string, maxLines int) {
+	lines := strings.Split(diff, "\n")
+	previewLines := lines
+	if maxLines > 0 && len(lines) > maxLines {
+		previewLines = lines[:maxLines]
+	}
+	for _, line := range previewLines {
+		fmt.Fprintln(o.out, ui.FormatDiffLine(line))
+	}
+	if len(previewLines) < len(lines) {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("... (%d more lines)", len(lines)-maxLines)))
+	}
+	fmt.Fprintln(o.out)
+}
+
+// patchRequest builds the request applying a diagnosis to its target
+func patchRequest(diagnosis *copilot.DiagnosisResult) *patcher.PatchRequest {
+	return &patcher.PatchRequest{
+		FilePath:    diagnosis.TargetFile,
+		NewContent:  diagnosis.FixedContent,
+		BaseContent: diagnosis.BaseContent,
+		Validate:    true,
+	}
+}
+
+// confirmPatch asks whether to apply a patch. A patch of several hunks is
+// reviewed hunk by hunk, next to what each one addresses, and only the
+// accepted hunks are selected.
+func (o *Orchestrator) confirmPatch(req *patcher.PatchRequest, details string, changes []ReportChange) (bool, error) {
+	hunks, err := o.patcher.Hunks(req)
+	if err != nil || len(hunks) < 2 {
+		return ui.ShowConfirmation(fmt.Sprintf("Apply patch to %s?", req.FilePath), details)
+	}
+
+	review := make([]ui.DiffHunk, len(hunks))
+	for i, hunk := range hunks {
+		review[i].Header = hunk.Header()
+		if len(changes) == len(hunks) {
+			review[i].Notes = changeNotes(changes[i])
+		}
+		for _, line := range hunk.Lines {
+			review[i].Lines = append(review[i].Lines, line.String())
+		}
+	}
+	accepted, err := ui.ShowHunkSelector(fmt.Sprintf("Apply patch to %s? (%d hunks)", req.FilePath, len(hunks)), details, review)
+	if err != nil {
+		return false, err
+	}
+	for i, ok := range accepted {
+		if ok {
+			req.Selected = append(req.Selected, i)
+		}
+	}
+	return len(req.Selected) > 0, nil
+}
+
+// applyFix applies the suggested fix
+func (o *Orchestrator) applyFix(diagnosis *copilot.DiagnosisResult) error {
+	// With --repo there is no local file to patch
+	if o.config.Repository != "" {
+		return o.applyWithoutCheckout(diagnosis)
+	}
+
+	// The fix was made from the remote files, which the checkout may not match
+	if ok, err := o.checkLocalContent(diagnosis); err != nil || !ok {
+		if err == nil {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+		}
+		return err
+	}
+	if len(diagnosis.Patches) > 0 {
+		return o.applyFixes(diagnosis)
+	}
+
+	fmt.Fprintln(o.out, ui.FormatHeader("━━━━━━━━━━━━━━ PROPOSED FIX ━━━━━━━━━━━━━━\n"))
+
+	// Show diff preview
+	req := patchRequest(diagnosis)
+	diff, err := o.patcher.PreviewDiff(req)
+	if err != nil {
+		o.logger.Warn("Could not generate diff preview: %v", err)
+	} else {
+		// Show first 15 lines of diff
+		o.printDiff(diff, 15)
+	}
+	changes := o.annotateFix(req, []*Report{o.report})
+	if o.report.Diagnosis != nil {
+		o.report.Diagnosis.Changes = changes
+	}
+	o.printChanges(changes)
+
+	// Structural problems are shown so a broken fix can be rejected
+	issues := patcher.ValidateSchema(diagnosis.TargetFile, diagnosis.FixedContent)
+	if len(issues) > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The fixed file has %d schema issues:", len(issues))))
+		for _, issue := range issues {
+			fmt.Fprintf(o.out, "  • %s\n", issue)
+			o.report.Diagnosis.SchemaIssues = append(o.report.Diagnosis.SchemaIssues, issue.String())
+		}
+		fmt.Fprintln(o.out)
+	}
+
+	// Confirm with user; --yes applies directly, other non-interactive
+	// sessions only propose the fix
+	critical := o.critical(diagnosis.TargetFile)
+	action, status, reason := o.applyDecision(applyGate{
+		confidences: []string{diagnosis.Confidence},
+		invalid:     len(issues) > 0,
+		disputed:    disputed(o.report),
+	})
+	switch {
+	case o.opts.DryRun:
+		base, created, err := localBase(diagnosis)
+		if err != nil {
+			return err
+		}
+		return o.saveProposal(o.report, diagnosis, base, created, issues)
+	case action == applyHeld:
+		o.report.Status = status
+		fmt.Fprintln(o.out, ui.FormatWarning(reason))
+		return nil
+	case action == applyAuto && critical:
+		fmt.Fprintln(o.out, ui.FormatInfo("Opening the fix for review (--yes)"))
+	case action == applyAuto:
+		fmt.Fprintln(o.out, ui.FormatInfo("Auto-applying fix (--yes)"))
+	case action == applyProposed:
+		o.report.Status = status
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Proposed content for %s (not applied, pass --yes to apply):", diagnosis.TargetFile)))
+		fmt.Fprintln(o.out, diagnosis.FixedContent)
+		return nil
+	default:
+		details := "A backup will be created automatically"
+		if critical {
+			details = "This workflow is critical: the fix is committed to its own branch and opened as a pull request for a second reviewer"
+		}
+		if len(issues) > 0 {
+			details = fmt.Sprintf("Warning: %d schema issues found. %s", len(issues), details)
+		}
+		confirmed, err := o.confirmPatch(req, details, changes)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+
+		if !confirmed {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+			return nil
+		}
+	}
+
+	// The file may have changed upstream while the fix was reviewed
+	upstream, err := o.checkUpstream(diagnosis.TargetFile)
+	if err != nil {
+		return err
+	}
+	switch upstream {
+	case upstreamRediagnose:
+		return errRediagnose
+	case upstreamCancel:
+		o.report.Status = StatusDeclined
+		fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+		return nil
+	}
+
+	// The working tree may not be ready for the fix
+	if ok, err := o.guardPatch(diagnosis.Files(), o.report.Branch); err != nil || !ok {
+		if err == nil {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+		}
+		return err
+	}
+
+	// Critical workflows only change through a reviewed pull request
+	if critical {
+		return o.requestReview(req, diagnosis)
+	}
+
+	// Apply patch
+	fmt.Fprintln(o.out, ui.FormatInfo("Applying patch..."))
+	var result *patcher.PatchResult
+	ok, err := o.applyMerging([]*patcher.PatchRequest{req}, func() (err error) {
+		result, err = o.patcher.Apply(req)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	if !ok {
+		o.report.Status = StatusDeclined
+		fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+		return nil
+	}
+	o.report.Status = StatusApplied
+	metrics.FixesApplied.Inc()
+	o.report.Patch = &ReportPatch{
+		BackupPath:   result.BackupPath,
+		LinesAdded:   result.LinesAdded,
+		LinesRemoved: result.LinesRemoved,
+		HunksSkipped: result.HunksSkipped,
+		Merged:       result.Merged,
+		Conflicts:    len(req.Resolutions),
+	}
+
+	// Success!
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s patched successfully!", diagnosis.TargetFile)))
+	if result.BackupPath != "" {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Backup: %s", result.BackupPath)))
+	}
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Changes: +%d -%d lines", result.LinesAdded, result.LinesRemoved)))
+	if result.HunksSkipped > 0 {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Skipped: %d of %d hunks", result.HunksSkipped, result.HunksSkipped+len(req.Selected))))
+	}
+	o.printMerged(req, result)
+	fmt.Fprintln(o.out)
+	o.verifyPatched(diagnosis.TargetFile)
+	o.report.Patch.Hooks = o.runHooks(diagnosis.TargetFile)
+	fmt.Fprintln(o.out)
+
+	if o.opts.CreateBranch {
+		if err := o.publishFix(diagnosis); err != nil {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not publish the fix: %v", err)))
+			fmt.Fprintln(o.out, ui.FormatDim("  The patch is applied locally; commit and push it manually"))
+		}
+		fmt.Fprintln(o.out)
+	}
+
+	// Next steps are listed in the session summary
+	o.offerRerun(o.report.RunID)
+	return nil
+}
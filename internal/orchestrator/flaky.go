@@ -0,0 +1,128 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/github"
+)
+
+// checkFlaky compares the failed steps of a run with the recent runs of its
+// workflow on the same branch. It reports whether the run should not be
+// diagnosed because its steps fail intermittently, which a re-run or a
+// quarantine addresses better than a workflow fix.
+func (o *Orchestrator) checkFlaky(runID int64) (bool, error) {
+	if o.config.FlakyHistory == 0 || len(o.report.Failed) == 0 {
+		return false, nil
+	}
+	run, err := o.github.GetWorkflowRun(runID)
+	if err != nil {
+		o.logger.Debug("Cannot check run #%d for flakiness: %v", runID, err)
+		return false, nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Comparing with the last %d runs of the workflow on %s...", o.config.FlakyHistory, run.HeadBranch)))
+	history, err := o.stepHistory(run)
+	if err != nil {
+		o.logger.Debug("Cannot check run #%d for flakiness: %v", runID, err)
+		return false, nil
+	}
+
+	current := analyzer.RunSteps{RunID: run.ID, HeadSHA: run.HeadSHA, CreatedAt: run.CreatedAt}
+	for _, failed := range o.report.Failed {
+		current.Failed = append(current.Failed, analyzer.StepName(failed.Job, failed.Step))
+	}
+	steps := analyzer.DetectFlaky(current, history)
+	if len(steps) == 0 {
+		fmt.Fprintln(o.out, ui.FormatDim("The failure is not intermittent in recent runs\n"))
+		return false, nil
+	}
+
+	o.report.Flaky = &ReportFlaky{Runs: len(history) + 1}
+	o.report.Tag = analyzer.TagFlaky
+	for _, step := range steps {
+		o.report.Flaky.Steps = append(o.report.Flaky.Steps, ReportFlakyStep{Step: step.Step, Failures: step.Failures, Passes: step.Passes})
+	}
+	fmt.Fprintln(o.out, ui.FormatWarning("Likely flaky: "+flakySummary(o.report.Flaky)))
+	if o.skipIgnored() {
+		return true, nil
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo("\n💡 Flaky failures need a re-run, not a workflow fix:"))
+	fmt.Fprintf(o.out, "  1. Re-run the failed jobs: gh run rerun %d --failed\n", runID)
+	fmt.Fprintln(o.out, "  2. If it keeps flaking, quarantine the step: retry it (e.g. nick-fields/retry) or set continue-on-error: true until the test is fixed")
+	fmt.Fprintln(o.out)
+
+	if !o.interactive() {
+		o.report.Status = StatusFlaky
+		return true, nil
+	}
+	choice, err := ui.ShowChoice(
+		fmt.Sprintf("Run #%d likely failed because of a flaky step", runID),
+		"Diagnosing sends it to the AI for a workflow fix, which rarely helps an intermittent failure",
+		[]string{"Re-run the failed jobs", "Diagnose anyway", "Skip"},
+	)
+	if err != nil {
+		return true, fmt.Errorf("flaky failure dialog failed: %w", err)
+	}
+	switch choice {
+	case 0:
+		o.report.Status = StatusFlaky
+		if err := o.github.RerunFailedJobs(runID); err != nil {
+			return true, err
+		}
+		o.report.Rerun = &ReportRerun{RunID: runID, Status: "requested"}
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Re-running the failed jobs of run #%d; follow it with: gh run watch %d", runID, runID)))
+		return true, nil
+	case 1:
+		return false, nil
+	}
+	o.report.Status = StatusFlaky
+	return true, nil
+}
+
+// stepHistory returns the failed and passed steps of the recent completed
+// runs of a run's workflow, other than the run itself
+func (o *Orchestrator) stepHistory(run *github.WorkflowRun) ([]analyzer.RunSteps, error) {
+	runs, err := o.github.ListWorkflowHistory(run.WorkflowID, run.HeadBranch, o.config.FlakyHistory+1)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []analyzer.RunSteps
+	for _, r := range runs {
+		if len(history) == o.config.FlakyHistory {
+			break
+		}
+		if r.ID == run.ID {
+			continue
+		}
+		jobs, err := o.github.ListRunJobs(r.ID)
+		if err != nil {
+			return nil, err
+		}
+		steps := analyzer.RunSteps{RunID: r.ID, HeadSHA: r.HeadSHA, CreatedAt: r.CreatedAt}
+		for _, job := range jobs {
+			for _, step := range job.Steps {
+				switch {
+				case failedConclusions[step.Conclusion]:
+					steps.Failed = append(steps.Failed, analyzer.StepName(job.Name, step.Name))
+				case step.Conclusion == "success":
+					steps.Passed = append(steps.Passed, analyzer.StepName(job.Name, step.Name))
+				}
+			}
+		}
+		history = append(history, steps)
+	}
+	return history, nil
+}
+
+// flakySummary describes the steps that fail intermittently
+func flakySummary(flaky *ReportFlaky) string {
+	var parts []string
+	for _, step := range flaky.Steps {
+		parts = append(parts, fmt.Sprintf("%s failed in %d of the last %d runs", step.Step, step.Failures, flaky.Runs))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+)
+
+// guardPatch runs the git safety checks on the files of a fix right before
+// they are patched: branch, the branch the run failed on, should be checked
+// out, and files with uncommitted changes may be stashed first. A stashed
+// file that differs from the content the fix was made from is pointed out.
+// Without a user to ask, the problems are only warned about, as the backups
+// keep what is overwritten. It reports whether to go on; outside a git
+// repository there is nothing to check.
+func (o *Orchestrator) guardPatch(files []copilot.FilePatch, branch string) (bool, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	guard, err := patcher.NewGitGuard(o.ctx, cwd, o.logger)
+	if err != nil {
+		o.logger.Debug("Skipping the git checks: %v", err)
+		return true, nil
+	}
+
+	states := o.gitStates(guard, files, branch)
+	if len(states) == 0 {
+		return true, nil
+	}
+
+	if state := states[0]; state.WrongBranch() {
+		warning := fmt.Sprintf("The run failed on %s, but %s is checked out", state.Expected, state.Branch)
+		fmt.Fprintln(o.out, ui.FormatWarning(warning))
+		if o.interactive() {
+			confirmed, err := ui.ShowConfirmation(
+				fmt.Sprintf("Patch the files on %s anyway?", state.Branch),
+				fmt.Sprintf("%s; check out %s first to fix the branch that failed", warning, state.Expected),
+			)
+			if err != nil {
+				return false, fmt.Errorf("confirmation dialog failed: %w", err)
+			}
+			if !confirmed {
+				return false, nil
+			}
+		}
+	}
+
+	var modified []string
+	for _, state := range states {
+		if state.Modified {
+			modified = append(modified, state.Path)
+		}
+	}
+	if len(modified) > 0 {
+		stashed, ok, err := o.offerStash(guard, modified)
+		if err != nil || !ok {
+			return false, err
+		}
+		// The stashed files are back to their committed content, which
+		// checkLocalContent has not compared with the analyzed version
+		if stashed {
+			for _, state := range o.gitStates(guard, files, branch) {
+				if state.Diverged {
+					fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The committed %s differs from the version the fix was made from; only the fix's changes are applied onto it", state.Path)))
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+// gitStates checks the git state of each file of a fix, leaving out the
+// files that cannot be checked
+func (o *Orchestrator) gitStates(guard *patcher.GitGuard, files []copilot.FilePatch, branch string) []*patcher.GitState {
+	var states []*patcher.GitState
+	for _, file := range files {
+		state, err := guard.Check(file.TargetFile, branch, file.BaseContent)
+		if err != nil {
+			o.logger.Warn("Could not check the git state of %s: %v", file.TargetFile, err)
+			continue
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// offerStash asks whether to stash the uncommitted changes of the files
+// about to be patched, patch over them or cancel. It reports whether the
+// changes were stashed, and whether to go on.
+func (o *Orchestrator) offerStash(guard *patcher.GitGuard, modified []string) (bool, bool, error) {
+	files := strings.Join(modified, ", ")
+	warning := fmt.Sprintf("Uncommitted changes in %s", files)
+	fmt.Fprintln(o.out, ui.FormatWarning(warning))
+	if !o.interactive() {
+		fmt.Fprintln(o.out, ui.FormatDim("  Patching over them; the backups keep them"))
+		return false, true, nil
+	}
+
+	choice, err := ui.ShowChoice(
+		warning+": stash them first?",
+		"Stashing puts them aside until git stash pop; patching over them keeps them in the backups",
+		[]string{"Stash them, then patch", "Patch over them", "Cancel"},
+	)
+	if err != nil {
+		return false, false, fmt.Errorf("uncommitted changes dialog failed: %w", err)
+	}
+	switch choice {
+	case 0:
+		if err := guard.Stash("gh-sentinel: before patching "+files, modified...); err != nil {
+			return false, false, fmt.Errorf("could not stash the changes of %s: %w", files, err)
+		}
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Stashed the changes of %s; bring them back with git stash pop", files)))
+		return true, true, nil
+	case 1:
+		return false, true, nil
+	}
+	return false, false, nil
+}
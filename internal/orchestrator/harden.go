@@ -0,0 +1,287 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/expr"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// What harden found for a uses: of another repository
+const (
+	pinPinned     = "pinned"     // A tag or branch, pinned to its commit
+	pinVerified   = "verified"   // Pinned to the commit the tag in its comment points at
+	pinMoved      = "moved"      // Pinned to another commit than the tag in its comment points at now
+	pinUnresolved = "unresolved" // The tag or branch does not exist
+	pinAmbiguous  = "ambiguous"  // The name resolves to different commits, e.g. a tag and a branch
+)
+
+// hardenFinding is a uses: harden pinned or checked
+type hardenFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Uses     string `json:"uses"`
+	Ref      string `json:"ref"` // Tag or branch, from the uses: or the comment of a pinned one
+	SHA      string `json:"sha,omitempty"`
+	Status   string `json:"status"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Harden pins the actions and reusable workflows of other repositories that
+// local workflow files and actions use by tag or branch to the commit the
+// ref points at, which a new push to the ref cannot change. Every commit is
+// resolved through the API, and checked against the one the commits API
+// gives for the ref. References already pinned with the tag in a comment
+// are verified. The changes are shown as a diff and applied with --yes or
+// after confirmation. Without paths every workflow and every action in
+// .github/actions is hardened. It fails when a reference cannot be pinned.
+func (o *Orchestrator) Harden(opts Options, paths []string) error {
+	o.opts = opts
+	// JSON goes to stdout; everything meant for humans moves to stderr
+	if opts.Output == OutputJSON {
+		o.out = os.Stderr
+	}
+	if len(paths) == 0 {
+		var err error
+		if paths, err = localPaths(); err != nil {
+			return err
+		}
+	}
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+
+	contents := make(map[string]string)
+	pinned := make(map[string]string)
+	var findings []hardenFinding
+	resolved := make(map[string]hardenFinding)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		path = filepath.ToSlash(path)
+		contents[path] = string(data)
+
+		var pins []workflow.Pin
+		for _, ref := range workflow.ActionRefs(string(data)) {
+			finding, ok := o.hardenRef(ref, resolved)
+			if !ok {
+				continue
+			}
+			finding.File = path
+			findings = append(findings, finding)
+			if finding.Status == pinPinned {
+				pins = append(pins, workflow.Pin{ActionRef: ref, SHA: finding.SHA})
+			}
+		}
+		if len(pins) > 0 {
+			pinned[path] = workflow.PinRefs(string(data), pins)
+		}
+	}
+
+	errorCount := 0
+	for _, f := range findings {
+		if f.Severity == expr.SeverityError {
+			errorCount++
+		}
+	}
+	if opts.Output == OutputJSON {
+		if findings == nil {
+			findings = []hardenFinding{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		o.printHarden(paths, findings)
+	}
+
+	if err := o.applyPins(contents, pinned); err != nil {
+		return err
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("harden could not pin %d references", errorCount)
+	}
+	return nil
+}
+
+// localPaths lists the local workflow files and the action.yml of the
+// actions in .github/actions
+func localPaths() ([]string, error) {
+	var paths []string
+	files, err := filepath.Glob(filepath.Join(workflow.Dir, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow files: %w", err)
+	}
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext == ".yml" || ext == ".yaml" {
+			paths = append(paths, file)
+		}
+	}
+	err = filepath.WalkDir(workflow.ActionsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && workflow.IsAction(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil && !stderrors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list local actions: %w", err)
+	}
+	return paths, nil
+}
+
+// hardenRef pins a reference by tag or branch, or verifies a pinned one
+// against the tag in its comment. A pinned reference without a comment is
+// skipped. resolved caches the commits of the refs already resolved.
+func (o *Orchestrator) hardenRef(ref workflow.ActionRef, resolved map[string]hardenFinding) (hardenFinding, bool) {
+	name := ref.Ref
+	if ref.Pinned() {
+		name, _, _ = strings.Cut(ref.Comment, " ")
+		if name == "" {
+			return hardenFinding{}, false
+		}
+	}
+
+	key := ref.Repository + "@" + name
+	finding, ok := resolved[key]
+	if !ok {
+		finding = hardenFinding{Ref: name}
+		sha, err := o.github.ResolveActionRef(ref.Repository, name)
+		switch {
+		case stderrors.Is(err, github.ErrRefMismatch):
+			finding.Status, finding.Severity, finding.Message = pinAmbiguous, expr.SeverityError, err.Error()
+		case stderrors.Is(err, github.ErrRefNotFound) && ref.Pinned():
+			// The comment is not a tag, so there is nothing to verify
+		case err != nil:
+			finding.Status, finding.Severity, finding.Message = pinUnresolved, expr.SeverityError, err.Error()
+		default:
+			finding.SHA = sha
+		}
+		resolved[key] = finding
+	}
+	finding.Line, finding.Uses = ref.Line, ref.Uses
+
+	switch {
+	case finding.Status == "" && finding.SHA == "":
+		return hardenFinding{}, false
+	case finding.Status != "":
+	case !ref.Pinned():
+		finding.Status, finding.Severity = pinPinned, "info"
+		finding.Message = fmt.Sprintf("%s → %s", name, finding.SHA)
+	case finding.SHA == ref.Ref:
+		finding.Status, finding.Severity = pinVerified, "info"
+		finding.Message = fmt.Sprintf("pinned to %s", name)
+	default:
+		finding.Status, finding.Severity = pinMoved, expr.SeverityWarning
+		finding.Message = fmt.Sprintf("pinned to %s, but %s now points at %s", shortSHA(ref.Ref), name, shortSHA(finding.SHA))
+	}
+	return finding, true
+}
+
+// printHarden prints the findings grouped by file, with a diff of each
+// file that has references to pin
+func (o *Orchestrator) printHarden(paths []string, findings []hardenFinding) {
+	if len(paths) == 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo("No workflow files found"))
+		return
+	}
+
+	file := ""
+	counts := make(map[string]int)
+	for _, f := range findings {
+		if f.File != file {
+			if file != "" {
+				fmt.Fprintln(o.out)
+			}
+			file = f.File
+			fmt.Fprintln(o.out, ui.FormatHeader(file))
+		}
+		counts[f.Status]++
+		label := fmt.Sprintf("line %d: %s: %s", f.Line, f.Uses, f.Message)
+		fmt.Fprintf(o.out, "  %s\n", ui.Format(ui.LevelSeverity(f.Severity), label))
+	}
+	if len(findings) > 0 {
+		fmt.Fprintln(o.out)
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("No references to pin in %d files", len(paths))))
+		return
+	}
+	summary := fmt.Sprintf("%d to pin, %d verified", counts[pinPinned], counts[pinVerified])
+	if n := counts[pinMoved]; n > 0 {
+		summary += fmt.Sprintf(", %d whose tag moved", n)
+	}
+	if n := counts[pinUnresolved] + counts[pinAmbiguous]; n > 0 {
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("%s, %d that cannot be pinned", summary, n)))
+		return
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo(summary))
+}
+
+// applyPins shows the pinned files as a diff and writes them, with a backup
+// of each, when --yes is given or the user confirms. Other non-interactive
+// sessions only show the diff.
+func (o *Orchestrator) applyPins(contents, pinned map[string]string) error {
+	if len(pinned) == 0 {
+		return nil
+	}
+	paths := sortedPaths(pinned)
+	fmt.Fprintln(o.out, "\n"+ui.FormatHeader("━━━━━━━━━━━━━━ PROPOSED PINS ━━━━━━━━━━━━━━\n"))
+	for _, path := range paths {
+		o.printDiff(patcher.Unified(path, patcher.Diff(contents[path], pinned[path]), false), 0)
+	}
+
+	switch {
+	case o.opts.Yes:
+	case !o.interactive():
+		fmt.Fprintln(o.out, ui.FormatInfo("Not applied, pass --yes to apply"))
+		return nil
+	default:
+		confirmed, err := ui.ShowConfirmation(
+			fmt.Sprintf("Pin the references in %d files?", len(paths)),
+			"A backup of each file will be created automatically",
+		)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Fprintln(o.out, ui.FormatDim("Pinning cancelled"))
+			return nil
+		}
+	}
+
+	// The files are pinned together: if one cannot be written, none is
+	var reqs []*patcher.PatchRequest
+	for _, path := range paths {
+		reqs = append(reqs, &patcher.PatchRequest{FilePath: path, NewContent: pinned[path], Validate: true})
+	}
+	results, _, err := o.patcher.ApplyGroup(reqs)
+	if err != nil {
+		return fmt.Errorf("failed to pin the references, no file was changed: %w", err)
+	}
+	for i, result := range results {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s pinned", paths[i])))
+		if result.BackupPath != "" {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Backup: %s", result.BackupPath)))
+		}
+	}
+	return nil
+}
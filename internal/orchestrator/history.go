@@ -0,0 +1,276 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/report"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/workflow"
+)
+
+// maxHistorySessions is how many session recaps history shows
+const maxHistorySessions = 5
+
+// History lists previous patches to the local workflow files, newest
+// first. With --category only patches and sessions of failures of those
+// categories are listed. In a terminal the patches are shown in a table to
+// browse their diffs and roll them back.
+func (o *Orchestrator) History(opts Options) error {
+	o.opts = opts
+
+	entries, err := o.historyEntries()
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == OutputJSON {
+		if entries == nil {
+			entries = []patchRecord{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		if len(opts.Categories) > 0 {
+			fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("No patches of %s failures recorded in this repository", strings.Join(opts.Categories, " or "))))
+		} else {
+			fmt.Fprintln(o.out, ui.FormatInfo("No patches recorded in this repository"))
+		}
+		o.printSessions()
+		o.printReviews()
+		return nil
+	}
+
+	if o.interactive() {
+		if err := o.browseHistory(entries); err != nil {
+			return err
+		}
+		o.printSessions()
+		o.printReviews()
+		return nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatHeader(fmt.Sprintf("%d previous patches", len(entries))))
+	for _, entry := range entries {
+		fmt.Fprintf(o.out, "  %s  %s  %s\n", ui.FormatDim(entry.PatchedAt.Format("Jan 02 2006, 15:04")), ui.FormatHighlight(entry.File), ui.FormatDim(tagOf(entry.Tag)))
+		if entry.RunID != 0 {
+			details := []string{fmt.Sprintf("run #%d", entry.RunID), patchSource(entry), patchChanges(entry)}
+			if entry.Rerun != "" {
+				details = append(details, "re-run "+entry.Rerun)
+			}
+			fmt.Fprintf(o.out, "      %s\n", strings.Join(details, " • "))
+		}
+		if entry.BackupPath != "" {
+			fmt.Fprintf(o.out, "      %s\n", ui.FormatDim("backup: "+entry.BackupPath))
+		}
+	}
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, ui.FormatInfo("Run `gh sentinel rollback <file>` to restore the latest backup"))
+	o.printSessions()
+	o.printReviews()
+	return nil
+}
+
+// historyEntries returns the patches of the working directory, newest
+// first: those in the patch history, and those only known from backups,
+// such as patches applied before it was kept
+func (o *Orchestrator) historyEntries() ([]patchRecord, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	recorded, err := readPatches(o.config.PatchesFile, dir)
+	if err != nil {
+		o.logger.Warn("Could not read the patch history: %v", err)
+	}
+
+	var entries []patchRecord
+	seen := make(map[string]bool)
+	for _, record := range recorded {
+		if record.BackupPath != "" {
+			seen[record.BackupPath] = true
+		}
+		if o.selectedTag(record.Tag) {
+			entries = append(entries, record)
+		}
+	}
+
+	tags := o.backupTags()
+	files, err := filepath.Glob(filepath.Join(workflow.Dir, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow files: %w", err)
+	}
+	originals := make(map[string]bool)
+	for _, file := range files {
+		original := o.patcher.OriginalPath(file)
+		if originals[original] {
+			continue
+		}
+		originals[original] = true
+
+		backups, err := o.patcher.ListBackups(original)
+		if err != nil {
+			return nil, err
+		}
+		for _, backup := range backups {
+			if seen[backup] {
+				continue
+			}
+			patchedAt, err := o.patcher.BackupTimestamp(backup)
+			if err != nil {
+				o.logger.Debug("Skipping %s: %v", backup, err)
+				continue
+			}
+			entry := patchRecord{
+				File:       original,
+				BackupPath: backup,
+				PatchedAt:  patchedAt,
+				Tag:        tags[backup],
+			}
+			if o.selectedTag(entry.Tag) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].PatchedAt.After(entries[j].PatchedAt)
+	})
+	return entries, nil
+}
+
+// browseHistory shows the patches in a table until the user quits or rolls
+// one back
+func (o *Orchestrator) browseHistory(entries []patchRecord) error {
+	headers := []string{"Patched", "File", "Run", "Diagnosis", "Changes", "Re-run", "Category"}
+	rows := make([][]string, len(entries))
+	for i, entry := range entries {
+		run, rerun := "–", "–"
+		if entry.RunID != 0 {
+			run = fmt.Sprintf("#%d", entry.RunID)
+		}
+		if entry.Rerun != "" {
+			rerun = ui.Icon(ui.ConclusionSeverity("completed", entry.Rerun)) + " " + entry.Rerun
+		}
+		rows[i] = []string{entry.PatchedAt.Format("Jan 02 15:04"), entry.File, run, patchSource(entry), patchChanges(entry), rerun, entry.Tag}
+	}
+
+	title := fmt.Sprintf("%d previous patches", len(entries))
+	cursor := 0
+	for {
+		i, action, err := ui.ShowPatchTable(title, headers, rows, cursor)
+		if err != nil {
+			return err
+		}
+		if action == ui.TableQuit {
+			return nil
+		}
+		cursor = i
+		entry := entries[i]
+
+		switch action {
+		case ui.TableInspect:
+			diffTitle := fmt.Sprintf("Patch of %s, %s", entry.File, entry.PatchedAt.Format("Jan 02 2006, 15:04"))
+			diff := entry.Diff
+			if diff == "" && entry.BackupPath != "" {
+				diff = backupDiff(entry.File, entry.BackupPath)
+				diffTitle += " (changes since the backup)"
+			}
+			if diff == "" {
+				fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("No diff recorded for the patch of %s", entry.File)))
+				continue
+			}
+			if err := ui.ShowDiff(diffTitle, diff); err != nil {
+				return err
+			}
+		case ui.TableRollback:
+			if entry.BackupPath == "" {
+				fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The patch of %s has no backup to restore", entry.File)))
+				continue
+			}
+			return o.Rollback(Options{}, entry.File, entry.BackupPath, false, false)
+		}
+	}
+}
+
+// patchSource describes how a patch was diagnosed, e.g. "ai, HIGH"
+func patchSource(entry patchRecord) string {
+	source := entry.Source
+	if entry.Confidence != "" {
+		source += ", " + entry.Confidence
+	}
+	return source
+}
+
+// patchChanges describes the lines a patch changed, e.g. "+3 -1"
+func patchChanges(entry patchRecord) string {
+	if entry.RunID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("+%d -%d", entry.LinesAdded, entry.LinesRemoved)
+}
+
+// printSessions lists the most recent session recaps
+func (o *Orchestrator) printSessions() {
+	recaps, err := readRecaps(o.config.HistoryFile, maxHistorySessions)
+	if err != nil {
+		o.logger.Warn("Could not read session history: %v", err)
+		return
+	}
+	if len(recaps) == 0 {
+		return
+	}
+
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, ui.FormatHeader("Recent sessions"))
+	for _, recap := range recaps {
+		var runs []sessionRun
+		applied := 0
+		for _, run := range recap.Runs {
+			if !o.selectedTag(run.Tag) {
+				continue
+			}
+			runs = append(runs, run)
+			if run.Status == StatusApplied {
+				applied++
+			}
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		line := fmt.Sprintf("%d runs analyzed, %d fixed", len(runs), applied)
+		if tags := report.TagCounts(runs); tags != "" {
+			line += ui.FormatDim(" • " + tags)
+		}
+		fmt.Fprintf(o.out, "  %s  %s  %s\n",
+			ui.FormatDim(recap.StartedAt.Format("Jan 02 2006, 15:04")),
+			ui.FormatHighlight(recap.Repository),
+			line)
+	}
+}
+
+// backupTags maps the backups of applied fixes in the session history to
+// the failure category of the run they fixed
+func (o *Orchestrator) backupTags() map[string]string {
+	tags := make(map[string]string)
+	recaps, err := readRecaps(o.config.HistoryFile, maxReviewSessions)
+	if err != nil {
+		o.logger.Debug("Could not read session history: %v", err)
+		return tags
+	}
+	for _, recap := range recaps {
+		for _, run := range recap.Runs {
+			if run.BackupPath != "" && run.Tag != "" {
+				tags[run.BackupPath] = run.Tag
+			}
+		}
+	}
+	return tags
+}
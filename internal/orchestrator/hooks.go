@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/hooks"
+)
+
+// runHooks runs the repository's commit hooks that apply to a patched file,
+// so a fix the team's own commit gates would reject is caught before it is
+// committed. Hooks run the repository's code, so without --hooks they are
+// only pointed out.
+func (o *Orchestrator) runHooks(path string) []ReportHook {
+	repo, err := o.openRepo()
+	if err != nil {
+		o.logger.Debug("Not looking for commit hooks: %v", err)
+		return nil
+	}
+	runner := hooks.NewRunner(repo.Root(), o.logger)
+	runner.SetContext(o.ctx)
+	runners := runner.Detect()
+	if len(runners) == 0 {
+		return nil
+	}
+	names := strings.Join(runners, " and ")
+	if !o.config.RunHooks {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  The repository has %s hooks; check the patch with them using --hooks", names)))
+		return nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Running the %s hooks on %s...", names, path)))
+	results, err := runner.Run(path)
+	if err != nil {
+		o.logger.Warn("Could not run the commit hooks: %v", err)
+	}
+	if len(results) == 0 {
+		if err == nil {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  No hook applies to %s", path)))
+		}
+		return nil
+	}
+
+	var recorded []ReportHook
+	failed := 0
+	for _, result := range results {
+		recorded = append(recorded, ReportHook{
+			Runner:   result.Runner,
+			Name:     result.Name,
+			Passed:   result.Passed,
+			Modified: result.Modified,
+			Output:   result.Output,
+		})
+		if result.Passed {
+			fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s: %s passed", result.Runner, result.Name)))
+			continue
+		}
+		failed++
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s: %s failed", result.Runner, result.Name)))
+		for _, line := range strings.Split(result.Output, "\n") {
+			if line != "" {
+				fmt.Fprintf(o.out, "  %s\n", ui.FormatDim(line))
+			}
+		}
+	}
+	for _, result := range results {
+		if result.Modified {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  %s rewrote %s; review its changes before committing", result.Name, path)))
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  %d of %d hooks fail; the commit would be rejected until they pass. Undo with: gh sentinel rollback %s", failed, len(results), path)))
+	}
+	return recorded
+}
@@ -0,0 +1,144 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+)
+
+// maxIssuePatterns bounds the log patterns listed in an issue
+const maxIssuePatterns = 5
+
+// issueMarker tags the issues of the failures of a workflow, so that a
+// failure that comes back is added to the open issue instead of a new one
+func issueMarker(workflow string) string {
+	return fmt.Sprintf("<!-- gh-sentinel issue: %s -->", workflow)
+}
+
+// unresolved reports whether the run ended without a fix sentinel stands
+// behind: nothing actionable was found, or the only fix found has LOW
+// confidence and was not applied
+func (o *Orchestrator) unresolved() bool {
+	switch o.report.Status {
+	case StatusNoFix:
+		return true
+	case StatusApplied, StatusAwaitingReview, StatusFlaky, StatusIgnored, StatusDisabled:
+		return false
+	}
+	d := o.report.Diagnosis
+	return d != nil && d.Confidence == "LOW"
+}
+
+// offerIssue files an issue for a failure sentinel could not fix, with the
+// failure summary, the top suggestions of the log patterns and a link to
+// the run, so that it is not silently dropped. In a terminal the user is
+// asked first; otherwise it is only filed with --issue. While an issue of
+// the same workflow is open, the failure is added to it as a comment.
+func (o *Orchestrator) offerIssue(runID int64) error {
+	if !o.unresolved() || (!o.opts.Issue && !o.interactive()) {
+		return nil
+	}
+	if !o.opts.Issue {
+		details := "A failure of this workflow while the issue is open is added to it as a comment"
+		if len(o.config.IssueLabels) > 0 {
+			details = fmt.Sprintf("Labeled %s. %s", strings.Join(o.config.IssueLabels, ", "), details)
+		}
+		confirmed, err := ui.ShowConfirmation("File an issue for this failure?", details)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	marker := issueMarker(o.report.Workflow)
+	issue, err := o.github.FileIssue(marker, o.issueTitle(), o.issueBody(marker), o.config.IssueLabels, o.config.IssueAssignees)
+	if err != nil {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not file an issue for run #%d: %v", runID, err)))
+		return nil
+	}
+	o.report.IssueURL = issue.URL
+	if issue.Existing {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Added the failure to issue #%d", issue.Number)))
+	} else {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Filed issue #%d", issue.Number)))
+	}
+	fmt.Fprintln(o.out, ui.FormatDim("  "+issue.URL))
+	return nil
+}
+
+// issueTitle names the workflow and the first step that failed
+func (o *Orchestrator) issueTitle() string {
+	if len(o.report.Failed) == 0 {
+		return fmt.Sprintf("CI: %s fails", o.report.Workflow)
+	}
+	f := o.report.Failed[0]
+	return fmt.Sprintf("CI: %s fails at %s › %s", o.report.Workflow, f.Job, f.Step)
+}
+
+// issueBody renders the failure of the report as Markdown: the failed
+// steps, the log patterns matched, their top suggestions and the diagnosis
+// sentinel could not act on
+func (o *Orchestrator) issueBody(marker string) string {
+	var b strings.Builder
+	b.WriteString(marker + "\n")
+	fmt.Fprintf(&b, "### 🛡️ gh-sentinel could not fix `%s`\n\n", o.report.Workflow)
+	fmt.Fprintf(&b, "[Run %d](%s) failed", o.report.RunID, o.github.RunURL(o.report.RunID))
+	var steps []string
+	for _, f := range o.report.Failed {
+		steps = append(steps, fmt.Sprintf("%s › `%s`", f.Job, f.Step))
+	}
+	if len(steps) > 0 {
+		b.WriteString(" at " + strings.Join(steps, ", "))
+	}
+	b.WriteString(".\n\n")
+
+	if len(o.report.Detected) > 0 {
+		b.WriteString("**Detected in the logs**\n\n")
+		for i, d := range o.report.Detected {
+			if i == maxIssuePatterns {
+				fmt.Fprintf(&b, "- ... and %d more\n", len(o.report.Detected)-maxIssuePatterns)
+				break
+			}
+			fmt.Fprintf(&b, "- **%s** (%s): %s", d.Pattern, d.Severity, d.Message)
+			if d.Step != "" {
+				fmt.Fprintf(&b, " in %s", d.Step)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if len(o.report.Suggestions) > 0 {
+		b.WriteString("**Suggestions**\n\n")
+		for i, suggestion := range o.report.Suggestions {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, suggestion)
+		}
+		b.WriteString("\n")
+	}
+	if o.report.RootCause != "" {
+		fmt.Fprintf(&b, "**Root cause** in %s, not in the workflow\n\n", rootCauseWhere(o.report.RootCause))
+		for _, culprit := range o.report.Culprits {
+			fmt.Fprintf(&b, "- `%s`\n", culprit)
+		}
+		if len(o.report.Culprits) > 0 {
+			b.WriteString("\n")
+		}
+	}
+
+	if d := o.report.Diagnosis; d != nil {
+		source := d.Source
+		if d.Confidence != "" {
+			source += ", " + strings.ToLower(d.Confidence) + " confidence"
+		}
+		fmt.Fprintf(&b, "**Diagnosis** (%s)\n\n", source)
+		if explanation := strings.TrimSpace(d.Explanation); explanation != "" {
+			b.WriteString(explanation + "\n\n")
+		}
+	} else {
+		b.WriteString("No recipe or AI diagnosis explained this failure.\n\n")
+	}
+	b.WriteString("<sub>Filed by gh-sentinel; later failures of this workflow are added here while the issue is open.</sub>\n")
+	return b.String()
+}
@@ -0,0 +1,209 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// Lint gate choices
+const (
+	lintApply = iota
+	lintAbort
+)
+
+// lintGate validates a proposed fix against the workflow schema and
+// actionlint, or a fix of another file by its type. An AI fix with errors
+// is sent back to the AI together with the errors, up to config.FixRetries
+// times. When lint errors remain the user can apply anyway or abort;
+// remaining schema issues are flagged again before applying. It returns the fix to apply, or nil when the patch was
+// aborted.
+func (o *Orchestrator) lintGate(diagnosis *copilot.DiagnosisResult) (*copilot.DiagnosisResult, error) {
+	maxAttempts := 1 + o.config.FixRetries
+	if diagnosis.Attempt == 0 {
+		diagnosis.Attempt = 1
+	}
+
+	var lintIssues []patcher.LintIssue
+	for {
+		var errs []string
+		for _, issue := range patcher.ValidateSchema(diagnosis.TargetFile, diagnosis.FixedContent) {
+			errs = append(errs, issue.String())
+		}
+		lintIssues = o.lintFix(diagnosis)
+		for _, issue := range lintIssues {
+			errs = append(errs, issue.String())
+		}
+		if len(errs) == 0 {
+			if o.config.Lint && workflow.IsWorkflowFile(diagnosis.TargetFile) {
+				fmt.Fprintln(o.out, ui.FormatSuccess("actionlint found no issues in the proposed fix"))
+			}
+			return diagnosis, nil
+		}
+
+		canRetry := o.copilot != nil && o.report.Diagnosis.Source == "ai" &&
+			diagnosis.Attempt < maxAttempts && o.ctx.Err() == nil
+		if !canRetry {
+			break
+		}
+
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Attempt %d of %d has %d validation errors:", diagnosis.Attempt, maxAttempts, len(errs))))
+		for _, e := range errs {
+			fmt.Fprintf(o.out, "  • %s\n", e)
+		}
+		fmt.Fprintln(o.out)
+
+		revised, err := o.retryWithErrors(diagnosis, errs)
+		if err != nil {
+			o.logger.Warn("AI retry failed: %v", err)
+			fmt.Fprintln(o.out, ui.FormatWarning("Could not get a revised fix, keeping the current one"))
+			break
+		}
+		revised.Attempt = diagnosis.Attempt + 1
+		o.recordDiagnosis(revised, "ai", nil)
+		o.checkDisagreement(revised)
+		o.displayDiagnosisResults(revised, diagnosis.TargetFile)
+		diagnosis = revised
+	}
+
+	if diagnosis.Attempt > 1 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The fix still has errors after %d attempts", diagnosis.Attempt)))
+	}
+	if len(lintIssues) == 0 {
+		return diagnosis, nil
+	}
+	o.printLintIssues("The proposed fix", lintIssues)
+	if o.lintChoice() == lintAbort {
+		o.report.Status = StatusInvalid
+		fmt.Fprintln(o.out, ui.FormatDim("Patch aborted"))
+		return nil, nil
+	}
+	return diagnosis, nil
+}
+
+// lintFix runs actionlint on a proposed fix and records the issues in the
+// report. It returns nil when linting is disabled or unavailable, and for
+// files other than workflows.
+func (o *Orchestrator) lintFix(diagnosis *copilot.DiagnosisResult) []patcher.LintIssue {
+	if !o.config.Lint || !workflow.IsWorkflowFile(diagnosis.TargetFile) {
+		return nil
+	}
+	issues, err := patcher.Lint(diagnosis.TargetFile, diagnosis.FixedContent)
+	if err != nil {
+		o.logger.Warn("Skipping actionlint verification: %v", err)
+		return nil
+	}
+
+	o.report.Diagnosis.LintIssues = nil
+	for _, issue := range issues {
+		o.report.Diagnosis.LintIssues = append(o.report.Diagnosis.LintIssues, issue.String())
+	}
+	return issues
+}
+
+// lintChoice decides what to do about lint errors. --yes never applies a fix
+// with lint errors; other non-interactive runs only propose the fix, so it
+// is shown with its issues.
+func (o *Orchestrator) lintChoice() int {
+	if !o.interactive() {
+		if o.opts.Yes {
+			return lintAbort
+		}
+		return lintApply
+	}
+
+	choice, err := ui.ShowChoice("actionlint reported errors in the fix", "", []string{"Apply anyway", "Abort the patch"})
+	if err != nil || choice != 0 {
+		return lintAbort
+	}
+	return lintApply
+}
+
+// retryWithErrors sends the previous attempt and its validation errors back
+// to the AI for correction
+func (o *Orchestrator) retryWithErrors(diagnosis *copilot.DiagnosisResult, errs []string) (*copilot.DiagnosisResult, error) {
+	fmt.Fprintln(o.out, ui.FormatInfo("Sending the errors back to the AI..."))
+
+	var logs strings.Builder
+	logs.WriteString("The current file content is your previous attempt at fixing a failed workflow.\n")
+	fmt.Fprintf(&logs, "Its explanation was: %s\n\n", diagnosis.Explanation)
+	logs.WriteString("Validation reported these errors in it. Correct them without undoing the fix:\n")
+	for _, e := range errs {
+		logs.WriteString(e + "\n")
+	}
+
+	req := &copilot.DiagnosisRequest{
+		ErrorLogs:      logs.String(),
+		CurrentFile:    diagnosis.TargetFile,
+		FileContent:    diagnosis.FixedContent,
+		AvailableFiles: []string{filepath.Base(diagnosis.TargetFile)},
+		WorkflowPath:   diagnosis.TargetFile,
+	}
+	// A source file stays the target, whatever the root cause
+	if !workflow.IsWorkflowFile(diagnosis.TargetFile) {
+		req.SourceFiles = map[string]string{diagnosis.TargetFile: diagnosis.FixedContent}
+	}
+	revised, err := o.copilot.DiagnoseAndFix(req)
+	if err != nil {
+		return nil, err
+	}
+	if revised.FixedContent == "" {
+		return nil, fmt.Errorf("the AI returned no revised content")
+	}
+	revised.TargetFile = diagnosis.TargetFile
+	revised.BaseContent = diagnosis.BaseContent
+	revised.Patches = diagnosis.Patches
+	return revised, nil
+}
+
+// verifyPatched checks the job graph of the file as written to disk and
+// lints it. Files other than workflows were checked by their type before
+// being written.
+func (o *Orchestrator) verifyPatched(path string) {
+	if !workflow.IsWorkflowFile(path) {
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		o.logger.Warn("Could not re-read %s for verification: %v", path, err)
+		return
+	}
+
+	// A rewrite can break the needs: graph in ways YAML parsing misses
+	if graph := patcher.ValidateNeeds(string(content)); len(graph) > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s has a broken job dependency graph:", path)))
+		for _, issue := range graph {
+			fmt.Fprintf(o.out, "  • %s\n", issue)
+		}
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Undo with: gh sentinel rollback %s", path)))
+	}
+
+	if !o.config.Lint {
+		return
+	}
+	issues, err := patcher.Lint(path, string(content))
+	if err != nil {
+		o.logger.Warn("Skipping actionlint verification: %v", err)
+		return
+	}
+	if len(issues) == 0 {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("actionlint: %s is clean", path)))
+		return
+	}
+	o.printLintIssues(path, issues)
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Undo with: gh sentinel rollback %s", path)))
+}
+
+func (o *Orchestrator) printLintIssues(subject string, issues []patcher.LintIssue) {
+	fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s has %d actionlint errors:", subject, len(issues))))
+	for _, issue := range issues {
+		fmt.Fprintf(o.out, "  • %s\n", issue)
+	}
+	fmt.Fprintln(o.out)
+}
@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+)
+
+// localContentError makes analyzeAndFix diagnose the run again from the
+// local content of files, instead of the versions the AI was given
+type localContentError struct {
+	files map[string]string // Local content by path
+}
+
+func (e *localContentError) Error() string {
+	return "the local files differ from the analyzed versions"
+}
+
+// checkLocalContent compares the local files a fix patches with the content
+// the AI analyzed, fetched from the default branch, which the checkout may
+// be behind or ahead of. Each file that differs gets a three-way notice of
+// the analyzed, local and fixed versions. In a terminal the user may have
+// the run diagnosed again from the local content, reported as a
+// *localContentError, apply the fix onto the local files, or cancel;
+// otherwise only the fix's changes are merged onto the local files. It
+// reports whether to go on.
+func (o *Orchestrator) checkLocalContent(diagnosis *copilot.DiagnosisResult) (bool, error) {
+	local := make(map[string]string)
+	diverged := 0
+	for _, file := range diagnosis.Files() {
+		if file.BaseContent == "" {
+			continue
+		}
+		content, err := os.ReadFile(file.TargetFile)
+		if err != nil && !os.IsNotExist(err) {
+			o.logger.Warn("Could not compare %s with the analyzed version: %v", file.TargetFile, err)
+			continue
+		}
+		if patcher.ContentHash(string(content)) == patcher.ContentHash(file.BaseContent) {
+			continue
+		}
+		o.printThreeWay(file, string(content), err == nil)
+		diverged++
+		if err == nil {
+			local[file.TargetFile] = string(content)
+		}
+		if o.report.Diagnosis != nil {
+			o.report.Diagnosis.Diverged = append(o.report.Diagnosis.Diverged, file.TargetFile)
+		}
+	}
+	if diverged == 0 {
+		return true, nil
+	}
+
+	switch {
+	case o.opts.DryRun:
+		return true, nil
+	case !o.interactive():
+		fmt.Fprintln(o.out, ui.FormatDim("  Only the fix's changes are applied onto the local files"))
+		fmt.Fprintln(o.out)
+		return true, nil
+	}
+	options := []string{"Apply the fix onto the local files", "Cancel"}
+	if len(local) > 0 {
+		options = append([]string{"Diagnose again with the local content"}, options...)
+	}
+	choice, err := ui.ShowChoice(
+		"The local files differ from the versions the fix was made from",
+		"Diagnosing again sends the local content to the AI; applying merges only the fix's changes onto the local files",
+		options,
+	)
+	if err != nil {
+		return false, fmt.Errorf("local content dialog failed: %w", err)
+	}
+	switch {
+	case choice < 0 || choice == len(options)-1:
+		return false, nil
+	case len(local) > 0 && choice == 0:
+		return false, &localContentError{files: local}
+	}
+	return true, nil
+}
+
+// printThreeWay shows how the local content of a file, which exists or
+// not, compares with the version the AI analyzed and with the fix
+func (o *Orchestrator) printThreeWay(file copilot.FilePatch, local string, exists bool) {
+	fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s: the local file is not the version the fix was made from", file.TargetFile)))
+	version := func(content string) string {
+		return fmt.Sprintf("%s  %d lines", patcher.ContentHash(content)[:8], strings.Count(strings.TrimRight(content, "\n"), "\n")+1)
+	}
+	against := func(content string) string {
+		added, removed := patcher.CountChanges(patcher.Diff(file.BaseContent, content))
+		return fmt.Sprintf(", +%d -%d against the analyzed version", added, removed)
+	}
+	fmt.Fprintf(o.out, "   Analyzed (remote): %s\n", version(file.BaseContent))
+	if exists {
+		fmt.Fprintf(o.out, "   Local:             %s%s\n", version(local), against(local))
+	} else {
+		fmt.Fprintf(o.out, "   Local:             %s\n", ui.FormatDim("does not exist"))
+	}
+	fmt.Fprintf(o.out, "   Fix:               %s%s\n", version(file.FixedContent), against(file.FixedContent))
+	if exists {
+		fmt.Fprintln(o.out, ui.FormatDim("   Local changes the AI did not see:"))
+		o.printDiff(patcher.Unified(file.TargetFile, patcher.Diff(file.BaseContent, local), false), 10)
+	} else {
+		fmt.Fprintln(o.out)
+	}
+}
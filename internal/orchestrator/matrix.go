@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/workflow"
+)
+
+// analyzeMatrix groups the failed jobs of a matrix by combination, telling
+// whether every combination fails the same way or only those sharing an
+// axis value, and records the summary for the report and the AI. All jobs
+// of the run are listed, since the passing combinations are what single out
+// the failing axis.
+func (o *Orchestrator) analyzeMatrix(runID int64, jobLogs []*github.JobLogs, analysis *analyzer.Analysis, fileContent string) {
+	o.report.Matrix = nil
+	if analysis == nil || !hasMatrixJob(jobLogs) {
+		return
+	}
+	jobs, err := o.github.ListRunJobs(runID)
+	if err != nil {
+		o.logger.Debug("Cannot group matrix jobs: %v", err)
+		return
+	}
+
+	failedSteps := make(map[int64]string)
+	for _, logs := range jobLogs {
+		if step := logs.FailedStep(); step != nil {
+			failedSteps[logs.Job.ID] = step.Name
+		}
+	}
+	var shards []analyzer.MatrixShard
+	for _, job := range jobs {
+		name, values, ok := analyzer.ParseMatrixJob(job.Name)
+		if !ok {
+			continue
+		}
+		shards = append(shards, analyzer.MatrixShard{
+			Job:        name,
+			Values:     values,
+			Conclusion: job.Conclusion,
+			Signature:  failureSignature(job.Name, failedSteps[job.ID], analysis),
+		})
+	}
+
+	analysis.Matrix = analyzer.GroupMatrix(shards, workflow.MatrixAxes(fileContent))
+	for _, group := range analysis.Matrix {
+		o.report.Matrix = append(o.report.Matrix, group.String())
+		fmt.Fprintln(o.out, ui.FormatInfo("🧩 Matrix: "+group.String()))
+	}
+	if len(analysis.Matrix) > 0 {
+		fmt.Fprintln(o.out)
+	}
+}
+
+// hasMatrixJob reports whether a failed job is a combination of a matrix
+func hasMatrixJob(jobLogs []*github.JobLogs) bool {
+	for _, logs := range jobLogs {
+		if _, _, ok := analyzer.ParseMatrixJob(logs.Job.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// failureSignature describes how a job failed, from the step it failed at
+// and the log patterns matched in its logs, so that combinations failing
+// the same way compare equal
+func failureSignature(job, failedStep string, analysis *analyzer.Analysis) string {
+	prefix := job + " › "
+	patterns := make(map[string]bool)
+	for _, detected := range analysis.Errors {
+		if strings.HasPrefix(detected.Step, prefix) {
+			patterns[detected.Pattern] = true
+		}
+	}
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return failedStep + "|" + strings.Join(names, ",")
+}
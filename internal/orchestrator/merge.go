@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/patcher"
+)
+
+// applyMerging runs apply, which patches the files of reqs. A file that
+// changed since its fix was made gets the fix merged onto it three-way; the
+// conflicts of the merge are settled by the user in a terminal, then apply
+// runs again. Without a user to ask the conflicts are listed and the patch
+// fails, writing nothing. It reports whether the user went on.
+func (o *Orchestrator) applyMerging(reqs []*patcher.PatchRequest, apply func() error) (bool, error) {
+	for {
+		err := apply()
+		var conflict *patcher.ConflictError
+		if !stderrors.As(err, &conflict) {
+			return true, err
+		}
+		var req *patcher.PatchRequest
+		for _, r := range reqs {
+			if r.FilePath == conflict.Path {
+				req = r
+			}
+		}
+		if req == nil || req.Resolutions != nil || !o.interactive() {
+			o.printConflicts(conflict)
+			return false, fmt.Errorf("%w; apply the fix in a terminal, without --yes, to settle them", err)
+		}
+
+		conflicts := make([]ui.Conflict, len(conflict.Conflicts))
+		for i, c := range conflict.Conflicts {
+			conflicts[i] = ui.Conflict{Line: c.Line, Base: c.Base, Current: c.Current, Fix: c.Fix}
+		}
+		choices, err := ui.ShowConflicts(
+			fmt.Sprintf("%s changed since the fix was made: settle %d conflicts", conflict.Path, len(conflicts)),
+			"The changes that do not conflict are merged as they are; choose the lines to keep where both changed",
+			conflicts,
+		)
+		if err != nil {
+			return false, fmt.Errorf("conflict dialog failed: %w", err)
+		}
+		if choices == nil {
+			return false, nil
+		}
+		req.Resolutions = make([]patcher.Resolution, len(choices))
+		for i, choice := range choices {
+			switch choice {
+			case ui.ConflictKeepCurrent:
+				req.Resolutions[i] = patcher.KeepCurrent
+			case ui.ConflictTakeFix:
+				req.Resolutions[i] = patcher.TakeFix
+			case ui.ConflictKeepBoth:
+				req.Resolutions[i] = patcher.KeepBoth
+			}
+		}
+	}
+}
+
+// printMerged notes that a fix was merged onto a file that changed since it
+// was made
+func (o *Orchestrator) printMerged(req *patcher.PatchRequest, result *patcher.PatchResult) {
+	if !result.Merged {
+		return
+	}
+	note := "  Merged onto the changes made to the file since the fix was made"
+	if len(req.Resolutions) > 0 {
+		note += fmt.Sprintf(", %d conflicts settled", len(req.Resolutions))
+	}
+	fmt.Fprintln(o.out, ui.FormatDim(note))
+}
+
+// printConflicts lists the conflicts of a fix merged onto a file that
+// changed since it was made
+func (o *Orchestrator) printConflicts(conflict *patcher.ConflictError) {
+	fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s changed since the fix was made, and %d of its changes conflict with the fix:", conflict.Path, len(conflict.Conflicts))))
+	for _, c := range conflict.Conflicts {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  line %d", c.Line)))
+		for _, line := range c.Current {
+			fmt.Fprintln(o.out, ui.FormatDiffLine("-"+strings.TrimRight(line, "\r\n")))
+		}
+		for _, line := range c.Fix {
+			fmt.Fprintln(o.out, ui.FormatDiffLine("+"+strings.TrimRight(line, "\r\n")))
+		}
+	}
+	fmt.Fprintln(o.out)
+}
@@ -0,0 +1,171 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	sentinelContext "gh-sentinel/internal/context"
+)
+
+// metadataCacheDir is the subdirectory of the cache directory holding what
+// the last session learned about the repository of each working directory
+const metadataCacheDir = "metadata"
+
+// repoMetadata is what sentinel knows about a repository between sessions,
+// so the next one can show the run selector before gh and the API answer
+type repoMetadata struct {
+	UpdatedAt     time.Time                    `json:"updated_at"`
+	Dir           string                       `json:"dir"`
+	Repository    *sentinelContext.RepoContext `json:"repository"`
+	WorkflowFiles []string                     `json:"workflow_files,omitempty"`
+	WorkflowPaths map[int64]string             `json:"workflow_paths,omitempty"` // Workflow ID -> path
+}
+
+// metadataKey is what the metadata of the session is stored under: the
+// working directory, or the repository --repo names
+func (o *Orchestrator) metadataKey() string {
+	if o.config.Repository != "" {
+		return "repo:" + o.config.Repository
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// detectRepository asks gh for the repository of the session, giving it as
+// long as an API request
+func (o *Orchestrator) detectRepository() (*sentinelContext.RepoContext, error) {
+	ctx, cancel := context.WithTimeout(o.ctx, o.config.RequestTimeout)
+	defer cancel()
+	if o.config.Repository != "" {
+		return sentinelContext.LookupRepository(ctx, o.config.GitHubHost, o.config.Repository)
+	}
+	return sentinelContext.DetectRepository(ctx)
+}
+
+func (o *Orchestrator) metadataPath(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(o.config.CacheDir, metadataCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadMetadata returns the metadata the last session saved for a working
+// directory, or nil if there is none younger than the cache TTL or
+// --no-cache was given
+func (o *Orchestrator) loadMetadata(dir string) *repoMetadata {
+	if o.opts.NoCache {
+		return nil
+	}
+	data, err := os.ReadFile(o.metadataPath(dir))
+	if err != nil {
+		return nil
+	}
+	var meta repoMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		o.logger.Debug("Ignoring corrupt repository metadata: %v", err)
+		return nil
+	}
+	if meta.Repository == nil || meta.Dir != dir {
+		return nil
+	}
+	if o.config.CacheTTL > 0 && time.Since(meta.UpdatedAt) > o.config.CacheTTL {
+		return nil
+	}
+	return &meta
+}
+
+// saveMetadata stores the metadata of a working directory for the next
+// session. Failing to store it only costs a slower start.
+func (o *Orchestrator) saveMetadata(meta *repoMetadata) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+	path := o.metadataPath(meta.Dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		o.logger.Debug("Could not cache the repository metadata: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		o.logger.Debug("Could not cache the repository metadata: %v", err)
+	}
+}
+
+// refreshMetadata fetches the repository context and workflows and saves
+// them for the next session. It runs in the background and sends what it
+// fetched on o.refreshed, or nil if that failed. The repository is only
+// detected again when the client was created from the cache.
+func (o *Orchestrator) refreshMetadata(dir string, detect bool) {
+	fresh := &repoMetadata{
+		UpdatedAt:  time.Now(),
+		Dir:        dir,
+		Repository: o.github.GetRepository(),
+	}
+	if detect {
+		repo, err := o.detectRepository()
+		if err != nil {
+			o.logger.Debug("Could not refresh the repository context: %v", err)
+			o.refreshed <- nil
+			return
+		}
+		fresh.Repository = repo
+		if repo.FullName != o.github.GetRepository().FullName {
+			// The workflows listed would be those of the cached repository
+			o.saveMetadata(fresh)
+			o.refreshed <- fresh
+			return
+		}
+	}
+
+	files, err := o.github.ListWorkflowFiles()
+	if err != nil {
+		o.logger.Debug("Could not refresh the workflow files: %v", err)
+		o.refreshed <- nil
+		return
+	}
+	fresh.WorkflowFiles = files
+	if workflows, err := o.github.ListWorkflows(); err == nil {
+		fresh.WorkflowPaths = make(map[int64]string, len(workflows))
+		for _, w := range workflows {
+			fresh.WorkflowPaths[w.ID] = w.Path
+		}
+	}
+	o.saveMetadata(fresh)
+	o.refreshed <- fresh
+}
+
+// listWorkflowFiles lists the workflow files of the repository. They come
+// from the last session while the background refresh is running; see
+// freshWorkflowFiles.
+func (o *Orchestrator) listWorkflowFiles() ([]string, error) {
+	if o.cached != nil && len(o.cached.WorkflowFiles) > 0 {
+		o.logger.Debug("Using the workflow files cached %s ago", time.Since(o.cached.UpdatedAt).Round(time.Second))
+		return o.cached.WorkflowFiles, nil
+	}
+	return o.github.ListWorkflowFiles()
+}
+
+// freshWorkflowFiles returns the workflow files once the background refresh
+// is done, when files came from the cache. If the refresh failed, the cached
+// files are kept. It fails if the working directory now belongs to another
+// repository than the cached one.
+func (o *Orchestrator) freshWorkflowFiles(files []string) ([]string, error) {
+	if o.cached == nil || len(o.cached.WorkflowFiles) == 0 || o.refreshed == nil {
+		return files, nil
+	}
+	cached := o.cached
+	fresh := <-o.refreshed
+	o.cached, o.refreshed = nil, nil
+	if fresh == nil {
+		return files, nil
+	}
+	if fresh.Repository.FullName != cached.Repository.FullName {
+		return nil, fmt.Errorf("this directory now belongs to %s instead of %s; run the command again", fresh.Repository.FullName, cached.Repository.FullName)
+	}
+	o.github.SetWorkflowPaths(fresh.WorkflowPaths)
+	return fresh.WorkflowFiles, nil
+}
@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+)
+
+// timedDiagnoser records how long each diagnosis of an AI provider takes
+type timedDiagnoser struct {
+	copilot.Diagnoser
+	provider string
+}
+
+func (t timedDiagnoser) DiagnoseAndFix(req *copilot.DiagnosisRequest) (*copilot.DiagnosisResult, error) {
+	started := time.Now()
+	result, err := t.Diagnoser.DiagnoseAndFix(req)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.AIRequestDuration.Observe(time.Since(started).Seconds(), t.provider, outcome)
+	return result, err
+}
+
+func (t timedDiagnoser) QuickDiagnose(errorLogs string, keyLines []string) (string, error) {
+	started := time.Now()
+	explanation, err := t.Diagnoser.QuickDiagnose(errorLogs, keyLines)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.AIRequestDuration.Observe(time.Since(started).Seconds(), t.provider, outcome)
+	return explanation, err
+}
+
+// countDiagnosis counts the diagnosis of the current report
+func (o *Orchestrator) countDiagnosis() {
+	source := "none"
+	if d := o.report.Diagnosis; d != nil {
+		source = d.Source
+	}
+	metrics.Diagnoses.Inc(source, o.report.Status)
+}
+
+// serveMetrics serves the Prometheus metrics on addr until the session
+// ends; the returned function stops the server
+func (o *Orchestrator) serveMetrics(addr string) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serve metrics on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Serving Prometheus metrics on http://%s/metrics", listener.Addr())))
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			fmt.Fprintln(o.out, ui.FormatWarning("The metrics are reachable from other machines without a token, and name the repository"))
+		}
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,200 @@
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/github"
+)
+
+// MinDigestInterval is the shortest period a CI health digest covers
+const MinDigestInterval = time.Hour
+
+// monitorPageSize is how many recent failed runs each poll looks at
+const monitorPageSize = 20
+
+// MinMonitorInterval is the shortest polling interval, to stay well within
+// the API rate limit
+const MinMonitorInterval = 10 * time.Second
+
+// Monitor polls the default branch for newly failed runs until the session
+// is interrupted. Runs that had already failed when monitoring started are
+// ignored. Each new failure is announced with its failure category, and
+// diagnosed when diagnose is set; fixes are only proposed, never applied.
+// Failures of ignored categories, or of categories --category leaves out,
+// are neither. When a digest interval is configured, a summary of CI health
+// is sent at the end of every interval.
+func (o *Orchestrator) Monitor(opts Options, interval time.Duration, diagnose bool) error {
+	return o.monitor(opts, interval, diagnose, nil)
+}
+
+// monitor is the loop of Monitor. With a status API, it records the
+// failures it announces and the reports of their diagnoses there, and also
+// diagnoses the runs the API asks for, one at a time.
+func (o *Orchestrator) monitor(opts Options, interval time.Duration, diagnose bool, api *statusAPI) error {
+	opts.NoPrompt = true
+	o.opts = opts
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+	if diagnose || api != nil {
+		if err := o.connectAI(); err != nil {
+			return err
+		}
+	}
+
+	repo := o.github.GetRepository()
+	branch := repo.DefaultBranch
+
+	seen := make(map[int64]bool)
+	runs, err := o.github.ListFailedRuns(branch, monitorPageSize)
+	if err != nil {
+		if o.ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to get workflow runs: %w", err)
+	}
+	for _, run := range runs {
+		seen[run.ID] = true
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Watching %s (%s) for new failures every %s", ui.FormatHighlight(repo.FullName), branch, interval)))
+	var digest <-chan time.Time
+	since := time.Now()
+	if o.config.Digest > 0 {
+		destination := "printed here"
+		if o.config.NotifyWebhook != "" {
+			destination = "sent to the notify webhook"
+		}
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("A CI health digest is %s every %s", destination, o.config.Digest)))
+		digestTicker := time.NewTicker(o.config.Digest)
+		defer digestTicker.Stop()
+		digest = digestTicker.C
+	}
+	if opts.MetricsAddr != "" {
+		stop, err := o.serveMetrics(opts.MetricsAddr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	var queue <-chan queuedRun
+	if api != nil {
+		queue = api.queue
+		api.polled(time.Now())
+	}
+	fmt.Fprintln(o.out, ui.FormatDim("Press Ctrl-C to stop\n"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.ctx.Done():
+			fmt.Fprintln(o.out, ui.FormatDim("\nStopped watching"))
+			return nil
+		case now := <-digest:
+			o.sendDigest(since, now)
+			since = now
+			continue
+		case queued := <-queue:
+			o.diagnoseQueued(api, queued, seen)
+			continue
+		case <-ticker.C:
+		}
+
+		runs, err := o.github.ListFailedRuns(branch, monitorPageSize)
+		if err != nil {
+			if o.ctx.Err() != nil {
+				continue
+			}
+			// Transient API errors must not end a long-running watch, but
+			// e.g. revoked credentials will not heal by themselves
+			if !errors.IsRetryable(err) {
+				return fmt.Errorf("polling failed: %w", err)
+			}
+			o.logger.Warn("Poll failed, retrying in %s: %v", interval, err)
+			continue
+		}
+		if api != nil {
+			api.polled(time.Now())
+		}
+
+		// Oldest first, so failures are reported in the order they happened
+		for i := len(runs) - 1; i >= 0; i-- {
+			run := runs[i]
+			if seen[run.ID] {
+				continue
+			}
+			seen[run.ID] = true
+			if !o.admitFailure(run, api) || !diagnose || o.ctx.Err() != nil {
+				continue
+			}
+			if api != nil {
+				o.diagnoseServed(api, run)
+			} else {
+				o.diagnoseRun(run)
+			}
+		}
+	}
+}
+
+// admitFailure announces a new failure, unless its workflow or category is
+// ignored, and records it in the status API of serve
+func (o *Orchestrator) admitFailure(run *github.WorkflowRun, api *statusAPI) bool {
+	if matchWorkflow(o.config.IgnoreWorkflows, run.WorkflowPath) {
+		o.logger.Debug("Not announcing run #%d: %s is ignored (ignore_workflows)", run.ID, run.WorkflowPath)
+		return false
+	}
+	tag := o.classifyRun(run.ID)
+	if reason := o.ignoredTag(tag); reason != "" {
+		o.logger.Debug("Not announcing run #%d: %s", run.ID, reason)
+		return false
+	}
+	o.announceFailure(run, tag)
+	category := tag
+	if category == "" {
+		category = "unknown"
+	}
+	metrics.FailuresDetected.Inc(category)
+	if api != nil {
+		api.record(run, tag)
+	}
+	return true
+}
+
+// announceFailure prints a newly failed run and its failure category,
+// ringing the terminal bell when someone is watching
+func (o *Orchestrator) announceFailure(run *github.WorkflowRun, tag string) {
+	if ui.IsTerminal() {
+		fmt.Fprint(o.out, "\a")
+	}
+	stamp := ui.FormatDim(time.Now().Format("15:04:05"))
+	fmt.Fprintf(o.out, "%s %s\n", stamp, ui.Format(ui.ConclusionSeverity(run.Status, run.Conclusion), fmt.Sprintf("%s #%d failed: %s", run.Name, run.RunNumber, run.DisplayTitle))+" "+ui.FormatHighlight(tagOf(tag)))
+	fmt.Fprintf(o.out, "         %s\n", ui.FormatDim(fmt.Sprintf("%s • ID %d • gh sentinel fix --run-id %d", run.WorkflowPath, run.ID, run.ID)))
+}
+
+// diagnoseRun runs the fix flow on a failed run without prompting. Errors
+// are reported and monitoring continues.
+func (o *Orchestrator) diagnoseRun(run *github.WorkflowRun) {
+	o.report = &Report{Repository: o.github.GetRepository().FullName}
+
+	workflowFiles, err := o.github.ListWorkflowFiles()
+	if err != nil {
+		o.report.RunID = run.ID
+		o.report.Status = StatusError
+		o.report.Error = err.Error()
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not diagnose run #%d: %v", run.ID, err)))
+		return
+	}
+	selected := o.convertToUIItems([]*github.WorkflowRun{run})[0]
+	if err := o.analyzeAndFix(&selected, workflowFiles); err != nil {
+		o.report.Status = StatusError
+		o.report.Error = err.Error()
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not diagnose run #%d: %v", run.ID, err)))
+	}
+	o.countDiagnosis()
+	fmt.Fprintln(o.out)
+}
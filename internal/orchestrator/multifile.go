@@ -0,0 +1,236 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// reconcilePatches maps the further files of a fix that spans several onto
+// files of the repository: existing workflows and actions, or the other
+// files the AI was given. analyzed holds the content of each file the AI
+// was given, which the fix of that file is made from. A further file that
+// matches none is left out of the fix with a warning.
+func (o *Orchestrator) reconcilePatches(diagnosis *copilot.DiagnosisResult, workflowFiles []string, analyzed map[string]string) {
+	var kept []copilot.FilePatch
+	for _, patch := range diagnosis.Patches {
+		target := patch.TargetFile
+		_, ok := analyzed[target]
+		if !ok && workflow.IsWorkflowFile(target) {
+			target, ok = workflow.Resolve(target, workflowFiles)
+		}
+		if !ok || !inCheckout(target) {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Leaving %s out of the fix: it is neither an existing workflow nor a file the logs point to", patch.TargetFile)))
+			continue
+		}
+		if target == diagnosis.TargetFile {
+			continue
+		}
+		patch.TargetFile = target
+		patch.BaseContent = analyzed[target]
+		kept = append(kept, patch)
+	}
+	diagnosis.Patches = kept
+
+	if o.report.Diagnosis != nil {
+		o.report.Diagnosis.Further = nil
+		for _, patch := range kept {
+			o.report.Diagnosis.Further = append(o.report.Diagnosis.Further, patch.TargetFile)
+		}
+	}
+}
+
+// applyFixes applies a fix that spans several files. The diff of each file
+// is reviewed in its own tab, without hunk selection, and the files are
+// patched together or not at all; their backups are grouped so that
+// rollback restores every file of the fix.
+func (o *Orchestrator) applyFixes(diagnosis *copilot.DiagnosisResult) error {
+	fmt.Fprintln(o.out, ui.FormatHeader("━━━━━━━━━━━━━━ PROPOSED FIX ━━━━━━━━━━━━━━\n"))
+
+	var reqs []*patcher.PatchRequest
+	var diffs []ui.FileDiff
+	var issues []string
+	var critical []string
+	for _, patch := range diagnosis.Files() {
+		req := &patcher.PatchRequest{
+			FilePath:    patch.TargetFile,
+			NewContent:  patch.FixedContent,
+			BaseContent: patch.BaseContent,
+			Validate:    true,
+		}
+		reqs = append(reqs, req)
+
+		fmt.Fprintln(o.out, ui.FormatHighlight(req.FilePath))
+		diff, err := o.patcher.PreviewDiff(req)
+		if err != nil {
+			o.logger.Warn("Could not generate diff preview of %s: %v", req.FilePath, err)
+		} else {
+			o.printDiff(diff, 15)
+		}
+		diffs = append(diffs, ui.FileDiff{File: req.FilePath, Diff: diff})
+
+		for _, issue := range patcher.ValidateSchema(req.FilePath, req.NewContent) {
+			issues = append(issues, fmt.Sprintf("%s: %s", req.FilePath, issue))
+		}
+		if o.critical(req.FilePath) {
+			critical = append(critical, req.FilePath)
+		}
+	}
+
+	// Structural problems are shown so a broken fix can be rejected
+	if len(issues) > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The fixed files have %d schema issues:", len(issues))))
+		for _, issue := range issues {
+			fmt.Fprintf(o.out, "  • %s\n", issue)
+		}
+		o.report.Diagnosis.SchemaIssues = append(o.report.Diagnosis.SchemaIssues, issues...)
+		fmt.Fprintln(o.out)
+	}
+
+	action, status, reason := o.applyDecision(applyGate{
+		confidences: []string{diagnosis.Confidence},
+		invalid:     len(issues) > 0,
+		disputed:    disputed(o.report),
+	})
+	switch {
+	case o.opts.DryRun:
+		o.report.Status = StatusProposed
+		fmt.Fprintln(o.out, ui.FormatWarning("A fix of several files is not saved as a proposal; run without --dry-run to review it"))
+		return nil
+	case len(critical) > 0:
+		o.report.Status = StatusProposed
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Not applying a fix of several files that changes the critical workflow %s: apply it by hand for review", strings.Join(critical, ", "))))
+		o.printProposedFiles(diagnosis)
+		return nil
+	case action == applyHeld:
+		o.report.Status = status
+		fmt.Fprintln(o.out, ui.FormatWarning(reason))
+		return nil
+	case action == applyAuto:
+		fmt.Fprintln(o.out, ui.FormatInfo("Auto-applying fix (--yes)"))
+	case action == applyProposed:
+		o.report.Status = status
+		fmt.Fprintln(o.out, ui.FormatInfo("Proposed content (not applied, pass --yes to apply):"))
+		o.printProposedFiles(diagnosis)
+		return nil
+	default:
+		details := "The files are patched together, and a backup of each is created automatically"
+		if len(issues) > 0 {
+			details = fmt.Sprintf("Warning: %d schema issues found. %s", len(issues), details)
+		}
+		confirmed, err := ui.ShowFileDiffs(fmt.Sprintf("Apply the fix to %d files?", len(reqs)), details, diffs)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+			return nil
+		}
+	}
+
+	// Any of the files may have changed upstream while the fix was reviewed
+	for _, req := range reqs {
+		action, err := o.checkUpstream(req.FilePath)
+		if err != nil {
+			return err
+		}
+		switch action {
+		case upstreamRediagnose:
+			return errRediagnose
+		case upstreamCancel:
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+			return nil
+		}
+	}
+
+	// The working tree may not be ready for the fix
+	if ok, err := o.guardPatch(diagnosis.Files(), o.report.Branch); err != nil || !ok {
+		if err == nil {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+		}
+		return err
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Applying patches to %d files...", len(reqs))))
+	var results []*patcher.PatchResult
+	var group string
+	ok, err := o.applyMerging(reqs, func() (err error) {
+		results, group, err = o.patcher.ApplyGroup(reqs)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	if !ok {
+		o.report.Status = StatusDeclined
+		fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+		return nil
+	}
+	o.report.Status = StatusApplied
+	metrics.FixesApplied.Inc()
+	o.report.Patch = &ReportPatch{
+		BackupPath:   results[0].BackupPath,
+		LinesAdded:   results[0].LinesAdded,
+		LinesRemoved: results[0].LinesRemoved,
+		Group:        group,
+		Merged:       results[0].Merged,
+		Conflicts:    len(reqs[0].Resolutions),
+	}
+	for i, result := range results[1:] {
+		o.report.Patch.Further = append(o.report.Patch.Further, ReportFilePatch{
+			File:         reqs[i+1].FilePath,
+			BackupPath:   result.BackupPath,
+			LinesAdded:   result.LinesAdded,
+			LinesRemoved: result.LinesRemoved,
+			Merged:       result.Merged,
+		})
+	}
+
+	fmt.Fprintln(o.out)
+	for i, result := range results {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s patched successfully!", reqs[i].FilePath)))
+		if result.BackupPath != "" {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Backup: %s", result.BackupPath)))
+		}
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Changes: +%d -%d lines", result.LinesAdded, result.LinesRemoved)))
+		o.printMerged(reqs[i], result)
+	}
+	if len(o.patcher.BackupGroup(results[0].BackupPath)) > 1 {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Undo every file with: gh sentinel rollback %s", reqs[0].FilePath)))
+	}
+	fmt.Fprintln(o.out)
+	for _, req := range reqs {
+		o.verifyPatched(req.FilePath)
+		o.report.Patch.Hooks = append(o.report.Patch.Hooks, o.runHooks(req.FilePath)...)
+	}
+	fmt.Fprintln(o.out)
+
+	if o.opts.CreateBranch {
+		if err := o.publishFix(diagnosis); err != nil {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not publish the fix: %v", err)))
+			fmt.Fprintln(o.out, ui.FormatDim("  The patches are applied locally; commit and push them manually"))
+		}
+		fmt.Fprintln(o.out)
+	}
+
+	// Next steps are listed in the session summary
+	o.offerRerun(o.report.RunID)
+	return nil
+}
+
+// printProposedFiles prints the fixed content of every file of a fix
+func (o *Orchestrator) printProposedFiles(diagnosis *copilot.DiagnosisResult) {
+	for _, patch := range diagnosis.Files() {
+		fmt.Fprintln(o.out, ui.FormatHighlight(patch.TargetFile))
+		fmt.Fprintln(o.out, patch.FixedContent)
+		fmt.Fprintln(o.out)
+	}
+}
@@ -0,0 +1,153 @@
+package orchestrator
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/patcher"
+)
+
+// applyWithoutCheckout proposes a fix of the repository --repo names, which
+// has no local file to patch: once approved, the fix is committed to
+// sentinel/fix-<run-id> through the API and opened as a pull request
+// against the default branch. Without prompts it is only proposed, unless
+// --yes is given.
+func (o *Orchestrator) applyWithoutCheckout(diagnosis *copilot.DiagnosisResult) error {
+	if o.report.RunID == 0 {
+		return fmt.Errorf("cannot name the fix branch without a run ID")
+	}
+	// Without a checkout, the files of a fix cannot be written together
+	if len(diagnosis.Patches) > 0 {
+		o.report.Status = StatusProposed
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("The fix spans %d files, which are only applied together to a checkout; proposed content:", 1+len(diagnosis.Patches))))
+		o.printProposedFiles(diagnosis)
+		return nil
+	}
+	repo := o.github.GetRepository()
+	target := diagnosis.TargetFile
+
+	// The fix is made from the default branch's version of the file
+	content := diagnosis.BaseContent
+	created := false
+	if content == "" {
+		current, err := o.github.GetRemoteFileContent(repo.FullName, target, repo.DefaultBranch)
+		switch {
+		case github.IsNotFound(err):
+			created = true
+		case err != nil:
+			return err
+		}
+		content = current
+	}
+	hunks := patcher.Diff(content, diagnosis.FixedContent)
+
+	fmt.Fprintln(o.out, ui.FormatHeader("━━━━━━━━━━━━━━ PROPOSED FIX ━━━━━━━━━━━━━━\n"))
+	o.printDiff(patcher.Unified(target, hunks, created), 0)
+
+	issues := patcher.ValidateSchema(target, diagnosis.FixedContent)
+	if len(issues) > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The fixed file has %d schema issues:", len(issues))))
+		for _, issue := range issues {
+			fmt.Fprintf(o.out, "  • %s\n", issue)
+			o.report.Diagnosis.SchemaIssues = append(o.report.Diagnosis.SchemaIssues, issue.String())
+		}
+		fmt.Fprintln(o.out)
+	}
+
+	branch := fmt.Sprintf("%s%d", fixBranchPrefix, o.report.RunID)
+	action, status, reason := o.applyDecision(applyGate{
+		confidences: []string{diagnosis.Confidence},
+		invalid:     len(issues) > 0,
+		disputed:    disputed(o.report),
+	})
+	switch {
+	case o.opts.DryRun:
+		return o.saveProposal(o.report, diagnosis, content, created, issues)
+	case action == applyHeld:
+		o.report.Status = status
+		fmt.Fprintln(o.out, ui.FormatWarning(reason))
+		return nil
+	case action == applyAuto:
+		fmt.Fprintln(o.out, ui.FormatInfo("Opening the fix as a pull request (--yes)"))
+	case action == applyProposed:
+		o.report.Status = status
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Proposed content for %s (not applied, pass --yes to open it as a pull request):", target)))
+		fmt.Fprintln(o.out, diagnosis.FixedContent)
+		return nil
+	default:
+		details := fmt.Sprintf("There is no checkout of %s here: the fix is committed to %s with your gh credentials", repo.FullName, branch)
+		if len(issues) > 0 {
+			details = fmt.Sprintf("Warning: %d schema issues found. %s", len(issues), details)
+		}
+		confirmed, err := ui.ShowConfirmation(fmt.Sprintf("Open a pull request against %s?", repo.FullName), details)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			o.report.Status = StatusDeclined
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user"))
+			return nil
+		}
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Committing the fix to %s of %s...", branch, repo.FullName)))
+	req := &patcher.PatchRequest{
+		FilePath:    target,
+		NewContent:  diagnosis.FixedContent,
+		BaseContent: content,
+		Validate:    true,
+	}
+	result, sha, err := o.commitRemoteFix(repo.FullName, branch, repo.DefaultBranch, req, o.commitMessage(diagnosis))
+	if err != nil {
+		return err
+	}
+	o.report.Patch = &ReportPatch{
+		LinesAdded:   result.LinesAdded,
+		LinesRemoved: result.LinesRemoved,
+		Branch:       branch,
+		Commit:       sha,
+		Pushed:       true,
+	}
+
+	pr, err := o.github.CreatePullRequest(branch, repo.DefaultBranch, fmt.Sprintf("ci: fix %s (run %d)", target, o.report.RunID), o.pullBody(diagnosis))
+	if err != nil {
+		return fmt.Errorf("could not open a pull request for %s: %w", branch, err)
+	}
+	o.report.Status = StatusAwaitingReview
+	o.report.Patch.PullRequest = pr.Number
+	o.report.Patch.PullURL = pr.URL
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Opened pull request #%d: %s", pr.Number, pr.URL)))
+	return nil
+}
+
+// commitRemoteFix commits a fix to a new branch of a repository through the
+// Contents API. The fix is merged onto the file at the head of base, which
+// may have moved since it was made, and the commit is refused if the file
+// changes while it is written. It returns the patch and the commit SHA.
+func (o *Orchestrator) commitRemoteFix(repository, branch, base string, req *patcher.PatchRequest, message string) (*patcher.PatchResult, string, error) {
+	if err := o.github.CreateRemoteBranch(repository, branch, base); err != nil {
+		return nil, "", err
+	}
+	file, err := o.github.BranchFile(repository, branch, req.FilePath, message)
+	if err == nil {
+		var result *patcher.PatchResult
+		if result, err = o.patcher.ApplyRemote(file, req); err == nil {
+			return result, file.Commit, nil
+		}
+	}
+
+	// Nothing was committed to the branch, which would only be in the way
+	// of the next run, even after Ctrl-C
+	ctx, cancel := o.cleanupContext()
+	defer cancel()
+	if derr := o.github.WithContext(ctx).DeleteRemoteBranch(repository, branch); derr != nil {
+		o.logger.Warn("Could not delete %s of %s: %v", branch, repository, derr)
+	}
+	if stderrors.Is(err, patcher.ErrConflict) {
+		return nil, "", fmt.Errorf("%s changed on %s while the fix was committed; run sentinel again: %w", req.FilePath, base, err)
+	}
+	return nil, "", err
+}
@@ -1,327 +1,291 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"gh-sentinel/internal/cleanup"
 	"gh-sentinel/internal/config"
+	sentinelContext "gh-sentinel/internal/context"
 	"gh-sentinel/internal/logger"
+	"gh-sentinel/internal/report"
 	"gh-sentinel/internal/ui"
 	"gh-sentinel/pkg/analyzer"
 	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/fixer"
 	"gh-sentinel/pkg/github"
 	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/redact"
+	"gh-sentinel/pkg/scriptcheck"
 )
 
 // Orchestrator coordinates all sentinel operations
 type Orchestrator struct {
-	config   *config.Config
-	logger   *logger.Logger
-	github   *github.Client
-	copilot  *copilot.Client
-	analyzer *analyzer.Analyzer
-	patcher  *patcher.Patcher
+	config    *config.Config
+	logger    *logger.Logger
+	ctx       context.Context // Cancelled on interrupt
+	github    *github.Client
+	copilot   copilot.Diagnoser
+	analyzer  *analyzer.Analyzer
+	fixer     *fixer.Fixer
+	patcher   *patcher.Patcher
+	scripts   *scriptcheck.Checker
+	redactor  *redact.Redactor // Masks secrets in fetched logs
+	out       io.Writer // Human-readable output
+	opts      Options
+	report    *Report
+	session   []*Report                  // Reports of every run analyzed in this session
+	fetched   map[string]string          // Latest commit of each workflow file when its content was fetched
+	collected map[string]*cleanup.Result // Startup garbage collection by directory
+	cached    *repoMetadata              // Repository metadata of the last session, until refreshed
+	refreshed chan *repoMetadata         // Receives the background refresh of the metadata
 }
 
-// New creates a new orchestrator instance
-func New() (*Orchestrator, error) {
-	// Initialize configuration
-	cfg := config.Default()
+// New creates a new orchestrator instance from the given configuration
+func New(cfg *config.Config) (*Orchestrator, error) {
+	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	if err := ui.SetTheme(cfg.Theme); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	if err := cfg.EnsureDirectories(); err != nil {
 		return nil, fmt.Errorf("failed to create directories: %w", err)
 	}
 
-	// Initialize logger
-	log := logger.Default()
-
-	// Initialize GitHub client
-	ghClient, err := github.NewClient(cfg, log)
+	// Initialize logger at the configured level
+	level, err := logger.ParseLevel(cfg.LogLevel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize GitHub client: %w", err)
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	log := logger.New(level, os.Stderr)
+	if err := log.SetFormat(cfg.LogFormat); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.LogFile {
+		if path, err := log.OpenSessionLog(filepath.Join(cfg.CacheDir, sessionLogDir), maxSessionLogs); err != nil {
+			log.Warn("Could not open the session log: %v", err)
+		} else {
+			log.Debug("Logging the session to %s", path)
+		}
 	}
 
-	// Initialize Copilot client
-	copilotClient, err := copilot.NewClient(cfg, log)
+	// Initialize analyzer, fixer and patcher. The GitHub and AI clients are
+	// connected on demand so offline commands work without authentication.
+	analyzer, err := newAnalyzer(cfg, log)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize Copilot client: %w", err)
+		return nil, err
+	}
+	fixer, err := newFixer(cfg, log)
+	if err != nil {
+		return nil, err
 	}
-
-	// Initialize analyzer and patcher
-	analyzer := analyzer.NewAnalyzer(log)
 	patcher := patcher.NewPatcher(cfg, log)
+	redactor, err := redact.New(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Orchestrator{
+	o := &Orchestrator{
 		config:   cfg,
 		logger:   log,
-		github:   ghClient,
-		copilot:  copilotClient,
+		ctx:      context.Background(),
 		analyzer: analyzer,
+		fixer:    fixer,
 		patcher:  patcher,
-	}, nil
-}
-
-// Run executes the main sentinel workflow
-func (o *Orchestrator) Run() error {
-	// Display banner
-	ui.PrintBanner()
-
-	repo := o.github.GetRepository()
-	fmt.Println(ui.FormatInfo(fmt.Sprintf("Repository: %s", ui.FormatHighlight(repo.FullName))))
-	fmt.Println(ui.FormatDim("Scanning for failed workflows...\n"))
-
-	// Step 1: Get workflow files list
-	workflowFiles, err := o.github.ListWorkflowFiles()
-	if err != nil {
-		return fmt.Errorf("failed to list workflow files: %w", err)
-	}
-	o.logger.Debug("Found workflow files: %v", workflowFiles)
-
-	// Step 2: Get failed workflow runs
-	runs, err := o.github.GetFailedWorkflowRuns(10)
-	if err != nil {
-		return fmt.Errorf("failed to get workflow runs: %w", err)
-	}
-
-	if len(runs) == 0 {
-		fmt.Println(ui.FormatSuccess("System Clean. No failures detected! ✨"))
-		return nil
+		scripts:  scriptcheck.NewChecker(log),
+		redactor: redactor,
+		out:      os.Stdout,
 	}
+	o.collectGarbage()
+	return o, nil
+}
 
-	fmt.Println(ui.FormatWarning(fmt.Sprintf("Found %d failed workflow runs", len(runs))))
-
-	// Step 3: User selects a workflow to analyze
-	items := o.convertToUIItems(runs)
-	selected, err := ui.ShowWorkflowSelector(items)
-	if err != nil {
-		return fmt.Errorf("failed to show selector: %w", err)
+// SetContext sets the context of the session. Cancelling it aborts API
+// calls, AI requests and commands in progress.
+func (o *Orchestrator) SetContext(ctx context.Context) {
+	o.ctx = ctx
+	ui.SetContext(ctx)
+	o.scripts.SetContext(ctx)
+	if o.github != nil {
+		o.github.SetContext(ctx)
 	}
+}
 
-	if selected == nil {
-		fmt.Println(ui.FormatDim("Operation cancelled"))
-		return nil
-	}
+// cleanupTimeout bounds undoing partial work once the session is interrupted
+const cleanupTimeout = 10 * time.Second
 
-	// Step 4: Analyze the selected run
-	return o.analyzeAndFix(selected, workflowFiles)
+// cleanupContext returns the context of undoing partial work, e.g. switching
+// back to the base branch, which must still run after Ctrl-C
+func (o *Orchestrator) cleanupContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(o.ctx), cleanupTimeout)
 }
 
-// convertToUIItems converts workflow runs to UI items
-func (o *Orchestrator) convertToUIItems(runs []*github.WorkflowRun) []ui.WorkflowItem {
-	var items []ui.WorkflowItem
-	for _, run := range runs {
-		icon := o.getStatusIcon(run.Conclusion)
-		items = append(items, ui.WorkflowItem{
-			ID:          run.ID,
-			TitleText:   run.DisplayTitle,
-			DescText:    fmt.Sprintf("Run #%d • %s • %s", run.RunNumber, run.Event, run.UpdatedAt.Format("Jan 02, 15:04")),
-			Status:      run.Status,
-			Conclusion:  run.Conclusion,
-			Path:        run.WorkflowPath,
-			Icon:        icon,
-		})
+// connectGitHub initializes the GitHub client on first use. The repository
+// context and workflow paths the last session saved are used right away,
+// and refreshed in the background for the next one.
+func (o *Orchestrator) connectGitHub() error {
+	if o.github != nil {
+		return nil
 	}
-	return items
-}
-
-// getStatusIcon returns an icon based on workflow conclusion
-func (o *Orchestrator) getStatusIcon(conclusion string) string {
-	switch conclusion {
-	case "success":
-		return "✅"
-	case "failure":
-		return "❌"
-	case "cancelled":
-		return "🚫"
-	case "skipped":
-		return "⏭️"
-	default:
-		return "⏳"
+	dir := o.metadataKey()
+	cached := o.loadMetadata(dir)
+	var repo *sentinelContext.RepoContext
+	if cached != nil {
+		repo = cached.Repository
 	}
-}
 
-// analyzeAndFix performs the full analysis and fix workflow
-func (o *Orchestrator) analyzeAndFix(selected *ui.WorkflowItem, workflowFiles []string) error {
-	fmt.Println("\n" + ui.FormatHeader("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-	fmt.Println(ui.FormatHeader(fmt.Sprintf("🔍 Analyzing Run #%d", selected.ID)))
-	fmt.Println(ui.FormatHeader("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"))
-
-	// Step 1: Fetch logs (if available)
-	fmt.Println(ui.FormatInfo("Fetching job logs..."))
-	logs, err := o.github.GetWorkflowJobLogs(selected.ID)
-	
-	// If no job logs, this might be a configuration error
-	// Continue anyway and let Copilot analyze the workflow file
+	ghClient, err := github.NewClientFor(o.ctx, o.config, o.logger, repo)
 	if err != nil {
-		o.logger.Warn("Could not retrieve job logs: %v", err)
-		fmt.Println(ui.FormatWarning("⚠ No job logs available (possible workflow configuration error)"))
-		logs = "[No job execution logs available - workflow may have configuration error]"
-		fmt.Println(ui.FormatInfo("Proceeding with workflow file analysis...\n"))
-	} else {
-		o.logger.Debug("Retrieved %d chars of logs", len(logs))
+		return fmt.Errorf("failed to initialize GitHub client: %w", err)
 	}
-
-	// Step 2: Quick pattern analysis (skip if no real logs)
-	var analysis *analyzer.Analysis
-	if logs != "" && !strings.Contains(logs, "[No job execution logs") {
-		fmt.Println(ui.FormatInfo("Running pattern analysis..."))
-		analysis = o.analyzer.AnalyzeLogs(logs)
-
-		if len(analysis.Errors) > 0 {
-			fmt.Println(ui.FormatWarning(fmt.Sprintf("\nDetected %d potential issues:", len(analysis.Errors))))
-			for i, err := range analysis.Errors {
-				if i >= 3 {
-					break // Show top 3
-				}
-				fmt.Printf("  %d. %s: %s\n", i+1, ui.FormatHighlight(err.Pattern), err.Message[:min(80, len(err.Message))])
-			}
-		}
-
-		suggestions := o.analyzer.GetTopSuggestions(analysis, 3)
-		if len(suggestions) > 0 {
-			fmt.Println(ui.FormatInfo("\n💡 Quick Suggestions:"))
-			for i, suggestion := range suggestions {
-				fmt.Printf("  %d. %s\n", i+1, suggestion)
-			}
-		}
-		fmt.Println()
+	if cached != nil {
+		ghClient.SetWorkflowPaths(cached.WorkflowPaths)
 	}
+	o.github = ghClient
+	o.logger.SetField("repo", ghClient.GetRepository().FullName)
 
-	// Step 3: Get file content
-	fileContent, err := o.github.GetWorkflowFileContent(selected.Path)
-	if err != nil {
-		o.logger.Warn("Failed to fetch remote file content: %v", err)
-		fileContent = "[Remote file not accessible]"
-	}
+	o.cached = cached
+	o.refreshed = make(chan *repoMetadata, 1)
+	go o.refreshMetadata(dir, cached != nil)
+	return nil
+}
 
-	// Step 4: AI Diagnosis
-	fmt.Println(ui.FormatInfo("Consulting AI for diagnosis..."))
-	diagnosisReq := &copilot.DiagnosisRequest{
-		ErrorLogs:      logs,
-		CurrentFile:    selected.Path,
-		FileContent:    fileContent,
-		AvailableFiles: workflowFiles,
-		WorkflowPath:   selected.Path,
+// connectAI initializes the client of the configured AI provider on first use. It is a no-op in
+// rules-only mode, where the AI is never consulted.
+func (o *Orchestrator) connectAI() error {
+	if o.copilot != nil || o.config.RulesOnly {
+		return nil
 	}
-
-	diagnosis, err := o.copilot.DiagnoseAndFix(diagnosisReq)
+	copilotClient, err := copilot.NewClient(o.ctx, o.config, o.logger)
 	if err != nil {
-		return fmt.Errorf("AI diagnosis failed: %w", err)
-	}
-
-	// Display results
-	o.displayDiagnosisResults(diagnosis, selected.Path)
-
-	// Step 5: Apply fix if available
-	if diagnosis.FixedContent != "" && diagnosis.Confidence != "HEALTHY" {
-		return o.applyFix(diagnosis)
+		return fmt.Errorf("failed to initialize %s AI provider: %w", o.config.AI.Provider, err)
 	}
-
-	fmt.Println(ui.FormatInfo("No actionable fix required"))
+	o.copilot = timedDiagnoser{Diagnoser: copilotClient, provider: o.config.AI.Provider}
 	return nil
 }
 
-// displayDiagnosisResults shows the diagnosis results
-func (o *Orchestrator) displayDiagnosisResults(diagnosis *copilot.DiagnosisResult, originalPath string) {
-	fmt.Println("\n" + ui.FormatHeader("━━━━━━━━━━━━━━ DIAGNOSIS REPORT ━━━━━━━━━━━━━━\n"))
+// sessionLogDir is the subdirectory of the cache directory holding the
+// session logs of log_file, of which the newest maxSessionLogs are kept
+const (
+	sessionLogDir  = "logs"
+	maxSessionLogs = 20
+)
 
-	// Target redirection?
-	if diagnosis.TargetFile != originalPath {
-		fmt.Println(ui.FormatWarning("🎯 Target Redirection Detected"))
-		fmt.Printf("   User Selected: %s\n", ui.FormatDim(originalPath))
-		fmt.Printf("   AI Identified: %s\n", ui.FormatHighlight(diagnosis.TargetFile))
-		fmt.Println()
-	}
+// SetOperation names the command being run in the log entries
+func (o *Orchestrator) SetOperation(name string) {
+	o.logger.SetField("operation", name)
+}
 
-	// Confidence
-	confidenceStyle := ui.FormatSuccess
-	if diagnosis.Confidence == "MEDIUM" {
-		confidenceStyle = ui.FormatWarning
-	} else if diagnosis.Confidence == "LOW" {
-		confidenceStyle = ui.FormatError
-	}
-	fmt.Printf("Confidence: %s\n\n", confidenceStyle(diagnosis.Confidence))
+// Output formats
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputSARIF = "sarif"
+)
 
-	// Explanation
-	fmt.Println(ui.FormatHeader("Root Cause:"))
-	fmt.Println(wrapText(diagnosis.Explanation, 80))
-	fmt.Println()
+// maxAutoTargetDistance is the largest edit distance at which a missing fix
+// target is mapped to the closest workflow without asking
+const maxAutoTargetDistance = 3
+
+// Options controls a single command invocation
+type Options struct {
+	RunID  int64  // Analyze this run directly instead of showing the selector
+	Yes    bool   // Never prompt; auto-select the latest failure and apply fixes
+	Output string // OutputText or OutputJSON; fix accepts any report format and defaults to the configured one
+	All    bool   // Diagnose every failed run of the latest commit and apply the fixes together
+
+	CreateBranch bool // Commit an applied fix to sentinel/fix-<run-id> and push it
+	Watch        bool // Re-run the workflow after patching and watch the result
+	NoPrompt     bool // Never prompt, but only propose fixes instead of applying them
+	NoCache      bool // Ask the AI even when an earlier diagnosis of the run is cached
+	Comment      bool // Post the diagnosis on the run's pull request or commit without asking
+	Issue        bool // File an issue for a failure that cannot be fixed without asking
+	Categories   []string // Failure categories to diagnose, list or watch; empty selects all
+	Runs         github.RunFilter // Which runs discovery looks at for failures
+	Org          string           // Organization whose repositories scan looks at instead of one repository
+	Repos        github.RepoFilter // Which repositories of Org are scanned
+	MetricsAddr  string           // Address watch serves Prometheus metrics on; empty serves none
+	DryRun       bool             // Write fixes to ProposalsDir instead of applying them
 }
 
-// applyFix applies the suggested fix
-func (o *Orchestrator) applyFix(diagnosis *copilot.DiagnosisResult) error {
-	fmt.Println(ui.FormatHeader("━━━━━━━━━━━━━━ PROPOSED FIX ━━━━━━━━━━━━━━\n"))
+// interactive reports whether prompts may be shown. Machine-readable output
+// owns stdout, so it is never interactive.
+func (o *Orchestrator) interactive() bool {
+	format, err := o.format()
+	return !o.opts.Yes && !o.opts.NoPrompt && err == nil && !format.Stdout && ui.IsTerminal()
+}
 
-	// Show diff preview
-	diff, err := o.patcher.PreviewDiff(diagnosis.TargetFile, diagnosis.FixedContent)
-	if err != nil {
-		o.logger.Warn("Could not generate diff preview: %v", err)
-	} else {
-		// Show first 15 lines of diff
-		lines := strings.Split(diff, "\n")
-		previewLines := lines
-		if len(lines) > 15 {
-			previewLines = lines[:15]
-		}
-		for _, line := range previewLines {
-			if strings.HasPrefix(line, "+") {
-				fmt.Println(ui.FormatSuccess(line))
-			} else if strings.HasPrefix(line, "-") {
-				fmt.Println(ui.FormatError(line))
-			} else {
-				fmt.Println(ui.FormatDim(line))
-			}
-		}
-		if len(lines) > 15 {
-			fmt.Println(ui.FormatDim(fmt.Sprintf("... (%d more lines)", len(lines)-15)))
-		}
-		fmt.Println()
+// format returns the report format of the invocation
+func (o *Orchestrator) format() (report.Format, error) {
+	if o.opts.Output == "" {
+		return report.Lookup(o.config.Output)
 	}
+	return report.Lookup(o.opts.Output)
+}
 
-	// Confirm with user
-	confirmed, err := ui.ShowConfirmation(
-		fmt.Sprintf("Apply patch to %s?", diagnosis.TargetFile),
-		"A backup will be created automatically",
-	)
-	if err != nil {
-		return fmt.Errorf("confirmation dialog failed: %w", err)
+// newAnalyzer builds the log analyzer with built-in and user error patterns
+func newAnalyzer(cfg *config.Config, log *logger.Logger) (*analyzer.Analyzer, error) {
+	a := analyzer.NewAnalyzer(log)
+	files := []string{cfg.PatternsFile}
+	if cfg.Shared != "" {
+		files = []string{filepath.Join(cfg.SharedDir, patternsFileName), cfg.PatternsFile}
 	}
-
-	if !confirmed {
-		fmt.Println(ui.FormatDim("Patch cancelled by user"))
-		return nil
+	for _, file := range files {
+		n, err := a.LoadPatternsFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load error patterns: %w", err)
+		}
+		if n > 0 {
+			log.Debug("Loaded %d error patterns from %s", n, file)
+		}
 	}
+	return a, nil
+}
 
-	// Apply patch
-	fmt.Println(ui.FormatInfo("Applying patch..."))
-	patchReq := &patcher.PatchRequest{
-		FilePath:    diagnosis.TargetFile,
-		NewContent:  diagnosis.FixedContent,
-		ValidateYAML: true,
+// newFixer builds the deterministic fixer with built-in and user recipes
+func newFixer(cfg *config.Config, log *logger.Logger) (*fixer.Fixer, error) {
+	recipes := fixer.NewRegistry()
+	files := []string{cfg.RecipesFile}
+	if cfg.Shared != "" {
+		files = []string{filepath.Join(cfg.SharedDir, recipesFileName), cfg.RecipesFile}
 	}
-
-	result, err := o.patcher.Apply(patchReq)
-	if err != nil {
-		return fmt.Errorf("failed to apply patch: %w", err)
+	for _, file := range files {
+		n, err := recipes.LoadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fix recipes: %w", err)
+		}
+		if n > 0 {
+			log.Debug("Loaded %d fix recipes from %s", n, file)
+		}
 	}
+	return fixer.NewFixer(recipes, log), nil
+}
 
-	// Success!
-	fmt.Println()
-	fmt.Println(ui.FormatSuccess(fmt.Sprintf("✓ %s patched successfully!", diagnosis.TargetFile)))
-	if result.BackupPath != "" {
-		fmt.Println(ui.FormatDim(fmt.Sprintf("  Backup: %s", result.BackupPath)))
+// convertToUIItems converts workflow runs to UI items
+func (o *Orchestrator) convertToUIItems(runs []*github.WorkflowRun) []ui.WorkflowItem {
+	var items []ui.WorkflowItem
+	for _, run := range runs {
+		items = append(items, ui.WorkflowItem{
+			ID:          run.ID,
+			TitleText:   run.DisplayTitle,
+			DescText:    fmt.Sprintf("Run #%d • %s • %s", run.RunNumber, run.Event, run.UpdatedAt.Format("Jan 02, 15:04")),
+			Status:      run.Status,
+			Conclusion:  run.Conclusion,
+			Path:        run.WorkflowPath,
+			Branch:      run.HeadBranch,
+			Icon:        ui.Icon(ui.ConclusionSeverity(run.Status, run.Conclusion)),
+		})
 	}
-	fmt.Println(ui.FormatDim(fmt.Sprintf("  Changes: +%d -%d lines", result.LinesAdded, result.LinesRemoved)))
-	fmt.Println()
-	fmt.Println(ui.FormatInfo("💡 Next steps:"))
-	fmt.Println("  1. Review the changes")
-	fmt.Println("  2. Commit and push to trigger a new workflow run")
-	fmt.Println("  3. Monitor the results")
-
-	return nil
+	return items
 }
 
 // Helper functions
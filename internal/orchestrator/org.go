@@ -0,0 +1,171 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	sentinelContext "gh-sentinel/internal/context"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/github"
+)
+
+// orgWorkers bounds the repositories whose runs are fetched at once
+const orgWorkers = 8
+
+// maxOrgTitle bounds the run titles of the organization table
+const maxOrgTitle = 50
+
+// orgEntry is one failed run in the JSON output of an organization scan
+type orgEntry struct {
+	Repository string `json:"repository"`
+	scanEntry
+}
+
+// orgFailure is a failed run of a repository of the organization
+type orgFailure struct {
+	repo *sentinelContext.RepoContext
+	run  *github.WorkflowRun
+}
+
+// ScanOrg lists the failed runs of the latest commit of the default branch
+// of each repository of an organization that the filter selects. In a
+// terminal they are shown in a table, and picking one diagnoses it as
+// --repo would, without a checkout.
+func (o *Orchestrator) ScanOrg(opts Options) error {
+	o.opts = opts
+	client, err := github.NewClientFor(o.ctx, o.config, o.logger, &sentinelContext.RepoContext{
+		Owner:    opts.Org,
+		FullName: opts.Org,
+		Host:     o.config.GitHubHost,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize GitHub client: %w", err)
+	}
+	repos, err := client.ListOrgRepositories(opts.Org, opts.Repos)
+	if err != nil {
+		return fmt.Errorf("failed to list the repositories of %s: %w", opts.Org, err)
+	}
+	if opts.Output != OutputJSON {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Checking the default branch of %d repositories of %s...", len(repos), ui.FormatHighlight(opts.Org))))
+	}
+
+	failures, skipped := o.orgFailures(client, repos)
+	if opts.Output == OutputJSON {
+		entries := make([]orgEntry, 0, len(failures))
+		for _, f := range failures {
+			entries = append(entries, orgEntry{Repository: f.repo.FullName, scanEntry: scanEntry{
+				RunID:      f.run.ID,
+				RunNumber:  f.run.RunNumber,
+				Workflow:   f.run.WorkflowPath,
+				Title:      f.run.DisplayTitle,
+				Event:      f.run.Event,
+				Conclusion: f.run.Conclusion,
+				HeadSHA:    f.run.HeadSHA,
+				UpdatedAt:  f.run.UpdatedAt,
+			}})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if skipped > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not list the runs of %d repositories (run with --debug for details)", skipped)))
+	}
+	if len(failures) == 0 {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("No failures on the default branches of %d repositories ✨", len(repos)-skipped)))
+		return nil
+	}
+
+	headers := []string{"Repository", "Workflow", "Run", "Event", "Failed", "Title"}
+	rows := make([][]string, len(failures))
+	for i, f := range failures {
+		title := f.run.DisplayTitle
+		if r := []rune(title); len(r) > maxOrgTitle {
+			title = string(r[:maxOrgTitle-1]) + "…"
+		}
+		rows[i] = []string{
+			f.repo.FullName,
+			f.run.WorkflowPath,
+			fmt.Sprintf("#%d", f.run.RunNumber),
+			f.run.Event,
+			f.run.UpdatedAt.Format("Jan 02 15:04"),
+			title,
+		}
+	}
+	title := fmt.Sprintf("%d failed runs in %d repositories of %s", len(failures), countRepos(failures), opts.Org)
+	if !o.interactive() {
+		fmt.Fprintln(o.out, ui.FormatWarning(title+"\n"))
+		for _, f := range failures {
+			fmt.Fprintf(o.out, "  %s  %s  %s\n", ui.RunMark(f.run.Status, f.run.Conclusion), ui.FormatHighlight(f.repo.FullName), f.run.DisplayTitle)
+			fmt.Fprintf(o.out, "      %s\n", ui.FormatDim(fmt.Sprintf("Run #%d • ID %d • %s", f.run.RunNumber, f.run.ID, f.run.WorkflowPath)))
+		}
+		fmt.Fprintln(o.out)
+		fmt.Fprintln(o.out, ui.FormatInfo("Run `gh sentinel fix --repo <owner/name> --run-id <ID>` to diagnose a run"))
+		return nil
+	}
+
+	i, err := ui.ShowPickTable(title, headers, rows, "diagnose")
+	if err != nil || i < 0 {
+		return err
+	}
+	picked := failures[i]
+	o.config.Repository = picked.repo.FullName
+	return o.Fix(Options{RunID: picked.run.ID})
+}
+
+// orgFailures fetches the failed runs of the latest commit of each
+// repository's default branch, orgWorkers repositories at a time, in the
+// order of repos. It also returns how many repositories could not be read.
+func (o *Orchestrator) orgFailures(client *github.Client, repos []*sentinelContext.RepoContext) ([]orgFailure, int) {
+	found := make([][]*github.WorkflowRun, len(repos))
+	failed := make([]bool, len(repos))
+	queue := make(chan int, len(repos))
+	for i := range repos {
+		queue <- i
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(orgWorkers, len(repos)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				repo := repos[i]
+				runs, err := client.ForRepository(repo).GetFailedWorkflowRuns(10, github.RunFilter{Branch: repo.DefaultBranch})
+				if err != nil {
+					o.logger.Debug("Cannot list the runs of %s: %v", repo.FullName, err)
+					failed[i] = true
+					continue
+				}
+				found[i] = runs
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failures []orgFailure
+	skipped := 0
+	for i, repo := range repos {
+		if failed[i] {
+			skipped++
+			continue
+		}
+		for _, run := range o.withoutIgnoredWorkflows(found[i]) {
+			failures = append(failures, orgFailure{repo: repo, run: run})
+		}
+	}
+	return failures, skipped
+}
+
+// countRepos counts the repositories that have failures
+func countRepos(failures []orgFailure) int {
+	repos := make(map[string]bool)
+	for _, f := range failures {
+		repos[f.repo.FullName] = true
+	}
+	return len(repos)
+}
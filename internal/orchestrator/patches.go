@@ -0,0 +1,152 @@
+package orchestrator
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+
+	"gh-sentinel/pkg/patcher"
+)
+
+// patchRecord is a patch applied to a local workflow file, as stored in the
+// patch history
+type patchRecord struct {
+	PatchedAt    time.Time `json:"patched_at"`
+	Dir          string    `json:"dir,omitempty"` // Working directory the patch was applied in
+	Repository   string    `json:"repository,omitempty"`
+	RunID        int64     `json:"run_id,omitempty"`
+	Workflow     string    `json:"workflow,omitempty"`
+	File         string    `json:"file"`
+	Source       string    `json:"source,omitempty"` // "rules" or "ai"
+	Confidence   string    `json:"confidence,omitempty"`
+	Tag          string    `json:"tag,omitempty"`
+	BackupPath   string    `json:"backup_path,omitempty"`
+	LinesAdded   int       `json:"lines_added,omitempty"`
+	LinesRemoved int       `json:"lines_removed,omitempty"`
+	Rerun        string    `json:"rerun,omitempty"` // Conclusion of the watched re-run, e.g. "success"
+	Diff         string    `json:"diff,omitempty"`  // Unified diff from the backup to the patched file
+}
+
+// recordPatches appends the patches applied in the session to the patch
+// history, once the runs watched after them are known
+func (o *Orchestrator) recordPatches(recap *sessionRecap) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	var records []patchRecord
+	for _, report := range o.sessionReports() {
+		p, d := report.Patch, report.Diagnosis
+		if report.Status != StatusApplied || p == nil || d == nil {
+			continue
+		}
+		record := patchRecord{
+			PatchedAt:    recap.FinishedAt,
+			Dir:          dir,
+			Repository:   recap.Repository,
+			RunID:        report.RunID,
+			Workflow:     report.Workflow,
+			File:         d.Target,
+			Source:       d.Source,
+			Confidence:   d.Confidence,
+			Tag:          report.Tag,
+			BackupPath:   p.BackupPath,
+			LinesAdded:   p.LinesAdded,
+			LinesRemoved: p.LinesRemoved,
+		}
+		if p.BackupPath != "" {
+			if patchedAt, err := o.patcher.BackupTimestamp(p.BackupPath); err == nil {
+				record.PatchedAt = patchedAt
+			}
+			record.Diff = backupDiff(d.Target, p.BackupPath)
+		}
+		if report.Rerun != nil {
+			record.Rerun = report.Rerun.Conclusion
+			if record.Rerun == "" {
+				record.Rerun = report.Rerun.Status
+			}
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return
+	}
+	if err := appendPatches(o.config.PatchesFile, records); err != nil {
+		o.logger.Warn("Could not record the applied patches: %v", err)
+	}
+}
+
+// sessionReports returns the per-run reports of the session, batches
+// flattened
+func (o *Orchestrator) sessionReports() []*Report {
+	var reports []*Report
+	for _, report := range o.session {
+		if len(report.Runs) > 0 {
+			reports = append(reports, report.Runs...)
+		} else if report.RunID != 0 {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// backupDiff returns the unified diff from a backup to the file it was
+// taken from, or nothing if either cannot be read
+func backupDiff(file, backupPath string) string {
+	before, err := os.ReadFile(backupPath)
+	if err != nil {
+		return ""
+	}
+	after, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return patcher.Unified(file, patcher.Diff(string(before), string(after)), false)
+}
+
+// appendPatches adds records to the patch history file
+func appendPatches(path string, records []patchRecord) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPatches returns the patches recorded in a working directory, oldest
+// first. A missing file has none; corrupt lines are skipped.
+func readPatches(path, dir string) ([]patchRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []patchRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record patchRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // Skip lines from interrupted writes
+		}
+		if record.Dir == dir {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/github"
+)
+
+// The first stages of analyzing a run overlap: the workflow file is fetched
+// while the job logs download, and each job's logs are analyzed as soon as
+// they arrive instead of after the slowest job.
+
+// jobAnalysis is a job's logs with the pattern analysis of them
+type jobAnalysis struct {
+	logs     *github.JobLogs
+	analysis *analyzer.Analysis
+}
+
+// fetchedContent is the content of a workflow file fetched in the
+// background, with the latest commit that changed it
+type fetchedContent struct {
+	content   string
+	err       error
+	commit    *github.FileCommit
+	commitErr error
+	pending   bool // The content is a fix proposed earlier in the batch
+}
+
+// fetchWorkflowContent fetches a workflow file in the background. Content
+// in pending replaces the remote file, which is then not fetched.
+func (o *Orchestrator) fetchWorkflowContent(path string, pending map[string]string) <-chan fetchedContent {
+	result := make(chan fetchedContent, 1)
+	if content, ok := pending[path]; ok {
+		result <- fetchedContent{content: content, pending: true}
+		return result
+	}
+	go func() {
+		var fetched fetchedContent
+		fetched.commit, fetched.commitErr = o.github.LatestFileCommit(path)
+		fetched.content, fetched.err = o.github.GetWorkflowFileContent(path)
+		result <- fetched
+	}()
+	return result
+}
+
+// workflowContent waits for the workflow file fetched by
+// fetchWorkflowContent, noting its version to catch changes pushed before
+// the fix is applied
+func (o *Orchestrator) workflowContent(path string, fetching <-chan fetchedContent) string {
+	fetched := <-fetching
+	if fetched.pending {
+		return fetched.content
+	}
+	o.recordFetched(path, fetched.commit, fetched.commitErr)
+	if fetched.err != nil {
+		o.logger.Warn("Failed to fetch remote file content: %v", fetched.err)
+		return remoteUnavailable
+	}
+	return fetched.content
+}
+
+// fetchJobLogs downloads the logs of a run's jobs while analyzing each
+// job's logs as they arrive. It returns the logs in the order of the run,
+// with the combined analysis.
+func (o *Orchestrator) fetchJobLogs(runID int64) ([]*github.JobLogs, *analyzer.Analysis, error) {
+	jobs, stream, err := o.github.StreamWorkflowJobLogs(runID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[int64]jobAnalysis, len(jobs))
+	for analyzed := range o.analyzeJobs(stream) {
+		byID[analyzed.logs.Job.ID] = analyzed
+		if len(jobs) > 1 {
+			o.logger.Debug("Analyzed the logs of %s (%d/%d)", analyzed.logs.Job.Name, len(byID), len(jobs))
+		}
+	}
+
+	logs := make([]*github.JobLogs, 0, len(jobs))
+	analyses := make([]*analyzer.Analysis, 0, len(jobs))
+	for _, job := range jobs {
+		logs = append(logs, byID[job.ID].logs)
+		analyses = append(analyses, byID[job.ID].analysis)
+	}
+	return logs, o.analyzer.Merge(analyses), nil
+}
+
+// analyzeJobs masks the secrets in each job's logs it receives and analyzes
+// them, sending the result on. The returned channel is closed after the
+// last job.
+func (o *Orchestrator) analyzeJobs(stream <-chan *github.JobLogs) <-chan jobAnalysis {
+	analyzed := make(chan jobAnalysis)
+	go func() {
+		defer close(analyzed)
+		for logs := range stream {
+			text := github.FormatJobLogs([]*github.JobLogs{logs}, o.config.MaxRawLogSize)
+			text, _ = o.redactor.Redact(text)
+			analyzed <- jobAnalysis{logs: logs, analysis: o.analyzer.AnalyzePart(text)}
+		}
+	}()
+	return analyzed
+}
@@ -0,0 +1,119 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+)
+
+// Files of a proposal directory, next to the fixed file itself
+const (
+	proposalFile     = "proposal.json"
+	proposalDiffFile = "fix.diff"
+	proposalBaseExt  = ".orig" // The file the fix was made from, when it existed
+)
+
+// proposal is a fix written by --dry-run instead of being applied, stored
+// under <ProposalsDir>/<owner>/<name>/<run-id>/
+type proposal struct {
+	Repository   string    `json:"repository"`
+	RunID        int64     `json:"run_id"`
+	Workflow     string    `json:"workflow,omitempty"`
+	Target       string    `json:"target"`                // File the fix is for, relative to the repository root
+	BaseSHA256   string    `json:"base_sha256,omitempty"` // Of the file the fix was made from; empty when the fix creates it
+	Source       string    `json:"source,omitempty"`      // "rules" or "ai"
+	Confidence   string    `json:"confidence"`
+	Explanation  string    `json:"explanation"`
+	SchemaIssues []string  `json:"schema_issues,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// proposalDir returns the directory of the proposal for a run
+func (o *Orchestrator) proposalDir(repository string, runID int64) string {
+	return filepath.Join(o.config.ProposalsDir, filepath.FromSlash(repository), strconv.FormatInt(runID, 10))
+}
+
+// saveProposal writes the fix of a run to its proposal directory: the fixed
+// file, the file it was made from, their unified diff and the diagnosis. An
+// earlier proposal for the run is replaced. base is the content the fix was
+// made from; created tells that the fix creates the file.
+func (o *Orchestrator) saveProposal(report *Report, diagnosis *copilot.DiagnosisResult, base string, created bool, issues []patcher.SchemaIssue) error {
+	dir := o.proposalDir(report.Repository, report.RunID)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("cannot replace the proposal in %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create the proposal directory: %w", err)
+	}
+
+	name := filepath.Base(diagnosis.TargetFile)
+	p := proposal{
+		Repository:  report.Repository,
+		RunID:       report.RunID,
+		Workflow:    report.Workflow,
+		Target:      filepath.ToSlash(diagnosis.TargetFile),
+		Confidence:  diagnosis.Confidence,
+		Explanation: diagnosis.Explanation,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if report.Diagnosis != nil {
+		p.Source = report.Diagnosis.Source
+	}
+	for _, issue := range issues {
+		p.SchemaIssues = append(p.SchemaIssues, issue.String())
+	}
+	files := map[string]string{
+		name:             diagnosis.FixedContent,
+		proposalDiffFile: patcher.Unified(p.Target, patcher.Diff(base, diagnosis.FixedContent), created),
+	}
+	if !created {
+		p.BaseSHA256 = contentSHA256(base)
+		files[name+proposalBaseExt] = base
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	files[proposalFile] = string(data) + "\n"
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			return fmt.Errorf("cannot write the proposal: %w", err)
+		}
+	}
+
+	report.Status = StatusProposed
+	report.Proposal = dir
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Proposed fix for %s written to %s (--dry-run)", diagnosis.TargetFile, dir)))
+	return nil
+}
+
+// localBase returns the content a fix of a local file is made from and
+// whether the fix creates the file
+func localBase(diagnosis *copilot.DiagnosisResult) (string, bool, error) {
+	if diagnosis.BaseContent != "" {
+		return diagnosis.BaseContent, false, nil
+	}
+	content, err := os.ReadFile(diagnosis.TargetFile)
+	if os.IsNotExist(err) {
+		return "", true, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("cannot read %s: %w", diagnosis.TargetFile, err)
+	}
+	return string(content), false, nil
+}
+
+// contentSHA256 returns the hex SHA-256 of a file's content
+func contentSHA256(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"gh-sentinel/internal/report"
+	"gh-sentinel/pkg/copilot"
+)
+
+// Report statuses
+const (
+	StatusClean          = report.StatusClean
+	StatusNoFix          = report.StatusNoFix
+	StatusProposed       = report.StatusProposed
+	StatusDeclined       = report.StatusDeclined
+	StatusTargetNotFound = report.StatusTargetNotFound
+	StatusApplied        = report.StatusApplied
+	StatusDisabled       = report.StatusDisabled
+	StatusInvalid        = report.StatusInvalid
+	StatusInterrupted    = report.StatusInterrupted
+	StatusAwaitingReview = report.StatusAwaitingReview
+	StatusFlaky          = report.StatusFlaky
+	StatusIgnored        = report.StatusIgnored
+	StatusError          = report.StatusError
+)
+
+// The report types live in the report package, next to the reporters that
+// render them
+type (
+	Report             = report.Report
+	ReportDiagnosis    = report.Diagnosis
+	ReportScript       = report.Script
+	ReportSkipped      = report.Skipped
+	ReportSecurity     = report.Security
+	ReportDetected     = report.Detected
+	ReportFailedStep   = report.FailedStep
+	ReportChange       = report.Change
+	ReportDisagreement = report.Disagreement
+	ReportPatch        = report.Patch
+	ReportFilePatch    = report.FilePatch
+	ReportRerun        = report.Rerun
+	ReportFlaky        = report.Flaky
+	ReportFlakyStep    = report.FlakyStep
+	ReportHook         = report.Hook
+)
+
+// recordDiagnosis stores a diagnosis in the report
+func (o *Orchestrator) recordDiagnosis(diagnosis *copilot.DiagnosisResult, source string, recipes []string) {
+	o.report.Diagnosis = &ReportDiagnosis{
+		Source:       source,
+		Recipes:      recipes,
+		Target:       diagnosis.TargetFile,
+		Confidence:   diagnosis.Confidence,
+		Explanation:  diagnosis.Explanation,
+		FixedContent: diagnosis.FixedContent,
+		Attempt:      diagnosis.Attempt,
+	}
+	for _, patch := range diagnosis.Patches {
+		o.report.Diagnosis.Further = append(o.report.Diagnosis.Further, patch.TargetFile)
+	}
+}
@@ -0,0 +1,143 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// remoteWorkflow returns the reusable workflow of another repository that a
+// failed job of the run executes: the run's own workflow when its path is
+// in another repository, or the one a job of fileContent calls when a
+// failed job belongs to that job. Jobs of a called workflow are named
+// "<caller> / <job>".
+func (o *Orchestrator) remoteWorkflow(path, fileContent string) (workflow.Remote, bool) {
+	if remote, ok := workflow.ParseRemote(path); ok {
+		return remote, true
+	}
+	calls := workflow.RemoteCalls(fileContent)
+	for _, failed := range o.report.Failed {
+		caller, _, ok := strings.Cut(failed.Job, " / ")
+		if !ok {
+			continue
+		}
+		for _, call := range calls {
+			if caller == call.Job || caller == call.Name {
+				return call.Remote, true
+			}
+		}
+	}
+	return workflow.Remote{}, false
+}
+
+// remoteContent fetches a reusable workflow of another repository at the
+// ref the run used
+func (o *Orchestrator) remoteContent(remote workflow.Remote) (string, bool) {
+	content, err := o.github.GetRemoteFileContent(remote.Repository, remote.Path, remote.Ref)
+	if err != nil {
+		o.logger.Warn("Failed to fetch %s: %v", remote, err)
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The failed job runs %s, which could not be read; diagnosing the calling workflow", remote)))
+		return "", false
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("The failed job runs %s; diagnosing that workflow", ui.FormatHighlight(remote.String()))))
+	return content, true
+}
+
+// proposeRemote shows the fix of a reusable workflow of another repository,
+// which is changed there rather than patched here, and offers to open it as
+// a pull request against that repository. Without prompts the pull request
+// is opened with --create-branch or open_pr.
+func (o *Orchestrator) proposeRemote(remote workflow.Remote, content string, diagnosis *copilot.DiagnosisResult) error {
+	o.report.Status = StatusProposed
+	diagnosis.TargetFile = remote.String()
+	if o.report.Diagnosis != nil {
+		o.report.Diagnosis.Target = remote.String()
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("The fix belongs in %s, where the workflow lives:", ui.FormatHighlight(remote.Repository))))
+	o.printDiff(patcher.Unified(remote.Path, patcher.Diff(content, diagnosis.FixedContent), false), 0)
+
+	open := o.opts.CreateBranch || o.config.OpenPR
+	if o.interactive() {
+		confirmed, err := ui.ShowConfirmation(
+			fmt.Sprintf("Open a pull request against %s?", remote.Repository),
+			fmt.Sprintf("The fix is committed to a new branch of %s with your gh credentials", remote.Repository),
+		)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		open = confirmed
+	}
+	if !open {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Apply it in %s, or pass --create-branch to open a pull request there", remote.Repository)))
+		return nil
+	}
+	return o.openRemotePull(remote, content, diagnosis)
+}
+
+// openRemotePull commits the fix of a reusable workflow to a new branch of
+// its repository and opens a pull request there. A fix made from a tag or a
+// SHA is carried over to the default branch.
+func (o *Orchestrator) openRemotePull(remote workflow.Remote, content string, diagnosis *copilot.DiagnosisResult) error {
+	base, err := o.github.RemoteBaseBranch(remote.Repository, remote.Ref)
+	if err != nil {
+		return err
+	}
+	consumer := o.github.GetRepository()
+	branch := fmt.Sprintf("%s%s-%d", fixBranchPrefix, consumer.Name, o.report.RunID)
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Committing the fix to %s of %s...", branch, remote.Repository)))
+	req := &patcher.PatchRequest{
+		FilePath:    remote.Path,
+		NewContent:  diagnosis.FixedContent,
+		BaseContent: content,
+		Validate:    true,
+	}
+	result, sha, err := o.commitRemoteFix(remote.Repository, branch, base, req, o.remoteCommitMessage(remote, diagnosis))
+	if err != nil {
+		return err
+	}
+	o.report.Patch = &ReportPatch{
+		Repository:   remote.Repository,
+		LinesAdded:   result.LinesAdded,
+		LinesRemoved: result.LinesRemoved,
+		Branch:       branch,
+		Commit:       sha,
+		Pushed:       true,
+	}
+
+	title := fmt.Sprintf("ci: fix %s for %s (run %d)", remote.Path, consumer.FullName, o.report.RunID)
+	pr, err := o.github.CreateRemotePullRequest(remote.Repository, branch, base, title, o.remotePullBody(remote, diagnosis))
+	if err != nil {
+		return fmt.Errorf("could not open a pull request for %s of %s: %w", branch, remote.Repository, err)
+	}
+	o.report.Status = StatusAwaitingReview
+	o.report.Patch.PullRequest = pr.Number
+	o.report.Patch.PullURL = pr.URL
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Opened pull request #%d in %s: %s", pr.Number, remote.Repository, pr.URL)))
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  %s was not changed", consumer.FullName)))
+	return nil
+}
+
+// remoteCommitMessage is the commit message of a fix of a reusable
+// workflow, naming the repository whose run failed
+func (o *Orchestrator) remoteCommitMessage(remote workflow.Remote, diagnosis *copilot.DiagnosisResult) string {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "ci: fix %s (run %d of %s)\n\n", remote.Path, o.report.RunID, o.github.GetRepository().FullName)
+	if explanation := strings.TrimSpace(diagnosis.Explanation); explanation != "" {
+		msg.WriteString(wrapText(explanation, 72))
+		msg.WriteString("\n\n")
+	}
+	fmt.Fprintf(&msg, "Generated by gh-sentinel from failed run %d of %s, which calls %s.\n", o.report.RunID, o.github.GetRepository().FullName, remote)
+	return msg.String()
+}
+
+// remotePullBody describes a fix of a reusable workflow in a pull request
+// of its repository, starting with the run of the calling repository
+func (o *Orchestrator) remotePullBody(remote workflow.Remote, diagnosis *copilot.DiagnosisResult) string {
+	consumer := o.github.GetRepository().FullName
+	return fmt.Sprintf("`%s` calls `%s` and failed in [run %d](%s).\n\n",
+		consumer, remote, o.report.RunID, o.github.RunURL(o.report.RunID)) + o.pullBody(diagnosis)
+}
@@ -0,0 +1,306 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/codeowners"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/git"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/patcher"
+)
+
+// maxReviewSessions is how many past sessions history searches for fixes
+// awaiting review
+const maxReviewSessions = 50
+
+// critical reports whether a workflow is designated critical. Fixes of
+// critical workflows are never applied without a second reviewer.
+func (o *Orchestrator) critical(target string) bool {
+	return matchWorkflow(o.config.CriticalWorkflows, target)
+}
+
+// matchWorkflow reports whether a workflow path matches one of patterns,
+// paths or globs. Patterns without a directory match the file name.
+func matchWorkflow(patterns []string, target string) bool {
+	target = strings.TrimPrefix(filepath.ToSlash(target), "./")
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(target)); ok && !strings.Contains(pattern, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// openRepo opens the git repository of the working directory
+func (o *Orchestrator) openRepo() (*git.Repo, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	repo, err := git.Open(cwd, o.logger)
+	if err != nil {
+		return nil, err
+	}
+	repo.SetContext(o.ctx)
+	return repo, nil
+}
+
+// reviewersFor returns who must approve a fix of target: the configured
+// reviewers, or else the file's owners in CODEOWNERS. The author is left out
+// because GitHub does not let authors review their own pull requests.
+func (o *Orchestrator) reviewersFor(repo *git.Repo, target, author string) ([]string, error) {
+	reviewers := o.config.Reviewers
+	if len(reviewers) == 0 {
+		owners, err := codeowners.Load(repo.Root())
+		if err != nil {
+			return nil, err
+		}
+		if owners != nil {
+			rel := target
+			if abs, err := filepath.Abs(target); err == nil {
+				if r, err := filepath.Rel(repo.Root(), abs); err == nil {
+					rel = r
+				}
+			}
+			reviewers = owners.Owners(rel)
+		}
+	}
+
+	var second []string
+	for _, reviewer := range reviewers {
+		if !strings.EqualFold(strings.TrimPrefix(reviewer, "@"), author) {
+			second = append(second, reviewer)
+		}
+	}
+	if len(second) == 0 {
+		return nil, errors.ValidationError("review_fix",
+			fmt.Sprintf("%s is a critical workflow but has no reviewer other than you; pass --reviewers or add an owner in CODEOWNERS", target))
+	}
+	return second, nil
+}
+
+// requestReview commits the fix of a critical workflow to its own branch and
+// opens a pull request that a second reviewer must approve. The checked-out
+// branch is never patched.
+func (o *Orchestrator) requestReview(req *patcher.PatchRequest, diagnosis *copilot.DiagnosisResult) error {
+	if o.report.RunID == 0 {
+		return fmt.Errorf("cannot name the fix branch without a run ID")
+	}
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+	author, err := o.github.CurrentUser()
+	if err != nil {
+		return err
+	}
+	repo, err := o.openRepo()
+	if err != nil {
+		return err
+	}
+
+	// Everything that can be checked is checked before touching the tree
+	target := diagnosis.TargetFile
+	reviewers, err := o.reviewersFor(repo, target, author)
+	if err != nil {
+		return err
+	}
+	modified, err := repo.Modified(target)
+	if err != nil {
+		return err
+	}
+	if modified {
+		return fmt.Errorf("%s has uncommitted changes; commit or stash them first, the fix is reviewed on its own branch", target)
+	}
+	base, err := repo.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	branch := fmt.Sprintf("%s%d", fixBranchPrefix, o.report.RunID)
+	if repo.BranchExists(branch) {
+		return fmt.Errorf("branch %s already exists; delete it or commit the fix manually", branch)
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("%s is critical: committing the fix to %s for review...", target, branch)))
+	if err := repo.CreateBranch(branch); err != nil {
+		return err
+	}
+	req.NoBackup = true // The base branch keeps the original
+	result, sha, commitErr := o.commitFix(repo, req, diagnosis)
+
+	// Back to the base branch, which never sees the patch, even after Ctrl-C
+	ctx, cancel := o.cleanupContext()
+	defer cancel()
+	repo.SetContext(ctx)
+	if err := repo.Checkout(base); err != nil {
+		return fmt.Errorf("the fix is on %s, but switching back to %s failed: %w", branch, base, err)
+	}
+	if commitErr != nil {
+		if err := repo.DeleteBranch(branch); err != nil {
+			o.logger.Warn("Could not delete %s: %v", branch, err)
+		}
+		return commitErr
+	}
+	repo.SetContext(o.ctx)
+	o.report.Patch = &ReportPatch{
+		LinesAdded:   result.LinesAdded,
+		LinesRemoved: result.LinesRemoved,
+		HunksSkipped: result.HunksSkipped,
+		Branch:       branch,
+		Commit:       sha,
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Pushing %s to origin...", branch)))
+	if err := repo.Push("origin", branch); err != nil {
+		return err
+	}
+	o.report.Patch.Pushed = true
+
+	pr, err := o.github.CreatePullRequest(branch, base, fmt.Sprintf("ci: fix %s (run %d)", target, o.report.RunID), o.reviewBody(diagnosis, reviewers))
+	if err != nil {
+		return err
+	}
+	o.report.Status = StatusAwaitingReview
+	o.report.Patch.PullRequest = pr.Number
+	o.report.Patch.PullURL = pr.URL
+	if err := o.github.RequestReviewers(pr.Number, reviewers); err != nil {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not request reviews from %s: %v", strings.Join(reviewers, ", "), err)))
+	} else {
+		o.report.Patch.Reviewers = reviewers
+	}
+
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Opened pull request #%d for review: %s", pr.Number, pr.URL)))
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Reviewers: %s", strings.Join(reviewers, ", "))))
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  %s was not changed on %s", target, base)))
+	fmt.Fprintln(o.out)
+	return nil
+}
+
+// commitFix applies a patch on the checked-out branch and commits it,
+// discarding the patch if the commit fails
+func (o *Orchestrator) commitFix(repo *git.Repo, req *patcher.PatchRequest, diagnosis *copilot.DiagnosisResult) (*patcher.PatchResult, string, error) {
+	result, err := o.patcher.Apply(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to apply patch: %w", err)
+	}
+	sha, err := repo.Commit(o.commitMessage(diagnosis), req.FilePath)
+	if err != nil {
+		if err := repo.Discard(req.FilePath); err != nil {
+			o.logger.Warn("Could not discard the patch of %s: %v", req.FilePath, err)
+		}
+		return nil, "", err
+	}
+	return result, sha, nil
+}
+
+// reviewBody describes a fix for its reviewers
+func (o *Orchestrator) reviewBody(diagnosis *copilot.DiagnosisResult, reviewers []string) string {
+	var b strings.Builder
+	b.WriteString(o.pullBody(diagnosis))
+	fmt.Fprintf(&b, "`%s` is a critical workflow, so this fix needs the approval of a second reviewer (%s) before it is merged.\n",
+		diagnosis.TargetFile, strings.Join(reviewers, ", "))
+	return b.String()
+}
+
+// pullBody describes a fix in a pull request: the run, the explanation and
+// what each change addresses
+func (o *Orchestrator) pullBody(diagnosis *copilot.DiagnosisResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Fix proposed by gh-sentinel for failed run %d", o.report.RunID)
+	if o.report.Workflow != "" {
+		fmt.Fprintf(&b, " of `%s`", o.report.Workflow)
+	}
+	b.WriteString(".\n\n")
+	if explanation := strings.TrimSpace(diagnosis.Explanation); explanation != "" {
+		b.WriteString(explanation + "\n\n")
+	}
+	if d := o.report.Diagnosis; d != nil && len(d.Changes) > 0 {
+		b.WriteString("**What each change addresses**\n\n")
+		for _, change := range d.Changes {
+			addresses := "no finding explains this change"
+			if len(change.Addresses) > 0 {
+				addresses = strings.Join(change.Addresses, "; ")
+			}
+			fmt.Fprintf(&b, "- `%s`: %s\n", change.Hunk, addresses)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// printReviews lists fixes of critical workflows and of reusable workflows
+// of other repositories opened for review, with the current state of their
+// pull requests when GitHub is reachable
+func (o *Orchestrator) printReviews() {
+	recaps, err := readRecaps(o.config.HistoryFile, maxReviewSessions)
+	if err != nil {
+		return
+	}
+	var repositories []string
+	var reviews []sessionRun
+	for _, recap := range recaps {
+		for _, run := range recap.Runs {
+			if run.PullRequest != 0 && run.Status == StatusAwaitingReview && o.selectedTag(run.Tag) {
+				repositories = append(repositories, recap.Repository)
+				reviews = append(reviews, run)
+			}
+		}
+	}
+	if len(reviews) == 0 {
+		return
+	}
+
+	var repository string
+	if err := o.connectGitHub(); err != nil {
+		o.logger.Debug("Pull request states unavailable: %v", err)
+	} else {
+		repository = o.github.GetRepository().FullName
+	}
+
+	fmt.Fprintln(o.out)
+	fmt.Fprintln(o.out, ui.FormatHeader("Fixes awaiting review"))
+	for i, run := range reviews {
+		state := ui.Format(ui.SeverityPending, "awaiting review")
+		if run.Repository != "" && repository != "" {
+			// A fix of a reusable workflow, opened in its own repository
+			if pr, err := o.github.GetRemotePullRequest(run.Repository, run.PullRequest); err != nil {
+				o.logger.Debug("Could not get pull request #%d of %s: %v", run.PullRequest, run.Repository, err)
+			} else {
+				state = reviewState(pr)
+			}
+		} else if repositories[i] == repository {
+			if pr, err := o.github.GetPullRequest(run.PullRequest); err != nil {
+				o.logger.Debug("Could not get pull request #%d: %v", run.PullRequest, err)
+			} else {
+				state = reviewState(pr)
+			}
+		}
+		fmt.Fprintf(o.out, "  %s  %s  %s\n", ui.FormatHighlight(run.Target), state, ui.FormatDim(run.PullURL))
+	}
+}
+
+// reviewState describes where a pull request stands
+func reviewState(pr *github.PullRequest) string {
+	switch {
+	case pr.Merged:
+		return ui.Format(ui.SeveritySuccess, "merged")
+	case pr.State == "closed":
+		return ui.Format(ui.SeverityNeutral, "closed without merging")
+	case pr.Review == github.ReviewApproved:
+		return ui.Format(ui.SeveritySuccess, "approved, not merged yet")
+	case pr.Review == github.ReviewChangesRequested:
+		return ui.Format(ui.SeverityWarning, "changes requested")
+	}
+	return ui.Format(ui.SeverityPending, "awaiting review")
+}
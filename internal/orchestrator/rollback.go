@@ -0,0 +1,264 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// backupChoice is a backup the rollback picker offers
+type backupChoice struct {
+	file    string
+	backup  string
+	takenAt time.Time
+}
+
+// Rollback restores filePath from a backup after showing what it changes.
+// Without an explicit backup the user picks one in a terminal, newest
+// first, and the most recent one is used otherwise. Without filePath the
+// backups of every workflow file are offered, or the file the backup was
+// taken from is restored. A backup taken for a fix of several files
+// restores all of them, unless single is set. With remove the backup is
+// deleted once restored.
+func (o *Orchestrator) Rollback(opts Options, filePath, backupPath string, remove, single bool) error {
+	o.opts = opts
+
+	if filePath == "" && backupPath != "" {
+		filePath = o.patcher.OriginalPath(backupPath)
+		if filePath == backupPath {
+			return fmt.Errorf("cannot tell which file %s is a backup of; name the workflow file too", backupPath)
+		}
+	}
+
+	if backupPath == "" {
+		choices, err := o.backupChoices(filePath)
+		if err != nil {
+			return err
+		}
+		if len(choices) == 0 {
+			if filePath == "" {
+				return fmt.Errorf("no backups found in %s", workflow.Dir)
+			}
+			return fmt.Errorf("no backups found for %s", filePath)
+		}
+		choice := choices[0]
+		switch {
+		case o.interactive() && (len(choices) > 1 || filePath == ""):
+			picked, err := o.pickBackup(choices)
+			if err != nil {
+				return err
+			}
+			if picked < 0 {
+				fmt.Fprintln(o.out, ui.FormatDim("Rollback cancelled by user"))
+				return nil
+			}
+			choice = choices[picked]
+		case filePath == "":
+			return fmt.Errorf("rollback expects a workflow file when it cannot ask which backup to restore, e.g. gh sentinel rollback .github/workflows/ci.yml")
+		}
+		filePath, backupPath = choice.file, choice.backup
+	}
+
+	if group := o.patcher.BackupGroup(backupPath); len(group) > 1 && !single {
+		return o.rollbackGroup(group, remove)
+	}
+
+	diff, added, removed, err := rollbackDiff(filePath, backupPath)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("%s already matches %s; nothing to restore", filePath, backupPath)))
+		return nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Restoring %s from %s", ui.FormatHighlight(filePath), backupPath)))
+
+	switch {
+	case opts.Yes:
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Changes: +%d -%d lines", added, removed)))
+	case !o.interactive():
+		return fmt.Errorf("refusing to roll back without confirmation; pass --yes")
+	default:
+		if err := ui.ShowDiff(fmt.Sprintf("Restoring %s from %s", filePath, filepath.Base(backupPath)), diff); err != nil {
+			return err
+		}
+		confirmed, err := ui.ShowConfirmation(
+			fmt.Sprintf("Restore %s?", filePath),
+			fmt.Sprintf("The current content will be overwritten by the backup (+%d -%d lines)", added, removed),
+		)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Fprintln(o.out, ui.FormatDim("Rollback cancelled by user"))
+			return nil
+		}
+	}
+
+	if err := o.patcher.Rollback(filePath, backupPath); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s restored", filePath)))
+	if remove {
+		if err := o.patcher.RemoveBackup(backupPath); err != nil {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not delete the backup: %v", err)))
+		} else {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Deleted the backup %s", backupPath)))
+		}
+	}
+	return nil
+}
+
+// rollbackGroup restores every file of a fix that spans several from the
+// backups taken together for it, after showing what each restore changes
+func (o *Orchestrator) rollbackGroup(group []patcher.GroupBackup, remove bool) error {
+	var files []string
+	var diffs []ui.FileDiff
+	var added, removed int
+	for _, backup := range group {
+		files = append(files, backup.File)
+		diff, a, r, err := rollbackDiff(backup.File, backup.Backup)
+		if err != nil {
+			return err
+		}
+		if diff != "" {
+			diffs = append(diffs, ui.FileDiff{File: backup.File, Diff: diff})
+			added, removed = added+a, removed+r
+		}
+	}
+	if len(diffs) == 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("%s already match their backups; nothing to restore", strings.Join(files, ", "))))
+		return nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Restoring the %d files of the fix: %s", len(group), ui.FormatHighlight(strings.Join(files, ", ")))))
+
+	switch {
+	case o.opts.Yes:
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Changes: +%d -%d lines", added, removed)))
+	case !o.interactive():
+		return fmt.Errorf("refusing to roll back without confirmation; pass --yes")
+	default:
+		confirmed, err := ui.ShowFileDiffs(
+			fmt.Sprintf("Restore the %d files of the fix?", len(group)),
+			fmt.Sprintf("The current content of each file will be overwritten by its backup (+%d -%d lines); --single restores only one", added, removed),
+			diffs,
+		)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Fprintln(o.out, ui.FormatDim("Rollback cancelled by user"))
+			return nil
+		}
+	}
+
+	if err := o.patcher.RollbackGroup(group); err != nil {
+		return err
+	}
+
+	for _, backup := range group {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s restored", backup.File)))
+		if !remove {
+			continue
+		}
+		if err := o.patcher.RemoveBackup(backup.Backup); err != nil {
+			fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not delete the backup: %v", err)))
+		} else {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Deleted the backup %s", backup.Backup)))
+		}
+	}
+	return nil
+}
+
+// backupChoices returns the backups of a workflow file, or of every
+// workflow file when filePath is empty, newest first
+func (o *Orchestrator) backupChoices(filePath string) ([]backupChoice, error) {
+	files := []string{filePath}
+	if filePath == "" {
+		var err error
+		files, err = filepath.Glob(filepath.Join(workflow.Dir, "*"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflow files: %w", err)
+		}
+	}
+
+	var choices []backupChoice
+	seen := make(map[string]bool)
+	for _, file := range files {
+		original := o.patcher.OriginalPath(file)
+		if seen[original] {
+			continue
+		}
+		seen[original] = true
+
+		backups, err := o.patcher.ListBackups(original)
+		if err != nil {
+			return nil, err
+		}
+		for _, backup := range backups {
+			// Backups without a timestamp sort last
+			takenAt, _ := o.patcher.BackupTimestamp(backup)
+			choices = append(choices, backupChoice{file: original, backup: backup, takenAt: takenAt})
+		}
+	}
+
+	// Backup names embed a sortable timestamp, which breaks ties
+	sort.Slice(choices, func(i, j int) bool {
+		if !choices[i].takenAt.Equal(choices[j].takenAt) {
+			return choices[i].takenAt.After(choices[j].takenAt)
+		}
+		return choices[i].backup > choices[j].backup
+	})
+	return choices, nil
+}
+
+// pickBackup asks which backup to restore, or returns -1 if the user
+// cancelled
+func (o *Orchestrator) pickBackup(choices []backupChoice) (int, error) {
+	options := make([]string, len(choices))
+	for i, choice := range choices {
+		when := "unknown time"
+		if !choice.takenAt.IsZero() {
+			when = choice.takenAt.Format("Jan 02 2006, 15:04:05")
+		}
+		changes := "same as the current file"
+		if diff, added, removed, err := rollbackDiff(choice.file, choice.backup); err != nil {
+			changes = "unreadable"
+		} else if diff != "" {
+			changes = fmt.Sprintf("+%d -%d lines against the current file", added, removed)
+		}
+		options[i] = fmt.Sprintf("%s  %s  (%s)", when, choice.file, changes)
+	}
+	picked, err := ui.ShowChoice("Which backup should be restored?", "Newest first. The diff is shown before anything is restored.", options)
+	if err != nil {
+		return -1, fmt.Errorf("backup picker failed: %w", err)
+	}
+	return picked, nil
+}
+
+// rollbackDiff returns the unified diff that restoring a backup makes to
+// the current file, with the lines it adds and removes. A missing file is
+// diffed as empty.
+func rollbackDiff(filePath, backupPath string) (string, int, int, error) {
+	backup, err := os.ReadFile(backupPath)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("failed to read backup: %w", err)
+	}
+	current, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", 0, 0, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	hunks := patcher.Diff(string(current), string(backup))
+	added, removed := patcher.CountChanges(hunks)
+	return patcher.Unified(filePath, hunks, len(current) == 0), added, removed, nil
+}
@@ -0,0 +1,216 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/report"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/github"
+	"gh-sentinel/pkg/workflow"
+)
+
+// scanEntry is one failed run in the JSON scan output
+type scanEntry struct {
+	RunID      int64     `json:"run_id"`
+	RunNumber  int       `json:"run_number"`
+	Workflow   string    `json:"workflow"`
+	Title      string    `json:"title"`
+	Event      string    `json:"event"`
+	Conclusion string    `json:"conclusion"`
+	HeadSHA    string    `json:"head_sha"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Tag        string    `json:"tag,omitempty"` // Failure category, with --category
+}
+
+// Scan lists the failed workflow runs of the latest commit without
+// diagnosing or changing anything. With --category the logs of each run are
+// fetched to classify it, and only the runs of those categories are listed.
+// --output sarif analyzes the logs of every run and scans the workflow files
+// for security issues, and writes what both found as SARIF for code
+// scanning.
+func (o *Orchestrator) Scan(opts Options) error {
+	o.opts = opts
+	// SARIF goes to stdout; everything meant for humans moves to stderr
+	if opts.Output == OutputSARIF {
+		o.out = os.Stderr
+	}
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+
+	runs, err := o.github.GetFailedWorkflowRuns(10, opts.Runs)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow runs: %w", err)
+	}
+	runs = o.withoutIgnoredWorkflows(runs)
+	tags := make(map[int64]string)
+	analyses := make(map[int64]*analyzer.Analysis)
+	if len(opts.Categories) > 0 || opts.Output == OutputSARIF {
+		var selected []*github.WorkflowRun
+		for _, run := range runs {
+			_, analysis, err := o.fetchJobLogs(run.ID)
+			if err != nil {
+				o.logger.Debug("Cannot analyze run #%d: %v", run.ID, err)
+			} else {
+				analyses[run.ID], tags[run.ID] = analysis, analysis.Tag
+			}
+			if len(opts.Categories) == 0 || o.selectedTag(tags[run.ID]) {
+				selected = append(selected, run)
+			}
+		}
+		runs = selected
+	}
+
+	if opts.Output == OutputSARIF {
+		return o.scanSARIF(runs, analyses)
+	}
+	if opts.Output == OutputJSON {
+		entries := make([]scanEntry, 0, len(runs))
+		for _, run := range runs {
+			entries = append(entries, scanEntry{
+				RunID:      run.ID,
+				RunNumber:  run.RunNumber,
+				Workflow:   run.WorkflowPath,
+				Title:      run.DisplayTitle,
+				Event:      run.Event,
+				Conclusion: run.Conclusion,
+				HeadSHA:    run.HeadSHA,
+				UpdatedAt:  run.UpdatedAt,
+				Tag:        tags[run.ID],
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	repo := o.github.GetRepository()
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Repository: %s", ui.FormatHighlight(repo.FullName))))
+
+	if len(runs) == 0 && len(opts.Categories) > 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("No failed runs of category %s", strings.Join(opts.Categories, " or "))))
+		return nil
+	}
+	if len(runs) == 0 {
+		fmt.Fprintln(o.out, ui.FormatSuccess("System Clean. No failures detected! ✨"))
+		o.printDisabled()
+		return nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Found %d failed workflow runs\n", len(runs))))
+	for _, item := range o.convertToUIItems(runs) {
+		fmt.Fprintf(o.out, "  %s  %s  %s\n", ui.RunMark(item.Status, item.Conclusion), ui.FormatHighlight(item.TitleText), ui.FormatDim(tagOf(tags[item.ID])))
+		fmt.Fprintf(o.out, "      %s\n", ui.FormatDim(fmt.Sprintf("%s • ID %d • %s", item.DescText, item.ID, item.Path)))
+	}
+	fmt.Fprintln(o.out)
+	o.printDisabled()
+	fmt.Fprintln(o.out, ui.FormatInfo("Run `gh sentinel fix --run-id <ID>` to diagnose a run"))
+	return nil
+}
+
+// scanSARIF writes the log patterns matched in each failed run, and the
+// security findings of the workflow files, as SARIF. The files are those of
+// the checkout, or of the default branch with --repo.
+func (o *Orchestrator) scanSARIF(runs []*github.WorkflowRun, analyses map[int64]*analyzer.Analysis) error {
+	repo := o.github.GetRepository()
+	outcome := &report.Outcome{
+		Report:  &Report{Repository: repo.FullName},
+		Version: o.config.Version,
+	}
+	for _, run := range runs {
+		runReport := &Report{
+			Repository: repo.FullName,
+			RunID:      run.ID,
+			Workflow:   run.WorkflowPath,
+			Status:     StatusNoFix,
+		}
+		if analysis := analyses[run.ID]; analysis != nil {
+			runReport.Category, runReport.Tag = analysis.Category, analysis.Tag
+			for _, detected := range analysis.Errors {
+				runReport.Detected = append(runReport.Detected, ReportDetected{
+					Pattern:  detected.Pattern,
+					Category: detected.Category,
+					Tag:      detected.Tag,
+					Severity: detected.Severity,
+					Message:  detected.Message,
+					Step:     detected.Step,
+				})
+			}
+		}
+		outcome.Report.Runs = append(outcome.Report.Runs, runReport)
+	}
+
+	files, err := o.scannedFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range sortedPaths(files) {
+		found, err := scanSecurity(path, files[path])
+		if err != nil {
+			o.logger.Warn("Skipping the security scan of %s: %v", path, err)
+			continue
+		}
+		outcome.Security = append(outcome.Security, found...)
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("%d failed runs and %d security findings in %d files", len(runs), len(outcome.Security), len(files))))
+	return report.SARIF{}.Render(os.Stdout, outcome)
+}
+
+// scannedFiles reads the workflow files and local actions the security
+// scan covers, by path
+func (o *Orchestrator) scannedFiles() (map[string]string, error) {
+	files := make(map[string]string)
+	if o.config.Repository != "" {
+		paths, err := o.github.ListWorkflowFiles()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflow files: %w", err)
+		}
+		for _, path := range paths {
+			content, err := o.github.GetWorkflowFileContent(path)
+			if err != nil {
+				o.logger.Warn("Could not fetch %s: %v", path, err)
+				continue
+			}
+			files[workflow.FilePath(path)] = content
+		}
+		return files, nil
+	}
+
+	paths, err := localPaths()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		files[filepath.ToSlash(path)] = string(data)
+	}
+	return files, nil
+}
+
+// printDisabled reminds the user of workflows parked with `disable`
+func (o *Orchestrator) printDisabled() {
+	disabled, err := o.disabledWorkflows()
+	if err != nil {
+		o.logger.Warn("Could not list disabled workflows: %v", err)
+		return
+	}
+	if len(disabled) == 0 {
+		return
+	}
+
+	fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%d disabled workflows", len(disabled))))
+	for _, wf := range disabled {
+		fmt.Fprintf(o.out, "  ⏸  %s  %s\n", ui.FormatHighlight(wf.Name), ui.FormatDim(wf.Path))
+	}
+	fmt.Fprintln(o.out)
+}
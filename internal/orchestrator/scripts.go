@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/scriptcheck"
+	"gh-sentinel/pkg/workflow"
+)
+
+// maxScriptFindings bounds how many script findings are printed
+const maxScriptFindings = 8
+
+// checkScripts checks the workflow's run: scripts, correlates the findings
+// with shell errors in the logs and records them in the report
+func (o *Orchestrator) checkScripts(path, content, logs string) {
+	scripts, err := workflow.Scripts(path, content)
+	if err != nil || len(scripts) == 0 {
+		return
+	}
+
+	findings := scriptcheck.Correlate(o.scripts.Check(scripts), scripts, logs)
+	if len(findings) == 0 {
+		return
+	}
+
+	o.report.Scripts = nil
+	for _, f := range findings {
+		o.report.Scripts = append(o.report.Scripts, ReportScript{
+			Job:          f.Job,
+			Step:         f.Step,
+			Line:         f.Line,
+			Code:         f.Code,
+			Level:        f.Level,
+			Message:      f.Message,
+			Source:       f.Source,
+			InFailedStep: f.InFailedStep,
+		})
+	}
+
+	checker := "built-in checks"
+	if o.scripts.Source() == scriptcheck.SourceShellcheck {
+		checker = "shellcheck"
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("🐚 Script analysis of run: steps (%s):", checker)))
+	for i, f := range findings {
+		if i >= maxScriptFindings {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  ... (%d more)", len(findings)-i)))
+			break
+		}
+		switch {
+		case f.Source == scriptcheck.SourceLog:
+			fmt.Fprintf(o.out, "  %s %s\n", ui.Mark(ui.SeverityError), f)
+		case f.InFailedStep:
+			fmt.Fprintf(o.out, "  %s %s\n", ui.Mark(ui.SeverityWarning), f)
+		default:
+			fmt.Fprintf(o.out, "  • %s\n", ui.FormatDim(f.String()))
+		}
+	}
+	fmt.Fprintln(o.out)
+}
+
+// scriptFindings returns the recorded script problems for the AI prompt.
+// Findings outside failed steps are left out when any step is implicated.
+func (o *Orchestrator) scriptFindings() []string {
+	implicated := false
+	for _, s := range o.report.Scripts {
+		if s.InFailedStep {
+			implicated = true
+			break
+		}
+	}
+
+	var lines []string
+	for _, s := range o.report.Scripts {
+		if implicated && !s.InFailedStep {
+			continue
+		}
+		f := scriptcheck.Finding{Job: s.Job, Step: s.Step, Line: s.Line, Code: s.Code, Message: s.Message}
+		line := f.String()
+		if s.Source == scriptcheck.SourceLog {
+			line = "logged error at " + line
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
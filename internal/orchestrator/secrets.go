@@ -0,0 +1,261 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/workflow"
+)
+
+// Reference statuses in the secrets inventory
+const (
+	refOK         = "ok"
+	refMissing    = "missing"
+	refBuiltin    = "builtin"
+	refUnverified = "unverified" // The token cannot list secrets or variables
+)
+
+// inventoryEntry is one workflow in the JSON secrets output
+type inventoryEntry struct {
+	Workflow     string      `json:"workflow"`
+	Env          []string    `json:"env"`
+	Secrets      []refStatus `json:"secrets"`
+	Vars         []refStatus `json:"vars"`
+	Environments []string    `json:"environments,omitempty"`
+}
+
+// refStatus is a secret or variable reference and whether it is configured
+type refStatus struct {
+	Name   string `json:"name"`
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+}
+
+// configured holds the secret and variable names known to exist; nil maps
+// mean the names could not be listed
+type configured struct {
+	secrets map[string]bool
+	vars    map[string]bool
+}
+
+// Secrets lists the env vars, secrets and variables each workflow uses and
+// flags references to secrets and variables that do not exist
+func (o *Orchestrator) Secrets(opts Options) error {
+	o.opts = opts
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+
+	contents, err := o.workflowContents()
+	if err != nil {
+		return err
+	}
+
+	var inventories []*workflow.Inventory
+	for _, path := range sortedPaths(contents) {
+		inv, err := workflow.Inspect(path, contents[path])
+		if err != nil {
+			o.logger.Warn("Skipping %s: %v", path, err)
+			continue
+		}
+		inventories = append(inventories, inv)
+	}
+
+	repoNames := o.configuredNames()
+	envNames := make(map[string]configured)
+
+	var entries []inventoryEntry
+	missing := 0
+	for _, inv := range inventories {
+		known := []configured{repoNames}
+		for _, env := range inv.Environments {
+			if _, ok := envNames[env]; !ok {
+				secrets, vars, err := o.github.ListEnvironmentNames(env)
+				if err != nil {
+					o.logger.Debug("Could not list names for environment %s: %v", env, err)
+				}
+				envNames[env] = configured{secrets: secrets, vars: vars}
+			}
+			known = append(known, envNames[env])
+		}
+
+		entry := inventoryEntry{
+			Workflow:     inv.Path,
+			Env:          inv.Env,
+			Environments: inv.Environments,
+		}
+		for _, ref := range inv.Secrets {
+			status := referenceStatus(ref.Name, known, func(c configured) map[string]bool { return c.secrets })
+			if ref.Name == workflow.BuiltinSecret {
+				status = refBuiltin
+			}
+			entry.Secrets = append(entry.Secrets, refStatus{Name: ref.Name, Line: ref.Line, Status: status})
+			if status == refMissing {
+				missing++
+			}
+		}
+		for _, ref := range inv.Vars {
+			status := referenceStatus(ref.Name, known, func(c configured) map[string]bool { return c.vars })
+			entry.Vars = append(entry.Vars, refStatus{Name: ref.Name, Line: ref.Line, Status: status})
+			if status == refMissing {
+				missing++
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if opts.Output == OutputJSON {
+		if entries == nil {
+			entries = []inventoryEntry{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	o.printInventory(entries, repoNames, missing)
+	return nil
+}
+
+// workflowContents reads the local workflow files, falling back to the
+// default branch when the repository has no local checkout of them
+func (o *Orchestrator) workflowContents() (map[string]string, error) {
+	contents := make(map[string]string)
+
+	local, _ := filepath.Glob(filepath.Join(workflow.Dir, "*"))
+	for _, file := range local {
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		contents[filepath.ToSlash(file)] = string(data)
+	}
+	if len(contents) > 0 {
+		return contents, nil
+	}
+
+	files, err := o.github.ListWorkflowFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow files: %w", err)
+	}
+	for _, file := range files {
+		if workflow.IsAction(file) {
+			continue
+		}
+		path := workflow.Dir + "/" + file
+		content, err := o.github.GetWorkflowFileContent(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", path, err)
+		}
+		contents[path] = content
+	}
+	return contents, nil
+}
+
+// configuredNames lists repository and organization secret and variable
+// names. Listing secrets needs admin access; without it references are
+// reported as unverified instead of missing.
+func (o *Orchestrator) configuredNames() configured {
+	var names configured
+	secrets, err := o.github.ListSecretNames()
+	if err != nil {
+		o.logger.Warn("Cannot list secrets, references will not be verified: %v", err)
+	}
+	names.secrets = secrets
+
+	vars, err := o.github.ListVariableNames()
+	if err != nil {
+		o.logger.Warn("Cannot list variables, references will not be verified: %v", err)
+	}
+	names.vars = vars
+	return names
+}
+
+// referenceStatus checks name against every set of configured names
+func referenceStatus(name string, known []configured, names func(configured) map[string]bool) string {
+	// An unlisted repository scope leaves every reference unverified
+	if names(known[0]) == nil {
+		return refUnverified
+	}
+	status := refMissing
+	for _, k := range known {
+		if names(k)[name] {
+			return refOK
+		}
+		// The name may live in an environment that could not be listed
+		if names(k) == nil {
+			status = refUnverified
+		}
+	}
+	return status
+}
+
+// printInventory prints the inventory grouped by workflow
+func (o *Orchestrator) printInventory(entries []inventoryEntry, repoNames configured, missing int) {
+	if len(entries) == 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo("No workflow files found"))
+		return
+	}
+
+	for _, entry := range entries {
+		fmt.Fprintln(o.out, ui.FormatHeader(entry.Workflow))
+		if len(entry.Environments) > 0 {
+			fmt.Fprintf(o.out, "  %s %s\n", ui.FormatDim("environments:"), strings.Join(entry.Environments, ", "))
+		}
+		if len(entry.Env) > 0 {
+			fmt.Fprintf(o.out, "  %s %s\n", ui.FormatDim("env:"), strings.Join(entry.Env, ", "))
+		}
+		o.printRefs("secrets", entry.Secrets)
+		o.printRefs("vars", entry.Vars)
+		fmt.Fprintln(o.out)
+	}
+
+	if repoNames.secrets == nil || repoNames.vars == nil {
+		fmt.Fprintln(o.out, ui.FormatWarning("Some references could not be verified: listing secrets and variables needs admin access to the repository"))
+	}
+	if missing > 0 {
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("%d references to secrets or variables that do not exist", missing)))
+	} else if repoNames.secrets != nil && repoNames.vars != nil {
+		fmt.Fprintln(o.out, ui.FormatSuccess("No references to missing secrets or variables"))
+	}
+}
+
+// printRefs prints one kind of reference with its status
+func (o *Orchestrator) printRefs(kind string, refs []refStatus) {
+	if len(refs) == 0 {
+		return
+	}
+	fmt.Fprintf(o.out, "  %s\n", ui.FormatDim(kind+":"))
+	for _, ref := range refs {
+		label := fmt.Sprintf("%s (line %d)", ref.Name, ref.Line)
+		switch ref.Status {
+		case refMissing:
+			fmt.Fprintf(o.out, "    %s\n", ui.FormatError(label+" — not configured"))
+		case refUnverified:
+			fmt.Fprintf(o.out, "    %s\n", ui.FormatDim("? "+label))
+		case refBuiltin:
+			fmt.Fprintf(o.out, "    %s\n", ui.FormatDim("• "+label+" — provided automatically"))
+		default:
+			fmt.Fprintf(o.out, "    %s\n", ui.FormatSuccess(label))
+		}
+	}
+}
+
+// sortedPaths returns the keys of contents in order
+func sortedPaths(contents map[string]string) []string {
+	paths := make([]string, 0, len(contents))
+	for path := range contents {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,304 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/security"
+)
+
+// Per-finding remediation choices
+const (
+	remediateAI = iota
+	remediateSkip
+	remediateStop
+)
+
+// Security scans local workflow files and actions for script injection,
+// jobs with the default token permissions, pull_request_target workflows
+// that check out the pull request, and secrets in if: conditions. In a
+// terminal each finding can then be sent to the AI for a fix, shown as a
+// diff and applied after confirmation. Without paths every workflow and
+// every action in .github/actions is scanned. It fails when critical or
+// high findings remain.
+func (o *Orchestrator) Security(opts Options, paths []string) error {
+	o.opts = opts
+	if len(paths) == 0 {
+		var err error
+		if paths, err = localPaths(); err != nil {
+			return err
+		}
+	}
+
+	contents := make(map[string]string)
+	findings := []ReportSecurity{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		path = filepath.ToSlash(path)
+		contents[path] = string(data)
+		found, err := scanSecurity(path, string(data))
+		if err != nil {
+			return err
+		}
+		findings = append(findings, found...)
+	}
+
+	if opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		o.printSecurity(paths, findings)
+	}
+
+	remaining, err := o.remediate(findings, contents)
+	if err != nil {
+		return err
+	}
+	serious := 0
+	for _, f := range remaining {
+		if security.Rank(f.Severity) <= security.Rank(security.SeverityHigh) {
+			serious++
+		}
+	}
+	if serious > 0 {
+		return fmt.Errorf("security scan found %d critical or high findings", serious)
+	}
+	return nil
+}
+
+// scanSecurity scans one file, most severe findings first
+func scanSecurity(path, content string) ([]ReportSecurity, error) {
+	found, err := security.Scan(path, content)
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]ReportSecurity, 0, len(found))
+	for _, f := range found {
+		findings = append(findings, ReportSecurity{
+			File:        path,
+			Rule:        f.Rule,
+			Line:        f.Line,
+			Severity:    f.Severity,
+			Job:         f.Job,
+			Step:        f.Step,
+			Message:     f.Message,
+			Remediation: f.Remediation,
+		})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if ri, rj := security.Rank(findings[i].Severity), security.Rank(findings[j].Severity); ri != rj {
+			return ri < rj
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// printSecurity prints the findings grouped by file
+func (o *Orchestrator) printSecurity(paths []string, findings []ReportSecurity) {
+	if len(paths) == 0 {
+		fmt.Fprintln(o.out, ui.FormatInfo("No workflow files found"))
+		return
+	}
+
+	file := ""
+	counts := make(map[string]int)
+	for _, f := range findings {
+		if f.File != file {
+			if file != "" {
+				fmt.Fprintln(o.out)
+			}
+			file = f.File
+			fmt.Fprintln(o.out, ui.FormatHeader(file))
+		}
+		counts[f.Severity]++
+		label := fmt.Sprintf("line %d: [%s] %s", f.Line, f.Severity, f.Message)
+		fmt.Fprintf(o.out, "  %s\n", ui.Format(ui.LevelSeverity(f.Severity), label))
+		fmt.Fprintf(o.out, "    %s\n", ui.FormatDim("→ "+f.Remediation))
+	}
+	if len(findings) > 0 {
+		fmt.Fprintln(o.out)
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("No security issues found in %d files", len(paths))))
+		return
+	}
+	var parts []string
+	for _, severity := range []string{security.SeverityCritical, security.SeverityHigh, security.SeverityMedium, security.SeverityLow} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	summary := fmt.Sprintf("%s in %d files", strings.Join(parts, ", "), len(paths))
+	if counts[security.SeverityCritical]+counts[security.SeverityHigh] > 0 {
+		fmt.Fprintln(o.out, ui.FormatError(summary))
+		return
+	}
+	fmt.Fprintln(o.out, ui.FormatWarning(summary))
+}
+
+// remediate offers, in a terminal, to have the AI fix each finding in
+// turn. An applied fix rescans its file, and the findings it resolved are
+// no longer offered. It returns the findings that remain.
+func (o *Orchestrator) remediate(findings []ReportSecurity, contents map[string]string) ([]ReportSecurity, error) {
+	if len(findings) == 0 || !o.interactive() {
+		return findings, nil
+	}
+	if o.config.RulesOnly {
+		fmt.Fprintln(o.out, ui.FormatDim("AI remediation is disabled in rules-only mode"))
+		return findings, nil
+	}
+
+	var remaining []ReportSecurity
+	resolved := make(map[ReportSecurity]bool)
+	moved := make(map[ReportSecurity]int) // Lines of the findings in the fixed files
+	for i, f := range findings {
+		if resolved[securityKey(f)] {
+			continue
+		}
+		if line, ok := moved[securityKey(f)]; ok {
+			f.Line = line
+		}
+		choice, err := ui.ShowChoice(
+			fmt.Sprintf("%s:%d [%s] %s", f.File, f.Line, f.Severity, f.Rule),
+			f.Message,
+			[]string{"Ask the AI for a fix", "Skip", "Stop"},
+		)
+		if err != nil || choice == remediateStop {
+			for _, rest := range findings[i:] {
+				if !resolved[securityKey(rest)] {
+					remaining = append(remaining, rest)
+				}
+			}
+			break
+		}
+		if choice != remediateAI {
+			remaining = append(remaining, f)
+			continue
+		}
+
+		fixed, err := o.remediateFinding(f, contents[f.File])
+		if err != nil {
+			return nil, err
+		}
+		if fixed == "" {
+			remaining = append(remaining, f)
+			continue
+		}
+		before, _ := scanSecurity(f.File, contents[f.File])
+		after, _ := scanSecurity(f.File, fixed)
+		contents[f.File] = fixed
+		for _, gone := range resolvedFindings(before, after) {
+			resolved[securityKey(gone)] = true
+		}
+		for _, still := range after {
+			moved[securityKey(still)] = still.Line
+		}
+		if !resolved[securityKey(f)] {
+			remaining = append(remaining, f)
+		}
+	}
+	return remaining, nil
+}
+
+// remediateFinding asks the AI to fix one finding, shows the fix and
+// applies it after confirmation. It returns the new content of the file,
+// or "" when nothing was applied.
+func (o *Orchestrator) remediateFinding(f ReportSecurity, content string) (string, error) {
+	if err := o.connectAI(); err != nil {
+		return "", err
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Asking the AI to fix %s in %s...", f.Rule, f.File)))
+
+	var logs strings.Builder
+	logs.WriteString("No run failed: a security scan of this file found a dangerous pattern. Fix it without changing what the workflow does.\n")
+	fmt.Fprintf(&logs, "Finding (%s, %s severity) at line %d: %s\n", f.Rule, f.Severity, f.Line, f.Message)
+	fmt.Fprintf(&logs, "Suggested remediation: %s\n", f.Remediation)
+	diagnosis, err := o.copilot.DiagnoseAndFix(&copilot.DiagnosisRequest{
+		ErrorLogs:      logs.String(),
+		CurrentFile:    f.File,
+		FileContent:    content,
+		AvailableFiles: []string{filepath.Base(f.File)},
+		WorkflowPath:   f.File,
+	})
+	if err != nil {
+		o.logger.Warn("AI remediation failed: %v", err)
+		fmt.Fprintln(o.out, ui.FormatWarning("Could not get a fix from the AI"))
+		return "", nil
+	}
+	if diagnosis.FixedContent == "" || diagnosis.FixedContent == content {
+		fmt.Fprintln(o.out, ui.FormatWarning("The AI proposed no change"))
+		return "", nil
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo(diagnosis.Explanation))
+	fmt.Fprintln(o.out, "\n"+ui.FormatHeader("━━━━━━━━━━━━━━ PROPOSED FIX ━━━━━━━━━━━━━━\n"))
+	o.printDiff(patcher.Unified(f.File, patcher.Diff(content, diagnosis.FixedContent), false), 0)
+
+	details := "A backup of the file will be created automatically"
+	issues := patcher.ValidateSchema(f.File, diagnosis.FixedContent)
+	if len(issues) > 0 {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The fixed file has %d schema issues:", len(issues))))
+		for _, issue := range issues {
+			fmt.Fprintf(o.out, "  • %s\n", issue)
+		}
+		fmt.Fprintln(o.out)
+		details = fmt.Sprintf("Warning: %d schema issues found. %s", len(issues), details)
+	}
+	confirmed, err := ui.ShowConfirmation(fmt.Sprintf("Apply the fix to %s?", f.File), details)
+	if err != nil {
+		return "", fmt.Errorf("confirmation dialog failed: %w", err)
+	}
+	if !confirmed {
+		fmt.Fprintln(o.out, ui.FormatDim("Fix declined"))
+		return "", nil
+	}
+
+	result, err := o.patcher.Apply(&patcher.PatchRequest{FilePath: f.File, NewContent: diagnosis.FixedContent, Validate: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to apply the fix to %s: %w", f.File, err)
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s fixed", f.File)))
+	if result.BackupPath != "" {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Backup: %s", result.BackupPath)))
+	}
+	fmt.Fprintln(o.out)
+	return diagnosis.FixedContent, nil
+}
+
+// securityKey identifies a finding across fixes, which move its line
+func securityKey(f ReportSecurity) ReportSecurity {
+	f.Line = 0
+	return f
+}
+
+// resolvedFindings returns the findings of before that after no longer has
+func resolvedFindings(before, after []ReportSecurity) []ReportSecurity {
+	still := make(map[ReportSecurity]int)
+	for _, f := range after {
+		still[securityKey(f)]++
+	}
+	var gone []ReportSecurity
+	for _, f := range before {
+		if still[securityKey(f)] > 0 {
+			still[securityKey(f)]--
+			continue
+		}
+		gone = append(gone, f)
+	}
+	return gone
+}
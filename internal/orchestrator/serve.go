@@ -0,0 +1,577 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/notify"
+	"gh-sentinel/internal/report"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/github"
+)
+
+const (
+	// ServeTokenEnv holds the token clients of the status API authenticate
+	// with; without it a token is generated at startup
+	ServeTokenEnv = "SENTINEL_SERVE_TOKEN"
+	// WebhookSecretEnv holds the secret of the GitHub webhook whose
+	// workflow_run deliveries serve receives; without it there is none
+	WebhookSecretEnv = "SENTINEL_WEBHOOK_SECRET"
+	// maxNotifiedExplanation bounds the explanation a notification quotes
+	maxNotifiedExplanation = 300
+	// maxServedRuns bounds the runs the status API remembers
+	maxServedRuns = 200
+	// serveQueueSize bounds the diagnoses waiting to be run
+	serveQueueSize = 16
+	// serveShutdownTimeout is how long requests in flight get to finish
+	serveShutdownTimeout = 5 * time.Second
+)
+
+// Diagnosis states of the runs the status API lists
+const (
+	diagnosisQueued  = "queued"
+	diagnosisRunning = "running"
+	diagnosisDone    = "done"
+)
+
+// Serve runs the loop of watch behind a local HTTP API, so that dashboards
+// and other tools can list the failures it found, have runs diagnosed and
+// fetch the reports of the diagnoses as JSON. Every request must carry the
+// token of SENTINEL_SERVE_TOKEN, or the one generated into ServeTokenFile,
+// as a bearer token. With SENTINEL_WEBHOOK_SECRET, the workflow_run
+// webhooks of GitHub are received on /webhook, and a run is diagnosed as
+// soon as it fails instead of at the next poll. Each diagnosis is recorded
+// in the history and posted to the notify webhook. On interrupt the
+// requests in flight are finished before the server stops.
+func (o *Orchestrator) Serve(opts Options, addr string, interval time.Duration, diagnose bool) error {
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+	token, generated, err := o.serveToken()
+	if err != nil {
+		return err
+	}
+	if generated {
+		defer os.Remove(o.config.ServeTokenFile)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot serve the status API on %s: %w", addr, err)
+	}
+	repo := o.github.GetRepository()
+	api := &statusAPI{
+		token:    token,
+		repo:     repo.FullName,
+		branch:   repo.DefaultBranch,
+		interval: interval,
+		diagnose: diagnose,
+		started:  time.Now(),
+		secret:   []byte(os.Getenv(WebhookSecretEnv)),
+		queue:    make(chan queuedRun, serveQueueSize),
+		runs:     make(map[int64]*servedRun),
+	}
+	server := &http.Server{Handler: api.handler(), ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Serving the status API on http://%s", listener.Addr())))
+	if generated {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Its token is in %s (or set %s)", o.config.ServeTokenFile, ServeTokenEnv)))
+	}
+	if len(api.secret) > 0 {
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Receiving workflow_run webhooks on http://%s/webhook", listener.Addr())))
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			fmt.Fprintln(o.out, ui.FormatWarning("The status API is reachable from other machines; only its token protects it"))
+		}
+	}
+
+	err = o.monitor(opts, interval, diagnose, api)
+
+	ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if shutdownErr := server.Shutdown(ctx); shutdownErr != nil {
+		o.logger.Warn("The status API did not stop cleanly: %v", shutdownErr)
+	}
+	if serveErr := <-served; serveErr != http.ErrServerClosed && err == nil {
+		err = fmt.Errorf("status API failed: %w", serveErr)
+	}
+	return err
+}
+
+// serveToken returns the token of the status API, generating one into
+// ServeTokenFile when SENTINEL_SERVE_TOKEN is not set
+func (o *Orchestrator) serveToken() (token string, generated bool, err error) {
+	if token := strings.TrimSpace(os.Getenv(ServeTokenEnv)); token != "" {
+		return token, false, nil
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", false, fmt.Errorf("failed to generate a token: %w", err)
+	}
+	token = hex.EncodeToString(raw)
+	file := o.config.ServeTokenFile
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return "", false, errors.FilesystemError("write_serve_token", file, err)
+	}
+	if err := os.WriteFile(file, []byte(token+"\n"), 0600); err != nil {
+		return "", false, errors.FilesystemError("write_serve_token", file, err)
+	}
+	return token, true, nil
+}
+
+// diagnoseQueued diagnoses a run the status API asked for, or a failure a
+// webhook delivered. A delivered failure is announced first, as if a poll
+// had found it, unless a poll already did.
+func (o *Orchestrator) diagnoseQueued(api *statusAPI, queued queuedRun, seen map[int64]bool) {
+	run, err := o.github.GetWorkflowRun(queued.runID)
+	if err != nil {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not diagnose run #%d: %v", queued.runID, err)))
+		api.done(queued.runID, &Report{
+			Repository: o.github.GetRepository().FullName,
+			RunID:      queued.runID,
+			Status:     StatusError,
+			Error:      err.Error(),
+		})
+		return
+	}
+	switch {
+	case queued.delivered && !seen[run.ID]:
+		seen[run.ID] = true
+		if !o.admitFailure(run, api) {
+			api.forget(run.ID)
+			return
+		}
+	case queued.delivered:
+		// A poll got to it first, and ignored or diagnosed it
+		if announced, diagnosed := api.polledState(run.ID); !announced || diagnosed {
+			if !announced {
+				api.forget(run.ID)
+			}
+			return
+		}
+	default:
+		api.record(run, "")
+	}
+	o.diagnoseServed(api, run)
+}
+
+// diagnoseServed diagnoses a run for serve: the status API, the history
+// and the notify webhook receive its report
+func (o *Orchestrator) diagnoseServed(api *statusAPI, run *github.WorkflowRun) {
+	api.start(run.ID)
+	started := time.Now()
+	o.diagnoseRun(run)
+	api.done(run.ID, o.report)
+	o.publishDiagnosis(started)
+}
+
+// publishDiagnosis records the report of a diagnosis in the history, as a
+// fix session is, and posts a summary to the notify webhook, if any
+func (o *Orchestrator) publishDiagnosis(started time.Time) {
+	o.session = []*Report{o.report}
+	defer func() { o.session = nil }()
+	if recap := o.recap(started, nil); len(recap.Runs) > 0 {
+		if err := appendRecap(o.config.HistoryFile, recap); err != nil {
+			o.logger.Warn("Could not record the diagnosis: %v", err)
+		}
+	}
+
+	if o.config.NotifyWebhook == "" || o.ctx.Err() != nil {
+		return
+	}
+	webhook := &notify.Webhook{URL: o.config.NotifyWebhook, UserAgent: o.config.UserAgent}
+	if err := webhook.Send(o.ctx, o.diagnosisMessage()); err != nil {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not notify the diagnosis of run #%d: %v", o.report.RunID, err)))
+	}
+}
+
+// diagnosisMessage summarizes the report of a diagnosis for a chat webhook
+func (o *Orchestrator) diagnosisMessage() string {
+	r := o.report
+	what := r.Workflow
+	if what == "" {
+		what = fmt.Sprintf("Run %d", r.RunID)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s failed in %s*\n", what, r.Repository)
+	fmt.Fprintf(&b, "• Run: %s\n", o.github.RunURL(r.RunID))
+	if len(r.Failed) > 0 {
+		fmt.Fprintf(&b, "• Failed at: %s › %s\n", r.Failed[0].Job, r.Failed[0].Step)
+	}
+	if r.Tag != "" {
+		fmt.Fprintf(&b, "• Category: %s\n", r.Tag)
+	}
+	fmt.Fprintf(&b, "• Outcome: %s\n", report.Describe(r.Status))
+	if d := r.Diagnosis; d != nil {
+		source := d.Source
+		if d.Confidence != "" {
+			source += ", " + strings.ToLower(d.Confidence) + " confidence"
+		}
+		explanation, _, _ := strings.Cut(strings.TrimSpace(d.Explanation), "\n")
+		if e := []rune(explanation); len(e) > maxNotifiedExplanation {
+			explanation = string(e[:maxNotifiedExplanation-1]) + "…"
+		}
+		fmt.Fprintf(&b, "• Diagnosis (%s): %s\n", source, explanation)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "• Error: %s\n", r.Error)
+	}
+	return b.String()
+}
+
+// statusAPI is the state the HTTP API of serve shows. The watch loop
+// writes it and the handlers read it, under mu.
+type statusAPI struct {
+	token    string
+	repo     string
+	branch   string
+	interval time.Duration
+	diagnose bool
+	started  time.Time
+	secret   []byte         // Of the GitHub webhook; empty without one
+	queue    chan queuedRun // Runs to diagnose, read by the watch loop
+
+	mu       sync.Mutex
+	lastPoll time.Time
+	runs     map[int64]*servedRun
+	order    []int64 // IDs of runs, oldest first
+}
+
+// queuedRun is a run waiting to be diagnosed
+type queuedRun struct {
+	runID     int64
+	delivered bool // A failure a workflow_run webhook delivered, rather than a request
+}
+
+// servedRun is a failed run the watch loop announced, or a run diagnosed
+// on request, with the state of its diagnosis
+type servedRun struct {
+	scanEntry
+	Diagnosis string `json:"diagnosis,omitempty"` // queued, running or done
+	Status    string `json:"status,omitempty"`    // Status of the report, once done
+	report    *Report
+}
+
+// statusResponse is the body of GET /api/status
+type statusResponse struct {
+	Repository string    `json:"repository"`
+	Branch     string    `json:"branch"`
+	Started    time.Time `json:"started_at"`
+	LastPoll   time.Time `json:"last_poll"`
+	Interval   string    `json:"interval"`
+	Diagnose   bool      `json:"diagnose"`
+	Failures   int       `json:"failures"`
+	Queued     int       `json:"queued"`
+}
+
+func (a *statusAPI) handler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/status", a.handleStatus)
+	api.HandleFunc("GET /api/failures", a.handleFailures)
+	api.HandleFunc("POST /api/runs/{id}/diagnose", a.handleDiagnose)
+	api.HandleFunc("GET /api/reports/{id}", a.handleReport)
+
+	// The metrics name the repository, so they need the token too
+	mux := http.NewServeMux()
+	mux.Handle("/", a.authenticated(api))
+	mux.Handle("GET /metrics", a.authenticated(metrics.Handler()))
+	// GitHub signs its deliveries with the webhook's secret instead
+	if len(a.secret) > 0 {
+		mux.HandleFunc("POST /webhook", a.handleWebhook)
+	}
+	return mux
+}
+
+// authenticated rejects requests without the API's bearer token
+func (a *statusAPI) authenticated(next http.Handler) http.Handler {
+	want := []byte("Bearer " + a.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gh-sentinel"`)
+			writeError(w, http.StatusUnauthorized, "missing or wrong bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *statusAPI) handleStatus(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	status := statusResponse{
+		Repository: a.repo,
+		Branch:     a.branch,
+		Started:    a.started,
+		LastPoll:   a.lastPoll,
+		Interval:   a.interval.String(),
+		Diagnose:   a.diagnose,
+		Failures:   len(a.runs),
+		Queued:     len(a.queue),
+	}
+	a.mu.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleFailures lists the runs, newest first
+func (a *statusAPI) handleFailures(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	runs := make([]servedRun, 0, len(a.order))
+	for i := len(a.order) - 1; i >= 0; i-- {
+		runs = append(runs, *a.runs[a.order[i]])
+	}
+	a.mu.Unlock()
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// handleDiagnose queues the diagnosis of a run; its report is fetched from
+// /api/reports/{id} once done
+func (a *statusAPI) handleDiagnose(w http.ResponseWriter, r *http.Request) {
+	runID, ok := pathRunID(w, r)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	run := a.runs[runID]
+	if run != nil && (run.Diagnosis == diagnosisQueued || run.Diagnosis == diagnosisRunning) {
+		state := run.Diagnosis
+		a.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %d is already %s", runID, state))
+		return
+	}
+	select {
+	case a.queue <- queuedRun{runID: runID}:
+	default:
+		a.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, "too many diagnoses are queued; try again later")
+		return
+	}
+	if run == nil {
+		run = a.add(runID)
+	}
+	run.Diagnosis = diagnosisQueued
+	run.report = nil
+	run.Status = ""
+	queued := *run
+	a.mu.Unlock()
+	writeJSON(w, http.StatusAccepted, queued)
+}
+
+// handleWebhook queues the diagnosis of a run a workflow_run delivery
+// reports as failed on the watched branch. Other deliveries are
+// acknowledged and ignored.
+func (a *statusAPI) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	event, err := github.ParseRunEvent(w, r, a.secret)
+	if err != nil {
+		code := http.StatusBadRequest
+		if e, ok := err.(*errors.SentinelError); ok && e.Type == errors.ErrTypeAuth {
+			code = http.StatusUnauthorized
+		}
+		writeError(w, code, err.Error())
+		return
+	}
+	ignore := func(reason string) {
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ignored", "reason": reason})
+	}
+	switch {
+	case event == nil:
+		ignore("not a workflow_run event")
+		return
+	case event.Action != "completed" || event.Conclusion != "failure":
+		ignore("the run did not fail")
+		return
+	case !strings.EqualFold(event.Repository, a.repo):
+		ignore("a run of another repository than " + a.repo)
+		return
+	case event.Branch != a.branch:
+		ignore("a run of another branch than " + a.branch)
+		return
+	}
+
+	a.mu.Lock()
+	if run := a.runs[event.RunID]; run != nil && run.Diagnosis != "" {
+		a.mu.Unlock()
+		ignore(fmt.Sprintf("run %d is already %s", event.RunID, run.Diagnosis))
+		return
+	}
+	select {
+	case a.queue <- queuedRun{runID: event.RunID, delivered: true}:
+	default:
+		a.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, "too many diagnoses are queued; redeliver later")
+		return
+	}
+	run := a.runs[event.RunID]
+	if run == nil {
+		run = a.add(event.RunID)
+	}
+	run.Diagnosis = diagnosisQueued
+	queued := *run
+	a.mu.Unlock()
+	writeJSON(w, http.StatusAccepted, queued)
+}
+
+// handleReport returns the report of a run's diagnosis, or its state while
+// it is not done
+func (a *statusAPI) handleReport(w http.ResponseWriter, r *http.Request) {
+	runID, ok := pathRunID(w, r)
+	if !ok {
+		return
+	}
+	// A report is not changed once its diagnosis is done
+	a.mu.Lock()
+	var run servedRun
+	if served := a.runs[runID]; served != nil {
+		run = *served
+	}
+	a.mu.Unlock()
+	switch {
+	case run.Diagnosis == "":
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %d was not diagnosed; POST /api/runs/%d/diagnose first", runID, runID))
+	case run.report == nil:
+		writeJSON(w, http.StatusAccepted, run)
+	default:
+		writeJSON(w, http.StatusOK, run.report)
+	}
+}
+
+// polled records a successful poll of the failed runs
+func (a *statusAPI) polled(at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastPoll = at
+}
+
+// record adds a run, or refreshes what is known of it; without a tag, the
+// one it was recorded with is kept
+func (a *statusAPI) record(run *github.WorkflowRun, tag string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	served := a.runs[run.ID]
+	if served == nil {
+		served = a.add(run.ID)
+	}
+	if tag == "" {
+		tag = served.Tag
+	}
+	served.scanEntry = scanEntry{
+		RunID:      run.ID,
+		RunNumber:  run.RunNumber,
+		Workflow:   run.WorkflowPath,
+		Title:      run.DisplayTitle,
+		Event:      run.Event,
+		Conclusion: run.Conclusion,
+		HeadSHA:    run.HeadSHA,
+		UpdatedAt:  run.UpdatedAt,
+		Tag:        tag,
+	}
+}
+
+// polledState tells whether a poll announced a run, and whether its
+// diagnosis is done
+func (a *statusAPI) polledState(runID int64) (announced, diagnosed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	served := a.runs[runID]
+	if served == nil {
+		return false, false
+	}
+	return served.Workflow != "", served.Diagnosis == diagnosisDone
+}
+
+// forget drops a run, e.g. a delivered failure of an ignored workflow
+func (a *statusAPI) forget(runID int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.runs, runID)
+	for i, id := range a.order {
+		if id == runID {
+			a.order = append(a.order[:i], a.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// start marks the diagnosis of a run as running
+func (a *statusAPI) start(runID int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	served := a.runs[runID]
+	if served == nil {
+		served = a.add(runID)
+	}
+	served.Diagnosis = diagnosisRunning
+	served.report = nil
+	served.Status = ""
+}
+
+// done stores the report of a run's diagnosis
+func (a *statusAPI) done(runID int64, report *Report) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	served := a.runs[runID]
+	if served == nil {
+		served = a.add(runID)
+	}
+	served.Diagnosis = diagnosisDone
+	served.report = report
+	served.Status = report.Status
+}
+
+// add starts tracking a run, forgetting the oldest ones that are not being
+// diagnosed beyond maxServedRuns. The caller holds mu.
+func (a *statusAPI) add(runID int64) *servedRun {
+	served := &servedRun{scanEntry: scanEntry{RunID: runID}}
+	a.runs[runID] = served
+	a.order = append(a.order, runID)
+	for i := 0; len(a.order) > maxServedRuns && i < len(a.order); {
+		old := a.runs[a.order[i]]
+		if old.Diagnosis == diagnosisQueued || old.Diagnosis == diagnosisRunning {
+			i++
+			continue
+		}
+		delete(a.runs, a.order[i])
+		a.order = append(a.order[:i], a.order[i+1:]...)
+	}
+	return served
+}
+
+// pathRunID parses the {id} of a request path, answering 400 when it is
+// not a run ID
+func pathRunID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	runID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || runID <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid run ID %q", r.PathValue("id")))
+		return 0, false
+	}
+	return runID, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
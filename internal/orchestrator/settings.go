@@ -0,0 +1,662 @@
+package orchestrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/config"
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/fixer"
+	"gh-sentinel/pkg/git"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File names of the settings, recipes and error patterns, in bundles and in
+// the shared settings repository
+const (
+	settingsFileName = "config.yml"
+	recipesFileName  = "recipes.yml"
+	patternsFileName = "patterns.yml"
+	manifestFileName = "sentinel-bundle.json"
+)
+
+// bundleFiles are the files a bundle can hold, in order
+var bundleFiles = []string{settingsFileName, recipesFileName, patternsFileName}
+
+// bundleFormat is the version of the bundle layout
+const bundleFormat = 1
+
+// maxBundleEntry bounds the size of each file of a bundle
+const maxBundleEntry = 1 << 20
+
+// sharedSyncInterval is how often the shared settings are pulled
+const sharedSyncInterval = 24 * time.Hour
+
+// sharedSyncTimeout bounds a sync, which must not hold up a command for long
+const sharedSyncTimeout = 30 * time.Second
+
+// bundleManifest identifies a settings bundle
+type bundleManifest struct {
+	Format    int       `json:"format"`
+	Version   string    `json:"version"` // Sentinel version that exported it
+	CreatedAt time.Time `json:"created_at"`
+	Files     []string  `json:"files"`
+}
+
+// LoadSettings applies the shared settings, then the user's settings file,
+// then the .sentinel.yml of the repository, to cfg. With --repo
+// (cfg.Repository), no .sentinel.yml is read. The shared settings come
+// from sharedURL, or else from the shared: key of the settings file, and are
+// pulled when the last sync is old enough. A failed sync falls back to the
+// last synced copy.
+func LoadSettings(ctx context.Context, cfg *config.Config, sharedURL string) error {
+	user, err := config.LoadSettings(cfg.SettingsFile)
+	if err != nil {
+		return err
+	}
+	// With --repo, the working directory's repository is not the one
+	// worked on
+	repo := &config.Settings{}
+	if path, err := repoSettingsPath(); err == nil && cfg.Repository == "" {
+		if repo, err = config.LoadSettings(path); err != nil {
+			return err
+		}
+		if err := repo.CheckRepository(path); err != nil {
+			return err
+		}
+		if !repo.Empty() {
+			cfg.RepoSettingsFile = path
+		}
+	}
+	if sharedURL == "" {
+		sharedURL = user.Shared
+	}
+
+	if sharedURL != "" {
+		cfg.Shared = sharedURL
+		if err := syncShared(ctx, cfg, false); err != nil {
+			logger.Default().Warn("Could not sync the shared settings from %s, using the last synced copy: %v", sharedURL, err)
+		}
+		shared, ignored, err := loadSharedSettings(cfg)
+		if err != nil {
+			return err
+		}
+		if len(ignored) > 0 {
+			logger.Default().Warn("Ignored %s in the shared settings: they choose where credentials and logs go or run code, so only your own settings can set them", strings.Join(ignored, ", "))
+		}
+		shared.Apply(cfg)
+	}
+	user.Apply(cfg)
+	repo.Apply(cfg)
+	cfg.Shared = sharedURL
+	return nil
+}
+
+// loadSharedSettings reads the synced shared settings. Like a repository's,
+// they cannot set the keys only the user's own settings may set: those are
+// left out, and returned apart.
+func loadSharedSettings(cfg *config.Config) (*config.Settings, []string, error) {
+	shared, err := config.LoadSettings(filepath.Join(cfg.SharedDir, settingsFileName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("shared settings: %w", err)
+	}
+	shared.Shared = "" // The shared settings cannot point elsewhere
+	ignored := shared.UserOnlyKeys()
+	return shared.Without(ignored), ignored, nil
+}
+
+// repoSettingsPath returns where the repository of the working directory
+// keeps its settings, whether or not the file exists
+func repoSettingsPath() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	repo, err := git.Open(cwd, logger.Default())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(repo.Root(), config.RepoSettingsName), nil
+}
+
+// syncStamp is touched after each successful sync of the shared settings
+func syncStamp(cfg *config.Config) string {
+	return filepath.Join(cfg.SharedDir, ".git", "sentinel-synced")
+}
+
+// syncShared clones or updates the shared settings repository, unless it was
+// synced less than sharedSyncInterval ago and force is not set
+func syncShared(ctx context.Context, cfg *config.Config, force bool) error {
+	stamp := syncStamp(cfg)
+	if info, err := os.Stat(stamp); err == nil && !force && time.Since(info.ModTime()) < sharedSyncInterval {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, sharedSyncTimeout)
+	defer cancel()
+	log := logger.Default()
+
+	var repo *git.Repo
+	if _, err := os.Stat(filepath.Join(cfg.SharedDir, ".git")); err == nil {
+		if repo, err = git.Open(cfg.SharedDir, log); err != nil {
+			return err
+		}
+		repo.SetContext(ctx)
+		// A different repository replaces the clone
+		if remote, err := repo.RemoteURL("origin"); err != nil || remote != cfg.Shared {
+			repo = nil
+		}
+	}
+	if repo != nil {
+		if err := repo.Update("origin"); err != nil {
+			return err
+		}
+	} else {
+		if err := os.RemoveAll(cfg.SharedDir); err != nil {
+			return errors.FilesystemError("sync_shared", cfg.SharedDir, err)
+		}
+		if _, err := git.Clone(ctx, cfg.Shared, cfg.SharedDir, log); err != nil {
+			return err
+		}
+	}
+	log.Debug("Synced the shared settings from %s", cfg.Shared)
+	return os.WriteFile(stamp, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// Settings shows the settings in effect, sets or removes a key, opens the
+// settings in an editor, exports them with the user's recipes and error
+// patterns to a bundle, imports a bundle, or syncs the shared settings. With
+// repo, set, unset and edit change the repository's .sentinel.yml instead
+// of the user's settings.
+func (o *Orchestrator) Settings(opts Options, action string, args []string, repo bool) error {
+	o.opts = opts
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+	switch action {
+	case "", "show":
+		return o.showSettings()
+	case "set":
+		value := arg(1)
+		return o.setSetting(arg(0), &value, repo)
+	case "unset":
+		return o.setSetting(arg(0), nil, repo)
+	case "edit":
+		return o.editSettings(repo)
+	case "export":
+		return o.exportSettings(arg(0))
+	case "import":
+		return o.importSettings(arg(0))
+	case "sync":
+		if o.config.Shared == "" {
+			return errors.ValidationError("sync_shared", "no shared settings: set shared: in "+o.config.SettingsFile+" or SENTINEL_SHARED_CONFIG")
+		}
+		if err := syncShared(o.ctx, o.config, true); err != nil {
+			return err
+		}
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Synced the shared settings from %s", o.config.Shared)))
+		fmt.Fprintln(o.out, ui.FormatDim("They apply from the next command"))
+		return nil
+	}
+	return fmt.Errorf("unknown config action %q (expected show, set, unset, edit, export, import or sync)", action)
+}
+
+// showSettings prints where the settings come from and those that differ
+// from the defaults
+func (o *Orchestrator) showSettings() error {
+	settings := config.SettingsOf(o.config)
+	var synced time.Time
+	if info, err := os.Stat(syncStamp(o.config)); err == nil && o.config.Shared != "" {
+		synced = info.ModTime()
+	}
+
+	if o.opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			SettingsFile     string              `json:"settings_file"`
+			RepoSettingsFile string              `json:"repo_settings_file,omitempty"`
+			RecipesFile      string              `json:"recipes_file"`
+			PatternsFile     string              `json:"patterns_file"`
+			Shared           string              `json:"shared,omitempty"`
+			SyncedAt         time.Time           `json:"synced_at,omitzero"`
+			Settings         *config.Settings    `json:"settings"`
+			Sources          map[string][]string `json:"sources,omitempty"` // Keys each file sets
+		}{o.config.SettingsFile, o.config.RepoSettingsFile, o.config.RecipesFile, o.config.PatternsFile, settings.Shared, synced, settings, o.settingsSources()})
+	}
+
+	fmt.Fprintf(o.out, "settings: %s\n", o.config.SettingsFile)
+	if o.config.RepoSettingsFile != "" {
+		fmt.Fprintf(o.out, "repo:     %s\n", o.config.RepoSettingsFile)
+	}
+	fmt.Fprintf(o.out, "recipes:  %s\n", o.config.RecipesFile)
+	fmt.Fprintf(o.out, "patterns: %s\n", o.config.PatternsFile)
+	switch {
+	case o.config.Shared == "":
+		fmt.Fprintln(o.out, ui.FormatDim("No shared settings. Point shared: in the settings file (or SENTINEL_SHARED_CONFIG) at a git repository to follow your team's."))
+	case synced.IsZero():
+		fmt.Fprintf(o.out, "shared:   %s %s\n", settings.Shared, ui.FormatWarning("never synced"))
+	default:
+		fmt.Fprintf(o.out, "shared:   %s %s\n", settings.Shared, ui.FormatDim("(synced "+synced.Format("Jan 02 15:04")+")"))
+	}
+	fmt.Fprintln(o.out)
+
+	settings.Shared = ""
+	if settings.Empty() {
+		fmt.Fprintln(o.out, ui.FormatInfo("All settings have their default values"))
+		return nil
+	}
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, ui.FormatHeader("Settings that differ from the defaults:"))
+	fmt.Fprint(o.out, string(data))
+
+	sources := o.settingsSources()
+	fmt.Fprintln(o.out)
+	for _, file := range []string{filepath.Join(o.config.SharedDir, settingsFileName), o.config.SettingsFile, o.config.RepoSettingsFile} {
+		if keys := sources[file]; len(keys) > 0 {
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Set in %s: %s", file, strings.Join(keys, ", "))))
+		}
+	}
+	fmt.Fprintln(o.out, ui.FormatDim("Later files win; environment variables and flags override them all"))
+	return nil
+}
+
+// settingsSources returns the keys each settings file in effect sets, by
+// file
+func (o *Orchestrator) settingsSources() map[string][]string {
+	files := []string{o.config.SettingsFile}
+	if o.config.Shared != "" {
+		files = append(files, filepath.Join(o.config.SharedDir, settingsFileName))
+	}
+	if o.config.RepoSettingsFile != "" {
+		files = append(files, o.config.RepoSettingsFile)
+	}
+	sources := make(map[string][]string)
+	for _, file := range files {
+		settings, err := config.LoadSettings(file)
+		if err != nil {
+			continue
+		}
+		if file == filepath.Join(o.config.SharedDir, settingsFileName) {
+			settings = settings.Without(settings.UserOnlyKeys())
+		}
+		settings.Shared = ""
+		if keys := settings.Keys(); len(keys) > 0 {
+			sources[file] = keys
+		}
+	}
+	return sources
+}
+
+// settingsTarget returns the settings file set, unset and edit change: the
+// user's, or the repository's with repo
+func (o *Orchestrator) settingsTarget(repo bool) (string, error) {
+	if !repo {
+		return o.config.SettingsFile, nil
+	}
+	path, err := repoSettingsPath()
+	if err != nil {
+		return "", fmt.Errorf("--repo needs a git repository: %w", err)
+	}
+	return path, nil
+}
+
+// setSetting sets a key of a settings file, or removes it when value is nil,
+// after checking the file would still be valid
+func (o *Orchestrator) setSetting(key string, value *string, repo bool) error {
+	if key == "" {
+		return errors.ValidationError("set_setting", "expected a settings key, e.g. gh sentinel config set min_confidence MEDIUM")
+	}
+	path, err := o.settingsTarget(repo)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.FilesystemError("set_setting", path, err)
+	}
+	data, err = config.SetSetting(data, key, value)
+	if err != nil {
+		return err
+	}
+	if err := checkSettings(data, path, repo); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.FilesystemError("set_setting", path, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return errors.FilesystemError("set_setting", path, err)
+	}
+
+	if value == nil {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Removed %s from %s", key, path)))
+	} else {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Set %s in %s", key, path)))
+	}
+	return nil
+}
+
+// editSettings opens a settings file in $VISUAL or $EDITOR and checks it
+// once the editor exits
+func (o *Orchestrator) editSettings(repo bool) error {
+	if !ui.IsTerminal() {
+		return errors.ValidationError("edit_settings", "config edit needs a terminal; use gh sentinel config set <key> <value>")
+	}
+	path, err := o.settingsTarget(repo)
+	if err != nil {
+		return err
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.FilesystemError("edit_settings", path, err)
+	}
+
+	args := append(strings.Fields(editor), path)
+	cmd := exec.CommandContext(o.ctx, args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		fmt.Fprintln(o.out, ui.FormatDim("No settings were saved"))
+		return nil
+	}
+	if err != nil {
+		return errors.FilesystemError("edit_settings", path, err)
+	}
+	if err := checkSettings(data, path, repo); err != nil {
+		return fmt.Errorf("%s was saved but is invalid, fix it before the next command: %w", path, err)
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s is valid", path)))
+	return nil
+}
+
+// checkSettings validates settings file data: its keys, what a repository
+// may set when repo is set, and the configuration it results in
+func checkSettings(data []byte, path string, repo bool) error {
+	settings, err := config.ParseSettings(data, path)
+	if err != nil {
+		return err
+	}
+	if repo {
+		if err := settings.CheckRepository(path); err != nil {
+			return err
+		}
+	}
+	cfg := config.Default()
+	settings.Apply(cfg)
+	if err := cfg.Validate(); err != nil {
+		return errors.ValidationError("check_settings", fmt.Sprintf("invalid settings in %s: %v", path, err))
+	}
+	if err := ui.CheckTheme(cfg.Theme); err != nil {
+		return errors.ValidationError("check_settings", fmt.Sprintf("invalid settings in %s: %v", path, err))
+	}
+	return nil
+}
+
+// exportSettings writes the settings of the shared and user settings files
+// and the user's recipes and error patterns to a gzipped tar bundle. The
+// repository's .sentinel.yml, environment variables and flags are left out,
+// so they do not become user-wide settings where the bundle is imported.
+// Credentials never enter the settings, and are removed from URLs.
+func (o *Orchestrator) exportSettings(path string) error {
+	if path == "" {
+		return errors.ValidationError("export_settings", "export expects a bundle path, e.g. gh sentinel config export sentinel.tgz")
+	}
+	cfg := config.Default()
+	if o.config.Shared != "" {
+		shared, _, err := loadSharedSettings(o.config)
+		if err != nil {
+			return err
+		}
+		shared.Apply(cfg)
+	}
+	user, err := config.LoadSettings(o.config.SettingsFile)
+	if err != nil {
+		return err
+	}
+	user.Apply(cfg)
+	settings, err := yaml.Marshal(config.SettingsOf(cfg))
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{settingsFileName: settings}
+	for name, source := range map[string]string{recipesFileName: o.config.RecipesFile, patternsFileName: o.config.PatternsFile} {
+		data, err := os.ReadFile(source)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.FilesystemError("export_settings", source, err)
+		}
+		if err == nil {
+			files[name] = data
+		}
+	}
+
+	manifest := bundleManifest{Format: bundleFormat, Version: o.config.Version, CreatedAt: time.Now().UTC()}
+	for _, name := range bundleFiles {
+		if _, ok := files[name]; ok {
+			manifest.Files = append(manifest.Files, name)
+		}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range append([]string{manifestFileName}, manifest.Files...) {
+		content := files[name]
+		if name == manifestFileName {
+			content = data
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: manifest.CreatedAt}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return errors.FilesystemError("export_settings", path, err)
+	}
+
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Exported %s to %s", strings.Join(manifest.Files, ", "), path)))
+	fmt.Fprintln(o.out, ui.FormatDim("Teammates apply it with: gh sentinel config import "+filepath.Base(path)))
+	return nil
+}
+
+// importSettings validates a bundle and installs its settings, recipes and
+// error patterns, keeping the replaced files as .bak. Settings that only the
+// user's own settings may set, like endpoints and hooks, are installed only
+// once confirmed.
+func (o *Orchestrator) importSettings(path string) error {
+	if path == "" {
+		return errors.ValidationError("import_settings", "import expects a bundle path, e.g. gh sentinel config import sentinel.tgz")
+	}
+	files, err := readBundle(path)
+	if err != nil {
+		return err
+	}
+
+	// Nothing is installed unless everything is valid
+	if data, ok := files[settingsFileName]; ok {
+		if err := checkSettings(data, path+":"+settingsFileName, false); err != nil {
+			return err
+		}
+		confirmed, err := o.confirmUserOnly(data, path+":"+settingsFileName)
+		if err != nil || !confirmed {
+			return err
+		}
+	}
+	details := make(map[string]string)
+	if data, ok := files[recipesFileName]; ok {
+		recipes, err := fixer.NewRegistry().Load(data, path+":"+recipesFileName)
+		if err != nil {
+			return err
+		}
+		details[recipesFileName] = fmt.Sprintf(" (%d recipes)", recipes)
+	}
+	if data, ok := files[patternsFileName]; ok {
+		patterns, err := analyzer.NewAnalyzer(o.logger).LoadPatterns(data, path+":"+patternsFileName)
+		if err != nil {
+			return err
+		}
+		details[patternsFileName] = fmt.Sprintf(" (%d error patterns)", patterns)
+	}
+
+	targets := map[string]string{
+		settingsFileName: o.config.SettingsFile,
+		recipesFileName:  o.config.RecipesFile,
+		patternsFileName: o.config.PatternsFile,
+	}
+	for _, name := range bundleFiles {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		target := targets[name]
+		if old, err := os.ReadFile(target); err == nil && !bytes.Equal(old, data) {
+			if err := os.WriteFile(target+".bak", old, 0644); err != nil {
+				return errors.FilesystemError("import_settings", target+".bak", err)
+			}
+			fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Kept the previous %s as %s.bak", name, target)))
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return errors.FilesystemError("import_settings", target, err)
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return errors.FilesystemError("import_settings", target, err)
+		}
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Installed %s%s", target, details[name])))
+	}
+	fmt.Fprintln(o.out, ui.FormatDim("The settings apply from the next command; environment variables and flags still take precedence"))
+	return nil
+}
+
+// confirmUserOnly asks before importing settings that choose where
+// credentials and logs go or run code, which a repository's settings cannot
+// set. It reports whether the import goes on.
+func (o *Orchestrator) confirmUserOnly(data []byte, path string) (bool, error) {
+	settings, err := config.ParseSettings(data, path)
+	if err != nil {
+		return false, err
+	}
+	keys := settings.UserOnlyKeys()
+	if len(keys) == 0 {
+		return true, nil
+	}
+	restricted, err := yaml.Marshal(settings.Only(keys))
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Fprintln(o.out, ui.FormatWarning("The bundle sets where credentials and logs go or what code runs:"))
+	fmt.Fprint(o.out, string(restricted))
+	fmt.Fprintln(o.out)
+	switch {
+	case o.opts.Yes:
+	case !o.interactive():
+		return false, fmt.Errorf("refusing to import %s without confirmation; pass --yes", strings.Join(keys, ", "))
+	default:
+		confirmed, err := ui.ShowConfirmation(
+			fmt.Sprintf("Import %s?", strings.Join(keys, ", ")),
+			"Only import them from someone you trust: they can send your GitHub and AI credentials to other hosts or run hooks",
+		)
+		if err != nil {
+			return false, fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Fprintln(o.out, ui.FormatDim("Import cancelled by user"))
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// readBundle reads the files of a settings bundle, rejecting anything that
+// is not one
+func readBundle(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.FilesystemError("import_settings", path, err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.ValidationError("import_settings", "not a settings bundle (expected a .tgz made by gh sentinel config export)").WithPath(path)
+	}
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.ValidationError("import_settings", fmt.Sprintf("corrupt bundle: %v", err)).WithPath(path)
+		}
+		switch header.Name {
+		case manifestFileName, settingsFileName, recipesFileName, patternsFileName:
+		default:
+			return nil, errors.ValidationError("import_settings", fmt.Sprintf("unexpected file %q in the bundle", header.Name)).WithPath(path)
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > maxBundleEntry {
+			return nil, errors.ValidationError("import_settings", fmt.Sprintf("%s in the bundle is not a regular file under 1 MiB", header.Name)).WithPath(path)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxBundleEntry))
+		if err != nil {
+			return nil, errors.ValidationError("import_settings", fmt.Sprintf("corrupt bundle: %v", err)).WithPath(path)
+		}
+		files[header.Name] = data
+	}
+
+	var manifest bundleManifest
+	if data, ok := files[manifestFileName]; !ok || json.Unmarshal(data, &manifest) != nil {
+		return nil, errors.ValidationError("import_settings", "not a settings bundle: its manifest is missing").WithPath(path)
+	}
+	if manifest.Format > bundleFormat {
+		return nil, errors.ValidationError("import_settings", fmt.Sprintf("the bundle was made by a newer sentinel (%s); update to import it", manifest.Version)).WithPath(path)
+	}
+	delete(files, manifestFileName)
+	return files, nil
+}
@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/expr"
+	"gh-sentinel/pkg/github"
+)
+
+// explainSkipped explains jobs and steps of the run that were skipped
+// because their if: condition was false for the run's event and branch
+func (o *Orchestrator) explainSkipped(runID int64, path, content string) {
+	conditions, err := expr.Conditions(path, content)
+	if err != nil || len(conditions) == 0 {
+		return
+	}
+	run, err := o.github.GetWorkflowRun(runID)
+	if err != nil {
+		o.logger.Debug("Cannot explain skipped jobs: %v", err)
+		return
+	}
+	jobs, err := o.github.ListRunJobs(runID)
+	if err != nil {
+		o.logger.Debug("Cannot explain skipped jobs: %v", err)
+		return
+	}
+
+	scope := o.runScope(run)
+	o.report.Skipped = nil
+	for _, cond := range conditions {
+		if !wasSkipped(cond, jobs) {
+			continue
+		}
+		tree, err := expr.Parse(cond.Expression)
+		if err != nil || expr.Truth(tree, scope) != expr.TriFalse {
+			continue
+		}
+		o.report.Skipped = append(o.report.Skipped, ReportSkipped{
+			Job:       cond.Job,
+			Step:      cond.Step,
+			Line:      cond.Line,
+			Condition: cond.Expression,
+			Values:    knownValues(tree, scope),
+		})
+	}
+	if len(o.report.Skipped) == 0 {
+		return
+	}
+
+	fmt.Fprintln(o.out, ui.FormatInfo("⏭  Skipped because their if: condition was false:"))
+	for _, s := range o.report.Skipped {
+		what := "job " + s.Job
+		if s.Step != "" {
+			what = fmt.Sprintf("step %q of job %s", s.Step, s.Job)
+		}
+		fmt.Fprintf(o.out, "  • %s (line %d): %s\n", what, s.Line, ui.FormatHighlight(s.Condition))
+		for _, value := range s.Values {
+			fmt.Fprintf(o.out, "      %s\n", ui.FormatDim(value))
+		}
+	}
+	fmt.Fprintln(o.out)
+}
+
+// runScope is the part of the github context known from the run itself
+func (o *Orchestrator) runScope(run *github.WorkflowRun) expr.Scope {
+	repo := o.github.GetRepository()
+	ctx := map[string]expr.Value{
+		"event_name":       run.Event,
+		"ref_name":         run.HeadBranch,
+		"sha":              run.HeadSHA,
+		"repository":       repo.FullName,
+		"repository_owner": repo.Owner,
+		"run_id":           float64(run.ID),
+		"run_number":       float64(run.RunNumber),
+		"run_attempt":      float64(run.Attempt),
+	}
+	if strings.HasPrefix(run.Event, "pull_request") {
+		ctx["head_ref"] = run.HeadBranch
+	}
+	return expr.Scope{"github": ctx}
+}
+
+// wasSkipped reports whether the job or step of a condition was skipped.
+// Jobs are matched by name, including matrix variants; unnamed steps
+// cannot be matched to the run and are never reported.
+func wasSkipped(cond expr.Condition, jobs []*github.Job) bool {
+	for _, job := range jobs {
+		if job.Name != cond.JobName && !strings.HasPrefix(job.Name, cond.JobName+" (") {
+			continue
+		}
+		if cond.Step == "" {
+			if job.Conclusion == "skipped" {
+				return true
+			}
+			continue
+		}
+		for _, step := range job.Steps {
+			if step.Name == cond.Step && step.Conclusion == "skipped" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// knownValues lists the values the condition read, e.g. github.ref_name = 'dev'
+func knownValues(tree expr.Node, scope expr.Scope) []string {
+	var values []string
+	for _, ref := range expr.References(tree) {
+		node, err := expr.Parse(ref)
+		if err != nil {
+			continue
+		}
+		value := expr.Eval(node, scope)
+		if _, unknown := value.(expr.Unknown); unknown {
+			continue
+		}
+		values = append(values, fmt.Sprintf("%s = %s", ref, (&expr.Literal{Value: value}).String()))
+	}
+	return values
+}
@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/workflow"
+)
+
+// Bounds of the repository files other than workflows that go to the AI
+const (
+	maxSourceFiles    = 5
+	maxSourceFileSize = 64 * 1024
+)
+
+// sourceFiles reads the files of the repository other than workflows that
+// the logs point to, by path, so the AI can fix a failure of the application
+// in them: the dependency manifests and Dockerfiles the logs name, then the
+// source files of the failure. They are read from the checkout, or from the
+// default branch with --repo. Content in pending, a fix proposed earlier in
+// the batch, replaces the file's.
+func (o *Orchestrator) sourceFiles(logs string, pending map[string]string) map[string]string {
+	candidates := analyzer.BuildFiles(logs, maxSourceFiles)
+	for _, name := range analyzer.Implicated(logs, maxCulprits) {
+		// Tests are named without a file, e.g. TestParse
+		file, _, _ := strings.Cut(name, ":")
+		if path.Ext(file) != "" && !workflow.IsWorkflowFile(file) {
+			candidates = append(candidates, file)
+		}
+	}
+
+	sources := make(map[string]string)
+	for _, file := range candidates {
+		if len(sources) == maxSourceFiles {
+			break
+		}
+		if _, seen := sources[file]; seen {
+			continue
+		}
+		content, err := o.readSource(file, pending)
+		if err != nil {
+			o.logger.Debug("Could not read %s, which the logs point to: %v", file, err)
+			continue
+		}
+		if len(content) > maxSourceFileSize {
+			o.logger.Debug("Leaving %s out of the diagnosis: %d bytes", file, len(content))
+			continue
+		}
+		sources[file] = content
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("The logs point to %s; it is part of the diagnosis", ui.FormatHighlight(file))))
+	}
+	return sources
+}
+
+// readSource reads a file of the repository from where a fix of it is
+// applied
+func (o *Orchestrator) readSource(file string, pending map[string]string) (string, error) {
+	if !inCheckout(file) {
+		return "", fmt.Errorf("%s is outside the repository", file)
+	}
+	if content, ok := pending[file]; ok {
+		return content, nil
+	}
+	if o.config.Repository != "" {
+		repo := o.github.GetRepository()
+		return o.github.GetRemoteFileContent(repo.FullName, file, repo.DefaultBranch)
+	}
+	content, err := os.ReadFile(file)
+	return string(content), err
+}
+
+// inCheckout reports whether a path the logs or the AI name is a file of
+// the checkout, rather than one outside it reached through an absolute path,
+// a drive letter or .. segments
+func inCheckout(file string) bool {
+	local := filepath.FromSlash(file)
+	if !filepath.IsLocal(local) {
+		return false
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, filepath.Clean(filepath.Join(root, local)))
+	return err == nil && filepath.IsLocal(rel)
+}
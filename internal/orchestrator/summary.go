@@ -0,0 +1,220 @@
+package orchestrator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/report"
+)
+
+// sessionRecap is what a fix session did, as rendered at its end and stored
+// in the history file
+type (
+	sessionRecap = report.Session
+	sessionRun   = report.SessionRun
+)
+
+// finishSession renders the outcome of the session with the selected
+// reporter and appends its recap to the history file. Sessions that analyzed
+// no run leave no recap.
+func (o *Orchestrator) finishSession(started time.Time, format report.Format, err error) error {
+	recap := o.recap(started, err)
+	if len(recap.Runs) > 0 {
+		if err := appendRecap(o.config.HistoryFile, recap); err != nil {
+			o.logger.Warn("Could not record the session: %v", err)
+		}
+		o.recordPatches(recap)
+		o.countSession()
+	}
+
+	if err != nil {
+		o.report.Status = StatusError
+		o.report.Error = err.Error()
+		if o.ctx.Err() != nil {
+			o.report.Status = StatusInterrupted
+		}
+	}
+	w := o.out
+	if format.Stdout {
+		w = os.Stdout
+	}
+	return format.New().Render(w, &report.Outcome{
+		Report:   o.report,
+		Session:  recap,
+		Version:  o.config.Version,
+		Language: o.config.Language,
+	})
+}
+
+// recap summarizes the reports of the session
+func (o *Orchestrator) recap(started time.Time, err error) *sessionRecap {
+	recap := &sessionRecap{StartedAt: started, FinishedAt: time.Now()}
+
+	for _, report := range o.session {
+		recap.Repository = report.Repository
+	}
+	reports := o.sessionReports()
+	for i, report := range reports {
+		run := sessionRun{
+			RunID:    report.RunID,
+			Workflow: report.Workflow,
+			Status:   report.Status,
+			Tag:      report.Tag,
+			Error:    report.Error,
+		}
+		// An error ending the session belongs to the run in progress
+		if err != nil && i == len(reports)-1 && run.Error == "" {
+			run.Status = StatusError
+			run.Error = err.Error()
+			if o.ctx.Err() != nil {
+				run.Status = StatusInterrupted
+				run.Error = ""
+			}
+		}
+		if d := report.Diagnosis; d != nil {
+			run.Diagnosis = d.Source
+			if d.Confidence != "" {
+				run.Diagnosis += ", " + d.Confidence
+			}
+			if d.Cached {
+				run.Diagnosis += ", cached"
+			}
+			run.Target = d.Target
+		}
+		if p := report.Patch; p != nil {
+			run.Repository = p.Repository
+			run.BackupPath = p.BackupPath
+			if p.Pushed {
+				run.Branch = p.Branch
+				run.Commit = p.Commit
+			}
+			run.PullRequest = p.PullRequest
+			run.PullURL = p.PullURL
+			for _, hook := range p.Hooks {
+				if !hook.Passed {
+					run.HooksFailed = append(run.HooksFailed, hook.Name)
+				}
+			}
+		}
+		if f := report.Flaky; f != nil {
+			run.Flaky = flakySummary(f)
+		}
+		if report.Rerun != nil {
+			run.Rerun = report.Rerun.Conclusion
+			if run.Rerun == "" {
+				run.Rerun = report.Rerun.Status
+			}
+		}
+		recap.Runs = append(recap.Runs, run)
+	}
+	recap.NextSteps = nextSteps(recap.Runs)
+	return recap
+}
+
+// nextSteps recommends what to do after the session
+func nextSteps(runs []sessionRun) []string {
+	var steps []string
+	seen := make(map[string]bool)
+	add := func(step string) {
+		if !seen[step] {
+			seen[step] = true
+			steps = append(steps, step)
+		}
+	}
+
+	var unpushed []string
+	for _, run := range runs {
+		switch run.Status {
+		case StatusApplied:
+			switch {
+			case run.Branch != "":
+				add(fmt.Sprintf("Open a pull request: gh pr create --head %s", run.Branch))
+			case run.Target != "" && !seen["file:"+run.Target]:
+				seen["file:"+run.Target] = true
+				unpushed = append(unpushed, run.Target)
+			}
+			if len(run.HooksFailed) > 0 {
+				add(fmt.Sprintf("Fix what the commit hooks report for %s before committing: %s", run.Target, strings.Join(run.HooksFailed, ", ")))
+			}
+			if run.Rerun != "" && run.Rerun != "success" {
+				add(fmt.Sprintf("The re-run of run #%d ended with %s: diagnose it again with gh sentinel fix", run.RunID, run.Rerun))
+			}
+		case StatusAwaitingReview:
+			add(fmt.Sprintf("Ask for a review of the fix for run #%d: %s", run.RunID, run.PullURL))
+			add("Follow pending reviews with: gh sentinel history")
+		case StatusProposed, StatusDeclined, StatusTargetNotFound, StatusInvalid:
+			add(fmt.Sprintf("Revisit run #%d: gh sentinel fix --run-id %d", run.RunID, run.RunID))
+		case StatusFlaky:
+			if run.Rerun == "" {
+				add(fmt.Sprintf("Re-run the flaky jobs of run #%d: gh run rerun %d --failed", run.RunID, run.RunID))
+			} else {
+				add(fmt.Sprintf("Follow the re-run of run #%d: gh run watch %d", run.RunID, run.RunID))
+			}
+		case StatusInterrupted:
+			add(fmt.Sprintf("Resume run #%d: gh sentinel fix --run-id %d", run.RunID, run.RunID))
+		case StatusNoFix, StatusError:
+			add(fmt.Sprintf("Read the failed logs of run #%d: gh run view %d --log-failed", run.RunID, run.RunID))
+		}
+	}
+	if len(unpushed) > 0 {
+		steps = append([]string{
+			fmt.Sprintf("Review and commit the patched files: git add %s && git commit", strings.Join(unpushed, " ")),
+			"Push to trigger new workflow runs",
+		}, steps...)
+		add("Undo a patch with: gh sentinel rollback <file>")
+	}
+	return steps
+}
+
+// appendRecap adds a recap to the history file
+func appendRecap(path string, recap *sessionRecap) error {
+	data, err := json.Marshal(recap)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readRecaps returns the last n recaps of the history file, newest first
+func readRecaps(path string, n int) ([]sessionRecap, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recaps []sessionRecap
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var recap sessionRecap
+		if err := json.Unmarshal(scanner.Bytes(), &recap); err != nil {
+			continue // Skip lines from interrupted writes
+		}
+		recaps = append(recaps, recap)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(recaps) > n {
+		recaps = recaps[len(recaps)-n:]
+	}
+	for i, j := 0, len(recaps)-1; i < j; i, j = i+1, j-1 {
+		recaps[i], recaps[j] = recaps[j], recaps[i]
+	}
+	return recaps, nil
+}
@@ -0,0 +1,146 @@
+package orchestrator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gh-sentinel/internal/config"
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/github"
+)
+
+// ignoredTag returns why a failure of a category is not diagnosed: the
+// settings ignore it, or --category leaves it out. It is empty when the
+// failure is diagnosed.
+func (o *Orchestrator) ignoredTag(tag string) string {
+	if tag != "" && slices.Contains(o.config.IgnoreCategories, tag) {
+		return fmt.Sprintf("%s failures are ignored (ignore_categories)", tag)
+	}
+	if o.selectedTag(tag) {
+		return ""
+	}
+	selected := strings.Join(o.opts.Categories, ",")
+	if tag == "" {
+		return fmt.Sprintf("the failure has no category, and --category only selects %s", selected)
+	}
+	return fmt.Sprintf("%s failures are left out by --category %s", tag, selected)
+}
+
+// selectedTag reports whether --category selects a failure category
+func (o *Orchestrator) selectedTag(tag string) bool {
+	return len(o.opts.Categories) == 0 || slices.Contains(o.opts.Categories, tag)
+}
+
+// skipIgnored marks the run as ignored when its failure category is not to
+// be diagnosed, and reports whether it is
+func (o *Orchestrator) skipIgnored() bool {
+	reason := o.ignoredTag(o.report.Tag)
+	if reason == "" {
+		return false
+	}
+	o.report.Status = StatusIgnored
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Not diagnosing run #%d: %s", o.report.RunID, reason)))
+	return true
+}
+
+// withoutIgnoredWorkflows drops the runs of workflows ignore_workflows
+// lists
+func (o *Orchestrator) withoutIgnoredWorkflows(runs []*github.WorkflowRun) []*github.WorkflowRun {
+	if len(o.config.IgnoreWorkflows) == 0 {
+		return runs
+	}
+	var kept []*github.WorkflowRun
+	for _, run := range runs {
+		if matchWorkflow(o.config.IgnoreWorkflows, run.WorkflowPath) {
+			o.logger.Debug("Ignoring run #%d: %s is ignored (ignore_workflows)", run.ID, run.WorkflowPath)
+			continue
+		}
+		kept = append(kept, run)
+	}
+	return kept
+}
+
+// classifyRun returns the failure category of a run from its logs. It is
+// empty when they cannot be fetched or no classified pattern matches.
+func (o *Orchestrator) classifyRun(runID int64) string {
+	_, analysis, err := o.fetchJobLogs(runID)
+	if err != nil {
+		o.logger.Debug("Cannot classify run #%d: %v", runID, err)
+		return ""
+	}
+	return analysis.Tag
+}
+
+// checkDisagreement compares the failure category of the log patterns with
+// the ones an AI diagnosis's explanation points to. When the explanation
+// never mentions the patterns' category, the disagreement is recorded and
+// the diagnosis's confidence lowered one level, so that min_confidence and
+// --yes do not take the AI's word over the evidence.
+func (o *Orchestrator) checkDisagreement(diagnosis *copilot.DiagnosisResult) {
+	d := o.report.Diagnosis
+	if d == nil || d.Source != "ai" || o.report.Tag == "" || diagnosis.Confidence == "HEALTHY" {
+		return
+	}
+	blamed := analyzer.MentionedTags(diagnosis.Explanation)
+	if len(blamed) == 0 || slices.Contains(blamed, o.report.Tag) {
+		return
+	}
+	d.Disagreement = &ReportDisagreement{Pattern: o.report.Tag, AI: blamed[0], AIConfidence: diagnosis.Confidence}
+	if rank := config.ConfidenceRank(diagnosis.Confidence); rank > 0 {
+		diagnosis.Confidence = config.ConfidenceLevels[rank-1]
+		d.Confidence = diagnosis.Confidence
+	}
+	o.logger.Debug("Run #%d: the AI blames %v, the log patterns %s", o.report.RunID, blamed, o.report.Tag)
+}
+
+// maxCulprits bounds the files, tests and services a failure is blamed on
+const maxCulprits = 5
+
+// reportRootCause reports a failure whose root cause is not the workflow,
+// with the culprit the AI named and the tests and source files the logs
+// point to, instead of proposing a fix. The run ends without a fix, so an
+// issue can be filed for it.
+func (o *Orchestrator) reportRootCause(cause, culprit, logs string) {
+	o.report.Status = StatusNoFix
+	o.report.RootCause = cause
+	o.report.Culprits = nil
+	if culprit != "" {
+		o.report.Culprits = append(o.report.Culprits, culprit)
+	}
+	for _, implicated := range analyzer.Implicated(logs, maxCulprits) {
+		if len(o.report.Culprits) < maxCulprits && !slices.Contains(o.report.Culprits, implicated) {
+			o.report.Culprits = append(o.report.Culprits, implicated)
+		}
+	}
+
+	fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("The root cause is in %s, not in the workflow: no workflow change is proposed", rootCauseWhere(cause))))
+	for _, culprit := range o.report.Culprits {
+		fmt.Fprintf(o.out, "  • %s\n", ui.FormatHighlight(culprit))
+	}
+	fmt.Fprintln(o.out)
+}
+
+// rootCauseWhere names where a failure of a root cause has to be fixed
+func rootCauseWhere(cause string) string {
+	if cause == analyzer.RootCauseInfrastructure {
+		return "the infrastructure (a service, registry or runner)"
+	}
+	return "the application code"
+}
+
+// disputed reports whether the AI diagnosis of a run disagrees with its log
+// patterns
+func disputed(run *Report) bool {
+	return run.Diagnosis != nil && run.Diagnosis.Disagreement != nil
+}
+
+// tagOf names the failure category of a run for display, e.g. "[dependency]"
+func tagOf(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	return "[" + tag + "]"
+}
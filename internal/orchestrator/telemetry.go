@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gh-sentinel/internal/telemetry"
+	"gh-sentinel/internal/ui"
+)
+
+// countSession adds the outcome of each run of the session to the usage
+// counters. Nothing is counted unless telemetry was enabled.
+func (o *Orchestrator) countSession() {
+	err := telemetry.Record(o.config.TelemetryFile, o.config.Version, func(c *telemetry.Counters) {
+		for _, report := range o.session {
+			runs := report.Runs
+			if len(runs) == 0 && report.RunID != 0 {
+				runs = []*Report{report}
+			}
+			for _, run := range runs {
+				if run.Status == StatusApplied {
+					c.Fixes["applied"]++
+				} else if run.Diagnosis != nil {
+					c.Fixes["not_applied"]++
+				}
+				if run.Diagnosis != nil && run.Diagnosis.Source == "ai" && !run.Diagnosis.Cached {
+					c.Providers[o.config.AI.Provider]++
+				}
+				if run.Category != "" {
+					c.Patterns[run.Category]++
+				}
+				if run.Tag != "" {
+					c.Tags[run.Tag]++
+				}
+			}
+		}
+	})
+	if err != nil {
+		o.logger.Debug("Could not record usage counters: %v", err)
+	}
+}
+
+// Telemetry shows the telemetry setting and exactly what would be sent,
+// or turns telemetry on or off
+func (o *Orchestrator) Telemetry(opts Options, action string) error {
+	o.opts = opts
+
+	state, err := telemetry.Load(o.config.TelemetryFile)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "enable", "disable":
+		state.Telemetry = action == "enable"
+		if !state.Telemetry {
+			state.Pending = telemetry.Counters{} // Never send what was counted before opting out
+		}
+		if err := state.Save(o.config.TelemetryFile); err != nil {
+			return err
+		}
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("telemetry: %t", state.Telemetry)))
+		return nil
+	case "", "show":
+	default:
+		return fmt.Errorf("unknown telemetry action %q (expected show, enable or disable)", action)
+	}
+
+	if opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(state)
+	}
+
+	fmt.Fprintf(o.out, "telemetry: %t\n", state.Telemetry)
+	if !state.Telemetry {
+		fmt.Fprintln(o.out, ui.FormatDim("Nothing is counted or sent. Enable with: gh sentinel telemetry enable"))
+		return nil
+	}
+
+	destination := o.config.TelemetryEndpoint
+	if destination == "" {
+		destination = "nowhere (this build has no telemetry endpoint; counters stay local)"
+	}
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Counters are kept in %s and sent at most once a day to %s", o.config.TelemetryFile, destination)))
+	fmt.Fprintln(o.out)
+	if state.Pending.Empty() {
+		fmt.Fprintln(o.out, ui.FormatInfo("Nothing counted since the last report"))
+		return nil
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo("The next report, exactly as it would be sent:"))
+	payload, err := json.MarshalIndent(state.Pending, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, string(payload))
+	return nil
+}
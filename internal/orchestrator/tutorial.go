@@ -0,0 +1,301 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/eval"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/workflow"
+)
+
+// tutorialWorkflow is the workflow of the demo failure: setup-node installs
+// an older Node.js than the project's engines field requires
+const tutorialWorkflow = `name: CI
+
+on:
+  push:
+    branches: [main]
+  pull_request:
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-node@v4
+        with:
+          node-version: 16
+      - run: npm ci
+      - run: npm test
+`
+
+// tutorialLogs are the logs of the demo failure's failed job
+const tutorialLogs = `
+=== Job: build (ID: 1, conclusion: failure, runs-on: ubuntu-latest) ===
+Failed step: Run npm ci
+--- Step 1: Set up job (success, 2s) ---
+Current runner version: '2.319.1'
+--- Step 2: Run actions/checkout@v4 (success, 1s) ---
+Syncing repository: octo-org/demo-app
+--- Step 3: Run actions/setup-node@v4 (success, 3s) ---
+Attempting to download 16...
+Found in cache @ /opt/hostedtoolcache/node/16.20.2/x64
+--- Step 4: Run npm ci (failure, 4s) ---
+npm ERR! code EBADENGINE
+error demo-app@1.0.0: The engine "node" is incompatible with this module. Expected version ">=20". Got "16.20.2"
+npm ERR! notsup Required: {"node":">=20"}
+##[error]Process completed with exit code 1.
+--- Step 5: Run npm test ---
+`
+
+// tutorialStages names the stages of a session, in order
+var tutorialStages = []string{"Detection", "Analysis", "Diagnosis", "Diff review", "Apply", "Verify"}
+
+// Tutorial walks through a session stage by stage on the demo failure, or
+// on a case directory of an eval corpus, explaining each stage as it runs.
+// Nothing leaves the machine unless the AI is asked, and the fix is only
+// applied to a scratch copy of the workflow.
+func (o *Orchestrator) Tutorial(opts Options, caseDir string) error {
+	o.opts = opts
+	demo := eval.Case{Name: "demo", Logs: tutorialLogs, Workflow: tutorialWorkflow}
+	if caseDir != "" {
+		var err error
+		if demo, err = eval.LoadCase(caseDir); err != nil {
+			return err
+		}
+	}
+	path := workflow.Dir + "/ci.yml"
+
+	fmt.Fprintln(o.out, ui.FormatHeader("🎓 Sentinel tutorial"))
+	fmt.Fprintln(o.out, wrapText("This walks through what `gh sentinel fix` does with a failed run, one stage at a time, on "+
+		tutorialSubject(caseDir)+". Nothing is sent to GitHub, and the fix only touches a scratch copy of the workflow.", 80))
+	fmt.Fprintln(o.out)
+
+	// Stage 1: the failed run
+	if !o.tutorialStage(1,
+		"A session starts by listing the failed runs of the latest commit on the default branch, newest first. "+
+			"In a terminal you pick one in a selector; with --yes, or without a terminal, the most recent one is taken. "+
+			"`gh sentinel scan` lists them without changing anything.",
+		"--run-id <id> skips the selector; x in the selector cancels a run that is still going") {
+		return nil
+	}
+	fmt.Fprintln(o.out, ui.Format(ui.SeverityError, fmt.Sprintf("%s failed (%s)", ui.FormatHighlight("CI"), path)))
+	fmt.Fprintln(o.out)
+
+	// Stage 2: the log patterns
+	if !o.tutorialStage(2,
+		"The logs of the failed jobs are downloaded, split by step and matched against known error patterns. "+
+			"This is instant, offline and classifies the failure, e.g. as a dependency or permission failure.",
+		"your own patterns go in ~/.gh-sentinel/patterns.yml; secrets in the logs are masked before anything else sees them") {
+		return nil
+	}
+	analysis := o.analyzer.AnalyzeLogs(demo.Logs)
+	if len(analysis.Errors) == 0 {
+		fmt.Fprintln(o.out, ui.FormatDim("No known pattern matched these logs; the AI gets them all"))
+	}
+	for i, detected := range analysis.Errors {
+		fmt.Fprintf(o.out, "  %d. %s %s: %s\n", i+1, ui.Mark(ui.LevelSeverity(detected.Severity)), ui.FormatHighlight(detected.Pattern), detected.Message[:min(80, len(detected.Message))])
+		if detected.Suggestion != "" {
+			fmt.Fprintf(o.out, "     %s\n", ui.FormatDim("💡 "+detected.Suggestion))
+		}
+	}
+	if analysis.Tag != "" {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Failure category: %s", analysis.Tag)))
+	}
+	fmt.Fprintln(o.out)
+
+	// Stage 3: deterministic recipes, then the AI
+	if !o.tutorialStage(3,
+		"Deterministic recipes are tried first: known failures get the same reviewed fix every time, without the AI. "+
+			"When none applies, the logs, the workflow and the matched lines go to the AI, which explains the root cause and rewrites the file.",
+		"--rules-only never calls the AI; AI diagnoses are cached for 7 days, so analyzing a run again is free") {
+		return nil
+	}
+	diagnosis, err := o.tutorialDiagnosis(demo, analysis, path)
+	if err != nil {
+		return err
+	}
+	if diagnosis == nil {
+		fmt.Fprintln(o.out, ui.FormatInfo("No recipe matched this failure. In a real session the AI would diagnose it now; run the tutorial with --ai to try it."))
+		return o.tutorialEnd()
+	}
+	fmt.Fprintf(o.out, "Confidence: %s\n\n", ui.Format(ui.ConfidenceSeverity(diagnosis.Confidence), diagnosis.Confidence))
+	fmt.Fprintln(o.out, ui.FormatHeader("Root Cause:"))
+	fmt.Fprintln(o.out, wrapText(diagnosis.Explanation, 80))
+	fmt.Fprintln(o.out)
+
+	// Stage 4: the diff and its checks
+	if !o.tutorialStage(4,
+		"Nothing is written before you see the change as a diff. The fixed file is checked against the workflow schema and with actionlint; "+
+			"an AI fix that fails them is sent back for correction. Each hunk is matched to the finding it addresses.",
+		"with several hunks you can accept them one by one; --no-lint skips actionlint") {
+		return nil
+	}
+	o.printDiff(patcher.Unified(path, patcher.Diff(demo.Workflow, diagnosis.FixedContent), false), 0)
+	issues := patcher.ValidateWorkflowSchema(diagnosis.FixedContent)
+	for _, issue := range issues {
+		fmt.Fprintln(o.out, ui.FormatWarning(issue.String()))
+	}
+	if lint := o.lintFixContent(path, diagnosis.FixedContent); lint == 0 && len(issues) == 0 {
+		fmt.Fprintln(o.out, ui.FormatSuccess("The fixed workflow passes the checks"))
+	}
+	fmt.Fprintln(o.out)
+
+	// Stage 5: applying to a scratch copy
+	if !o.tutorialStage(5,
+		"Once you confirm, the file is backed up and patched in place, only where the fix changed it. "+
+			"`gh sentinel rollback` restores the backup, and --create-branch commits the fix to its own branch and pushes it.",
+		"critical workflows (--critical) are never patched directly: their fixes are opened as pull requests") {
+		return nil
+	}
+	scratch := filepath.Join(o.config.TempDir, "tutorial")
+	defer os.RemoveAll(scratch)
+	target := filepath.Join(scratch, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, []byte(demo.Workflow), 0644); err != nil {
+		return err
+	}
+	if o.interactive() {
+		confirmed, err := ui.ShowConfirmation(fmt.Sprintf("Apply patch to %s?", path), "This is the tutorial: only a scratch copy is patched")
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Fprintln(o.out, ui.FormatDim("Patch cancelled by user; a real session stops here and records the fix as declined"))
+			return o.tutorialEnd()
+		}
+	}
+	result, err := o.patcher.Apply(&patcher.PatchRequest{
+		FilePath:    target,
+		NewContent:  diagnosis.FixedContent,
+		BaseContent: demo.Workflow,
+		Validate:    true,
+		NoBackup:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s patched (+%d -%d lines)", path, result.LinesAdded, result.LinesRemoved)))
+	fmt.Fprintln(o.out)
+
+	// Stage 6: verifying
+	if !o.tutorialStage(6,
+		"The patched file is checked again, then sentinel offers to re-run the failed jobs. "+
+			"--watch follows the re-run, or the run the pushed branch triggers, until it completes.",
+		"the session ends with a summary; --output json, markdown or sarif writes it as a report") {
+		return nil
+	}
+	patched, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	if expected := strings.TrimSpace(demo.Expected); expected != "" {
+		if strings.TrimSpace(string(patched)) == expected {
+			fmt.Fprintln(o.out, ui.FormatSuccess("The patched file matches the case's expected fix"))
+		} else {
+			fmt.Fprintln(o.out, ui.FormatWarning("The patched file differs from the case's expected fix"))
+		}
+	}
+	if o.lintFixContent(path, string(patched)) == 0 {
+		fmt.Fprintln(o.out, ui.FormatSuccess("actionlint found no issues in the patched file"))
+	}
+	fmt.Fprintln(o.out, ui.FormatDim("A real session would now offer: Re-run the failed jobs of run #1?"))
+	fmt.Fprintln(o.out)
+	return o.tutorialEnd()
+}
+
+// tutorialSubject describes what the tutorial runs on
+func tutorialSubject(caseDir string) string {
+	if caseDir == "" {
+		return "a demo failure: a build that installs Node.js 16 for a project that requires 20"
+	}
+	return "the recorded failure in " + caseDir
+}
+
+// tutorialStage introduces a stage of the tutorial with a tip. In a
+// terminal it first asks to go on, and reports whether to.
+func (o *Orchestrator) tutorialStage(n int, about, tip string) bool {
+	if o.interactive() && n > 1 {
+		next, err := ui.ShowConfirmation(fmt.Sprintf("Continue to stage %d of %d, %s?", n, len(tutorialStages), tutorialStages[n-1]), "")
+		if err != nil || !next {
+			fmt.Fprintln(o.out, ui.FormatDim("Tutorial stopped; run gh sentinel tutorial to start again"))
+			return false
+		}
+	}
+	fmt.Fprintln(o.out, ui.FormatHeader(fmt.Sprintf("━━━━━━━━━━ Stage %d of %d · %s ━━━━━━━━━━", n, len(tutorialStages), tutorialStages[n-1])))
+	fmt.Fprintln(o.out, wrapText(about, 80))
+	fmt.Fprintln(o.out, ui.FormatDim("Tip: "+tip))
+	fmt.Fprintln(o.out)
+	return true
+}
+
+// tutorialDiagnosis diagnoses the tutorial's failure with the recipes, or
+// with the AI when --ai allows it. It is nil when neither has a fix.
+func (o *Orchestrator) tutorialDiagnosis(demo eval.Case, analysis *analyzer.Analysis, path string) (*copilot.DiagnosisResult, error) {
+	if fix := o.fixer.Fix(analysis, demo.Logs, demo.Workflow); fix != nil {
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Matched deterministic recipe: %s", strings.Join(fix.Recipes, ", "))))
+		return &copilot.DiagnosisResult{
+			Explanation:  fix.Explanation,
+			FixedContent: fix.FixedContent,
+			TargetFile:   path,
+			Confidence:   fix.Confidence,
+		}, nil
+	}
+	if o.config.RulesOnly {
+		return nil, nil
+	}
+	if err := o.connectAI(); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(o.out, ui.FormatInfo("Consulting AI for diagnosis..."))
+	diagnosis, err := o.copilot.DiagnoseAndFix(&copilot.DiagnosisRequest{
+		ErrorLogs:      demo.Logs,
+		CurrentFile:    path,
+		FileContent:    demo.Workflow,
+		AvailableFiles: []string{path},
+		WorkflowPath:   path,
+		RunnerOS:       analysis.RunnerOS,
+		KeyLines:       analysis.MatchedLines(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AI diagnosis failed: %w", err)
+	}
+	if diagnosis.FixedContent == "" {
+		return nil, nil
+	}
+	return diagnosis, nil
+}
+
+// lintFixContent runs actionlint on workflow content, prints its issues and
+// returns how many it found. It finds none when linting is off.
+func (o *Orchestrator) lintFixContent(path, content string) int {
+	if !o.config.Lint {
+		return 0
+	}
+	issues, err := patcher.Lint(path, content)
+	if err != nil {
+		o.logger.Warn("Skipping actionlint verification: %v", err)
+		return 0
+	}
+	if len(issues) > 0 {
+		o.printLintIssues(path, issues)
+	}
+	return len(issues)
+}
+
+// tutorialEnd points at the commands to try next
+func (o *Orchestrator) tutorialEnd() error {
+	fmt.Fprintln(o.out, ui.FormatHeader("What next"))
+	fmt.Fprintln(o.out, "  gh sentinel doctor   check gh, authentication and the AI provider")
+	fmt.Fprintln(o.out, "  gh sentinel scan     list the failed runs of your repository")
+	fmt.Fprintln(o.out, "  gh sentinel fix      diagnose and fix one of them")
+	return nil
+}
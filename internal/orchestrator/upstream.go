@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/github"
+)
+
+// maxRediagnoses bounds how often a run is diagnosed again because its
+// workflow file keeps changing upstream
+const maxRediagnoses = 2
+
+// errRediagnose makes analyzeAndFix fetch the workflow file again and
+// diagnose the run from the new version
+var errRediagnose = stderrors.New("the workflow file changed upstream")
+
+// upstreamAction is what to do with a fix whose target changed upstream
+type upstreamAction int
+
+const (
+	upstreamApply      upstreamAction = iota // Unchanged, or apply anyway
+	upstreamRediagnose                       // Refetch the file and diagnose again
+	upstreamCancel                           // Drop the fix
+)
+
+// recordFetched remembers the latest commit of a workflow file when its
+// content is fetched, so a change pushed meanwhile can be noticed before a
+// fix made from that content is applied. commit and err are what
+// LatestFileCommit returned.
+func (o *Orchestrator) recordFetched(path string, commit *github.FileCommit, err error) {
+	if err != nil || commit == nil {
+		o.logger.Debug("Cannot track upstream changes of %s: %v", path, err)
+		delete(o.fetched, path)
+		return
+	}
+	if o.fetched == nil {
+		o.fetched = make(map[string]string)
+	}
+	o.fetched[path] = commit.SHA
+}
+
+// changedUpstream describes the change of a workflow file pushed since its
+// content was fetched, or returns "" if there is none
+func (o *Orchestrator) changedUpstream(path string) string {
+	fetched, ok := o.fetched[path]
+	if !ok {
+		return ""
+	}
+	latest, err := o.github.LatestFileCommit(path)
+	if err != nil {
+		o.logger.Debug("Cannot check %s for upstream changes: %v", path, err)
+		return ""
+	}
+	if latest == nil || latest.SHA == fetched {
+		return ""
+	}
+	return fmt.Sprintf("%s changed upstream %s (%s by %s)", path, ago(latest.Date), latest.SHA[:min(7, len(latest.SHA))], latest.Author)
+}
+
+// checkUpstream asks what to do when the target of a fix changed upstream
+// since the fix was made. Without a user to ask, the run is diagnosed again.
+func (o *Orchestrator) checkUpstream(path string) (upstreamAction, error) {
+	change := o.changedUpstream(path)
+	if change == "" {
+		return upstreamApply, nil
+	}
+	fmt.Fprintln(o.out, ui.FormatWarning(change))
+	if !o.interactive() {
+		return upstreamRediagnose, nil
+	}
+
+	choice, err := ui.ShowChoice(
+		change+": refetch and rediagnose?",
+		"The fix was made from the previous version and may undo or clash with the new one",
+		[]string{"Refetch and rediagnose", "Apply anyway", "Cancel"},
+	)
+	if err != nil {
+		return upstreamCancel, fmt.Errorf("upstream change dialog failed: %w", err)
+	}
+	switch choice {
+	case 0:
+		return upstreamRediagnose, nil
+	case 1:
+		return upstreamApply, nil
+	}
+	return upstreamCancel, nil
+}
+
+// ago describes how long ago a time was
+func ago(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < 2*time.Minute:
+		return "1 minute ago"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(d.Minutes()))
+	case d < 2*time.Hour:
+		return "1 hour ago"
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days ago", int(d.Hours()/24))
+}
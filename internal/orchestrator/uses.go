@@ -0,0 +1,126 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/workflow"
+)
+
+// maxRelatedFiles bounds how many local actions and called workflows go to
+// the AI with the workflow, when the failed steps are unknown
+const maxRelatedFiles = 5
+
+// relatedFiles fetches the local actions and reusable workflows of the same
+// repository that the failed jobs of a workflow run through, by path, so the
+// AI can fix the failure where it happens. When the failed steps are known,
+// only the actions of those steps and the workflows of the failed callers
+// are kept. Content in pending, a fix proposed earlier in the batch,
+// replaces the file's.
+func (o *Orchestrator) relatedFiles(fileContent string, workflowFiles []string, pending map[string]string) map[string]string {
+	uses := o.failedUses(workflow.LocalUses(fileContent))
+	if len(uses) == 0 {
+		return nil
+	}
+
+	related := make(map[string]string)
+	for _, use := range uses {
+		// Actions outside ActionsDir are not indexed, and may be in either
+		// action.yml or action.yaml
+		candidates := []string{use.Path}
+		if resolved, ok := workflow.Resolve(use.Uses, workflowFiles); ok {
+			candidates = []string{resolved}
+		} else if !use.Workflow {
+			candidates = append(candidates, strings.TrimSuffix(use.Path, ".yml")+".yaml")
+		}
+		target, content, ok := o.fetchRelated(candidates, pending)
+		if !ok {
+			o.logger.Debug("Could not fetch %s, which job %s uses", use.Uses, use.Job)
+			continue
+		}
+		if _, seen := related[target]; seen {
+			continue
+		}
+		related[target] = content
+
+		what := fmt.Sprintf("The failed step %q runs %s", use.Step, ui.FormatHighlight(target))
+		switch {
+		case len(o.report.Failed) == 0:
+			what = fmt.Sprintf("Job %s uses %s", use.Job, ui.FormatHighlight(target))
+		case use.Workflow:
+			what = fmt.Sprintf("The failed job %s calls %s", use.Job, ui.FormatHighlight(target))
+		}
+		fmt.Fprintln(o.out, ui.FormatInfo(what+"; it is part of the diagnosis"))
+	}
+	return related
+}
+
+// fetchRelated fetches the first of candidate paths that exists, noting its
+// version as workflowContent does
+func (o *Orchestrator) fetchRelated(candidates []string, pending map[string]string) (string, string, bool) {
+	for _, path := range candidates {
+		if content, ok := pending[path]; ok {
+			return path, content, true
+		}
+		content, err := o.github.GetWorkflowFileContent(path)
+		if err != nil {
+			o.logger.Debug("Could not fetch %s: %v", path, err)
+			continue
+		}
+		commit, err := o.github.LatestFileCommit(path)
+		o.recordFetched(path, commit, err)
+		return path, content, true
+	}
+	return "", "", false
+}
+
+// failedUses keeps the uses of the failed jobs and steps. Without failed
+// steps to go by, all of them are kept, up to maxRelatedFiles.
+func (o *Orchestrator) failedUses(uses []workflow.LocalUse) []workflow.LocalUse {
+	if len(o.report.Failed) == 0 {
+		return uses[:min(len(uses), maxRelatedFiles)]
+	}
+	var failed []workflow.LocalUse
+	for _, use := range uses {
+		for _, f := range o.report.Failed {
+			if failedUse(use, f) {
+				failed = append(failed, use)
+				break
+			}
+		}
+	}
+	return failed
+}
+
+// failedUse reports whether a failed step is in a use: a step of the called
+// workflow, named "<caller> / <job>", or the step that runs the action
+func failedUse(use workflow.LocalUse, f ReportFailedStep) bool {
+	if use.Workflow {
+		caller, _, ok := strings.Cut(f.Job, " / ")
+		return ok && sameJob(use, caller)
+	}
+	return sameJob(use, f.Job) && f.Step == use.Step
+}
+
+// sameJob reports whether a job name of a run is the job of a use, or one
+// of the combinations of its matrix
+func sameJob(use workflow.LocalUse, name string) bool {
+	if name == use.Job || name == use.Name {
+		return true
+	}
+	job, _, ok := analyzer.ParseMatrixJob(name)
+	return ok && (job == use.Job || job == use.Name)
+}
+
+// withRelated adds the paths of related files missing from a workflow file
+// listing, e.g. actions outside ActionsDir, so a fix can target them
+func withRelated(files []string, related map[string]string) []string {
+	for _, path := range sortedPaths(related) {
+		if _, ok := workflow.Resolve(path, files); !ok {
+			files = append(files[:len(files):len(files)], path)
+		}
+	}
+	return files
+}
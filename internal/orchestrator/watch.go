@@ -0,0 +1,188 @@
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+
+	"gh-sentinel/internal/metrics"
+	"gh-sentinel/internal/ui"
+)
+
+const (
+	watchInterval = 5 * time.Second
+	watchTimeout  = 30 * time.Minute
+	// runStartTimeout bounds the wait for a pushed fix to trigger a run
+	runStartTimeout = 2 * time.Minute
+)
+
+// offerRerun runs the workflow again after a patch and watches the result.
+// A pushed fix is verified by the run its commit triggers; otherwise the
+// failed jobs are re-run, which uses the workflow as committed.
+func (o *Orchestrator) offerRerun(runID int64) {
+	pushed := o.report.Patch != nil && o.report.Patch.Pushed
+
+	switch {
+	case o.opts.Watch:
+	case !o.interactive():
+		return
+	default:
+		prompt := fmt.Sprintf("Re-run the failed jobs of run #%d and watch the result?", runID)
+		details := "The fix is only local, so the re-run uses the committed workflow. Use --create-branch to test the patched file."
+		if pushed {
+			prompt = fmt.Sprintf("Watch the run triggered by %s?", o.report.Patch.Branch)
+			details = "Waits for the pushed fix to start a new run"
+		}
+		confirmed, err := ui.ShowConfirmation(prompt, details)
+		if err != nil || !confirmed {
+			return
+		}
+	}
+
+	var err error
+	if pushed {
+		err = o.watchPushedFix()
+	} else {
+		err = o.rerunAndWatch(runID)
+	}
+	if err != nil {
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("Could not watch the re-run: %v", err)))
+	}
+}
+
+// rerunAndWatch re-runs a run's failed jobs and watches the new attempt
+func (o *Orchestrator) rerunAndWatch(runID int64) error {
+	run, err := o.github.GetWorkflowRun(runID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, ui.FormatDim("Note: the re-run uses the committed workflow, not the local patch"))
+	if err := o.github.RerunFailedJobs(runID); err != nil {
+		return err
+	}
+	return o.watchRun(runID, run.Attempt+1)
+}
+
+// watchPushedFix waits for the pushed fix commit to trigger the patched
+// workflow and watches that run
+func (o *Orchestrator) watchPushedFix() error {
+	commit := o.report.Patch.Commit
+	fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("Waiting for %s to start a run of %s...", shortSHA(commit), o.report.Workflow)))
+
+	deadline := time.Now().Add(runStartTimeout)
+	for {
+		runs, err := o.github.ListRunsForCommit(commit)
+		if err != nil {
+			return err
+		}
+		for _, run := range runs {
+			if run.WorkflowPath == o.report.Workflow {
+				return o.watchRun(run.ID, 0)
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no run of %s started for %s; the workflow may not run on push to this branch", o.report.Workflow, shortSHA(commit))
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// watchRun polls a run until it completes, streaming job progress. Polls
+// that still see an attempt older than minAttempt report the run as queued.
+func (o *Orchestrator) watchRun(runID int64, minAttempt int) error {
+	deadline := time.Now().Add(watchTimeout)
+	poll := func() ui.RunProgress {
+		run, err := o.github.GetWorkflowRun(runID)
+		if err != nil {
+			return ui.RunProgress{Err: err}
+		}
+		if run.Attempt < minAttempt {
+			return ui.RunProgress{Status: "queued"}
+		}
+
+		progress := ui.RunProgress{
+			Status:     run.Status,
+			Conclusion: run.Conclusion,
+			Done:       !run.Active(),
+		}
+		if jobs, err := o.github.ListRunJobs(runID); err == nil {
+			for _, job := range jobs {
+				progress.Jobs = append(progress.Jobs, ui.JobProgress{
+					Name:       job.Name,
+					Status:     job.Status,
+					Conclusion: job.Conclusion,
+				})
+			}
+		}
+		if !progress.Done && time.Now().After(deadline) {
+			progress.Err = fmt.Errorf("run #%d is still %s after %s", runID, run.Status, watchTimeout)
+		}
+		return progress
+	}
+
+	title := fmt.Sprintf("Run #%d", runID)
+	var final ui.RunProgress
+	if o.interactive() && !ui.PlainMode() {
+		var err error
+		final, err = ui.ShowRunWatch(title, watchInterval, poll)
+		if ui.IsProgramError(err) {
+			final = o.streamRun(title, poll)
+		} else if err != nil {
+			return fmt.Errorf("watch view failed: %w", err)
+		}
+	} else {
+		final = o.streamRun(title, poll)
+	}
+	if final.Err != nil {
+		return final.Err
+	}
+
+	o.report.Rerun = &ReportRerun{
+		RunID:      runID,
+		Status:     final.Status,
+		Conclusion: final.Conclusion,
+	}
+	if final.Done {
+		metrics.FixReruns.Inc(final.Conclusion)
+	}
+	switch {
+	case !final.Done:
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("Stopped watching; follow it with: gh run watch %d", runID)))
+	case final.Conclusion == "success":
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("Run #%d passed, the fix works", runID)))
+	default:
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("Run #%d finished with %s", runID, final.Conclusion)))
+		fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Diagnose it with: gh sentinel fix --run-id %d", runID)))
+	}
+	return nil
+}
+
+// streamRun polls without a TUI, printing each job state change
+func (o *Orchestrator) streamRun(title string, poll func() ui.RunProgress) ui.RunProgress {
+	seen := make(map[string]string)
+	for {
+		progress := poll()
+		if progress.Err != nil {
+			return progress
+		}
+		for _, job := range progress.Jobs {
+			state := job.Status + "/" + job.Conclusion
+			if seen[job.Name] == state {
+				continue
+			}
+			seen[job.Name] = state
+			fmt.Fprintf(o.out, "  %s %s: %s\n", ui.RunMark(job.Status, job.Conclusion), job.Name, jobState(job))
+		}
+		if progress.Done {
+			fmt.Fprintln(o.out, ui.Format(ui.ConclusionSeverity("completed", progress.Conclusion), fmt.Sprintf("%s completed: %s", title, progress.Conclusion)))
+			return progress
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+func jobState(job ui.JobProgress) string {
+	if job.Status == "completed" {
+		return job.Conclusion
+	}
+	return job.Status
+}
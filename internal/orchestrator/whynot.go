@@ -0,0 +1,79 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/workflow"
+)
+
+// WhyNot explains whether a local workflow file would run for a
+// hypothetical event by evaluating its on: filters
+func (o *Orchestrator) WhyNot(opts Options, path string, ev workflow.TriggerEvent) error {
+	o.opts = opts
+
+	path, err := localWorkflow(path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	result, err := workflow.EvaluateTrigger(path, string(content), ev)
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	fmt.Fprintln(o.out, ui.FormatHeader(filepath.ToSlash(path)))
+	for _, check := range result.Checks {
+		switch check.Outcome {
+		case workflow.TriggerSkipped:
+			fmt.Fprintf(o.out, "  %s\n", ui.FormatError(check.Reason))
+		case workflow.TriggerDepends:
+			fmt.Fprintf(o.out, "  %s\n", ui.FormatWarning(check.Reason))
+		default:
+			fmt.Fprintf(o.out, "  %s\n", ui.FormatSuccess(check.Reason))
+		}
+	}
+	fmt.Fprintln(o.out)
+
+	switch result.Outcome {
+	case workflow.TriggerSkipped:
+		fmt.Fprintln(o.out, ui.FormatError(fmt.Sprintf("%s would not run for this %s event", path, ev.Name)))
+	case workflow.TriggerDepends:
+		fmt.Fprintln(o.out, ui.FormatWarning(fmt.Sprintf("%s may run for this %s event; give the missing details to decide", path, ev.Name)))
+	default:
+		fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s would run for this %s event", path, ev.Name)))
+		fmt.Fprintln(o.out, ui.FormatDim("  If it still did not run: check that the workflow is enabled, exists on the pushed ref, and the commit message has no [skip ci]"))
+	}
+	return nil
+}
+
+// localWorkflow finds a workflow file given its path or its name inside the
+// workflows directory
+func localWorkflow(path string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	var names []string
+	files, _ := filepath.Glob(filepath.Join(workflow.Dir, "*"))
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	if resolved, ok := workflow.Resolve(path, names); ok {
+		return resolved, nil
+	}
+	return "", fmt.Errorf("workflow %s not found in %s", path, workflow.Dir)
+}
@@ -0,0 +1,139 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"gh-sentinel/internal/ui"
+	"gh-sentinel/pkg/github"
+)
+
+// Disable turns off a workflow the team has decided not to fix for now
+func (o *Orchestrator) Disable(opts Options, path string) error {
+	o.opts = opts
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+
+	wf, err := o.github.GetWorkflow(path)
+	if err != nil {
+		return fmt.Errorf("failed to find workflow %s: %w", path, err)
+	}
+	if wf.Disabled() {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("%s is already disabled", wf.Path)))
+		return nil
+	}
+
+	switch {
+	case opts.Yes:
+	case !o.interactive():
+		return fmt.Errorf("refusing to disable %s without confirmation; pass --yes", wf.Path)
+	default:
+		confirmed, err := ui.ShowConfirmation(
+			fmt.Sprintf("Disable %s?", wf.Path),
+			"It will not run on any trigger until it is enabled again",
+		)
+		if err != nil {
+			return fmt.Errorf("confirmation dialog failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Fprintln(o.out, ui.FormatDim("Cancelled"))
+			return nil
+		}
+	}
+
+	return o.disableWorkflow(wf)
+}
+
+// Enable turns a disabled workflow back on
+func (o *Orchestrator) Enable(opts Options, path string) error {
+	o.opts = opts
+	if err := o.connectGitHub(); err != nil {
+		return err
+	}
+
+	wf, err := o.github.GetWorkflow(path)
+	if err != nil {
+		return fmt.Errorf("failed to find workflow %s: %w", path, err)
+	}
+	if !wf.Disabled() {
+		fmt.Fprintln(o.out, ui.FormatInfo(fmt.Sprintf("%s is not disabled (state: %s)", wf.Path, wf.State)))
+		return nil
+	}
+
+	if err := o.github.EnableWorkflow(wf.ID); err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s enabled", wf.Path)))
+	return nil
+}
+
+// disableWorkflow disables wf and tells the user how to undo it
+func (o *Orchestrator) disableWorkflow(wf *github.Workflow) error {
+	if err := o.github.DisableWorkflow(wf.ID); err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, ui.FormatSuccess(fmt.Sprintf("%s disabled", wf.Path)))
+	fmt.Fprintln(o.out, ui.FormatDim(fmt.Sprintf("  Re-enable it with: gh sentinel enable %s", wf.Path)))
+	return nil
+}
+
+// offerDisable lets the user park a workflow that could not be repaired.
+// It only asks in interactive sessions, after a diagnosis that produced no
+// applied fix.
+func (o *Orchestrator) offerDisable(path string) error {
+	if !o.interactive() {
+		return nil
+	}
+	switch o.report.Status {
+	case StatusDeclined, StatusTargetNotFound:
+	case StatusNoFix:
+		if o.report.Diagnosis != nil && o.report.Diagnosis.Confidence == "HEALTHY" {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	wf, err := o.github.GetWorkflow(path)
+	if err != nil {
+		o.logger.Debug("Not offering to disable %s: %v", path, err)
+		return nil
+	}
+	if wf.Disabled() {
+		return nil
+	}
+
+	choice, err := ui.ShowChoice(
+		fmt.Sprintf("%s is still broken. What now?", wf.Path),
+		"Disabling stops it from running until you enable it again",
+		[]string{"Leave it enabled", "Disable the workflow for now"},
+	)
+	if err != nil {
+		return fmt.Errorf("choice dialog failed: %w", err)
+	}
+	if choice != 1 {
+		return nil
+	}
+
+	if err := o.disableWorkflow(wf); err != nil {
+		return err
+	}
+	o.report.Status = StatusDisabled
+	return nil
+}
+
+// disabledWorkflows returns the workflows that are currently disabled
+func (o *Orchestrator) disabledWorkflows() ([]*github.Workflow, error) {
+	workflows, err := o.github.ListWorkflows()
+	if err != nil {
+		return nil, err
+	}
+
+	var disabled []*github.Workflow
+	for _, wf := range workflows {
+		if wf.Disabled() {
+			disabled = append(disabled, wf)
+		}
+	}
+	return disabled, nil
+}
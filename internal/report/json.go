@@ -0,0 +1,16 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSON encodes the report as indented JSON
+type JSON struct{}
+
+// Render implements Reporter
+func (JSON) Render(w io.Writer, outcome *Outcome) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(outcome.Report)
+}
@@ -0,0 +1,264 @@
+package report
+
+// translations holds the text of the Markdown report in each language but
+// English, keyed by the English text. Diagnoses come from the AI in the
+// requested language already; text without a translation stays in English.
+var translations = map[string]map[string]string{
+	"fr": {
+		"Sentinel CI report":                    "Rapport Sentinel CI",
+		"Repository: %s":                        "Dépôt: %s",
+		"No failed workflow runs.":              "Aucune exécution de workflow en échec.",
+		"No workflow run was analyzed.":         "Aucune exécution de workflow n'a été analysée.",
+		"Error":                                 "Erreur",
+		"Run":                                   "Exécution",
+		"Workflow":                              "Workflow",
+		"Result":                                "Résultat",
+		"Diagnosis":                             "Diagnostic",
+		"Target":                                "Cible",
+		"Interrupted: this report is partial":   "Interrompu: ce rapport est partiel",
+		"By category":                           "Par catégorie",
+		"Run #%d":                               "Exécution #%d",
+		"Category":                              "Catégorie",
+		"Failed step":                           "Étape en échec",
+		"Matrix":                                "Matrice",
+		"Script issues":                         "Problèmes de script",
+		"Skipped because their `if:` was false": "Ignorés car leur `if:` était faux",
+		"Validation issues of the fix":          "Problèmes de validation du correctif",
+		"What each change addresses":            "Ce que corrige chaque modification",
+		"no finding explains this change":       "aucun constat n'explique cette modification",
+		"Proposed content for %s":               "Contenu proposé pour %s",
+		"Next steps":                            "Prochaines étapes",
+		"category":                              "catégorie",
+		"diagnosis":                             "diagnostic",
+		"target":                                "cible",
+		"backup":                                "sauvegarde",
+		"pushed":                                "poussé",
+		"review":                                "revue",
+		"flaky":                                 "instable",
+		"hooks failed":                          "hooks en échec",
+		"re-run":                                "relance",
+		"error":                                 "erreur",
+		"fix applied":                           "correctif appliqué",
+		"fix proposed, not applied":             "correctif proposé, non appliqué",
+		"fix declined":                          "correctif refusé",
+		"fix target not found":                  "cible du correctif introuvable",
+		"fix rejected by validation":            "correctif rejeté par la validation",
+		"workflow disabled":                     "workflow désactivé",
+		"no fix available":                      "aucun correctif disponible",
+		"failed":                                "échec",
+		"interrupted":                           "interrompu",
+		"no failed runs":                        "aucune exécution en échec",
+		"fix awaiting review":                   "correctif en attente de revue",
+		"likely flaky, not diagnosed":           "probablement instable, non diagnostiqué",
+		"ignored by category, not diagnosed":    "ignoré par catégorie, non diagnostiqué",
+		"Pattern evidence suggests a %s failure; the AI blames %s": "Les indices des logs suggèrent un échec %s; l'IA accuse %s",
+	},
+	"de": {
+		"Sentinel CI report":                    "Sentinel-CI-Bericht",
+		"Repository: %s":                        "Repository: %s",
+		"No failed workflow runs.":              "Keine fehlgeschlagenen Workflow-Läufe.",
+		"No workflow run was analyzed.":         "Es wurde kein Workflow-Lauf analysiert.",
+		"Error":                                 "Fehler",
+		"Run":                                   "Lauf",
+		"Workflow":                              "Workflow",
+		"Result":                                "Ergebnis",
+		"Diagnosis":                             "Diagnose",
+		"Target":                                "Ziel",
+		"Interrupted: this report is partial":   "Abgebrochen: dieser Bericht ist unvollständig",
+		"By category":                           "Nach Kategorie",
+		"Run #%d":                               "Lauf #%d",
+		"Category":                              "Kategorie",
+		"Failed step":                           "Fehlgeschlagener Schritt",
+		"Matrix":                                "Matrix",
+		"Script issues":                         "Skriptprobleme",
+		"Skipped because their `if:` was false": "Übersprungen, weil ihr `if:` falsch war",
+		"Validation issues of the fix":          "Validierungsprobleme der Korrektur",
+		"What each change addresses":            "Was jede Änderung behebt",
+		"no finding explains this change":       "kein Befund erklärt diese Änderung",
+		"Proposed content for %s":               "Vorgeschlagener Inhalt für %s",
+		"Next steps":                            "Nächste Schritte",
+		"category":                              "Kategorie",
+		"diagnosis":                             "Diagnose",
+		"target":                                "Ziel",
+		"backup":                                "Sicherung",
+		"pushed":                                "gepusht",
+		"review":                                "Review",
+		"flaky":                                 "instabil",
+		"hooks failed":                          "fehlgeschlagene Hooks",
+		"re-run":                                "erneuter Lauf",
+		"error":                                 "Fehler",
+		"fix applied":                           "Korrektur angewendet",
+		"fix proposed, not applied":             "Korrektur vorgeschlagen, nicht angewendet",
+		"fix declined":                          "Korrektur abgelehnt",
+		"fix target not found":                  "Ziel der Korrektur nicht gefunden",
+		"fix rejected by validation":            "Korrektur von der Validierung abgelehnt",
+		"workflow disabled":                     "Workflow deaktiviert",
+		"no fix available":                      "keine Korrektur verfügbar",
+		"failed":                                "fehlgeschlagen",
+		"interrupted":                           "abgebrochen",
+		"no failed runs":                        "keine fehlgeschlagenen Läufe",
+		"fix awaiting review":                   "Korrektur wartet auf Review",
+		"likely flaky, not diagnosed":           "wahrscheinlich instabil, nicht diagnostiziert",
+		"ignored by category, not diagnosed":    "nach Kategorie ignoriert, nicht diagnostiziert",
+		"Pattern evidence suggests a %s failure; the AI blames %s": "Die Log-Muster deuten auf einen %s-Fehler hin; die KI macht %s verantwortlich",
+	},
+	"es": {
+		"Sentinel CI report":                    "Informe de Sentinel CI",
+		"Repository: %s":                        "Repositorio: %s",
+		"No failed workflow runs.":              "No hay ejecuciones de workflow fallidas.",
+		"No workflow run was analyzed.":         "No se analizó ninguna ejecución de workflow.",
+		"Error":                                 "Error",
+		"Run":                                   "Ejecución",
+		"Workflow":                              "Workflow",
+		"Result":                                "Resultado",
+		"Diagnosis":                             "Diagnóstico",
+		"Target":                                "Destino",
+		"Interrupted: this report is partial":   "Interrumpido: este informe es parcial",
+		"By category":                           "Por categoría",
+		"Run #%d":                               "Ejecución #%d",
+		"Category":                              "Categoría",
+		"Failed step":                           "Paso fallido",
+		"Matrix":                                "Matriz",
+		"Script issues":                         "Problemas de script",
+		"Skipped because their `if:` was false": "Omitidos porque su `if:` era falso",
+		"Validation issues of the fix":          "Problemas de validación de la corrección",
+		"What each change addresses":            "Qué resuelve cada cambio",
+		"no finding explains this change":       "ningún hallazgo explica este cambio",
+		"Proposed content for %s":               "Contenido propuesto para %s",
+		"Next steps":                            "Próximos pasos",
+		"category":                              "categoría",
+		"diagnosis":                             "diagnóstico",
+		"target":                                "destino",
+		"backup":                                "copia de seguridad",
+		"pushed":                                "publicado",
+		"review":                                "revisión",
+		"flaky":                                 "inestable",
+		"hooks failed":                          "hooks fallidos",
+		"re-run":                                "nueva ejecución",
+		"error":                                 "error",
+		"fix applied":                           "corrección aplicada",
+		"fix proposed, not applied":             "corrección propuesta, no aplicada",
+		"fix declined":                          "corrección rechazada",
+		"fix target not found":                  "destino de la corrección no encontrado",
+		"fix rejected by validation":            "corrección rechazada por la validación",
+		"workflow disabled":                     "workflow desactivado",
+		"no fix available":                      "no hay corrección disponible",
+		"failed":                                "fallido",
+		"interrupted":                           "interrumpido",
+		"no failed runs":                        "no hay ejecuciones fallidas",
+		"fix awaiting review":                   "corrección pendiente de revisión",
+		"likely flaky, not diagnosed":           "probablemente inestable, no diagnosticado",
+		"ignored by category, not diagnosed":    "ignorado por categoría, no diagnosticado",
+		"Pattern evidence suggests a %s failure; the AI blames %s": "La evidencia de los logs sugiere un fallo de %s; la IA culpa a %s",
+	},
+	"pt": {
+		"Sentinel CI report":                    "Relatório do Sentinel CI",
+		"Repository: %s":                        "Repositório: %s",
+		"No failed workflow runs.":              "Nenhuma execução de workflow com falha.",
+		"No workflow run was analyzed.":         "Nenhuma execução de workflow foi analisada.",
+		"Error":                                 "Erro",
+		"Run":                                   "Execução",
+		"Workflow":                              "Workflow",
+		"Result":                                "Resultado",
+		"Diagnosis":                             "Diagnóstico",
+		"Target":                                "Alvo",
+		"Interrupted: this report is partial":   "Interrompido: este relatório é parcial",
+		"By category":                           "Por categoria",
+		"Run #%d":                               "Execução #%d",
+		"Category":                              "Categoria",
+		"Failed step":                           "Etapa com falha",
+		"Matrix":                                "Matriz",
+		"Script issues":                         "Problemas de script",
+		"Skipped because their `if:` was false": "Ignorados porque o `if:` era falso",
+		"Validation issues of the fix":          "Problemas de validação da correção",
+		"What each change addresses":            "O que cada alteração resolve",
+		"no finding explains this change":       "nenhuma constatação explica esta alteração",
+		"Proposed content for %s":               "Conteúdo proposto para %s",
+		"Next steps":                            "Próximos passos",
+		"category":                              "categoria",
+		"diagnosis":                             "diagnóstico",
+		"target":                                "alvo",
+		"backup":                                "backup",
+		"pushed":                                "enviado",
+		"review":                                "revisão",
+		"flaky":                                 "instável",
+		"hooks failed":                          "hooks com falha",
+		"re-run":                                "nova execução",
+		"error":                                 "erro",
+		"fix applied":                           "correção aplicada",
+		"fix proposed, not applied":             "correção proposta, não aplicada",
+		"fix declined":                          "correção recusada",
+		"fix target not found":                  "alvo da correção não encontrado",
+		"fix rejected by validation":            "correção rejeitada pela validação",
+		"workflow disabled":                     "workflow desativado",
+		"no fix available":                      "nenhuma correção disponível",
+		"failed":                                "falhou",
+		"interrupted":                           "interrompido",
+		"no failed runs":                        "nenhuma execução com falha",
+		"fix awaiting review":                   "correção aguardando revisão",
+		"likely flaky, not diagnosed":           "provavelmente instável, não diagnosticado",
+		"ignored by category, not diagnosed":    "ignorado pela categoria, não diagnosticado",
+		"Pattern evidence suggests a %s failure; the AI blames %s": "As evidências dos logs sugerem uma falha de %s; a IA culpa %s",
+	},
+	"it": {
+		"Sentinel CI report":                    "Report di Sentinel CI",
+		"Repository: %s":                        "Repository: %s",
+		"No failed workflow runs.":              "Nessuna esecuzione di workflow fallita.",
+		"No workflow run was analyzed.":         "Nessuna esecuzione di workflow è stata analizzata.",
+		"Error":                                 "Errore",
+		"Run":                                   "Esecuzione",
+		"Workflow":                              "Workflow",
+		"Result":                                "Risultato",
+		"Diagnosis":                             "Diagnosi",
+		"Target":                                "Destinazione",
+		"Interrupted: this report is partial":   "Interrotto: questo report è parziale",
+		"By category":                           "Per categoria",
+		"Run #%d":                               "Esecuzione #%d",
+		"Category":                              "Categoria",
+		"Failed step":                           "Passo fallito",
+		"Matrix":                                "Matrice",
+		"Script issues":                         "Problemi degli script",
+		"Skipped because their `if:` was false": "Saltati perché il loro `if:` era falso",
+		"Validation issues of the fix":          "Problemi di validazione della correzione",
+		"What each change addresses":            "Cosa risolve ogni modifica",
+		"no finding explains this change":       "nessun riscontro spiega questa modifica",
+		"Proposed content for %s":               "Contenuto proposto per %s",
+		"Next steps":                            "Prossimi passi",
+		"category":                              "categoria",
+		"diagnosis":                             "diagnosi",
+		"target":                                "destinazione",
+		"backup":                                "backup",
+		"pushed":                                "inviato",
+		"review":                                "revisione",
+		"flaky":                                 "instabile",
+		"hooks failed":                          "hook falliti",
+		"re-run":                                "riesecuzione",
+		"error":                                 "errore",
+		"fix applied":                           "correzione applicata",
+		"fix proposed, not applied":             "correzione proposta, non applicata",
+		"fix declined":                          "correzione rifiutata",
+		"fix target not found":                  "destinazione della correzione non trovata",
+		"fix rejected by validation":            "correzione respinta dalla validazione",
+		"workflow disabled":                     "workflow disabilitato",
+		"no fix available":                      "nessuna correzione disponibile",
+		"failed":                                "fallito",
+		"interrupted":                           "interrotto",
+		"no failed runs":                        "nessuna esecuzione fallita",
+		"fix awaiting review":                   "correzione in attesa di revisione",
+		"likely flaky, not diagnosed":           "probabilmente instabile, non diagnosticato",
+		"ignored by category, not diagnosed":    "ignorato per categoria, non diagnosticato",
+		"Pattern evidence suggests a %s failure; the AI blames %s": "Le evidenze dei log suggeriscono un errore %s; l'IA incolpa %s",
+	},
+}
+
+// translator returns a function translating the report's text to a language.
+// English and unknown languages keep the text as it is.
+func translator(lang string) func(string) string {
+	catalog := translations[lang]
+	return func(text string) string {
+		if translated, ok := catalog[text]; ok {
+			return translated
+		}
+		return text
+	}
+}
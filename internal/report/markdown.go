@@ -0,0 +1,168 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+)
+
+// Markdown renders the outcome as a Markdown document, suitable for a pull
+// request comment or a job summary
+type Markdown struct{}
+
+// Render implements Reporter
+func (Markdown) Render(w io.Writer, outcome *Outcome) error {
+	t := translator(outcome.Language)
+	var b strings.Builder
+	b.WriteString("## 🛡️ " + t("Sentinel CI report") + "\n\n")
+	if outcome.Report != nil && outcome.Report.Repository != "" {
+		fmt.Fprintf(&b, t("Repository: %s")+"\n\n", code(outcome.Report.Repository))
+	}
+
+	var runs []SessionRun
+	if outcome.Session != nil {
+		runs = outcome.Session.Runs
+	}
+	if len(runs) == 0 {
+		if outcome.Report != nil && outcome.Report.Status == StatusClean {
+			b.WriteString(t("No failed workflow runs.") + " ✨\n")
+		} else {
+			b.WriteString(t("No workflow run was analyzed.") + "\n")
+		}
+		if outcome.Report != nil && outcome.Report.Error != "" {
+			fmt.Fprintf(&b, "\n**%s:** %s\n", t("Error"), outcome.Report.Error)
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", t("Run"), t("Workflow"), t("Result"), t("Diagnosis"), t("Target"))
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	interrupted := false
+	for _, run := range runs {
+		interrupted = interrupted || run.Status == StatusInterrupted
+		fmt.Fprintf(&b, "| #%d | %s | %s | %s | %s |\n",
+			run.RunID, code(run.Workflow), ui.Icon(Severity(run.Status))+" "+t(Describe(run.Status)), cell(run.Diagnosis), code(run.Target))
+	}
+	if interrupted {
+		b.WriteString("\n> " + ui.Icon(ui.SeverityWarning) + " " + t("Interrupted: this report is partial") + "\n")
+	}
+	if tags := TagCounts(runs); tags != "" {
+		fmt.Fprintf(&b, "\n**%s:** %s\n", t("By category"), tags)
+	}
+
+	reports := make(map[int64]*Report)
+	for _, report := range outcome.RunReports() {
+		reports[report.RunID] = report
+	}
+	for _, run := range runs {
+		writeRunSection(&b, run, reports[run.RunID], t)
+	}
+
+	if steps := outcome.Session.NextSteps; len(steps) > 0 {
+		fmt.Fprintf(&b, "\n### %s\n\n", t("Next steps"))
+		for i, step := range steps {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRunSection writes the details of one run. The report is nil for
+// runs of earlier fixes in the session. t translates the report's text.
+func writeRunSection(b *strings.Builder, run SessionRun, report *Report, t func(string) string) {
+	title := fmt.Sprintf(t("Run #%d"), run.RunID)
+	if run.Workflow != "" {
+		title += " — " + code(run.Workflow)
+	}
+	fmt.Fprintf(b, "\n### %s\n\n", title)
+	fmt.Fprintf(b, "**%s:** %s %s\n", t("Result"), ui.Icon(Severity(run.Status)), t(Describe(run.Status)))
+	for _, detail := range runDetails(run, t) {
+		fmt.Fprintf(b, "- %s\n", detail)
+	}
+	if report == nil {
+		return
+	}
+
+	if report.Category != "" {
+		fmt.Fprintf(b, "\n**%s:** `%s`\n", t("Category"), report.Category)
+	}
+	for _, f := range report.Failed {
+		fmt.Fprintf(b, "\n**%s:** %s › %s\n", t("Failed step"), f.Job, code(f.Step))
+	}
+	for _, m := range report.Matrix {
+		fmt.Fprintf(b, "\n**%s:** %s\n", t("Matrix"), m)
+	}
+	if d := report.Diagnosis; d != nil && d.Explanation != "" {
+		fmt.Fprintf(b, "\n%s\n", strings.TrimSpace(d.Explanation))
+	}
+	if d := report.Diagnosis; d != nil && d.Disagreement != nil {
+		fmt.Fprintf(b, "\n%s "+t("Pattern evidence suggests a %s failure; the AI blames %s")+"\n", ui.Icon(ui.SeverityWarning), d.Disagreement.Pattern, d.Disagreement.AI)
+	}
+
+	if len(report.Scripts) > 0 {
+		fmt.Fprintf(b, "\n**%s**\n\n", t("Script issues"))
+		for _, s := range report.Scripts {
+			what := fmt.Sprintf("job %s, step %q, line %d", s.Job, s.Step, s.Line)
+			if s.Code != "" {
+				what += ", " + s.Code
+			}
+			fmt.Fprintf(b, "- %s (%s): %s\n", what, s.Level, s.Message)
+		}
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(b, "\n**%s**\n\n", t("Skipped because their `if:` was false"))
+		for _, s := range report.Skipped {
+			what := "job " + s.Job
+			if s.Step != "" {
+				what = fmt.Sprintf("step %q of job %s", s.Step, s.Job)
+			}
+			fmt.Fprintf(b, "- %s (line %d): %s\n", what, s.Line, code(s.Condition))
+		}
+	}
+
+	if d := report.Diagnosis; d != nil {
+		issues := append(append([]string{}, d.SchemaIssues...), d.LintIssues...)
+		if len(issues) > 0 {
+			fmt.Fprintf(b, "\n**%s**\n\n", t("Validation issues of the fix"))
+			for _, issue := range issues {
+				fmt.Fprintf(b, "- %s\n", issue)
+			}
+		}
+		if len(d.Changes) > 0 {
+			fmt.Fprintf(b, "\n**%s**\n\n", t("What each change addresses"))
+			for _, change := range d.Changes {
+				addresses := ui.Icon(ui.SeverityWarning) + " " + t("no finding explains this change")
+				if len(change.Addresses) > 0 {
+					addresses = strings.Join(change.Addresses, "; ")
+				}
+				fmt.Fprintf(b, "- %s: %s\n", code(change.Hunk), cell(addresses))
+			}
+		}
+		if d.FixedContent != "" && run.Status != StatusApplied {
+			fmt.Fprintf(b, "\n<details><summary>"+t("Proposed content for %s")+"</summary>\n\n", code(d.Target))
+			fence := "```"
+			for strings.Contains(d.FixedContent, fence) {
+				fence += "`"
+			}
+			fmt.Fprintf(b, "%syaml\n%s\n%s\n\n</details>\n", fence, strings.TrimRight(d.FixedContent, "\n"), fence)
+		}
+	}
+}
+
+// cell makes text safe for a table cell
+func cell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// code formats text as inline code, or nothing when it is empty
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + cell(s) + "`"
+}
@@ -0,0 +1,344 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/ui"
+)
+
+// Report statuses
+const (
+	StatusClean          = "clean"            // No failed runs found
+	StatusNoFix          = "no_fix"           // Diagnosed, but nothing to apply
+	StatusProposed       = "proposed"         // Fix generated but not applied
+	StatusDeclined       = "declined"         // User declined the fix
+	StatusTargetNotFound = "target_not_found" // Fix target could not be reconciled
+	StatusApplied        = "applied"          // Fix written to disk
+	StatusDisabled       = "disabled"         // Workflow disabled instead of fixed
+	StatusInvalid        = "invalid"          // Fix failed schema validation and was not auto-applied
+	StatusInterrupted    = "interrupted"      // Session interrupted before the run finished
+	StatusAwaitingReview = "awaiting_review"  // Fix of a critical workflow opened as a pull request for a second reviewer
+	StatusFlaky          = "flaky"            // Failure of steps that fail intermittently; not diagnosed
+	StatusIgnored        = "ignored"          // Failure of a category ignore_categories lists or --category leaves out; not diagnosed
+	StatusError          = "error"
+)
+
+// Report is the machine-readable outcome of a run, emitted with --output json
+type Report struct {
+	Repository  string       `json:"repository"`
+	RunID       int64        `json:"run_id,omitempty"`
+	Workflow    string       `json:"workflow,omitempty"`
+	Branch      string       `json:"branch,omitempty"` // Branch the run ran on
+	Status      string       `json:"status"`
+	Category    string       `json:"category,omitempty"`     // Log pattern category, when one was detected
+	Tag         string       `json:"tag,omitempty"`          // Failure category of the taxonomy, e.g. "dependency"
+	RootCause   string       `json:"root_cause,omitempty"`   // APPLICATION_CODE or INFRASTRUCTURE for a failure the workflow cannot fix
+	Culprits    []string     `json:"culprits,omitempty"`     // Files, tests or services at fault then, the AI's first
+	Failed      []FailedStep `json:"failed_steps,omitempty"` // Steps that failed, from the job logs
+	Matrix      []string     `json:"matrix,omitempty"`       // How the combinations of each matrix job fared, when some failed
+	Detected    []Detected   `json:"detected,omitempty"`     // Log patterns matched in the run
+	Suggestions []string     `json:"suggestions,omitempty"`  // Top suggestions of the matched patterns
+	Scripts     []Script     `json:"script_issues,omitempty"`
+	Skipped     []Skipped    `json:"skipped,omitempty"`
+	Diagnosis   *Diagnosis   `json:"diagnosis,omitempty"`
+	Patch       *Patch       `json:"patch,omitempty"`
+	Rerun       *Rerun       `json:"rerun,omitempty"`
+	Flaky       *Flaky       `json:"flaky,omitempty"`       // Failed steps that also failed intermittently in recent runs
+	CommentURL  string       `json:"comment_url,omitempty"` // The diagnosis posted on the run's pull request or commit
+	IssueURL    string       `json:"issue_url,omitempty"`   // The issue filed, or commented on, for a failure sentinel could not fix
+	Proposal    string       `json:"proposal,omitempty"`    // Directory --dry-run wrote the fix to
+	Runs        []*Report    `json:"runs,omitempty"`        // Per-run reports of a --all batch
+	Error       string       `json:"error,omitempty"`
+}
+
+// Diagnosis describes the diagnosis and proposed fix
+type Diagnosis struct {
+	Source       string        `json:"source"` // "rules" or "ai"
+	Recipes      []string      `json:"recipes,omitempty"`
+	Target       string        `json:"target"`
+	Confidence   string        `json:"confidence"`
+	Explanation  string        `json:"explanation"`
+	FixedContent string        `json:"fixed_content,omitempty"`
+	SchemaIssues []string      `json:"schema_issues,omitempty"`
+	LintIssues   []string      `json:"lint_issues,omitempty"`
+	Attempt      int           `json:"attempt,omitempty"`         // Corrections after validation errors make it > 1
+	Cached       bool          `json:"cached,omitempty"`          // Reused from an earlier analysis of the run instead of asking the AI
+	Changes      []Change      `json:"changes,omitempty"`         // What each hunk of the fix addresses
+	Disagreement *Disagreement `json:"disagreement,omitempty"`    // The AI blames another kind of failure than the log patterns
+	Further      []string      `json:"further_targets,omitempty"` // Further files the fix changes, when it spans several
+	Diverged     []string      `json:"diverged,omitempty"`        // Files whose local content is not the version the fix was made from
+}
+
+// Disagreement is an AI diagnosis whose root cause is of another failure
+// category than the log patterns matched. Its confidence is lowered one
+// level and --yes does not apply it.
+type Disagreement struct {
+	Pattern      string `json:"pattern_tag"`   // Failure category of the log patterns
+	AI           string `json:"ai_tag"`        // Failure category the explanation points to
+	AIConfidence string `json:"ai_confidence"` // Confidence the AI gave, before it was lowered
+}
+
+// String describes the disagreement, e.g. "pattern evidence suggests a
+// dependency failure; the AI blames syntax"
+func (d *Disagreement) String() string {
+	return fmt.Sprintf("pattern evidence suggests a %s failure; the AI blames %s", d.Pattern, d.AI)
+}
+
+// Change is one hunk of a fix and the findings it addresses. A hunk no
+// finding explains has no Addresses and deserves a closer look.
+type Change struct {
+	Hunk      string   `json:"hunk"` // Unified diff header, e.g. @@ -3,7 +3,8 @@
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// FailedStep is a step that failed in a job of a run
+type FailedStep struct {
+	Job      string `json:"job"`
+	Step     string `json:"step"`
+	Number   int64  `json:"number,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Detected is a known error pattern found in the logs of a run
+type Detected struct {
+	Pattern  string `json:"pattern"`
+	Category string `json:"category"`
+	Tag      string `json:"tag,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`        // The matching log text
+	Step     string `json:"step,omitempty"` // Job and step whose log it is in
+}
+
+// Script is a problem found in a run: script, or a shell error the logs
+// attribute to one
+type Script struct {
+	Job          string `json:"job"`
+	Step         string `json:"step"`
+	Line         int    `json:"line"`
+	Code         string `json:"code,omitempty"`
+	Level        string `json:"level"`
+	Message      string `json:"message"`
+	Source       string `json:"source"` // "shellcheck", "builtin" or "log"
+	InFailedStep bool   `json:"in_failed_step,omitempty"`
+}
+
+// Skipped is a job or step the run skipped because its if: condition was
+// false
+type Skipped struct {
+	Job       string   `json:"job"`
+	Step      string   `json:"step,omitempty"`
+	Line      int      `json:"line"`
+	Condition string   `json:"condition"`
+	Values    []string `json:"values,omitempty"` // Context values the condition read
+}
+
+// Security is a dangerous pattern the security scan found in a workflow
+// file or local action
+type Security struct {
+	File        string `json:"file"`
+	Rule        string `json:"rule"`
+	Line        int    `json:"line"`
+	Severity    string `json:"severity"` // critical, high, medium or low
+	Job         string `json:"job,omitempty"`
+	Step        string `json:"step,omitempty"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// Patch describes an applied patch
+type Patch struct {
+	Repository   string      `json:"repository,omitempty"` // Where the fix was committed, when not in the analyzed repository
+	BackupPath   string      `json:"backup_path,omitempty"`
+	LinesAdded   int         `json:"lines_added"`
+	LinesRemoved int         `json:"lines_removed"`
+	HunksSkipped int         `json:"hunks_skipped,omitempty"` // Hunks the user left out
+	Branch       string      `json:"branch,omitempty"`        // Set by --create-branch
+	Commit       string      `json:"commit,omitempty"`
+	Pushed       bool        `json:"pushed,omitempty"`
+	PullRequest  int         `json:"pull_request,omitempty"` // Opened for review of a critical workflow, or by open_pr
+	PullURL      string      `json:"pull_request_url,omitempty"`
+	Reviewers    []string    `json:"reviewers,omitempty"`
+	Hooks        []Hook      `json:"hooks,omitempty"`        // Commit hooks run on the patched file, with --hooks
+	Group        string      `json:"backup_group,omitempty"` // Backups of a fix of several files, which rollback restores together
+	Further      []FilePatch `json:"further_files,omitempty"`
+	Merged       bool        `json:"merged,omitempty"`            // The file changed since the fix was made, which was merged onto it
+	Conflicts    int         `json:"conflicts_settled,omitempty"` // Conflicts of the merge the user settled
+}
+
+// FilePatch is the patch of one of the further files of a fix that spans
+// several
+type FilePatch struct {
+	File         string `json:"file"`
+	BackupPath   string `json:"backup_path,omitempty"`
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+	Merged       bool   `json:"merged,omitempty"`
+}
+
+// Hook is the outcome of one of the repository's commit hooks on a patched
+// file
+type Hook struct {
+	Runner   string `json:"runner"` // "pre-commit" or "lint-staged"
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Modified bool   `json:"modified,omitempty"` // The hook rewrote the file
+	Output   string `json:"output,omitempty"`
+}
+
+// Rerun describes the run watched after a patch
+type Rerun struct {
+	RunID      int64  `json:"run_id"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+}
+
+// Flaky describes the failed steps of a run that fail intermittently in the
+// recent runs of its workflow
+type Flaky struct {
+	Runs  int         `json:"runs"` // Runs compared, the analyzed one included
+	Steps []FlakyStep `json:"steps"`
+}
+
+// FlakyStep is a step that fails intermittently
+type FlakyStep struct {
+	Step     string `json:"step"` // Job and step
+	Failures int    `json:"failures"`
+	Passes   int    `json:"passes"`
+}
+
+// SessionRun is the outcome of one run in a session recap
+type SessionRun struct {
+	RunID       int64    `json:"run_id"`
+	Workflow    string   `json:"workflow,omitempty"`
+	Status      string   `json:"status"`
+	Tag         string   `json:"tag,omitempty"`       // Failure category of the taxonomy
+	Diagnosis   string   `json:"diagnosis,omitempty"` // Source and confidence, e.g. "ai, HIGH"
+	Target      string   `json:"target,omitempty"`
+	Repository  string   `json:"repository,omitempty"` // Where the fix was committed, when not in the session's repository
+	BackupPath  string   `json:"backup_path,omitempty"`
+	Branch      string   `json:"branch,omitempty"`
+	Commit      string   `json:"commit,omitempty"`
+	Rerun       string   `json:"rerun,omitempty"`        // Conclusion of the watched re-run
+	PullRequest int      `json:"pull_request,omitempty"` // Pull request of the fix; awaiting a second reviewer for critical workflows
+	PullURL     string   `json:"pull_request_url,omitempty"`
+	Flaky       string   `json:"flaky,omitempty"`        // The steps that fail intermittently
+	HooksFailed []string `json:"hooks_failed,omitempty"` // Commit hooks the patched file fails
+	Error       string   `json:"error,omitempty"`
+}
+
+// Session is what a fix session did, as shown at its end and stored in the
+// history file
+type Session struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Repository string       `json:"repository"`
+	Runs       []SessionRun `json:"runs"`
+	NextSteps  []string     `json:"next_steps,omitempty"`
+}
+
+// Outcome is everything a fix session produced, as given to a Reporter
+type Outcome struct {
+	Report   *Report    // The fix, with per-run reports for a batch
+	Session  *Session   // Recap of every run analyzed; it has no runs when none was
+	Version  string     // Version of gh-sentinel
+	Language string     // Language of the Markdown report, e.g. "fr"; empty is English
+	Security []Security // Findings of the security scan, with scan --output sarif
+}
+
+// RunReports returns the per-run reports of the outcome: the runs of a batch
+// or the report itself when it is about one run
+func (o *Outcome) RunReports() []*Report {
+	if o.Report == nil {
+		return nil
+	}
+	if len(o.Report.Runs) > 0 {
+		return o.Report.Runs
+	}
+	if o.Report.RunID != 0 {
+		return []*Report{o.Report}
+	}
+	return nil
+}
+
+// Describe describes a report status in words
+func Describe(status string) string {
+	switch status {
+	case StatusApplied:
+		return "fix applied"
+	case StatusProposed:
+		return "fix proposed, not applied"
+	case StatusDeclined:
+		return "fix declined"
+	case StatusTargetNotFound:
+		return "fix target not found"
+	case StatusInvalid:
+		return "fix rejected by validation"
+	case StatusDisabled:
+		return "workflow disabled"
+	case StatusNoFix:
+		return "no fix available"
+	case StatusError:
+		return "failed"
+	case StatusInterrupted:
+		return "interrupted"
+	case StatusClean:
+		return "no failed runs"
+	case StatusAwaitingReview:
+		return "fix awaiting review"
+	case StatusFlaky:
+		return "likely flaky, not diagnosed"
+	case StatusIgnored:
+		return "ignored by category, not diagnosed"
+	}
+	return status
+}
+
+// TagCounts counts the runs of each failure category, most frequent first,
+// e.g. "dependency 2 • test 1". It is empty when no run has a category.
+func TagCounts(runs []SessionRun) string {
+	counts := make(map[string]int)
+	for _, run := range runs {
+		if run.Tag != "" {
+			counts[run.Tag]++
+		}
+	}
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = fmt.Sprintf("%s %d", tag, counts[tag])
+	}
+	return strings.Join(parts, " • ")
+}
+
+// Severity maps a report status to the severity it is shown with
+func Severity(status string) ui.Severity {
+	switch status {
+	case StatusApplied, StatusClean:
+		return ui.SeveritySuccess
+	case StatusError, StatusInvalid:
+		return ui.SeverityError
+	case StatusAwaitingReview, StatusIgnored:
+		return ui.SeverityInfo
+	}
+	return ui.SeverityWarning
+}
+
+// shortSHA abbreviates a commit SHA
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+)
+
+// Reporter renders the outcome of a fix session in one output format
+type Reporter interface {
+	Render(w io.Writer, outcome *Outcome) error
+}
+
+// Format is a registered output format
+type Format struct {
+	Name        string
+	Description string
+	// Stdout formats are meant for machines: they own stdout, so output for
+	// humans moves to stderr and prompts are never shown
+	Stdout bool
+	New    func() Reporter
+}
+
+// formats lists the output formats in the order help shows them
+var formats = []Format{
+	{Name: "text", Description: "session summary for the terminal (default)", New: func() Reporter { return Terminal{} }},
+	{Name: "json", Description: "machine-readable report", Stdout: true, New: func() Reporter { return JSON{} }},
+	{Name: "markdown", Description: "Markdown report, e.g. for a pull request comment", Stdout: true, New: func() Reporter { return Markdown{} }},
+	{Name: "sarif", Description: "SARIF 2.1.0 for code scanning", Stdout: true, New: func() Reporter { return SARIF{} }},
+	{Name: "job-summary", Description: "terminal summary, plus Markdown appended to the GitHub Actions job summary", New: func() Reporter { return JobSummary{} }},
+}
+
+// Lookup returns the output format with the given name
+func Lookup(name string) (Format, error) {
+	for _, format := range formats {
+		if format.Name == name {
+			return format, nil
+		}
+	}
+	return Format{}, errors.ValidationError("lookup_format",
+		fmt.Sprintf("unknown output format %q (expected %s)", name, strings.Join(Names(), ", ")))
+}
+
+// Names returns the names of every output format
+func Names() []string {
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		names[i] = format.Name
+	}
+	return names
+}
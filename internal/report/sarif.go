@@ -0,0 +1,260 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SARIF renders the findings of the session as SARIF 2.1.0, which GitHub
+// code scanning can upload and annotate workflows with
+type SARIF struct{}
+
+// sarifLog is the subset of SARIF 2.1.0 that sentinel produces
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	ShortDescription sarifMessage           `json:"shortDescription"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// Render implements Reporter
+func (SARIF) Render(w io.Writer, outcome *Outcome) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "gh-sentinel",
+			Version:        outcome.Version,
+			InformationURI: "https://github.com/Madiyanke/gh-sentinel",
+			Rules:          []sarifRule{},
+		}},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: true}},
+		Results:     []sarifResult{},
+	}
+
+	rules := make(map[string]bool)
+	add := func(rule, description string, result sarifResult) {
+		if !rules[rule] {
+			rules[rule] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: rule, ShortDescription: sarifMessage{description}})
+		}
+		result.RuleID = rule
+		run.Results = append(run.Results, result)
+	}
+	notify := func(text string) {
+		invocation := &run.Invocations[0]
+		invocation.ExecutionSuccessful = false
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications,
+			sarifNotification{Level: "error", Message: sarifMessage{text}})
+	}
+
+	if outcome.Report != nil && outcome.Report.Error != "" {
+		notify(outcome.Report.Error)
+	}
+	for _, report := range outcome.RunReports() {
+		if report.Error != "" && report != outcome.Report {
+			notify(fmt.Sprintf("run #%d: %s", report.RunID, report.Error))
+		}
+
+		if d := report.Diagnosis; d != nil {
+			rule := "failure"
+			if report.Category != "" {
+				rule += "/" + report.Category
+			}
+			properties := map[string]interface{}{
+				"runId":      report.RunID,
+				"status":     report.Status,
+				"source":     d.Source,
+				"confidence": d.Confidence,
+			}
+			if d.Disagreement != nil {
+				properties["disagreement"] = d.Disagreement.String()
+			}
+			add(rule, "Failed workflow run", sarifResult{
+				Level:      "error",
+				Message:    sarifMessage{fmt.Sprintf("Run #%d failed: %s (%s)", report.RunID, strings.TrimSpace(d.Explanation), Describe(report.Status))},
+				Locations:  sarifLocations(d.Target, 0),
+				Properties: sarifTags(properties, report.Tag),
+			})
+		}
+
+		for _, d := range report.Detected {
+			message := fmt.Sprintf("Run #%d: %s", report.RunID, strings.TrimSpace(d.Message))
+			if d.Step != "" {
+				message += fmt.Sprintf(" (%s)", d.Step)
+			}
+			add("pattern/"+d.Pattern, "Known failure pattern in the logs of a run", sarifResult{
+				Level:      sarifLevel(strings.ToLower(d.Severity)),
+				Message:    sarifMessage{message},
+				Locations:  sarifLocations(report.Workflow, 0),
+				Properties: sarifTags(map[string]interface{}{"runId": report.RunID, "category": d.Category}, d.Tag),
+			})
+		}
+
+		for _, s := range report.Scripts {
+			rule := "script/" + s.Source
+			if s.Code != "" {
+				rule = "script/" + s.Code
+			}
+			add(rule, "Problem in a run: script", sarifResult{
+				Level:     sarifLevel(s.Level),
+				Message:   sarifMessage{fmt.Sprintf("%s (job %s, step %q)", s.Message, s.Job, s.Step)},
+				Locations: sarifLocations(report.Workflow, s.Line),
+			})
+		}
+
+		for _, s := range report.Skipped {
+			what := "Job " + s.Job
+			if s.Step != "" {
+				what = fmt.Sprintf("Step %q of job %s", s.Step, s.Job)
+			}
+			add("skipped-condition", "Job or step skipped because its if: condition was false", sarifResult{
+				Level:     "note",
+				Message:   sarifMessage{fmt.Sprintf("%s was skipped: if: %s was false", what, s.Condition)},
+				Locations: sarifLocations(report.Workflow, s.Line),
+			})
+		}
+	}
+
+	// Code scanning ranks security alerts by the security-severity of their
+	// rule, so a rule takes the highest of its findings
+	scores := make(map[string]float64)
+	for _, f := range outcome.Security {
+		rule := "security/" + f.Rule
+		add(rule, securityRules[f.Rule], sarifResult{
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{fmt.Sprintf("%s. %s", f.Message, f.Remediation)},
+			Locations: sarifLocations(f.File, f.Line),
+		})
+		scores[rule] = max(scores[rule], securitySeverity[f.Severity])
+	}
+	for i, rule := range run.Tool.Driver.Rules {
+		if score, ok := scores[rule.ID]; ok {
+			run.Tool.Driver.Rules[i].Properties = map[string]interface{}{
+				"tags":              []string{"security"},
+				"security-severity": strconv.FormatFloat(score, 'f', 1, 64),
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifLocations points a result at a line of a file; line 0 means the
+// whole file
+func sarifLocations(path string, line int) []sarifLocation {
+	if path == "" {
+		return nil
+	}
+	location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifact{URI: path}}}
+	if line > 0 {
+		location.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+	}
+	return []sarifLocation{location}
+}
+
+// sarifLevel maps a shellcheck level or a finding's severity to a SARIF
+// level
+func sarifLevel(level string) string {
+	switch level {
+	case "error", "critical", "high":
+		return "error"
+	case "warning", "medium":
+		return "warning"
+	}
+	return "note"
+}
+
+// securityRules describes the rules of the security scan
+var securityRules = map[string]string{
+	"script-injection":             "Untrusted input interpolated into a script",
+	"pull-request-target-checkout": "Pull request code checked out in a pull_request_target workflow",
+	"secret-in-condition":          "Secret read in an if: condition",
+	"missing-permissions":          "Job with the default GITHUB_TOKEN permissions",
+}
+
+// securitySeverity is the security-severity code scanning ranks alerts by,
+// from 0.1 (low) to 10 (critical)
+var securitySeverity = map[string]float64{
+	"critical": 9.5,
+	"high":     8.0,
+	"medium":   5.5,
+	"low":      3.0,
+}
+
+// sarifTags adds a failure category to result properties as a SARIF tag,
+// which code scanning shows and filters by
+func sarifTags(properties map[string]interface{}, tag string) map[string]interface{} {
+	if tag != "" {
+		properties["tags"] = []string{tag}
+	}
+	return properties
+}
@@ -0,0 +1,33 @@
+package report
+
+import (
+	"io"
+	"os"
+
+	"gh-sentinel/internal/errors"
+)
+
+// JobSummary prints the terminal summary and appends the Markdown report to
+// the summary of the running GitHub Actions job
+type JobSummary struct{}
+
+// Render implements Reporter
+func (JobSummary) Render(w io.Writer, outcome *Outcome) error {
+	if err := (Terminal{}).Render(w, outcome); err != nil {
+		return err
+	}
+
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return errors.ValidationError("job_summary", "GITHUB_STEP_SUMMARY is not set; the job-summary output only works in a GitHub Actions job")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.FilesystemError("job_summary", path, err)
+	}
+	defer f.Close()
+	if err := (Markdown{}).Render(f, outcome); err != nil {
+		return errors.FilesystemError("job_summary", path, err)
+	}
+	return nil
+}
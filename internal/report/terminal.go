@@ -0,0 +1,113 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gh-sentinel/internal/ui"
+)
+
+// Terminal prints the session summary for humans. Sessions that analyzed
+// no run print nothing.
+type Terminal struct{}
+
+// Render implements Reporter
+func (Terminal) Render(w io.Writer, outcome *Outcome) error {
+	recap := outcome.Session
+	if recap == nil || len(recap.Runs) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, run := range recap.Runs {
+		counts[run.Status]++
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, ui.FormatHeader("━━━━━━━━━━━━━━ SESSION SUMMARY ━━━━━━━━━━━━━━"))
+	fmt.Fprintf(w, "  %d runs analyzed • %d fixed • %d declined • %d without a fix\n",
+		len(recap.Runs), counts[StatusApplied],
+		counts[StatusDeclined]+counts[StatusProposed]+counts[StatusInvalid]+counts[StatusTargetNotFound],
+		counts[StatusNoFix]+counts[StatusError])
+	if n := counts[StatusAwaitingReview]; n > 0 {
+		fmt.Fprintf(w, "  %s\n", ui.Format(ui.SeverityInfo, fmt.Sprintf("%d fixes awaiting a second reviewer", n)))
+	}
+	if n := counts[StatusFlaky]; n > 0 {
+		fmt.Fprintf(w, "  %s\n", ui.FormatWarning(fmt.Sprintf("%d likely flaky failures, re-run instead of fixed", n)))
+	}
+	if n := counts[StatusIgnored]; n > 0 {
+		fmt.Fprintf(w, "  %s\n", ui.Format(ui.SeverityInfo, fmt.Sprintf("%d failures ignored by category", n)))
+	}
+	if tags := TagCounts(recap.Runs); tags != "" {
+		fmt.Fprintf(w, "  %s\n", ui.FormatDim("By category: "+tags))
+	}
+	if counts[StatusInterrupted] > 0 {
+		fmt.Fprintf(w, "  %s\n", ui.FormatWarning("Interrupted: this summary is partial"))
+	}
+	fmt.Fprintln(w)
+
+	for _, run := range recap.Runs {
+		title := fmt.Sprintf("Run #%d", run.RunID)
+		if run.Workflow != "" {
+			title += " " + run.Workflow
+		}
+		line := fmt.Sprintf("%s: %s", title, Describe(run.Status))
+		fmt.Fprintf(w, "  %s\n", ui.Format(Severity(run.Status), line))
+
+		for _, detail := range runDetails(run, translator("")) {
+			fmt.Fprintf(w, "      %s\n", ui.FormatDim(detail))
+		}
+	}
+
+	if len(recap.NextSteps) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, ui.FormatInfo("💡 Next steps:"))
+		for i, step := range recap.NextSteps {
+			fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+		}
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// runDetails lists what is known about a run besides its status, with its
+// labels translated by t
+func runDetails(run SessionRun, t func(string) string) []string {
+	var details []string
+	if run.Tag != "" {
+		details = append(details, t("category")+": "+run.Tag)
+	}
+	if run.Diagnosis != "" {
+		details = append(details, t("diagnosis")+": "+run.Diagnosis)
+	}
+	if run.Target != "" {
+		details = append(details, t("target")+": "+run.Target)
+	}
+	if run.BackupPath != "" {
+		details = append(details, t("backup")+": "+run.BackupPath)
+	}
+	if run.Branch != "" {
+		pushed := fmt.Sprintf("%s: %s (%s)", t("pushed"), run.Branch, shortSHA(run.Commit))
+		if run.Repository != "" {
+			pushed += " → " + run.Repository
+		}
+		details = append(details, pushed)
+	}
+	if run.PullURL != "" {
+		details = append(details, t("review")+": "+run.PullURL)
+	}
+	if run.Flaky != "" {
+		details = append(details, t("flaky")+": "+run.Flaky)
+	}
+	if len(run.HooksFailed) > 0 {
+		details = append(details, t("hooks failed")+": "+strings.Join(run.HooksFailed, ", "))
+	}
+	if run.Rerun != "" {
+		details = append(details, t("re-run")+": "+run.Rerun)
+	}
+	if run.Error != "" {
+		details = append(details, t("error")+": "+run.Error)
+	}
+	return details
+}
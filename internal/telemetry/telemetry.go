@@ -0,0 +1,150 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// sendInterval is the least time between two reports
+const sendInterval = 24 * time.Hour
+
+// sendTimeout bounds a report, which must never hold up a command
+const sendTimeout = 5 * time.Second
+
+// Counters are the coarse usage counts reported when telemetry is enabled.
+// They never hold repository or workflow names, paths, logs or run IDs.
+type Counters struct {
+	Version   string         `json:"version"`
+	Commands  map[string]int `json:"commands,omitempty"`
+	Errors    map[string]int `json:"errors,omitempty"`    // By error category
+	Fixes     map[string]int `json:"fixes,omitempty"`     // "applied" or "not_applied"
+	Providers map[string]int `json:"providers,omitempty"` // AI providers that diagnosed a run
+	Patterns  map[string]int `json:"patterns,omitempty"`  // Log pattern categories of diagnosed runs
+	Tags      map[string]int `json:"tags,omitempty"`      // Failure categories of analyzed runs
+}
+
+// Empty reports whether nothing was counted yet
+func (c *Counters) Empty() bool {
+	return len(c.Commands) == 0 && len(c.Errors) == 0 && len(c.Fixes) == 0 &&
+		len(c.Providers) == 0 && len(c.Patterns) == 0 && len(c.Tags) == 0
+}
+
+// State is the telemetry file: the opt-in setting and the counters not
+// sent yet
+type State struct {
+	Telemetry bool      `json:"telemetry"`
+	LastSent  time.Time `json:"last_sent,omitzero"`
+	Pending   Counters  `json:"pending"`
+}
+
+// Load reads the telemetry file. Without one telemetry is disabled.
+func Load(path string) (*State, error) {
+	state := &State{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.FilesystemError("load_telemetry", path, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "load_telemetry", "invalid telemetry file", err).WithPath(path)
+	}
+	return state, nil
+}
+
+// Save writes the telemetry file
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.FilesystemError("save_telemetry", path, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return errors.FilesystemError("save_telemetry", path, err)
+	}
+	return nil
+}
+
+// Record adds to the pending counters when telemetry is enabled, and does
+// nothing otherwise
+func Record(path, version string, count func(c *Counters)) error {
+	state, err := Load(path)
+	if err != nil || !state.Telemetry {
+		return err
+	}
+	c := &state.Pending
+	c.Version = version
+	for _, m := range []*map[string]int{&c.Commands, &c.Errors, &c.Fixes, &c.Providers, &c.Patterns, &c.Tags} {
+		if *m == nil {
+			*m = make(map[string]int)
+		}
+	}
+	count(c)
+	return state.Save(path)
+}
+
+// Flush sends the pending counters to endpoint when telemetry is enabled
+// and the last report is old enough, then clears them. Without an endpoint
+// the counters stay local.
+func Flush(ctx context.Context, path, endpoint, userAgent string) error {
+	state, err := Load(path)
+	if err != nil || !state.Telemetry || endpoint == "" || state.Pending.Empty() {
+		return err
+	}
+	if time.Since(state.LastSent) < sendInterval {
+		return nil
+	}
+
+	payload, err := json.Marshal(state.Pending)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.NetworkError("send_telemetry", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.NetworkError("send_telemetry", fmt.Errorf("%s", resp.Status))
+	}
+
+	state.LastSent = time.Now()
+	state.Pending = Counters{}
+	return state.Save(path)
+}
+
+// ErrorCategory returns the coarse category of an error, or "" for nil
+func ErrorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+	if stderrors.Is(err, context.Canceled) {
+		return "interrupted"
+	}
+	var sentinelErr *errors.SentinelError
+	if stderrors.As(err, &sentinelErr) {
+		return sentinelErr.Type.String()
+	}
+	return "other"
+}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ChoiceModel lets the user pick one of several options
+type ChoiceModel struct {
+	prompt    string
+	details   string
+	options   []string
+	cursor    int
+	chosen    int
+	cancelled bool
+}
+
+func (m ChoiceModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ChoiceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.options)-1 {
+				m.cursor++
+			}
+		case "enter":
+			m.chosen = m.cursor
+			return m, tea.Quit
+		case "q", "esc", "ctrl+c":
+			m.cancelled = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func (m ChoiceModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(Format(SeverityWarning, m.prompt) + "\n\n")
+
+	if m.details != "" {
+		b.WriteString(dimStyle.Render(m.details) + "\n\n")
+	}
+
+	for i, option := range m.options {
+		if i == m.cursor {
+			b.WriteString(highlightStyle.Render(fmt.Sprintf("▸ %s", option)) + "\n")
+		} else {
+			b.WriteString(fmt.Sprintf("  %s\n", option))
+		}
+	}
+
+	b.WriteString("\n" + infoStyle.Render("↑/↓ to move, [enter] to choose, [q] to cancel") + "\n")
+
+	return b.String()
+}
+
+// NewChoiceModel creates a new choice dialog
+func NewChoiceModel(prompt, details string, options []string) ChoiceModel {
+	return ChoiceModel{
+		prompt:  prompt,
+		details: details,
+		options: options,
+		chosen:  -1,
+	}
+}
+
+// ShowChoice displays a choice dialog and returns the index of the chosen
+// option, or -1 if the user cancelled
+func ShowChoice(prompt, details string, options []string) (int, error) {
+	model := NewChoiceModel(prompt, details, options)
+	finalModel, err := runProgram(model)
+	if IsProgramError(err) {
+		return promptChoice(prompt, details, options)
+	}
+	if err != nil {
+		return -1, err
+	}
+
+	if m := finalModel.(ChoiceModel); !m.cancelled {
+		return m.chosen, nil
+	}
+
+	return -1, nil
+}
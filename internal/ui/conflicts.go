@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Conflict is a region that both a file and the fix merged onto it changed
+type Conflict struct {
+	Line    int      // Line of the file where the region starts
+	Base    []string // Lines the fix was made from
+	Current []string // Lines of the file
+	Fix     []string // Lines the fix writes
+}
+
+// Choices for a conflict, in the order ShowConflicts returns them
+const (
+	ConflictKeepCurrent = iota
+	ConflictTakeFix
+	ConflictKeepBoth
+)
+
+// conflictOptions are the choices for a conflict, indexed as above
+var conflictOptions = []string{"Keep the current lines", "Take the fix's lines", "Keep both, current first"}
+
+// ConflictsModel steps through the conflicts of a merge and lets the user
+// settle each one
+type ConflictsModel struct {
+	title     string
+	details   string
+	conflicts []Conflict
+	choices   []int // -1 while undecided
+	current   int
+	cancelled bool
+	done      bool
+}
+
+func (m ConflictsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ConflictsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "c", "C":
+			return m.choose(ConflictKeepCurrent)
+		case "f", "F":
+			return m.choose(ConflictTakeFix)
+		case "b", "B":
+			return m.choose(ConflictKeepBoth)
+		case "right", "l", "tab":
+			m.current = (m.current + 1) % len(m.conflicts)
+		case "left", "h", "shift+tab":
+			m.current = (m.current + len(m.conflicts) - 1) % len(m.conflicts)
+		case "q", "esc", "ctrl+c":
+			m.cancelled = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// choose settles the current conflict and moves to the next undecided one,
+// finishing once every conflict is settled
+func (m ConflictsModel) choose(choice int) (tea.Model, tea.Cmd) {
+	choices := append([]int(nil), m.choices...)
+	choices[m.current] = choice
+	m.choices = choices
+	for step := 1; step <= len(m.conflicts); step++ {
+		if next := (m.current + step) % len(m.conflicts); m.choices[next] < 0 {
+			m.current = next
+			return m, nil
+		}
+	}
+	m.done = true
+	return m, tea.Quit
+}
+
+func (m ConflictsModel) View() string {
+	if m.cancelled || m.done {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(m.title) + "\n\n")
+	if m.details != "" {
+		b.WriteString(dimStyle.Render(m.details) + "\n\n")
+	}
+
+	// Choices so far: ✓ settled, ▸ current, · undecided
+	var marks []string
+	for i, choice := range m.choices {
+		switch {
+		case i == m.current:
+			marks = append(marks, highlightStyle.Render("▸"))
+		case choice < 0:
+			marks = append(marks, dimStyle.Render("·"))
+		default:
+			marks = append(marks, Mark(SeveritySuccess))
+		}
+	}
+	b.WriteString(fmt.Sprintf("Conflict %d of %d  %s\n\n", m.current+1, len(m.conflicts), strings.Join(marks, " ")))
+	b.WriteString(conflictView(m.conflicts[m.current]))
+	if choice := m.choices[m.current]; choice >= 0 {
+		b.WriteString(dimStyle.Render("Chosen: "+conflictOptions[choice]) + "\n")
+	}
+
+	b.WriteString("\n" + infoStyle.Render("[c] keep the current lines, [f] take the fix's, [b] keep both, [←→] other conflicts, [q] cancel") + "\n")
+	return b.String()
+}
+
+// conflictView renders the three versions of a conflicting region
+func conflictView(conflict Conflict) string {
+	var b strings.Builder
+	section := func(label string, lines []string, prefix string) {
+		b.WriteString(highlightStyle.Render(label) + "\n")
+		if len(lines) == 0 {
+			b.WriteString(dimStyle.Render("  (no lines)") + "\n")
+		}
+		for _, line := range lines {
+			b.WriteString(FormatDiffLine(prefix+strings.TrimRight(line, "\r\n")) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	section(fmt.Sprintf("Current file, line %d:", conflict.Line), conflict.Current, "-")
+	section("Fix:", conflict.Fix, "+")
+	section("Analyzed version:", conflict.Base, " ")
+	return b.String()
+}
+
+// NewConflictsModel creates a conflict resolver with every conflict
+// undecided
+func NewConflictsModel(title, details string, conflicts []Conflict) ConflictsModel {
+	choices := make([]int, len(conflicts))
+	for i := range choices {
+		choices[i] = -1
+	}
+	return ConflictsModel{
+		title:     title,
+		details:   details,
+		conflicts: conflicts,
+		choices:   choices,
+	}
+}
+
+// ShowConflicts lets the user settle the conflicts of a merge one by one. It
+// returns the choice for each conflict, ConflictKeepCurrent, ConflictTakeFix
+// or ConflictKeepBoth, or nil if the user cancelled.
+func ShowConflicts(title, details string, conflicts []Conflict) ([]int, error) {
+	model := NewConflictsModel(title, details, conflicts)
+	finalModel, err := runProgram(model)
+	if IsProgramError(err) {
+		return promptConflicts(title, details, conflicts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if m := finalModel.(ConflictsModel); m.done {
+		return m.choices, nil
+	}
+
+	return nil, nil
+}
+
+// promptConflicts asks about each conflict in turn, or returns nil if the
+// user cancelled
+func promptConflicts(title, details string, conflicts []Conflict) ([]int, error) {
+	fmt.Fprintln(promptOut, headerStyle.Render(title))
+	printDetails(details)
+	choices := make([]int, len(conflicts))
+	for i, conflict := range conflicts {
+		fmt.Fprintf(promptOut, "\nConflict %d of %d\n", i+1, len(conflicts))
+		fmt.Fprint(promptOut, conflictView(conflict))
+		choice, err := promptChoice("Settle this conflict:", "", conflictOptions)
+		if err != nil || choice < 0 {
+			return nil, err
+		}
+		choices[i] = choice
+	}
+	return choices, nil
+}
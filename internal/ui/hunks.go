@@ -0,0 +1,143 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DiffHunk is one hunk of a change offered for review
+type DiffHunk struct {
+	Header string   // e.g. @@ -3,7 +3,8 @@
+	Lines  []string // Diff lines, prefixed with " ", "-" or "+"
+	Notes  []string // What the hunk addresses, shown under its header
+}
+
+// HunkSelectorModel steps through the hunks of a change and lets the user
+// accept or reject each one, like git add -p
+type HunkSelectorModel struct {
+	title     string
+	details   string
+	hunks     []DiffHunk
+	accepted  []bool
+	current   int
+	cancelled bool
+}
+
+func (m HunkSelectorModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m HunkSelectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "y", "Y":
+			m.accepted[m.current] = true
+			return m.next()
+		case "n", "N":
+			m.accepted[m.current] = false
+			return m.next()
+		case "a", "A":
+			for i := m.current; i < len(m.hunks); i++ {
+				m.accepted[i] = true
+			}
+			m.current = len(m.hunks)
+			return m, tea.Quit
+		case "d", "D":
+			for i := m.current; i < len(m.hunks); i++ {
+				m.accepted[i] = false
+			}
+			m.current = len(m.hunks)
+			return m, tea.Quit
+		case "k", "up", "K":
+			if m.current > 0 {
+				m.current--
+			}
+		case "q", "esc", "ctrl+c":
+			m.cancelled = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// next moves to the following hunk, finishing after the last one
+func (m HunkSelectorModel) next() (tea.Model, tea.Cmd) {
+	m.current++
+	if m.current >= len(m.hunks) {
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m HunkSelectorModel) View() string {
+	if m.cancelled || m.current >= len(m.hunks) {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(m.title) + "\n\n")
+	if m.details != "" {
+		b.WriteString(dimStyle.Render(m.details) + "\n\n")
+	}
+
+	// Decisions so far: ✓ accepted, ✗ rejected, ▸ current, · pending
+	var marks []string
+	for i := range m.hunks {
+		switch {
+		case i == m.current:
+			marks = append(marks, highlightStyle.Render("▸"))
+		case i > m.current:
+			marks = append(marks, dimStyle.Render("·"))
+		case m.accepted[i]:
+			marks = append(marks, Mark(SeveritySuccess))
+		default:
+			marks = append(marks, Mark(SeverityError))
+		}
+	}
+	b.WriteString(fmt.Sprintf("Hunk %d of %d  %s\n\n", m.current+1, len(m.hunks), strings.Join(marks, " ")))
+
+	hunk := m.hunks[m.current]
+	b.WriteString(FormatDiffLine(hunk.Header) + "\n")
+	for _, note := range hunk.Notes {
+		b.WriteString("  " + note + "\n")
+	}
+	for _, line := range hunk.Lines {
+		b.WriteString(FormatDiffLine(line) + "\n")
+	}
+
+	b.WriteString("\n" + infoStyle.Render("[y] apply this hunk, [n] skip it, [a] apply it and all later hunks, [d] skip the rest, [k] back, [q] cancel") + "\n")
+	return b.String()
+}
+
+// NewHunkSelectorModel creates a hunk selector with every hunk rejected
+// until the user accepts it
+func NewHunkSelectorModel(title, details string, hunks []DiffHunk) HunkSelectorModel {
+	return HunkSelectorModel{
+		title:    title,
+		details:  details,
+		hunks:    hunks,
+		accepted: make([]bool, len(hunks)),
+	}
+}
+
+// ShowHunkSelector lets the user review the hunks of a change one by one. It
+// returns which hunks were accepted, or nil if the user cancelled.
+func ShowHunkSelector(title, details string, hunks []DiffHunk) ([]bool, error) {
+	model := NewHunkSelectorModel(title, details, hunks)
+	finalModel, err := runProgram(model)
+	if IsProgramError(err) {
+		return promptHunks(title, details, hunks)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if m := finalModel.(HunkSelectorModel); !m.cancelled && m.current >= len(m.hunks) {
+		return m.accepted, nil
+	}
+
+	return nil, nil
+}
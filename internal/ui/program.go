@@ -0,0 +1,21 @@
+package ui
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// programCtx is the context TUI programs run under
+var programCtx = context.Background()
+
+// SetContext sets the context of TUI programs. Cancelling it closes the
+// running program and restores the terminal, leaving the alternate screen.
+func SetContext(ctx context.Context) {
+	programCtx = ctx
+}
+
+// newProgram creates a TUI program bound to the program context
+func newProgram(model tea.Model, opts ...tea.ProgramOption) *tea.Program {
+	return tea.NewProgram(model, append(opts, tea.WithContext(programCtx))...)
+}
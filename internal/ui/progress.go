@@ -8,7 +8,6 @@ import (
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 // ProgressModel displays a progress indicator with status
@@ -80,9 +79,9 @@ func (m ProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m ProgressModel) View() string {
 	if m.done {
 		if m.err != nil {
-			return errorStyle.Render(fmt.Sprintf("✗ %s: %v\n", m.status, m.err))
+			return Format(SeverityError, fmt.Sprintf("%s: %v", m.status, m.err)) + "\n"
 		}
-		return successStyle.Render(fmt.Sprintf("✓ %s\n", m.status))
+		return Format(SeveritySuccess, m.status) + "\n"
 	}
 
 	return fmt.Sprintf("\n%s %s\n\n", m.spinner.View(), infoStyle.Render(m.status))
@@ -92,7 +91,7 @@ func (m ProgressModel) View() string {
 func NewProgressModel(status string) ProgressModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	s.Style = accentStyle
 
 	p := progress.New(progress.WithDefaultGradient())
 
@@ -133,7 +132,7 @@ func (m ConfirmationModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m ConfirmationModel) View() string {
 	var b strings.Builder
 
-	b.WriteString(warningStyle.Render("⚠  " + m.prompt) + "\n\n")
+	b.WriteString(Format(SeverityWarning, m.prompt) + "\n\n")
 	
 	if m.details != "" {
 		b.WriteString(dimStyle.Render(m.details) + "\n\n")
@@ -155,18 +154,15 @@ func NewConfirmationModel(prompt, details string) ConfirmationModel {
 // ShowConfirmation displays a confirmation dialog and returns the result
 func ShowConfirmation(prompt, details string) (bool, error) {
 	model := NewConfirmationModel(prompt, details)
-	p := tea.NewProgram(model)
-
-	finalModel, err := p.Run()
+	finalModel, err := runProgram(model)
+	if IsProgramError(err) {
+		return promptConfirmation(prompt, details)
+	}
 	if err != nil {
 		return false, err
 	}
 
-	if m, ok := finalModel.(ConfirmationModel); ok {
-		return m.confirmed, nil
-	}
-
-	return false, nil
+	return finalModel.(ConfirmationModel).confirmed, nil
 }
 
 // DiffViewerModel displays a diff comparison
@@ -211,15 +207,7 @@ func (m DiffViewerModel) View() string {
 	}
 
 	for _, line := range displayLines {
-		if strings.HasPrefix(line, "+") {
-			b.WriteString(successStyle.Render(line) + "\n")
-		} else if strings.HasPrefix(line, "-") {
-			b.WriteString(errorStyle.Render(line) + "\n")
-		} else if strings.HasPrefix(line, "===") {
-			b.WriteString(headerStyle.Render(line) + "\n")
-		} else {
-			b.WriteString(dimStyle.Render(line) + "\n")
-		}
+		b.WriteString(FormatDiffLine(line) + "\n")
 	}
 
 	if len(lines) > m.viewport && m.viewport > 0 {
@@ -240,10 +228,16 @@ func NewDiffViewerModel(title, diff string) DiffViewerModel {
 	}
 }
 
-// ShowDiff displays a diff viewer
+// ShowDiff displays a diff viewer, or prints the diff if the TUI fails
 func ShowDiff(title, diff string) error {
 	model := NewDiffViewerModel(title, diff)
-	p := tea.NewProgram(model)
-	_, err := p.Run()
+	_, err := runProgram(model)
+	if IsProgramError(err) {
+		fmt.Fprintln(promptOut, headerStyle.Render(title))
+		for _, line := range strings.Split(diff, "\n") {
+			fmt.Fprintln(promptOut, FormatDiffLine(line))
+		}
+		return nil
+	}
 	return err
 }
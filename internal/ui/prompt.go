@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ProgramError reports that a TUI program could not run, for instance on a
+// broken terminal. Unlike a cancellation, it says nothing about what the
+// user wanted.
+type ProgramError struct {
+	Err error
+}
+
+func (e *ProgramError) Error() string {
+	return fmt.Sprintf("terminal UI failed: %v", e.Err)
+}
+
+func (e *ProgramError) Unwrap() error {
+	return e.Err
+}
+
+// IsProgramError reports whether an error is a TUI failure
+func IsProgramError(err error) bool {
+	var programErr *ProgramError
+	return errors.As(err, &programErr)
+}
+
+// Plain-text prompts replace the TUI once it has failed
+var (
+	plainMode bool
+	promptIn            = bufio.NewReader(os.Stdin)
+	promptOut io.Writer = os.Stdout
+)
+
+// PlainMode reports whether dialogs use plain-text prompts because the TUI
+// failed earlier
+func PlainMode() bool {
+	return plainMode
+}
+
+// runProgram runs a TUI program. A failure that is not due to the program
+// context being cancelled switches later dialogs to plain-text prompts and
+// is returned as a ProgramError.
+func runProgram(model tea.Model, opts ...tea.ProgramOption) (tea.Model, error) {
+	if plainMode {
+		return nil, &ProgramError{Err: errors.New("plain-text prompts in use")}
+	}
+	finalModel, err := newProgram(model, opts...).Run()
+	if err == nil {
+		return finalModel, nil
+	}
+	if programCtx.Err() != nil {
+		return nil, programCtx.Err()
+	}
+	plainMode = true
+	fmt.Fprintln(promptOut, FormatWarning(fmt.Sprintf("Terminal UI unavailable (%v), using text prompts", err)))
+	return nil, &ProgramError{Err: err}
+}
+
+// readAnswer prints a prompt and reads a trimmed line. End of input counts
+// as a cancellation and returns io.EOF.
+func readAnswer(prompt string) (string, error) {
+	fmt.Fprint(promptOut, prompt)
+	line, err := promptIn.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(promptOut)
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// printDetails prints the details of a dialog, if any
+func printDetails(details string) {
+	if details != "" {
+		fmt.Fprintln(promptOut, dimStyle.Render(details))
+		fmt.Fprintln(promptOut)
+	}
+}
+
+// promptConfirmation asks a yes/no question; anything but yes declines
+func promptConfirmation(prompt, details string) (bool, error) {
+	printDetails(details)
+	answer, err := readAnswer(Format(SeverityWarning, prompt) + " [y/N]: ")
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// promptChoice asks for one of several options by number, or -1 if the user
+// cancelled
+func promptChoice(prompt, details string, options []string) (int, error) {
+	fmt.Fprintln(promptOut, Format(SeverityWarning, prompt))
+	printDetails(details)
+	for i, option := range options {
+		fmt.Fprintf(promptOut, "  %d. %s\n", i+1, option)
+	}
+	for {
+		answer, err := readAnswer(fmt.Sprintf("Choice [1-%d, q to cancel]: ", len(options)))
+		if err == io.EOF {
+			return -1, nil
+		}
+		if err != nil {
+			return -1, err
+		}
+		if answer == "q" {
+			return -1, nil
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		fmt.Fprintln(promptOut, FormatDim(fmt.Sprintf("Enter a number from 1 to %d", len(options))))
+	}
+}
+
+// promptHunks asks about each hunk in turn, or returns nil if the user
+// cancelled
+func promptHunks(title, details string, hunks []DiffHunk) ([]bool, error) {
+	fmt.Fprintln(promptOut, headerStyle.Render(title))
+	printDetails(details)
+	accepted := make([]bool, len(hunks))
+	for i := 0; i < len(hunks); i++ {
+		hunk := hunks[i]
+		fmt.Fprintf(promptOut, "\nHunk %d of %d\n", i+1, len(hunks))
+		fmt.Fprintln(promptOut, FormatDiffLine(hunk.Header))
+		for _, note := range hunk.Notes {
+			fmt.Fprintln(promptOut, "  "+note)
+		}
+		for _, line := range hunk.Lines {
+			fmt.Fprintln(promptOut, FormatDiffLine(line))
+		}
+		answer, err := readAnswer("Apply this hunk? [y]es, [n]o, [a]ll remaining, [d]one, [q]uit: ")
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			accepted[i] = true
+		case "n", "no":
+		case "a":
+			for j := i; j < len(hunks); j++ {
+				accepted[j] = true
+			}
+			return accepted, nil
+		case "d":
+			return accepted, nil
+		case "q":
+			return nil, nil
+		default:
+			i--
+		}
+	}
+	return accepted, nil
+}
+
+// promptWorkflowSelector lists the runs and reads which to fix or cancel
+func promptWorkflowSelector(items []WorkflowItem) ([]WorkflowItem, SelectorAction, error) {
+	fmt.Fprintln(promptOut, headerStyle.Render("Workflow runs"))
+	for i, item := range items {
+		fmt.Fprintf(promptOut, "  %d. %s  %s\n", i+1, item.Icon, item.TitleText)
+		if item.DescText != "" {
+			fmt.Fprintln(promptOut, "     "+dimStyle.Render(item.DescText))
+		}
+	}
+	fmt.Fprintln(promptOut, FormatDim("Enter run numbers to fix (e.g. 1,3), a to fix all, x N to cancel run N, X N to cancel its branch runs, q to quit"))
+	for {
+		answer, err := readAnswer("Runs: ")
+		if err == io.EOF {
+			return nil, ActionCancel, nil
+		}
+		if err != nil {
+			return nil, ActionCancel, err
+		}
+		if selected, action, ok := parseSelection(answer, items); ok {
+			return selected, action, nil
+		}
+		fmt.Fprintln(promptOut, FormatDim(fmt.Sprintf("Enter numbers from 1 to %d, a, x N, X N or q", len(items))))
+	}
+}
+
+// parseSelection reads an answer to the workflow selector prompt
+func parseSelection(answer string, items []WorkflowItem) ([]WorkflowItem, SelectorAction, bool) {
+	index := func(s string) (int, bool) {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		return n - 1, err == nil && n >= 1 && n <= len(items)
+	}
+	switch {
+	case answer == "q" || answer == "":
+		return nil, ActionCancel, answer == "q"
+	case answer == "a":
+		return items[:1], ActionFixAll, true
+	case strings.HasPrefix(answer, "x ") || strings.HasPrefix(answer, "X "):
+		i, ok := index(answer[2:])
+		if !ok {
+			return nil, ActionCancel, false
+		}
+		if answer[0] == 'X' {
+			return []WorkflowItem{items[i]}, ActionCancelBranch, true
+		}
+		return []WorkflowItem{items[i]}, ActionCancelRun, true
+	}
+	var selected []WorkflowItem
+	for _, field := range strings.Split(answer, ",") {
+		i, ok := index(field)
+		if !ok {
+			return nil, ActionCancel, false
+		}
+		selected = append(selected, items[i])
+	}
+	return selected, ActionAnalyze, true
+}
@@ -2,49 +2,13 @@ package ui
 
 import (
 	"fmt"
+	"io"
+	"os"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
-)
-
-// Styles for the TUI
-var (
-	titleStyle = lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("205")).
-		Background(lipgloss.Color("235")).
-		Padding(0, 1)
-
-	docStyle = lipgloss.NewStyle().
-		Margin(1, 2)
-
-	successStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("42")).
-		Bold(true)
-
-	errorStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("196")).
-		Bold(true)
-
-	warningStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("214")).
-		Bold(true)
-
-	infoStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("86"))
-
-	dimStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240"))
-
-	headerStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("99")).
-		Bold(true).
-		Underline(true)
-
-	highlightStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("226")).
-		Bold(true)
+	"github.com/mattn/go-isatty"
 )
 
 // WorkflowItem represents a workflow run in the list
@@ -55,14 +19,38 @@ type WorkflowItem struct {
 	Status      string
 	Conclusion  string
 	Path        string
+	Branch      string
 	Icon        string
+	Checked     bool // Marked for multi-selection
 }
 
+// SelectorAction is what the user asked to do with the highlighted run
+type SelectorAction int
+
+const (
+	ActionAnalyze      SelectorAction = iota // Diagnose and fix the run
+	ActionCancelRun                          // Cancel the run itself
+	ActionCancelBranch                       // Cancel every active run on its branch
+	ActionFixAll                             // Diagnose and fix every listed run
+	ActionCancel                             // The user quit without choosing
+)
+
+var (
+	cancelRunKey    = key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "cancel run"))
+	cancelBranchKey = key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "cancel branch runs"))
+	fixAllKey       = key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "fix all"))
+	toggleKey       = key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select"))
+	applyKey        = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "fix selected"))
+)
+
 func (i WorkflowItem) FilterValue() string {
 	return i.TitleText
 }
 
 func (i WorkflowItem) Title() string {
+	if i.Checked {
+		return fmt.Sprintf("✓ %s  %s", i.Icon, i.TitleText)
+	}
 	return fmt.Sprintf("%s  %s", i.Icon, i.TitleText)
 }
 
@@ -70,10 +58,12 @@ func (i WorkflowItem) Description() string {
 	return i.DescText
 }
 
-// WorkflowSelectorModel is the model for workflow selection
+// WorkflowSelectorModel is the model for workflow selection. Space marks
+// runs, and enter returns the marked runs or else the highlighted one.
 type WorkflowSelectorModel struct {
 	list     list.Model
-	selected *WorkflowItem
+	selected []WorkflowItem
+	action   SelectorAction
 	quitting bool
 }
 
@@ -84,14 +74,38 @@ func (m WorkflowSelectorModel) Init() tea.Cmd {
 func (m WorkflowSelectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// Keys typed into the filter belong to the filter
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
 		switch msg.String() {
-		case "enter":
+		case " ":
+			if item, ok := m.list.SelectedItem().(WorkflowItem); ok {
+				item.Checked = !item.Checked
+				cmd := m.list.SetItem(m.list.Index(), item)
+				return m, cmd
+			}
+		case "enter", "x", "X", "a":
 			if item, ok := m.list.SelectedItem().(WorkflowItem); ok {
-				m.selected = &item
+				m.selected = []WorkflowItem{item}
+				m.action = ActionAnalyze
+				switch {
+				case key.Matches(msg, cancelRunKey):
+					m.action = ActionCancelRun
+				case key.Matches(msg, cancelBranchKey):
+					m.action = ActionCancelBranch
+				case key.Matches(msg, fixAllKey):
+					m.action = ActionFixAll
+				default:
+					if checked := m.checked(); len(checked) > 0 {
+						m.selected = checked
+					}
+				}
 				m.quitting = true
 				return m, tea.Quit
 			}
 		case "q", "ctrl+c", "esc":
+			m.action = ActionCancel
 			m.quitting = true
 			return m, tea.Quit
 		}
@@ -107,8 +121,13 @@ func (m WorkflowSelectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m WorkflowSelectorModel) View() string {
 	if m.quitting {
-		if m.selected != nil {
-			return successStyle.Render(fmt.Sprintf("✓ Selected: %s", m.selected.TitleText))
+		switch {
+		case len(m.selected) > 0 && m.action == ActionFixAll:
+			return Format(SeveritySuccess, fmt.Sprintf("Fixing all %d runs", len(m.list.Items())))
+		case len(m.selected) > 1:
+			return Format(SeveritySuccess, fmt.Sprintf("Selected %d runs", len(m.selected)))
+		case len(m.selected) == 1:
+			return Format(SeveritySuccess, fmt.Sprintf("Selected: %s", m.selected[0].TitleText))
 		}
 		return dimStyle.Render("Operation cancelled")
 	}
@@ -126,60 +145,83 @@ func NewWorkflowSelector(items []WorkflowItem) *WorkflowSelectorModel {
 	// Create custom delegate with better styling
 	delegate := list.NewDefaultDelegate()
 	delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.
-		Foreground(lipgloss.Color("205")).
-		BorderForeground(lipgloss.Color("205"))
+		Foreground(current.Accent).
+		BorderForeground(current.Accent)
 	delegate.Styles.SelectedDesc = delegate.Styles.SelectedDesc.
-		Foreground(lipgloss.Color("240"))
+		Foreground(current.Dim)
 
 	l := list.New(listItems, delegate, 0, 0)
 	l.Title = "🛡️  Sentinel CI - Workflow Runs"
 	l.Styles.Title = titleStyle
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{toggleKey, applyKey, fixAllKey, cancelRunKey, cancelBranchKey}
+	}
 
 	return &WorkflowSelectorModel{
 		list: l,
 	}
 }
 
-// GetSelected returns the selected item after the program exits
-func (m *WorkflowSelectorModel) GetSelected() *WorkflowItem {
+// checked returns the marked items in list order
+func (m WorkflowSelectorModel) checked() []WorkflowItem {
+	var items []WorkflowItem
+	for _, listItem := range m.list.Items() {
+		if item, ok := listItem.(WorkflowItem); ok && item.Checked {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetSelected returns the selected items after the program exits
+func (m *WorkflowSelectorModel) GetSelected() []WorkflowItem {
 	return m.selected
 }
 
-// ShowWorkflowSelector displays the workflow selector and returns the selected item
-func ShowWorkflowSelector(items []WorkflowItem) (*WorkflowItem, error) {
-	model := NewWorkflowSelector(items)
-	p := tea.NewProgram(model, tea.WithAltScreen())
+// GetAction returns what the user asked to do with the selected item
+func (m *WorkflowSelectorModel) GetAction() SelectorAction {
+	return m.action
+}
 
-	finalModel, err := p.Run()
+// ShowWorkflowSelector displays the workflow selector and returns the selected
+// items with the requested action, or ActionCancel if the user quit. Cancel
+// actions apply to the first item. If the TUI fails, the runs are listed and
+// chosen with a plain-text prompt instead.
+func ShowWorkflowSelector(items []WorkflowItem) ([]WorkflowItem, SelectorAction, error) {
+	model := NewWorkflowSelector(items)
+	finalModel, err := runProgram(model, tea.WithAltScreen())
+	if IsProgramError(err) {
+		return promptWorkflowSelector(items)
+	}
 	if err != nil {
-		return nil, err
+		return nil, ActionCancel, err
 	}
 
-	if m, ok := finalModel.(WorkflowSelectorModel); ok {
-		return m.GetSelected(), nil
+	m := finalModel.(WorkflowSelectorModel)
+	if len(m.GetSelected()) == 0 {
+		return nil, ActionCancel, nil
 	}
-
-	return nil, nil
+	return m.GetSelected(), m.GetAction(), nil
 }
 
 // FormatSuccess returns a success message with styling
 func FormatSuccess(msg string) string {
-	return successStyle.Render("✓ " + msg)
+	return Format(SeveritySuccess, msg)
 }
 
 // FormatError returns an error message with styling
 func FormatError(msg string) string {
-	return errorStyle.Render("✗ " + msg)
+	return Format(SeverityError, msg)
 }
 
 // FormatWarning returns a warning message with styling
 func FormatWarning(msg string) string {
-	return warningStyle.Render("⚠ " + msg)
+	return Format(SeverityWarning, msg)
 }
 
 // FormatInfo returns an info message with styling
 func FormatInfo(msg string) string {
-	return infoStyle.Render("ℹ " + msg)
+	return Format(SeverityInfo, msg)
 }
 
 // FormatHeader returns a header with styling
@@ -197,13 +239,19 @@ func FormatDim(msg string) string {
 	return dimStyle.Render(msg)
 }
 
+// IsTerminal reports whether stdin is an interactive terminal
+func IsTerminal() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
 // PrintBanner displays the application banner
-func PrintBanner() {
+func PrintBanner(w io.Writer) {
 	banner := `
 🛡️  ╔═══════════════════════════════════════╗
    ║     SENTINEL CI - DevOps Guardian    ║
    ║   AI-Powered CI/CD Pipeline Repair   ║
    ╚═══════════════════════════════════════╝
 `
-	fmt.Println(infoStyle.Render(banner))
+	fmt.Fprintln(w, infoStyle.Render(banner))
 }
\ No newline at end of file
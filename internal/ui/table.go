@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TableAction is what the user asked to do with the highlighted row of a
+// patch table
+type TableAction int
+
+const (
+	TableInspect  TableAction = iota // Show the row's diff
+	TableRollback                    // Restore the row's backup
+	TableQuit                        // The user quit the table
+)
+
+// PatchTableModel lists patches in columns. Enter shows the highlighted
+// patch's diff and r rolls it back. A table with a verb only picks a row,
+// with enter.
+type PatchTableModel struct {
+	title   string
+	headers []string
+	rows    [][]string
+	widths  []int
+	cursor  int
+	height  int
+	action  TableAction
+	verb    string // What enter does to the row of a pick table, e.g. "diagnose"
+}
+
+func (m PatchTableModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m PatchTableModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.rows)-1 {
+				m.cursor++
+			}
+		case "home", "g":
+			m.cursor = 0
+		case "end", "G":
+			m.cursor = len(m.rows) - 1
+		case "enter", "d":
+			m.action = TableInspect
+			return m, tea.Quit
+		case "r":
+			if m.verb != "" {
+				break
+			}
+			m.action = TableRollback
+			return m, tea.Quit
+		case "q", "esc", "ctrl+c":
+			m.action = TableQuit
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.height = msg.Height - 6
+	}
+	return m, nil
+}
+
+func (m PatchTableModel) View() string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(m.title) + "\n\n")
+	b.WriteString("  " + dimStyle.Render(m.line(m.headers)) + "\n")
+
+	// Scroll so the cursor stays visible
+	first, last := 0, len(m.rows)
+	if m.height > 0 && len(m.rows) > m.height {
+		first = m.cursor - m.height + 1
+		if first < 0 {
+			first = 0
+		}
+		last = first + m.height
+	}
+	for i := first; i < last; i++ {
+		if i == m.cursor {
+			b.WriteString(highlightStyle.Render("▸ "+m.line(m.rows[i])) + "\n")
+		} else {
+			b.WriteString("  " + m.line(m.rows[i]) + "\n")
+		}
+	}
+	if first > 0 || last < len(m.rows) {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  %d-%d of %d", first+1, last, len(m.rows))) + "\n")
+	}
+
+	help := "↑/↓ to move, [enter] to show the diff, [r] to roll back, [q] to quit"
+	if m.verb != "" {
+		help = fmt.Sprintf("↑/↓ to move, [enter] to %s, [q] to quit", m.verb)
+	}
+	b.WriteString("\n" + infoStyle.Render(help) + "\n")
+	return b.String()
+}
+
+// line pads the cells of a row to the column widths
+func (m PatchTableModel) line(cells []string) string {
+	padded := make([]string, len(cells))
+	for i, cell := range cells {
+		padded[i] = cell + strings.Repeat(" ", m.widths[i]-lipgloss.Width(cell))
+	}
+	return strings.TrimRight(strings.Join(padded, "  "), " ")
+}
+
+// NewPatchTableModel creates a table of rows under headers, with the cursor
+// on row cursor
+func NewPatchTableModel(title string, headers []string, rows [][]string, cursor int) PatchTableModel {
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+	return PatchTableModel{
+		title:   title,
+		headers: headers,
+		rows:    rows,
+		widths:  widths,
+		cursor:  min(max(cursor, 0), len(rows)-1),
+	}
+}
+
+// ShowPatchTable displays a table of patches and returns the row the user
+// chose with what to do with it. The cursor starts on row cursor. If the
+// TUI fails, the rows are listed and chosen with a plain-text prompt.
+func ShowPatchTable(title string, headers []string, rows [][]string, cursor int) (int, TableAction, error) {
+	return showTable(NewPatchTableModel(title, headers, rows, cursor))
+}
+
+// ShowPickTable displays a table and returns the row the user picked, or -1
+// when they quit. verb says what picking a row does, e.g. "diagnose".
+func ShowPickTable(title string, headers []string, rows [][]string, verb string) (int, error) {
+	model := NewPatchTableModel(title, headers, rows, 0)
+	model.verb = verb
+	i, _, err := showTable(model)
+	return i, err
+}
+
+func showTable(model PatchTableModel) (int, TableAction, error) {
+	finalModel, err := runProgram(model, tea.WithAltScreen())
+	if IsProgramError(err) {
+		return promptPatchTable(model)
+	}
+	if err != nil {
+		return -1, TableQuit, err
+	}
+
+	m := finalModel.(PatchTableModel)
+	if m.action == TableQuit {
+		return -1, TableQuit, nil
+	}
+	return m.cursor, m.action, nil
+}
+
+// promptPatchTable prints a patch table and reads which patch to inspect or
+// roll back
+func promptPatchTable(m PatchTableModel) (int, TableAction, error) {
+	fmt.Fprintln(promptOut, headerStyle.Render(m.title))
+	fmt.Fprintln(promptOut, "      "+dimStyle.Render(m.line(m.headers)))
+	for i, row := range m.rows {
+		fmt.Fprintf(promptOut, "  %2d. %s\n", i+1, m.line(row))
+	}
+	prompt, retry := "Patch: ", fmt.Sprintf("Enter a number from 1 to %d, r N or q", len(m.rows))
+	if m.verb != "" {
+		prompt, retry = "Row: ", fmt.Sprintf("Enter a number from 1 to %d or q", len(m.rows))
+		fmt.Fprintln(promptOut, FormatDim(fmt.Sprintf("Enter N to %s row N, q to quit", m.verb)))
+	} else {
+		fmt.Fprintln(promptOut, FormatDim("Enter N to show the diff of patch N, r N to roll it back, q to quit"))
+	}
+	for {
+		answer, err := readAnswer(prompt)
+		if err == io.EOF {
+			return -1, TableQuit, nil
+		}
+		if err != nil {
+			return -1, TableQuit, err
+		}
+		if answer == "q" || answer == "" {
+			return -1, TableQuit, nil
+		}
+		action := TableInspect
+		if rest, ok := strings.CutPrefix(answer, "r "); ok && m.verb == "" {
+			action, answer = TableRollback, rest
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(answer)); err == nil && n >= 1 && n <= len(m.rows) {
+			return n - 1, action, nil
+		}
+		fmt.Fprintln(promptOut, FormatDim(retry))
+	}
+}
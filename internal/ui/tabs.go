@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// FileDiff is the change to one file of a fix that spans several
+type FileDiff struct {
+	File string
+	Diff string // Unified diff
+}
+
+// FileDiffsModel shows the diff of each file of a change in its own tab and
+// asks whether to apply the change as a whole
+type FileDiffsModel struct {
+	title     string
+	details   string
+	files     []FileDiff
+	current   int
+	offset    int // First line of the diff shown
+	height    int // Diff lines that fit on the screen
+	confirmed bool
+	done      bool
+}
+
+func (m FileDiffsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m FileDiffsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "right", "l":
+			m.current = (m.current + 1) % len(m.files)
+			m.offset = 0
+		case "shift+tab", "left", "h":
+			m.current = (m.current + len(m.files) - 1) % len(m.files)
+			m.offset = 0
+		case "down", "j":
+			if m.offset+m.height < len(m.lines()) {
+				m.offset++
+			}
+		case "up", "k":
+			if m.offset > 0 {
+				m.offset--
+			}
+		case "y", "Y":
+			m.confirmed, m.done = true, true
+			return m, tea.Quit
+		case "n", "N", "q", "esc", "ctrl+c":
+			m.done = true
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.height = max(msg.Height-10, 5)
+	}
+	return m, nil
+}
+
+// lines returns the diff lines of the current tab
+func (m FileDiffsModel) lines() []string {
+	return strings.Split(strings.TrimRight(m.files[m.current].Diff, "\n"), "\n")
+}
+
+func (m FileDiffsModel) View() string {
+	if m.done {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(m.title) + "\n\n")
+	if m.details != "" {
+		b.WriteString(dimStyle.Render(m.details) + "\n\n")
+	}
+
+	var tabs []string
+	for i, file := range m.files {
+		if i == m.current {
+			tabs = append(tabs, highlightStyle.Render("[ "+file.File+" ]"))
+		} else {
+			tabs = append(tabs, dimStyle.Render("  "+file.File+"  "))
+		}
+	}
+	b.WriteString(strings.Join(tabs, " ") + "\n\n")
+
+	lines := m.lines()
+	end := min(m.offset+m.height, len(lines))
+	for _, line := range lines[m.offset:end] {
+		b.WriteString(FormatDiffLine(line) + "\n")
+	}
+	if end < len(lines) {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("... (%d more lines)", len(lines)-end)) + "\n")
+	}
+
+	b.WriteString("\n" + infoStyle.Render(fmt.Sprintf("File %d of %d: [tab] next file, [↑↓] scroll, [y] apply every file, [n] cancel", m.current+1, len(m.files))) + "\n")
+	return b.String()
+}
+
+// NewFileDiffsModel creates a tabbed diff of the files of a change
+func NewFileDiffsModel(title, details string, files []FileDiff) FileDiffsModel {
+	return FileDiffsModel{
+		title:   title,
+		details: details,
+		files:   files,
+		height:  20,
+	}
+}
+
+// ShowFileDiffs shows the diff of each file of a change in a tab and asks
+// whether to apply all of them; the files are applied together or not at
+// all. Without the TUI the diffs are printed one after the other.
+func ShowFileDiffs(title, details string, files []FileDiff) (bool, error) {
+	model := NewFileDiffsModel(title, details, files)
+	finalModel, err := runProgram(model)
+	if IsProgramError(err) {
+		return promptFileDiffs(title, details, files)
+	}
+	if err != nil {
+		return false, err
+	}
+	return finalModel.(FileDiffsModel).confirmed, nil
+}
+
+// promptFileDiffs prints the diff of each file and asks whether to apply
+// all of them
+func promptFileDiffs(title, details string, files []FileDiff) (bool, error) {
+	fmt.Fprintln(promptOut, headerStyle.Render(title))
+	for i, file := range files {
+		fmt.Fprintln(promptOut, highlightStyle.Render(fmt.Sprintf("\n[%d/%d] %s", i+1, len(files), file.File)))
+		for _, line := range strings.Split(strings.TrimRight(file.Diff, "\n"), "\n") {
+			fmt.Fprintln(promptOut, FormatDiffLine(line))
+		}
+	}
+	fmt.Fprintln(promptOut)
+	return promptConfirmation(fmt.Sprintf("Apply the changes to all %d files?", len(files)), details)
+}
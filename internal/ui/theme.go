@@ -0,0 +1,354 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Severity is how good or bad something shown to the user is. Every screen
+// renders a severity with the same icon and color.
+type Severity int
+
+const (
+	SeverityNeutral Severity = iota // Skipped, cancelled or without a verdict
+	SeverityPending                 // Queued, not started yet
+	SeverityRunning                 // In progress
+	SeverityInfo
+	SeveritySuccess
+	SeverityWarning
+	SeverityError
+)
+
+// icons are the markers of each severity
+var icons = map[Severity]string{
+	SeverityNeutral: "–",
+	SeverityPending: "○",
+	SeverityRunning: "●",
+	SeverityInfo:    "ℹ",
+	SeveritySuccess: "✓",
+	SeverityWarning: "⚠",
+	SeverityError:   "✗",
+}
+
+// Theme is the palette every style is built from
+type Theme struct {
+	Success   lipgloss.TerminalColor
+	Error     lipgloss.TerminalColor
+	Warning   lipgloss.TerminalColor
+	Info      lipgloss.TerminalColor
+	Dim       lipgloss.TerminalColor
+	Header    lipgloss.TerminalColor
+	Highlight lipgloss.TerminalColor
+	Accent    lipgloss.TerminalColor // Spinners, titles and the selected list item
+	TitleBg   lipgloss.TerminalColor
+}
+
+var (
+	darkTheme = Theme{
+		Success:   lipgloss.Color("42"),
+		Error:     lipgloss.Color("196"),
+		Warning:   lipgloss.Color("214"),
+		Info:      lipgloss.Color("86"),
+		Dim:       lipgloss.Color("240"),
+		Header:    lipgloss.Color("99"),
+		Highlight: lipgloss.Color("226"),
+		Accent:    lipgloss.Color("205"),
+		TitleBg:   lipgloss.Color("235"),
+	}
+
+	lightTheme = Theme{
+		Success:   lipgloss.Color("28"),
+		Error:     lipgloss.Color("160"),
+		Warning:   lipgloss.Color("166"),
+		Info:      lipgloss.Color("30"),
+		Dim:       lipgloss.Color("244"),
+		Header:    lipgloss.Color("55"),
+		Highlight: lipgloss.Color("130"),
+		Accent:    lipgloss.Color("162"),
+		TitleBg:   lipgloss.Color("254"),
+	}
+
+	// The high-contrast themes take success and failure from the blue and
+	// orange of the Okabe-Ito palette, which stay apart with deuteranopia
+	// and protanopia, instead of green and red. Status icons and diff
+	// prefixes carry the same meaning for readers who cannot tell any
+	// colors apart.
+	highContrastDark = Theme{
+		Success:   lipgloss.Color("39"),
+		Error:     lipgloss.Color("208"),
+		Warning:   lipgloss.Color("226"),
+		Info:      lipgloss.Color("117"),
+		Dim:       lipgloss.Color("250"),
+		Header:    lipgloss.Color("231"),
+		Highlight: lipgloss.Color("231"),
+		Accent:    lipgloss.Color("75"),
+		TitleBg:   lipgloss.Color("16"),
+	}
+
+	highContrastLight = Theme{
+		Success:   lipgloss.Color("25"),
+		Error:     lipgloss.Color("166"),
+		Warning:   lipgloss.Color("94"),
+		Info:      lipgloss.Color("24"),
+		Dim:       lipgloss.Color("238"),
+		Header:    lipgloss.Color("16"),
+		Highlight: lipgloss.Color("16"),
+		Accent:    lipgloss.Color("25"),
+		TitleBg:   lipgloss.Color("231"),
+	}
+
+	// The monochrome themes use shades of gray only, bright for what needs
+	// attention and darker for details, and leave meaning to the icons
+	monochromeDark = Theme{
+		Success:   lipgloss.Color("255"),
+		Error:     lipgloss.Color("255"),
+		Warning:   lipgloss.Color("255"),
+		Info:      lipgloss.Color("252"),
+		Dim:       lipgloss.Color("246"),
+		Header:    lipgloss.Color("255"),
+		Highlight: lipgloss.Color("255"),
+		Accent:    lipgloss.Color("252"),
+		TitleBg:   lipgloss.Color("238"),
+	}
+
+	monochromeLight = Theme{
+		Success:   lipgloss.Color("232"),
+		Error:     lipgloss.Color("232"),
+		Warning:   lipgloss.Color("232"),
+		Info:      lipgloss.Color("235"),
+		Dim:       lipgloss.Color("242"),
+		Header:    lipgloss.Color("232"),
+		Highlight: lipgloss.Color("232"),
+		Accent:    lipgloss.Color("235"),
+		TitleBg:   lipgloss.Color("252"),
+	}
+)
+
+// themes lists the selectable themes. auto, high-contrast and monochrome
+// follow the terminal background.
+var themes = map[string]Theme{
+	"auto":          adaptiveTheme(lightTheme, darkTheme),
+	"dark":          darkTheme,
+	"light":         lightTheme,
+	"high-contrast": adaptiveTheme(highContrastLight, highContrastDark),
+	"monochrome":    adaptiveTheme(monochromeLight, monochromeDark),
+	"plain": {
+		Success:   lipgloss.NoColor{},
+		Error:     lipgloss.NoColor{},
+		Warning:   lipgloss.NoColor{},
+		Info:      lipgloss.NoColor{},
+		Dim:       lipgloss.NoColor{},
+		Header:    lipgloss.NoColor{},
+		Highlight: lipgloss.NoColor{},
+		Accent:    lipgloss.NoColor{},
+		TitleBg:   lipgloss.NoColor{},
+	},
+}
+
+// current is the active theme
+var current Theme
+
+// Styles for the TUI, rebuilt from the active theme
+var (
+	titleStyle     lipgloss.Style
+	docStyle       lipgloss.Style
+	successStyle   lipgloss.Style
+	errorStyle     lipgloss.Style
+	warningStyle   lipgloss.Style
+	infoStyle      lipgloss.Style
+	dimStyle       lipgloss.Style
+	headerStyle    lipgloss.Style
+	highlightStyle lipgloss.Style
+	accentStyle    lipgloss.Style
+)
+
+func init() {
+	applyTheme(themes["auto"])
+}
+
+func adaptive(light, dark lipgloss.TerminalColor) lipgloss.AdaptiveColor {
+	return lipgloss.AdaptiveColor{Light: string(light.(lipgloss.Color)), Dark: string(dark.(lipgloss.Color))}
+}
+
+// adaptiveTheme picks the colors of light or dark by the terminal background
+func adaptiveTheme(light, dark Theme) Theme {
+	return Theme{
+		Success:   adaptive(light.Success, dark.Success),
+		Error:     adaptive(light.Error, dark.Error),
+		Warning:   adaptive(light.Warning, dark.Warning),
+		Info:      adaptive(light.Info, dark.Info),
+		Dim:       adaptive(light.Dim, dark.Dim),
+		Header:    adaptive(light.Header, dark.Header),
+		Highlight: adaptive(light.Highlight, dark.Highlight),
+		Accent:    adaptive(light.Accent, dark.Accent),
+		TitleBg:   adaptive(light.TitleBg, dark.TitleBg),
+	}
+}
+
+// SetTheme selects the color theme: auto, dark, light, high-contrast,
+// monochrome or plain
+func SetTheme(name string) error {
+	if err := CheckTheme(name); err != nil {
+		return err
+	}
+	applyTheme(themes[name])
+	return nil
+}
+
+// CheckTheme reports an error if no theme has the name
+func CheckTheme(name string) error {
+	if _, ok := themes[name]; !ok {
+		return fmt.Errorf("unknown theme %q (expected %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	return nil
+}
+
+// ThemeNames returns the names of the themes
+func ThemeNames() []string {
+	var names []string
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyTheme rebuilds the styles from a theme
+func applyTheme(theme Theme) {
+	current = theme
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(theme.Accent).
+		Background(theme.TitleBg).
+		Padding(0, 1)
+	docStyle = lipgloss.NewStyle().
+		Margin(1, 2)
+	successStyle = lipgloss.NewStyle().
+		Foreground(theme.Success).
+		Bold(true)
+	errorStyle = lipgloss.NewStyle().
+		Foreground(theme.Error).
+		Bold(true)
+	warningStyle = lipgloss.NewStyle().
+		Foreground(theme.Warning).
+		Bold(true)
+	infoStyle = lipgloss.NewStyle().
+		Foreground(theme.Info)
+	dimStyle = lipgloss.NewStyle().
+		Foreground(theme.Dim)
+	headerStyle = lipgloss.NewStyle().
+		Foreground(theme.Header).
+		Bold(true).
+		Underline(true)
+	highlightStyle = lipgloss.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true)
+	accentStyle = lipgloss.NewStyle().
+		Foreground(theme.Accent)
+}
+
+// severityStyle returns the style of a severity
+func severityStyle(sev Severity) lipgloss.Style {
+	switch sev {
+	case SeveritySuccess:
+		return successStyle
+	case SeverityError:
+		return errorStyle
+	case SeverityWarning:
+		return warningStyle
+	case SeverityInfo, SeverityRunning:
+		return infoStyle
+	}
+	return dimStyle
+}
+
+// Icon returns the plain marker of a severity, for text that is styled as a
+// whole, such as list items
+func Icon(sev Severity) string {
+	return icons[sev]
+}
+
+// Mark returns the styled marker of a severity
+func Mark(sev Severity) string {
+	return severityStyle(sev).Render(icons[sev])
+}
+
+// Format renders a message with the marker and color of its severity
+func Format(sev Severity, msg string) string {
+	return severityStyle(sev).Render(icons[sev] + " " + msg)
+}
+
+// Colorize renders text in the color of a severity, without a marker
+func Colorize(sev Severity, text string) string {
+	return severityStyle(sev).Render(text)
+}
+
+// ConclusionSeverity maps the status and conclusion of a workflow run or job
+// to a severity
+func ConclusionSeverity(status, conclusion string) Severity {
+	switch {
+	case status == "in_progress":
+		return SeverityRunning
+	case status != "" && status != "completed":
+		return SeverityPending
+	}
+	switch conclusion {
+	case "success":
+		return SeveritySuccess
+	case "skipped", "neutral", "cancelled", "stale":
+		return SeverityNeutral
+	case "":
+		return SeverityPending
+	}
+	return SeverityError
+}
+
+// RunMark returns the styled marker of a workflow run or job
+func RunMark(status, conclusion string) string {
+	return Mark(ConclusionSeverity(status, conclusion))
+}
+
+// LevelSeverity maps the level of a finding (error, warning, info, style or
+// note) or the severity of a log pattern (CRITICAL, HIGH, MEDIUM, LOW) to a
+// severity
+func LevelSeverity(level string) Severity {
+	switch strings.ToLower(level) {
+	case "error", "critical", "high":
+		return SeverityError
+	case "warning", "medium":
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// ConfidenceSeverity maps the confidence of a diagnosis to a severity
+func ConfidenceSeverity(confidence string) Severity {
+	switch confidence {
+	case "HIGH", "HEALTHY":
+		return SeveritySuccess
+	case "MEDIUM":
+		return SeverityWarning
+	case "LOW":
+		return SeverityError
+	}
+	return SeverityInfo
+}
+
+// FormatDiffLine colors a line of a unified diff: file and hunk headers,
+// additions, removals and context
+func FormatDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ "):
+		return headerStyle.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return successStyle.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return errorStyle.Render(line)
+	case strings.HasPrefix(line, "@@"):
+		return infoStyle.Render(line)
+	}
+	return dimStyle.Render(line)
+}
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// JobProgress is the state of one job in a watched run
+type JobProgress struct {
+	Name       string
+	Status     string
+	Conclusion string
+}
+
+// RunProgress is a snapshot of a watched workflow run
+type RunProgress struct {
+	Status     string
+	Conclusion string
+	Jobs       []JobProgress
+	Done       bool
+	Err        error
+}
+
+// WatchModel polls a workflow run and shows its jobs until it completes
+type WatchModel struct {
+	title    string
+	spinner  spinner.Model
+	poll     func() RunProgress
+	interval time.Duration
+	latest   RunProgress
+}
+
+type runProgressMsg RunProgress
+type pollMsg struct{}
+
+func (m WatchModel) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, m.pollCmd())
+}
+
+func (m WatchModel) pollCmd() tea.Cmd {
+	return func() tea.Msg {
+		return runProgressMsg(m.poll())
+	}
+}
+
+func (m WatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" || msg.String() == "q" {
+			return m, tea.Quit
+		}
+
+	case runProgressMsg:
+		m.latest = RunProgress(msg)
+		if m.latest.Done || m.latest.Err != nil {
+			return m, tea.Quit
+		}
+		return m, tea.Tick(m.interval, func(time.Time) tea.Msg {
+			return pollMsg{}
+		})
+
+	case pollMsg:
+		return m, m.pollCmd()
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+func (m WatchModel) View() string {
+	var b strings.Builder
+
+	status := m.latest.Status
+	if status == "" {
+		status = "waiting"
+	}
+	if m.latest.Done {
+		b.WriteString(Format(ConclusionSeverity("completed", m.latest.Conclusion), fmt.Sprintf("%s: %s", m.title, m.latest.Conclusion)) + "\n")
+	} else {
+		b.WriteString(fmt.Sprintf("%s %s\n", m.spinner.View(), infoStyle.Render(fmt.Sprintf("%s: %s", m.title, status))))
+	}
+
+	for _, job := range m.latest.Jobs {
+		b.WriteString(fmt.Sprintf("  %s %s\n", RunMark(job.Status, job.Conclusion), job.Name))
+	}
+
+	if !m.latest.Done {
+		b.WriteString("\n" + dimStyle.Render("[q] to stop watching (the run continues)") + "\n")
+	}
+	return b.String()
+}
+
+// NewWatchModel creates a watch view that calls poll every interval
+func NewWatchModel(title string, interval time.Duration, poll func() RunProgress) WatchModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = accentStyle
+
+	return WatchModel{
+		title:    title,
+		spinner:  s,
+		poll:     poll,
+		interval: interval,
+	}
+}
+
+// ShowRunWatch streams a run's progress until it completes or the user
+// stops watching, and returns the last snapshot. A ProgramError means the
+// TUI failed, and the caller should stream the progress as text.
+func ShowRunWatch(title string, interval time.Duration, poll func() RunProgress) (RunProgress, error) {
+	finalModel, err := runProgram(NewWatchModel(title, interval, poll))
+	if err != nil {
+		return RunProgress{}, err
+	}
+	return finalModel.(WatchModel).latest, nil
+}
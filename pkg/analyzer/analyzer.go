@@ -11,6 +11,7 @@ import (
 // Analyzer performs intelligent log analysis
 type Analyzer struct {
 	logger *logger.Logger
+	custom []ErrorPattern // User-defined patterns, matched before the built-ins
 }
 
 // NewAnalyzer creates a new analyzer
@@ -25,6 +26,7 @@ type ErrorPattern struct {
 	Severity    string
 	Suggestion  string
 	Category    string
+	Tag         string // One of Tags; empty for patterns too generic to classify
 }
 
 // Analysis contains the results of log analysis
@@ -34,6 +36,9 @@ type Analysis struct {
 	Summary     string
 	Confidence  float64
 	Category    string
+	Tag         string   // Failure tag of the first classified error
+	RunnerOS    []string // Operating systems of the failing jobs' runners
+	Matrix      []MatrixGroup // Combinations of the matrix jobs, when some failed
 }
 
 // DetectedError represents an error found in logs
@@ -44,8 +49,16 @@ type DetectedError struct {
 	Severity    string
 	Suggestion  string
 	Category    string
+	Tag         string
+	Step        string // "job › step" whose log the line is in, when the logs are split by step
 }
 
+// Headers of the jobs and steps in logs split by step
+var (
+	jobHeaderRe  = regexp.MustCompile(`^=== Job: (.+?) \(ID: `)
+	stepHeaderRe = regexp.MustCompile(`^--- (?:Step \d+: )?(.+?)(?: \([^()]*\))? ---$`)
+)
+
 // Common error patterns
 var errorPatterns = []ErrorPattern{
 	{
@@ -54,6 +67,87 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "HIGH",
 		Suggestion:  "Update to a newer Node.js version in your workflow",
 		Category:    "deprecation",
+		Tag:         TagInfra,
+	},
+	{
+		Name:        "Deprecated Action Version",
+		Pattern:     regexp.MustCompile(`(?i)uses a deprecated version of|deprecated version of .?actions/`),
+		Severity:    "CRITICAL",
+		Suggestion:  "Bump the deprecated action to its current major version",
+		Category:    "deprecation",
+		Tag:         TagInfra,
+	},
+	{
+		Name:        "Repository Not Checked Out",
+		Pattern:     regexp.MustCompile(`(?i)ENOENT: no such file or directory, open .*package\.json|go\.mod file not found|Could not open requirements file`),
+		Severity:    "HIGH",
+		Suggestion:  "Add an actions/checkout step before steps that use repository files",
+		Category:    "checkout",
+		Tag:         TagInfra,
+	},
+	{
+		Name:        "Private Submodule Clone Failed",
+		Pattern:     regexp.MustCompile(`(?i)clone of '[^']+' into submodule path '[^']+' failed|Failed to clone '[^']+'\. Retry scheduled|fatal: could not read Username for 'https://github\.com'|git@github\.com: Permission denied \(publickey\)`),
+		Severity:    "CRITICAL",
+		Suggestion:  "Give actions/checkout a `token:` (PAT) or `ssh-key:` that can read the submodule repositories; GITHUB_TOKEN only covers this repository",
+		Category:    "submodules",
+		Tag:         TagPermission,
+	},
+	{
+		Name:        "Git LFS Objects Missing",
+		Pattern:     regexp.MustCompile(`(?i)version https://git-lfs\.github\.com/spec/v1|git lfs pointer|Smudge error: Error downloading|external filter 'git-lfs filter-process' failed|Object does not exist on the server`),
+		Severity:    "HIGH",
+		Suggestion:  "Set `lfs: true` on actions/checkout; if it is already set, push the missing objects with `git lfs push --all`",
+		Category:    "lfs",
+		Tag:         TagInfra,
+	},
+	{
+		Name:        "Git LFS Quota Exceeded",
+		Pattern:     regexp.MustCompile(`(?i)over its data quota|LFS.*bandwidth.*(?:exceeded|limit)`),
+		Severity:    "HIGH",
+		Suggestion:  "Cache LFS objects with actions/cache keyed on `git lfs ls-files -l`, or buy more LFS bandwidth",
+		Category:    "lfs",
+		Tag:         TagResource,
+	},
+	{
+		Name:        "Node Engine Mismatch",
+		Pattern:     regexp.MustCompile(`(?i)The engine "node" is incompatible|requires? node(?:\.js)?(?: version)? >=`),
+		Severity:    "HIGH",
+		Suggestion:  "Raise node-version in actions/setup-node to satisfy the engines field",
+		Category:    "runtime_version",
+		Tag:         TagInfra,
+	},
+	{
+		Name:        "Token Permissions",
+		Pattern:     regexp.MustCompile(`(?i)Resource not accessible by integration|remote: Repository not found`),
+		Severity:    "HIGH",
+		Suggestion:  "Grant the workflow token the permissions it needs in a permissions: block",
+		Category:    "permissions",
+		Tag:         TagPermission,
+	},
+	{
+		Name:        "Missing OIDC Token Permission",
+		Pattern:     regexp.MustCompile(`(?i)ACTIONS_ID_TOKEN_REQUEST_(?:URL|TOKEN)|Credentials could not be loaded.*id-token`),
+		Severity:    "CRITICAL",
+		Suggestion:  "Grant the job `permissions: id-token: write` so it can request an OIDC token",
+		Category:    "cloud_auth",
+		Tag:         TagPermission,
+	},
+	{
+		Name:        "OIDC Trust Mismatch",
+		Pattern:     regexp.MustCompile(`(?i)Not authorized to perform sts:AssumeRoleWithWebIdentity|AADSTS700(?:21|213)|No matching federated identity record|Permission 'iam\.serviceAccounts\.getAccessToken' denied|unable to (?:generate|exchange).*(?:workload identity|token)`),
+		Severity:    "HIGH",
+		Suggestion:  "The cloud role's trust policy does not accept this workflow's OIDC subject",
+		Category:    "cloud_auth",
+		Tag:         TagPermission,
+	},
+	{
+		Name:        "Expired Cloud Credentials",
+		Pattern:     regexp.MustCompile(`(?i)ExpiredToken|security token included in the request is expired|AADSTS7000222|client secret keys? .*(?:are|is) expired|invalid_grant.*(?:expired|revoked)`),
+		Severity:    "HIGH",
+		Suggestion:  "Rotate the stored cloud credentials, or switch to OIDC federation to stop storing them",
+		Category:    "cloud_auth",
+		Tag:         TagPermission,
 	},
 	{
 		Name:        "Command Not Found",
@@ -61,6 +155,7 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "HIGH",
 		Suggestion:  "Install the missing command or check PATH configuration",
 		Category:    "dependency",
+		Tag:         TagDependency,
 	},
 	{
 		Name:        "Python Import Error",
@@ -68,6 +163,7 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "HIGH",
 		Suggestion:  "Install missing Python dependencies or check requirements.txt",
 		Category:    "dependency",
+		Tag:         TagDependency,
 	},
 	{
 		Name:        "NPM Install Failed",
@@ -75,6 +171,7 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "HIGH",
 		Suggestion:  "Check package.json or run npm install locally first",
 		Category:    "dependency",
+		Tag:         TagDependency,
 	},
 	{
 		Name:        "YAML Syntax Error",
@@ -82,6 +179,7 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "CRITICAL",
 		Suggestion:  "Fix YAML indentation or syntax errors",
 		Category:    "syntax",
+		Tag:         TagSyntax,
 	},
 	{
 		Name:        "Permission Denied",
@@ -89,6 +187,7 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "MEDIUM",
 		Suggestion:  "Add execute permissions or check file ownership",
 		Category:    "permissions",
+		Tag:         TagPermission,
 	},
 	{
 		Name:        "Docker Build Failed",
@@ -96,6 +195,7 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "HIGH",
 		Suggestion:  "Check Dockerfile syntax and build context",
 		Category:    "docker",
+		Tag:         TagInfra,
 	},
 	{
 		Name:        "Test Failure",
@@ -103,6 +203,31 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "MEDIUM",
 		Suggestion:  "Review test results and fix failing tests",
 		Category:    "testing",
+		Tag:         TagTest,
+	},
+	{
+		Name:        "Out of Disk Space",
+		Pattern:     regexp.MustCompile(`(?i)No space left on device|ENOSPC|System\.IO\.IOException: There is not enough space on the disk`),
+		Severity:    "HIGH",
+		Suggestion:  "Free runner disk space (remove unused toolchains or prune Docker images) or use a larger runner",
+		Category:    "disk",
+		Tag:         TagResource,
+	},
+	{
+		Name:        "Job Timed Out",
+		Pattern:     regexp.MustCompile(`(?i)has exceeded the maximum execution time of \d+ minutes`),
+		Severity:    "HIGH",
+		Suggestion:  "Raise timeout-minutes, or find the step that hangs and cache or parallelize the slow work",
+		Category:    "timeout",
+		Tag:         TagResource,
+	},
+	{
+		Name:        "Service Unavailable",
+		Pattern:     regexp.MustCompile(`(?i)\b(?:502 Bad Gateway|503 Service (?:Temporarily )?Unavailable|504 Gateway Time-?out)\b|Could not resolve host|ECONNRESET|ETIMEDOUT|TLS handshake timeout|API rate limit exceeded`),
+		Severity:    "MEDIUM",
+		Suggestion:  "A registry or service the job depends on was down or unreachable; re-run the job, and add retries to the step if it happens often",
+		Category:    "network",
+		Tag:         TagExternalService,
 	},
 	{
 		Name:        "Exit Code Non-Zero",
@@ -117,23 +242,43 @@ var errorPatterns = []ErrorPattern{
 		Severity:    "CRITICAL",
 		Suggestion:  "Fix workflow YAML syntax according to GitHub Actions schema",
 		Category:    "syntax",
+		Tag:         TagSyntax,
 	},
 }
 
 // AnalyzeLogs performs comprehensive log analysis
 func (a *Analyzer) AnalyzeLogs(logs string) *Analysis {
+	analysis := a.AnalyzePart(logs)
+	a.logger.Info("Analysis complete: %d errors, confidence %.2f", len(analysis.Errors), analysis.Confidence)
+	return analysis
+}
+
+// AnalyzePart analyzes part of a run's logs, e.g. a job's, to Merge with
+// the other parts
+func (a *Analyzer) AnalyzePart(logs string) *Analysis {
 	a.logger.Debug("Analyzing logs (%d chars)", len(logs))
 
 	analysis := &Analysis{
 		Errors:   []DetectedError{},
 		Warnings: []string{},
+		RunnerOS: DetectRunnerOS(logs),
 	}
+	patterns := a.activePatterns(analysis.RunnerOS)
 
 	lines := strings.Split(logs, "\n")
 
 	// Pattern matching
+	var job, step string
 	for i, line := range lines {
-		for _, pattern := range errorPatterns {
+		if m := jobHeaderRe.FindStringSubmatch(line); m != nil {
+			job, step = m[1], ""
+			continue
+		}
+		if m := stepHeaderRe.FindStringSubmatch(line); m != nil {
+			step = m[1]
+			continue
+		}
+		for _, pattern := range patterns {
 			if pattern.Pattern.MatchString(line) {
 				analysis.Errors = append(analysis.Errors, DetectedError{
 					Pattern:    pattern.Name,
@@ -142,24 +287,77 @@ func (a *Analyzer) AnalyzeLogs(logs string) *Analysis {
 					Severity:   pattern.Severity,
 					Suggestion: pattern.Suggestion,
 					Category:   pattern.Category,
+					Tag:        pattern.Tag,
+					Step:       stepLabel(job, step),
 				})
 				a.logger.Debug("Detected error pattern: %s at line %d", pattern.Name, i+1)
 			}
 		}
 	}
 
-	// Categorize and summarize
+	a.summarize(analysis)
+	return analysis
+}
+
+// Merge combines the analyses of parts of a run's logs, e.g. of each job,
+// in the order given. Line numbers stay relative to the part they are in.
+func (a *Analyzer) Merge(parts []*Analysis) *Analysis {
+	merged := &Analysis{
+		Errors:   []DetectedError{},
+		Warnings: []string{},
+	}
+	systems := make(map[string]bool)
+	for _, part := range parts {
+		merged.Errors = append(merged.Errors, part.Errors...)
+		merged.Warnings = append(merged.Warnings, part.Warnings...)
+		for _, system := range part.RunnerOS {
+			systems[system] = true
+		}
+	}
+	for _, system := range []string{OSLinux, OSWindows, OSMacOS} {
+		if systems[system] {
+			merged.RunnerOS = append(merged.RunnerOS, system)
+		}
+	}
+	a.summarize(merged)
+	a.logger.Info("Analysis complete: %d errors, confidence %.2f", len(merged.Errors), merged.Confidence)
+	return merged
+}
+
+// summarize sets the category, tag, summary and confidence of an analysis
+// from its errors
+func (a *Analyzer) summarize(analysis *Analysis) {
 	if len(analysis.Errors) > 0 {
 		analysis.Category = analysis.Errors[0].Category
+		for _, e := range analysis.Errors {
+			if e.Tag != "" {
+				analysis.Tag = e.Tag
+				break
+			}
+		}
 		analysis.Summary = a.generateSummary(analysis.Errors)
 		analysis.Confidence = a.calculateConfidence(analysis.Errors)
 	} else {
 		analysis.Summary = "No specific error patterns detected"
 		analysis.Confidence = 0.3
 	}
+}
 
-	a.logger.Info("Analysis complete: %d errors, confidence %.2f", len(analysis.Errors), analysis.Confidence)
-	return analysis
+// MatchedLines returns the log lines the patterns matched, in log order
+func (a *Analysis) MatchedLines() []string {
+	var lines []string
+	for _, e := range a.Errors {
+		lines = append(lines, e.Message)
+	}
+	return lines
+}
+
+// stepLabel names a step within its job
+func stepLabel(job, step string) string {
+	if step == "" {
+		return job
+	}
+	return job + " › " + step
 }
 
 // generateSummary creates a human-readable summary
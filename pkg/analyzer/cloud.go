@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cloud providers recognized in authentication failures
+const (
+	ProviderAWS   = "aws"
+	ProviderAzure = "azure"
+	ProviderGCP   = "gcp"
+)
+
+// CloudContext identifies the OIDC subject a workflow run presents
+type CloudContext struct {
+	Repository   string // owner/name
+	Branch       string
+	Environments []string // Deployment environments used by the workflow
+}
+
+// Subject returns the OIDC `sub` claim GitHub issues for the run. Jobs that
+// deploy to an environment are identified by it instead of the branch.
+func (c CloudContext) Subject() string {
+	if len(c.Environments) > 0 {
+		return fmt.Sprintf("repo:%s:environment:%s", c.Repository, c.Environments[0])
+	}
+	return fmt.Sprintf("repo:%s:ref:refs/heads/%s", c.Repository, c.Branch)
+}
+
+// DetectCloudProvider guesses which cloud a failing auth step talks to from
+// the error messages and the workflow's auth actions. It returns "" when
+// nothing points to a known provider.
+func DetectCloudProvider(analysis *Analysis, workflowContent string) string {
+	var messages strings.Builder
+	for _, e := range analysis.Errors {
+		if e.Category == "cloud_auth" {
+			messages.WriteString(e.Message + "\n")
+		}
+	}
+	text := messages.String()
+
+	switch {
+	case strings.Contains(text, "sts:") || strings.Contains(text, "ExpiredToken") ||
+		strings.Contains(workflowContent, "aws-actions/configure-aws-credentials"):
+		return ProviderAWS
+	case strings.Contains(text, "AADSTS") || strings.Contains(workflowContent, "azure/login"):
+		return ProviderAzure
+	case strings.Contains(text, "iam.") || strings.Contains(workflowContent, "google-github-actions/auth"):
+		return ProviderGCP
+	}
+	return ""
+}
+
+// CloudGuidance returns targeted steps for the cloud authentication
+// failures in analysis. Only the workflow side can be fixed automatically;
+// these cover the cloud side.
+func CloudGuidance(analysis *Analysis, workflowContent string, ctx CloudContext) []string {
+	detected := make(map[string]bool)
+	for _, e := range analysis.Errors {
+		if e.Category == "cloud_auth" {
+			detected[e.Pattern] = true
+		}
+	}
+	if len(detected) == 0 {
+		return nil
+	}
+
+	provider := DetectCloudProvider(analysis, workflowContent)
+	var steps []string
+
+	if detected["Missing OIDC Token Permission"] {
+		steps = append(steps, "Add `id-token: write` to the job's permissions so it can request an OIDC token (keep `contents: read` for checkout)")
+	}
+
+	if detected["OIDC Trust Mismatch"] {
+		subject := ctx.Subject()
+		switch provider {
+		case ProviderAWS:
+			steps = append(steps,
+				fmt.Sprintf("In the IAM role's trust policy, allow token.actions.githubusercontent.com:sub to match %q (StringLike, wildcards allowed)", subject),
+				"Set token.actions.githubusercontent.com:aud to \"sts.amazonaws.com\" and check role-to-assume is the role's full ARN")
+		case ProviderAzure:
+			steps = append(steps,
+				fmt.Sprintf("Add a federated credential to the app registration with subject %q", subject),
+				"Use issuer https://token.actions.githubusercontent.com and audience api://AzureADTokenExchange")
+		case ProviderGCP:
+			steps = append(steps,
+				fmt.Sprintf("Make the workload identity provider's attribute condition accept assertion.repository == '%s'", ctx.Repository),
+				fmt.Sprintf("Grant roles/iam.workloadIdentityUser on the service account to principalSet://…/attribute.repository/%s", ctx.Repository))
+		default:
+			steps = append(steps, fmt.Sprintf("Allow the OIDC subject %q in the cloud role's trust configuration", subject))
+		}
+		if len(ctx.Environments) > 0 {
+			steps = append(steps, "Jobs with an environment present the environment in the subject, not the branch")
+		} else {
+			steps = append(steps, "Pull request runs present the subject repo:"+ctx.Repository+":pull_request instead of a branch")
+		}
+	}
+
+	if detected["Expired Cloud Credentials"] {
+		switch provider {
+		case ProviderAWS:
+			steps = append(steps, "Rotate the access key stored in AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, or replace them with role-to-assume and OIDC")
+		case ProviderAzure:
+			steps = append(steps, "Create a new client secret for the app registration and update AZURE_CREDENTIALS, or switch azure/login to client-id/tenant-id/subscription-id with a federated credential")
+		case ProviderGCP:
+			steps = append(steps, "Replace the service account key in credentials_json, or switch google-github-actions/auth to workload_identity_provider")
+		default:
+			steps = append(steps, "Rotate the expired credential secret, or switch to OIDC federation")
+		}
+	}
+
+	return steps
+}
@@ -0,0 +1,308 @@
+package analyzer
+
+import "regexp"
+
+// ecosystemPatterns are rule packs for the failures of language toolchains
+// and deployment tools. They are more specific than the general patterns,
+// so they are matched first.
+var ecosystemPatterns = [][]ErrorPattern{
+	// go build, go test and modules
+	{
+		{
+			Name:       "Go Compile Error",
+			Pattern:    regexp.MustCompile(`\.go:\d+:\d+: |# [\w./-]+ \[[\w./-]+\.test\]$`),
+			Severity:   "HIGH",
+			Suggestion: "Fix the compile error at the reported file:line:column; `go vet ./...` reproduces it locally",
+			Category:   "go",
+			Tag:        TagSyntax,
+		},
+		{
+			Name:       "Go Module Out of Sync",
+			Pattern:    regexp.MustCompile(`no required module provides package|missing go\.sum entry|go: updates to go\.mod needed|go\.mod file indicates go [\d.]+, but maximum supported version`),
+			Severity:   "HIGH",
+			Suggestion: "Run `go mod tidy` and commit go.mod and go.sum",
+			Category:   "go",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "Go Version Too Old",
+			Pattern:    regexp.MustCompile(`go\.mod requires go >= [\d.]+|requires go >= [\d.]+ \(running go [\d.]+|toolchain not available`),
+			Severity:   "HIGH",
+			Suggestion: "Install the Go the module needs: `go-version-file: go.mod` in actions/setup-go",
+			Category:   "go",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "Go Test Failure",
+			Pattern:    regexp.MustCompile(`--- FAIL: \S+|(?:^|\s)FAIL\s+\S+\s+[\d.]+s$|panic: .*\[recovered\]`),
+			Severity:   "MEDIUM",
+			Suggestion: "Reproduce with `go test -run <TestName> -v` in the failing package",
+			Category:   "go",
+			Tag:        TagTest,
+		},
+		{
+			Name:       "Go Data Race",
+			Pattern:    regexp.MustCompile(`WARNING: DATA RACE`),
+			Severity:   "HIGH",
+			Suggestion: "The race detector found unsynchronized access; the two stacks under the warning show the conflicting reads and writes",
+			Category:   "go",
+			Tag:        TagTest,
+		},
+	},
+	// cargo and rustc
+	{
+		{
+			Name:       "Rust Compile Error",
+			Pattern:    regexp.MustCompile(`error\[E\d{4}\]:|error: could not compile ` + "`" + `[\w-]+` + "`"),
+			Severity:   "HIGH",
+			Suggestion: "Fix the rustc error at the reported location; `rustc --explain E<code>` describes it",
+			Category:   "rust",
+			Tag:        TagSyntax,
+		},
+		{
+			Name:       "Cargo Dependency Resolution",
+			Pattern:    regexp.MustCompile(`failed to select a version for|no matching package named|failed to load source for dependency|the lock file .* needs to be updated but --locked was passed`),
+			Severity:   "HIGH",
+			Suggestion: "Align the versions in Cargo.toml, then run `cargo update -p <crate>` and commit Cargo.lock",
+			Category:   "rust",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "Rust Toolchain Too Old",
+			Pattern:    regexp.MustCompile(`requires rustc [\d.]+ or newer|is not supported by the following package|feature .edition\d+. is required`),
+			Severity:   "HIGH",
+			Suggestion: "Install a newer toolchain with dtolnay/rust-toolchain, or pin one in rust-toolchain.toml",
+			Category:   "rust",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "Cargo Test Failure",
+			Pattern:    regexp.MustCompile(`test result: FAILED|error: test failed, to rerun pass`),
+			Severity:   "MEDIUM",
+			Suggestion: "The failing tests are listed under `failures:`; rerun one with `cargo test <name> -- --nocapture`",
+			Category:   "rust",
+			Tag:        TagTest,
+		},
+	},
+	// Java with Maven or Gradle
+	{
+		{
+			Name:       "Java Version Mismatch",
+			Pattern:    regexp.MustCompile(`(?:invalid|unsupported) (?:target|source) release: \d+|UnsupportedClassVersionError|compiled by a more recent version of the Java Runtime|class file has wrong version`),
+			Severity:   "HIGH",
+			Suggestion: "Set java-version in actions/setup-java to the release the build targets",
+			Category:   "jvm",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "Java Compile Error",
+			Pattern:    regexp.MustCompile(`\[ERROR\] .*\.java:\[\d+,\d+\]|\.java:\d+: error: |\.kt:\d+:\d+ `),
+			Severity:   "HIGH",
+			Suggestion: "Fix the compile error at the reported file and line",
+			Category:   "jvm",
+			Tag:        TagSyntax,
+		},
+		{
+			Name:       "Maven Dependency Resolution",
+			Pattern:    regexp.MustCompile(`Could not resolve dependencies for project|Could not transfer artifact|Failed to read artifact descriptor|Non-resolvable parent POM`),
+			Severity:   "HIGH",
+			Suggestion: "Check the artifact version and repository; private repositories need credentials in settings.xml (server-id of actions/setup-java)",
+			Category:   "jvm",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "Maven Goal Failed",
+			Pattern:    regexp.MustCompile(`\[ERROR\] Failed to execute goal`),
+			Severity:   "HIGH",
+			Suggestion: "The named plugin goal failed; the [ERROR] lines before it say why, and `mvn -e` shows the stack trace",
+			Category:   "jvm",
+		},
+		{
+			Name:       "Gradle Dependency Resolution",
+			Pattern:    regexp.MustCompile(`Could not resolve all (?:files|dependencies|artifacts) for configuration|Could not find [\w.-]+:[\w.-]+:[\w.+-]+\.`),
+			Severity:   "HIGH",
+			Suggestion: "Check the dependency coordinates and the repositories block; private repositories need credentials",
+			Category:   "jvm",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "Gradle Build Failed",
+			Pattern:    regexp.MustCompile(`FAILURE: Build failed with an exception|Execution failed for task '[^']+'`),
+			Severity:   "HIGH",
+			Suggestion: "Rerun the failing task with --stacktrace; the \"What went wrong\" section names the cause",
+			Category:   "jvm",
+		},
+		{
+			Name:       "Gradle Wrapper Not Executable",
+			Pattern:    regexp.MustCompile(`\./gradlew: Permission denied|Gradle Wrapper Validation failed|Invalid Gradle wrapper`),
+			Severity:   "HIGH",
+			Suggestion: "Commit gradlew as executable with `git update-index --chmod=+x gradlew`, and regenerate a wrapper that fails validation",
+			Category:   "jvm",
+			Tag:        TagPermission,
+		},
+		{
+			Name:       "JVM Out of Memory",
+			Pattern:    regexp.MustCompile(`java\.lang\.OutOfMemoryError|GC overhead limit exceeded|Gradle build daemon disappeared unexpectedly`),
+			Severity:   "HIGH",
+			Suggestion: "Raise the heap with org.gradle.jvmargs=-Xmx in gradle.properties or MAVEN_OPTS=-Xmx",
+			Category:   "jvm",
+			Tag:        TagResource,
+		},
+	},
+	// dotnet and NuGet
+	{
+		{
+			Name:       ".NET SDK Missing",
+			Pattern:    regexp.MustCompile(`The current \.NET SDK does not support targeting|A compatible \.NET SDK was not found|error NETSDK1045`),
+			Severity:   "HIGH",
+			Suggestion: "Install the SDK the project targets with actions/setup-dotnet (dotnet-version or global-json-file)",
+			Category:   "dotnet",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "NuGet Restore Failed",
+			Pattern:    regexp.MustCompile(`error NU\d{4}:|Unable to find package [\w.]+`),
+			Severity:   "HIGH",
+			Suggestion: "Check the package sources in nuget.config; private feeds need source-url and NUGET_AUTH_TOKEN in actions/setup-dotnet",
+			Category:   "dotnet",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "C# Compile Error",
+			Pattern:    regexp.MustCompile(`error CS\d{4}:|error MSB\d{4}:`),
+			Severity:   "HIGH",
+			Suggestion: "Fix the compiler or MSBuild error at the reported file and line",
+			Category:   "dotnet",
+			Tag:        TagSyntax,
+		},
+		{
+			Name:       ".NET Test Failure",
+			Pattern:    regexp.MustCompile(`Failed!\s+-\s+Failed:\s+[1-9]\d*`),
+			Severity:   "MEDIUM",
+			Suggestion: "Reproduce with `dotnet test --filter <TestName>`",
+			Category:   "dotnet",
+			Tag:        TagTest,
+		},
+	},
+	// Terraform plan and apply
+	{
+		{
+			Name:       "Terraform State Locked",
+			Pattern:    regexp.MustCompile(`Error acquiring the state lock|Error: Error locking state`),
+			Severity:   "HIGH",
+			Suggestion: "Another run holds the state lock; serialize plans and applies with a concurrency: group, and `terraform force-unlock` a stale lock",
+			Category:   "terraform",
+			Tag:        TagResource,
+		},
+		{
+			Name:       "Terraform Init Required",
+			Pattern:    regexp.MustCompile(`Error: (?:Backend initialization required|Inconsistent dependency lock file|Failed to (?:query available provider packages|install provider)|Module not installed)|Error: Failed to get existing workspaces`),
+			Severity:   "HIGH",
+			Suggestion: "Run `terraform init` (with -upgrade after changing providers) and commit .terraform.lock.hcl; check the backend credentials",
+			Category:   "terraform",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "Terraform Configuration Error",
+			Pattern:    regexp.MustCompile(`Error: (?:Unsupported argument|Missing required argument|Reference to undeclared|Invalid reference|Unsupported attribute|Invalid value for|Unsupported block type|Argument or block definition required)`),
+			Severity:   "HIGH",
+			Suggestion: "Run `terraform validate`; the error names the file and line of the configuration",
+			Category:   "terraform",
+			Tag:        TagSyntax,
+		},
+		{
+			Name:       "Terraform Format Check",
+			Pattern:    regexp.MustCompile(`Terraform exited with code 3`),
+			Severity:   "MEDIUM",
+			Suggestion: "Run `terraform fmt -recursive` and commit the result",
+			Category:   "terraform",
+			Tag:        TagSyntax,
+		},
+	},
+	// kubectl and Helm
+	{
+		{
+			Name:       "Kubernetes Cluster Unreachable",
+			Pattern:    regexp.MustCompile(`The connection to the server .* was refused|Unable to connect to the server|error: You must be logged in to the server|Kubernetes cluster unreachable`),
+			Severity:   "HIGH",
+			Suggestion: "The job has no working kubeconfig; set up cluster credentials (azure/k8s-set-context, aws eks update-kubeconfig, google-github-actions/get-gke-credentials) before kubectl or helm",
+			Category:   "kubernetes",
+			Tag:        TagExternalService,
+		},
+		{
+			Name:       "Kubernetes RBAC Forbidden",
+			Pattern:    regexp.MustCompile(`Error from server \(Forbidden\)|is forbidden: User "[^"]*" cannot`),
+			Severity:   "HIGH",
+			Suggestion: "Grant the deploying identity the RBAC role it needs in the namespace",
+			Category:   "kubernetes",
+			Tag:        TagPermission,
+		},
+		{
+			Name:       "Kubernetes Manifest Invalid",
+			Pattern:    regexp.MustCompile(`error validating data:|Error from server \(BadRequest\)|no matches for kind "[^"]+" in version|error: unable to recognize`),
+			Severity:   "HIGH",
+			Suggestion: "Fix the manifest; check its apiVersion against the cluster with `kubectl api-resources`",
+			Category:   "kubernetes",
+			Tag:        TagSyntax,
+		},
+		{
+			Name:       "Kubernetes Rollout Failed",
+			Pattern:    regexp.MustCompile(`exceeded its progress deadline|timed out waiting for the condition|ErrImagePull|ImagePullBackOff|CrashLoopBackOff`),
+			Severity:   "HIGH",
+			Suggestion: "The pods never became ready; `kubectl describe pod` and the namespace events show whether the image, probes or resources are at fault",
+			Category:   "kubernetes",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "Helm Release Failed",
+			Pattern:    regexp.MustCompile(`Error: (?:UPGRADE|INSTALLATION) FAILED|another operation \(install/upgrade/rollback\) is in progress|has no deployed releases`),
+			Severity:   "HIGH",
+			Suggestion: "Inspect the release with `helm history`; a release stuck pending needs `helm rollback` before the next upgrade",
+			Category:   "kubernetes",
+			Tag:        TagInfra,
+		},
+	},
+	// pip and Poetry
+	{
+		{
+			Name:       "pip Dependency Conflict",
+			Pattern:    regexp.MustCompile(`ResolutionImpossible|because these package versions have conflicting dependencies`),
+			Severity:   "HIGH",
+			Suggestion: "Relax or align the conflicting version pins; the lines above list which requirements clash",
+			Category:   "python",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "pip Package Not Found",
+			Pattern:    regexp.MustCompile(`No matching distribution found for|Could not find a version that satisfies the requirement`),
+			Severity:   "HIGH",
+			Suggestion: "The pinned version has no distribution for this Python or platform; check the pin and python-version in actions/setup-python",
+			Category:   "python",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "Python Version Unsupported",
+			Pattern:    regexp.MustCompile(`requires a different Python: [\d.]+ not in|The current project's supported Python range .* is not compatible`),
+			Severity:   "HIGH",
+			Suggestion: "Set python-version in actions/setup-python to a version the project supports",
+			Category:   "python",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "Poetry Lock Outdated",
+			Pattern:    regexp.MustCompile(`pyproject\.toml changed significantly since poetry\.lock was last generated|poetry\.lock is not consistent with pyproject\.toml`),
+			Severity:   "HIGH",
+			Suggestion: "Run `poetry lock` and commit poetry.lock",
+			Category:   "python",
+			Tag:        TagDependency,
+		},
+		{
+			Name:       "Poetry Solver Failed",
+			Pattern:    regexp.MustCompile(`SolverProblemError|version solving failed`),
+			Severity:   "HIGH",
+			Suggestion: "Align the version constraints in pyproject.toml; the solver output explains which ones exclude each other",
+			Category:   "python",
+			Tag:        TagDependency,
+		},
+	},
+}
@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+)
+
+// RunSteps are the steps of a completed run that failed and passed, named
+// like "job › step"
+type RunSteps struct {
+	RunID     int64
+	HeadSHA   string
+	CreatedAt time.Time
+	Failed    []string
+	Passed    []string
+}
+
+// FlakyStep is a failed step that fails intermittently
+type FlakyStep struct {
+	Step     string
+	Failures int // Runs it failed in, the analyzed one included
+	Passes   int // Runs it passed in
+}
+
+// StepName names a step of a job like the steps of RunSteps
+func StepName(job, step string) string {
+	return stepLabel(job, step)
+}
+
+// DetectFlaky returns the failed steps of a run that fail intermittently in
+// the recent runs of its workflow: a step is flaky when it both failed and
+// passed on one commit, or failed more than once and passed in between. A
+// step that has failed since some run on is a regression, not flaky.
+func DetectFlaky(run RunSteps, history []RunSteps) []FlakyStep {
+	runs := append([]RunSteps{run}, history...)
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+
+	var flaky []FlakyStep
+	for _, step := range run.Failed {
+		result := FlakyStep{Step: step}
+		failedAt := make(map[string]bool)
+		passedAt := make(map[string]bool)
+		failed, recovered, intermittent := false, false, false
+		for _, r := range runs {
+			switch {
+			case contains(r.Failed, step):
+				result.Failures++
+				failedAt[r.HeadSHA] = true
+				intermittent = intermittent || recovered
+				failed = true
+			case contains(r.Passed, step):
+				result.Passes++
+				passedAt[r.HeadSHA] = true
+				recovered = recovered || failed
+			}
+		}
+		sameCommit := false
+		for sha := range failedAt {
+			sameCommit = sameCommit || passedAt[sha]
+		}
+		if sameCommit || intermittent {
+			flaky = append(flaky, result)
+		}
+	}
+	return flaky
+}
+
+// contains reports whether a list holds a string
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
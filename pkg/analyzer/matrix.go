@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MatrixShard is a job of a run that is one combination of a matrix
+type MatrixShard struct {
+	Job        string   // Job name without the combination, e.g. "build"
+	Values     []string // Values of the combination, e.g. [ubuntu-latest 18]
+	Conclusion string   // success, failure, cancelled, ...
+	Signature  string   // How it failed, e.g. the failed step and matched patterns
+}
+
+// failed reports whether the combination failed, as opposed to passing or
+// being cancelled, e.g. by fail-fast
+func (s MatrixShard) failed() bool {
+	return s.Conclusion == "failure" || s.Conclusion == "timed_out"
+}
+
+// matrixJobRe matches the name GitHub gives a job of a matrix
+var matrixJobRe = regexp.MustCompile(`^(.+?) \((.+)\)$`)
+
+// ParseMatrixJob splits the name of a matrix job, "build (ubuntu-latest,
+// 18)", into the job and the values of its combination
+func ParseMatrixJob(name string) (string, []string, bool) {
+	m := matrixJobRe.FindStringSubmatch(name)
+	if m == nil {
+		return "", nil, false
+	}
+	values := strings.Split(m[2], ", ")
+	return m[1], values, true
+}
+
+// MatrixGroup tells how the combinations of a matrix job fared in a run
+type MatrixGroup struct {
+	Job       string     `json:"job"`
+	Axes      []string   `json:"axes,omitempty"` // Matrix keys, when the workflow names as many as there are values
+	Shards    int        `json:"shards"`
+	Passed    int        `json:"passed"`
+	Failed    [][]string `json:"failed"`            // Values of the failed combinations
+	Identical bool       `json:"identical"`         // Every failed combination failed the same way
+	Culprit   string     `json:"culprit,omitempty"` // Axis values only the failed combinations have, e.g. "node-version=16"
+}
+
+// String summarizes the group in one line, e.g. "build: 2 of 6 combinations
+// fail, only where node-version=16, the same way"
+func (g MatrixGroup) String() string {
+	var b strings.Builder
+	if len(g.Failed) == g.Shards {
+		fmt.Fprintf(&b, "%s: all %d combinations fail", g.Job, g.Shards)
+	} else {
+		fmt.Fprintf(&b, "%s: %d of %d combinations fail", g.Job, len(g.Failed), g.Shards)
+		switch {
+		case g.Culprit != "":
+			fmt.Fprintf(&b, ", only where %s", g.Culprit)
+		case g.Passed == 0:
+			b.WriteString(", the others were cancelled")
+		default:
+			b.WriteString(", not along a single axis")
+		}
+	}
+	switch {
+	case len(g.Failed) == 1:
+	case g.Identical:
+		b.WriteString(", the same way")
+	default:
+		b.WriteString(", in different ways")
+	}
+	combinations := make([]string, 0, len(g.Failed))
+	for _, values := range g.Failed {
+		combinations = append(combinations, g.combination(values))
+	}
+	fmt.Fprintf(&b, " (failed: %s)", strings.Join(combinations, "; "))
+	return b.String()
+}
+
+// combination renders the values of a combination, with their axes if known
+func (g MatrixGroup) combination(values []string) string {
+	if len(g.Axes) != len(values) {
+		return strings.Join(values, ", ")
+	}
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = g.Axes[i] + "=" + v
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// GroupMatrix groups the combinations of the matrix jobs of a run by job,
+// keeping the jobs with more than one combination and at least one failure.
+// axes gives the matrix keys of each job, as workflow.MatrixAxes returns
+// them. Cancelled combinations count neither as failed nor as passed.
+func GroupMatrix(shards []MatrixShard, axes map[string][]string) []MatrixGroup {
+	byJob := make(map[string][]MatrixShard)
+	var order []string
+	for _, shard := range shards {
+		if _, ok := byJob[shard.Job]; !ok {
+			order = append(order, shard.Job)
+		}
+		byJob[shard.Job] = append(byJob[shard.Job], shard)
+	}
+
+	var groups []MatrixGroup
+	for _, job := range order {
+		jobShards := byJob[job]
+		group := MatrixGroup{Job: job, Shards: len(jobShards), Identical: true}
+		if keys := axes[job]; len(keys) == len(jobShards[0].Values) {
+			group.Axes = keys
+		}
+		var passed [][]string
+		signature := ""
+		for _, shard := range jobShards {
+			switch {
+			case shard.failed():
+				if len(group.Failed) > 0 && shard.Signature != signature {
+					group.Identical = false
+				}
+				signature = shard.Signature
+				group.Failed = append(group.Failed, shard.Values)
+			case shard.Conclusion == "success":
+				passed = append(passed, shard.Values)
+			}
+		}
+		if group.Shards < 2 || len(group.Failed) == 0 {
+			continue
+		}
+		group.Passed = len(passed)
+		group.Culprit = group.culprit(passed)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// culprit finds the axes whose values in the failed combinations never
+// occur in a passing one, e.g. "node-version=16" or "os=windows-latest or
+// macos-latest"
+func (g MatrixGroup) culprit(passed [][]string) string {
+	if len(passed) == 0 {
+		return ""
+	}
+	var culprits []string
+	for axis := range g.Failed[0] {
+		failing := make(map[string]bool)
+		for _, values := range g.Failed {
+			if axis < len(values) {
+				failing[values[axis]] = true
+			}
+		}
+		explains := true
+		for _, values := range passed {
+			if axis < len(values) && failing[values[axis]] {
+				explains = false
+				break
+			}
+		}
+		if !explains {
+			continue
+		}
+		name := fmt.Sprintf("axis %d", axis+1)
+		if axis < len(g.Axes) {
+			name = g.Axes[axis]
+		}
+		culprits = append(culprits, name+"="+strings.Join(sortedSet(failing), " or "))
+	}
+	return strings.Join(culprits, ", or where ")
+}
+
+func sortedSet(set map[string]bool) []string {
+	items := make([]string, 0, len(set))
+	for item := range set {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
@@ -0,0 +1,128 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities lists the severities of error patterns, most severe first
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+
+// customCategory is the category of user patterns that name none
+const customCategory = "custom"
+
+// patternFile is the on-disk format of user-defined error patterns
+type patternFile struct {
+	Patterns []patternEntry `yaml:"patterns"`
+}
+
+// patternEntry is one error pattern of a pattern file
+type patternEntry struct {
+	Name       string `yaml:"name"`
+	Regex      string `yaml:"regex"`
+	Severity   string `yaml:"severity"` // One of Severities; MEDIUM if unset
+	Suggestion string `yaml:"suggestion"`
+	Category   string `yaml:"category"` // custom if unset
+	Tag        string `yaml:"tag"`      // One of Tags; unclassified if unset
+}
+
+// LoadPatternsFile adds the error patterns defined in a YAML file. A
+// missing file is not an error.
+func (a *Analyzer) LoadPatternsFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, errors.FilesystemError("load_patterns", path, err)
+	}
+	return a.LoadPatterns(data, path)
+}
+
+// LoadPatterns adds the error patterns defined in YAML; path is only used
+// in errors. Nothing is added unless every pattern is valid. A pattern named
+// like a built-in or an earlier user pattern replaces it.
+func (a *Analyzer) LoadPatterns(data []byte, path string) (int, error) {
+	var file patternFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && err != io.EOF {
+		return 0, errors.ValidationError("load_patterns", fmt.Sprintf("invalid pattern file: %v", err)).WithPath(path)
+	}
+
+	var patterns []ErrorPattern
+	seen := make(map[string]bool)
+	for i, entry := range file.Patterns {
+		invalid := func(msg string) error {
+			return errors.ValidationError("load_patterns", fmt.Sprintf("pattern %d (%s): %s", i+1, entry.Name, msg)).WithPath(path)
+		}
+		if entry.Name == "" || entry.Regex == "" {
+			return 0, invalid("a pattern needs a name and a regex")
+		}
+		if seen[entry.Name] {
+			return 0, invalid("duplicate name")
+		}
+		seen[entry.Name] = true
+		re, err := regexp.Compile(entry.Regex)
+		if err != nil {
+			return 0, invalid(fmt.Sprintf("invalid regex: %v", err))
+		}
+		severity := strings.ToUpper(entry.Severity)
+		if severity == "" {
+			severity = "MEDIUM"
+		}
+		if !validSeverity(severity) {
+			return 0, invalid(fmt.Sprintf("unknown severity %q (expected %s)", entry.Severity, strings.Join(Severities, ", ")))
+		}
+		tag := strings.ToLower(entry.Tag)
+		if tag != "" && !ValidTag(tag) {
+			return 0, invalid(fmt.Sprintf("unknown tag %q (expected %s)", entry.Tag, strings.Join(Tags, ", ")))
+		}
+		category := entry.Category
+		if category == "" {
+			category = customCategory
+		}
+		patterns = append(patterns, ErrorPattern{
+			Name:       entry.Name,
+			Pattern:    re,
+			Severity:   severity,
+			Suggestion: entry.Suggestion,
+			Category:   category,
+			Tag:        tag,
+		})
+	}
+
+	for _, pattern := range patterns {
+		a.custom = append(withoutPattern(a.custom, pattern.Name), pattern)
+	}
+	return len(patterns), nil
+}
+
+// validSeverity reports whether a severity is one of Severities
+func validSeverity(severity string) bool {
+	for _, s := range Severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutPattern returns the patterns except the one with a name
+func withoutPattern(patterns []ErrorPattern, name string) []ErrorPattern {
+	kept := make([]ErrorPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern.Name != name {
+			kept = append(kept, pattern)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,139 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Runner operating systems, as reported by the runner.os context
+const (
+	OSLinux   = "Linux"
+	OSWindows = "Windows"
+	OSMacOS   = "macOS"
+)
+
+var (
+	// runsOnRe matches the job headers written by the GitHub client
+	runsOnRe = regexp.MustCompile(`(?m)^=== Job: .*runs-on: ([^)]*)\) ===`)
+	// runnerImageRe matches the "Runner Image" section of hosted runner logs
+	runnerImageRe = regexp.MustCompile(`(?i)\bImage: (ubuntu|windows|macos)-`)
+)
+
+// platformPatterns are rule packs for failures specific to one runner OS.
+// They are only matched when the logs come from that OS, or when the OS
+// cannot be determined.
+var platformPatterns = map[string][]ErrorPattern{
+	OSWindows: {
+		{
+			Name:       "Bash Syntax in PowerShell",
+			Pattern:    regexp.MustCompile(`(?i)The token '&&' is not a valid statement separator|Missing expression after unary operator '--'|ParserError:.*(?:Unexpected token|Missing)|is not recognized as (?:the )?name of a cmdlet|is not recognized as an internal or external command`),
+			Severity:   "HIGH",
+			Suggestion: "Windows runners run steps with pwsh; set `shell: bash` on the step or job defaults, or rewrite the command for PowerShell",
+			Category:   "shell",
+			Tag:        TagSyntax,
+		},
+		{
+			Name:       "Windows Path Separator",
+			Pattern:    regexp.MustCompile(`(?i)The system cannot find the path specified|Cannot find path '[A-Z]:\\|[A-Z]:\\[^\s:]*: No such file or directory|ENOENT: no such file or directory, \w+ '[A-Z]:\\`),
+			Severity:   "HIGH",
+			Suggestion: "Build paths with forward slashes or path.join instead of hardcoding separators; bash on Windows expects /c/... paths",
+			Category:   "paths",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "CRLF Line Endings",
+			Pattern:    regexp.MustCompile(`\$'\\r': command not found|/bin/(?:bash|sh)\^M: bad interpreter|\r: No such file or directory`),
+			Severity:   "HIGH",
+			Suggestion: "Scripts were checked out with CRLF endings; add `* text=auto eol=lf` to .gitattributes or run `git config --global core.autocrlf false` before checkout",
+			Category:   "line_endings",
+			Tag:        TagSyntax,
+		},
+	},
+	OSMacOS: {
+		{
+			Name:       "Xcode Version Unavailable",
+			Pattern:    regexp.MustCompile(`(?i)xcode-select: error|Could not find Xcode version|Xcode \d+(?:\.\d+)* (?:is )?not (?:installed|available|found)|Unable to find (?:a )?(?:destination|Xcode)|SDK "\w+" cannot be located|requires Xcode \d+`),
+			Severity:   "HIGH",
+			Suggestion: "The runner image does not ship this Xcode; pin a macos-NN image that includes it and select it with `sudo xcode-select -s /Applications/Xcode_<version>.app`",
+			Category:   "xcode",
+			Tag:        TagInfra,
+		},
+		{
+			Name:       "BSD sed In-Place Edit",
+			Pattern:    regexp.MustCompile(`sed: 1: "[^"]*": (?:invalid command code|extra characters at the end|unterminated substitute)`),
+			Severity:   "MEDIUM",
+			Suggestion: "macOS ships BSD sed; use `sed -i ''` or install gnu-sed and call gsed",
+			Category:   "shell",
+			Tag:        TagInfra,
+		},
+	},
+	OSLinux: {
+		{
+			Name:       "Case-Sensitive Path Mismatch",
+			Pattern:    regexp.MustCompile(`(?i)differs from (?:already included )?file name .* only in casing|Can't resolve '\.{1,2}/[^']*[A-Z][^']*'|Cannot find module '\.{1,2}/[^']*[A-Z][^']*'`),
+			Severity:   "HIGH",
+			Suggestion: "Linux runners have a case-sensitive filesystem; make the path match the file name's case exactly (rename with `git mv`)",
+			Category:   "case_sensitivity",
+			Tag:        TagInfra,
+		},
+	},
+}
+
+// DetectRunnerOS returns the operating systems of the runners that
+// produced the logs, using the job headers and the runner image section
+func DetectRunnerOS(logs string) []string {
+	found := make(map[string]bool)
+	for _, match := range runsOnRe.FindAllStringSubmatch(logs, -1) {
+		if system := labelOS(match[1]); system != "" {
+			found[system] = true
+		}
+	}
+	for _, match := range runnerImageRe.FindAllStringSubmatch(logs, -1) {
+		found[labelOS(match[1])] = true
+	}
+
+	var systems []string
+	for _, system := range []string{OSLinux, OSWindows, OSMacOS} {
+		if found[system] {
+			systems = append(systems, system)
+		}
+	}
+	return systems
+}
+
+// labelOS maps runner labels to an operating system
+func labelOS(labels string) string {
+	labels = strings.ToLower(labels)
+	switch {
+	case strings.Contains(labels, "windows"):
+		return OSWindows
+	case strings.Contains(labels, "macos"):
+		return OSMacOS
+	case strings.Contains(labels, "ubuntu"), strings.Contains(labels, "linux"):
+		return OSLinux
+	}
+	return ""
+}
+
+// activePatterns returns the user patterns, then the ecosystem and general
+// patterns plus the rule packs for the given runner systems, or every rule
+// pack when none are known. A user pattern replaces the built-in of the same
+// name.
+func (a *Analyzer) activePatterns(systems []string) []ErrorPattern {
+	var builtin []ErrorPattern
+	for _, pack := range ecosystemPatterns {
+		builtin = append(builtin, pack...)
+	}
+	builtin = append(builtin, errorPatterns...)
+	if len(systems) == 0 {
+		systems = []string{OSLinux, OSWindows, OSMacOS}
+	}
+	for _, system := range systems {
+		builtin = append(builtin, platformPatterns[system]...)
+	}
+	patterns := append([]ErrorPattern{}, a.custom...)
+	for _, pattern := range a.custom {
+		builtin = withoutPattern(builtin, pattern.Name)
+	}
+	return append(patterns, builtin...)
+}
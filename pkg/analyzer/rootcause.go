@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Root causes a failure is classified with, by where it has to be fixed.
+// Only a failure of the workflow configuration is fixed in the workflow.
+const (
+	RootCauseWorkflow       = "WORKFLOW_CONFIG"  // The workflow, its actions or its run: scripts
+	RootCauseApplication    = "APPLICATION_CODE" // Source code, tests or their build files
+	RootCauseInfrastructure = "INFRASTRUCTURE"   // Services, registries or runners that are down or unreachable
+)
+
+// RootCauses lists the root causes
+var RootCauses = []string{RootCauseWorkflow, RootCauseApplication, RootCauseInfrastructure}
+
+// RootCauseOf returns the root cause a failure tag points to, or "" for
+// tags whose failures are fixed in the workflow as often as elsewhere
+func RootCauseOf(tag string) string {
+	switch tag {
+	case TagTest:
+		return RootCauseApplication
+	case TagExternalService:
+		return RootCauseInfrastructure
+	}
+	return ""
+}
+
+// implicatedPatterns find the tests and source files a log line points to;
+// the first group is the test or path, the second the line when known
+var implicatedPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`--- FAIL: (\S+)`),                             // go test
+	regexp.MustCompile(`FAILED (\S+\.py::\S+)`),                       // pytest
+	regexp.MustCompile(`FAIL\s+(\S+\.(?:test|spec)\.[cm]?[jt]sx?)\b`), // jest, vitest
+	regexp.MustCompile(`File "([^"]+\.py)", line (\d+)`),              // Python tracebacks
+	regexp.MustCompile(`(?:^|[\s('"])((?:[A-Za-z]:[/\\]|/)?(?:[\w.@-]+[/\\])*[\w.@-]+\.(?:go|py|js|jsx|mjs|cjs|ts|tsx|rb|rs|java|kt|cs|c|cc|cpp|h|hpp|php|swift|scala)):(\d+)`),
+}
+
+// buildFilePattern finds the dependency manifests and Dockerfiles a log
+// line names, by path
+var buildFilePattern = regexp.MustCompile(`(?:^|[\s('"])((?:[A-Za-z]:[/\\]|/)?(?:[\w.@-]+[/\\])*(?:package\.json|requirements[\w.-]*\.txt|pyproject\.toml|setup\.py|Pipfile|go\.mod|Cargo\.toml|Gemfile|composer\.json|pom\.xml|build\.gradle(?:\.kts)?|(?:[\w.-]+\.)?Dockerfile(?:\.[\w-]+)?))(?:$|[\s:.)'",])`)
+
+// volumeName is the drive letter of a Windows path
+var volumeName = regexp.MustCompile(`^[A-Za-z]:`)
+
+// workspacePrefix is the checkout directory of a hosted or container runner
+var workspacePrefix = regexp.MustCompile(`^(?:/home/runner/work|/__w|[A-Za-z]:/a)/[^/]+/[^/]+/`)
+
+// thirdParty marks paths of dependencies and toolchains rather than the
+// repository's own code
+var thirdParty = []string{"node_modules/", "site-packages/", "dist-packages/", "vendor/", "/go/pkg/mod/", "/hostedtoolcache/", "/usr/"}
+
+// Implicated returns the tests and source files of the repository the logs
+// point to, e.g. "TestParse" or "src/app.ts:12", in the order they first
+// appear, at most limit. Paths are made relative to the checkout, and
+// dependencies, toolchains and workflow files are left out.
+func Implicated(logs string, limit int) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(logs, "\n") {
+		for _, pattern := range implicatedPatterns {
+			for _, match := range pattern.FindAllStringSubmatch(line, -1) {
+				name, ok := repositoryPath(match[1])
+				if !ok || seen[name] {
+					continue
+				}
+				seen[name] = true
+				if len(match) > 2 && match[2] != "" {
+					name += ":" + match[2]
+				}
+				found = append(found, name)
+				if len(found) == limit {
+					return found
+				}
+			}
+		}
+	}
+	return found
+}
+
+// BuildFiles returns the dependency manifests and Dockerfiles of the
+// repository the logs name, e.g. "package.json" or "docker/Dockerfile", in
+// the order they first appear, at most limit. Paths are made relative to the
+// checkout, and those of dependencies and toolchains are left out.
+func BuildFiles(logs string, limit int) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(logs, "\n") {
+		for _, match := range buildFilePattern.FindAllStringSubmatch(line, -1) {
+			name, ok := repositoryPath(match[1])
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			found = append(found, name)
+			if len(found) == limit {
+				return found
+			}
+		}
+	}
+	return found
+}
+
+// repositoryPath makes a path of the logs relative to the checkout, and
+// reports whether it is a file of the repository's own code. Absolute
+// paths, drive letters and .. segments that reach out of the checkout are
+// not.
+func repositoryPath(path string) (string, bool) {
+	path = workspacePrefix.ReplaceAllString(strings.ReplaceAll(path, `\`, "/"), "")
+	for _, marker := range thirdParty {
+		if strings.Contains(path, marker) {
+			return "", false
+		}
+	}
+	if strings.HasPrefix(path, "/") || volumeName.MatchString(path) || strings.HasPrefix(path, ".github/") {
+		return "", false
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." {
+			return "", false
+		}
+	}
+	return strings.TrimPrefix(path, "./"), true
+}
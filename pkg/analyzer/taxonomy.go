@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Failure tags of the taxonomy every failure is classified with. Pattern
+// categories are fine-grained ("go", "lfs"); tags group them across
+// ecosystems so failures can be counted, filtered and ignored by kind.
+const (
+	TagInfra           = "infra"            // Runner, toolchain or CI environment
+	TagDependency      = "dependency"       // Packages or modules that cannot be resolved or installed
+	TagSyntax          = "syntax"           // Workflow, script or source code that does not parse or compile
+	TagTest            = "test"             // Failing tests
+	TagPermission      = "permission"       // Tokens, credentials, RBAC and file permissions
+	TagResource        = "resource"         // Memory, disk, quotas, locks and time limits
+	TagFlaky           = "flaky"            // Steps that fail intermittently
+	TagExternalService = "external-service" // Registries, clusters and other services that are down or unreachable
+)
+
+// Tags lists the failure tags
+var Tags = []string{TagInfra, TagDependency, TagSyntax, TagTest, TagPermission, TagResource, TagFlaky, TagExternalService}
+
+// ValidTag reports whether a tag is one of Tags
+func ValidTag(tag string) bool {
+	for _, t := range Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTags splits a comma-separated list of tags, rejecting unknown ones
+func ParseTags(list string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if !ValidTag(tag) {
+			return nil, fmt.Errorf("unknown failure category %q (expected %s)", tag, strings.Join(Tags, ", "))
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// tagKeywords are phrases that point to each tag in a written explanation
+// of a failure, such as the AI's
+var tagKeywords = map[string][]string{
+	TagInfra:           {"runner image", "toolchain", "command not found", "not installed", "runs-on", "self-hosted"},
+	TagDependency:      {"dependenc", "package", "lockfile", "lock file", "module not found", "could not resolve", "version conflict", "go.sum", "requirements.txt"},
+	TagSyntax:          {"syntax", "indentation", "invalid yaml", "invalid workflow", "parse error", "malformed", "unexpected token", "compil"},
+	TagTest:            {"test fail", "failing test", "tests fail", "assertion", "unit test", "test suite"},
+	TagPermission:      {"permission", "unauthorized", "forbidden", "credential", "access denied", "oidc", "401", "403"},
+	TagResource:        {"out of memory", "disk space", "no space left", "rate limit", "quota", "timed out", "timeout"},
+	TagFlaky:           {"flaky", "intermittent", "transient"},
+	TagExternalService: {"registry", "unreachable", "service unavailable", "connection refused", "dns", "outage", "502", "503"},
+}
+
+// MentionedTags returns the tags an explanation points to, the most
+// mentioned first. Ties keep the order of Tags.
+func MentionedTags(explanation string) []string {
+	text := strings.ToLower(explanation)
+	hits := make(map[string]int)
+	var tags []string
+	for _, tag := range Tags {
+		for _, keyword := range tagKeywords[tag] {
+			hits[tag] += strings.Count(text, keyword)
+		}
+		if hits[tag] > 0 {
+			tags = append(tags, tag)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return hits[tags[i]] > hits[tags[j]] })
+	return tags
+}
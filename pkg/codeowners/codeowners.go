@@ -0,0 +1,122 @@
+// Package codeowners reads a repository's CODEOWNERS file to find who owns
+// a path
+package codeowners
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+)
+
+// locations are where GitHub looks for CODEOWNERS, in order
+var locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// rule assigns owners to the paths matching a pattern
+type rule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// File is a parsed CODEOWNERS file
+type File struct {
+	Path  string
+	rules []rule
+}
+
+// Load reads the CODEOWNERS file of the repository at root. It returns nil
+// if the repository has none.
+func Load(root string) (*File, error) {
+	for _, location := range locations {
+		path := filepath.Join(root, filepath.FromSlash(location))
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.FilesystemError("load_codeowners", path, err)
+		}
+		defer f.Close()
+
+		file := &File{Path: location}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			r := rule{pattern: compile(fields[0])}
+			for _, owner := range fields[1:] {
+				if strings.HasPrefix(owner, "#") {
+					break
+				}
+				// Owners given by email cannot be requested as reviewers
+				if strings.HasPrefix(owner, "@") {
+					r.owners = append(r.owners, owner)
+				}
+			}
+			file.rules = append(file.rules, r)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, errors.FilesystemError("load_codeowners", path, err)
+		}
+		return file, nil
+	}
+	return nil, nil
+}
+
+// Owners returns the owners of a path relative to the repository root. As
+// on GitHub, the last matching pattern wins, and it may have no owners.
+func (f *File) Owners(path string) []string {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "./")
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		if f.rules[i].pattern.MatchString(path) {
+			return f.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// compile turns a gitignore-style CODEOWNERS pattern into a regular
+// expression matching the paths it covers
+func compile(pattern string) *regexp.Regexp {
+	// Patterns with a slash before their end are relative to the root;
+	// others match at any depth
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	// A pattern naming a directory covers everything below it
+	if dirOnly {
+		expr.WriteString("/.*$")
+	} else {
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.MustCompile(expr.String())
+}
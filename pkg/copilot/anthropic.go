@@ -0,0 +1,105 @@
+package copilot
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	anthropicEndpoint  = "https://api.anthropic.com/v1"
+	anthropicVersion   = "2023-06-01"
+	anthropicModel     = "claude-sonnet-4-5"
+	anthropicMaxTokens = 8192 // The API requires a limit; room for a full workflow
+)
+
+// thinkingBudgets maps reasoning effort to an extended thinking budget
+var thinkingBudgets = map[string]int{
+	"low":    1024,
+	"medium": 4096,
+	"high":   16384,
+}
+
+// anthropic sends prompts to the Anthropic messages API. The key is read
+// from ANTHROPIC_API_KEY.
+type anthropic struct {
+	client *Client
+	key    string
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Messages    []chatMessage      `json:"messages"`
+	Temperature float64            `json:"temperature,omitempty"`
+	Thinking    *anthropicThinking `json:"thinking,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func newAnthropic(c *Client) (*anthropic, error) {
+	key, err := apiKey("new_anthropic", "ANTHROPIC_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	return &anthropic{client: c, key: key}, nil
+}
+
+func (p *anthropic) name() string {
+	return "Anthropic"
+}
+
+func (p *anthropic) complete(prompt string) (string, error) {
+	settings := p.client.config.Model()
+	req := anthropicRequest{
+		Model:       settings.Model,
+		MaxTokens:   settings.MaxTokens,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: settings.Temperature,
+	}
+	if req.Model == "" {
+		req.Model = anthropicModel
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = anthropicMaxTokens
+	}
+
+	// Thinking tokens count against max_tokens, so the budget comes on top,
+	// and the API only accepts the default temperature with thinking on
+	if budget := thinkingBudgets[settings.ReasoningEffort]; budget > 0 {
+		req.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		req.MaxTokens += budget
+		if req.Temperature != 0 {
+			p.client.logger.Debug("Anthropic ignores temperature when reasoning effort is set")
+			req.Temperature = 0
+		}
+	}
+
+	var resp anthropicResponse
+	err := p.client.postJSON(endpoint(settings.Endpoint, anthropicEndpoint)+"/messages",
+		map[string]string{"x-api-key": p.key, "anthropic-version": anthropicVersion},
+		req, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	var text []string
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text = append(text, block.Text)
+		}
+	}
+	if len(text) == 0 {
+		return "", fmt.Errorf("response has no text")
+	}
+	return strings.Join(text, "\n"), nil
+}
@@ -0,0 +1,229 @@
+package copilot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	sentinelContext "gh-sentinel/internal/context"
+	"gh-sentinel/internal/errors"
+)
+
+const (
+	copilotTokenURL      = "https://api.github.com/copilot_internal/v2/token"
+	copilotEndpoint      = "https://api.githubcopilot.com"
+	copilotModel         = "gpt-4o"
+	copilotIntegrationID = "copilot-developer-cli"
+)
+
+// copilotAPI sends prompts to the Copilot chat API, authenticating with a
+// Copilot token exchanged for the gh CLI's token. Requests that fail for
+// any reason other than cancellation are retried through the CLI when it is
+// installed.
+type copilotAPI struct {
+	client   *Client
+	ghToken  string
+	fallback *copilotCLI // nil when the CLI is not installed
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	url     string
+}
+
+type copilotRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream"`
+}
+
+// copilotChunk is one server-sent event of a streamed reply
+type copilotChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// newCopilot prefers the chat API and falls back to the gh copilot CLI when
+// no Copilot token can be obtained
+func newCopilot(c *Client) (provider, error) {
+	fallback, cliErr := newCopilotCLI(c)
+
+	p := &copilotAPI{client: c, fallback: fallback}
+	// Copilot tokens are issued for GitHub.com accounts, also to users of a
+	// GitHub Enterprise Server
+	ghToken, err := sentinelContext.GetAuthToken(c.ctx, sentinelContext.DefaultHost)
+	if err == nil {
+		p.ghToken = ghToken
+		_, err = p.copilotToken()
+	}
+	if err != nil {
+		if fallback == nil {
+			return nil, errors.CopilotError("new_copilot", fmt.Errorf("copilot API unavailable (%v) and %v", err, cliErr))
+		}
+		c.logger.Debug("Copilot API unavailable, using the gh copilot CLI: %v", err)
+		return fallback, nil
+	}
+	return p, nil
+}
+
+func (p *copilotAPI) name() string {
+	return "Copilot"
+}
+
+func (p *copilotAPI) complete(prompt string) (string, error) {
+	reply, err := p.chat(prompt)
+	if err != nil && p.fallback != nil && p.client.ctx.Err() == nil {
+		p.client.logger.Debug("Copilot API request failed, retrying with the gh copilot CLI: %v", err)
+		return p.fallback.complete(prompt)
+	}
+	return reply, err
+}
+
+// chat streams a completion from the chat API. The request timeout applies
+// to the wait for each chunk rather than to the whole reply, which can take
+// minutes for a complete workflow.
+func (p *copilotAPI) chat(prompt string) (string, error) {
+	token, err := p.copilotToken()
+	if err != nil {
+		return "", err
+	}
+
+	settings := p.client.config.Model()
+	model := settings.Model
+	if model == "" {
+		model = copilotModel
+	}
+	if settings.ReasoningEffort != "" {
+		p.client.logger.Debug("Copilot API ignores the reasoning effort setting")
+	}
+	payload, err := json.Marshal(copilotRequest{
+		Model:       model,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: settings.Temperature,
+		MaxTokens:   settings.MaxTokens,
+		Stream:      true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(p.client.ctx)
+	defer cancel()
+	timeout := p.client.config.RequestTimeout
+	idle := time.AfterFunc(timeout, cancel)
+	defer idle.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", p.client.config.UserAgent)
+	req.Header.Set("Editor-Version", p.client.config.UserAgent)
+	req.Header.Set("Copilot-Integration-Id", copilotIntegrationID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", p.requestError(ctx, err, timeout)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("%s: %s", resp.Status, apiErrorMessage(data))
+	}
+
+	var reply strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		idle.Reset(timeout)
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return reply.String(), nil
+		}
+		var chunk copilotChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("invalid response chunk: %w", err)
+		}
+		for _, choice := range chunk.Choices {
+			reply.WriteString(choice.Delta.Content)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", p.requestError(ctx, err, timeout)
+	}
+	return reply.String(), nil
+}
+
+// requestError reports a request aborted by the idle timer as a timeout
+func (p *copilotAPI) requestError(ctx context.Context, err error, timeout time.Duration) error {
+	if ctx.Err() != nil && p.client.ctx.Err() == nil {
+		return errors.NetworkError("copilot_chat", fmt.Errorf("no response for %s", timeout))
+	}
+	return errors.NetworkError("copilot_chat", err)
+}
+
+// copilotToken returns a valid Copilot token, exchanging the gh token for a
+// new one shortly before the current one expires
+func (p *copilotAPI) copilotToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Until(p.expires) > time.Minute {
+		return p.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(p.client.ctx, p.client.config.RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, copilotTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "token "+p.ghToken)
+	req.Header.Set("User-Agent", p.client.config.UserAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.NetworkError("copilot_token", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.NetworkError("copilot_token", err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", errors.AuthError("copilot_token", fmt.Errorf("%s: %s", resp.Status, apiErrorMessage(data)))
+	}
+
+	var body struct {
+		Token     string `json:"token"`
+		ExpiresAt int64  `json:"expires_at"`
+		Endpoints struct {
+			API string `json:"api"`
+		} `json:"endpoints"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil || body.Token == "" {
+		return "", errors.AuthError("copilot_token", fmt.Errorf("no token in response"))
+	}
+
+	p.token = body.Token
+	p.expires = time.Unix(body.ExpiresAt, 0)
+	p.url = endpoint(p.client.config.Model().Endpoint, endpoint(body.Endpoints.API, copilotEndpoint))
+	return p.token, nil
+}
@@ -0,0 +1,54 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// copilotCLI runs prompts through the gh copilot extension
+type copilotCLI struct {
+	client *Client
+}
+
+func newCopilotCLI(c *Client) (*copilotCLI, error) {
+	if err := CheckAvailable(c.ctx); err != nil {
+		return nil, err
+	}
+	return &copilotCLI{client: c}, nil
+}
+
+func (p *copilotCLI) name() string {
+	return "Copilot"
+}
+
+// complete runs a prompt through gh copilot and returns its combined output.
+// A reply that takes longer than an HTTP provider's is given up on.
+func (p *copilotCLI) complete(prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(p.client.ctx, aiRequestTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "gh", p.commandArgs(prompt)...)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("gh copilot did not answer within %s", aiRequestTimeout)
+	}
+	return string(output), err
+}
+
+// commandArgs builds the gh copilot arguments for a prompt, applying the
+// configured model settings
+func (p *copilotCLI) commandArgs(prompt string) []string {
+	args := []string{"copilot", "-p", prompt}
+
+	settings := p.client.config.Model()
+	if settings.Model != "" {
+		args = append(args, "--model", settings.Model)
+	}
+
+	// The Copilot CLI does not expose sampling parameters
+	if settings.Temperature != 0 || settings.MaxTokens != 0 || settings.ReasoningEffort != "" {
+		p.client.logger.Debug("Copilot CLI ignores temperature, max tokens and reasoning effort settings")
+	}
+
+	return args
+}
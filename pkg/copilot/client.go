@@ -1,34 +1,95 @@
 package copilot
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"path"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"gh-sentinel/internal/config"
 	"gh-sentinel/internal/errors"
 	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/patcher"
+	"gh-sentinel/pkg/redact"
+	"gh-sentinel/pkg/workflow"
 )
 
-// Client handles interaction with GitHub Copilot CLI
+// Diagnoser diagnoses a failed workflow and proposes a fix
+type Diagnoser interface {
+	DiagnoseAndFix(req *DiagnosisRequest) (*DiagnosisResult, error)
+	QuickDiagnose(errorLogs string, keyLines []string) (string, error)
+}
+
+// Client diagnoses failures through the configured AI provider. Prompts,
+// log compression and response parsing are shared; only the transport to
+// the model differs between providers.
 type Client struct {
-	config *config.Config
-	logger *logger.Logger
+	config   *config.Config
+	logger   *logger.Logger
+	provider provider
+	redactor *redact.Redactor // Masks secrets in logs before they leave the machine
+	ctx      context.Context
 }
 
-// NewClient creates a new Copilot client
-func NewClient(cfg *config.Config, log *logger.Logger) (*Client, error) {
-	// Verify gh copilot is available
-	cmd := exec.Command("gh", "copilot", "--version")
+// provider sends a prompt to a model and returns its reply
+type provider interface {
+	name() string
+	complete(prompt string) (string, error)
+}
+
+// CheckAvailable verifies that the gh copilot command can be run
+func CheckAvailable(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "gh", "copilot", "--version")
 	if err := cmd.Run(); err != nil {
-		return nil, errors.CopilotError("new_client", fmt.Errorf("gh copilot not available - install with: gh extension install github/gh-copilot"))
+		return errors.CopilotError("check_available", fmt.Errorf("gh copilot not available - install with: gh extension install github/gh-copilot"))
+	}
+	return nil
+}
+
+// NewClient creates a client for the AI provider selected in the
+// configuration, verifying that the provider can be reached. Cancelling ctx
+// aborts AI requests.
+func NewClient(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Client, error) {
+	redactor, err := redact.New(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		config:   cfg,
+		logger:   log,
+		redactor: redactor,
+		ctx:      ctx,
 	}
 
-	return &Client{
-		config: cfg,
-		logger: log,
-	}, nil
+	switch cfg.AI.Provider {
+	case "copilot":
+		c.provider, err = newCopilot(c)
+	case "openai":
+		c.provider, err = newOpenAI(c)
+	case "anthropic":
+		c.provider, err = newAnthropic(c)
+	case "ollama":
+		c.provider, err = newOllama(c)
+	default:
+		err = errors.ValidationError("new_client", fmt.Sprintf("unknown AI provider %q", cfg.AI.Provider))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("Using AI provider %s", c.provider.name())
+	return c, nil
+}
+
+// SetContext replaces the context of AI requests, so cancelling ctx aborts
+// the request in flight
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
 }
 
 // DiagnosisRequest contains all information needed for diagnosis
@@ -38,6 +99,14 @@ type DiagnosisRequest struct {
 	FileContent    string
 	AvailableFiles []string
 	WorkflowPath   string
+	RunnerOS       []string // Operating systems of the failing jobs, if known
+	ScriptFindings []string // Shell problems found in the workflow's run: scripts
+	FailedSteps    []string // Steps that failed, as "job › step", if known
+	Matrix         []string // How the combinations of each failed matrix job fared
+	RelatedFiles   map[string]string // Local actions and called workflows the failed jobs run, by path
+	KeyLines       []string // Log lines the analyzer matched, kept when the logs are condensed
+	Implicated     []string // Tests and source files of the repository the logs point to
+	SourceFiles    map[string]string // Other files of the repository the logs point to, which a fix may target, by path
 }
 
 // DiagnosisResult contains the AI diagnosis and fix suggestion
@@ -46,34 +115,47 @@ type DiagnosisResult struct {
 	FixedContent string
 	TargetFile   string
 	Confidence   string
+	Attempt      int // 1 for the first proposal, incremented by each correction
+	BaseContent  string // Content of the target the fix was made from, when known
+	RootCause    string // One of analyzer.RootCauses; only WORKFLOW_CONFIG comes with a fix
+	Culprit      string // File, test or service at fault when the workflow is not
+	Patches      []FilePatch // Further files the fix changes, when it spans several
+}
+
+// FilePatch is the fixed content of one file of a fix
+type FilePatch struct {
+	TargetFile   string
+	FixedContent string
+	BaseContent  string // Content of the target the fix was made from, when known
+}
+
+// Files returns every file the fix changes, TargetFile first
+func (d *DiagnosisResult) Files() []FilePatch {
+	files := []FilePatch{{TargetFile: d.TargetFile, FixedContent: d.FixedContent, BaseContent: d.BaseContent}}
+	return append(files, d.Patches...)
 }
 
-// DiagnoseAndFix uses GitHub Copilot to analyze errors and suggest fixes
+// DiagnoseAndFix asks the AI provider to analyze errors and suggest fixes
 func (c *Client) DiagnoseAndFix(req *DiagnosisRequest) (*DiagnosisResult, error) {
 	c.logger.Info("Requesting AI diagnosis for %s", req.CurrentFile)
 
-	// Truncate logs if necessary
-	logs := req.ErrorLogs
-	if len(logs) > c.config.MaxLogSize {
-		logs = "... [Truncated for buffer safety] ...\n" + logs[len(logs)-c.config.MaxLogSize:]
-		c.logger.Debug("Truncated logs from %d to %d chars", len(req.ErrorLogs), len(logs))
-	}
+	logs := c.promptLogs(req.ErrorLogs, req.KeyLines)
 
 	// Build context-rich prompt
 	prompt := c.buildDiagnosisPrompt(req, logs)
 
-	// Execute gh copilot
-	cmd := exec.Command("gh", "copilot", "-p", prompt)
-	output, err := cmd.CombinedOutput()
+	rawResult, err := c.execute(prompt)
 	if err != nil {
-		return nil, errors.CopilotError("diagnose_and_fix", fmt.Errorf("copilot execution failed: %v\nOutput: %s", err, string(output)))
+		if rawResult != "" {
+			err = fmt.Errorf("%v\nOutput: %s", err, rawResult)
+		}
+		return nil, errors.AIProviderError("diagnose_and_fix", c.provider.name(), err)
 	}
 
-	rawResult := string(output)
-	c.logger.Debug("Received %d bytes from Copilot", len(rawResult))
+	c.logger.Debug("Received %d bytes from %s", len(rawResult), c.provider.name())
 
 	// Parse the result
-	result, err := c.parseResponse(rawResult, req.CurrentFile)
+	result, err := c.parseResponse(rawResult, req.CurrentFile, req.AvailableFiles, req.SourceFiles)
 	if err != nil {
 		return nil, err
 	}
@@ -82,9 +164,88 @@ func (c *Client) DiagnoseAndFix(req *DiagnosisRequest) (*DiagnosisResult, error)
 	return result, nil
 }
 
+// promptLogs redacts the logs and fits them into the prompt budget,
+// keeping the key lines of the failure
+func (c *Client) promptLogs(errorLogs string, keyLines []string) string {
+	// Secrets never reach the provider, even from callers that did not
+	// redact the logs themselves
+	redacted, n := c.redactor.Redact(errorLogs)
+	if n > 0 {
+		c.logger.Debug("Redacted %d secrets from the logs", n)
+	}
+	keys := make([]string, len(keyLines))
+	for i, key := range keyLines {
+		keys[i], _ = c.redactor.Redact(key)
+	}
+
+	logs := strings.Join(cleanLogs(redacted), "\n")
+	if len(logs) > c.config.MaxLogSize {
+		if c.config.CompressLogs {
+			logs = c.compressLogs(logs, keys)
+		} else {
+			logs = condenseLogs(logs, keys, c.config.MaxLogSize)
+		}
+	}
+	if len(logs) != len(errorLogs) {
+		c.logger.Debug("Reduced logs from %d to %d chars", len(errorLogs), len(logs))
+	}
+	return logs
+}
+
+// execute sends a prompt to the AI provider and returns its reply
+func (c *Client) execute(prompt string) (string, error) {
+	return c.provider.complete(prompt)
+}
+
 // buildDiagnosisPrompt creates a comprehensive prompt for Copilot
 func (c *Client) buildDiagnosisPrompt(req *DiagnosisRequest, logs string) string {
 	filesContext := strings.Join(req.AvailableFiles, ", ")
+	runnerOS := "unknown"
+	if len(req.RunnerOS) > 0 {
+		runnerOS = strings.Join(req.RunnerOS, ", ")
+	}
+	failedSteps := "unknown"
+	if len(req.FailedSteps) > 0 {
+		failedSteps = strings.Join(req.FailedSteps, "; ")
+	}
+	matrix := "not a matrix failure"
+	if len(req.Matrix) > 0 {
+		matrix = "- " + strings.Join(req.Matrix, "\n- ")
+	}
+	relatedFiles := "None"
+	if len(req.RelatedFiles) > 0 {
+		var b strings.Builder
+		paths := make([]string, 0, len(req.RelatedFiles))
+		for path := range req.RelatedFiles {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(&b, "\n%s:\n```yaml\n%s\n```\n", path, req.RelatedFiles[path])
+		}
+		relatedFiles = b.String()
+	}
+	sourceFiles := "None"
+	if len(req.SourceFiles) > 0 {
+		var b strings.Builder
+		files := make([]string, 0, len(req.SourceFiles))
+		for file := range req.SourceFiles {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			fmt.Fprintf(&b, "\n%s:\n```%s\n%s\n```\n", file, fenceLanguage(file), req.SourceFiles[file])
+		}
+		sourceFiles = b.String()
+	}
+	implicated := "None found"
+	if len(req.Implicated) > 0 {
+		implicated = strings.Join(req.Implicated, ", ")
+	}
+	scriptFindings := "None found"
+	if len(req.ScriptFindings) > 0 {
+		scriptFindings = "- " + strings.Join(req.ScriptFindings, "\n- ")
+	}
 	
 	// Escape quotes in logs
 	safeErrorLogs := strings.ReplaceAll(logs, `"`, `'`)
@@ -105,21 +266,40 @@ You are analyzing a failed GitHub Actions workflow. Your goal is to:
 
 **Suspected File:** %s
 
+**Runner OS:** %s
+
+**Failed Steps:** %s (the logs below are split by job and step)
+
+**Matrix Combinations:**
+%s
+
 **Current File Content:**
 `+"```yaml\n%s\n```"+`
 
+**Local Actions and Called Workflows of the Failed Steps:** %s
+
 **Failure Logs:**
 %s
 
+**Tests and Source Files the Logs Point To:** %s
+
+**Other Repository Files the Logs Point To:** %s
+
+**Shell Script Findings (run: steps):**
+%s
+
 ### ANALYSIS REQUIREMENTS
 
-1. **Root Cause Analysis:** Examine the logs to find the exact error (exit codes, syntax errors, missing dependencies, etc.)
-2. **Target Identification:** The suspected file may not be the actual culprit. Check logs for references to other workflow files.
-3. **Surgical Fix:** Provide the COMPLETE file content with the fix applied. NO placeholders, NO comments like "# rest of file unchanged"
+1. **Root Cause Analysis:** Start from the failed steps and examine their logs to find the exact error (exit codes, syntax errors, missing dependencies, etc.). Shell findings in failed steps often are the root cause; fix the script itself rather than the surrounding YAML
+2. **Target Identification:** The suspected file may not be the actual culprit. Check logs for references to other workflow files. A failure inside a local action or called workflow listed above is fixed in that file: give its path as FIX_TARGET and its complete content as FIXED_CONTENT.
+3. **Root Cause Classification:** WORKFLOW_CONFIG when the workflow, its actions or its run: scripts have to change; APPLICATION_CODE when the repository's source code, tests or their build files fail (a failing test, a compile error in the sources); INFRASTRUCTURE when a service, registry or runner is down or unreachable. Only WORKFLOW_CONFIG is fixed here: for the others, never rewrite the workflow to hide the failure, and name the file, test or service at fault as CULPRIT. An APPLICATION_CODE failure in one of the other repository files listed above (a dependency manifest, a Dockerfile, a source file) is fixed in that file: give its repository path as FIX_TARGET and its complete content as FIXED_CONTENT. Otherwise leave out FIX_TARGET and FIXED_CONTENT
+4. **Surgical Fix:** Provide the COMPLETE file content with the fix applied. NO placeholders, NO comments like "# rest of file unchanged"
 
 ### OUTPUT FORMAT (STRICT)
 
-FIX_TARGET: [exact-filename.yml]
+ROOT_CAUSE: [WORKFLOW_CONFIG|APPLICATION_CODE|INFRASTRUCTURE]
+CULPRIT: [file, test or service at fault, only when ROOT_CAUSE is not WORKFLOW_CONFIG]
+FIX_TARGET: [exact-filename.yml, or the path of another repository file listed above]
 CONFIDENCE: [HIGH|MEDIUM|LOW]
 
 EXPLANATION:
@@ -128,8 +308,13 @@ EXPLANATION:
 FIXED_CONTENT:
 `+"```yaml\n[COMPLETE YAML FILE WITH FIX APPLIED]\n```"+`
 
+[Only when the fix cannot work without changing further files, e.g. the workflow and requirements.txt, one block per further file:]
+ALSO_FIX: [path of the further file]
+`+"```[language]\n[COMPLETE FILE WITH FIX APPLIED]\n```"+`
+
 ### EXAMPLES OF GOOD OUTPUT
 
+ROOT_CAUSE: WORKFLOW_CONFIG
 FIX_TARGET: ci.yml
 CONFIDENCE: HIGH
 
@@ -142,32 +327,76 @@ FIXED_CONTENT:
 ### CRITICAL RULES
 
 - Output ONLY the format above
-- Include the ENTIRE file in FIXED_CONTENT
+- Include the ENTIRE file in FIXED_CONTENT whenever there is a FIX_TARGET; for a file other than a workflow, fence it with its language (e.g. json, dockerfile, text) instead of yaml
 - Match the original indentation exactly
+- Change further files with ALSO_FIX only when the fix needs them; each is a workflow file, a local action or called workflow, or one of the other repository files listed above, with its ENTIRE content
+- When only some matrix combinations fail, fix what differs for the failing axis value (e.g. an `+"`include:`"+` entry or an `+"`if:`"+` on that value) rather than every combination, and never drop the failing combination; when all fail the same way, the cause is common to them
+- Make the fix correct for the runner OS above; Windows runs steps with pwsh by default, and a matrix fix must keep working on every OS (use `+"`shell: bash`"+` or `+"`if: runner.os == ...`"+` for OS-specific steps)
 - If the workflow is actually healthy, use: CONFIDENCE: HEALTHY`,
 		filesContext,
 		req.CurrentFile,
+		runnerOS,
+		failedSteps,
+		matrix,
 		req.FileContent,
+		relatedFiles,
 		safeErrorLogs,
+		implicated,
+		sourceFiles,
+		scriptFindings,
 	)
 
-	return prompt
+	return prompt + c.languageRule()
+}
+
+// languageRule asks for the explanation in the configured language. The
+// markers, file names and YAML stay as they are, so the reply still parses.
+func (c *Client) languageRule() string {
+	if c.config.Language == "" || c.config.Language == "en" {
+		return ""
+	}
+	return fmt.Sprintf("\n- Write the EXPLANATION in %s; keep the ROOT_CAUSE, CULPRIT, FIX_TARGET, CONFIDENCE, EXPLANATION, FIXED_CONTENT and ALSO_FIX markers, file names, YAML and quoted log lines unchanged",
+		config.Languages[c.config.Language])
 }
 
 // parseResponse extracts structured information from Copilot's response
-func (c *Client) parseResponse(rawResponse string, defaultTarget string) (*DiagnosisResult, error) {
+func (c *Client) parseResponse(rawResponse string, defaultTarget string, availableFiles []string, sourceFiles map[string]string) (*DiagnosisResult, error) {
 	result := &DiagnosisResult{
 		TargetFile: defaultTarget,
 		Confidence: "MEDIUM",
+		Attempt:    1,
+		RootCause:  analyzer.RootCauseWorkflow,
+	}
+
+	// Extract the root cause; a failure of the application code or of the
+	// infrastructure names its culprit, and is only fixed in one of the
+	// source files, never in the workflow
+	rootCauseRe := regexp.MustCompile(`(?i)ROOT_CAUSE:\s*\[?([A-Z_]+)`)
+	if match := rootCauseRe.FindStringSubmatch(rawResponse); len(match) > 1 && slices.Contains(analyzer.RootCauses, strings.ToUpper(match[1])) {
+		result.RootCause = strings.ToUpper(match[1])
+	}
+	if result.RootCause != analyzer.RootCauseWorkflow {
+		culpritRe := regexp.MustCompile(`(?i)CULPRIT:[ \t]*([^\n\r]+)`)
+		if match := culpritRe.FindStringSubmatch(rawResponse); len(match) > 1 {
+			result.Culprit = strings.Trim(match[1], "[]`* \"'")
+		}
 	}
 
 	// Extract target file
 	targetRe := regexp.MustCompile(`(?i)FIX_TARGET:\s*([^\s\n\r]+)`)
 	if match := targetRe.FindStringSubmatch(rawResponse); len(match) > 1 {
 		extracted := strings.Trim(match[1], "[]`* \"'")
-		result.TargetFile = c.normalizeWorkflowPath(extracted)
+		if source, ok := sourcePath(extracted, sourceFiles); ok {
+			result.TargetFile = source
+		} else if result.RootCause == analyzer.RootCauseWorkflow {
+			result.TargetFile = c.normalizeWorkflowPath(extracted, availableFiles)
+		}
 		c.logger.Debug("Extracted target: %s (normalized to %s)", match[1], result.TargetFile)
 	}
+	_, sourceFix := sourceFiles[result.TargetFile]
+	if sourceFix && result.Culprit == "" {
+		result.Culprit = result.TargetFile
+	}
 
 	// Extract confidence
 	confidenceRe := regexp.MustCompile(`(?i)CONFIDENCE:\s*([A-Z]+)`)
@@ -190,13 +419,20 @@ func (c *Client) parseResponse(rawResponse string, defaultTarget string) (*Diagn
 		result.Explanation = rawResponse
 	}
 
-	// Extract YAML fix
-	yamlRe := regexp.MustCompile("(?s)```(?:yaml|yml)?\\n(.*?)\\n```")
-	if match := yamlRe.FindStringSubmatch(rawResponse); len(match) > 1 {
+	if result.RootCause != analyzer.RootCauseWorkflow && !sourceFix {
+		c.logger.Debug("Root cause %s, culprit %q: no workflow fix", result.RootCause, result.Culprit)
+		return result, nil
+	}
+
+	// Extract the fix; a source file is fenced with its own language. The
+	// further files of a fix that spans several follow it.
+	primary, further, _ := strings.Cut(rawResponse, "ALSO_FIX:")
+	codeRe := regexp.MustCompile("(?s)```[\\w+-]*\\n(.*?)\\n```")
+	if match := codeRe.FindStringSubmatch(primary); len(match) > 1 {
 		result.FixedContent = strings.TrimSpace(match[1])
-		c.logger.Debug("Extracted YAML fix: %d lines", strings.Count(result.FixedContent, "\n")+1)
+		c.logger.Debug("Extracted fix: %d lines", strings.Count(result.FixedContent, "\n")+1)
 	} else {
-		c.logger.Warn("No YAML code block found in Copilot response")
+		c.logger.Warn("No code block found in Copilot response")
 	}
 
 	// Validate we got meaningful output
@@ -204,17 +440,85 @@ func (c *Client) parseResponse(rawResponse string, defaultTarget string) (*Diagn
 		return nil, errors.ValidationError("parse_copilot_response", "no actionable fix found in AI response")
 	}
 
+	if further != "" {
+		result.Patches = c.parseFurtherFiles("ALSO_FIX:"+further, result.TargetFile, availableFiles, sourceFiles)
+	}
+
 	return result, nil
 }
 
-// normalizeWorkflowPath ensures the path is in the correct format
-func (c *Client) normalizeWorkflowPath(path string) string {
+// parseFurtherFiles extracts the further files of a fix that spans several,
+// each given after an ALSO_FIX marker. Files without content, or that repeat
+// the target or one another, are left out.
+func (c *Client) parseFurtherFiles(response, target string, availableFiles []string, sourceFiles map[string]string) []FilePatch {
+	alsoRe := regexp.MustCompile("(?s)ALSO_FIX:[ \\t]*(\\S+)\\s*```[\\w+-]*\\n(.*?)\\n```")
+	seen := map[string]bool{target: true}
+	var patches []FilePatch
+	for _, match := range alsoRe.FindAllStringSubmatch(response, -1) {
+		extracted := strings.Trim(match[1], "[]`* \"'")
+		file, ok := sourcePath(extracted, sourceFiles)
+		if !ok {
+			file = c.normalizeWorkflowPath(extracted, availableFiles)
+		}
+		content := strings.TrimSpace(match[2])
+		if seen[file] || content == "" {
+			continue
+		}
+		seen[file] = true
+		patches = append(patches, FilePatch{TargetFile: file, FixedContent: content})
+		c.logger.Debug("Extracted further fix of %s: %d lines", file, strings.Count(content, "\n")+1)
+	}
+	return patches
+}
+
+// sourcePath matches a fix target against the other repository files the
+// AI was given, by path, or by name when only one has that name
+func sourcePath(target string, sourceFiles map[string]string) (string, bool) {
+	target = strings.TrimPrefix(strings.ReplaceAll(target, "\\", "/"), "./")
+	if _, ok := sourceFiles[target]; ok {
+		return target, true
+	}
+	var match string
+	for file := range sourceFiles {
+		if path.Base(file) != target {
+			continue
+		}
+		if match != "" {
+			return "", false
+		}
+		match = file
+	}
+	return match, match != ""
+}
+
+// fenceLanguage names the language of a file for a code fence, e.g. json
+// for package.json
+func fenceLanguage(file string) string {
+	if patcher.IsDockerfile(file) {
+		return "dockerfile"
+	}
+	return strings.TrimPrefix(strings.ToLower(path.Ext(file)), ".")
+}
+
+// normalizeWorkflowPath ensures the path is in the correct format, preferring
+// the exact name and extension of an existing workflow file
+func (c *Client) normalizeWorkflowPath(path string, availableFiles []string) string {
 	// Remove quotes and extra characters
 	path = strings.Trim(path, "[]`* \"'")
 	
 	// Normalize backslashes to forward slashes
 	path = strings.ReplaceAll(path, "\\", "/")
+
+	// Match against the real directory listing (case and extension insensitive)
+	if resolved, ok := workflow.Resolve(path, availableFiles); ok {
+		return resolved
+	}
 	
+	// A local action keeps its path
+	if workflow.IsAction(path) {
+		return strings.TrimPrefix(path, "./")
+	}
+
 	// Ensure it starts with .github/workflows/
 	if strings.HasPrefix(path, ".github/workflows/") {
 		return path
@@ -233,17 +537,20 @@ func (c *Client) normalizeWorkflowPath(path string) string {
 	return ".github/workflows/" + cleanName
 }
 
-// QuickDiagnose provides a quick diagnosis without full file context
-func (c *Client) QuickDiagnose(errorLogs string) (string, error) {
+// QuickDiagnose explains the root cause of a failure from its logs alone,
+// without a fix
+func (c *Client) QuickDiagnose(errorLogs string, keyLines []string) (string, error) {
 	prompt := fmt.Sprintf(`Analyze this CI/CD failure log and explain the root cause in 2-3 sentences:
 
-%s`, errorLogs)
+%s`, c.promptLogs(errorLogs, keyLines))
+	if name, ok := config.Languages[c.config.Language]; ok && c.config.Language != "en" {
+		prompt += "\n\nAnswer in " + name + "."
+	}
 
-	cmd := exec.Command("gh", "copilot", "-p", prompt)
-	output, err := cmd.CombinedOutput()
+	output, err := c.execute(prompt)
 	if err != nil {
 		return "", errors.CopilotError("quick_diagnose", err)
 	}
 
-	return string(output), nil
+	return output, nil
 }
@@ -0,0 +1,139 @@
+package copilot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxSummaryChunks bounds the number of summarization calls per diagnosis
+const maxSummaryChunks = 3
+
+// stepHeader matches the step headers of logs split by step
+var stepHeader = regexp.MustCompile(`^--- .+ ---$`)
+
+// isHeader reports whether a log line is a job or step header
+func isHeader(line string) bool {
+	return strings.HasPrefix(line, "=== Job:") || strings.HasPrefix(line, "Failed step:") || stepHeader.MatchString(line)
+}
+
+// failureMarker matches log lines that usually carry the actual failure
+var failureMarker = regexp.MustCompile(`(?i)##\[error\]|\berror\b|\bfailed\b|\bfatal\b|exit code \d+|traceback|panic:|exception`)
+
+// compressLogs reduces oversized logs in two stages: the failure-bearing
+// chunks are condensed into a structured summary (by the AI, or locally when
+// that fails), then combined with the raw excerpt around the key error lines.
+// The summary is capped at half the budget first, so only the excerpt is cut
+// to fit what remains.
+func (c *Client) compressLogs(logs string, keys []string) string {
+	budget := c.config.MaxLogSize
+	excerpt := condenseLogs(logs, keys, budget/2)
+
+	var summaries []string
+	for _, chunk := range selectFailureChunks(logs, budget) {
+		summary, err := c.summarizeChunk(chunk)
+		if err != nil {
+			c.logger.Debug("AI log summarization failed, using local summary: %v", err)
+			summary = summarizeLocally(chunk)
+		}
+		summaries = append(summaries, strings.TrimSpace(summary))
+	}
+
+	var b strings.Builder
+	b.WriteString("[Logs compressed: structured failure summary followed by key excerpt]\n\n")
+	b.WriteString("FAILURE SUMMARY:\n")
+	b.WriteString(truncateHead(strings.Join(summaries, "\n"), budget/2))
+	b.WriteString("\n\nKEY EXCERPT:\n")
+	if remaining := budget - b.Len(); remaining > 0 {
+		b.WriteString(truncateTail(excerpt, remaining))
+	}
+	return b.String()
+}
+
+// summarizeChunk asks the AI to condense one chunk of logs
+func (c *Client) summarizeChunk(chunk string) (string, error) {
+	prompt := fmt.Sprintf(`Condense this CI log excerpt into a structured failure summary.
+Reply with at most 5 lines using exactly these keys:
+
+FAILED_STEP: <step or job name, or unknown>
+ERROR: <the exact error message>
+LOCATION: <file, line or command involved>
+LIKELY_CAUSE: <one sentence>
+
+LOG EXCERPT:
+%s`, strings.ReplaceAll(chunk, `"`, `'`))
+
+	output, err := c.execute(prompt)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(output) == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return output, nil
+}
+
+// selectFailureChunks splits logs into budget-sized chunks and returns the
+// last few that contain failure markers, in log order
+func selectFailureChunks(logs string, size int) []string {
+	var chunks []string
+	for start := 0; start < len(logs); start += size {
+		end := start + size
+		if end > len(logs) {
+			end = len(logs)
+		}
+		chunks = append(chunks, logs[start:end])
+	}
+
+	var selected []string
+	for i := len(chunks) - 1; i >= 0 && len(selected) < maxSummaryChunks; i-- {
+		if failureMarker.MatchString(chunks[i]) {
+			selected = append([]string{chunks[i]}, selected...)
+		}
+	}
+
+	// No recognizable failure: the tail is the best guess
+	if len(selected) == 0 && len(chunks) > 0 {
+		selected = chunks[len(chunks)-1:]
+	}
+	return selected
+}
+
+// summarizeLocally builds a summary from job headers and failure lines
+func summarizeLocally(chunk string) string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(chunk, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		if strings.HasPrefix(line, "=== Job:") || strings.HasPrefix(line, "Failed step:") || failureMarker.MatchString(line) {
+			seen[line] = true
+			lines = append(lines, "- "+line)
+		}
+		if len(lines) >= 10 {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return "- no failure markers found in this section"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateTail keeps the last size characters of s
+func truncateTail(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	return "... [Truncated for buffer safety] ...\n" + s[len(s)-size:]
+}
+
+// truncateHead keeps the first size characters of s
+func truncateHead(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	return s[:size] + "\n... [Truncated for buffer safety] ..."
+}
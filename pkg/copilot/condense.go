@@ -0,0 +1,196 @@
+package copilot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Context windows around the lines the condenser keeps: matched lines get
+// keyRadius lines on each side, widened up to maxKeyRadius while the budget
+// lasts; other failure lines get markerRadius
+const (
+	keyRadius    = 3
+	maxKeyRadius = 12
+	markerRadius = 1
+)
+
+// gapCost is what the marker of omitted lines costs in the budget
+const gapCost = len("[... 99999 lines omitted ...]\n")
+
+var (
+	ansiRe      = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+	timestampRe = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?Z ?`)
+	digitsRe    = regexp.MustCompile(`\d+`)
+)
+
+// cleanLine strips terminal escape codes and the timestamp of a log line
+func cleanLine(line string) string {
+	line = ansiRe.ReplaceAllString(line, "")
+	line = timestampRe.ReplaceAllString(line, "")
+	return strings.TrimRight(line, " \t\r")
+}
+
+// cleanLogs strips escape codes and timestamps, and collapses repeated
+// lines. Lines differing only in their numbers, like progress output, count
+// as repeats unless they report a failure.
+func cleanLogs(logs string) []string {
+	var lines []string
+	var last string
+	repeats := 0
+	flush := func() {
+		if repeats > 0 {
+			lines = append(lines, fmt.Sprintf("[... %d similar lines ...]", repeats))
+			repeats = 0
+		}
+	}
+	for _, line := range strings.Split(strings.Trim(logs, "\n"), "\n") {
+		line = cleanLine(line)
+		key := line
+		if !failureMarker.MatchString(line) {
+			key = digitsRe.ReplaceAllString(line, "#")
+		}
+		if len(lines) > 0 && key == last {
+			repeats++
+			continue
+		}
+		flush()
+		lines = append(lines, line)
+		last = key
+	}
+	flush()
+	return lines
+}
+
+// condenser selects the lines of a log that fit a budget
+type condenser struct {
+	lines  []string
+	kept   []bool
+	size   int // Of the kept lines and the markers of the gaps between them
+	budget int
+}
+
+// add keeps a line if it fits the budget
+func (c *condenser) add(i int) bool {
+	if i < 0 || i >= len(c.lines) || c.kept[i] {
+		return true
+	}
+	// Keeping a line splits, shrinks or closes a gap
+	before := i == 0 || c.kept[i-1]
+	after := i == len(c.lines)-1 || c.kept[i+1]
+	delta := len(c.lines[i]) + 1
+	switch {
+	case !before && !after:
+		delta += gapCost
+	case before && after:
+		delta -= gapCost
+	}
+	if c.size+delta > c.budget {
+		return false
+	}
+	c.kept[i] = true
+	c.size += delta
+	return true
+}
+
+// window keeps the lines around a line, nearest first
+func (c *condenser) window(i, radius int) {
+	c.add(i)
+	for d := 1; d <= radius; d++ {
+		c.add(i + d)
+		c.add(i - d)
+	}
+}
+
+// String assembles the kept lines, marking what was left out
+func (c *condenser) String() string {
+	var b strings.Builder
+	omitted := 0
+	for i, line := range c.lines {
+		if !c.kept[i] {
+			omitted++
+			continue
+		}
+		b.WriteString(omittedMarker(omitted))
+		omitted = 0
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(omittedMarker(omitted))
+	return b.String()
+}
+
+// omittedMarker marks n omitted lines, if any
+func omittedMarker(n int) string {
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return "[... 1 line omitted ...]\n"
+	}
+	return fmt.Sprintf("[... %d lines omitted ...]\n", n)
+}
+
+// condenseLogs fits logs into a budget while keeping the failure: after
+// cleaning, it keeps the job and step headers, windows around the key
+// lines (those the analyzer matched) and around other failure lines, the
+// latest first, then widens the key windows and fills in from the end.
+func condenseLogs(logs string, keys []string, budget int) string {
+	lines := cleanLogs(logs)
+	text := strings.Join(lines, "\n")
+	if len(text) <= budget {
+		return text
+	}
+	var cleanKeys []string
+	for _, key := range keys {
+		if key = strings.TrimSpace(cleanLine(key)); key != "" {
+			cleanKeys = append(cleanKeys, key)
+		}
+	}
+
+	c := &condenser{lines: lines, kept: make([]bool, len(lines)), size: gapCost, budget: budget}
+	var keyLines, markerLines []int
+	for i, line := range lines {
+		switch {
+		case isHeader(line):
+			c.add(i)
+		case isKeyLine(line, cleanKeys):
+			keyLines = append(keyLines, i)
+		case failureMarker.MatchString(line):
+			markerLines = append(markerLines, i)
+		}
+	}
+	for j := len(keyLines) - 1; j >= 0; j-- {
+		c.window(keyLines[j], keyRadius)
+	}
+	for j := len(markerLines) - 1; j >= 0; j-- {
+		c.window(markerLines[j], markerRadius)
+	}
+	for radius := keyRadius + 1; radius <= maxKeyRadius; radius++ {
+		for j := len(keyLines) - 1; j >= 0; j-- {
+			c.add(keyLines[j] + radius)
+			c.add(keyLines[j] - radius)
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !c.add(i) {
+			break
+		}
+	}
+
+	condensed := c.String()
+	if len(condensed) > budget {
+		// Headers alone can exceed a tiny budget
+		return truncateTail(condensed, budget)
+	}
+	return condensed
+}
+
+// isKeyLine reports whether a line holds one of the cleaned key lines
+func isKeyLine(line string, keys []string) bool {
+	for _, key := range keys {
+		if strings.Contains(line, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// aiRequestTimeout bounds one request to an HTTP provider. Writing out a
+// complete workflow file can take a model well over a minute.
+const aiRequestTimeout = 5 * time.Minute
+
+var httpClient = &http.Client{Timeout: aiRequestTimeout}
+
+// chatMessage is one message of a chat request or reply
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// apiKey reads a provider's API key from the environment
+func apiKey(op, env string) (string, error) {
+	key := strings.TrimSpace(os.Getenv(env))
+	if key == "" {
+		return "", errors.AuthError(op, fmt.Errorf("%s is not set", env))
+	}
+	return key, nil
+}
+
+// endpoint returns the configured base URL of a provider, or its default
+func endpoint(configured, fallback string) string {
+	if configured == "" {
+		configured = fallback
+	}
+	return strings.TrimRight(configured, "/")
+}
+
+// postJSON sends body as JSON and decodes the JSON reply into out. Replies
+// with an error status are returned as errors carrying the API's message.
+func (c *Client) postJSON(url string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.NetworkError("post_json", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.NetworkError("post_json", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, apiErrorMessage(data))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// apiErrorMessage extracts the message of an API error reply. OpenAI and
+// Anthropic nest it under error.message, Ollama sends a plain error string.
+func apiErrorMessage(data []byte) string {
+	var nested struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &nested) == nil && nested.Error.Message != "" {
+		return nested.Error.Message
+	}
+	var plain struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &plain) == nil && plain.Error != "" {
+		return plain.Error
+	}
+	return strings.TrimSpace(string(data))
+}
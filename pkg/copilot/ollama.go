@@ -0,0 +1,78 @@
+package copilot
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+const (
+	ollamaEndpoint = "http://localhost:11434"
+	ollamaModel    = "llama3.1"
+)
+
+// ollama sends prompts to a local Ollama server
+type ollama struct {
+	client *Client
+	url    string
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message chatMessage `json:"message"`
+}
+
+// newOllama verifies that the server is up, so a stopped server is
+// reported before any log is fetched
+func newOllama(c *Client) (*ollama, error) {
+	url := endpoint(c.config.Model().Endpoint, ollamaEndpoint)
+	probe := &http.Client{Timeout: 5 * time.Second}
+	resp, err := probe.Get(url + "/api/version")
+	if err != nil {
+		return nil, errors.AIProviderError("new_ollama", "Ollama", fmt.Errorf("server not reachable at %s - start it with: ollama serve", url))
+	}
+	resp.Body.Close()
+	return &ollama{client: c, url: url}, nil
+}
+
+func (p *ollama) name() string {
+	return "Ollama"
+}
+
+func (p *ollama) complete(prompt string) (string, error) {
+	settings := p.client.config.Model()
+	model := settings.Model
+	if model == "" {
+		model = ollamaModel
+	}
+	if settings.ReasoningEffort != "" {
+		p.client.logger.Debug("Ollama ignores the reasoning effort setting")
+	}
+
+	var resp ollamaResponse
+	err := p.client.postJSON(p.url+"/api/chat", nil, ollamaRequest{
+		Model:    model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+		Options: ollamaOptions{
+			Temperature: settings.Temperature,
+			NumPredict:  settings.MaxTokens,
+		},
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
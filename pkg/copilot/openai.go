@@ -0,0 +1,69 @@
+package copilot
+
+import (
+	"fmt"
+)
+
+const (
+	openAIEndpoint = "https://api.openai.com/v1"
+	openAIModel    = "gpt-4o"
+)
+
+// openAI sends prompts to the OpenAI chat completions API. The key is read
+// from OPENAI_API_KEY; set an endpoint to use a compatible server.
+type openAI struct {
+	client *Client
+	key    string
+}
+
+type openAIRequest struct {
+	Model               string        `json:"model"`
+	Messages            []chatMessage `json:"messages"`
+	Temperature         float64       `json:"temperature,omitempty"`
+	MaxCompletionTokens int           `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string        `json:"reasoning_effort,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func newOpenAI(c *Client) (*openAI, error) {
+	key, err := apiKey("new_openai", "OPENAI_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	return &openAI{client: c, key: key}, nil
+}
+
+func (p *openAI) name() string {
+	return "OpenAI"
+}
+
+func (p *openAI) complete(prompt string) (string, error) {
+	settings := p.client.config.Model()
+	model := settings.Model
+	if model == "" {
+		model = openAIModel
+	}
+
+	var resp openAIResponse
+	err := p.client.postJSON(endpoint(settings.Endpoint, openAIEndpoint)+"/chat/completions",
+		map[string]string{"Authorization": "Bearer " + p.key},
+		openAIRequest{
+			Model:               model,
+			Messages:            []chatMessage{{Role: "user", Content: prompt}},
+			Temperature:         settings.Temperature,
+			MaxCompletionTokens: settings.MaxTokens,
+			ReasoningEffort:     settings.ReasoningEffort,
+		}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("response has no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,301 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/analyzer"
+	"gh-sentinel/pkg/copilot"
+	"gh-sentinel/pkg/fixer"
+	"gh-sentinel/pkg/patcher"
+)
+
+// Corpus file names inside each case directory
+const (
+	logsFile     = "logs.txt"
+	workflowFile = "workflow.yml"
+	expectedFile = "expected.yml"
+)
+
+// Case is one recorded failure in the evaluation corpus
+type Case struct {
+	Name     string
+	Logs     string
+	Workflow string
+	Expected string // Known-good fix; empty when only validity is measured
+}
+
+// Outcome records how one fixer performed on a case
+type Outcome struct {
+	Produced bool
+	Valid    bool
+	Accurate bool
+	Err      string
+}
+
+// CaseResult compares both fixers on a single case
+type CaseResult struct {
+	Case     string
+	Category string
+	Tag      string // Failure category of the taxonomy; "unclassified" if none
+	Labeled  bool
+	Rules    Outcome
+	AI       Outcome
+}
+
+// Stats aggregates outcomes
+type Stats struct {
+	Produced int
+	Valid    int
+	Accurate int
+}
+
+// CategoryStats aggregates results for one analyzer category or failure
+// tag
+type CategoryStats struct {
+	Category string
+	Cases    int
+	Labeled  int // Cases with an expected fix
+	Rules    Stats
+	AI       Stats
+}
+
+// Report is the outcome of an evaluation run
+type Report struct {
+	Results    []CaseResult
+	Categories []CategoryStats
+	Tags       []CategoryStats
+}
+
+// Harness runs the deterministic fixer and the AI over a corpus
+type Harness struct {
+	analyzer   *analyzer.Analyzer
+	fixer      *fixer.Fixer
+	copilot    copilot.Diagnoser // nil evaluates the deterministic fixer only
+	patcher    *patcher.Patcher
+	logger     *logger.Logger
+	scratchDir string
+}
+
+// NewHarness creates an evaluation harness writing scratch copies to scratchDir
+func NewHarness(a *analyzer.Analyzer, f *fixer.Fixer, c copilot.Diagnoser, p *patcher.Patcher, log *logger.Logger, scratchDir string) *Harness {
+	return &Harness{
+		analyzer:   a,
+		fixer:      f,
+		copilot:    c,
+		patcher:    p,
+		logger:     log,
+		scratchDir: scratchDir,
+	}
+}
+
+// LoadCorpus reads every case directory under dir
+func LoadCorpus(dir string) ([]Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.FilesystemError("load_corpus", dir, err)
+	}
+
+	var cases []Case
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c, err := LoadCase(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+
+	if len(cases) == 0 {
+		return nil, errors.ValidationError("load_corpus", "corpus contains no case directories").WithPath(dir)
+	}
+	return cases, nil
+}
+
+// LoadCase reads one case directory, named after it
+func LoadCase(caseDir string) (Case, error) {
+	logs, err := os.ReadFile(filepath.Join(caseDir, logsFile))
+	if err != nil {
+		return Case{}, errors.FilesystemError("load_corpus", filepath.Join(caseDir, logsFile), err)
+	}
+	workflow, err := os.ReadFile(filepath.Join(caseDir, workflowFile))
+	if err != nil {
+		return Case{}, errors.FilesystemError("load_corpus", filepath.Join(caseDir, workflowFile), err)
+	}
+	expected, err := os.ReadFile(filepath.Join(caseDir, expectedFile))
+	if err != nil && !os.IsNotExist(err) {
+		return Case{}, errors.FilesystemError("load_corpus", filepath.Join(caseDir, expectedFile), err)
+	}
+
+	return Case{
+		Name:     filepath.Base(caseDir),
+		Logs:     string(logs),
+		Workflow: string(workflow),
+		Expected: string(expected),
+	}, nil
+}
+
+// Run evaluates every case and aggregates the results per category
+func (h *Harness) Run(cases []Case) *Report {
+	report := &Report{}
+	for _, c := range cases {
+		report.Results = append(report.Results, h.runCase(c))
+	}
+	report.Categories = aggregate(report.Results, func(r CaseResult) string { return r.Category })
+	report.Tags = aggregate(report.Results, func(r CaseResult) string { return r.Tag })
+	return report
+}
+
+// runCase evaluates both fixers on one case
+func (h *Harness) runCase(c Case) CaseResult {
+	h.logger.Info("Evaluating case %s", c.Name)
+
+	analysis := h.analyzer.AnalyzeLogs(c.Logs)
+	result := CaseResult{
+		Case:     c.Name,
+		Category: analysis.Category,
+		Tag:      analysis.Tag,
+		Labeled:  c.Expected != "",
+	}
+	if result.Category == "" {
+		result.Category = "uncategorized"
+	}
+	if result.Tag == "" {
+		result.Tag = "unclassified"
+	}
+
+	if fix := h.fixer.Fix(analysis, c.Logs, c.Workflow); fix != nil {
+		result.Rules = h.check(c, "rules", fix.FixedContent)
+	}
+
+	if h.copilot != nil {
+		diagnosis, err := h.copilot.DiagnoseAndFix(&copilot.DiagnosisRequest{
+			ErrorLogs:      c.Logs,
+			CurrentFile:    workflowFile,
+			FileContent:    c.Workflow,
+			AvailableFiles: []string{workflowFile},
+			WorkflowPath:   workflowFile,
+			RunnerOS:       analysis.RunnerOS,
+			KeyLines:       analysis.MatchedLines(),
+		})
+		if err != nil {
+			result.AI.Err = err.Error()
+		} else {
+			result.AI = h.check(c, "ai", diagnosis.FixedContent)
+		}
+	}
+
+	return result
+}
+
+// check applies a proposed fix to a scratch copy of the case workflow,
+// validates the patched file against the schema and with actionlint, and
+// compares it to the expected fix
+func (h *Harness) check(c Case, source, fixed string) Outcome {
+	outcome := Outcome{Produced: fixed != ""}
+	if !outcome.Produced {
+		return outcome
+	}
+
+	dir := filepath.Join(h.scratchDir, c.Name, source)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		outcome.Err = err.Error()
+		return outcome
+	}
+	path := filepath.Join(dir, workflowFile)
+	if err := os.WriteFile(path, []byte(c.Workflow), 0644); err != nil {
+		outcome.Err = err.Error()
+		return outcome
+	}
+
+	if _, err := h.patcher.Apply(&patcher.PatchRequest{
+		FilePath:   path,
+		NewContent: fixed,
+		Validate:   true,
+	}); err != nil {
+		outcome.Err = err.Error()
+		return outcome
+	}
+	if issues := patcher.ValidateWorkflowSchema(fixed); len(issues) > 0 {
+		outcome.Err = issues[0].String()
+		return outcome
+	}
+
+	// A fix that parses can still reference undefined expressions, jobs or
+	// runner labels, which actionlint finds in the patched file
+	patched, err := os.ReadFile(path)
+	if err != nil {
+		outcome.Err = err.Error()
+		return outcome
+	}
+	issues, err := patcher.Lint(path, string(patched))
+	if err != nil {
+		outcome.Err = err.Error()
+		return outcome
+	}
+	if len(issues) > 0 {
+		outcome.Err = issues[0].String()
+		return outcome
+	}
+	outcome.Valid = true
+
+	if c.Expected != "" {
+		outcome.Accurate = normalize(fixed) == normalize(c.Expected)
+	}
+	return outcome
+}
+
+// aggregate groups case results by their category or tag, sorted by name
+func aggregate(results []CaseResult, key func(CaseResult) string) []CategoryStats {
+	byCategory := make(map[string]*CategoryStats)
+	for _, r := range results {
+		stats, ok := byCategory[key(r)]
+		if !ok {
+			stats = &CategoryStats{Category: key(r)}
+			byCategory[key(r)] = stats
+		}
+		stats.Cases++
+		if r.Labeled {
+			stats.Labeled++
+		}
+		stats.Rules.add(r.Rules)
+		stats.AI.add(r.AI)
+	}
+
+	var categories []CategoryStats
+	for _, stats := range byCategory {
+		categories = append(categories, *stats)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Category < categories[j].Category
+	})
+	return categories
+}
+
+func (s *Stats) add(o Outcome) {
+	if o.Produced {
+		s.Produced++
+	}
+	if o.Valid {
+		s.Valid++
+	}
+	if o.Accurate {
+		s.Accurate++
+	}
+}
+
+// normalize makes content comparable across line endings and trailing space
+func normalize(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
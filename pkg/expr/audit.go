@@ -0,0 +1,266 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/pkg/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Finding is an expression problem in a workflow
+type Finding struct {
+	Line       int
+	Severity   string
+	Expression string
+	Message    string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s", f.Line, f.Message)
+}
+
+// Condition is the if: condition of a job or step
+type Condition struct {
+	Job        string
+	JobName    string // The job's name:, or its ID
+	Step       string // Step name or 1-based position, "" for job conditions
+	Expression string // Without the ${{ }} wrapper
+	Line       int
+}
+
+// Audit checks every ${{ }} expression and if: condition in a workflow:
+// syntax, context and function names, github.event fields for the
+// workflow's triggers, and conditions that are always true or false
+func Audit(path, content string) ([]Finding, error) {
+	root, err := parseWorkflow(path, content)
+	if err != nil {
+		return nil, err
+	}
+	ctx := CheckContext{Events: Triggers(root)}
+
+	var findings []Finding
+	var visit func(node *yaml.Node, key string)
+	visit = func(node *yaml.Node, key string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				visit(node.Content[i+1], node.Content[i].Value)
+			}
+		case yaml.SequenceNode:
+			for _, child := range node.Content {
+				visit(child, "")
+			}
+		case yaml.ScalarNode:
+			if key == "if" {
+				findings = append(findings, auditCondition(node, ctx)...)
+			} else if strings.Contains(node.Value, "${{") {
+				findings = append(findings, auditTemplate(node, ctx)...)
+			}
+		}
+	}
+	visit(root, "")
+	return findings, nil
+}
+
+// auditTemplate checks the expressions embedded in a string value
+func auditTemplate(node *yaml.Node, ctx CheckContext) []Finding {
+	var findings []Finding
+	for _, e := range extract(node.Value) {
+		line := workflow.ScalarLine(node, e.offset)
+		if e.unterminated {
+			findings = append(findings, Finding{line, SeverityError, e.body, "${{ is not closed by }}"})
+			continue
+		}
+		tree, err := Parse(e.body)
+		if err != nil {
+			findings = append(findings, Finding{line, SeverityError, e.body, fmt.Sprintf("invalid expression ${{ %s }}: %v", e.body, err)})
+			continue
+		}
+		for _, issue := range Check(tree, ctx) {
+			findings = append(findings, Finding{line, issue.Severity, e.body, issue.Message})
+		}
+	}
+	return findings
+}
+
+// auditCondition checks an if: condition and evaluates it for each trigger
+func auditCondition(node *yaml.Node, ctx CheckContext) []Finding {
+	line := workflow.ScalarLine(node, 0)
+	if exprs := extract(node.Value); len(exprs) > 0 && exprs[len(exprs)-1].unterminated {
+		return []Finding{{line, SeverityError, node.Value, "${{ is not closed by }}"}}
+	}
+	body, ok := conditionBody(node.Value)
+	if !ok {
+		return []Finding{{line, SeverityError, node.Value,
+			"the condition mixes ${{ }} with other text, so it is a non-empty string and always true; put the whole condition inside ${{ }}"}}
+	}
+
+	tree, err := Parse(body)
+	if err != nil {
+		return []Finding{{line, SeverityError, body, fmt.Sprintf("invalid condition %q: %v", body, err)}}
+	}
+
+	var findings []Finding
+	for _, issue := range Check(tree, ctx) {
+		findings = append(findings, Finding{line, issue.Severity, body, issue.Message})
+	}
+
+	if lit, ok := tree.(*Literal); ok {
+		if s, isString := lit.Value.(string); isString && s != "" {
+			return append(findings, Finding{line, SeverityError, body,
+				fmt.Sprintf("the condition is the string %q, which is always true", s)})
+		}
+		if !truthy(lit.Value) {
+			return append(findings, Finding{line, SeverityWarning, body, "the condition is always false, so this never runs"})
+		}
+		return findings
+	}
+
+	// Evaluate with only the event name known; a condition false for every
+	// trigger never runs. Issues found above already explain why.
+	if len(ctx.Events) > 0 && len(findings) == 0 {
+		for _, event := range ctx.Events {
+			if Truth(tree, EventScope(event)) != TriFalse {
+				return findings
+			}
+		}
+		findings = append(findings, Finding{line, SeverityError, body,
+			fmt.Sprintf("the condition is false for every trigger (%s), so this never runs", strings.Join(ctx.Events, ", "))})
+	}
+	return findings
+}
+
+// Conditions returns the if: conditions of every job and step
+func Conditions(path, content string) ([]Condition, error) {
+	root, err := parseWorkflow(path, content)
+	if err != nil {
+		return nil, err
+	}
+	jobs := workflow.Value(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var conditions []Condition
+	add := func(job, jobName, step string, node *yaml.Node) {
+		if node == nil || node.Kind != yaml.ScalarNode {
+			return
+		}
+		body, ok := conditionBody(node.Value)
+		if !ok {
+			body = node.Value
+		}
+		conditions = append(conditions, Condition{job, jobName, step, body, workflow.ScalarLine(node, 0)})
+	}
+
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i].Value, jobs.Content[i+1]
+		name := id
+		if v := workflow.Value(job, "name"); v != nil && v.Value != "" {
+			name = v.Value
+		}
+		add(id, name, "", workflow.Value(job, "if"))
+		steps := workflow.Value(job, "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for n, step := range steps.Content {
+			stepName := fmt.Sprint(n + 1)
+			if v := workflow.Value(step, "name"); v != nil && v.Value != "" {
+				stepName = v.Value
+			}
+			add(id, name, stepName, workflow.Value(step, "if"))
+		}
+	}
+	return conditions, nil
+}
+
+// EventScope is the scope of a run where only the event name is known
+func EventScope(event string) Scope {
+	return Scope{"github": map[string]Value{"event_name": event}}
+}
+
+// Triggers returns the events in a workflow's on: key
+func Triggers(root *yaml.Node) []string {
+	on := workflow.Value(root, "on")
+	if on == nil {
+		return nil
+	}
+	var events []string
+	switch on.Kind {
+	case yaml.ScalarNode:
+		events = append(events, on.Value)
+	case yaml.SequenceNode:
+		for _, item := range on.Content {
+			events = append(events, item.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(on.Content); i += 2 {
+			events = append(events, on.Content[i].Value)
+		}
+	}
+	return events
+}
+
+// conditionBody strips the optional ${{ }} around a condition. It fails
+// when the value mixes expressions with other text.
+func conditionBody(value string) (string, bool) {
+	trimmed := strings.TrimSpace(value)
+	if !strings.Contains(trimmed, "${{") {
+		return trimmed, true
+	}
+	exprs := extract(trimmed)
+	if len(exprs) != 1 || exprs[0].unterminated || exprs[0].offset != 0 || exprs[0].end != len(trimmed) {
+		return "", false
+	}
+	return exprs[0].body, true
+}
+
+type embedded struct {
+	body         string
+	offset, end  int // Byte offsets of ${{ and the end of }}
+	unterminated bool
+}
+
+// extract finds the ${{ }} expressions in a string, skipping }} inside
+// string literals
+func extract(s string) []embedded {
+	var exprs []embedded
+	for start := 0; ; {
+		i := strings.Index(s[start:], "${{")
+		if i < 0 {
+			return exprs
+		}
+		open := start + i
+		inString := false
+		j := open + 3
+		for ; j < len(s); j++ {
+			if s[j] == '\'' {
+				inString = !inString
+			}
+			if !inString && strings.HasPrefix(s[j:], "}}") {
+				break
+			}
+		}
+		if j >= len(s) {
+			exprs = append(exprs, embedded{strings.TrimSpace(s[open+3:]), open, len(s), true})
+			return exprs
+		}
+		exprs = append(exprs, embedded{strings.TrimSpace(s[open+3 : j]), open, j + 2, false})
+		start = j + 2
+	}
+}
+
+func parseWorkflow(path, content string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "audit_expressions", "invalid YAML", err).WithPath(path)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.ValidationError("audit_expressions", "workflow is not a mapping").WithPath(path)
+	}
+	return doc.Content[0], nil
+}
@@ -0,0 +1,296 @@
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found in an expression
+type Issue struct {
+	Severity string
+	Message  string
+}
+
+// CheckContext describes where an expression appears
+type CheckContext struct {
+	Events []string // Events that trigger the workflow
+}
+
+// contexts lists the named contexts available in workflows
+var contexts = map[string]bool{
+	"github": true, "env": true, "vars": true, "job": true, "jobs": true,
+	"steps": true, "runner": true, "secrets": true, "strategy": true,
+	"matrix": true, "needs": true, "inputs": true,
+}
+
+// functions maps built-in functions to their minimum and maximum arity
+var functions = map[string][2]int{
+	"contains":   {2, 2},
+	"startswith": {2, 2},
+	"endswith":   {2, 2},
+	"format":     {1, 255},
+	"join":       {1, 2},
+	"tojson":     {1, 1},
+	"fromjson":   {1, 1},
+	"hashfiles":  {1, 255},
+	"success":    {0, 0},
+	"always":     {0, 0},
+	"cancelled":  {0, 0},
+	"failure":    {0, 1},
+}
+
+// githubProperties are the properties of the github context
+var githubProperties = setOf(
+	"action", "action_path", "action_ref", "action_repository", "action_status",
+	"actor", "actor_id", "api_url", "base_ref", "env", "event", "event_name",
+	"event_path", "graphql_url", "head_ref", "job", "job_workflow_sha", "path",
+	"ref", "ref_name", "ref_protected", "ref_type", "repository", "repository_id",
+	"repository_owner", "repository_owner_id", "repositoryurl", "retention_days",
+	"run_id", "run_number", "run_attempt", "secret_source", "server_url", "sha",
+	"token", "triggering_actor", "workflow", "workflow_ref", "workflow_sha",
+	"workspace",
+)
+
+// runnerProperties are the properties of the runner context
+var runnerProperties = setOf("name", "os", "arch", "temp", "tool_cache", "debug", "environment")
+
+// runnerOSValues are the values runner.os takes
+var runnerOSValues = setOf("linux", "windows", "macos")
+
+// commonPayload is present in every webhook payload
+var commonPayload = []string{"action", "repository", "sender", "organization", "installation", "enterprise"}
+
+// eventPayloads lists the top-level github.event fields of common triggers
+var eventPayloads = map[string][]string{
+	"push":                        {"ref", "before", "after", "base_ref", "commits", "compare", "created", "deleted", "forced", "head_commit", "pusher"},
+	"pull_request":                {"number", "pull_request", "assignee", "label", "requested_reviewer", "requested_team", "changes", "reason"},
+	"pull_request_target":         {"number", "pull_request", "assignee", "label", "requested_reviewer", "requested_team", "changes", "reason"},
+	"pull_request_review":         {"review", "pull_request", "changes"},
+	"pull_request_review_comment": {"comment", "pull_request", "changes"},
+	"workflow_dispatch":           {"inputs", "ref", "workflow"},
+	"workflow_call":               {"inputs"},
+	"repository_dispatch":         {"client_payload", "branch"},
+	"schedule":                    {"schedule"},
+	"release":                     {"release", "changes"},
+	"issues":                      {"issue", "assignee", "label", "milestone", "changes"},
+	"issue_comment":               {"issue", "comment", "changes"},
+	"workflow_run":                {"workflow_run", "workflow"},
+	"merge_group":                 {"merge_group"},
+	"create":                      {"ref", "ref_type", "master_branch", "description", "pusher_type"},
+	"delete":                      {"ref", "ref_type", "pusher_type"},
+	"deployment":                  {"deployment", "workflow", "workflow_run"},
+	"deployment_status":           {"deployment", "deployment_status", "check_run", "workflow", "workflow_run"},
+	"check_run":                   {"check_run", "requested_action"},
+	"check_suite":                 {"check_suite"},
+	"discussion":                  {"discussion", "answer", "changes", "label"},
+	"discussion_comment":          {"comment", "discussion"},
+	"label":                       {"label", "changes"},
+	"milestone":                   {"milestone", "changes"},
+	"page_build":                  {"build", "id"},
+	"public":                      {},
+	"registry_package":            {"registry_package"},
+	"status":                      {"sha", "state", "description", "target_url", "branches", "commit", "context", "id", "name", "created_at", "updated_at", "avatar_url"},
+	"watch":                       {},
+	"fork":                        {"forkee"},
+	"gollum":                      {"pages"},
+	"branch_protection_rule":      {"rule", "changes"},
+}
+
+// KnownEvent reports whether name is a workflow trigger
+func KnownEvent(name string) bool {
+	_, ok := eventPayloads[name]
+	return ok
+}
+
+func setOf(items ...string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// Check statically checks an expression for unknown contexts, functions
+// and properties, github.event fields the triggers do not provide, and
+// comparisons that can never be true
+func Check(node Node, ctx CheckContext) []Issue {
+	var issues []Issue
+	add := func(severity, format string, args ...interface{}) {
+		issues = append(issues, Issue{severity, fmt.Sprintf(format, args...)})
+	}
+
+	walk(node, func(n Node) {
+		switch x := n.(type) {
+		case *Context:
+			if !contexts[x.Name] {
+				add(SeverityError, "unknown context %q", x.Name)
+			}
+		case *Call:
+			arity, ok := functions[strings.ToLower(x.Name)]
+			switch {
+			case !ok:
+				add(SeverityError, "unknown function %s()", x.Name)
+			case len(x.Args) < arity[0] || len(x.Args) > arity[1]:
+				add(SeverityError, "%s() takes %s, got %d", x.Name, arityText(arity), len(x.Args))
+			}
+		case *Property:
+			checkProperty(x, ctx, add)
+		case *Binary:
+			checkComparison(x, ctx, add)
+		}
+	})
+	return issues
+}
+
+// checkProperty flags unknown github and runner properties and github.event
+// fields absent from every trigger's payload
+func checkProperty(p *Property, ctx CheckContext, add func(string, string, ...interface{})) {
+	root, ok := p.Object.(*Context)
+	if ok {
+		name := strings.ToLower(p.Name)
+		switch root.Name {
+		case "github":
+			if !githubProperties[name] {
+				add(SeverityWarning, "github.%s is not a property of the github context", p.Name)
+			}
+		case "runner":
+			if !runnerProperties[name] {
+				add(SeverityWarning, "runner.%s is not a property of the runner context", p.Name)
+			}
+		}
+		return
+	}
+
+	event, ok := p.Object.(*Property)
+	if !ok || event.Name != "event" {
+		return
+	}
+	if root, ok := event.Object.(*Context); !ok || root.Name != "github" || len(ctx.Events) == 0 {
+		return
+	}
+
+	available := setOf(commonPayload...)
+	for _, ev := range ctx.Events {
+		fields, known := eventPayloads[ev]
+		if !known {
+			return // Unknown payloads are not checked
+		}
+		for _, field := range fields {
+			available[field] = true
+		}
+	}
+	if !available[p.Name] {
+		add(SeverityWarning, "github.event.%s does not exist in the payload of %s events", p.Name, strings.Join(ctx.Events, "/"))
+	}
+}
+
+// checkComparison flags equality comparisons that can never be true
+func checkComparison(b *Binary, ctx CheckContext, add func(string, string, ...interface{})) {
+	if b.Op != "==" && b.Op != "!=" {
+		return
+	}
+	outcome := "never true"
+	if b.Op == "!=" {
+		outcome = "always true"
+	}
+
+	subject, lit := b.Left, literalOf(b.Right)
+	if lit == nil {
+		subject, lit = b.Right, literalOf(b.Left)
+	}
+	if lit == nil {
+		return
+	}
+	path := subject.String()
+
+	switch value := lit.Value.(type) {
+	case bool:
+		if strings.HasPrefix(path, "github.event.inputs.") {
+			add(SeverityError, "%s is %s: github.event.inputs values are strings, so compare with '%t' or use inputs.%s",
+				b, outcome, value, strings.TrimPrefix(path, "github.event.inputs."))
+		}
+	case string:
+		switch path {
+		case "github.ref":
+			if !strings.HasPrefix(value, "refs/") {
+				add(SeverityError, "%s is %s: github.ref is a full ref like refs/heads/%s; use github.ref_name for the short name", b, outcome, value)
+			}
+		case "github.event_name":
+			switch {
+			case !KnownEvent(value):
+				add(SeverityError, "%s is %s: %q is not an event name", b, outcome, value)
+			case len(ctx.Events) > 0 && !contains(ctx.Events, value):
+				add(SeverityWarning, "%s is %s: the workflow is not triggered by %s", b, outcome, value)
+			}
+		case "runner.os":
+			if !runnerOSValues[strings.ToLower(value)] {
+				add(SeverityError, "%s is %s: runner.os is Linux, Windows or macOS", b, outcome)
+			}
+		}
+	}
+}
+
+func literalOf(n Node) *Literal {
+	lit, _ := n.(*Literal)
+	return lit
+}
+
+func arityText(arity [2]int) string {
+	switch {
+	case arity[0] == arity[1] && arity[0] == 1:
+		return "1 argument"
+	case arity[0] == arity[1]:
+		return fmt.Sprintf("%d arguments", arity[0])
+	case arity[1] == 255:
+		return fmt.Sprintf("at least %d arguments", arity[0])
+	}
+	return fmt.Sprintf("%d to %d arguments", arity[0], arity[1])
+}
+
+// walk visits every node of an expression, parents first
+func walk(n Node, visit func(Node)) {
+	visit(n)
+	switch x := n.(type) {
+	case *Property:
+		walk(x.Object, visit)
+	case *Index:
+		walk(x.Object, visit)
+		walk(x.Index, visit)
+	case *Filter:
+		walk(x.Object, visit)
+	case *Call:
+		for _, arg := range x.Args {
+			walk(arg, visit)
+		}
+	case *Unary:
+		walk(x.X, visit)
+	case *Binary:
+		walk(x.Left, visit)
+		walk(x.Right, visit)
+	}
+}
+
+// References returns the context paths an expression reads, sorted
+func References(n Node) []string {
+	set := make(map[string]bool)
+	walk(n, func(node Node) {
+		if p, ok := node.(*Property); ok {
+			if _, root := p.Object.(*Context); root {
+				set[p.String()] = true
+			}
+		}
+	})
+	refs := make([]string, 0, len(set))
+	for ref := range set {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
@@ -0,0 +1,439 @@
+package expr
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value is an expression value: nil, bool, float64, string,
+// map[string]Value, []Value or Unknown
+type Value interface{}
+
+// Unknown is a value that cannot be determined statically
+type Unknown struct{}
+
+// Tri is a truth value that may be unknown
+type Tri int
+
+const (
+	TriUnknown Tri = iota
+	TriFalse
+	TriTrue
+)
+
+// Scope holds the context values known when evaluating. Missing contexts
+// and missing keys of known objects evaluate to Unknown.
+type Scope map[string]Value
+
+// Eval evaluates an expression with GitHub Actions semantics. Status
+// functions other than always() evaluate to Unknown.
+func Eval(node Node, scope Scope) Value {
+	switch n := node.(type) {
+	case *Literal:
+		return n.Value
+	case *Context:
+		if v, ok := lookup(scope, n.Name); ok {
+			return v
+		}
+		return Unknown{}
+	case *Property:
+		return property(Eval(n.Object, scope), n.Name)
+	case *Index:
+		index := Eval(n.Index, scope)
+		if _, unknown := index.(Unknown); unknown {
+			return Unknown{}
+		}
+		obj := Eval(n.Object, scope)
+		if arr, ok := obj.([]Value); ok {
+			i := toNumber(index)
+			if i != math.Trunc(i) || i < 0 || int(i) >= len(arr) {
+				return nil
+			}
+			return arr[int(i)]
+		}
+		return property(obj, toString(index))
+	case *Filter:
+		return Unknown{}
+	case *Unary:
+		return triValue(Truth(n.X, scope).Not())
+	case *Binary:
+		return evalBinary(n, scope)
+	case *Call:
+		return evalCall(n, scope)
+	}
+	return Unknown{}
+}
+
+// Truth evaluates an expression as a condition
+func Truth(node Node, scope Scope) Tri {
+	switch n := node.(type) {
+	case *Unary:
+		return Truth(n.X, scope).Not()
+	case *Binary:
+		switch n.Op {
+		case "&&":
+			left := Truth(n.Left, scope)
+			if left == TriFalse {
+				return TriFalse
+			}
+			right := Truth(n.Right, scope)
+			if left == TriTrue || right == TriFalse {
+				return right
+			}
+			return TriUnknown
+		case "||":
+			left := Truth(n.Left, scope)
+			if left == TriTrue {
+				return TriTrue
+			}
+			right := Truth(n.Right, scope)
+			if left == TriFalse || right == TriTrue {
+				return right
+			}
+			return TriUnknown
+		}
+	}
+	return truthOf(Eval(node, scope))
+}
+
+// Not negates a truth value
+func (t Tri) Not() Tri {
+	switch t {
+	case TriTrue:
+		return TriFalse
+	case TriFalse:
+		return TriTrue
+	}
+	return TriUnknown
+}
+
+func triValue(t Tri) Value {
+	switch t {
+	case TriTrue:
+		return true
+	case TriFalse:
+		return false
+	}
+	return Unknown{}
+}
+
+func truthOf(v Value) Tri {
+	if _, unknown := v.(Unknown); unknown {
+		return TriUnknown
+	}
+	if truthy(v) {
+		return TriTrue
+	}
+	return TriFalse
+}
+
+// truthy applies the falsy rules: false, 0, -0, "", null and NaN
+func truthy(v Value) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0 && !math.IsNaN(x)
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+func evalBinary(n *Binary, scope Scope) Value {
+	switch n.Op {
+	case "&&":
+		left := Eval(n.Left, scope)
+		if t := truthOf(left); t == TriFalse {
+			return left
+		} else if t == TriUnknown {
+			return Unknown{}
+		}
+		return Eval(n.Right, scope)
+	case "||":
+		left := Eval(n.Left, scope)
+		if t := truthOf(left); t == TriTrue {
+			return left
+		} else if t == TriUnknown {
+			return Unknown{}
+		}
+		return Eval(n.Right, scope)
+	}
+
+	left, right := Eval(n.Left, scope), Eval(n.Right, scope)
+	if isUnknown(left) || isUnknown(right) {
+		return Unknown{}
+	}
+	switch n.Op {
+	case "==":
+		return looseEqual(left, right)
+	case "!=":
+		return !looseEqual(left, right)
+	}
+
+	// Relational operators compare strings case-insensitively and
+	// everything else as numbers
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		c := strings.Compare(strings.ToLower(ls), strings.ToLower(rs))
+		return compareResult(n.Op, float64(c), 0)
+	}
+	return compareResult(n.Op, toNumber(left), toNumber(right))
+}
+
+func compareResult(op string, a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return false
+	}
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// looseEqual implements ==: values of different types are compared as
+// numbers, strings ignore case
+func looseEqual(a, b Value) bool {
+	switch x := a.(type) {
+	case nil:
+		if b == nil {
+			return true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return x == y
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x == y
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.EqualFold(x, y)
+		}
+	case map[string]Value, []Value:
+		// Objects and arrays are only equal to themselves
+		return false
+	}
+	if isComposite(a) || isComposite(b) {
+		return false
+	}
+	x, y := toNumber(a), toNumber(b)
+	return !math.IsNaN(x) && !math.IsNaN(y) && x == y
+}
+
+func isComposite(v Value) bool {
+	switch v.(type) {
+	case map[string]Value, []Value:
+		return true
+	}
+	return false
+}
+
+func isUnknown(v Value) bool {
+	_, unknown := v.(Unknown)
+	return unknown
+}
+
+// toNumber converts a value the way comparisons do
+func toNumber(v Value) float64 {
+	switch x := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	case float64:
+		return x
+	case string:
+		s := strings.TrimSpace(x)
+		if s == "" {
+			return 0
+		}
+		if n, err := parseNumber(s); err == nil {
+			return n
+		}
+	}
+	return math.NaN()
+}
+
+// toString converts a value the way format() and string functions do
+func toString(v Value) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	}
+	return formatValue(v)
+}
+
+func formatValue(v Value) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		return x
+	case Unknown:
+		return "<unknown>"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "<object>"
+	}
+	return string(data)
+}
+
+// lookup finds a key case-insensitively
+func lookup(obj map[string]Value, key string) (Value, bool) {
+	if v, ok := obj[key]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func property(obj Value, name string) Value {
+	switch o := obj.(type) {
+	case Unknown:
+		return Unknown{}
+	case map[string]Value:
+		if v, ok := lookup(o, name); ok {
+			return v
+		}
+		return Unknown{}
+	}
+	// Properties of anything else are null
+	return nil
+}
+
+func evalCall(n *Call, scope Scope) Value {
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = Eval(arg, scope)
+	}
+
+	switch strings.ToLower(n.Name) {
+	case "always":
+		return true
+	case "success", "failure", "cancelled", "hashfiles":
+		return Unknown{}
+	}
+	for _, arg := range args {
+		if isUnknown(arg) {
+			return Unknown{}
+		}
+	}
+
+	switch strings.ToLower(n.Name) {
+	case "contains":
+		if len(args) != 2 {
+			return Unknown{}
+		}
+		if arr, ok := args[0].([]Value); ok {
+			for _, item := range arr {
+				if looseEqual(item, args[1]) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(strings.ToLower(toString(args[0])), strings.ToLower(toString(args[1])))
+	case "startswith":
+		if len(args) != 2 {
+			return Unknown{}
+		}
+		return strings.HasPrefix(strings.ToLower(toString(args[0])), strings.ToLower(toString(args[1])))
+	case "endswith":
+		if len(args) != 2 {
+			return Unknown{}
+		}
+		return strings.HasSuffix(strings.ToLower(toString(args[0])), strings.ToLower(toString(args[1])))
+	case "format":
+		if len(args) == 0 {
+			return Unknown{}
+		}
+		out := toString(args[0])
+		for i, arg := range args[1:] {
+			out = strings.ReplaceAll(out, "{"+strconv.Itoa(i)+"}", toString(arg))
+		}
+		return out
+	case "join":
+		if len(args) == 0 {
+			return Unknown{}
+		}
+		sep := ","
+		if len(args) > 1 {
+			sep = toString(args[1])
+		}
+		arr, ok := args[0].([]Value)
+		if !ok {
+			return toString(args[0])
+		}
+		parts := make([]string, len(arr))
+		for i, item := range arr {
+			parts[i] = toString(item)
+		}
+		return strings.Join(parts, sep)
+	case "tojson":
+		if len(args) != 1 {
+			return Unknown{}
+		}
+		data, err := json.Marshal(args[0])
+		if err != nil {
+			return Unknown{}
+		}
+		return string(data)
+	case "fromjson":
+		if len(args) != 1 {
+			return Unknown{}
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(toString(args[0])), &v); err != nil {
+			return Unknown{}
+		}
+		return fromJSON(v)
+	}
+	return Unknown{}
+}
+
+// fromJSON converts decoded JSON into expression values
+func fromJSON(v interface{}) Value {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]Value, len(x))
+		for k, item := range x {
+			obj[k] = fromJSON(item)
+		}
+		return obj
+	case []interface{}:
+		arr := make([]Value, len(x))
+		for i, item := range x {
+			arr[i] = fromJSON(item)
+		}
+		return arr
+	}
+	return v
+}
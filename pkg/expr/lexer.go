@@ -0,0 +1,104 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOperator // ! == != < <= > >= && || *
+	tokPunct    // . [ ] ( ) ,
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// lex splits an expression into tokens
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j >= len(source) {
+					return nil, &SyntaxError{i, "unterminated string"}
+				}
+				if source[j] == '\'' {
+					// '' is an escaped quote
+					if j+1 < len(source) && source[j+1] == '\'' {
+						b.WriteByte('\'')
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(source[j])
+				j++
+			}
+			tokens = append(tokens, token{tokString, b.String(), i})
+			i = j + 1
+
+		case c == '"':
+			return nil, &SyntaxError{i, "strings must use single quotes"}
+
+		case c >= '0' && c <= '9' || (c == '-' && i+1 < len(source) && source[i+1] >= '0' && source[i+1] <= '9'):
+			j := i + 1
+			for j < len(source) && (isIdentChar(source[j]) || source[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokNumber, source[i:j], i})
+			i = j
+
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(source) && (isIdentChar(source[j]) || source[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, source[i:j], i})
+			i = j
+
+		case strings.ContainsRune(".[](),", rune(c)):
+			tokens = append(tokens, token{tokPunct, string(c), i})
+			i++
+
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "!", "<", ">", "*"} {
+				if strings.HasPrefix(source[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &SyntaxError{i, fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, token{tokOperator, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(source)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c))
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,309 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Node is a parsed expression
+type Node interface {
+	String() string
+}
+
+// Literal is a null, boolean, number or string constant
+type Literal struct {
+	Value Value
+}
+
+// Context is a named context such as github or env
+type Context struct {
+	Name string
+}
+
+// Property is obj.name
+type Property struct {
+	Object Node
+	Name   string
+}
+
+// Index is obj[index]
+type Index struct {
+	Object Node
+	Index  Node
+}
+
+// Filter is obj.*, the object filter
+type Filter struct {
+	Object Node
+}
+
+// Call is a function call
+type Call struct {
+	Name string
+	Args []Node
+}
+
+// Unary is !x
+type Unary struct {
+	Op string
+	X  Node
+}
+
+// Binary is a comparison or logical operation
+type Binary struct {
+	Op          string
+	Left, Right Node
+}
+
+func (n *Literal) String() string {
+	if s, ok := n.Value.(string); ok {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return formatValue(n.Value)
+}
+
+func (n *Context) String() string  { return n.Name }
+func (n *Property) String() string { return n.Object.String() + "." + n.Name }
+func (n *Index) String() string    { return n.Object.String() + "[" + n.Index.String() + "]" }
+func (n *Filter) String() string   { return n.Object.String() + ".*" }
+func (n *Unary) String() string    { return n.Op + n.X.String() }
+func (n *Binary) String() string {
+	return n.Left.String() + " " + n.Op + " " + n.Right.String()
+}
+func (n *Call) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return n.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// SyntaxError is an invalid expression
+type SyntaxError struct {
+	Offset  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Offset+1)
+}
+
+// Parse parses the body of a ${{ }} expression
+func Parse(source string) (Node, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &SyntaxError{tok.offset, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return node, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind || (text != "" && tok.text != text) {
+		found := tok.text
+		if tok.kind == tokEOF {
+			found = "end of expression"
+		}
+		return &SyntaxError{tok.offset, fmt.Sprintf("expected %q, found %q", text, found)}
+	}
+	return nil
+}
+
+// Operator precedence, lowest first: ||, &&, == !=, < <= > >=, !
+
+func (p *parser) or() (Node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (Node, error) {
+	return p.binary(p.equality, "&&")
+}
+
+func (p *parser) equality() (Node, error) {
+	return p.binary(p.comparison, "==", "!=")
+}
+
+func (p *parser) comparison() (Node, error) {
+	return p.binary(p.unary, "<", "<=", ">", ">=")
+}
+
+func (p *parser) binary(operand func() (Node, error), ops ...string) (Node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOperator || !contains(ops, tok.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: tok.text, Left: left, Right: right}
+	}
+}
+
+func (p *parser) unary() (Node, error) {
+	if tok := p.peek(); tok.kind == tokOperator && tok.text == "!" {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Op: "!", X: x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (Node, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == tokPunct && tok.text == ".":
+			p.next()
+			name := p.next()
+			switch name.kind {
+			case tokIdent:
+				node = &Property{Object: node, Name: name.text}
+			case tokOperator:
+				if name.text != "*" {
+					return nil, &SyntaxError{name.offset, "expected a property name after '.'"}
+				}
+				node = &Filter{Object: node}
+			default:
+				return nil, &SyntaxError{name.offset, "expected a property name after '.'"}
+			}
+		case tok.kind == tokPunct && tok.text == "[":
+			p.next()
+			if star := p.peek(); star.kind == tokOperator && star.text == "*" {
+				p.next()
+				node = &Filter{Object: node}
+			} else {
+				index, err := p.or()
+				if err != nil {
+					return nil, err
+				}
+				node = &Index{Object: node, Index: index}
+			}
+			if err := p.expect(tokPunct, "]"); err != nil {
+				return nil, err
+			}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *parser) primary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return &Literal{Value: tok.text}, nil
+	case tokNumber:
+		n, err := parseNumber(tok.text)
+		if err != nil {
+			return nil, &SyntaxError{tok.offset, fmt.Sprintf("invalid number %q", tok.text)}
+		}
+		return &Literal{Value: n}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &Literal{Value: true}, nil
+		case "false":
+			return &Literal{Value: false}, nil
+		case "null":
+			return &Literal{Value: nil}, nil
+		}
+		if next := p.peek(); next.kind == tokPunct && next.text == "(" {
+			return p.call(tok)
+		}
+		return &Context{Name: tok.text}, nil
+	case tokPunct:
+		if tok.text == "(" {
+			node, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokPunct, ")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	case tokEOF:
+		return nil, &SyntaxError{tok.offset, "unexpected end of expression"}
+	}
+	return nil, &SyntaxError{tok.offset, fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+func (p *parser) call(name token) (Node, error) {
+	p.next() // (
+	call := &Call{Name: name.text}
+	if tok := p.peek(); tok.kind == tokPunct && tok.text == ")" {
+		p.next()
+		return call, nil
+	}
+	for {
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		tok := p.next()
+		if tok.kind == tokPunct && tok.text == ")" {
+			return call, nil
+		}
+		if tok.kind != tokPunct || tok.text != "," {
+			return nil, &SyntaxError{tok.offset, fmt.Sprintf("expected ',' or ')' in call to %s", name.text)}
+		}
+	}
+}
+
+func parseNumber(text string) (float64, error) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		n, err := strconv.ParseInt(text[2:], 16, 64)
+		return float64(n), err
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
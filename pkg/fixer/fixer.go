@@ -0,0 +1,77 @@
+package fixer
+
+import (
+	"strings"
+
+	"gh-sentinel/internal/config"
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/analyzer"
+)
+
+// Fix is the result of applying one or more deterministic recipes
+type Fix struct {
+	Recipes      []string
+	Explanation  string
+	FixedContent string
+	Confidence   string
+}
+
+// Fixer applies deterministic recipes to workflow content without any AI call
+type Fixer struct {
+	logger   *logger.Logger
+	registry *Registry
+}
+
+// NewFixer creates a fixer backed by the given recipe registry
+func NewFixer(registry *Registry, log *logger.Logger) *Fixer {
+	return &Fixer{
+		logger:   log,
+		registry: registry,
+	}
+}
+
+// Fix applies every recipe registered for the categories the analyzer
+// detected, in order of detection. It returns nil when no recipe applies.
+func (f *Fixer) Fix(analysis *analyzer.Analysis, logs, content string) *Fix {
+	if analysis == nil || content == "" {
+		return nil
+	}
+
+	fixed := content
+	confidence := "HIGH"
+	var names, explanations []string
+	seen := make(map[string]bool)
+	for _, detected := range analysis.Errors {
+		if seen[detected.Category] {
+			continue
+		}
+		seen[detected.Category] = true
+
+		for _, recipe := range f.registry.Recipes(detected.Category) {
+			updated, changed := f.registry.apply(recipe, fixed, logs)
+			if !changed {
+				f.logger.Debug("Recipe %s did not apply", recipe.Name)
+				continue
+			}
+			f.logger.Debug("Applied deterministic recipe: %s", recipe.Name)
+			fixed = updated
+			names = append(names, recipe.Name)
+			explanations = append(explanations, recipe.Description)
+			// A fix is as confident as its least confident recipe
+			if recipe.Confidence != "" && config.ConfidenceRank(recipe.Confidence) < config.ConfidenceRank(confidence) {
+				confidence = recipe.Confidence
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	return &Fix{
+		Recipes:      names,
+		Explanation:  strings.Join(explanations, " "),
+		FixedContent: fixed,
+		Confidence:   confidence,
+	}
+}
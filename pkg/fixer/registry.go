@@ -0,0 +1,221 @@
+package fixer
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gh-sentinel/internal/config"
+	"gh-sentinel/internal/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Transform is a parameterized, deterministic rewrite of workflow content.
+// It reports whether it changed anything.
+type Transform func(content string, params map[string]string) (string, bool)
+
+// Recipe binds a registered transform and its parameters to an analyzer
+// category. When Detect is set it must match the logs, and its capture
+// groups can be referenced from Params as ${1}, ${name}, etc.
+type Recipe struct {
+	Name        string
+	Category    string
+	Description string
+	Detect      *regexp.Regexp
+	Transform   string
+	Params      map[string]string
+	Confidence  string // HIGH, MEDIUM or LOW; empty means HIGH
+}
+
+// Registry maps analyzer categories to the recipes that can fix them
+type Registry struct {
+	transforms map[string]Transform
+	recipes    map[string][]Recipe
+}
+
+// NewRegistry creates a registry holding the built-in transforms and recipes
+func NewRegistry() *Registry {
+	r := &Registry{
+		transforms: make(map[string]Transform),
+		recipes:    make(map[string][]Recipe),
+	}
+	for name, transform := range builtinTransforms {
+		r.RegisterTransform(name, transform)
+	}
+	for _, recipe := range builtinRecipes {
+		// Built-in recipes only reference built-in transforms
+		_ = r.Register(recipe)
+	}
+	return r
+}
+
+// RegisterTransform makes a transform available to recipes under name
+func (r *Registry) RegisterTransform(name string, transform Transform) {
+	r.transforms[name] = transform
+}
+
+// Register adds a recipe to its category
+func (r *Registry) Register(recipe Recipe) error {
+	if recipe.Name == "" || recipe.Category == "" {
+		return errors.ValidationError("register_recipe", "recipe needs a name and a category")
+	}
+	if _, ok := r.transforms[recipe.Transform]; !ok {
+		return errors.ValidationError("register_recipe", fmt.Sprintf("recipe %s uses unknown transform %q", recipe.Name, recipe.Transform))
+	}
+	if recipe.Confidence != "" && config.ConfidenceRank(recipe.Confidence) < 0 {
+		return errors.ValidationError("register_recipe", fmt.Sprintf("recipe %s has unknown confidence %q; use HIGH, MEDIUM or LOW", recipe.Name, recipe.Confidence))
+	}
+	r.recipes[recipe.Category] = append(r.recipes[recipe.Category], recipe)
+	return nil
+}
+
+// Recipes returns the recipes registered for a category
+func (r *Registry) Recipes(category string) []Recipe {
+	return r.recipes[category]
+}
+
+// apply runs a recipe against content, expanding parameters from the logs
+func (r *Registry) apply(recipe Recipe, content, logs string) (string, bool) {
+	params := recipe.Params
+	if recipe.Detect != nil {
+		match := recipe.Detect.FindStringSubmatchIndex(logs)
+		if match == nil {
+			return content, false
+		}
+		params = make(map[string]string, len(recipe.Params))
+		for key, value := range recipe.Params {
+			params[key] = string(recipe.Detect.ExpandString(nil, value, logs, match))
+		}
+	}
+	return r.transforms[recipe.Transform](content, params)
+}
+
+// recipeFile is the on-disk format of user-contributed recipes
+type recipeFile struct {
+	Recipes []struct {
+		Name        string            `yaml:"name"`
+		Category    string            `yaml:"category"`
+		Description string            `yaml:"description"`
+		Detect      string            `yaml:"detect"`
+		Transform   string            `yaml:"transform"`
+		Params      map[string]string `yaml:"params"`
+		Confidence  string            `yaml:"confidence"`
+	} `yaml:"recipes"`
+}
+
+// LoadFile registers the recipes defined in a YAML rule file. A missing
+// file is not an error.
+func (r *Registry) LoadFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, errors.FilesystemError("load_recipes", path, err)
+	}
+	return r.Load(data, path)
+}
+
+// Load registers the recipes defined in YAML; path is only used in errors
+func (r *Registry) Load(data []byte, path string) (int, error) {
+	var file recipeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return 0, errors.ValidationError("load_recipes", fmt.Sprintf("invalid recipe file: %v", err)).WithPath(path)
+	}
+
+	for _, entry := range file.Recipes {
+		recipe := Recipe{
+			Name:        entry.Name,
+			Category:    entry.Category,
+			Description: entry.Description,
+			Transform:   entry.Transform,
+			Params:      entry.Params,
+			Confidence:  strings.ToUpper(entry.Confidence),
+		}
+		if entry.Detect != "" {
+			re, err := regexp.Compile(entry.Detect)
+			if err != nil {
+				return 0, errors.ValidationError("load_recipes", fmt.Sprintf("recipe %s: invalid detect pattern: %v", entry.Name, err)).WithPath(path)
+			}
+			recipe.Detect = re
+		}
+		if err := r.Register(recipe); err != nil {
+			return 0, err
+		}
+	}
+	return len(file.Recipes), nil
+}
+
+// builtinRecipes are the curated fixes shipped with sentinel
+var builtinRecipes = []Recipe{
+	{
+		Name:        "deprecated-action-version",
+		Category:    "deprecation",
+		Description: "Deprecated action versions were bumped to their current major release.",
+		Transform:   "bump-deprecated-actions",
+	},
+	{
+		Name:        "missing-checkout",
+		Category:    "checkout",
+		Description: "A job ran commands against repository files without checking out the repository first, so actions/checkout was added as its first step.",
+		Transform:   "add-checkout",
+	},
+	{
+		Name:        "setup-node-version",
+		Category:    "runtime_version",
+		Description: "The project requires a newer Node.js than the workflow installs, so setup-node's node-version was raised to match.",
+		Detect:      regexp.MustCompile(`(?i)expected version "[^\d"]*(\d+)|requires? node(?:\.js)?(?: version)? >=?\s*v?(\d+)`),
+		Transform:   "raise-node-version",
+		Params:      map[string]string{"version": "${1}${2}"},
+	},
+	{
+		Name:        "missing-token-permission",
+		Category:    "permissions",
+		Description: "The workflow token could not write to a repository resource, so its permission was granted in the workflow's permissions blocks; check that no job gets write access it does not need.",
+		Detect:      regexp.MustCompile(`(?i)Resource not accessible by integration[\s\S]{0,500}?/repos/[^/\s]+/[^/\s]+/([a-z-]+)|/repos/[^/\s]+/[^/\s]+/([a-z-]+)[^\n]*\n?[\s\S]{0,500}?Resource not accessible by integration`),
+		Transform:   "add-token-permission",
+		Params:      map[string]string{"resource": "${1}${2}"},
+		Confidence:  "MEDIUM",
+	},
+	{
+		Name:        "missing-id-token-permission",
+		Category:    "cloud_auth",
+		Description: "The cloud login step could not request an OIDC token, so `permissions: id-token: write` was granted to the jobs that authenticate.",
+		Detect:      regexp.MustCompile(`(?i)ACTIONS_ID_TOKEN_REQUEST_(?:URL|TOKEN)|Credentials could not be loaded.*id-token`),
+		Transform:   "add-id-token-permission",
+	},
+	{
+		Name:        "submodule-token",
+		Category:    "submodules",
+		Description: "actions/checkout could not clone a private submodule with the default GITHUB_TOKEN, so it now uses the SUBMODULES_TOKEN secret; create it with a token that can read the submodule repositories.",
+		Detect:      regexp.MustCompile(`(?i)clone of 'https://[^']+' into submodule path|could not read Username for 'https://github\.com'`),
+		Transform:   "submodule-auth",
+		Params:      map[string]string{"input": "token", "secret": "SUBMODULES_TOKEN"},
+	},
+	{
+		Name:        "submodule-ssh-key",
+		Category:    "submodules",
+		Description: "actions/checkout could not clone a submodule over SSH, so it now uses the SUBMODULES_SSH_KEY secret; create it with a private deploy key that has read access to the submodule repositories.",
+		Detect:      regexp.MustCompile(`(?i)clone of '(?:git@|ssh://)[^']+' into submodule path|Permission denied \(publickey\)`),
+		Transform:   "submodule-auth",
+		Params:      map[string]string{"input": "ssh-key", "secret": "SUBMODULES_SSH_KEY"},
+	},
+	{
+		Name:        "checkout-lfs",
+		Category:    "lfs",
+		Description: "Git LFS files were checked out as pointer files, so `lfs: true` was set on actions/checkout to download their contents.",
+		Detect:      regexp.MustCompile(`(?i)version https://git-lfs\.github\.com/spec/v1|git lfs pointer`),
+		Transform:   "set-input",
+		Params:      map[string]string{"action": "actions/checkout", "input": "lfs", "value": "true"},
+	},
+	{
+		Name:        "windows-bash-shell",
+		Category:    "shell",
+		Description: "PowerShell, the default shell on Windows runners, could not parse a bash command, so jobs running on Windows now default to `shell: bash`.",
+		Detect:      regexp.MustCompile(`(?i)The token '&&' is not a valid statement separator|Missing expression after unary operator '--'|ParserError:.*(?:Unexpected token|Missing)`),
+		Transform:   "default-shell",
+		Params:      map[string]string{"os": "windows", "shell": "bash"},
+	},
+}
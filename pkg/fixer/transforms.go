@@ -0,0 +1,655 @@
+package fixer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gh-sentinel/pkg/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+// latestActionMajors lists the current major version of common first-party actions
+var latestActionMajors = map[string]int{
+	"actions/cache":             4,
+	"actions/checkout":          4,
+	"actions/download-artifact": 4,
+	"actions/github-script":     7,
+	"actions/setup-go":          5,
+	"actions/setup-java":        4,
+	"actions/setup-node":        4,
+	"actions/setup-python":      5,
+	"actions/upload-artifact":   4,
+}
+
+var (
+	usesLineRe    = regexp.MustCompile(`^(\s*(?:-\s+)?uses:\s*["']?)([\w.-]+/[\w.-]+)@v(\d+)((?:\.\d+)*)(.*)$`)
+	usesRefRe     = regexp.MustCompile(`^(\s*(?:-\s+)?uses:\s*["']?)([^@\s"']+)@([^\s"'#]+)(.*)$`)
+	nodeVersionRe = regexp.MustCompile(`^(\s*node-version:\s*)(\S.*?)(\s*#.*)?$`)
+)
+
+// builtinTransforms are the transforms every registry starts with
+var builtinTransforms = map[string]Transform{
+	"bump-deprecated-actions": bumpActionVersions,
+	"add-checkout":            addMissingCheckout,
+	"raise-node-version":      fixNodeVersion,
+	"add-token-permission":    addTokenPermission,
+	"add-id-token-permission": addIDTokenPermission,
+	"bump-action":             bumpAction,
+	"add-step-after-checkout": addStepAfterCheckout,
+	"set-input":               setActionInput,
+	"submodule-auth":          configureSubmoduleAuth,
+	"default-shell":           setDefaultShell,
+}
+
+// bumpActionVersions rewrites outdated `uses: owner/action@vN` references
+func bumpActionVersions(content string, _ map[string]string) (string, bool) {
+	lines, trailing := splitLines(content)
+	changed := false
+	for i, line := range lines {
+		match := usesLineRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		latest, known := latestActionMajors[match[2]]
+		current, _ := strconv.Atoi(match[3])
+		if !known || current >= latest {
+			continue
+		}
+		lines[i] = fmt.Sprintf("%s%s@v%d%s", match[1], match[2], latest, match[5])
+		changed = true
+	}
+	return joinLines(lines, trailing), changed
+}
+
+// addMissingCheckout inserts actions/checkout as the first step of every job
+// that runs shell commands but never checks out the repository
+func addMissingCheckout(content string, _ map[string]string) (string, bool) {
+	root, err := parseDocument(content)
+	if err != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	var edits []lineEdit
+
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		_, steps := workflow.Entry(jobs[id], "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+			continue
+		}
+
+		hasCheckout, hasRun := false, false
+		for _, step := range steps.Content {
+			if _, uses := workflow.Entry(step, "uses"); uses != nil && strings.HasPrefix(uses.Value, "actions/checkout") {
+				hasCheckout = true
+			}
+			if _, run := workflow.Entry(step, "run"); run != nil {
+				hasRun = true
+			}
+		}
+		if hasCheckout || !hasRun {
+			continue
+		}
+		at := steps.Content[0].Line - 1
+		indent := leadingWhitespace(lines[at])
+		edits = append(edits, lineEdit{at, []string{indent + "- uses: actions/checkout@v4"}})
+	}
+
+	if len(edits) == 0 {
+		return content, false
+	}
+	return joinLines(applyEdits(lines, edits), trailing), true
+}
+
+// fixNodeVersion raises literal node-version values to the major version
+// given by the "version" parameter
+func fixNodeVersion(content string, params map[string]string) (string, bool) {
+	requiredMajor, err := strconv.Atoi(strings.TrimPrefix(params["version"], "v"))
+	if err != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	changed := false
+	for i, line := range lines {
+		m := nodeVersionRe.FindStringSubmatch(line)
+		if m == nil || strings.Contains(m[2], "${{") {
+			continue
+		}
+		value := strings.Trim(m[2], `"'`)
+		major, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(value, "v"), ".", 2)[0])
+		if err != nil || major >= requiredMajor {
+			continue
+		}
+		lines[i] = fmt.Sprintf("%s'%d'%s", m[1], requiredMajor, m[3])
+		changed = true
+	}
+	return joinLines(lines, trailing), changed
+}
+
+// tokenScopes maps the REST resources of a repository to the token
+// permission they need to be written
+var tokenScopes = map[string]string{
+	"issues":       "issues",
+	"pulls":        "pull-requests",
+	"statuses":     "statuses",
+	"check-runs":   "checks",
+	"check-suites": "checks",
+	"deployments":  "deployments",
+	"releases":     "contents",
+	"git":          "contents",
+	"contents":     "contents",
+	"actions":      "actions",
+	"pages":        "pages",
+	"packages":     "packages",
+}
+
+// addTokenPermission grants write access to the scope of the "resource"
+// parameter, a REST resource like issues or pulls, in the permissions
+// blocks the workflow already has: the top-level one and those of jobs. A
+// block is never created, since any permissions block drops every scope it
+// does not list; without one, the token's permissions come from the
+// repository settings.
+func addTokenPermission(content string, params map[string]string) (string, bool) {
+	scope, ok := tokenScopes[strings.ToLower(params["resource"])]
+	if !ok {
+		return content, false
+	}
+	root, err := parseDocument(content)
+	if err != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	var edits []lineEdit
+	changed := false
+	grant := func(key, perms *yaml.Node) {
+		var granted bool
+		edits, granted = grantWrite(lines, edits, key, perms, scope)
+		changed = changed || granted
+	}
+
+	grant(workflow.Entry(root, "permissions"))
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		grant(workflow.Entry(jobs[id], "permissions"))
+	}
+
+	if !changed {
+		return content, false
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].at < edits[j].at })
+	return joinLines(applyEdits(lines, edits), trailing), true
+}
+
+// grantWrite gives scope write access in a permissions mapping: a weaker
+// level is raised in place and a missing entry is added to edits. It reports
+// whether the scope was granted.
+func grantWrite(lines []string, edits []lineEdit, key, perms *yaml.Node, scope string) ([]lineEdit, bool) {
+	if key == nil || perms.Kind != yaml.MappingNode {
+		return edits, false
+	}
+	if k, v := workflow.Entry(perms, scope); k != nil {
+		return edits, v.Value != "write" && setScalar(lines, v, "write")
+	}
+	if e, ok := permissionEdit(lines, key, perms, scope, "write"); ok {
+		return append(edits, e), true
+	}
+	return edits, false
+}
+
+// setScalar rewrites the scalar of node where it sits in lines, leaving the
+// rest of the line alone, so only that value changes in flow mappings like
+// `{contents: read, issues: read}`
+func setScalar(lines []string, node *yaml.Node, value string) bool {
+	line := []rune(lines[node.Line-1])
+	start := node.Column - 1
+	if start < 0 || start >= len(line) {
+		return false
+	}
+	token := node.Value
+	if q := line[start]; q == '"' || q == '\'' {
+		token = string(q) + token + string(q)
+	}
+	end := start + len([]rune(token))
+	if end > len(line) || string(line[start:end]) != token {
+		return false
+	}
+	lines[node.Line-1] = string(line[:start]) + value + string(line[end:])
+	return true
+}
+
+// permissionEdit returns the insertion of `scope: level` into a permissions
+// mapping, if it lacks an entry for scope
+func permissionEdit(lines []string, key, perms *yaml.Node, scope, level string) (lineEdit, bool) {
+	if k, _ := workflow.Entry(perms, scope); k != nil {
+		return lineEdit{}, false
+	}
+
+	// Flow mappings like `permissions: {}` are rewritten as a block
+	if perms.Style&yaml.FlowStyle != 0 {
+		if len(perms.Content) > 0 {
+			return lineEdit{}, false
+		}
+		indent := leadingWhitespace(lines[key.Line-1])
+		lines[key.Line-1] = indent + "permissions:"
+		return lineEdit{key.Line, []string{indent + "  " + scope + ": " + level}}, true
+	}
+
+	indent := strings.Repeat(" ", perms.Content[0].Column-1)
+	return lineEdit{key.Line, []string{indent + scope + ": " + level}}, true
+}
+
+// cloudAuthActions exchange the job's OIDC token for cloud credentials
+var cloudAuthActions = []string{
+	"aws-actions/configure-aws-credentials",
+	"azure/login",
+	"google-github-actions/auth",
+}
+
+// addIDTokenPermission grants `id-token: write` to every job that runs a
+// cloud auth action. Jobs with their own permissions get the entry there,
+// other jobs through the top-level permissions. A block is never created,
+// since it would drop every scope it does not list; MissingIDToken reports
+// the jobs left without one.
+func addIDTokenPermission(content string, _ map[string]string) (string, bool) {
+	root, err := parseDocument(content)
+	if err != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	var edits []lineEdit
+	changed := false
+
+	rootKey, rootPerms := workflow.Entry(root, "permissions")
+	rootDone := false
+
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		job := jobs[id]
+		if job.Kind != yaml.MappingNode || len(job.Content) == 0 || !usesCloudAuth(job) {
+			continue
+		}
+
+		key, perms := workflow.Entry(job, "permissions")
+		if key == nil && rootKey != nil {
+			if rootDone {
+				continue
+			}
+			key, perms, rootDone = rootKey, rootPerms, true
+		}
+
+		var granted bool
+		edits, granted = grantWrite(lines, edits, key, perms, "id-token")
+		changed = changed || granted
+	}
+
+	if !changed {
+		return content, false
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].at < edits[j].at })
+	return joinLines(applyEdits(lines, edits), trailing), true
+}
+
+// MissingIDToken returns the jobs that run a cloud auth action without any
+// permissions block, neither their own nor a top-level one, to which
+// addIDTokenPermission cannot grant `id-token: write`
+func MissingIDToken(content string) []string {
+	root, err := parseDocument(content)
+	if err != nil {
+		return nil
+	}
+	if key, _ := workflow.Entry(root, "permissions"); key != nil {
+		return nil
+	}
+
+	var missing []string
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		if key, _ := workflow.Entry(jobs[id], "permissions"); key == nil && usesCloudAuth(jobs[id]) {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// usesCloudAuth reports whether a job runs one of the cloud auth actions
+func usesCloudAuth(job *yaml.Node) bool {
+	_, steps := workflow.Entry(job, "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, step := range steps.Content {
+		_, uses := workflow.Entry(step, "uses")
+		if uses == nil {
+			continue
+		}
+		action := strings.SplitN(uses.Value, "@", 2)[0]
+		for _, known := range cloudAuthActions {
+			if strings.EqualFold(action, known) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bumpAction pins every reference to params["action"] to params["version"]
+func bumpAction(content string, params map[string]string) (string, bool) {
+	action, version := params["action"], params["version"]
+	if action == "" || version == "" {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	changed := false
+	for i, line := range lines {
+		match := usesRefRe.FindStringSubmatch(line)
+		if match == nil || match[2] != action || match[3] == version {
+			continue
+		}
+		lines[i] = fmt.Sprintf("%s%s@%s%s", match[1], action, version, match[4])
+		changed = true
+	}
+	return joinLines(lines, trailing), changed
+}
+
+// addStepAfterCheckout inserts params["step"] (one YAML line per step line,
+// without the leading dash) after actions/checkout in every job that checks
+// out the repository and does not already contain the step
+func addStepAfterCheckout(content string, params map[string]string) (string, bool) {
+	stepLines := strings.Split(strings.TrimSpace(params["step"]), "\n")
+	if len(stepLines) == 0 || stepLines[0] == "" {
+		return content, false
+	}
+	if strings.Contains(content, strings.TrimSpace(stepLines[0])) {
+		return content, false
+	}
+
+	root, err := parseDocument(content)
+	if err != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	var edits []lineEdit
+
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		_, steps := workflow.Entry(jobs[id], "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, step := range steps.Content {
+			_, uses := workflow.Entry(step, "uses")
+			if uses == nil || !strings.HasPrefix(uses.Value, "actions/checkout") {
+				continue
+			}
+			start := step.Line - 1
+			indent := leadingWhitespace(lines[start])
+
+			var inserted []string
+			for i, line := range stepLines {
+				prefix := "  "
+				if i == 0 {
+					prefix = "- "
+				}
+				inserted = append(inserted, indent+prefix+strings.TrimRight(line, " "))
+			}
+			edits = append(edits, lineEdit{blockEnd(lines, start, len(indent)), inserted})
+			break
+		}
+	}
+
+	if len(edits) == 0 {
+		return content, false
+	}
+	return joinLines(applyEdits(lines, edits), trailing), true
+}
+
+// setActionInput sets the input params["input"] to params["value"] in the
+// `with:` block of every step using params["action"]
+func setActionInput(content string, params map[string]string) (string, bool) {
+	action, input, value := params["action"], params["input"], params["value"]
+	if action == "" || input == "" || value == "" {
+		return content, false
+	}
+
+	root, err := parseDocument(content)
+	if err != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	var edits []lineEdit
+	changed := false
+
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		_, steps := workflow.Entry(jobs[id], "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, step := range steps.Content {
+			if stepAction(step) != action {
+				continue
+			}
+			edit, ok := setStepInput(lines, step, input, value)
+			if edit != nil {
+				edits = append(edits, *edit)
+			}
+			changed = changed || ok
+		}
+	}
+
+	if !changed {
+		return content, false
+	}
+	return joinLines(applyEdits(lines, edits), trailing), true
+}
+
+// setStepInput sets input to value in a step's `with:` block. Existing
+// values are replaced in lines directly; a missing entry or block is
+// returned as an insertion. Flow-style blocks are left alone.
+func setStepInput(lines []string, step *yaml.Node, input, value string) (*lineEdit, bool) {
+	usesKey, _ := workflow.Entry(step, "uses")
+	if usesKey == nil {
+		return nil, false
+	}
+
+	withKey, with := workflow.Entry(step, "with")
+	switch {
+	case withKey == nil:
+		indent := strings.Repeat(" ", usesKey.Column-1)
+		return &lineEdit{usesKey.Line, []string{
+			indent + "with:",
+			indent + "  " + input + ": " + value,
+		}}, true
+	case with.Kind == yaml.MappingNode && len(with.Content) > 0 && with.Style&yaml.FlowStyle == 0:
+		if key, current := workflow.Entry(with, input); key != nil {
+			if current.Value == strings.Trim(value, `"'`) {
+				return nil, false
+			}
+			lines[key.Line-1] = leadingWhitespace(lines[key.Line-1]) + input + ": " + value
+			return nil, true
+		}
+		indent := strings.Repeat(" ", with.Content[0].Column-1)
+		return &lineEdit{withKey.Line, []string{indent + input + ": " + value}}, true
+	}
+	return nil, false
+}
+
+// stepAction returns the action a step uses, without its ref
+func stepAction(step *yaml.Node) string {
+	_, uses := workflow.Entry(step, "uses")
+	if uses == nil {
+		return ""
+	}
+	return strings.SplitN(uses.Value, "@", 2)[0]
+}
+
+// stepInput returns the value of a step's `with:` input, or "" when unset
+func stepInput(step *yaml.Node, input string) string {
+	_, with := workflow.Entry(step, "with")
+	if _, value := workflow.Entry(with, input); value != nil {
+		return value.Value
+	}
+	return ""
+}
+
+// configureSubmoduleAuth gives actions/checkout credentials that can read
+// private submodules. The default GITHUB_TOKEN only covers the workflow's
+// own repository, so every checkout that fetches submodules, or is followed
+// by `git submodule` commands, gets params["input"] (token or ssh-key) set to
+// the secret params["secret"]. Checkouts that disable persist-credentials
+// while later steps run `git submodule` get it re-enabled.
+func configureSubmoduleAuth(content string, params map[string]string) (string, bool) {
+	input, secret := params["input"], params["secret"]
+	if (input != "token" && input != "ssh-key") || secret == "" {
+		return content, false
+	}
+
+	root, err := parseDocument(content)
+	if err != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	var edits []lineEdit
+	changed := false
+
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		_, steps := workflow.Entry(jobs[id], "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		runsSubmodule := false
+		for _, step := range steps.Content {
+			if _, run := workflow.Entry(step, "run"); run != nil && strings.Contains(run.Value, "git submodule") {
+				runsSubmodule = true
+			}
+		}
+
+		for _, step := range steps.Content {
+			if stepAction(step) != "actions/checkout" {
+				continue
+			}
+			submodules := stepInput(step, "submodules")
+			if !runsSubmodule && (submodules == "" || submodules == "false") {
+				continue
+			}
+
+			token := stepInput(step, "token")
+			if stepInput(step, "ssh-key") == "" && (token == "" || usesDefaultToken(token)) {
+				value := fmt.Sprintf("${{ secrets.%s }}", secret)
+				if edit, ok := setStepInput(lines, step, input, value); ok {
+					if edit != nil {
+						edits = append(edits, *edit)
+					}
+					changed = true
+				}
+			}
+
+			if runsSubmodule && stepInput(step, "persist-credentials") == "false" {
+				if _, ok := setStepInput(lines, step, "persist-credentials", "true"); ok {
+					changed = true
+				}
+			}
+		}
+	}
+
+	if !changed {
+		return content, false
+	}
+	return joinLines(applyEdits(lines, edits), trailing), true
+}
+
+// usesDefaultToken reports whether a token expression is the workflow's own
+// GITHUB_TOKEN, which cannot read other private repositories
+func usesDefaultToken(token string) bool {
+	return strings.Contains(token, "secrets.GITHUB_TOKEN") || strings.Contains(token, "github.token")
+}
+
+// setDefaultShell adds `defaults: run: shell: params["shell"]` to every job
+// that runs on params["os"] runners, directly or through its matrix. The
+// workflow is left alone when it already sets a default shell; jobs with
+// their own defaults block are skipped.
+func setDefaultShell(content string, params map[string]string) (string, bool) {
+	shell, runnerOS := params["shell"], strings.ToLower(params["os"])
+	if shell == "" || runnerOS == "" {
+		return content, false
+	}
+
+	root, err := parseDocument(content)
+	if err != nil {
+		return content, false
+	}
+	_, defaults := workflow.Entry(root, "defaults")
+	_, run := workflow.Entry(defaults, "run")
+	if key, _ := workflow.Entry(run, "shell"); key != nil {
+		return content, false
+	}
+
+	lines, trailing := splitLines(content)
+	var edits []lineEdit
+
+	ids, jobs := jobNodes(root)
+	for _, id := range ids {
+		job := jobs[id]
+		if job.Kind != yaml.MappingNode || len(job.Content) == 0 {
+			continue
+		}
+		if key, _ := workflow.Entry(job, "defaults"); key != nil {
+			continue
+		}
+		_, runsOn := workflow.Entry(job, "runs-on")
+		if runsOn == nil {
+			continue
+		}
+		onRunner := containsScalar(runsOn, runnerOS)
+		if !onRunner && containsScalar(runsOn, "matrix.") {
+			_, strategy := workflow.Entry(job, "strategy")
+			onRunner = containsScalar(strategy, runnerOS)
+		}
+		if !onRunner {
+			continue
+		}
+
+		indent := strings.Repeat(" ", job.Content[0].Column-1)
+		edits = append(edits, lineEdit{job.Content[0].Line - 1, []string{
+			indent + "defaults:",
+			indent + "  run:",
+			indent + "    shell: " + shell,
+		}})
+	}
+
+	if len(edits) == 0 {
+		return content, false
+	}
+	return joinLines(applyEdits(lines, edits), trailing), true
+}
+
+// containsScalar reports whether any scalar under node contains substr,
+// ignoring case
+func containsScalar(node *yaml.Node, substr string) bool {
+	if node == nil {
+		return false
+	}
+	if node.Kind == yaml.ScalarNode {
+		return strings.Contains(strings.ToLower(node.Value), strings.ToLower(substr))
+	}
+	for _, child := range node.Content {
+		if containsScalar(child, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package fixer
+
+import (
+	"fmt"
+	"strings"
+
+	"gh-sentinel/pkg/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseDocument parses workflow content and returns its root mapping node
+func parseDocument(content string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("workflow root is not a mapping")
+	}
+	return root, nil
+}
+
+// jobNodes returns the job mappings of a workflow keyed by job ID, in file order
+func jobNodes(root *yaml.Node) ([]string, map[string]*yaml.Node) {
+	_, jobs := workflow.Entry(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var ids []string
+	nodes := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id := jobs.Content[i].Value
+		ids = append(ids, id)
+		nodes[id] = jobs.Content[i+1]
+	}
+	return ids, nodes
+}
+
+// splitLines splits content into lines, remembering whether it ended with a newline
+func splitLines(content string) ([]string, bool) {
+	trailing := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), trailing
+}
+
+// joinLines reverses splitLines
+func joinLines(lines []string, trailing bool) string {
+	out := strings.Join(lines, "\n")
+	if trailing {
+		out += "\n"
+	}
+	return out
+}
+
+// lineEdit inserts text before the 0-based line index at
+type lineEdit struct {
+	at   int
+	text []string
+}
+
+// applyEdits applies edits collected in file order, bottom-up so earlier
+// line numbers stay valid
+func applyEdits(lines []string, edits []lineEdit) []string {
+	for i := len(edits) - 1; i >= 0; i-- {
+		lines = insertLines(lines, edits[i].at, edits[i].text...)
+	}
+	return lines
+}
+
+// insertLines inserts new lines before the 0-based line index at
+func insertLines(lines []string, at int, inserted ...string) []string {
+	out := make([]string, 0, len(lines)+len(inserted))
+	out = append(out, lines[:at]...)
+	out = append(out, inserted...)
+	return append(out, lines[at:]...)
+}
+
+// leadingWhitespace returns the indentation of a line
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// blockEnd returns the 0-based index of the first line after the sequence
+// item starting at start whose dash sits at column indent
+func blockEnd(lines []string, start, indent int) int {
+	end := start + 1
+	last := start
+	for ; end < len(lines); end++ {
+		trimmed := strings.TrimSpace(lines[end])
+		if trimmed == "" {
+			continue
+		}
+		if len(leadingWhitespace(lines[end])) <= indent {
+			break
+		}
+		last = end
+	}
+	return last + 1
+}
@@ -0,0 +1,203 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/logger"
+)
+
+// Repo runs git commands against a local working tree. Paths passed to its
+// methods are relative to the directory it was opened in.
+type Repo struct {
+	dir    string
+	root   string
+	logger *logger.Logger
+	ctx    context.Context
+}
+
+// interruptGrace is how long an interrupted git command may take to clean up
+// before it is killed
+const interruptGrace = 5 * time.Second
+
+// Open returns the repository containing dir
+func Open(dir string, log *logger.Logger) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New(errors.ErrTypeFilesystem, "git_open", "git not found in PATH", err)
+	}
+	r := &Repo{dir: dir, logger: log, ctx: context.Background()}
+	root, err := r.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	r.root = root
+	return r, nil
+}
+
+// Clone makes a shallow clone of url into dir and opens it. Private
+// repositories are read through the gh CLI's credential helper.
+func Clone(ctx context.Context, url, dir string, log *logger.Logger) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New(errors.ErrTypeFilesystem, "git_clone", "git not found in PATH", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, errors.FilesystemError("git_clone", dir, err)
+	}
+	parent := &Repo{dir: filepath.Dir(dir), logger: log, ctx: ctx}
+	if _, err := parent.run(append(ghCredentials, "clone", "--depth", "1", "--quiet", url, dir)...); err != nil {
+		return nil, err
+	}
+	r, err := Open(dir, log)
+	if err != nil {
+		return nil, err
+	}
+	r.SetContext(ctx)
+	return r, nil
+}
+
+// SetContext replaces the context of git commands, so cancelling ctx
+// interrupts the command in progress
+func (r *Repo) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// Root returns the top-level directory of the working tree
+func (r *Repo) Root() string {
+	return r.root
+}
+
+// CurrentBranch returns the checked-out branch name
+func (r *Repo) CurrentBranch() (string, error) {
+	return r.run("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// BranchExists reports whether a local branch exists
+func (r *Repo) BranchExists(name string) bool {
+	_, err := r.run("rev-parse", "--verify", "--quiet", "refs/heads/"+name)
+	return err == nil
+}
+
+// CreateBranch creates and checks out a branch from HEAD, carrying over
+// uncommitted changes
+func (r *Repo) CreateBranch(name string) error {
+	_, err := r.run("checkout", "-b", name)
+	return err
+}
+
+// Checkout switches to an existing branch, carrying over uncommitted changes
+func (r *Repo) Checkout(name string) error {
+	_, err := r.run("checkout", name)
+	return err
+}
+
+// DeleteBranch force-deletes a local branch
+func (r *Repo) DeleteBranch(name string) error {
+	_, err := r.run("branch", "-D", name)
+	return err
+}
+
+// Discard reverts uncommitted changes to a tracked path
+func (r *Repo) Discard(path string) error {
+	_, err := r.run("checkout", "HEAD", "--", path)
+	return err
+}
+
+// Modified reports whether a path has uncommitted changes, staged or not
+func (r *Repo) Modified(path string) (bool, error) {
+	out, err := r.run("status", "--porcelain", "--", path)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Stash stashes the uncommitted changes of paths, untracked ones included,
+// under message; git stash pop brings them back
+func (r *Repo) Stash(message string, paths ...string) error {
+	args := append([]string{"stash", "push", "--include-untracked", "-m", message, "--"}, paths...)
+	_, err := r.run(args...)
+	return err
+}
+
+// Commit commits only the given paths and returns the new commit SHA.
+// Other staged or modified files are left untouched.
+func (r *Repo) Commit(message string, paths ...string) (string, error) {
+	args := append([]string{"add", "--"}, paths...)
+	if _, err := r.run(args...); err != nil {
+		return "", err
+	}
+	args = append([]string{"commit", "-m", message, "--"}, paths...)
+	if _, err := r.run(args...); err != nil {
+		return "", err
+	}
+	return r.run("rev-parse", "HEAD")
+}
+
+// ghCredentials makes git authenticate through the gh CLI's credential
+// helper, so no separate git credentials are needed
+var ghCredentials = []string{
+	"-c", "credential.helper=",
+	"-c", "credential.helper=!gh auth git-credential",
+}
+
+// Push pushes a branch and sets its upstream
+func (r *Repo) Push(remote, branch string) error {
+	_, err := r.run(append(ghCredentials, "push", "--set-upstream", remote, branch)...)
+	return err
+}
+
+// RemoteURL returns the URL of a remote
+func (r *Repo) RemoteURL(remote string) (string, error) {
+	return r.run("remote", "get-url", remote)
+}
+
+// Update moves the checked-out branch to the latest commit of the remote's
+// default branch, discarding local changes. It is meant for clones that are
+// only ever read.
+func (r *Repo) Update(remote string) error {
+	if _, err := r.run(append(ghCredentials, "fetch", "--depth", "1", "--quiet", remote, "HEAD")...); err != nil {
+		return err
+	}
+	_, err := r.run("reset", "--hard", "--quiet", "FETCH_HEAD")
+	return err
+}
+
+// run executes git in the repository and returns trimmed stdout
+func (r *Repo) run(args ...string) (string, error) {
+	r.logger.Debug("git %s", strings.Join(args, " "))
+
+	cmd := exec.CommandContext(r.ctx, "git", args...)
+	cmd.Dir = r.dir
+	// git removes its index.lock when interrupted; killing it outright would
+	// leave the repository locked
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = interruptGrace
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		op := "git"
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+				op = "git_" + arg
+				break
+			}
+		}
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = fmt.Sprintf("git %s failed", args[0])
+		}
+		return "", errors.New(errors.ErrTypeFilesystem, op, message, err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
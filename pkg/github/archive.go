@@ -0,0 +1,155 @@
+package github
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+)
+
+// archiveMinJobs is how many jobs a run must have logs fetched for before
+// the run's log archive is downloaded instead of one log per job
+const archiveMinJobs = 8
+
+// maxLogArchiveSize bounds the log archive of a run. A larger one is not
+// downloaded and the jobs' logs are fetched one by one.
+const maxLogArchiveSize = 200 << 20
+
+// archiveJobLogs downloads the log archive of a run into TempDir and reads
+// the logs of jobs from it, split by step. The archive holds one file per
+// job at its root, named after the job. Jobs whose file is missing are left
+// out, so their logs can be fetched on their own. The archive is removed
+// once read.
+func (c *Client) archiveJobLogs(runID int64, jobs []*Job) (map[int64]*JobLogs, error) {
+	archive, err := c.downloadLogArchive(runID)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(archive)
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, errors.FilesystemError("read_log_archive", archive, err)
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range r.File {
+		if name, ok := archiveJobName(f.Name); ok {
+			files[name] = f
+		}
+	}
+
+	result := make(map[int64]*JobLogs, len(jobs))
+	for _, job := range jobs {
+		f, ok := files[archiveName(job.Name)]
+		if !ok {
+			c.logger.Debug("No log of job %q in the archive of run %d", job.Name, runID)
+			continue
+		}
+		text, err := c.readArchiveLog(f)
+		if err != nil {
+			c.logger.Debug("Could not read the log of job %q from the archive: %v", job.Name, err)
+			continue
+		}
+		result[job.ID] = &JobLogs{Job: job, Steps: splitSteps(job, text)}
+	}
+	c.logger.Debug("Read logs of %d of %d jobs from the archive of run %d", len(result), len(jobs), runID)
+	return result, nil
+}
+
+// downloadLogArchive downloads the log archive of a run to a file in
+// TempDir and returns its path
+func (c *Client) downloadLogArchive(runID int64) (string, error) {
+	u, _, err := c.client.Actions.GetWorkflowRunLogs(c.ctx, c.repo.Owner, c.repo.Name, runID, 2)
+	if err != nil {
+		return "", errors.GitHubAPIError("get_run_logs", err)
+	}
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.download.Do(req)
+	if err != nil {
+		return "", errors.NetworkError("get_run_logs", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.NetworkError("get_run_logs", fmt.Errorf("%s", resp.Status))
+	}
+	if resp.ContentLength > maxLogArchiveSize {
+		return "", errors.ValidationError("get_run_logs", fmt.Sprintf("the log archive of run %d is larger than %d MB", runID, maxLogArchiveSize>>20))
+	}
+
+	if err := os.MkdirAll(c.config.TempDir, 0755); err != nil {
+		return "", errors.FilesystemError("get_run_logs", c.config.TempDir, err)
+	}
+	file, err := os.CreateTemp(c.config.TempDir, fmt.Sprintf("run-%d-logs-*.zip", runID))
+	if err != nil {
+		return "", errors.FilesystemError("get_run_logs", c.config.TempDir, err)
+	}
+	n, err := io.Copy(file, io.LimitReader(resp.Body, maxLogArchiveSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err != nil:
+		os.Remove(file.Name())
+		return "", errors.NetworkError("get_run_logs", err)
+	case n > maxLogArchiveSize:
+		os.Remove(file.Name())
+		return "", errors.ValidationError("get_run_logs", fmt.Sprintf("the log archive of run %d is larger than %d MB", runID, maxLogArchiveSize>>20))
+	}
+	return file.Name(), nil
+}
+
+// readArchiveLog reads a job's log from the archive, keeping the tail of
+// oversized logs as jobLog does
+func (c *Client) readArchiveLog(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	if skip := int64(f.UncompressedSize64) - int64(c.config.MaxRawLogSize); skip > 0 {
+		if _, err := io.CopyN(io.Discard, rc, skip); err != nil {
+			return "", err
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, int64(c.config.MaxRawLogSize)))
+	return string(data), err
+}
+
+// archiveEntryRe matches the file of a whole job at the root of a log
+// archive, e.g. "3_build (ubuntu-latest).txt"
+var archiveEntryRe = regexp.MustCompile(`^\d+_(.+)\.txt$`)
+
+// archiveJobName returns the job name of an archive entry holding a whole
+// job's log, as archiveName renders it. Steps have their own files in a
+// directory per job, which are not used.
+func archiveJobName(entry string) (string, bool) {
+	if strings.Contains(entry, "/") {
+		return "", false
+	}
+	m := archiveEntryRe.FindStringSubmatch(entry)
+	if m == nil {
+		return "", false
+	}
+	return archiveName(m[1]), true
+}
+
+// archiveName renders a job name as it appears in archive file names,
+// which leave out the characters file systems reject
+func archiveName(job string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(job))
+}
@@ -0,0 +1,228 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gh-sentinel/internal/config"
+	sentinelContext "gh-sentinel/internal/context"
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/logger"
+
+	"github.com/google/go-github/v60/github"
+	"golang.org/x/oauth2"
+)
+
+// Environment variables holding the credentials of the token and app
+// authentication modes; they are never read from settings files
+const (
+	TokenEnv       = "SENTINEL_GITHUB_TOKEN"
+	AppKeyEnv      = "SENTINEL_APP_PRIVATE_KEY"      // PEM of the GitHub App's private key
+	AppKeyFileEnv  = "SENTINEL_APP_PRIVATE_KEY_FILE" // Or the file holding it
+	appJWTLifetime = 9 * time.Minute                 // GitHub accepts at most 10
+	appTokenLeeway = 5 * time.Minute                 // Installation tokens are renewed this long before they expire
+	appClockSkew   = time.Minute                     // JWTs are issued this far in the past
+)
+
+// CheckCredentials obtains a token the way the configuration says, telling
+// whether sentinel can authenticate with GitHub
+func CheckCredentials(ctx context.Context, cfg *config.Config, log *logger.Logger) error {
+	if cfg.Auth == config.AuthGH {
+		return sentinelContext.CheckAuthentication(ctx, cfg.GitHubHost)
+	}
+	_, err := providedTokens(ctx, cfg, cfg.GitHubHost, log)
+	return err
+}
+
+// providedTokens returns the tokens of the token and app modes. Each new
+// token is also exported to the gh CLI, through GH_TOKEN or, on a GitHub
+// Enterprise Server, GH_ENTERPRISE_TOKEN, so that the repository lookups
+// and git pushes that go through gh use the same credentials.
+func providedTokens(ctx context.Context, cfg *config.Config, host string, log *logger.Logger) (oauth2.TokenSource, error) {
+	if host == "" {
+		host = sentinelContext.DefaultHost
+	}
+	env := "GH_TOKEN"
+	if host != sentinelContext.DefaultHost {
+		env = "GH_ENTERPRISE_TOKEN"
+	}
+
+	var source oauth2.TokenSource
+	switch cfg.Auth {
+	case config.AuthToken:
+		token := strings.TrimSpace(os.Getenv(TokenEnv))
+		if token == "" {
+			return nil, errors.AuthError("github_token", fmt.Errorf("auth: token needs a token in %s", TokenEnv))
+		}
+		source = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	case config.AuthApp:
+		key, err := appPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		api, upload := apiURLs(cfg, host)
+		source = &appTokenSource{
+			ctx:          ctx,
+			appID:        cfg.AppID,
+			installation: cfg.AppInstallationID,
+			key:          key,
+			api:          api,
+			upload:       upload,
+			transport:    newRetryTransport(timedTransport(cfg.RequestTimeout), cfg.APIRetries, cfg.APIRetryDelay, log),
+			logger:       log,
+		}
+	default:
+		return nil, errors.ValidationError("github_token", fmt.Sprintf("auth %q does not provide tokens", cfg.Auth))
+	}
+
+	// Tokens are renewed, and exported, only once they near expiry
+	ts := oauth2.ReuseTokenSource(nil, &exportedTokens{source: source, env: env})
+	if _, err := ts.Token(); err != nil {
+		return nil, err
+	}
+	log.Debug("Authenticating with GitHub through auth: %s", cfg.Auth)
+	return ts, nil
+}
+
+// exportedTokens sets an environment variable to each token of a source
+type exportedTokens struct {
+	source oauth2.TokenSource
+	env    string
+	mu     sync.Mutex
+}
+
+func (e *exportedTokens) Token() (*oauth2.Token, error) {
+	token, err := e.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if os.Getenv(e.env) != token.AccessToken {
+		os.Setenv(e.env, token.AccessToken)
+	}
+	return token, nil
+}
+
+// appPrivateKey reads the GitHub App's private key from the environment
+func appPrivateKey() (*rsa.PrivateKey, error) {
+	data := []byte(os.Getenv(AppKeyEnv))
+	source := AppKeyEnv
+	if path := os.Getenv(AppKeyFileEnv); len(data) == 0 && path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, errors.FilesystemError("github_app_key", path, err)
+		}
+		source = path
+	}
+	if len(data) == 0 {
+		return nil, errors.AuthError("github_app_key", fmt.Errorf("auth: app needs the app's private key in %s or %s", AppKeyEnv, AppKeyFileEnv))
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.AuthError("github_app_key", fmt.Errorf("%s does not hold a PEM private key", source))
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.AuthError("github_app_key", fmt.Errorf("invalid private key in %s: %v", source, err))
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.AuthError("github_app_key", fmt.Errorf("the private key in %s is not an RSA key", source))
+	}
+	return key, nil
+}
+
+// appTokenSource issues installation tokens of a GitHub App, which expire
+// after an hour. Without an installation ID, the app must have exactly one
+// installation, e.g. on the organization it automates.
+type appTokenSource struct {
+	ctx          context.Context
+	appID        int64
+	installation int64
+	key          *rsa.PrivateKey
+	api, upload  string // Empty for GitHub.com
+	transport    http.RoundTripper
+	logger       *logger.Logger
+}
+
+func (a *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return nil, errors.AuthError("github_app_token", err)
+	}
+	client := github.NewClient(&http.Client{Transport: &oauth2.Transport{
+		Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt}),
+		Base:   a.transport,
+	}})
+	if a.api != "" {
+		if client, err = client.WithEnterpriseURLs(a.api, a.upload); err != nil {
+			return nil, errors.ValidationError("github_app_token", fmt.Sprintf("invalid GitHub API URL: %v", err))
+		}
+	}
+
+	if a.installation == 0 {
+		installations, _, err := client.Apps.ListInstallations(a.ctx, &github.ListOptions{PerPage: 2})
+		if err != nil {
+			return nil, appError("list_app_installations", a.appID, err)
+		}
+		if len(installations) != 1 {
+			return nil, errors.ValidationError("github_app_token", fmt.Sprintf("GitHub App %d has %d installations; set app_installation_id to the one to use", a.appID, len(installations)))
+		}
+		a.installation = installations[0].GetID()
+	}
+
+	token, _, err := client.Apps.CreateInstallationToken(a.ctx, a.installation, nil)
+	if err != nil {
+		return nil, appError("create_installation_token", a.appID, err)
+	}
+	a.logger.Debug("Issued a token of installation %d of GitHub App %d, valid until %s", a.installation, a.appID, token.GetExpiresAt().Format(time.RFC3339))
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Add(-appTokenLeeway),
+	}, nil
+}
+
+// jwt signs the RS256 token a GitHub App authenticates as itself with
+func (a *appTokenSource) jwt(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(a.appID, 10),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the GitHub App's token: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appError explains the errors of authenticating as a GitHub App, which
+// mostly come from a wrong app ID or key
+func appError(op string, appID int64, err error) error {
+	if e := errors.GitHubAPIError(op, err); e.Type != errors.ErrTypeAuth {
+		return e
+	}
+	return errors.AuthError(op, fmt.Errorf("GitHub rejected GitHub App %d: check app_id and its private key: %w", appID, err))
+}
@@ -3,6 +3,8 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"path"
 	"strings"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	sentinelContext "gh-sentinel/internal/context"
 	"gh-sentinel/internal/errors"
 	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/workflow"
 
 	"github.com/google/go-github/v60/github"
 	"golang.org/x/oauth2"
@@ -17,38 +20,99 @@ import (
 
 // Client wraps GitHub API client with enhanced functionality
 type Client struct {
-	client  *github.Client
-	repo    *sentinelContext.RepoContext
-	config  *config.Config
-	logger  *logger.Logger
-	ctx     context.Context
+	client   *github.Client
+	repo     *sentinelContext.RepoContext
+	config   *config.Config
+	logger   *logger.Logger
+	ctx      context.Context
+	download *http.Client // For signed URLs, e.g. of logs, which need no credentials
+
+	workflowPaths map[int64]string // Workflow ID -> path, filled on demand
 }
 
 // NewClient creates a new GitHub client with automatic authentication
-func NewClient(cfg *config.Config, log *logger.Logger) (*Client, error) {
-	// Check authentication
-	if err := sentinelContext.CheckAuthentication(); err != nil {
-		return nil, err
+func NewClient(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Client, error) {
+	return NewClientFor(ctx, cfg, log, nil)
+}
+
+// NewClientFor creates a GitHub client for a repository whose context is
+// already known, e.g. from the previous session, which saves asking gh for
+// it and for the authentication status. A nil repo is detected, or looked
+// up when the configuration names one. Cancelling ctx aborts API calls.
+func NewClientFor(ctx context.Context, cfg *config.Config, log *logger.Logger, repo *sentinelContext.RepoContext) (*Client, error) {
+	// gh asks the API too, so it gets as long as an API request
+	ghCtx, cancel := context.WithTimeout(ctx, cfg.RequestTimeout)
+	defer cancel()
+
+	// A token or GitHub App of the configuration is also handed to gh,
+	// before gh looks the repository up. Its tokens are renewed for as
+	// long as ctx lasts.
+	var ts oauth2.TokenSource
+	if cfg.Auth != config.AuthGH {
+		host := cfg.GitHubHost
+		if repo != nil && repo.Host != "" {
+			host = repo.Host
+		}
+		var err error
+		if ts, err = providedTokens(ctx, cfg, host, log); err != nil {
+			return nil, err
+		}
 	}
 
-	// Detect repository context
-	repo, err := sentinelContext.DetectRepository()
-	if err != nil {
-		return nil, err
+	if repo == nil {
+		// Check authentication
+		if ts == nil {
+			if err := sentinelContext.CheckAuthentication(ghCtx, cfg.GitHubHost); err != nil {
+				return nil, err
+			}
+		}
+
+		// Detect repository context, unless --repo names it
+		var detected *sentinelContext.RepoContext
+		var err error
+		if cfg.Repository != "" {
+			detected, err = sentinelContext.LookupRepository(ghCtx, cfg.GitHubHost, cfg.Repository)
+		} else {
+			detected, err = sentinelContext.DetectRepository(ghCtx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		repo = detected
 	}
 
-	// Get auth token
-	token, err := sentinelContext.GetAuthToken()
-	if err != nil {
-		return nil, err
+	// Get the auth token of the repository's host. Repositories cached
+	// before hosts were recorded are on the configured one.
+	host := repo.Host
+	if host == "" {
+		host = cfg.GitHubHost
 	}
+	if ts == nil {
+		token, err := sentinelContext.GetAuthToken(ghCtx, host)
+		if err != nil {
+			return nil, err
+		}
+		ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	}
+	if host == "" {
+		host = sentinelContext.DefaultHost
+	}
+	repo.Host = host
 
-	// Create authenticated client
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(ctx, ts)
+	// Create authenticated client, whose requests are retried after
+	// transient errors
+	tc := &http.Client{
+		Transport: newRetryTransport(&oauth2.Transport{Source: ts, Base: timedTransport(cfg.RequestTimeout)}, cfg.APIRetries, cfg.APIRetryDelay, log),
+	}
 	
 	ghClient := github.NewClient(tc)
+	if api, upload := apiURLs(cfg, host); api != "" {
+		var err error
+		if ghClient, err = ghClient.WithEnterpriseURLs(api, upload); err != nil {
+			return nil, errors.ValidationError("github_client", fmt.Sprintf("invalid GitHub API URL: %v", err))
+		}
+		log.Debug("Using the GitHub API at %s", ghClient.BaseURL)
+	}
 	ghClient.UserAgent = cfg.UserAgent
 
 	log.Info("Authenticated as repository: %s", repo.FullName)
@@ -59,14 +123,65 @@ func NewClient(cfg *config.Config, log *logger.Logger) (*Client, error) {
 		config: cfg,
 		logger: log,
 		ctx:    ctx,
+		download: &http.Client{Transport: newRetryTransport(timedTransport(cfg.RequestTimeout), cfg.APIRetries, cfg.APIRetryDelay, log)},
+		workflowPaths: make(map[int64]string),
 	}, nil
 }
 
+// apiURLs returns the API roots of a GitHub Enterprise Server host, which
+// the settings can override, or empty ones for GitHub.com
+func apiURLs(cfg *config.Config, host string) (string, string) {
+	api, upload := cfg.GitHubAPIURL, cfg.GitHubUploadURL
+	if api == "" && host != sentinelContext.DefaultHost {
+		api = "https://" + host + "/"
+	}
+	if upload == "" {
+		upload = strings.TrimSuffix(strings.TrimSuffix(api, "/"), "/api/v3")
+	}
+	return api, upload
+}
+
+// SetContext replaces the context of API calls, so cancelling ctx aborts
+// requests in flight
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// WithContext returns a copy of the client whose API calls use ctx, e.g.
+// to clean up after the session's context is cancelled
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// RunURL is the web page of a run of the repository
+func (c *Client) RunURL(runID int64) string {
+	return fmt.Sprintf("https://%s/%s/actions/runs/%d", c.repo.Host, c.repo.FullName, runID)
+}
+
 // GetRepository returns the repository context
 func (c *Client) GetRepository() *sentinelContext.RepoContext {
 	return c.repo
 }
 
+// WorkflowPaths returns the workflow paths looked up so far, by workflow ID
+func (c *Client) WorkflowPaths() map[int64]string {
+	paths := make(map[int64]string, len(c.workflowPaths))
+	for id, path := range c.workflowPaths {
+		paths[id] = path
+	}
+	return paths
+}
+
+// SetWorkflowPaths adds known workflow paths by ID, so runs of those
+// workflows need no lookup
+func (c *Client) SetWorkflowPaths(paths map[int64]string) {
+	for id, path := range paths {
+		c.workflowPaths[id] = path
+	}
+}
+
 // WorkflowRun represents a simplified workflow run
 type WorkflowRun struct {
 	ID          int64
@@ -76,11 +191,14 @@ type WorkflowRun struct {
 	Conclusion  string
 	Event       string
 	HeadSHA     string    // Commit SHA for this run
+	HeadBranch  string
+	WorkflowID  int64
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	WorkflowPath string
 	RunNumber   int
 	Attempt     int
+	PullRequests []int // Pull requests of the same repository the run is for
 }
 
 // ListWorkflowRuns retrieves recent workflow runs
@@ -105,161 +223,389 @@ func (c *Client) ListWorkflowRuns(limit int) ([]*WorkflowRun, error) {
 
 	var result []*WorkflowRun
 	for _, run := range runs.WorkflowRuns {
-		// Construct workflow path from workflow name
-		// The API doesn't provide the exact path, so we construct it
-		workflowName := run.GetName()
-		if workflowName == "" {
-			workflowName = "unknown"
-		}
-		workflowPath := ".github/workflows/" + strings.ToLower(strings.ReplaceAll(workflowName, " ", "-")) + ".yml"
-		
-		result = append(result, &WorkflowRun{
-			ID:          run.GetID(),
-			Name:        run.GetName(),
-			DisplayTitle: run.GetDisplayTitle(),
-			Status:      run.GetStatus(),
-			Conclusion:  run.GetConclusion(),
-			Event:       run.GetEvent(),
-			HeadSHA:     run.GetHeadSHA(),
-			CreatedAt:   run.GetCreatedAt().Time,
-			UpdatedAt:   run.GetUpdatedAt().Time,
-			WorkflowPath: workflowPath,
-			RunNumber:   run.GetRunNumber(),
-			Attempt:     run.GetRunAttempt(),
-		})
+		result = append(result, c.toWorkflowRun(run))
 	}
 
 	c.logger.Debug("Retrieved %d workflow runs", len(result))
 	return result, nil
 }
 
-// GetFailedWorkflowRuns retrieves only failed workflow runs from the latest push
-func (c *Client) GetFailedWorkflowRuns(limit int) ([]*WorkflowRun, error) {
-	runs, err := c.ListWorkflowRuns(limit * 2) // Fetch more to ensure we get latest commit
+// GetWorkflowRun retrieves a single workflow run by ID
+func (c *Client) GetWorkflowRun(runID int64) (*WorkflowRun, error) {
+	run, _, err := c.client.Actions.GetWorkflowRunByID(
+		c.ctx,
+		c.repo.Owner,
+		c.repo.Name,
+		runID,
+	)
 	if err != nil {
-		return nil, err
+		return nil, errors.GitHubAPIError("get_workflow_run", err)
 	}
 
-	if len(runs) == 0 {
-		return []*WorkflowRun{}, nil
+	return c.toWorkflowRun(run), nil
+}
+
+// toWorkflowRun converts an API workflow run into a WorkflowRun
+func (c *Client) toWorkflowRun(run *github.WorkflowRun) *WorkflowRun {
+	workflowPath := c.workflowPath(run)
+
+	return &WorkflowRun{
+		ID:          run.GetID(),
+		Name:        run.GetName(),
+		DisplayTitle: run.GetDisplayTitle(),
+		Status:      run.GetStatus(),
+		Conclusion:  run.GetConclusion(),
+		Event:       run.GetEvent(),
+		HeadSHA:     run.GetHeadSHA(),
+		HeadBranch:  run.GetHeadBranch(),
+		WorkflowID:  run.GetWorkflowID(),
+		CreatedAt:   run.GetCreatedAt().Time,
+		UpdatedAt:   run.GetUpdatedAt().Time,
+		WorkflowPath: workflowPath,
+		RunNumber:   run.GetRunNumber(),
+		Attempt:     run.GetRunAttempt(),
+		PullRequests: pullNumbers(run.PullRequests),
 	}
+}
 
-	// Find the most recent commit SHA (latest push)
-	latestCommitSHA := runs[0].HeadSHA
-	
-	// Only return failed runs from the latest commit
-	var failed []*WorkflowRun
-	for _, run := range runs {
-		// Only consider runs from the latest commit
-		if run.HeadSHA != latestCommitSHA {
-			continue
+// pullNumbers returns the numbers of pull requests
+func pullNumbers(pulls []*github.PullRequest) []int {
+	var numbers []int
+	for _, pr := range pulls {
+		numbers = append(numbers, pr.GetNumber())
+	}
+	return numbers
+}
+
+// workflowPath returns the path of the workflow file a run was started from.
+// Runs only carry the workflow ID, so the workflow is looked up once per ID.
+// If the lookup fails the path is guessed from the workflow name.
+func (c *Client) workflowPath(run *github.WorkflowRun) string {
+	id := run.GetWorkflowID()
+	if path, ok := c.workflowPaths[id]; ok {
+		return path
+	}
+
+	w, _, err := c.client.Actions.GetWorkflowByID(c.ctx, c.repo.Owner, c.repo.Name, id)
+	if err == nil && w.GetPath() != "" {
+		c.workflowPaths[id] = w.GetPath()
+		return w.GetPath()
+	}
+	c.logger.Debug("Could not look up workflow %d, guessing its path from the name: %v", id, err)
+
+	workflowName := run.GetName()
+	if workflowName == "" {
+		workflowName = "unknown"
+	}
+	return ".github/workflows/" + strings.ToLower(strings.ReplaceAll(workflowName, " ", "-")) + ".yml"
+}
+
+// Active reports whether the run can still be cancelled
+func (r *WorkflowRun) Active() bool {
+	return r.Status != "completed"
+}
+
+// ListActiveRuns retrieves queued and in-progress runs for a branch
+func (c *Client) ListActiveRuns(branch string) ([]*WorkflowRun, error) {
+	var result []*WorkflowRun
+	for _, status := range []string{"queued", "in_progress"} {
+		runs, _, err := c.client.Actions.ListRepositoryWorkflowRuns(
+			c.ctx,
+			c.repo.Owner,
+			c.repo.Name,
+			&github.ListWorkflowRunsOptions{
+				Branch:      branch,
+				Status:      status,
+				ListOptions: github.ListOptions{PerPage: 100},
+			},
+		)
+		if err != nil {
+			return nil, errors.GitHubAPIError("list_active_runs", err)
 		}
-		
-		// Check if it failed
-		if run.Conclusion == "failure" || (run.Status == "completed" && run.Conclusion != "success") {
-			failed = append(failed, run)
+		for _, run := range runs.WorkflowRuns {
+			result = append(result, c.toWorkflowRun(run))
 		}
 	}
+	return result, nil
+}
 
-	c.logger.Info("Found %d failed runs from latest commit (%s)", len(failed), latestCommitSHA[:7])
-	return failed, nil
+// CancelWorkflowRun requests cancellation of a queued or in-progress run
+func (c *Client) CancelWorkflowRun(runID int64) error {
+	_, err := c.client.Actions.CancelWorkflowRunByID(c.ctx, c.repo.Owner, c.repo.Name, runID)
+	// The API answers 202 Accepted, which go-github reports as an error
+	if _, accepted := err.(*github.AcceptedError); err != nil && !accepted {
+		return errors.GitHubAPIError("cancel_workflow_run", err)
+	}
+	c.logger.Info("Requested cancellation of run %d", runID)
+	return nil
+}
+
+// RerunFailedJobs starts a new attempt of a run that re-runs only its
+// failed jobs. The new attempt uses the workflow file of the run's commit.
+func (c *Client) RerunFailedJobs(runID int64) error {
+	_, err := c.client.Actions.RerunFailedJobsByID(c.ctx, c.repo.Owner, c.repo.Name, runID)
+	if _, accepted := err.(*github.AcceptedError); err != nil && !accepted {
+		return errors.GitHubAPIError("rerun_failed_jobs", err)
+	}
+	c.logger.Info("Requested re-run of failed jobs in run %d", runID)
+	return nil
+}
+
+// ListRunsForCommit retrieves the workflow runs triggered by a commit
+func (c *Client) ListRunsForCommit(sha string) ([]*WorkflowRun, error) {
+	runs, _, err := c.client.Actions.ListRepositoryWorkflowRuns(
+		c.ctx,
+		c.repo.Owner,
+		c.repo.Name,
+		&github.ListWorkflowRunsOptions{
+			HeadSHA:     sha,
+			ListOptions: github.ListOptions{PerPage: 100},
+		},
+	)
+	if err != nil {
+		return nil, errors.GitHubAPIError("list_runs_for_commit", err)
+	}
+
+	var result []*WorkflowRun
+	for _, run := range runs.WorkflowRuns {
+		result = append(result, c.toWorkflowRun(run))
+	}
+	return result, nil
 }
 
-// GetWorkflowJobLogs retrieves logs for all failed jobs in a workflow run
-func (c *Client) GetWorkflowJobLogs(runID int64) (string, error) {
+// ListFailedRuns retrieves the most recent failed runs on a branch, newest
+// first
+func (c *Client) ListFailedRuns(branch string, limit int) ([]*WorkflowRun, error) {
+	runs, _, err := c.client.Actions.ListRepositoryWorkflowRuns(
+		c.ctx,
+		c.repo.Owner,
+		c.repo.Name,
+		&github.ListWorkflowRunsOptions{
+			Branch:      branch,
+			Status:      "failure",
+			ListOptions: github.ListOptions{PerPage: limit},
+		},
+	)
+	if err != nil {
+		return nil, errors.GitHubAPIError("list_failed_runs", err)
+	}
+
+	var result []*WorkflowRun
+	for _, run := range runs.WorkflowRuns {
+		result = append(result, c.toWorkflowRun(run))
+	}
+	return result, nil
+}
+
+// ListCompletedRuns retrieves the completed runs on a branch created since a
+// time, newest first, stopping after limit runs
+func (c *Client) ListCompletedRuns(branch string, since time.Time, limit int) ([]*WorkflowRun, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Branch:      branch,
+		Status:      "completed",
+		Created:     ">=" + since.UTC().Format(time.RFC3339),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var result []*WorkflowRun
+	for len(result) < limit {
+		runs, resp, err := c.client.Actions.ListRepositoryWorkflowRuns(c.ctx, c.repo.Owner, c.repo.Name, opts)
+		if err != nil {
+			return nil, errors.GitHubAPIError("list_completed_runs", err)
+		}
+		for _, run := range runs.WorkflowRuns {
+			if len(result) < limit {
+				result = append(result, c.toWorkflowRun(run))
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return result, nil
+}
+
+// ListWorkflowHistory retrieves the latest completed runs of a workflow on
+// a branch, newest first
+func (c *Client) ListWorkflowHistory(workflowID int64, branch string, limit int) ([]*WorkflowRun, error) {
+	runs, _, err := c.client.Actions.ListWorkflowRunsByID(
+		c.ctx,
+		c.repo.Owner,
+		c.repo.Name,
+		workflowID,
+		&github.ListWorkflowRunsOptions{
+			Branch:      branch,
+			Status:      "completed",
+			ListOptions: github.ListOptions{PerPage: limit},
+		},
+	)
+	if err != nil {
+		return nil, errors.GitHubAPIError("list_workflow_history", err)
+	}
+
+	var result []*WorkflowRun
+	for _, run := range runs.WorkflowRuns {
+		result = append(result, c.toWorkflowRun(run))
+	}
+	return result, nil
+}
+
+// Job is a job of a workflow run attempt
+type Job struct {
+	ID         int64
+	Name       string
+	Status     string
+	Conclusion string
+	Labels     []string // runs-on labels
+	Steps      []JobStep
+}
+
+// JobStep is one step of a job
+type JobStep struct {
+	Number      int64
+	Name        string
+	Conclusion  string
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// Duration returns how long the step ran, or 0 if it did not complete
+func (s JobStep) Duration() time.Duration {
+	if s.StartedAt.IsZero() || s.CompletedAt.Before(s.StartedAt) {
+		return 0
+	}
+	return s.CompletedAt.Sub(s.StartedAt)
+}
+
+// ListRunJobs retrieves the jobs of a run's latest attempt
+func (c *Client) ListRunJobs(runID int64) ([]*Job, error) {
 	jobs, _, err := c.client.Actions.ListWorkflowJobs(
 		c.ctx,
 		c.repo.Owner,
 		c.repo.Name,
 		runID,
-		nil,
+		&github.ListWorkflowJobsOptions{
+			Filter:      "latest",
+			ListOptions: github.ListOptions{PerPage: 100},
+		},
 	)
 	if err != nil {
-		return "", errors.GitHubAPIError("list_workflow_jobs", err)
+		return nil, errors.GitHubAPIError("list_run_jobs", err)
 	}
 
-	var logBuilder strings.Builder
-	failedCount := 0
-	
-	// First pass: try to get logs from explicitly failed jobs
+	var result []*Job
 	for _, job := range jobs.Jobs {
-		if job.GetConclusion() == "failure" {
-			failedCount++
-			logBuilder.WriteString(fmt.Sprintf("\n=== Job: %s (ID: %d) ===\n", job.GetName(), job.GetID()))
-			
-			// Get job logs
-			logs, _, err := c.client.Actions.GetWorkflowJobLogs(
-				c.ctx,
-				c.repo.Owner,
-				c.repo.Name,
-				job.GetID(),
-				2, // Follow redirects
-			)
-			if err != nil {
-				c.logger.Warn("Failed to get logs for job %d: %v", job.GetID(), err)
-				continue
-			}
+		result = append(result, toJob(job))
+	}
+	return result, nil
+}
 
-			logBuilder.WriteString(logs.String())
-			logBuilder.WriteString("\n")
-		}
+// toJob converts a workflow job with its steps
+func toJob(job *github.WorkflowJob) *Job {
+	j := &Job{
+		ID:         job.GetID(),
+		Name:       job.GetName(),
+		Status:     job.GetStatus(),
+		Conclusion: job.GetConclusion(),
+		Labels:     job.Labels,
 	}
+	for _, step := range job.Steps {
+		j.Steps = append(j.Steps, JobStep{
+			Number:      step.GetNumber(),
+			Name:        step.GetName(),
+			Conclusion:  step.GetConclusion(),
+			StartedAt:   step.GetStartedAt().Time,
+			CompletedAt: step.GetCompletedAt().Time,
+		})
+	}
+	return j
+}
+
+// RunFilter narrows down the runs GetFailedWorkflowRuns looks at. The zero
+// value looks at every run of the newest commit.
+type RunFilter struct {
+	Branch   string // Branch the runs ran on
+	Workflow string // Workflow file, e.g. ci.yml or .github/workflows/ci.yml
+	Event    string // Event that triggered the runs, e.g. push or pull_request
+	Actor    string // User whose push or action triggered the runs
+	Commits  int    // Newest commits to look at; 0 means 1
+}
 
-	// If no failed jobs found, try cancelled or incomplete jobs
-	if failedCount == 0 {
-		c.logger.Debug("No failed jobs found, checking cancelled/skipped jobs")
-		for _, job := range jobs.Jobs {
-			conclusion := job.GetConclusion()
-			status := job.GetStatus()
-			
-			// Include cancelled, timed_out, or still in_progress jobs
-			if conclusion == "cancelled" || conclusion == "timed_out" || 
-			   (status == "completed" && conclusion != "success" && conclusion != "skipped") {
-				failedCount++
-				logBuilder.WriteString(fmt.Sprintf("\n=== Job: %s (Status: %s, Conclusion: %s) ===\n", 
-					job.GetName(), status, conclusion))
-				
-				logs, _, err := c.client.Actions.GetWorkflowJobLogs(
-					c.ctx,
-					c.repo.Owner,
-					c.repo.Name,
-					job.GetID(),
-					2,
-				)
-				if err != nil {
-					logBuilder.WriteString(fmt.Sprintf("[Could not retrieve logs: %v]\n", err))
-					continue
-				}
-
-				logBuilder.WriteString(logs.String())
-				logBuilder.WriteString("\n")
+// GetFailedWorkflowRuns retrieves the failed workflow runs of the latest
+// commits that have runs matching the filter, newest first
+func (c *Client) GetFailedWorkflowRuns(limit int, filter RunFilter) ([]*WorkflowRun, error) {
+	commits := filter.Commits
+	if commits <= 0 {
+		commits = 1
+	}
+	perPage := limit * 2 // Fetch more to ensure we get every run of the latest commits
+	if commits > 1 {
+		perPage = 100
+	}
+	opts := &github.ListWorkflowRunsOptions{
+		Branch:      filter.Branch,
+		Event:       filter.Event,
+		Actor:       filter.Actor,
+		ListOptions: github.ListOptions{PerPage: perPage},
+	}
+
+	var runs *github.WorkflowRuns
+	var err error
+	if filter.Workflow != "" {
+		runs, _, err = c.client.Actions.ListWorkflowRunsByFileName(c.ctx, c.repo.Owner, c.repo.Name, path.Base(filter.Workflow), opts)
+	} else {
+		runs, _, err = c.client.Actions.ListRepositoryWorkflowRuns(c.ctx, c.repo.Owner, c.repo.Name, opts)
+	}
+	if err != nil {
+		return nil, errors.GitHubAPIError("list_workflow_runs", err)
+	}
+	if len(runs.WorkflowRuns) == 0 {
+		return []*WorkflowRun{}, nil
+	}
+
+	// Only return failed runs from the latest commits (latest pushes)
+	latest := make(map[string]bool)
+	var failed []*WorkflowRun
+	for _, run := range runs.WorkflowRuns {
+		sha := run.GetHeadSHA()
+		if !latest[sha] {
+			if len(latest) == commits {
+				continue
 			}
+			latest[sha] = true
 		}
-	}
 
-	// If still no logs, the workflow might have failed at configuration level
-	if failedCount == 0 {
-		c.logger.Warn("Workflow run marked as failed but contains no failed/cancelled jobs")
-		return "", errors.ValidationError("get_workflow_job_logs", 
-			"workflow failed but no job logs available (possible configuration error)")
+		// Check if it failed
+		if run.GetConclusion() == "failure" || (run.GetStatus() == "completed" && run.GetConclusion() != "success") {
+			failed = append(failed, c.toWorkflowRun(run))
+		}
 	}
 
-	c.logger.Debug("Retrieved logs from %d jobs", failedCount)
-	
-	// Truncate if needed
-	result := logBuilder.String()
-	if len(result) > c.config.MaxLogSize {
-		truncated := "... [LOGS TRUNCATED FOR SAFETY] ...\n" + result[len(result)-c.config.MaxLogSize:]
-		c.logger.Warn("Logs truncated from %d to %d characters", len(result), len(truncated))
-		return truncated, nil
+	newest := runs.WorkflowRuns[0].GetHeadSHA()
+	if commits == 1 {
+		c.logger.Info("Found %d failed runs from latest commit (%s)", len(failed), shortSHA(newest))
+	} else {
+		c.logger.Info("Found %d failed runs from the latest %d commits (newest %s)", len(failed), len(latest), shortSHA(newest))
 	}
+	return failed, nil
+}
 
-	return result, nil
+// shortSHA abbreviates a commit SHA for messages
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
 }
 
-// ListWorkflowFiles retrieves all workflow YAML files from .github/workflows
+// ListWorkflowFiles retrieves all workflow YAML files from .github/workflows,
+// by name, followed by the action.yml of the local actions in
+// .github/actions, by path. A repository without the directory on its
+// default branch has no workflows: its runs may come from another branch or
+// from reusable workflows elsewhere.
 func (c *Client) ListWorkflowFiles() ([]string, error) {
-	_, directoryContent, _, err := c.client.Repositories.GetContents(
+	_, directoryContent, resp, err := c.client.Repositories.GetContents(
 		c.ctx,
 		c.repo.Owner,
 		c.repo.Name,
@@ -267,6 +613,10 @@ func (c *Client) ListWorkflowFiles() ([]string, error) {
 		nil,
 	)
 	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			c.logger.Debug("%s has no .github/workflows directory", c.repo.FullName)
+			return nil, nil
+		}
 		return nil, errors.GitHubAPIError("list_workflow_files", err)
 	}
 
@@ -279,13 +629,56 @@ func (c *Client) ListWorkflowFiles() ([]string, error) {
 	}
 
 	c.logger.Debug("Found %d workflow files", len(files))
+
+	// Failures often come from the actions the workflows run
+	actions, err := c.listActionFiles()
+	if err != nil {
+		c.logger.Debug("Could not list the local actions: %v", err)
+		return files, nil
+	}
+	return append(files, actions...), nil
+}
+
+// listActionFiles returns the paths of the action.yml of the actions in
+// .github/actions, at any depth. The directory's tree is read in one
+// request rather than one per action.
+func (c *Client) listActionFiles() ([]string, error) {
+	_, directoryContent, resp, err := c.client.Repositories.GetContents(c.ctx, c.repo.Owner, c.repo.Name, ".github", nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, errors.GitHubAPIError("list_action_files", err)
+	}
+	sha := ""
+	for _, entry := range directoryContent {
+		if entry.GetName() == path.Base(workflow.ActionsDir) && entry.GetType() == "dir" {
+			sha = entry.GetSHA()
+		}
+	}
+	if sha == "" {
+		return nil, nil
+	}
+
+	tree, _, err := c.client.Git.GetTree(c.ctx, c.repo.Owner, c.repo.Name, sha, true)
+	if err != nil {
+		return nil, errors.GitHubAPIError("list_action_files", err)
+	}
+	var files []string
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" && workflow.IsAction(entry.GetPath()) {
+			files = append(files, workflow.ActionsDir+"/"+entry.GetPath())
+		}
+	}
+	c.logger.Debug("Found %d local actions", len(files))
 	return files, nil
 }
 
-// GetWorkflowFileContent retrieves the content of a workflow file
+// GetWorkflowFileContent retrieves the content of a workflow file, or of the
+// action.yml of a local action
 func (c *Client) GetWorkflowFileContent(path string) (string, error) {
-	// Ensure path starts with .github/workflows
-	if !strings.HasPrefix(path, ".github/workflows/") {
+	// Ensure path starts with .github/workflows, unless it is an action's
+	if !strings.HasPrefix(path, ".github/workflows/") && !workflow.IsAction(path) {
 		path = ".github/workflows/" + strings.TrimPrefix(path, "/")
 	}
 
@@ -307,3 +700,263 @@ func (c *Client) GetWorkflowFileContent(path string) (string, error) {
 
 	return content, nil
 }
+
+// GetRemoteFileContent retrieves a file of another repository at a ref,
+// e.g. a reusable workflow this repository calls. It is read with the same
+// credentials, so private repositories need access.
+func (c *Client) GetRemoteFileContent(repository, path, ref string) (string, error) {
+	owner, name, err := splitRepository("get_remote_file_content", repository)
+	if err != nil {
+		return "", err
+	}
+	fileContent, _, _, err := c.client.Repositories.GetContents(
+		c.ctx,
+		owner,
+		name,
+		path,
+		&github.RepositoryContentGetOptions{Ref: ref},
+	)
+	if err != nil {
+		return "", errors.GitHubAPIError("get_remote_file_content", err).WithPath(repository + "/" + path)
+	}
+	if fileContent == nil {
+		return "", errors.ValidationError("get_remote_file_content", "not a file").WithPath(repository + "/" + path)
+	}
+	content, err := fileContent.GetContent()
+	if err != nil {
+		return "", errors.ValidationError("get_remote_file_content", "failed to decode file content").WithPath(repository + "/" + path)
+	}
+	return content, nil
+}
+
+// FileCommit is the latest commit that changed a file
+type FileCommit struct {
+	SHA    string
+	Author string // Login, or the commit author's name without an account
+	Date   time.Time
+}
+
+// LatestFileCommit returns the latest commit of the default branch that
+// changed a workflow file, or nil if none did. It is a cheap way to tell
+// whether the file changed since it was fetched.
+func (c *Client) LatestFileCommit(path string) (*FileCommit, error) {
+	if !strings.HasPrefix(path, ".github/workflows/") {
+		path = ".github/workflows/" + strings.TrimPrefix(path, "/")
+	}
+
+	commits, _, err := c.client.Repositories.ListCommits(c.ctx, c.repo.Owner, c.repo.Name, &github.CommitsListOptions{
+		Path:        path,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return nil, errors.GitHubAPIError("latest_file_commit", err).WithPath(path)
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	commit := commits[0]
+	author := commit.GetAuthor().GetLogin()
+	if author == "" {
+		author = commit.GetCommit().GetAuthor().GetName()
+	}
+	return &FileCommit{
+		SHA:    commit.GetSHA(),
+		Author: author,
+		Date:   commit.GetCommit().GetCommitter().GetDate().Time,
+	}, nil
+}
+
+// Workflow state reported by the API when a workflow was disabled by hand
+const WorkflowStateDisabled = "disabled_manually"
+
+// Workflow represents a workflow definition and whether it is enabled
+type Workflow struct {
+	ID    int64
+	Name  string
+	Path  string
+	State string
+}
+
+// Disabled reports whether the workflow was disabled manually
+func (w *Workflow) Disabled() bool {
+	return w.State == WorkflowStateDisabled
+}
+
+// ListWorkflows retrieves every workflow defined in the repository
+func (c *Client) ListWorkflows() ([]*Workflow, error) {
+	workflows, _, err := c.client.Actions.ListWorkflows(
+		c.ctx,
+		c.repo.Owner,
+		c.repo.Name,
+		&github.ListOptions{PerPage: 100},
+	)
+	if err != nil {
+		return nil, errors.GitHubAPIError("list_workflows", err)
+	}
+
+	var result []*Workflow
+	for _, w := range workflows.Workflows {
+		result = append(result, toWorkflow(w))
+	}
+	return result, nil
+}
+
+// GetWorkflow retrieves a workflow by its path or file name
+func (c *Client) GetWorkflow(path string) (*Workflow, error) {
+	name := path[strings.LastIndex(path, "/")+1:]
+	w, _, err := c.client.Actions.GetWorkflowByFileName(
+		c.ctx,
+		c.repo.Owner,
+		c.repo.Name,
+		name,
+	)
+	if err != nil {
+		return nil, errors.GitHubAPIError("get_workflow", err)
+	}
+	return toWorkflow(w), nil
+}
+
+// DisableWorkflow stops a workflow from being triggered
+func (c *Client) DisableWorkflow(workflowID int64) error {
+	if _, err := c.client.Actions.DisableWorkflowByID(c.ctx, c.repo.Owner, c.repo.Name, workflowID); err != nil {
+		return errors.GitHubAPIError("disable_workflow", err)
+	}
+	c.logger.Info("Disabled workflow %d", workflowID)
+	return nil
+}
+
+// EnableWorkflow re-enables a disabled workflow
+func (c *Client) EnableWorkflow(workflowID int64) error {
+	if _, err := c.client.Actions.EnableWorkflowByID(c.ctx, c.repo.Owner, c.repo.Name, workflowID); err != nil {
+		return errors.GitHubAPIError("enable_workflow", err)
+	}
+	c.logger.Info("Enabled workflow %d", workflowID)
+	return nil
+}
+
+func toWorkflow(w *github.Workflow) *Workflow {
+	return &Workflow{
+		ID:    w.GetID(),
+		Name:  w.GetName(),
+		Path:  w.GetPath(),
+		State: w.GetState(),
+	}
+}
+
+// ListSecretNames returns the names of the Actions secrets available to the
+// repository: its own and those shared by its organization. Secret values
+// are never returned by the API.
+func (c *Client) ListSecretNames() (map[string]bool, error) {
+	names, err := c.collectNames("list_repo_secrets", func(opts *github.ListOptions) ([]string, *github.Response, error) {
+		secrets, resp, err := c.client.Actions.ListRepoSecrets(c.ctx, c.repo.Owner, c.repo.Name, opts)
+		return secretNames(secrets), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := c.collectNames("list_repo_org_secrets", func(opts *github.ListOptions) ([]string, *github.Response, error) {
+		secrets, resp, err := c.client.Actions.ListRepoOrgSecrets(c.ctx, c.repo.Owner, c.repo.Name, opts)
+		return secretNames(secrets), resp, err
+	})
+	if err != nil {
+		// Personal repositories have no organization secrets
+		c.logger.Debug("Skipping organization secrets: %v", err)
+	}
+	for name := range org {
+		names[name] = true
+	}
+	return names, nil
+}
+
+// ListVariableNames returns the names of the Actions configuration variables
+// available to the repository, including organization variables
+func (c *Client) ListVariableNames() (map[string]bool, error) {
+	names, err := c.collectNames("list_repo_variables", func(opts *github.ListOptions) ([]string, *github.Response, error) {
+		vars, resp, err := c.client.Actions.ListRepoVariables(c.ctx, c.repo.Owner, c.repo.Name, opts)
+		return variableNames(vars), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := c.collectNames("list_repo_org_variables", func(opts *github.ListOptions) ([]string, *github.Response, error) {
+		vars, resp, err := c.client.Actions.ListRepoOrgVariables(c.ctx, c.repo.Owner, c.repo.Name, opts)
+		return variableNames(vars), resp, err
+	})
+	if err != nil {
+		c.logger.Debug("Skipping organization variables: %v", err)
+	}
+	for name := range org {
+		names[name] = true
+	}
+	return names, nil
+}
+
+// ListEnvironmentNames returns the secret and variable names defined on a
+// deployment environment
+func (c *Client) ListEnvironmentNames(env string) (secrets, vars map[string]bool, err error) {
+	repo, _, err := c.client.Repositories.Get(c.ctx, c.repo.Owner, c.repo.Name)
+	if err != nil {
+		return nil, nil, errors.GitHubAPIError("get_repository", err)
+	}
+	repoID := int(repo.GetID())
+
+	secrets, err = c.collectNames("list_env_secrets", func(opts *github.ListOptions) ([]string, *github.Response, error) {
+		s, resp, err := c.client.Actions.ListEnvSecrets(c.ctx, repoID, env, opts)
+		return secretNames(s), resp, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	vars, err = c.collectNames("list_env_variables", func(opts *github.ListOptions) ([]string, *github.Response, error) {
+		v, resp, err := c.client.Actions.ListEnvVariables(c.ctx, repoID, env, opts)
+		return variableNames(v), resp, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return secrets, vars, nil
+}
+
+// collectNames pages through a list endpoint and gathers the names
+func (c *Client) collectNames(op string, list func(opts *github.ListOptions) ([]string, *github.Response, error)) (map[string]bool, error) {
+	names := make(map[string]bool)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := list(opts)
+		if err != nil {
+			return nil, errors.GitHubAPIError(op, err)
+		}
+		for _, name := range page {
+			names[name] = true
+		}
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func secretNames(secrets *github.Secrets) []string {
+	if secrets == nil {
+		return nil
+	}
+	names := make([]string, 0, len(secrets.Secrets))
+	for _, s := range secrets.Secrets {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func variableNames(vars *github.ActionsVariables) []string {
+	if vars == nil {
+		return nil
+	}
+	names := make([]string, 0, len(vars.Variables))
+	for _, v := range vars.Variables {
+		names = append(names, v.Name)
+	}
+	return names
+}
@@ -0,0 +1,103 @@
+package github
+
+import (
+	"strings"
+
+	"gh-sentinel/internal/errors"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// Comment is a comment sentinel posted or updated
+type Comment struct {
+	URL     string
+	Updated bool // An earlier comment with the same marker was edited
+}
+
+// RunPullRequest returns the number of the open pull request a run was
+// triggered for, or 0 when the run is not of a pull request. Runs of pull
+// requests from forks do not list theirs, so it is then looked up by the
+// run's commit.
+func (c *Client) RunPullRequest(run *WorkflowRun) (int, error) {
+	if len(run.PullRequests) > 0 {
+		return run.PullRequests[0], nil
+	}
+	if !strings.HasPrefix(run.Event, "pull_request") {
+		return 0, nil
+	}
+	pulls, _, err := c.client.PullRequests.ListPullRequestsWithCommit(c.ctx, c.repo.Owner, c.repo.Name, run.HeadSHA, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return 0, errors.GitHubAPIError("find_pull_request", err)
+	}
+	for _, pr := range pulls {
+		if pr.GetState() == "open" && pr.GetHead().GetSHA() == run.HeadSHA {
+			return pr.GetNumber(), nil
+		}
+	}
+	return 0, nil
+}
+
+// CommentOnPullRequest posts a comment on a pull request, or edits the
+// earlier one whose body contains marker, so that posting again does not
+// add another
+func (c *Client) CommentOnPullRequest(number int, marker, body string) (*Comment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := c.client.Issues.ListComments(c.ctx, c.repo.Owner, c.repo.Name, number, opts)
+		if err != nil {
+			return nil, errors.GitHubAPIError("list_pull_request_comments", err)
+		}
+		for _, comment := range comments {
+			if !strings.Contains(comment.GetBody(), marker) {
+				continue
+			}
+			edited, _, err := c.client.Issues.EditComment(c.ctx, c.repo.Owner, c.repo.Name, comment.GetID(), &github.IssueComment{Body: github.String(body)})
+			if err != nil {
+				return nil, errors.GitHubAPIError("edit_pull_request_comment", err)
+			}
+			return &Comment{URL: edited.GetHTMLURL(), Updated: true}, nil
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	created, _, err := c.client.Issues.CreateComment(c.ctx, c.repo.Owner, c.repo.Name, number, &github.IssueComment{Body: github.String(body)})
+	if err != nil {
+		return nil, errors.GitHubAPIError("create_pull_request_comment", err)
+	}
+	return &Comment{URL: created.GetHTMLURL()}, nil
+}
+
+// CommentOnCommit posts a comment on a commit, or edits the earlier one
+// whose body contains marker
+func (c *Client) CommentOnCommit(sha, marker, body string) (*Comment, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		comments, resp, err := c.client.Repositories.ListCommitComments(c.ctx, c.repo.Owner, c.repo.Name, sha, opts)
+		if err != nil {
+			return nil, errors.GitHubAPIError("list_commit_comments", err)
+		}
+		for _, comment := range comments {
+			if !strings.Contains(comment.GetBody(), marker) {
+				continue
+			}
+			edited, _, err := c.client.Repositories.UpdateComment(c.ctx, c.repo.Owner, c.repo.Name, comment.GetID(), &github.RepositoryComment{Body: github.String(body)})
+			if err != nil {
+				return nil, errors.GitHubAPIError("edit_commit_comment", err)
+			}
+			return &Comment{URL: edited.GetHTMLURL(), Updated: true}, nil
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	created, _, err := c.client.Repositories.CreateComment(c.ctx, c.repo.Owner, c.repo.Name, sha, &github.RepositoryComment{Body: github.String(body)})
+	if err != nil {
+		return nil, errors.GitHubAPIError("create_commit_comment", err)
+	}
+	return &Comment{URL: created.GetHTMLURL()}, nil
+}
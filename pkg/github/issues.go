@@ -0,0 +1,61 @@
+package github
+
+import (
+	"strings"
+
+	"gh-sentinel/internal/errors"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// Issue is an issue sentinel filed, or commented on
+type Issue struct {
+	Number   int
+	URL      string
+	Existing bool // An open issue with the same marker got a comment instead
+}
+
+// FileIssue opens an issue with the given labels and assignees. When an open
+// issue with those labels already contains marker, body is added to it as a
+// comment instead, so that a failure that keeps coming back is tracked in
+// one place.
+func (c *Client) FileIssue(marker, title, body string, labels, assignees []string) (*Issue, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      labels,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, resp, err := c.client.Issues.ListByRepo(c.ctx, c.repo.Owner, c.repo.Name, opts)
+		if err != nil {
+			return nil, errors.GitHubAPIError("list_issues", err)
+		}
+		for _, issue := range issues {
+			if issue.IsPullRequest() || !strings.Contains(issue.GetBody(), marker) {
+				continue
+			}
+			comment, _, err := c.client.Issues.CreateComment(c.ctx, c.repo.Owner, c.repo.Name, issue.GetNumber(), &github.IssueComment{Body: github.String(body)})
+			if err != nil {
+				return nil, errors.GitHubAPIError("comment_on_issue", err)
+			}
+			return &Issue{Number: issue.GetNumber(), URL: comment.GetHTMLURL(), Existing: true}, nil
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	req := &github.IssueRequest{Title: github.String(title), Body: github.String(body)}
+	if len(labels) > 0 {
+		req.Labels = &labels
+	}
+	if len(assignees) > 0 {
+		req.Assignees = &assignees
+	}
+	created, _, err := c.client.Issues.Create(c.ctx, c.repo.Owner, c.repo.Name, req)
+	if err != nil {
+		return nil, errors.GitHubAPIError("create_issue", err)
+	}
+	return &Issue{Number: created.GetNumber(), URL: created.GetHTMLURL()}, nil
+}
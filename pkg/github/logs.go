@@ -0,0 +1,300 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// logTimestampRe matches the timestamp GitHub prefixes each log line with
+var logTimestampRe = regexp.MustCompile(`^(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?Z) ?`)
+
+// StepLog is the part of a job's log a step wrote
+type StepLog struct {
+	JobStep
+	Log string
+}
+
+// Failed reports whether the step failed
+func (s *StepLog) Failed() bool {
+	return s.Conclusion == "failure" || s.Conclusion == "timed_out"
+}
+
+// JobLogs is the log of a job, split by step
+type JobLogs struct {
+	Job   *Job
+	Steps []StepLog
+	Note  string // Why the log is missing, if it is
+}
+
+// FailedStep returns the first step that failed, or nil
+func (j *JobLogs) FailedStep() *StepLog {
+	for i := range j.Steps {
+		if j.Steps[i].Failed() {
+			return &j.Steps[i]
+		}
+	}
+	return nil
+}
+
+// logWorkers is how many job logs are downloaded at once
+const logWorkers = 4
+
+// GetWorkflowJobLogs retrieves the logs of the failed jobs of a run, split by
+// step. Without failed jobs, the cancelled and incomplete ones are returned.
+func (c *Client) GetWorkflowJobLogs(runID int64) ([]*JobLogs, error) {
+	jobs, stream, err := c.StreamWorkflowJobLogs(runID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*JobLogs, len(jobs))
+	for logs := range stream {
+		byID[logs.Job.ID] = logs
+	}
+	result := make([]*JobLogs, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, byID[job.ID])
+	}
+	return result, nil
+}
+
+// StreamWorkflowJobLogs selects the jobs GetWorkflowJobLogs returns and
+// downloads their logs concurrently. Each job's logs are sent as soon as they
+// arrive, in no particular order, and the channel is closed after the last
+// one. The jobs are returned in the order of the run. When there are many
+// jobs, the run's log archive is downloaded instead, and only the jobs it
+// lacks are downloaded one by one.
+func (c *Client) StreamWorkflowJobLogs(runID int64) ([]*Job, <-chan *JobLogs, error) {
+	jobs, err := c.failedJobs(runID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Buffered so that the downloads finish even if nobody reads them
+	stream := make(chan *JobLogs, len(jobs))
+	queue := make(chan *Job, len(jobs))
+	var archived map[int64]*JobLogs
+	if len(jobs) >= archiveMinJobs {
+		if archived, err = c.archiveJobLogs(runID, jobs); err != nil {
+			c.logger.Debug("Could not use the log archive of run %d, fetching job logs one by one: %v", runID, err)
+		}
+	}
+	for _, job := range jobs {
+		if logs, ok := archived[job.ID]; ok {
+			stream <- logs
+		} else {
+			queue <- job
+		}
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(logWorkers, len(queue)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				stream <- c.downloadJobLogs(job)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		c.logger.Debug("Retrieved logs from %d jobs", len(jobs))
+		close(stream)
+	}()
+	return jobs, stream, nil
+}
+
+// failedJobs returns the failed jobs of a run, or the cancelled and
+// incomplete ones when none failed
+func (c *Client) failedJobs(runID int64) ([]*Job, error) {
+	jobs, err := c.ListRunJobs(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []*Job
+	for _, job := range jobs {
+		if job.Conclusion == "failure" {
+			selected = append(selected, job)
+		}
+	}
+	if len(selected) == 0 {
+		c.logger.Debug("No failed jobs found, checking cancelled/skipped jobs")
+		for _, job := range jobs {
+			if job.Conclusion == "cancelled" || job.Conclusion == "timed_out" ||
+				(job.Status == "completed" && job.Conclusion != "success" && job.Conclusion != "skipped") {
+				selected = append(selected, job)
+			}
+		}
+	}
+
+	// The workflow might have failed at configuration level
+	if len(selected) == 0 {
+		c.logger.Warn("Workflow run marked as failed but contains no failed/cancelled jobs")
+		return nil, errors.ValidationError("get_workflow_job_logs",
+			"workflow failed but no job logs available (possible configuration error)")
+	}
+	return selected, nil
+}
+
+// downloadJobLogs downloads a job's log and splits it by step. A log that
+// cannot be downloaded is noted instead.
+func (c *Client) downloadJobLogs(job *Job) *JobLogs {
+	text, err := c.jobLog(job.ID)
+	if err != nil {
+		c.logger.Warn("Failed to get logs for job %d: %v", job.ID, err)
+		return &JobLogs{Job: job, Steps: splitSteps(job, ""), Note: fmt.Sprintf("Could not retrieve logs: %v", err)}
+	}
+	return &JobLogs{Job: job, Steps: splitSteps(job, text)}
+}
+
+// jobLog downloads the plain-text log of a job. The API answers with a
+// short-lived signed URL that needs no credentials.
+func (c *Client) jobLog(jobID int64) (string, error) {
+	u, _, err := c.client.Actions.GetWorkflowJobLogs(c.ctx, c.repo.Owner, c.repo.Name, jobID, 2)
+	if err != nil {
+		return "", errors.GitHubAPIError("get_job_logs", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.config.RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.download.Do(req)
+	if err != nil {
+		return "", errors.NetworkError("get_job_logs", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.NetworkError("get_job_logs", fmt.Errorf("%s", resp.Status))
+	}
+	// Keep the tail of oversized logs, where failures are
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.NetworkError("get_job_logs", err)
+	}
+	if len(data) > c.config.MaxRawLogSize {
+		data = data[len(data)-c.config.MaxRawLogSize:]
+	}
+	return string(data), nil
+}
+
+// splitSteps attributes each line of a job's log to the step that was
+// running when it was written, judged by its timestamp. Timestamps are
+// dropped from the lines.
+func splitSteps(job *Job, text string) []StepLog {
+	steps := make([]StepLog, 0, len(job.Steps))
+	for _, step := range job.Steps {
+		steps = append(steps, StepLog{JobStep: step})
+	}
+	if len(steps) == 0 {
+		steps = append(steps, StepLog{JobStep: JobStep{Name: job.Name, Conclusion: job.Conclusion}})
+	}
+	if text == "" {
+		return steps
+	}
+
+	logs := make([]strings.Builder, len(steps))
+	current := 0
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if m := logTimestampRe.FindStringSubmatch(line); m != nil {
+			line = line[len(m[0]):]
+			if at, err := time.Parse(time.RFC3339Nano, m[1]); err == nil {
+				current = stepAt(steps, at, current)
+			}
+		}
+		logs[current].WriteString(line)
+		logs[current].WriteString("\n")
+	}
+	for i := range steps {
+		steps[i].Log = logs[i].String()
+	}
+	return steps
+}
+
+// stepAt returns the last step started by a time. Step times are truncated
+// to the second, so a line belongs to the latest step started in its second
+// or before. Without step times, lines stay with the current step.
+func stepAt(steps []StepLog, at time.Time, current int) int {
+	at = at.Truncate(time.Second)
+	found := -1
+	for i, step := range steps {
+		if !step.StartedAt.IsZero() && !step.StartedAt.After(at) {
+			found = i
+		}
+	}
+	if found < 0 {
+		return current
+	}
+	return found
+}
+
+// FormatJobLogs renders job logs as text, each job and step under its own
+// header. When the text exceeds max, the logs of steps that did not fail are
+// left out, then the head of the text is cut.
+func FormatJobLogs(jobs []*JobLogs, max int) string {
+	text := formatJobLogs(jobs, false)
+	if len(text) <= max {
+		return text
+	}
+	text = formatJobLogs(jobs, true)
+	if len(text) <= max {
+		return text
+	}
+	return "... [LOGS TRUNCATED FOR SAFETY] ...\n" + text[len(text)-max:]
+}
+
+// formatJobLogs renders job logs, leaving out the logs of steps that did not
+// fail when failedOnly is set
+func formatJobLogs(jobs []*JobLogs, failedOnly bool) string {
+	var b strings.Builder
+	for _, job := range jobs {
+		fmt.Fprintf(&b, "\n=== Job: %s (ID: %d, conclusion: %s, runs-on: %s) ===\n",
+			job.Job.Name, job.Job.ID, job.Job.Conclusion, strings.Join(job.Job.Labels, ", "))
+		if step := job.FailedStep(); step != nil {
+			fmt.Fprintf(&b, "Failed step: %s\n", step.Name)
+		}
+		if job.Note != "" {
+			fmt.Fprintf(&b, "[%s]\n", job.Note)
+		}
+		for _, step := range job.Steps {
+			fmt.Fprintf(&b, "--- %s ---\n", step.Header())
+			if failedOnly && !step.Failed() && step.Log != "" {
+				b.WriteString("[log omitted: the step did not fail]\n")
+			} else {
+				b.WriteString(step.Log)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Header describes a step: its number, name, conclusion and duration
+func (s *StepLog) Header() string {
+	header := s.Name
+	if s.Number > 0 {
+		header = fmt.Sprintf("Step %d: %s", s.Number, s.Name)
+	}
+	var details []string
+	if s.Conclusion != "" {
+		details = append(details, s.Conclusion)
+	}
+	if d := s.Duration(); d > 0 {
+		details = append(details, d.Round(time.Second).String())
+	}
+	if len(details) > 0 {
+		header += " (" + strings.Join(details, ", ") + ")"
+	}
+	return header
+}
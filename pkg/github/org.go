@@ -0,0 +1,74 @@
+package github
+
+import (
+	"path"
+	"slices"
+	"sort"
+
+	sentinelContext "gh-sentinel/internal/context"
+	"gh-sentinel/internal/errors"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// RepoFilter selects repositories of an organization
+type RepoFilter struct {
+	Topics []string // Topics a repository must all have
+	Name   string   // Glob its name must match, e.g. api-*
+}
+
+// ListOrgRepositories returns the repositories of an organization that the
+// filter selects, sorted by name. Archived and disabled repositories, which
+// run no workflows, are left out.
+func (c *Client) ListOrgRepositories(org string, filter RepoFilter) ([]*sentinelContext.RepoContext, error) {
+	opts := &github.RepositoryListByOrgOptions{Type: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	var repos []*sentinelContext.RepoContext
+	for {
+		page, resp, err := c.client.Repositories.ListByOrg(c.ctx, org, opts)
+		if err != nil {
+			return nil, errors.GitHubAPIError("list_org_repositories", err)
+		}
+		for _, repo := range page {
+			if repo.GetArchived() || repo.GetDisabled() || !filter.matches(repo) {
+				continue
+			}
+			repos = append(repos, &sentinelContext.RepoContext{
+				Owner:         repo.GetOwner().GetLogin(),
+				Name:          repo.GetName(),
+				FullName:      repo.GetFullName(),
+				DefaultBranch: repo.GetDefaultBranch(),
+				IsPrivate:     repo.GetPrivate(),
+				Host:          c.repo.Host,
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].FullName < repos[j].FullName })
+	return repos, nil
+}
+
+func (f RepoFilter) matches(repo *github.Repository) bool {
+	if f.Name != "" {
+		if ok, _ := path.Match(f.Name, repo.GetName()); !ok {
+			return false
+		}
+	}
+	for _, topic := range f.Topics {
+		if !slices.Contains(repo.Topics, topic) {
+			return false
+		}
+	}
+	return true
+}
+
+// ForRepository returns a client of another repository, with the same
+// credentials and host
+func (c *Client) ForRepository(repo *sentinelContext.RepoContext) *Client {
+	clone := *c
+	clone.repo = repo
+	clone.workflowPaths = make(map[int64]string)
+	return &clone
+}
@@ -0,0 +1,119 @@
+package github
+
+import (
+	"strings"
+
+	"gh-sentinel/internal/errors"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// Review states of a pull request
+const (
+	ReviewPending          = "pending"
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes_requested"
+)
+
+// PullRequest represents a simplified pull request
+type PullRequest struct {
+	Number int
+	URL    string
+	State  string // open or closed
+	Merged bool
+	Review string // ReviewPending, ReviewApproved or ReviewChangesRequested
+}
+
+// CurrentUser returns the login of the authenticated user
+func (c *Client) CurrentUser() (string, error) {
+	user, _, err := c.client.Users.Get(c.ctx, "")
+	if err != nil {
+		return "", errors.GitHubAPIError("get_current_user", err)
+	}
+	return user.GetLogin(), nil
+}
+
+// CreatePullRequest opens a pull request merging head into base
+func (c *Client) CreatePullRequest(head, base, title, body string) (*PullRequest, error) {
+	return c.createPullRequest(c.repo.Owner, c.repo.Name, head, base, title, body)
+}
+
+func (c *Client) createPullRequest(owner, name, head, base, title, body string) (*PullRequest, error) {
+	pr, _, err := c.client.PullRequests.Create(c.ctx, owner, name, &github.NewPullRequest{
+		Title: github.String(title),
+		Head:  github.String(head),
+		Base:  github.String(base),
+		Body:  github.String(body),
+	})
+	if err != nil {
+		return nil, errors.GitHubAPIError("create_pull_request", err)
+	}
+	c.logger.Info("Opened pull request #%d", pr.GetNumber())
+	return &PullRequest{
+		Number: pr.GetNumber(),
+		URL:    pr.GetHTMLURL(),
+		State:  pr.GetState(),
+		Review: ReviewPending,
+	}, nil
+}
+
+// RequestReviewers asks users and teams to review a pull request. Teams are
+// given as org/team, users as their login; a leading @ is ignored.
+func (c *Client) RequestReviewers(number int, reviewers []string) error {
+	var request github.ReviewersRequest
+	for _, reviewer := range reviewers {
+		reviewer = strings.TrimPrefix(reviewer, "@")
+		if _, team, ok := strings.Cut(reviewer, "/"); ok {
+			request.TeamReviewers = append(request.TeamReviewers, team)
+		} else {
+			request.Reviewers = append(request.Reviewers, reviewer)
+		}
+	}
+	if _, _, err := c.client.PullRequests.RequestReviewers(c.ctx, c.repo.Owner, c.repo.Name, number, request); err != nil {
+		return errors.GitHubAPIError("request_reviewers", err)
+	}
+	return nil
+}
+
+// GetPullRequest retrieves a pull request with the verdict of its reviews:
+// changes requested by anyone outweigh approvals, and each reviewer's latest
+// review counts
+func (c *Client) GetPullRequest(number int) (*PullRequest, error) {
+	return c.pullRequest(c.repo.Owner, c.repo.Name, number)
+}
+
+func (c *Client) pullRequest(owner, name string, number int) (*PullRequest, error) {
+	pr, _, err := c.client.PullRequests.Get(c.ctx, owner, name, number)
+	if err != nil {
+		return nil, errors.GitHubAPIError("get_pull_request", err)
+	}
+	reviews, _, err := c.client.PullRequests.ListReviews(c.ctx, owner, name, number, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, errors.GitHubAPIError("list_reviews", err)
+	}
+
+	latest := make(map[string]string)
+	for _, review := range reviews {
+		switch state := review.GetState(); state {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latest[review.GetUser().GetLogin()] = state
+		}
+	}
+	verdict := ReviewPending
+	for _, state := range latest {
+		switch {
+		case state == "CHANGES_REQUESTED":
+			verdict = ReviewChangesRequested
+		case state == "APPROVED" && verdict == ReviewPending:
+			verdict = ReviewApproved
+		}
+	}
+
+	return &PullRequest{
+		Number: pr.GetNumber(),
+		URL:    pr.GetHTMLURL(),
+		State:  pr.GetState(),
+		Merged: pr.GetMerged(),
+		Review: verdict,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package github
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"gh-sentinel/internal/errors"
+)
+
+// ErrRefMismatch is returned by ResolveActionRef when the commit of a tag or
+// branch and the commit the commits API gives for its name differ, e.g.
+// because a branch shadows a tag of the same name
+var ErrRefMismatch = stderrors.New("the ref does not resolve to a single commit")
+
+// ErrRefNotFound is returned by ResolveActionRef when the repository has no
+// tag or branch of the name
+var ErrRefNotFound = stderrors.New("no such tag or branch")
+
+// ResolveActionRef returns the commit a tag or branch of an action's
+// repository points at, as "uses: owner/repo@ref" runs it. Tags are tried
+// before branches, and annotated tags are followed to their commit. The
+// commit is then checked against the one the commits API gives for the ref.
+func (c *Client) ResolveActionRef(repository, ref string) (string, error) {
+	owner, name, err := splitRepository("resolve_action_ref", repository)
+	if err != nil {
+		return "", err
+	}
+
+	sha := ""
+	for _, kind := range []string{"tags/", "heads/"} {
+		reference, resp, err := c.client.Git.GetRef(c.ctx, owner, name, kind+ref)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return "", errors.GitHubAPIError("resolve_action_ref", err).WithPath(repository + "@" + ref)
+		}
+		object := reference.GetObject()
+		sha = object.GetSHA()
+		for object.GetType() == "tag" {
+			tag, _, err := c.client.Git.GetTag(c.ctx, owner, name, object.GetSHA())
+			if err != nil {
+				return "", errors.GitHubAPIError("resolve_action_ref", err).WithPath(repository + "@" + ref)
+			}
+			object = tag.GetObject()
+			sha = object.GetSHA()
+		}
+		break
+	}
+	if sha == "" {
+		return "", errors.New(errors.ErrTypeValidation, "resolve_action_ref", fmt.Sprintf("%s has no tag or branch %s", repository, ref), ErrRefNotFound)
+	}
+
+	commit, _, err := c.client.Repositories.GetCommitSHA1(c.ctx, owner, name, ref, "")
+	if err != nil {
+		return "", errors.GitHubAPIError("resolve_action_ref", err).WithPath(repository + "@" + ref)
+	}
+	if commit != sha {
+		return "", fmt.Errorf("%s@%s: %w (%s, but the commits API gives %s)", repository, ref, ErrRefMismatch, shortSHA(sha), shortSHA(commit))
+	}
+	return sha, nil
+}
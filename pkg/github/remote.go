@@ -0,0 +1,193 @@
+package github
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/pkg/patcher"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// splitRepository splits "owner/name"
+func splitRepository(op, repository string) (string, string, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || name == "" {
+		return "", "", errors.ValidationError(op, fmt.Sprintf("repository %q is not owner/name", repository))
+	}
+	return owner, name, nil
+}
+
+// IsNotFound reports whether an API error is a 404, e.g. for a file that
+// does not exist
+func IsNotFound(err error) bool {
+	var resp *github.ErrorResponse
+	return stderrors.As(err, &resp) && resp.Response != nil && resp.Response.StatusCode == http.StatusNotFound
+}
+
+// RemoteBaseBranch returns the branch of another repository that a change
+// of a file read at ref is proposed to: ref itself when it is a branch,
+// otherwise, e.g. for a tag or a SHA, the default branch
+func (c *Client) RemoteBaseBranch(repository, ref string) (string, error) {
+	owner, name, err := splitRepository("remote_base_branch", repository)
+	if err != nil {
+		return "", err
+	}
+	if branch, _, err := c.client.Repositories.GetBranch(c.ctx, owner, name, ref, 0); err == nil {
+		return branch.GetName(), nil
+	}
+	repo, _, err := c.client.Repositories.Get(c.ctx, owner, name)
+	if err != nil {
+		return "", errors.GitHubAPIError("remote_base_branch", err)
+	}
+	return repo.GetDefaultBranch(), nil
+}
+
+// CreateRemoteBranch creates a branch of another repository at the head of
+// base
+func (c *Client) CreateRemoteBranch(repository, branch, base string) error {
+	owner, name, err := splitRepository("create_branch", repository)
+	if err != nil {
+		return err
+	}
+	head, _, err := c.client.Repositories.GetBranch(c.ctx, owner, name, base, 0)
+	if err != nil {
+		return errors.GitHubAPIError("create_branch", err)
+	}
+	ref := &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: head.GetCommit().SHA},
+	}
+	if _, _, err := c.client.Git.CreateRef(c.ctx, owner, name, ref); err != nil {
+		return errors.GitHubAPIError("create_branch", err)
+	}
+	return nil
+}
+
+// DeleteRemoteBranch deletes a branch of another repository, e.g. one a fix
+// could not be committed to
+func (c *Client) DeleteRemoteBranch(repository, branch string) error {
+	owner, name, err := splitRepository("delete_branch", repository)
+	if err != nil {
+		return err
+	}
+	if _, err := c.client.Git.DeleteRef(c.ctx, owner, name, "heads/"+branch); err != nil {
+		return errors.GitHubAPIError("delete_branch", err)
+	}
+	return nil
+}
+
+// BranchFile is a file on a branch of a repository, read and written through
+// the Contents API. It is the patcher.Remote of fixes made without a checkout.
+type BranchFile struct {
+	client     *Client
+	owner      string
+	name       string
+	Repository string
+	Branch     string
+	Path       string
+	Message    string // Message of the commit Write makes
+	Commit     string // SHA of the commit Write made
+}
+
+// BranchFile returns a file on a branch of another repository, whose
+// writes are committed with message
+func (c *Client) BranchFile(repository, branch, path, message string) (*BranchFile, error) {
+	owner, name, err := splitRepository("branch_file", repository)
+	if err != nil {
+		return nil, err
+	}
+	return &BranchFile{
+		client:     c,
+		owner:      owner,
+		name:       name,
+		Repository: repository,
+		Branch:     branch,
+		Path:       path,
+		Message:    message,
+	}, nil
+}
+
+// Read returns the content of the file and its blob SHA. A file that does
+// not exist has an empty SHA.
+func (f *BranchFile) Read() (string, string, error) {
+	c := f.client
+	file, _, _, err := c.client.Repositories.GetContents(c.ctx, f.owner, f.name, f.Path, &github.RepositoryContentGetOptions{Ref: f.Branch})
+	switch {
+	case IsNotFound(err):
+		return "", "", nil
+	case err != nil:
+		return "", "", errors.GitHubAPIError("read_branch_file", err).WithPath(f.Repository + "/" + f.Path)
+	case file == nil:
+		return "", "", errors.ValidationError("read_branch_file", "not a file").WithPath(f.Repository + "/" + f.Path)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return "", "", errors.GitHubAPIError("read_branch_file", err).WithPath(f.Repository + "/" + f.Path)
+	}
+	return content, file.GetSHA(), nil
+}
+
+// Write commits content to the file if its blob SHA is still sha, creating
+// it when sha is empty. GitHub refuses the commit otherwise, which is
+// reported as patcher.ErrConflict.
+func (f *BranchFile) Write(content, sha string) error {
+	c := f.client
+	opts := &github.RepositoryContentFileOptions{
+		Message: github.String(f.Message),
+		Content: []byte(content),
+		Branch:  github.String(f.Branch),
+	}
+	if sha != "" {
+		opts.SHA = github.String(sha)
+	}
+	result, _, err := c.client.Repositories.UpdateFile(c.ctx, f.owner, f.name, f.Path, opts)
+	if isConflict(err) {
+		return errors.New(errors.ErrTypeGitHub, "write_branch_file", fmt.Sprintf("%s changed on %s", f.Path, f.Branch), patcher.ErrConflict).WithPath(f.Repository + "/" + f.Path)
+	}
+	if err != nil {
+		return errors.GitHubAPIError("write_branch_file", err).WithPath(f.Repository + "/" + f.Path)
+	}
+	f.Commit = result.GetSHA()
+	c.logger.Debug("Committed %s to %s of %s", f.Path, f.Branch, f.Repository)
+	return nil
+}
+
+// isConflict reports whether the Contents API refused a write for the blob
+// SHA: 409 when it is stale, 422 when it is missing for an existing file
+func isConflict(err error) bool {
+	var resp *github.ErrorResponse
+	if !stderrors.As(err, &resp) || resp.Response == nil {
+		return false
+	}
+	switch resp.Response.StatusCode {
+	case http.StatusConflict:
+		return true
+	case http.StatusUnprocessableEntity:
+		return strings.Contains(strings.ToLower(resp.Message), "sha")
+	}
+	return false
+}
+
+// CreateRemotePullRequest opens a pull request merging head into base in
+// another repository
+func (c *Client) CreateRemotePullRequest(repository, head, base, title, body string) (*PullRequest, error) {
+	owner, name, err := splitRepository("create_pull_request", repository)
+	if err != nil {
+		return nil, err
+	}
+	return c.createPullRequest(owner, name, head, base, title, body)
+}
+
+// GetRemotePullRequest retrieves a pull request of another repository, as
+// GetPullRequest does
+func (c *Client) GetRemotePullRequest(repository string, number int) (*PullRequest, error) {
+	owner, name, err := splitRepository("get_pull_request", repository)
+	if err != nil {
+		return nil, err
+	}
+	return c.pullRequest(owner, name, number)
+}
@@ -0,0 +1,147 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/internal/metrics"
+)
+
+// maxRetryDelay bounds the wait before a retry, including one the API asks
+// for with Retry-After
+const maxRetryDelay = 30 * time.Second
+
+// retryTransport retries API requests that fail with a transient error: a
+// network error, a server error (5xx) or a rate limit (429, or the 403 of a
+// secondary rate limit). POST and PATCH requests, which may have taken
+// effect before failing, are only retried when rate limited. It waits for
+// the delay the API asks for, or an exponential backoff with jitter.
+type retryTransport struct {
+	base     http.RoundTripper
+	attempts int           // Extra attempts after the first
+	delay    time.Duration // Base of the backoff
+	logger   *logger.Logger
+}
+
+// timedTransport returns a transport that gives up on a request whose
+// response does not start within timeout, so a hung server fails the attempt
+// instead of blocking the session
+func timedTransport(timeout time.Duration) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return transport
+}
+
+// newRetryTransport wraps a transport with retries; a nil one is
+// http.DefaultTransport. Every attempt is counted in the metrics.
+func newRetryTransport(base http.RoundTripper, attempts int, delay time.Duration, log *logger.Logger) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	base = countedTransport{base}
+	if attempts <= 0 {
+		return base
+	}
+	return &retryTransport{base: base, attempts: attempts, delay: delay, logger: log}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt == t.attempts || !t.retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if err != nil {
+			t.logger.Debug("%s %s failed, retrying in %s: %v", req.Method, req.URL.Path, wait, err)
+		} else {
+			t.logger.Debug("%s %s answered %s, retrying in %s", req.Method, req.URL.Path, resp.Status, wait)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		// The body was consumed by the failed attempt
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, stderrors.New("cannot retry a request whose body cannot be replayed")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// countedTransport counts requests by status code
+type countedTransport struct {
+	base http.RoundTripper
+}
+
+func (t countedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		metrics.GitHubRequests.Inc("error")
+	} else {
+		metrics.GitHubRequests.Inc(strconv.Itoa(resp.StatusCode))
+	}
+	return resp, err
+}
+
+// retryable reports whether a failed attempt is worth repeating
+func (t *retryTransport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	idempotent := req.Method != http.MethodPost && req.Method != http.MethodPatch
+	if err != nil {
+		return idempotent && req.Context().Err() == nil && !stderrors.Is(err, context.Canceled)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode == http.StatusForbidden:
+		return secondaryRateLimit(resp)
+	}
+	return idempotent && resp.StatusCode >= 500
+}
+
+// secondaryRateLimit reports whether a 403 is a secondary rate limit rather
+// than missing permissions. The body is read and put back.
+func secondaryRateLimit(resp *http.Response) bool {
+	if resp.Header.Get("Retry-After") != "" {
+		return true
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return err == nil && strings.Contains(strings.ToLower(string(data)), "secondary rate limit")
+}
+
+// backoff returns the wait before the next attempt: the Retry-After of the
+// response if it has one, otherwise between half and all of delay*2^attempt
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, maxRetryDelay)
+		}
+	}
+	limit := min(t.delay<<attempt, maxRetryDelay)
+	return limit/2 + time.Duration(rand.Int63n(int64(limit/2)+1))
+}
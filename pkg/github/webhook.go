@@ -0,0 +1,47 @@
+package github
+
+import (
+	"net/http"
+
+	"gh-sentinel/internal/errors"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// maxWebhookPayload is the largest delivery GitHub sends
+const maxWebhookPayload = 25 << 20
+
+// RunEvent is a workflow_run webhook delivery
+type RunEvent struct {
+	Action     string // requested, in_progress or completed
+	RunID      int64
+	Repository string // owner/name
+	Branch     string
+	Conclusion string
+}
+
+// ParseRunEvent checks the X-Hub-Signature-256 of a webhook delivery
+// against secret and returns the workflow run it is about. Deliveries of
+// other events, such as the ping of a new webhook, return nil.
+func ParseRunEvent(w http.ResponseWriter, r *http.Request, secret []byte) (*RunEvent, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookPayload)
+	payload, err := github.ValidatePayload(r, secret)
+	if err != nil {
+		return nil, errors.AuthError("validate_webhook", err)
+	}
+	if github.WebHookType(r) != "workflow_run" {
+		return nil, nil
+	}
+	event, err := github.ParseWebHook("workflow_run", payload)
+	if err != nil {
+		return nil, errors.ValidationError("parse_webhook", err.Error())
+	}
+	e := event.(*github.WorkflowRunEvent)
+	return &RunEvent{
+		Action:     e.GetAction(),
+		RunID:      e.GetWorkflowRun().GetID(),
+		Repository: e.GetRepo().GetFullName(),
+		Branch:     e.GetWorkflowRun().GetHeadBranch(),
+		Conclusion: e.GetWorkflowRun().GetConclusion(),
+	}, nil
+}
@@ -0,0 +1,299 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/mattn/go-shellwords"
+	"gopkg.in/yaml.v3"
+
+	"gh-sentinel/internal/logger"
+)
+
+// Hook runners
+const (
+	RunnerPreCommit  = "pre-commit"
+	RunnerLintStaged = "lint-staged" // Usually run by husky
+)
+
+const (
+	preCommitConfig = ".pre-commit-config.yaml"
+	maxOutput       = 2000 // Characters of a hook's output kept
+)
+
+// lintStagedConfigs are the non-JavaScript lint-staged config files, which
+// are JSON or YAML
+var lintStagedConfigs = []string{".lintstagedrc", ".lintstagedrc.json", ".lintstagedrc.yaml", ".lintstagedrc.yml"}
+
+// Result is the outcome of one hook run on a file
+type Result struct {
+	Runner   string
+	Name     string
+	Passed   bool
+	Modified bool   // The hook rewrote the file, as formatters do
+	Output   string // What the hook printed, if it failed
+}
+
+// Runner runs the commit hooks a repository is configured with. Hooks are
+// the repository's own code, so they are only run when the user asks.
+type Runner struct {
+	root   string
+	logger *logger.Logger
+	ctx    context.Context
+}
+
+// NewRunner creates a runner for the repository at root
+func NewRunner(root string, log *logger.Logger) *Runner {
+	return &Runner{root: root, logger: log, ctx: context.Background()}
+}
+
+// SetContext replaces the context of hook commands, so cancelling ctx
+// interrupts the hook in progress
+func (r *Runner) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// Detect returns the hook runners the repository is configured with
+func (r *Runner) Detect() []string {
+	var runners []string
+	if fileExists(filepath.Join(r.root, preCommitConfig)) {
+		runners = append(runners, RunnerPreCommit)
+	}
+	if _, found := r.lintStagedConfig(); found {
+		runners = append(runners, RunnerLintStaged)
+	}
+	return runners
+}
+
+// Run runs the hooks of every runner that apply to path. Hooks that do not
+// apply to it are left out of the results.
+func (r *Runner) Run(path string) ([]Result, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(r.root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("%s is outside the repository", path)
+	}
+	rel = filepath.ToSlash(rel)
+
+	var results []Result
+	for _, runner := range r.Detect() {
+		var found []Result
+		switch runner {
+		case RunnerPreCommit:
+			found, err = r.runPreCommit(rel)
+		case RunnerLintStaged:
+			found, err = r.runLintStaged(rel)
+		}
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", runner, err)
+		}
+		results = append(results, found...)
+	}
+	return results, nil
+}
+
+// preCommitStatusRe matches the status line pre-commit prints for a hook,
+// e.g. "check yaml.....Passed" or "prettier...(no files to check)Skipped"
+var preCommitStatusRe = regexp.MustCompile(`^(.*?[^.])\.+(?:\([^)]*\))?(Passed|Failed|Skipped)$`)
+
+// runPreCommit runs the pre-commit hooks whose filters match the file
+func (r *Runner) runPreCommit(rel string) ([]Result, error) {
+	bin, err := exec.LookPath("pre-commit")
+	if err != nil {
+		r.logger.Warn("The repository uses pre-commit, but it is not installed; skipping its hooks")
+		return nil, nil
+	}
+	cmd := exec.CommandContext(r.ctx, bin, "run", "--color", "never", "--files", rel)
+	cmd.Dir = r.root
+	out, runErr := cmd.CombinedOutput()
+
+	var results []Result
+	var current *Result
+	var output []string
+	flush := func() {
+		if current != nil {
+			if !current.Passed {
+				current.Output = truncate(strings.TrimSpace(strings.Join(output, "\n")))
+			}
+			results = append(results, *current)
+		}
+		current, output = nil, nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if m := preCommitStatusRe.FindStringSubmatch(line); m != nil {
+			flush()
+			if m[2] != "Skipped" {
+				current = &Result{Runner: RunnerPreCommit, Name: strings.TrimSpace(m[1]), Passed: m[2] == "Passed"}
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch {
+		case line == "- files were modified by this hook":
+			current.Modified = true
+		case strings.HasPrefix(line, "- hook id:"), strings.HasPrefix(line, "- exit code:"), strings.TrimSpace(line) == "" && len(output) == 0:
+		default:
+			output = append(output, line)
+		}
+	}
+	flush()
+
+	// Without any status line pre-commit failed before running hooks, e.g.
+	// on an invalid config
+	if runErr != nil && len(results) == 0 {
+		if r.ctx.Err() != nil {
+			return nil, r.ctx.Err()
+		}
+		return nil, fmt.Errorf("%v: %s", runErr, truncate(strings.TrimSpace(string(out))))
+	}
+	return results, nil
+}
+
+// lintStagedConfig returns the tasks of the repository's lint-staged config,
+// by glob, and whether it has one. JavaScript configs cannot be read and
+// are reported as not found.
+func (r *Runner) lintStagedConfig() (map[string][]string, bool) {
+	var raw map[string]any
+	for _, name := range lintStagedConfigs {
+		data, err := os.ReadFile(filepath.Join(r.root, name))
+		if err != nil {
+			continue
+		}
+		// JSON is YAML, so one parser reads every format
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			r.logger.Warn("Ignoring invalid %s: %v", name, err)
+			return nil, false
+		}
+		break
+	}
+	if raw == nil {
+		data, err := os.ReadFile(filepath.Join(r.root, "package.json"))
+		if err != nil {
+			return nil, false
+		}
+		var pkg struct {
+			LintStaged map[string]any `json:"lint-staged"`
+		}
+		if err := json.Unmarshal(data, &pkg); err != nil || pkg.LintStaged == nil {
+			return nil, false
+		}
+		raw = pkg.LintStaged
+	}
+
+	tasks := make(map[string][]string)
+	for glob, value := range raw {
+		switch v := value.(type) {
+		case string:
+			tasks[glob] = []string{v}
+		case []any:
+			for _, item := range v {
+				if command, ok := item.(string); ok {
+					tasks[glob] = append(tasks[glob], command)
+				}
+			}
+		}
+	}
+	return tasks, true
+}
+
+// runLintStaged runs the lint-staged commands whose glob matches the file,
+// with the file as their last argument as lint-staged does
+func (r *Runner) runLintStaged(rel string) ([]Result, error) {
+	tasks, _ := r.lintStagedConfig()
+	globs := make([]string, 0, len(tasks))
+	for glob := range tasks {
+		globs = append(globs, glob)
+	}
+	sort.Strings(globs)
+
+	var results []Result
+	for _, glob := range globs {
+		if !globMatches(glob, rel) {
+			continue
+		}
+		for _, command := range tasks[glob] {
+			args, err := shellwords.Parse(command)
+			if err != nil || len(args) == 0 {
+				r.logger.Warn("Skipping lint-staged command %q: %v", command, err)
+				continue
+			}
+			bin := r.lookPath(args[0])
+			if bin == "" {
+				r.logger.Warn("Skipping lint-staged command %q: %s is not installed", command, args[0])
+				continue
+			}
+
+			before, _ := os.ReadFile(filepath.Join(r.root, rel))
+			cmd := exec.CommandContext(r.ctx, bin, append(args[1:], rel)...)
+			cmd.Dir = r.root
+			out, err := cmd.CombinedOutput()
+			if r.ctx.Err() != nil {
+				return results, r.ctx.Err()
+			}
+			after, _ := os.ReadFile(filepath.Join(r.root, rel))
+
+			result := Result{Runner: RunnerLintStaged, Name: command, Passed: err == nil, Modified: !bytes.Equal(before, after)}
+			if err != nil {
+				result.Output = truncate(strings.TrimSpace(string(out)))
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// lookPath finds a command in node_modules/.bin first, as lint-staged does,
+// then in PATH
+func (r *Runner) lookPath(name string) string {
+	if !strings.Contains(name, "/") {
+		local := filepath.Join(r.root, "node_modules", ".bin", name)
+		if fileExists(local) {
+			return local
+		}
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// globMatches matches a lint-staged glob. Globs without a slash match the
+// file name at any depth.
+func globMatches(glob, rel string) bool {
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+	ok, err := doublestar.Match(glob, rel)
+	return err == nil && ok
+}
+
+// truncate keeps the end of long output, where errors usually are
+func truncate(s string) string {
+	if len(s) <= maxOutput {
+		return s
+	}
+	return "..." + s[len(s)-maxOutput:]
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,128 @@
+package patcher
+
+import (
+	"fmt"
+	"sort"
+
+	"gh-sentinel/pkg/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	actionKeys = keySet("name", "author", "description", "inputs", "outputs", "runs", "branding")
+
+	compositeStepKeys = keySet("id", "if", "name", "uses", "run", "working-directory", "shell", "with", "env",
+		"continue-on-error")
+
+	actionRunners = keySet("composite", "docker", "node12", "node16", "node20", "node24")
+)
+
+// ValidateSchema checks a file against the structure GitHub Actions
+// accepts for it: that of an action for an action.yml, of a workflow for a
+// workflow. Other files of the repository are checked by their type.
+func ValidateSchema(path, content string) []SchemaIssue {
+	switch {
+	case workflow.IsAction(path):
+		return ValidateActionSchema(content)
+	case !workflow.IsWorkflowFile(path):
+		return ValidateFile(path, content)
+	}
+	return ValidateWorkflowSchema(content)
+}
+
+// ValidateActionSchema checks the metadata of an action: required name,
+// description and runs keys, a known runs.using, and for a composite
+// action, steps that run a script with a shell or use an action. It
+// returns nil when no issues are found.
+func ValidateActionSchema(content string) []SchemaIssue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		issue := SchemaIssue{Message: "invalid YAML: " + err.Error()}
+		if m := yamlErrLine.FindStringSubmatch(err.Error()); m != nil {
+			fmt.Sscanf(m[1], "%d", &issue.Line)
+		}
+		return []SchemaIssue{issue}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return []SchemaIssue{{Line: 1, Message: "action must be a mapping"}}
+	}
+
+	v := &schemaValidator{}
+	v.action(doc.Content[0])
+	sort.SliceStable(v.issues, func(i, j int) bool { return v.issues[i].Line < v.issues[j].Line })
+	return v.issues
+}
+
+func (v *schemaValidator) action(root *yaml.Node) {
+	v.unknownKeys(root, "", actionKeys)
+	for _, key := range []string{"name", "description"} {
+		if workflow.Value(root, key) == nil {
+			v.add(root, "", "missing required key '%s'", key)
+		}
+	}
+
+	runs := workflow.Value(root, "runs")
+	if runs == nil {
+		v.add(root, "", "missing required key 'runs'")
+		return
+	}
+	if runs.Kind != yaml.MappingNode {
+		v.add(runs, "runs", "must be a mapping")
+		return
+	}
+	using := workflow.Value(runs, "using")
+	switch {
+	case using == nil:
+		v.add(runs, "runs", "missing required key 'using'")
+		return
+	case !actionRunners[using.Value]:
+		v.add(using, "runs.using", "unknown runner %q", using.Value)
+		return
+	}
+
+	switch using.Value {
+	case "composite":
+		steps := workflow.Value(runs, "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+			v.add(runs, "runs.steps", "must be a list with at least one step")
+			return
+		}
+		for i, step := range steps.Content {
+			v.compositeStep(step, fmt.Sprintf("runs.steps[%d]", i))
+		}
+	case "docker":
+		if workflow.Value(runs, "image") == nil {
+			v.add(runs, "runs", "missing required key 'image'")
+		}
+	default:
+		if workflow.Value(runs, "main") == nil {
+			v.add(runs, "runs", "missing required key 'main'")
+		}
+	}
+}
+
+// compositeStep checks a step of a composite action, which unlike a
+// workflow step must name the shell of its script
+func (v *schemaValidator) compositeStep(step *yaml.Node, path string) {
+	if step.Kind != yaml.MappingNode {
+		v.add(step, path, "step must be a mapping")
+		return
+	}
+	v.unknownKeys(step, path, compositeStepKeys)
+
+	uses, run := workflow.Value(step, "uses"), workflow.Value(step, "run")
+	switch {
+	case uses == nil && run == nil:
+		v.add(step, path, "step needs either 'uses' or 'run'")
+	case uses != nil && run != nil:
+		v.add(step, path, "step cannot have both 'uses' and 'run'")
+	case uses != nil && isEmpty(uses):
+		v.add(uses, path+".uses", "must name an action")
+	case run != nil && workflow.Value(step, "shell") == nil:
+		v.add(step, path, "a 'run' step of a composite action needs 'shell'")
+	}
+	if with := workflow.Value(step, "with"); with != nil && uses == nil {
+		v.add(with, path+".with", "'with' only applies to steps with 'uses'")
+	}
+}
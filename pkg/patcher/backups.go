@@ -0,0 +1,250 @@
+package patcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// backupTimeFormat is the timestamp backups are named after
+const backupTimeFormat = "20060102_150405"
+
+// manifestName is the file in the backup directory that maps backups to the
+// files they were taken from
+const manifestName = "manifest.json"
+
+// manifestEntry is a backup in the backup directory
+type manifestEntry struct {
+	Backup   string    `json:"backup"`   // Path relative to the backup directory
+	Original string    `json:"original"` // Absolute path of the file it was taken from
+	TakenAt  time.Time `json:"taken_at"`
+	Group    string    `json:"group,omitempty"` // Shared by the backups of a fix of several files
+}
+
+// backupLocation returns where a backup of filePath taken at takenAt goes:
+// <BackupDir>/<repo>/<file>/<timestamp>, with <file> relative to the root
+// of the repository
+func (p *Patcher) backupLocation(filePath string, takenAt time.Time) (string, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	root := repositoryRoot(filepath.Dir(abs))
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(p.config.BackupDir, repositoryKey(root), rel, takenAt.Format(backupTimeFormat)), nil
+}
+
+// repositoryRoot returns the closest directory above dir holding a .git
+// entry, or dir itself outside a repository
+func repositoryRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// repositoryKey names the backup directory of a repository after its root,
+// e.g. "gh-sentinel-1a2b3c4d", so clones with the same name do not mix
+func repositoryKey(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Base(root) + "-" + hex.EncodeToString(sum[:4])
+}
+
+// loadManifest reads the manifest of the backup directory. A missing
+// manifest is empty; an unreadable one is logged and treated as empty.
+func (p *Patcher) loadManifest() []manifestEntry {
+	data, err := os.ReadFile(filepath.Join(p.config.BackupDir, manifestName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			p.logger.Warn("Could not read the backup manifest: %v", err)
+		}
+		return nil
+	}
+	var entries []manifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		p.logger.Warn("Ignoring the corrupt backup manifest: %v", err)
+		return nil
+	}
+	return entries
+}
+
+// saveManifest writes the manifest of the backup directory
+func (p *Patcher) saveManifest(entries []manifestEntry) error {
+	path := filepath.Join(p.config.BackupDir, manifestName)
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.config.BackupDir, 0755); err != nil {
+		return errors.FilesystemError("save_manifest", path, err)
+	}
+	if err := writeAtomic(path, append(data, '\n')); err != nil {
+		return errors.FilesystemError("save_manifest", path, err)
+	}
+	return nil
+}
+
+// manifestEntryOf returns the manifest entry of a backup, if it has one
+func (p *Patcher) manifestEntryOf(backupPath string) (manifestEntry, bool) {
+	abs, err := filepath.Abs(backupPath)
+	if p.config.BackupDir == "" || err != nil {
+		return manifestEntry{}, false
+	}
+	for _, entry := range p.loadManifest() {
+		if filepath.Join(p.config.BackupDir, entry.Backup) == abs {
+			return entry, true
+		}
+	}
+	return manifestEntry{}, false
+}
+
+// RemoveBackup deletes a backup and drops it from the manifest
+func (p *Patcher) RemoveBackup(backupPath string) error {
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return errors.FilesystemError("remove_backup", backupPath, err)
+	}
+	if _, ok := p.manifestEntryOf(backupPath); !ok {
+		return nil
+	}
+	return p.saveManifest(p.liveEntries(p.loadManifest()))
+}
+
+// liveEntries drops the manifest entries whose backup no longer exists
+func (p *Patcher) liveEntries(entries []manifestEntry) []manifestEntry {
+	var live []manifestEntry
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(p.config.BackupDir, entry.Backup)); err == nil {
+			live = append(live, entry)
+		}
+	}
+	return live
+}
+
+// PruneBackups applies the retention limits to the backups of every file
+// in the manifest and returns how many backups were removed
+func (p *Patcher) PruneBackups() (int, error) {
+	if p.config.BackupDir == "" {
+		return 0, nil
+	}
+	entries := p.loadManifest()
+	originals := make(map[string]bool)
+	for _, entry := range entries {
+		originals[entry.Original] = true
+	}
+
+	removed := 0
+	var firstErr error
+	for original := range originals {
+		n, err := p.pruneBackups(original, false)
+		removed += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if live := p.liveEntries(p.loadManifest()); len(live) != len(entries) || removed > 0 {
+		if err := p.saveManifest(live); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return removed, firstErr
+}
+
+// pruneBackups removes the backups of filePath beyond BackupMaxCount or
+// older than BackupMaxAge, but never the newest one. The manifest is
+// updated when save is set.
+func (p *Patcher) pruneBackups(filePath string, save bool) (int, error) {
+	if p.config.BackupMaxCount == 0 && p.config.BackupMaxAge == 0 {
+		return 0, nil
+	}
+	backups, err := p.ListBackups(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	type dated struct {
+		path    string
+		takenAt time.Time
+	}
+	var sorted []dated
+	for _, backup := range backups {
+		takenAt, err := p.BackupTimestamp(backup)
+		if err != nil {
+			continue // Not named by sentinel; leave it alone
+		}
+		sorted = append(sorted, dated{backup, takenAt})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].takenAt.After(sorted[j].takenAt)
+	})
+
+	removed := 0
+	cutoff := time.Now().Add(-p.config.BackupMaxAge)
+	for i, backup := range sorted {
+		tooMany := p.config.BackupMaxCount > 0 && i >= p.config.BackupMaxCount
+		tooOld := p.config.BackupMaxAge > 0 && backup.takenAt.Before(cutoff)
+		if i == 0 || (!tooMany && !tooOld) {
+			continue
+		}
+		if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+			return removed, errors.FilesystemError("prune_backups", backup.path, err)
+		}
+		p.logger.Debug("Removed the backup %s", backup.path)
+		removed++
+	}
+	if save && removed > 0 && p.config.BackupDir != "" {
+		return removed, p.saveManifest(p.liveEntries(p.loadManifest()))
+	}
+	return removed, nil
+}
+
+// centralBackups returns the backups of filePath recorded in the manifest
+func (p *Patcher) centralBackups(filePath string) []string {
+	if p.config.BackupDir == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil
+	}
+	var backups []string
+	for _, entry := range p.loadManifest() {
+		if entry.Original != abs {
+			continue
+		}
+		backup := filepath.Join(p.config.BackupDir, entry.Backup)
+		if _, err := os.Stat(backup); err == nil {
+			backups = append(backups, backup)
+		}
+	}
+	return backups
+}
+
+// relativeToWorkingDir returns path relative to the working directory when
+// it is inside it
+func relativeToWorkingDir(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
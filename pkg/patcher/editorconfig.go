@@ -0,0 +1,306 @@
+package patcher
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	editorConfigName = ".editorconfig"
+	utf8BOM          = "\ufeff"
+)
+
+// EditorConfig holds the .editorconfig properties patched files are
+// written with. Unset properties leave the content alone.
+type EditorConfig struct {
+	IndentSize             int    // Spaces per indentation level; 0 if unset
+	InsertFinalNewline     *bool  // Whether the file ends with a newline
+	TrimTrailingWhitespace bool   // Strip spaces and tabs at the end of lines
+	Charset                string // Only utf-8 and utf-8-bom are applied
+}
+
+// LoadEditorConfig resolves the .editorconfig properties of a file from the
+// .editorconfig files in its directory and the ones above, up to the one
+// marked root. Unreadable files are ignored, as editors do.
+func LoadEditorConfig(path string) EditorConfig {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return EditorConfig{}
+	}
+
+	// Nearer files take precedence, so they are applied last
+	var files []string
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		file := filepath.Join(dir, editorConfigName)
+		if root, err := isRootEditorConfig(file); err == nil {
+			files = append([]string{file}, files...)
+			if root {
+				break
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	props := make(map[string]string)
+	for _, file := range files {
+		readEditorConfig(file, abs, props)
+	}
+	return editorConfigOf(props)
+}
+
+// isRootEditorConfig reports whether an .editorconfig file declares
+// root = true in its preamble
+func isRootEditorConfig(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			break
+		}
+		if key, value, ok := editorConfigProperty(line); ok && key == "root" {
+			return value == "true", nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// readEditorConfig sets the properties of the sections of an .editorconfig
+// file that match path, later sections overriding earlier ones
+func readEditorConfig(file, path string, props map[string]string) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	rel, err := filepath.Rel(filepath.Dir(file), path)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+
+	matched := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			matched = sectionMatches(line[1:len(line)-1], rel)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		if key, value, ok := editorConfigProperty(line); ok {
+			props[key] = value
+		}
+	}
+}
+
+// editorConfigProperty parses a key = value line. Keys and values are
+// lowercased; comments and blank lines are not properties.
+func editorConfigProperty(line string) (string, string, bool) {
+	if line == "" || line[0] == '#' || line[0] == ';' {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(key)), strings.ToLower(strings.TrimSpace(value)), true
+}
+
+// sectionMatches reports whether a section glob matches a path relative to
+// its .editorconfig. Globs without a slash match the file name at any depth.
+func sectionMatches(glob, rel string) bool {
+	switch {
+	case strings.HasPrefix(glob, "/"):
+		glob = glob[1:]
+	case !strings.Contains(glob, "/"):
+		glob = "**/" + glob
+	}
+	ok, err := doublestar.Match(glob, rel)
+	return err == nil && ok
+}
+
+// editorConfigOf converts resolved properties. "unset" and invalid values
+// leave a property unset.
+func editorConfigOf(props map[string]string) EditorConfig {
+	var ec EditorConfig
+	// YAML cannot be indented with tabs, so only a space width is used
+	if props["indent_style"] != "tab" {
+		size := props["indent_size"]
+		if size == "tab" {
+			size = props["tab_width"]
+		}
+		if n, err := strconv.Atoi(size); err == nil && n > 0 {
+			ec.IndentSize = n
+		}
+	}
+	if final := props["insert_final_newline"]; final == "true" || final == "false" {
+		insert := final == "true"
+		ec.InsertFinalNewline = &insert
+	}
+	ec.TrimTrailingWhitespace = props["trim_trailing_whitespace"] == "true"
+	if charset := props["charset"]; charset != "unset" {
+		ec.Charset = charset
+	}
+	return ec
+}
+
+// Format rewrites content, such as a workflow written by the AI, to follow
+// the properties
+func (ec EditorConfig) Format(content string) string {
+	if ec.IndentSize > 0 {
+		content = reindent(content, ec.IndentSize)
+	}
+	if ec.TrimTrailingWhitespace {
+		lines := strings.SplitAfter(content, "\n")
+		for i, line := range lines {
+			eol := line[len(strings.TrimRight(line, "\r\n")):]
+			lines[i] = strings.TrimRight(line, " \t\r\n") + eol
+		}
+		content = strings.Join(lines, "")
+	}
+	return ec.finish(content)
+}
+
+// finish applies the properties of the file as a whole, which hunks cannot
+// carry: its charset and final newline
+func (ec EditorConfig) finish(content string) string {
+	switch ec.Charset {
+	case "utf-8":
+		content = strings.TrimPrefix(content, utf8BOM)
+	case "utf-8-bom":
+		if !strings.HasPrefix(content, utf8BOM) {
+			content = utf8BOM + content
+		}
+	}
+	if ec.InsertFinalNewline != nil && content != "" {
+		if *ec.InsertFinalNewline {
+			if !strings.HasSuffix(content, "\n") {
+				content += "\n"
+			}
+		} else {
+			content = strings.TrimRight(content, "\r\n")
+		}
+	}
+	return content
+}
+
+// blockScalarRe matches a line whose value is a literal or folded block
+// scalar, capturing an explicit indentation indicator
+var blockScalarRe = regexp.MustCompile(`(?:^|[:-]\s+)[|>][+-]?(\d?)[+-]?\s*(?:#.*)?$`)
+
+// indentLevel maps an indentation column of the original content to the
+// column it is rewritten to
+type indentLevel struct {
+	old, new int
+}
+
+// reindent rewrites the indentation of YAML content to size spaces per
+// level. The content after a sequence dash stays aligned with it, and block
+// scalars keep the indentation of their lines relative to each other.
+func reindent(content string, size int) string {
+	lines := strings.SplitAfter(content, "\n")
+	levels := []indentLevel{{0, 0}}
+
+	// The lines of a block scalar deeper than its owner move as a whole
+	var block *indentLevel
+	blockShift, blockExplicit, blockStarted := 0, false, false
+
+	for i, line := range lines {
+		body := strings.TrimLeft(line, " ")
+		indent := len(line) - len(body)
+		text := strings.TrimRight(body, "\r\n")
+
+		if block != nil {
+			if text == "" {
+				continue
+			}
+			if indent > block.old {
+				if !blockStarted && !blockExplicit {
+					// The first line sets the indentation of the block
+					blockShift = block.new + size - indent
+				}
+				blockStarted = true
+				lines[i] = strings.Repeat(" ", max(0, indent+blockShift)) + body
+				continue
+			}
+			block = nil
+		}
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "#") {
+			// Comments follow the structure without changing it
+			lines[i] = strings.Repeat(" ", mapIndent(levels, indent, size)) + body
+			continue
+		}
+
+		for len(levels) > 1 && levels[len(levels)-1].old > indent {
+			levels = levels[:len(levels)-1]
+		}
+		top := levels[len(levels)-1]
+		newIndent := top.new
+		if indent > top.old {
+			newIndent = top.new + size
+			levels = append(levels, indentLevel{indent, newIndent})
+		}
+		lines[i] = strings.Repeat(" ", newIndent) + body
+
+		// Content after "- " is aligned with the dash, not indented
+		owner := indentLevel{indent, newIndent}
+		parent, rest := owner, text
+		for strings.HasPrefix(rest, "-") && (len(rest) == 1 || rest[1] == ' ') {
+			after := strings.TrimLeft(rest[1:], " ")
+			if after == "" {
+				break
+			}
+			owner = parent
+			offset := len(rest) - len(after)
+			parent = indentLevel{parent.old + offset, parent.new + offset}
+			levels = append(levels, parent)
+			rest = after
+		}
+		if !strings.HasPrefix(rest, "|") && !strings.HasPrefix(rest, ">") {
+			owner = parent
+		}
+
+		if m := blockScalarRe.FindStringSubmatch(text); m != nil {
+			block = &owner
+			blockExplicit, blockStarted = m[1] != "", false
+			// An explicit indentation is relative to the owner, so the
+			// block moves with it
+			blockShift = owner.new - owner.old
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// mapIndent returns the new column of an indentation without recording it
+func mapIndent(levels []indentLevel, indent, size int) int {
+	for i := len(levels) - 1; i >= 0; i-- {
+		if levels[i].old == indent {
+			return levels[i].new
+		}
+		if levels[i].old < indent {
+			return levels[i].new + size
+		}
+	}
+	return indent
+}
@@ -0,0 +1,109 @@
+package patcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dockerInstructions are the instructions a Dockerfile may use
+var dockerInstructions = keySet("FROM", "RUN", "CMD", "LABEL", "MAINTAINER", "EXPOSE", "ENV", "ADD",
+	"COPY", "ENTRYPOINT", "VOLUME", "USER", "WORKDIR", "ARG", "ONBUILD", "STOPSIGNAL", "HEALTHCHECK", "SHELL")
+
+// heredocStart finds the delimiter of a heredoc, e.g. RUN <<EOF
+var heredocStart = regexp.MustCompile(`<<-?["']?([A-Za-z_][A-Za-z0-9_]*)["']?`)
+
+// IsDockerfile reports whether a path is a Dockerfile: Dockerfile,
+// Dockerfile.<variant> or <variant>.Dockerfile
+func IsDockerfile(p string) bool {
+	name := strings.ToLower(path.Base(strings.ReplaceAll(p, "\\", "/")))
+	return name == "dockerfile" || strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile")
+}
+
+// ValidateFile checks a file of the repository other than a workflow or an
+// action by its type: JSON must parse, a Dockerfile must pass a basic lint
+// and other YAML must parse. Files of other types are not checked. It
+// returns nil when no issues are found.
+func ValidateFile(p, content string) []SchemaIssue {
+	switch {
+	case IsDockerfile(p):
+		return LintDockerfile(content)
+	case strings.EqualFold(path.Ext(p), ".json"):
+		return validateJSON(content)
+	case strings.EqualFold(path.Ext(p), ".yml"), strings.EqualFold(path.Ext(p), ".yaml"):
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+			issue := SchemaIssue{Message: "invalid YAML: " + err.Error()}
+			if m := yamlErrLine.FindStringSubmatch(err.Error()); m != nil {
+				fmt.Sscanf(m[1], "%d", &issue.Line)
+			}
+			return []SchemaIssue{issue}
+		}
+	}
+	return nil
+}
+
+// validateJSON checks that content is a single JSON value
+func validateJSON(content string) []SchemaIssue {
+	var value any
+	err := json.Unmarshal([]byte(content), &value)
+	if err == nil {
+		return nil
+	}
+	issue := SchemaIssue{Message: "invalid JSON: " + err.Error()}
+	if syntax, ok := err.(*json.SyntaxError); ok {
+		issue.Line = 1 + strings.Count(content[:min(int(syntax.Offset), len(content))], "\n")
+	}
+	return []SchemaIssue{issue}
+}
+
+// LintDockerfile checks a Dockerfile for what makes a build fail before it
+// starts: an unknown instruction, an instruction without arguments, a first
+// instruction other than FROM or ARG, or no FROM at all. Continuation lines
+// and heredocs belong to their instruction. It returns nil when no issues
+// are found.
+func LintDockerfile(content string) []SchemaIssue {
+	var issues []SchemaIssue
+	var first, from, continued bool
+	var heredoc string
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case heredoc != "":
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			continue
+		case continued:
+			continued = strings.HasSuffix(trimmed, "\\") || trimmed == "" || strings.HasPrefix(trimmed, "#")
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		}
+
+		word := strings.Fields(trimmed)[0]
+		instruction, args := strings.ToUpper(word), strings.TrimSpace(trimmed[len(word):])
+		continued = strings.HasSuffix(trimmed, "\\")
+		if m := heredocStart.FindStringSubmatch(args); m != nil {
+			heredoc = m[1]
+		}
+		switch {
+		case !dockerInstructions[instruction]:
+			issues = append(issues, SchemaIssue{Line: i + 1, Message: fmt.Sprintf("unknown instruction '%s'", instruction)})
+		case args == "" || args == "\\":
+			issues = append(issues, SchemaIssue{Line: i + 1, Message: fmt.Sprintf("%s requires arguments", instruction)})
+		case !first && instruction != "FROM" && instruction != "ARG":
+			issues = append(issues, SchemaIssue{Line: i + 1, Message: fmt.Sprintf("the first instruction must be FROM or ARG, not %s", instruction)})
+		}
+		first = true
+		from = from || instruction == "FROM"
+	}
+	if !from {
+		issues = append(issues, SchemaIssue{Line: 1, Message: "no FROM instruction"})
+	}
+	return issues
+}
@@ -0,0 +1,92 @@
+package patcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/git"
+)
+
+// GitGuard checks the git state of a working tree before its files are
+// patched, so a fix neither lands on another branch than the one it is for
+// nor mixes with uncommitted work unnoticed
+type GitGuard struct {
+	repo   *git.Repo
+	logger *logger.Logger
+}
+
+// GitState is what GitGuard found about a file about to be patched
+type GitState struct {
+	Path     string
+	Branch   string // Checked-out branch
+	Expected string // Branch the fix is for, empty when any will do
+	Modified bool   // The file has uncommitted changes, or is untracked
+	Diverged bool   // The local file differs from the content the fix was made from
+}
+
+// WrongBranch reports whether another branch than the one the fix is for
+// is checked out. A detached HEAD, as CI checkouts have, is not a branch
+// to compare.
+func (s *GitState) WrongBranch() bool {
+	return s.Expected != "" && s.Branch != "HEAD" && s.Branch != s.Expected
+}
+
+// NewGitGuard opens the git repository containing dir. Cancelling ctx
+// interrupts the git command in progress.
+func NewGitGuard(ctx context.Context, dir string, log *logger.Logger) (*GitGuard, error) {
+	repo, err := git.Open(dir, log)
+	if err != nil {
+		return nil, err
+	}
+	repo.SetContext(ctx)
+	return &GitGuard{repo: repo, logger: log}, nil
+}
+
+// Check returns the git state of a file about to be patched. expected is
+// the branch the fix is for, and analyzed the content the fix was made
+// from; either may be empty when unknown.
+func (g *GitGuard) Check(path, expected, analyzed string) (*GitState, error) {
+	branch, err := g.repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	modified, err := g.repo.Modified(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &GitState{Path: path, Branch: branch, Expected: expected, Modified: modified}
+
+	if analyzed != "" {
+		local, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			state.Diverged = true
+		case err != nil:
+			return nil, errors.FilesystemError("git_guard", path, err)
+		default:
+			state.Diverged = ContentHash(string(local)) != ContentHash(analyzed)
+		}
+	}
+	g.logger.Debug("Git state of %s: branch %s (expected %q), modified %t, diverged %t", path, branch, expected, modified, state.Diverged)
+	return state, nil
+}
+
+// Stash stashes the uncommitted changes of paths so they can be patched
+// from their committed content; git stash pop brings the changes back
+func (g *GitGuard) Stash(message string, paths ...string) error {
+	g.logger.Info("Stashing the changes of %s", strings.Join(paths, ", "))
+	return g.repo.Stash(message, paths...)
+}
+
+// ContentHash returns the hex SHA-256 of content, ignoring the differences
+// in line endings and trailing newlines that checkouts and the Contents API
+// introduce
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package patcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// GroupBackup is one of the backups taken together for a fix of several
+// files
+type GroupBackup struct {
+	File   string // The file the backup was taken from
+	Backup string
+}
+
+// ApplyGroup applies the patches of several files as one change: every
+// patch is merged and validated before any file is written, and the files
+// are either all patched or left as they were. The backups are taken
+// together under one group, which RollbackGroup restores. It returns the
+// results in the order of reqs, and the group.
+func (p *Patcher) ApplyGroup(reqs []*PatchRequest) ([]*PatchResult, string, error) {
+	var plans []*plannedPatch
+	for _, req := range reqs {
+		p.logger.Info("Applying patch to %s", req.FilePath)
+		planned, err := p.plan(req)
+		if err != nil {
+			return nil, "", err
+		}
+		plans = append(plans, planned)
+	}
+
+	now := time.Now()
+	group := groupID(now, reqs)
+	results, err := p.commit(plans, now, group)
+	if err != nil {
+		return nil, "", err
+	}
+	p.logger.Info("Patched %d files as group %s", len(results), group)
+	return results, group, nil
+}
+
+// groupID names the group of backups of a fix after when it was applied
+// and the files it patches
+func groupID(takenAt time.Time, reqs []*PatchRequest) string {
+	sum := sha256.New()
+	for _, req := range reqs {
+		abs, _ := filepath.Abs(req.FilePath)
+		sum.Write([]byte(abs + "\x00"))
+	}
+	return takenAt.Format(backupTimeFormat) + "-" + hex.EncodeToString(sum.Sum(nil)[:4])
+}
+
+// BackupGroup returns the backups taken together with a backup for a fix
+// of several files, the backup itself included, or nil when it was taken
+// alone. Only the backup directory records groups.
+func (p *Patcher) BackupGroup(backupPath string) []GroupBackup {
+	entry, ok := p.manifestEntryOf(backupPath)
+	if !ok || entry.Group == "" {
+		return nil
+	}
+	var backups []GroupBackup
+	for _, e := range p.liveEntries(p.loadManifest()) {
+		if e.Group == entry.Group {
+			backups = append(backups, GroupBackup{
+				File:   relativeToWorkingDir(e.Original),
+				Backup: filepath.Join(p.config.BackupDir, e.Backup),
+			})
+		}
+	}
+	return backups
+}
+
+// RollbackGroup reverts every file of a group to its backup, all or
+// nothing: the backups are all read before any file is written.
+func (p *Patcher) RollbackGroup(backups []GroupBackup) error {
+	var plans []*plannedPatch
+	for _, backup := range backups {
+		content, err := os.ReadFile(backup.Backup)
+		if err != nil {
+			return errors.FilesystemError("rollback", backup.Backup, err)
+		}
+		current, err := os.ReadFile(backup.File)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.FilesystemError("rollback", backup.File, err)
+		}
+		p.logger.Info("Rolling back %s from %s", backup.File, backup.Backup)
+		plans = append(plans, &plannedPatch{
+			req:      &PatchRequest{FilePath: backup.File, NoBackup: true},
+			original: current,
+			exists:   err == nil,
+			content:  string(content),
+		})
+	}
+
+	if _, err := p.commit(plans, time.Now(), ""); err != nil {
+		return err
+	}
+	p.logger.Info("Rollback successful")
+	return nil
+}
@@ -0,0 +1,445 @@
+package patcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+)
+
+// hunkContext is how many unchanged lines surround each hunk
+const hunkContext = 3
+
+// maxDiffEdits bounds the edit distance the diff searches for; rewrites
+// beyond it are diffed as one changed block
+const maxDiffEdits = 2048
+
+// Diff line operations
+const (
+	OpContext = ' '
+	OpRemove  = '-'
+	OpAdd     = '+'
+)
+
+// DiffLine is one line of a hunk. Text keeps the line's line ending.
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// String returns the line as it appears in a unified diff
+func (l DiffLine) String() string {
+	return string(l.Op) + strings.TrimRight(l.Text, "\r\n")
+}
+
+// Hunk is one changed region of a file with its surrounding context
+type Hunk struct {
+	OldStart int // 1-based line of the first old-side line
+	NewStart int // 1-based line of the first new-side line
+	Lines    []DiffLine
+}
+
+// Header returns the unified diff header of the hunk, e.g. @@ -3,7 +3,8 @@
+func (h Hunk) Header() string {
+	oldLines, newLines := 0, 0
+	for _, line := range h.Lines {
+		if line.Op != OpAdd {
+			oldLines++
+		}
+		if line.Op != OpRemove {
+			newLines++
+		}
+	}
+	// An empty side is numbered after the line it follows, as in diff -u
+	oldStart, newStart := h.OldStart, h.NewStart
+	if oldLines == 0 {
+		oldStart--
+	}
+	if newLines == 0 {
+		newStart--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldLines, newStart, newLines)
+}
+
+// Unified renders hunks as a unified diff of the file at path. A file the
+// patch creates is diffed against /dev/null. No hunks render as nothing.
+func Unified(path string, hunks []Hunk, created bool) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	path = filepath.ToSlash(path)
+	if created {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		b.WriteString("--- a/" + path + "\n")
+	}
+	b.WriteString("+++ b/" + path + "\n")
+	for _, hunk := range hunks {
+		b.WriteString(hunk.Header() + "\n")
+		for _, line := range hunk.Lines {
+			b.WriteString(line.String() + "\n")
+			if !strings.HasSuffix(line.Text, "\n") {
+				b.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// Diff computes the hunks turning old into new. Lines that differ only in
+// trailing whitespace or line endings count as unchanged.
+func Diff(old, new string) []Hunk {
+	return hunks(diffLines(splitLines(old), splitLines(new)))
+}
+
+// hunks groups the changes of an alignment into hunks with context
+func hunks(ops []DiffLine) []Hunk {
+	var hunks []Hunk
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].Op == OpContext {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// Start with the context before the change
+		start := i - hunkContext
+		if start < 0 {
+			start = 0
+		}
+		for start < i && ops[start].Op != OpContext {
+			start++
+		}
+		hunk := Hunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+
+		// Extend over changes closer together than twice the context
+		end := i
+		for end < len(ops) {
+			if ops[end].Op != OpContext {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Op == OpContext {
+				run++
+			}
+			if run == len(ops) || run-end > 2*hunkContext {
+				break
+			}
+			end = run
+		}
+		for ; i < end; i++ {
+			switch ops[i].Op {
+			case OpContext:
+				oldLine++
+				newLine++
+			case OpRemove:
+				oldLine++
+			case OpAdd:
+				newLine++
+			}
+		}
+		after := end
+		for after < len(ops) && after < end+hunkContext && ops[after].Op == OpContext {
+			after++
+		}
+		hunk.Lines = append(hunk.Lines, ops[start:after]...)
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+// diffLines aligns two files line by line with the shortest edit script
+func diffLines(a, b []string) []DiffLine {
+	// Common prefix and suffix need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && lineKey(a[prefix]) == lineKey(b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && lineKey(a[len(a)-1-suffix]) == lineKey(b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	var ops []DiffLine
+	for _, line := range a[:prefix] {
+		ops = append(ops, DiffLine{OpContext, line})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if edits, ok := myers(midA, midB); ok {
+		ops = append(ops, edits...)
+	} else {
+		for _, line := range midA {
+			ops = append(ops, DiffLine{OpRemove, line})
+		}
+		for _, line := range midB {
+			ops = append(ops, DiffLine{OpAdd, line})
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, DiffLine{OpContext, line})
+	}
+	return ops
+}
+
+// myers diffs two blocks of lines with Myers' O(ND) algorithm. Removals are
+// listed before additions within each change. It gives up when the blocks
+// are more than maxDiffEdits edits apart.
+func myers(a, b []string) ([]DiffLine, bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxDiffEdits {
+		limit = maxDiffEdits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k = x - y. trace keeps
+	// the diagonals -d-1..d+1 of v before each step d, for backtracking.
+	offset := limit + 1
+	v := make([]int32, 2*limit+3)
+	var trace [][]int32
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int32(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = int(v[offset+k+1]) // Down: add a line of b
+			} else {
+				x = int(v[offset+k-1]) + 1 // Right: remove a line of a
+			}
+			y := x - k
+			for x < n && y < m && lineKey(a[x]) == lineKey(b[y]) {
+				x++
+				y++
+			}
+			v[offset+k] = int32(x)
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	// Walk the trace back from the end, collecting operations in reverse
+	var reversed []DiffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		at := func(k int) int { return int(trace[d][k+d+1]) }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, DiffLine{OpContext, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, DiffLine{OpAdd, b[prevY]})
+			} else {
+				reversed = append(reversed, DiffLine{OpRemove, a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]DiffLine, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		ops = append(ops, reversed[i])
+	}
+	return removalsFirst(ops), true
+}
+
+// removalsFirst orders each run of changes so its removed lines come before
+// its added lines, as unified diffs show them
+func removalsFirst(ops []DiffLine) []DiffLine {
+	for i := 0; i < len(ops); {
+		if ops[i].Op == OpContext {
+			i++
+			continue
+		}
+		end := i
+		var added []DiffLine
+		for end < len(ops) && ops[end].Op != OpContext {
+			end++
+		}
+		pos := i
+		for _, op := range ops[i:end] {
+			if op.Op == OpRemove {
+				ops[pos] = op
+				pos++
+			} else {
+				added = append(added, op)
+			}
+		}
+		copy(ops[pos:end], added)
+		i = end
+	}
+	return ops
+}
+
+// ApplyHunks applies hunks to content. Each hunk is placed where its context
+// and removed lines match the content, near the expected line if the content
+// moved; a hunk that matches nowhere fails the whole patch. Lines outside the
+// hunks and context lines are kept byte for byte, and added lines take the
+// content's line endings.
+func ApplyHunks(content string, hunks []Hunk) (string, error) {
+	lines := splitLines(content)
+	eol := "\n"
+	if len(lines) > 0 && strings.HasSuffix(lines[0], "\r\n") {
+		eol = "\r\n"
+	}
+
+	var b strings.Builder
+	emit := func(line string) {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString(eol)
+		}
+		b.WriteString(line)
+	}
+
+	pos, offset := 0, 0 // Next unconsumed line; drift of the content from the hunks
+	for _, hunk := range hunks {
+		var old []string
+		for _, line := range hunk.Lines {
+			if line.Op != OpAdd {
+				old = append(old, line.Text)
+			}
+		}
+		at, ok := locate(lines, old, hunk.OldStart-1+offset, pos)
+		if !ok {
+			return "", errors.ValidationError("apply_hunks",
+				fmt.Sprintf("the file changed where the fix applies (hunk %s); its lines were not found", hunk.Header()))
+		}
+		offset = at - (hunk.OldStart - 1)
+
+		for _, line := range lines[pos:at] {
+			emit(line)
+		}
+		cur := at
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case OpContext:
+				emit(lines[cur])
+				cur++
+			case OpRemove:
+				cur++
+			case OpAdd:
+				text := line.Text
+				if eol == "\r\n" && strings.HasSuffix(text, "\n") && !strings.HasSuffix(text, "\r\n") {
+					text = strings.TrimSuffix(text, "\n") + eol
+				}
+				emit(text)
+			}
+		}
+		pos = cur
+	}
+	for _, line := range lines[pos:] {
+		emit(line)
+	}
+	return b.String(), nil
+}
+
+// locate finds where the old side of a hunk starts in lines: at the expected
+// index, or else at the closest match that starts at or after from
+func locate(lines, old []string, expected, from int) (int, bool) {
+	matches := func(at int) bool {
+		if at < from || at+len(old) > len(lines) {
+			return false
+		}
+		for i, line := range old {
+			if lineKey(lines[at+i]) != lineKey(line) {
+				return false
+			}
+		}
+		return true
+	}
+	for distance := 0; expected-distance >= from || expected+distance+len(old) <= len(lines); distance++ {
+		if matches(expected + distance) {
+			return expected + distance, true
+		}
+		if distance > 0 && matches(expected-distance) {
+			return expected - distance, true
+		}
+	}
+	return 0, false
+}
+
+// keepDroppedComments turns removed comments and blank lines back into
+// context, since AI models often drop them silently when they rewrite a file.
+// Removals are kept only where the fix writes comments of its own.
+func keepDroppedComments(ops []DiffLine) []DiffLine {
+	for i := 0; i < len(ops); {
+		if ops[i].Op == OpContext {
+			i++
+			continue
+		}
+		end := i
+		rewritesComments := false
+		for end < len(ops) && ops[end].Op != OpContext {
+			if ops[end].Op == OpAdd && strings.HasPrefix(strings.TrimSpace(ops[end].Text), "#") {
+				rewritesComments = true
+			}
+			end++
+		}
+		if !rewritesComments {
+			for j := i; j < end; j++ {
+				if ops[j].Op == OpRemove && isComment(ops[j].Text) {
+					ops[j].Op = OpContext
+				}
+			}
+		}
+		i = end
+	}
+	return ops
+}
+
+// isComment reports whether a YAML line is blank or only a comment
+func isComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// lineKey is what lines are compared by: their text without trailing
+// whitespace and line ending
+func lineKey(line string) string {
+	return strings.TrimRight(line, " \t\r\n")
+}
+
+// splitLines splits content into lines that keep their line endings
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// CountChanges counts the added and removed lines of hunks
+func CountChanges(hunks []Hunk) (added, removed int) {
+	for _, hunk := range hunks {
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case OpAdd:
+				added++
+			case OpRemove:
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
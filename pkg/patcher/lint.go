@@ -0,0 +1,53 @@
+package patcher
+
+import (
+	"fmt"
+	"io"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/pkg/workflow"
+
+	"github.com/rhysd/actionlint"
+)
+
+// LintIssue is a problem actionlint found in a workflow
+type LintIssue struct {
+	Line    int
+	Column  int
+	Kind    string // actionlint rule name
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("line %d:%d: %s [%s]", i.Line, i.Column, i.Message, i.Kind)
+}
+
+// Lint runs actionlint on workflow content. path locates the repository so
+// its actionlint configuration and local actions are used; it does not need
+// to exist. External checkers (shellcheck, pyflakes) are not run. An
+// action's action.yml is not a workflow and has no issues.
+func Lint(path, content string) ([]LintIssue, error) {
+	if workflow.IsAction(path) {
+		return nil, nil
+	}
+	linter, err := actionlint.NewLinter(io.Discard, &actionlint.LinterOptions{})
+	if err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "lint", "failed to create linter", err)
+	}
+
+	errs, err := linter.Lint(path, []byte(content), nil)
+	if err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "lint", "actionlint failed", err).WithPath(path)
+	}
+
+	issues := make([]LintIssue, 0, len(errs))
+	for _, e := range errs {
+		issues = append(issues, LintIssue{
+			Line:    e.Line,
+			Column:  e.Column,
+			Kind:    e.Kind,
+			Message: e.Message,
+		})
+	}
+	return issues, nil
+}
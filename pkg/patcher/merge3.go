@@ -0,0 +1,155 @@
+package patcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resolution is how a conflict of a three-way merge is settled
+type Resolution int
+
+const (
+	KeepCurrent Resolution = iota // Keep the lines of the current file
+	TakeFix                       // Take the lines of the fix
+	KeepBoth                      // Keep the current lines, then the fix's
+)
+
+// MergeConflict is a region that both the current file and the fix changed
+// differently since the content the fix was made from
+type MergeConflict struct {
+	Line    int      // Line of the current file where the region starts
+	Base    []string // Lines the fix was made from
+	Current []string // Lines of the current file
+	Fix     []string // Lines the fix writes
+}
+
+// ConflictError is returned when a fix merged onto a file that changed since
+// it was made conflicts with those changes. Merged is the merge with
+// conflict markers around each conflict, for review.
+type ConflictError struct {
+	Path      string
+	Conflicts []MergeConflict
+	Merged    string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s changed since the fix was made, and %d of its changes conflict with the fix", e.Path, len(e.Conflicts))
+}
+
+// Merge3 merges the changes from base to fix onto current, which also
+// changed from base, like diff3. Regions only one side changed take that
+// side; regions both changed alike take either. The others are conflicts,
+// settled in order by resolutions while there are, and otherwise written
+// between <<<<<<< current, ======= and >>>>>>> fix markers. It returns the
+// merged content and every conflict found.
+func Merge3(base, current, fix string, resolutions []Resolution) (string, []MergeConflict) {
+	baseLines, curLines, fixLines := splitLines(base), splitLines(current), splitLines(fix)
+	toCur, toFix := matchLines(baseLines, curLines), matchLines(baseLines, fixLines)
+
+	eol := "\n"
+	if len(curLines) > 0 && strings.HasSuffix(curLines[0], "\r\n") {
+		eol = "\r\n"
+	}
+	var b strings.Builder
+	emit := func(lines []string) {
+		for _, line := range lines {
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString(eol)
+			}
+			if eol == "\r\n" && strings.HasSuffix(line, "\n") && !strings.HasSuffix(line, "\r\n") {
+				line = strings.TrimSuffix(line, "\n") + eol
+			}
+			b.WriteString(line)
+		}
+	}
+	marker := func(text string) {
+		emit([]string{text + eol})
+	}
+
+	var conflicts []MergeConflict
+	i, c, f := 0, 0, 0 // Next line of base, current and fix
+	for {
+		// Lines unchanged on both sides are kept as they are in current
+		for i < len(baseLines) && toCur[i] == c && toFix[i] == f {
+			emit(curLines[c : c+1])
+			i, c, f = i+1, c+1, f+1
+		}
+		if i == len(baseLines) && c == len(curLines) && f == len(fixLines) {
+			break
+		}
+
+		// A changed region ends at the next base line both sides kept
+		j := i
+		for j < len(baseLines) && (toCur[j] < 0 || toFix[j] < 0) {
+			j++
+		}
+		curEnd, fixEnd := len(curLines), len(fixLines)
+		if j < len(baseLines) {
+			curEnd, fixEnd = toCur[j], toFix[j]
+		}
+		was, cur, fixed := baseLines[i:j], curLines[c:curEnd], fixLines[f:fixEnd]
+
+		switch {
+		case sameLines(cur, was):
+			emit(fixed)
+		case sameLines(fixed, was), sameLines(cur, fixed):
+			emit(cur)
+		default:
+			conflicts = append(conflicts, MergeConflict{Line: c + 1, Base: was, Current: cur, Fix: fixed})
+			resolution := Resolution(-1)
+			if n := len(conflicts) - 1; n < len(resolutions) {
+				resolution = resolutions[n]
+			}
+			switch resolution {
+			case KeepCurrent:
+				emit(cur)
+			case TakeFix:
+				emit(fixed)
+			case KeepBoth:
+				emit(cur)
+				emit(fixed)
+			default:
+				marker("<<<<<<< current")
+				emit(cur)
+				marker("=======")
+				emit(fixed)
+				marker(">>>>>>> fix")
+			}
+		}
+		i, c, f = j, curEnd, fixEnd
+	}
+	return b.String(), conflicts
+}
+
+// matchLines maps each line of a to the index of the line of b it is kept
+// as, or -1 where it was removed
+func matchLines(a, b []string) []int {
+	matched := make([]int, len(a))
+	i, j := 0, 0
+	for _, op := range diffLines(a, b) {
+		switch op.Op {
+		case OpContext:
+			matched[i] = j
+			i, j = i+1, j+1
+		case OpRemove:
+			matched[i] = -1
+			i++
+		case OpAdd:
+			j++
+		}
+	}
+	return matched
+}
+
+// sameLines reports whether two blocks of lines compare equal
+func sameLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if lineKey(a[i]) != lineKey(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package patcher
+
+import (
+	"sort"
+	"strings"
+
+	"gh-sentinel/pkg/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateNeeds checks only the needs: graph of a workflow: needs naming
+// jobs that do not exist, dependency cycles, and jobs that can never run
+// because something they need is missing or cyclic. It returns nil when the
+// graph is sound or the content is not a workflow.
+func ValidateNeeds(content string) []SchemaIssue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	jobs := workflow.Value(doc.Content[0], "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	v := &schemaValidator{}
+	v.needs(jobs)
+	sort.SliceStable(v.issues, func(i, j int) bool { return v.issues[i].Line < v.issues[j].Line })
+	return v.issues
+}
+
+// needs validates the dependency graph of the jobs mapping
+func (v *schemaValidator) needs(jobs *yaml.Node) {
+	type jobNode struct {
+		key   *yaml.Node
+		needs []*yaml.Node
+	}
+	var order []string
+	graph := make(map[string]*jobNode)
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i].Value, jobs.Content[i+1]
+		node := &jobNode{key: jobs.Content[i]}
+		if job.Kind == yaml.MappingNode {
+			node.needs = scalars(workflow.Value(job, "needs"))
+		}
+		order = append(order, id)
+		graph[id] = node
+	}
+
+	// broken marks jobs that can never run
+	broken := make(map[string]bool)
+	for _, id := range order {
+		for _, need := range graph[id].needs {
+			switch {
+			case need.Value == id:
+				v.add(need, "jobs."+id+".needs", "job cannot need itself")
+				broken[id] = true
+			case graph[need.Value] == nil:
+				v.add(need, "jobs."+id+".needs", "job %q does not exist", need.Value)
+				broken[id] = true
+			}
+		}
+	}
+
+	// Depth-first search; an edge back to a job on the stack closes a cycle
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, need := range graph[id].needs {
+			next := need.Value
+			if graph[next] == nil || next == id {
+				continue
+			}
+			switch state[next] {
+			case visiting:
+				start := 0
+				for stack[start] != next {
+					start++
+				}
+				cycle := append(append([]string{}, stack[start:]...), next)
+				v.add(need, "jobs."+id+".needs", "dependency cycle: %s", strings.Join(cycle, " → "))
+				for _, member := range cycle {
+					broken[member] = true
+				}
+			case unvisited:
+				visit(next)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+	}
+	for _, id := range order {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+
+	// Jobs downstream of a broken job never run either
+	for changed := true; changed; {
+		changed = false
+		for _, id := range order {
+			if broken[id] {
+				continue
+			}
+			for _, need := range graph[id].needs {
+				if broken[need.Value] {
+					v.add(graph[id].key, "jobs."+id, "job never runs: it needs %q, which never runs", need.Value)
+					broken[id] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
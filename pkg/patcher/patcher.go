@@ -10,6 +10,7 @@ import (
 	"gh-sentinel/internal/config"
 	"gh-sentinel/internal/errors"
 	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/workflow"
 )
 
 // Patcher handles safe file patching with backup and rollback
@@ -30,7 +31,11 @@ func NewPatcher(cfg *config.Config, log *logger.Logger) *Patcher {
 type PatchRequest struct {
 	FilePath    string
 	NewContent  string
-	ValidateYAML bool
+	BaseContent string       // Content the fix was made from; empty means the file on disk
+	Selected    []int        // Indexes of the hunks (as returned by Hunks) to apply; nil applies all
+	Resolutions []Resolution // How to settle, in order, the conflicts of a file that changed since BaseContent
+	NoBackup    bool         // Skip the backup, e.g. when git keeps the original on another branch
+	Validate    bool         // Check the patched content by the file's type
 }
 
 // PatchResult contains the result of a patch operation
@@ -40,75 +45,124 @@ type PatchResult struct {
 	Message     string
 	LinesAdded  int
 	LinesRemoved int
+	HunksSkipped int // Hunks left out by PatchRequest.Selected
+	Merged       bool // The file changed since the fix's base, which the fix was merged onto
 }
 
 // Apply applies a patch to a file with automatic backup
 func (p *Patcher) Apply(req *PatchRequest) (*PatchResult, error) {
 	p.logger.Info("Applying patch to %s", req.FilePath)
 
-	// Validate input
-	if req.NewContent == "" {
-		return nil, errors.ValidationError("apply_patch", "empty patch content")
+	planned, err := p.plan(req)
+	if err != nil {
+		return nil, err
 	}
+	results, err := p.commit([]*plannedPatch{planned}, time.Now(), "")
+	if err != nil {
+		return nil, err
+	}
+	result := results[0]
 
-	// Basic YAML validation
-	if req.ValidateYAML {
-		if err := p.validateYAML(req.NewContent); err != nil {
-			return nil, err
-		}
+	p.logger.Info("Patch applied: +%d -%d lines", result.LinesAdded, result.LinesRemoved)
+	return result, nil
+}
+
+// plannedPatch is a patch merged onto the current file and validated,
+// ready to be written
+type plannedPatch struct {
+	req      *PatchRequest
+	original []byte // Current content of the file
+	exists   bool
+	content  string // Patched content
+	hunks    []Hunk
+	skipped  int
+	merged   bool
+}
+
+// plan merges a patch onto the current content of its file and validates
+// the result, without writing anything
+func (p *Patcher) plan(req *PatchRequest) (*plannedPatch, error) {
+	// Validate input
+	if req.NewContent == "" {
+		return nil, errors.ValidationError("apply_patch", "empty patch content").WithPath(req.FilePath)
 	}
+	req, editorConfig := p.formatted(req)
 
 	// Read original file
 	originalContent, err := os.ReadFile(req.FilePath)
-	var backupPath string
-	
-	if err == nil {
-		// File exists - create backup
-		if p.config.BackupEnabled {
-			backupPath, err = p.createBackup(req.FilePath, originalContent)
-			if err != nil {
-				return nil, err
-			}
-			p.logger.Info("Created backup at %s", backupPath)
-		}
-	} else if !os.IsNotExist(err) {
+	if err != nil && !os.IsNotExist(err) {
 		// Error reading file (not just "doesn't exist")
 		return nil, errors.FilesystemError("apply_patch", req.FilePath, err)
 	}
+	planned := &plannedPatch{req: req, original: originalContent, exists: err == nil}
 
-	// Calculate diff stats
-	result := &PatchResult{
-		BackupPath: backupPath,
-	}
-	
-	if len(originalContent) > 0 {
-		result.LinesAdded, result.LinesRemoved = p.calculateDiff(string(originalContent), req.NewContent)
+	// Only the changed hunks are written; the rest of the file is kept as is
+	content, hunks, skipped, err := merge(string(originalContent), req)
+	if err != nil {
+		return nil, err
 	}
+	planned.content, planned.hunks, planned.skipped = editorConfig.finish(content), hunks, skipped
+	planned.merged = changedSince(string(originalContent), req)
 
-	// Write new content
-	if err := os.WriteFile(req.FilePath, []byte(req.NewContent), 0644); err != nil {
-		return nil, errors.FilesystemError("apply_patch", req.FilePath, err)
+	if req.Validate {
+		if err := p.validate(req.FilePath, planned.content); err != nil {
+			return nil, err
+		}
 	}
-
-	result.Success = true
-	result.Message = fmt.Sprintf("Successfully patched %s", filepath.Base(req.FilePath))
-	
-	p.logger.Info("Patch applied: +%d -%d lines", result.LinesAdded, result.LinesRemoved)
-	return result, nil
+	return planned, nil
 }
 
-// createBackup creates a timestamped backup of a file
-func (p *Patcher) createBackup(filePath string, content []byte) (string, error) {
-	timestamp := time.Now().Format("20060102_150405")
-	backupPath := fmt.Sprintf("%s.%s%s", filePath, timestamp, p.config.BackupSuffix)
+// createBackup creates a backup of a file timestamped now, in the backup
+// directory if there is one and next to the file otherwise, then applies the
+// retention limits to the file's backups. The backup directory records the
+// group of backups taken together for a fix of several files.
+func (p *Patcher) createBackup(filePath string, content []byte, now time.Time, group string) (string, error) {
+	if p.config.BackupDir == "" {
+		backupPath := fmt.Sprintf("%s.%s%s", filePath, now.Format(backupTimeFormat), p.config.BackupSuffix)
+		if err := os.WriteFile(backupPath, content, 0644); err != nil {
+			return "", errors.FilesystemError("create_backup", backupPath, err)
+		}
+		p.pruneAfterBackup(filePath)
+		return backupPath, nil
+	}
 
+	backupPath, err := p.backupLocation(filePath, now)
+	if err != nil {
+		return "", errors.FilesystemError("create_backup", filePath, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return "", errors.FilesystemError("create_backup", backupPath, err)
+	}
 	if err := os.WriteFile(backupPath, content, 0644); err != nil {
 		return "", errors.FilesystemError("create_backup", backupPath, err)
 	}
 
+	original, _ := filepath.Abs(filePath)
+	rel, _ := filepath.Rel(p.config.BackupDir, backupPath)
+	var entries []manifestEntry
+	for _, entry := range p.liveEntries(p.loadManifest()) {
+		if entry.Backup != rel { // Taken within the same second and overwritten
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, manifestEntry{Backup: rel, Original: original, TakenAt: now, Group: group})
+	if err := p.saveManifest(entries); err != nil {
+		return "", err
+	}
+	p.pruneAfterBackup(filePath)
 	return backupPath, nil
 }
 
+// pruneAfterBackup applies the retention limits once a backup is taken.
+// Failures only cost disk space, so they are logged.
+func (p *Patcher) pruneAfterBackup(filePath string) {
+	if removed, err := p.pruneBackups(filePath, true); err != nil {
+		p.logger.Warn("Could not prune the backups of %s: %v", filePath, err)
+	} else if removed > 0 {
+		p.logger.Info("Removed %d old backups of %s", removed, filePath)
+	}
+}
+
 // Rollback reverts a file to its backup
 func (p *Patcher) Rollback(filePath, backupPath string) error {
 	p.logger.Info("Rolling back %s from %s", filePath, backupPath)
@@ -118,7 +172,7 @@ func (p *Patcher) Rollback(filePath, backupPath string) error {
 		return errors.FilesystemError("rollback", backupPath, err)
 	}
 
-	if err := os.WriteFile(filePath, backupContent, 0644); err != nil {
+	if err := writeAtomic(filePath, backupContent); err != nil {
 		return errors.FilesystemError("rollback", filePath, err)
 	}
 
@@ -126,6 +180,18 @@ func (p *Patcher) Rollback(filePath, backupPath string) error {
 	return nil
 }
 
+// validate checks patched content: a workflow or an action with basic YAML
+// checks, another file of the repository by its type
+func (p *Patcher) validate(path, content string) error {
+	if !workflow.IsWorkflowFile(path) {
+		if issues := ValidateFile(path, content); len(issues) > 0 {
+			return errors.ValidationError("validate_file", issues[0].String()).WithPath(path)
+		}
+		return nil
+	}
+	return p.validateYAML(content)
+}
+
 // validateYAML performs basic YAML structure validation
 func (p *Patcher) validateYAML(content string) error {
 	// Basic checks for YAML structure
@@ -145,94 +211,138 @@ func (p *Patcher) validateYAML(content string) error {
 	return nil
 }
 
-// calculateDiff calculates rough diff statistics
-func (p *Patcher) calculateDiff(original, new string) (added, removed int) {
-	originalLines := strings.Split(original, "\n")
-	newLines := strings.Split(new, "\n")
-
-	// Simple line-based diff
-	originalSet := make(map[string]bool)
-	for _, line := range originalLines {
-		originalSet[line] = true
+// PreviewDiff renders the hunks a patch would apply as a unified diff. A
+// merge with conflicts left is shown with its conflict markers.
+func (p *Patcher) PreviewDiff(req *PatchRequest) (string, error) {
+	req, _ = p.formatted(req)
+	originalContent, err := os.ReadFile(req.FilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.FilesystemError("preview_diff", req.FilePath, err)
 	}
+	created := err != nil
 
-	newSet := make(map[string]bool)
-	for _, line := range newLines {
-		newSet[line] = true
+	_, hunks, _, err := merge(string(originalContent), req)
+	if conflict, ok := err.(*ConflictError); ok {
+		hunks, err = Diff(string(originalContent), conflict.Merged), nil
 	}
-
-	// Count additions
-	for _, line := range newLines {
-		if !originalSet[line] && strings.TrimSpace(line) != "" {
-			added++
-		}
+	if err != nil {
+		return "", err
 	}
-
-	// Count removals
-	for _, line := range originalLines {
-		if !newSet[line] && strings.TrimSpace(line) != "" {
-			removed++
-		}
+	if len(hunks) == 0 {
+		return fmt.Sprintf("(no changes to %s)\n", filepath.ToSlash(req.FilePath)), nil
 	}
-
-	return added, removed
+	return Unified(req.FilePath, hunks, created), nil
 }
 
-// PreviewDiff generates a human-readable diff preview
-func (p *Patcher) PreviewDiff(filePath, newContent string) (string, error) {
-	originalContent, err := os.ReadFile(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "[NEW FILE]\n" + newContent, nil
-		}
-		return "", errors.FilesystemError("preview_diff", filePath, err)
+// Hunks returns the hunks a patch consists of, for choosing which to apply.
+// A new file is a single hunk.
+func (p *Patcher) Hunks(req *PatchRequest) ([]Hunk, error) {
+	req, _ = p.formatted(req)
+	originalContent, err := os.ReadFile(req.FilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.FilesystemError("patch_hunks", req.FilePath, err)
 	}
+	if len(originalContent) == 0 {
+		return Diff("", req.NewContent), nil
+	}
+	return patchHunks(string(originalContent), req), nil
+}
 
-	var preview strings.Builder
-	preview.WriteString(fmt.Sprintf("=== Changes to %s ===\n\n", filepath.Base(filePath)))
-
-	originalLines := strings.Split(string(originalContent), "\n")
-	newLines := strings.Split(newContent, "\n")
-
-	// Simple side-by-side preview (first 20 lines)
-	maxLines := 20
-	if len(newLines) < maxLines {
-		maxLines = len(newLines)
+// formatted returns a copy of a patch request whose new content follows the
+// .editorconfig of its file, along with those properties
+func (p *Patcher) formatted(req *PatchRequest) (*PatchRequest, EditorConfig) {
+	editorConfig := LoadEditorConfig(req.FilePath)
+	formatted := *req
+	formatted.NewContent = editorConfig.Format(req.NewContent)
+	if formatted.NewContent != req.NewContent {
+		p.logger.Debug("Formatted the fix of %s to match .editorconfig", req.FilePath)
 	}
+	return &formatted, editorConfig
+}
 
-	for i := 0; i < maxLines; i++ {
-		var orig, new string
-		if i < len(originalLines) {
-			orig = originalLines[i]
-		}
-		if i < len(newLines) {
-			new = newLines[i]
-		}
+// patchHunks diffs the fix's base against its fixed content. Without a base
+// the fix is diffed against the current content of the file.
+func patchHunks(current string, req *PatchRequest) []Hunk {
+	base := req.BaseContent
+	if base == "" {
+		base = current
+	}
+	return hunks(keepDroppedComments(diffLines(splitLines(base), splitLines(req.NewContent))))
+}
 
-		if orig != new {
-			if orig != "" {
-				preview.WriteString(fmt.Sprintf("- %s\n", orig))
+// merge applies the selected hunks of a patch onto the current content of
+// the file. A file that changed since the fix's base gets the fix merged
+// three-way instead, failing with a *ConflictError while conflicts are left
+// that req.Resolutions does not settle. It returns the new content, the
+// hunks applied and how many were left out.
+func merge(current string, req *PatchRequest) (string, []Hunk, int, error) {
+	if current == "" {
+		return req.NewContent, Diff("", req.NewContent), 0, nil
+	}
+	hunks := patchHunks(current, req)
+	skipped := 0
+	if req.Selected != nil {
+		keep := make(map[int]bool)
+		for _, i := range req.Selected {
+			if i < 0 || i >= len(hunks) {
+				return "", nil, 0, errors.ValidationError("apply_patch", fmt.Sprintf("hunk %d does not exist", i+1)).WithPath(req.FilePath)
 			}
-			if new != "" {
-				preview.WriteString(fmt.Sprintf("+ %s\n", new))
+			keep[i] = true
+		}
+		var selected []Hunk
+		for i, hunk := range hunks {
+			if keep[i] {
+				selected = append(selected, hunk)
 			}
 		}
+		skipped = len(hunks) - len(selected)
+		hunks = selected
 	}
-
-	if len(newLines) > maxLines {
-		preview.WriteString(fmt.Sprintf("\n... (%d more lines)\n", len(newLines)-maxLines))
+	if changedSince(current, req) {
+		content, err := merge3(current, req, hunks)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		return content, Diff(current, content), skipped, nil
 	}
+	content, err := ApplyHunks(current, hunks)
+	if err != nil {
+		return "", nil, 0, errors.New(errors.ErrTypeValidation, "apply_patch", "the fix no longer applies to the current file", err).WithPath(req.FilePath)
+	}
+	return content, hunks, skipped, nil
+}
 
-	return preview.String(), nil
+// changedSince reports whether the current content of a file is not the
+// base of the fix, which is then merged onto it three-way
+func changedSince(current string, req *PatchRequest) bool {
+	return current != "" && req.BaseContent != "" && ContentHash(current) != ContentHash(req.BaseContent)
 }
 
-// ListBackups finds all backup files for a given path
+// merge3 merges the selected hunks of a patch onto a file that changed since
+// the fix's base
+func merge3(current string, req *PatchRequest, hunks []Hunk) (string, error) {
+	fixed, err := ApplyHunks(req.BaseContent, hunks)
+	if err != nil {
+		return "", errors.New(errors.ErrTypeValidation, "apply_patch", "the fix does not apply to its own base", err).WithPath(req.FilePath)
+	}
+	content, conflicts := Merge3(req.BaseContent, current, fixed, req.Resolutions)
+	switch {
+	case len(conflicts) == len(req.Resolutions):
+		return content, nil
+	case req.Resolutions != nil:
+		return "", errors.ValidationError("apply_patch", "the file changed again while its conflicts were resolved").WithPath(req.FilePath)
+	}
+	return "", &ConflictError{Path: req.FilePath, Conflicts: conflicts, Merged: content}
+}
+
+// ListBackups finds all backup files for a given path: those in the backup
+// directory and those next to the file
 func (p *Patcher) ListBackups(filePath string) ([]string, error) {
 	dir := filepath.Dir(filePath)
 	base := filepath.Base(filePath)
 	
 	entries, err := os.ReadDir(dir)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return nil, errors.FilesystemError("list_backups", dir, err)
 	}
 
@@ -245,5 +355,87 @@ func (p *Patcher) ListBackups(filePath string) ([]string, error) {
 		}
 	}
 
-	return backups, nil
+	return append(backups, p.centralBackups(filePath)...), nil
+}
+
+// BackupTimestamp extracts the creation time encoded in a backup file name
+func (p *Patcher) BackupTimestamp(backupPath string) (time.Time, error) {
+	if entry, ok := p.manifestEntryOf(backupPath); ok {
+		return entry.TakenAt, nil
+	}
+
+	// Backups next to the file are named <file>.<timestamp><suffix>, those
+	// in the backup directory just <timestamp>
+	name := strings.TrimSuffix(filepath.Base(backupPath), p.config.BackupSuffix)
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	created, err := time.ParseInLocation(backupTimeFormat, name, time.Local)
+	if err != nil {
+		return time.Time{}, errors.ValidationError("backup_timestamp", "backup file name has no timestamp").WithPath(backupPath)
+	}
+	return created, nil
+}
+
+// OriginalPath returns the file a backup was taken from, relative to the
+// working directory when it is inside it. Paths that are not backups are
+// returned unchanged.
+func (p *Patcher) OriginalPath(backupPath string) string {
+	if entry, ok := p.manifestEntryOf(backupPath); ok {
+		return relativeToWorkingDir(entry.Original)
+	}
+	if !strings.HasSuffix(backupPath, p.config.BackupSuffix) {
+		return backupPath
+	}
+	name := strings.TrimSuffix(backupPath, p.config.BackupSuffix)
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		return name[:idx]
+	}
+	return name
+}
+
+// writeAtomic writes a file through a temporary file in the same directory
+// and renames it into place, so an interrupted write never leaves a
+// half-written workflow. The temporary file is removed on failure.
+func writeAtomic(path string, content []byte) error {
+	tmp, err := stageFile(path, content)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// stageFile writes content to a temporary file next to path, which renaming
+// onto path writes it in one step. The temporary file takes the permissions
+// of the file at path, so a patched script stays executable; a new file gets
+// 0644.
+func stageFile(path string, content []byte) (string, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
 }
@@ -0,0 +1,62 @@
+package patcher
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"gh-sentinel/internal/errors"
+)
+
+// ErrConflict is returned by a Remote's Write when the file is no longer at
+// the version it was read at
+var ErrConflict = stderrors.New("the file changed since it was read")
+
+// Remote is a file kept somewhere else than on disk, e.g. on a branch of a
+// repository, that ApplyRemote patches
+type Remote interface {
+	// Read returns the content of the file and its version, e.g. its blob
+	// SHA. A file that does not exist has an empty version.
+	Read() (content, version string, err error)
+	// Write replaces the content of the file if it is still at version,
+	// and fails with ErrConflict otherwise
+	Write(content, version string) error
+}
+
+// ApplyRemote applies a patch to a remote file, as Apply does to a file on
+// disk: the selected hunks are merged onto its current content. The write
+// is conditional on the version read, so a change made in between fails
+// with ErrConflict instead of being overwritten. No backup is taken.
+func (p *Patcher) ApplyRemote(remote Remote, req *PatchRequest) (*PatchResult, error) {
+	p.logger.Info("Applying patch to remote %s", req.FilePath)
+
+	if req.NewContent == "" {
+		return nil, errors.ValidationError("apply_patch", "empty patch content")
+	}
+	original, version, err := remote.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	content, hunks, skipped, err := merge(original, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Validate {
+		if err := p.validate(req.FilePath, content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := remote.Write(content, version); err != nil {
+		return nil, err
+	}
+	result := &PatchResult{
+		Success:      true,
+		Message:      fmt.Sprintf("Successfully patched remote %s", req.FilePath),
+		HunksSkipped: skipped,
+		Merged:       changedSince(original, req),
+	}
+	result.LinesAdded, result.LinesRemoved = CountChanges(hunks)
+	p.logger.Info("Patch applied: +%d -%d lines", result.LinesAdded, result.LinesRemoved)
+	return result, nil
+}
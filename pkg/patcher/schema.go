@@ -0,0 +1,231 @@
+package patcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gh-sentinel/pkg/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaIssue is a structural problem in a workflow file, or an error in
+// another file checked by its type
+type SchemaIssue struct {
+	Line    int    // 1-based line, 0 when unknown
+	Path    string // Location in the document, e.g. jobs.build.steps[2]
+	Message string
+}
+
+func (i SchemaIssue) String() string {
+	var location []string
+	if i.Line > 0 {
+		location = append(location, fmt.Sprintf("line %d", i.Line))
+	}
+	if i.Path != "" {
+		location = append(location, i.Path)
+	}
+	if len(location) == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(location, ", "), i.Message)
+}
+
+var (
+	workflowKeys = keySet("name", "run-name", "on", "permissions", "env", "defaults", "concurrency", "jobs")
+
+	jobKeys = keySet("name", "permissions", "needs", "if", "runs-on", "environment", "concurrency",
+		"outputs", "env", "defaults", "steps", "timeout-minutes", "strategy", "continue-on-error",
+		"container", "services", "uses", "with", "secrets")
+
+	stepKeys = keySet("id", "if", "name", "uses", "run", "working-directory", "shell", "with", "env",
+		"continue-on-error", "timeout-minutes")
+
+	events = keySet("branch_protection_rule", "check_run", "check_suite", "create", "delete",
+		"deployment", "deployment_status", "discussion", "discussion_comment", "fork", "gollum",
+		"issue_comment", "issues", "label", "merge_group", "milestone", "page_build", "project",
+		"project_card", "project_column", "public", "pull_request", "pull_request_comment",
+		"pull_request_review", "pull_request_review_comment", "pull_request_target", "push",
+		"registry_package", "release", "repository_dispatch", "schedule", "status", "watch",
+		"workflow_call", "workflow_dispatch", "workflow_run")
+
+	jobIDPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	yamlErrLine  = regexp.MustCompile(`line (\d+)`)
+)
+
+func keySet(keys ...string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
+
+// ValidateWorkflowSchema checks workflow content against the structure
+// GitHub Actions accepts: required on/jobs keys, known events, jobs with a
+// runner and well-formed steps, and a needs graph without missing jobs or
+// cycles. It returns nil when no issues are found.
+func ValidateWorkflowSchema(content string) []SchemaIssue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		issue := SchemaIssue{Message: "invalid YAML: " + err.Error()}
+		if m := yamlErrLine.FindStringSubmatch(err.Error()); m != nil {
+			fmt.Sscanf(m[1], "%d", &issue.Line)
+		}
+		return []SchemaIssue{issue}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return []SchemaIssue{{Line: 1, Message: "workflow must be a mapping"}}
+	}
+
+	v := &schemaValidator{}
+	v.workflow(doc.Content[0])
+	sort.SliceStable(v.issues, func(i, j int) bool { return v.issues[i].Line < v.issues[j].Line })
+	return v.issues
+}
+
+// schemaValidator collects issues while walking a workflow document
+type schemaValidator struct {
+	issues []SchemaIssue
+}
+
+func (v *schemaValidator) add(node *yaml.Node, path, format string, args ...interface{}) {
+	v.issues = append(v.issues, SchemaIssue{Line: node.Line, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) workflow(root *yaml.Node) {
+	v.unknownKeys(root, "", workflowKeys)
+
+	if on := workflow.Value(root, "on"); on == nil {
+		v.add(root, "", "missing required key 'on'")
+	} else {
+		v.events(on)
+	}
+
+	jobs := workflow.Value(root, "jobs")
+	if jobs == nil {
+		v.add(root, "", "missing required key 'jobs'")
+		return
+	}
+	if jobs.Kind != yaml.MappingNode || len(jobs.Content) == 0 {
+		v.add(jobs, "jobs", "must be a mapping with at least one job")
+		return
+	}
+
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		v.job(jobs.Content[i], jobs.Content[i+1])
+	}
+	v.needs(jobs)
+}
+
+func (v *schemaValidator) events(on *yaml.Node) {
+	var names []*yaml.Node
+	switch on.Kind {
+	case yaml.ScalarNode:
+		names = append(names, on)
+	case yaml.SequenceNode:
+		names = on.Content
+	case yaml.MappingNode:
+		for i := 0; i < len(on.Content); i += 2 {
+			names = append(names, on.Content[i])
+		}
+	}
+	if len(names) == 0 {
+		v.add(on, "on", "must name at least one event")
+	}
+	for _, name := range names {
+		if !events[name.Value] {
+			v.add(name, "on", "unknown event %q", name.Value)
+		}
+	}
+}
+
+func (v *schemaValidator) job(key, job *yaml.Node) {
+	path := "jobs." + key.Value
+	if !jobIDPattern.MatchString(key.Value) {
+		v.add(key, path, "job ID must start with a letter or _ and contain only letters, digits, - and _")
+	}
+	if job.Kind != yaml.MappingNode {
+		v.add(job, path, "job must be a mapping")
+		return
+	}
+	v.unknownKeys(job, path, jobKeys)
+
+	// Reusable workflow calls have no runner or steps of their own
+	if workflow.Value(job, "uses") != nil {
+		return
+	}
+
+	if runsOn := workflow.Value(job, "runs-on"); runsOn == nil {
+		v.add(job, path, "missing required key 'runs-on'")
+	} else if isEmpty(runsOn) {
+		v.add(runsOn, path+".runs-on", "must name a runner")
+	}
+
+	steps := workflow.Value(job, "steps")
+	if steps == nil {
+		v.add(job, path, "missing required key 'steps'")
+		return
+	}
+	if steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+		v.add(steps, path+".steps", "must be a list with at least one step")
+		return
+	}
+	for i, step := range steps.Content {
+		v.step(step, fmt.Sprintf("%s.steps[%d]", path, i))
+	}
+}
+
+func (v *schemaValidator) step(step *yaml.Node, path string) {
+	if step.Kind != yaml.MappingNode {
+		v.add(step, path, "step must be a mapping")
+		return
+	}
+	v.unknownKeys(step, path, stepKeys)
+
+	uses, run := workflow.Value(step, "uses"), workflow.Value(step, "run")
+	switch {
+	case uses == nil && run == nil:
+		v.add(step, path, "step needs either 'uses' or 'run'")
+	case uses != nil && run != nil:
+		v.add(step, path, "step cannot have both 'uses' and 'run'")
+	case uses != nil && isEmpty(uses):
+		v.add(uses, path+".uses", "must name an action")
+	}
+	if with := workflow.Value(step, "with"); with != nil && uses == nil {
+		v.add(with, path+".with", "'with' only applies to steps with 'uses'")
+	}
+}
+
+// unknownKeys flags keys of a mapping that are not in allowed
+func (v *schemaValidator) unknownKeys(node *yaml.Node, path string, allowed map[string]bool) {
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !allowed[key.Value] {
+			v.add(key, strings.TrimPrefix(path+"."+key.Value, "."), "unknown key %q", key.Value)
+		}
+	}
+}
+
+// scalars returns a scalar or the scalars of a sequence
+func scalars(node *yaml.Node) []*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.ScalarNode {
+		return []*yaml.Node{node}
+	}
+	var out []*yaml.Node
+	for _, n := range node.Content {
+		if n.Kind == yaml.ScalarNode {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func isEmpty(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && strings.TrimSpace(node.Value) == ""
+}
@@ -0,0 +1,95 @@
+package patcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gh-sentinel/internal/errors"
+)
+
+// commit writes planned patches as one change, all or nothing. Every file
+// is validated by plan beforehand. The patched content of each file is
+// staged in a temporary file next to it, keeping the file's permissions,
+// then each file is backed up, taking the backups at takenAt as part of
+// group if any, and only then are the staged files renamed into place. A
+// failure before the renames leaves every file untouched; a failed rename
+// puts back the files already renamed. Either way the backups taken are
+// dropped.
+func (p *Patcher) commit(plans []*plannedPatch, takenAt time.Time, group string) ([]*PatchResult, error) {
+	var staged []string
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp) // No-op once renamed
+		}
+	}()
+	for _, planned := range plans {
+		tmp, err := stageFile(planned.req.FilePath, []byte(planned.content))
+		if err != nil {
+			return nil, errors.FilesystemError("apply_patch", planned.req.FilePath, err)
+		}
+		staged = append(staged, tmp)
+	}
+
+	var results []*PatchResult
+	for _, planned := range plans {
+		req := planned.req
+		result := &PatchResult{HunksSkipped: planned.skipped, Merged: planned.merged}
+		result.LinesAdded, result.LinesRemoved = CountChanges(planned.hunks)
+		if planned.exists && p.config.BackupEnabled && !req.NoBackup {
+			backupPath, err := p.createBackup(req.FilePath, planned.original, takenAt, group)
+			if err != nil {
+				p.dropBackups(results)
+				return nil, err
+			}
+			p.logger.Info("Created backup at %s", backupPath)
+			result.BackupPath = backupPath
+		}
+		results = append(results, result)
+	}
+
+	for i, planned := range plans {
+		if err := os.Rename(staged[i], planned.req.FilePath); err != nil {
+			p.restore(plans[:i])
+			p.dropBackups(results)
+			return nil, errors.FilesystemError("apply_patch", planned.req.FilePath, err)
+		}
+		results[i].Success = true
+		results[i].Message = fmt.Sprintf("Successfully patched %s", filepath.Base(planned.req.FilePath))
+	}
+	return results, nil
+}
+
+// restore puts back the content the files of planned patches had before
+// they were written, which is what their backups hold, removing the files
+// the patches created
+func (p *Patcher) restore(plans []*plannedPatch) {
+	for _, planned := range plans {
+		path := planned.req.FilePath
+		var err error
+		if planned.exists {
+			err = writeAtomic(path, planned.original)
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			p.logger.Error("Could not restore %s: %v", path, err)
+			continue
+		}
+		p.logger.Info("Restored %s", path)
+	}
+}
+
+// dropBackups removes the backups of a change that was not written, which
+// would only be offered for a rollback that changes nothing
+func (p *Patcher) dropBackups(results []*PatchResult) {
+	for _, result := range results {
+		if result.BackupPath == "" {
+			continue
+		}
+		if err := p.RemoveBackup(result.BackupPath); err != nil {
+			p.logger.Warn("Could not remove the backup %s: %v", result.BackupPath, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package redact removes secrets and tokens from logs before they are sent
+// to an AI provider or stored
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+)
+
+// Mask replaces a redacted secret
+const Mask = "[REDACTED]"
+
+// rule finds secrets. replace rewrites the submatches of a match; without
+// it, the whole match is masked.
+type rule struct {
+	re      *regexp.Regexp
+	replace func(m []string) string
+}
+
+// keepGroup masks everything after the first submatch, such as a header
+// name or URL scheme
+func keepGroup(m []string) string {
+	return m[1] + Mask
+}
+
+// builtin are the secrets recognized without configuration
+var builtin = []rule{
+	{re: regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`)},
+	{re: regexp.MustCompile(`\b([a-zA-Z][a-zA-Z0-9+.-]*://)[^\s/:@]+:[^\s/@]+@`), replace: func(m []string) string {
+		return m[1] + Mask + "@"
+	}},
+	{re: regexp.MustCompile(`(?i)\b(authorization:\s*(?:bearer|token|basic)\s+)[^\s"']+`), replace: keepGroup},
+	{re: regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]{16,}=*`), replace: keepGroup},
+	{re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{re: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[A-Z0-9]{16}\b`)},
+	{re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{re: regexp.MustCompile(`(?i)\b([A-Z0-9_.-]*(?:TOKEN|SECRET|PASSWORD|PASSWD|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIALS?)[A-Z0-9_.-]*\s*[:=]\s*["']?)([^\s"']+)`), replace: func(m []string) string {
+		if !secretValue(m[2]) {
+			return m[0]
+		}
+		return m[1] + Mask
+	}},
+	// GitHub masks registered secrets as ***, but not their transformations:
+	// a secret that was quoted, encoded or split leaks around the mask
+	{re: regexp.MustCompile(`[A-Za-z0-9+/_.%-]*\*\*\*[A-Za-z0-9+/_.%-]*=*`), replace: func(m []string) string {
+		if strings.Trim(m[0], "*./_%+=-") == "" {
+			return m[0]
+		}
+		return "***"
+	}},
+}
+
+// secretValue tells the value of a secret-looking assignment from a
+// placeholder, a reference or prose like "token: expired"
+func secretValue(value string) bool {
+	switch {
+	case strings.HasPrefix(value, "***"), strings.HasPrefix(value, Mask),
+		strings.HasPrefix(value, "${{"), strings.HasPrefix(value, "$"):
+		return false
+	case len(value) >= 16:
+		return true
+	}
+	return len(value) >= 6 && strings.ContainsAny(value, "0123456789")
+}
+
+// Redactor masks the secrets in text
+type Redactor struct {
+	rules []rule
+}
+
+// New creates a redactor with the built-in rules and custom regular
+// expressions, whose matches are masked whole
+func New(patterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]rule{}, builtin...)}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.ValidationError("new_redactor", fmt.Sprintf("invalid redact pattern %q: %v", pattern, err))
+		}
+		r.rules = append(r.rules, rule{re: re})
+	}
+	return r, nil
+}
+
+// Redact masks the secrets in text and returns how many it masked
+func (r *Redactor) Redact(text string) (string, int) {
+	count := 0
+	for _, rule := range r.rules {
+		text = rule.re.ReplaceAllStringFunc(text, func(match string) string {
+			replaced := Mask
+			if rule.replace != nil {
+				replaced = rule.replace(rule.re.FindStringSubmatch(match))
+			}
+			if replaced != match {
+				count++
+			}
+			return replaced
+		})
+	}
+	return text, count
+}
@@ -0,0 +1,94 @@
+package scriptcheck
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	trailingContinuationRe = regexp.MustCompile(`\\[ \t]+$`)
+	spacedAssignmentRe     = regexp.MustCompile(`^\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_]*\s+=\s*\S`)
+	testBracketRe          = regexp.MustCompile(`(?:^|\bif|\belif|\bwhile|\buntil|&&|\|\|)\s*\[\[?[^\s\[\]]`)
+	heredocRe              = regexp.MustCompile(`<<-?\s*['"]?([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+	quotedRe               = regexp.MustCompile(`'[^']*'|"(?:[^"\\]|\\.)*"`)
+)
+
+// blockPairs are the compound commands whose keywords must balance
+var blockPairs = []struct {
+	open, close, code string
+}{
+	{"if", "fi", "SC1046"},
+	{"do", "done", "SC1061"},
+	{"case", "esac", "SC1073"},
+}
+
+// builtinChecks runs a subset of shellcheck's checks that catch common
+// causes of step failures; lines are relative to the body
+func builtinChecks(body string) []Finding {
+	var findings []Finding
+	add := func(line int, code, level, message string) {
+		findings = append(findings, Finding{Line: line, Code: code, Level: level, Message: message, Source: SourceBuiltin})
+	}
+
+	counts := make(map[string]int)
+	firstOpen := make(map[string]int)
+	heredoc := ""
+
+	for i, line := range strings.Split(body, "\n") {
+		n := i + 1
+		if heredoc != "" {
+			if strings.TrimSpace(line) == heredoc {
+				heredoc = ""
+			}
+			continue
+		}
+
+		if strings.Contains(line, "\r") {
+			add(n, "SC1017", "error", "Literal carriage return. Convert the file to Unix line endings")
+		}
+		if trailingContinuationRe.MatchString(line) {
+			add(n, "SC1101", "error", "Delete trailing spaces after \\ to break the line")
+		}
+
+		code := stripComment(quotedRe.ReplaceAllString(line, `""`))
+		if spacedAssignmentRe.MatchString(code) {
+			add(n, "SC1068", "error", "Don't put spaces around the = in assignments")
+		}
+		if testBracketRe.MatchString(code) {
+			add(n, "SC1035", "error", "You need a space after the [ and before the ]")
+		}
+		if m := heredocRe.FindStringSubmatch(code); m != nil {
+			heredoc = m[1]
+		}
+
+		for _, word := range strings.FieldsFunc(code, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ';' || r == '(' || r == ')' || r == '&' || r == '|'
+		}) {
+			counts[word]++
+			if _, seen := firstOpen[word]; !seen {
+				firstOpen[word] = n
+			}
+		}
+	}
+
+	for _, pair := range blockPairs {
+		switch open, closed := counts[pair.open], counts[pair.close]; {
+		case open > closed:
+			add(firstOpen[pair.open], pair.code, "error", "Couldn't find '"+pair.close+"' for this '"+pair.open+"'")
+		case closed > open:
+			add(firstOpen[pair.close], "SC1089", "error", "Unexpected '"+pair.close+"' without a matching '"+pair.open+"'")
+		}
+	}
+	return findings
+}
+
+// stripComment removes a trailing # comment from a line whose quoted
+// strings were already blanked
+func stripComment(line string) string {
+	for i, r := range line {
+		if r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,231 @@
+package scriptcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/logger"
+	"gh-sentinel/pkg/workflow"
+)
+
+// Finding sources
+const (
+	SourceShellcheck = "shellcheck"
+	SourceBuiltin    = "builtin" // Built-in subset of shellcheck's checks
+	SourceLog        = "log"     // Shell error reported in the run's logs
+)
+
+// Finding is a problem in a run: script
+type Finding struct {
+	Job          string
+	Step         string
+	Line         int    // Workflow line, 1-based
+	Code         string // shellcheck code such as SC2086, if any
+	Level        string // error, warning or info
+	Message      string
+	Source       string
+	InFailedStep bool // The step this finding is in failed in the run
+}
+
+func (f Finding) String() string {
+	code := ""
+	if f.Code != "" {
+		code = " [" + f.Code + "]"
+	}
+	return fmt.Sprintf("line %d: %s%s (job %s, step %s)", f.Line, f.Message, code, f.Job, f.Step)
+}
+
+// Checker checks run: scripts with shellcheck when it is installed, and
+// with a built-in subset of its checks otherwise
+type Checker struct {
+	shellcheck string
+	logger     *logger.Logger
+	ctx        context.Context
+}
+
+// NewChecker creates a checker, looking up shellcheck in PATH
+func NewChecker(log *logger.Logger) *Checker {
+	path, err := exec.LookPath("shellcheck")
+	if err != nil {
+		log.Debug("shellcheck not found, using built-in script checks")
+	}
+	return &Checker{shellcheck: path, logger: log, ctx: context.Background()}
+}
+
+// SetContext replaces the context of shellcheck runs, so cancelling ctx
+// interrupts the check in progress
+func (c *Checker) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Source reports which checker produces findings
+func (c *Checker) Source() string {
+	if c.shellcheck != "" {
+		return SourceShellcheck
+	}
+	return SourceBuiltin
+}
+
+// exprRe matches ${{ }} expressions, which are substituted before the shell runs
+var exprRe = regexp.MustCompile(`\$\{\{.*?\}\}`)
+
+// Check checks every bash or sh script
+func (c *Checker) Check(scripts []workflow.Script) []Finding {
+	var findings []Finding
+	for _, script := range scripts {
+		if !script.IsPOSIXShell() {
+			continue
+		}
+		body := exprRe.ReplaceAllString(script.Body, "GITHUB_EXPR")
+
+		var found []Finding
+		if c.shellcheck != "" {
+			var err error
+			found, err = c.runShellcheck(body, shellDialect(script))
+			if err != nil {
+				c.logger.Warn("shellcheck failed, using built-in checks: %v", err)
+				found = builtinChecks(body)
+			}
+		} else {
+			found = builtinChecks(body)
+		}
+
+		for _, f := range found {
+			f.Job, f.Step = script.Job, script.Step
+			f.Line += script.Line - 1
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// shellcheckOutput is shellcheck's json1 format
+type shellcheckOutput struct {
+	Comments []struct {
+		Line    int    `json:"line"`
+		Level   string `json:"level"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"comments"`
+}
+
+// runShellcheck checks one script body; lines are relative to the body
+func (c *Checker) runShellcheck(body, dialect string) ([]Finding, error) {
+	cmd := exec.CommandContext(c.ctx, c.shellcheck, "--format=json1", "--severity=warning", "--shell="+dialect, "-")
+	cmd.Stdin = strings.NewReader(body)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	// shellcheck exits 1 when it reports findings
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return nil, err
+		}
+	}
+
+	var out shellcheckOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("invalid shellcheck output: %w", err)
+	}
+
+	findings := make([]Finding, 0, len(out.Comments))
+	for _, comment := range out.Comments {
+		findings = append(findings, Finding{
+			Line:    comment.Line,
+			Code:    fmt.Sprintf("SC%d", comment.Code),
+			Level:   comment.Level,
+			Message: comment.Message,
+			Source:  SourceShellcheck,
+		})
+	}
+	return findings, nil
+}
+
+// shellDialect maps a step's shell to a shellcheck dialect
+func shellDialect(script workflow.Script) string {
+	if fields := strings.Fields(script.Shell); len(fields) > 0 && fields[0] == "sh" {
+		return "sh"
+	}
+	return "bash"
+}
+
+var (
+	runStepRe    = regexp.MustCompile(`##\[group\]Run (.+)$`)
+	shellErrorRe = regexp.MustCompile(`_temp[/\\][\w-]+\.sh: line (\d+): (.+)$`)
+	exitCodeRe   = regexp.MustCompile(`##\[error\]Process completed with exit code \d+`)
+)
+
+// Correlate matches the logs of a run against its scripts. Shell errors
+// the logs report are returned as findings with SourceLog, and findings in
+// steps that failed are flagged. Log errors come first, then findings in
+// failed steps, then the rest, each by line.
+func Correlate(findings []Finding, scripts []workflow.Script, logs string) []Finding {
+	failed := make(map[string]bool)
+	var current *workflow.Script
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := runStepRe.FindStringSubmatch(line); m != nil {
+			current = scriptForCommand(scripts, m[1])
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := shellErrorRe.FindStringSubmatch(line); m != nil {
+			var n int
+			fmt.Sscanf(m[1], "%d", &n)
+			findings = append(findings, Finding{
+				Job:     current.Job,
+				Step:    current.Step,
+				Line:    current.Line + n - 1,
+				Level:   "error",
+				Message: strings.TrimSpace(m[2]),
+				Source:  SourceLog,
+			})
+			failed[current.Job+"\x00"+current.Step] = true
+		} else if exitCodeRe.MatchString(line) {
+			failed[current.Job+"\x00"+current.Step] = true
+		}
+	}
+
+	for i := range findings {
+		findings[i].InFailedStep = failed[findings[i].Job+"\x00"+findings[i].Step]
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if (a.Source == SourceLog) != (b.Source == SourceLog) {
+			return a.Source == SourceLog
+		}
+		if a.InFailedStep != b.InFailedStep {
+			return a.InFailedStep
+		}
+		return a.Line < b.Line
+	})
+	return findings
+}
+
+// scriptForCommand finds the script whose first line the logs show in a
+// "Run ..." group header
+func scriptForCommand(scripts []workflow.Script, command string) *workflow.Script {
+	command = strings.TrimSpace(command)
+	for i := range scripts {
+		first := strings.TrimSpace(strings.SplitN(strings.TrimSpace(scripts[i].Body), "\n", 2)[0])
+		if first == "" {
+			continue
+		}
+		// Expressions are already substituted in the logs
+		if loc := exprRe.FindStringIndex(first); loc != nil {
+			first = first[:loc[0]]
+		}
+		if first != "" && strings.HasPrefix(command, first) {
+			return &scripts[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package security
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+	"gh-sentinel/pkg/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Finding severities, from the most to the least severe
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// Rules, which name the findings
+const (
+	RuleScriptInjection    = "script-injection"
+	RuleMissingPermissions = "missing-permissions"
+	RuleTargetCheckout     = "pull-request-target-checkout"
+	RuleSecretCondition    = "secret-in-condition"
+)
+
+// Finding is a dangerous pattern in a workflow or composite action
+type Finding struct {
+	Rule        string
+	Severity    string
+	Line        int
+	Job         string // Job ID, "" for the workflow itself and for actions
+	Step        string // Step name or 1-based position, "" for jobs
+	Message     string
+	Remediation string // How to fix it, in a sentence
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s", f.Line, f.Message)
+}
+
+// Rank orders severities, critical first; unknown ones sort last
+func Rank(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 0
+	case SeverityHigh:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 3
+	}
+	return 4
+}
+
+// privilegedEvents run with the base repository's secrets and a token that
+// can write, while what triggers them can come from anyone
+var privilegedEvents = map[string]bool{
+	"pull_request_target":         true,
+	"issues":                      true,
+	"issue_comment":               true,
+	"discussion":                  true,
+	"discussion_comment":          true,
+	"pull_request_review":         true,
+	"pull_request_review_comment": true,
+	"workflow_run":                true,
+}
+
+var (
+	expressionRe = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+	contextRe    = regexp.MustCompile(`\bgithub\.(?:head_ref\b|event(?:\.[\w*-]+|\[[^\]]*\])+)`)
+	secretRe     = regexp.MustCompile(`\bsecrets(?:\.[\w-]+|\[[^\]]*\])`)
+	checkoutRe   = regexp.MustCompile(`^actions/checkout(?:@|$)`)
+	headRefRe    = regexp.MustCompile(`github\.(?:event\.pull_request\.head\.|head_ref\b)|refs/pull/`)
+)
+
+// untrustedFields end the github.event paths whose text whoever opens an
+// issue, pull request or comment, or names a branch or commit, chooses
+var untrustedFields = map[string]bool{
+	"title": true, "body": true, "message": true, "label": true,
+	"head_branch": true, "default_branch": true, "page_name": true, "email": true,
+}
+
+// untrusted reports whether a context path holds text an outsider controls
+func untrusted(path string) bool {
+	if path == "github.head_ref" {
+		return true
+	}
+	fields := strings.Split(path, ".")
+	last := fields[len(fields)-1]
+	if untrustedFields[last] {
+		return true
+	}
+	return strings.HasSuffix(path, ".head.ref") || strings.HasSuffix(path, "author.name") || strings.HasSuffix(path, "committer.name")
+}
+
+// Scan looks for script injection, jobs with the default token permissions,
+// pull_request_target workflows that check out the pull request, and
+// secrets in if: conditions, in a workflow or the steps of a composite
+// action
+func Scan(path, content string) ([]Finding, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "security_scan", "invalid YAML", err).WithPath(path)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	events := triggers(root)
+	privileged := false
+	for event := range events {
+		privileged = privileged || privilegedEvents[event]
+	}
+
+	var findings []Finding
+	checkSteps := func(job string, steps *yaml.Node) {
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			return
+		}
+		for n, step := range steps.Content {
+			name := strconv.Itoa(n + 1)
+			if node := workflow.Value(step, "name"); node != nil && node.Value != "" {
+				name = node.Value
+			}
+			findings = append(findings, injections(job, name, step, privileged)...)
+			findings = append(findings, secretConditions(job, name, workflow.Value(step, "if"))...)
+			if events["pull_request_target"] {
+				findings = append(findings, targetCheckout(job, name, step)...)
+			}
+		}
+	}
+
+	checkSteps("", workflow.Value(workflow.Value(root, "runs"), "steps"))
+	jobs := workflow.Value(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return findings, nil
+	}
+	workflowPermissions := workflow.Value(root, "permissions") != nil
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i], jobs.Content[i+1]
+		if !workflowPermissions && workflow.Value(job, "permissions") == nil {
+			findings = append(findings, Finding{
+				Rule:        RuleMissingPermissions,
+				Severity:    SeverityMedium,
+				Line:        id.Line,
+				Job:         id.Value,
+				Message:     fmt.Sprintf("job %s has no permissions: block, so its GITHUB_TOKEN gets the repository's default permissions, which may include write access", id.Value),
+				Remediation: "Add a top-level `permissions: contents: read` and grant each job only the scopes it needs",
+			})
+		}
+		findings = append(findings, secretConditions(id.Value, "", workflow.Value(job, "if"))...)
+		checkSteps(id.Value, workflow.Value(job, "steps"))
+	}
+	return findings, nil
+}
+
+// injections finds untrusted text interpolated into the script of a run:
+// step or of actions/github-script, where ${{ }} is substituted before the
+// script runs, so the text can run commands of its own
+func injections(job, step string, node *yaml.Node, privileged bool) []Finding {
+	script := workflow.Value(node, "run")
+	what := "the script"
+	if uses := workflow.Value(node, "uses"); uses != nil && strings.HasPrefix(uses.Value, "actions/github-script") {
+		script = workflow.Value(workflow.Value(node, "with"), "script")
+		what = "the github-script script"
+	}
+	if script == nil || script.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	severity := SeverityHigh
+	if privileged {
+		severity = SeverityCritical
+	}
+	var findings []Finding
+	seen := make(map[string]bool)
+	for _, m := range expressionRe.FindAllStringSubmatchIndex(script.Value, -1) {
+		for _, path := range contextRe.FindAllString(script.Value[m[2]:m[3]], -1) {
+			if !untrusted(path) || seen[path] {
+				continue
+			}
+			seen[path] = true
+			findings = append(findings, Finding{
+				Rule:        RuleScriptInjection,
+				Severity:    severity,
+				Line:        workflow.ScalarLine(script, m[0]),
+				Job:         job,
+				Step:        step,
+				Message:     fmt.Sprintf("%s is interpolated into %s of step %q%s; whoever sets it can run commands in the job", path, what, step, inJob(job)),
+				Remediation: fmt.Sprintf("Pass ${{ %s }} through the step's env: and use the variable, quoted, in the script instead", path),
+			})
+		}
+	}
+	return findings
+}
+
+// targetCheckout finds actions/checkout steps that check out the pull
+// request's head in a pull_request_target workflow, which then runs code of
+// the pull request with the base repository's secrets and a write token
+func targetCheckout(job, step string, node *yaml.Node) []Finding {
+	uses := workflow.Value(node, "uses")
+	if uses == nil || !checkoutRe.MatchString(uses.Value) {
+		return nil
+	}
+	with := workflow.Value(node, "with")
+	for _, key := range []string{"ref", "repository"} {
+		ref := workflow.Value(with, key)
+		if ref == nil || !headRefRe.MatchString(ref.Value) {
+			continue
+		}
+		return []Finding{{
+			Rule:        RuleTargetCheckout,
+			Severity:    SeverityCritical,
+			Line:        ref.Line,
+			Job:         job,
+			Step:        step,
+			Message:     fmt.Sprintf("step %q%s checks out the pull request's code in a pull_request_target workflow, which runs it with the repository's secrets and a write token", step, inJob(job)),
+			Remediation: "Build the pull request in a pull_request workflow instead, and keep pull_request_target to trusted code of the base branch",
+		}}
+	}
+	return nil
+}
+
+// secretConditions finds secrets read in an if: condition, where the
+// secrets context is not available
+func secretConditions(job, step string, cond *yaml.Node) []Finding {
+	if cond == nil || cond.Kind != yaml.ScalarNode {
+		return nil
+	}
+	var findings []Finding
+	for _, secret := range secretRe.FindAllString(cond.Value, -1) {
+		where := "job " + job
+		if step != "" {
+			where = fmt.Sprintf("step %q%s", step, inJob(job))
+		}
+		findings = append(findings, Finding{
+			Rule:        RuleSecretCondition,
+			Severity:    SeverityHigh,
+			Line:        cond.Line,
+			Job:         job,
+			Step:        step,
+			Message:     fmt.Sprintf("the if: of %s reads %s; secrets cannot be used in conditions", where, secret),
+			Remediation: fmt.Sprintf("Set ${{ %s != '' }} in an env: variable of the job and test the variable in the condition", secret),
+		})
+	}
+	return findings
+}
+
+func inJob(job string) string {
+	if job == "" {
+		return ""
+	}
+	return " in job " + job
+}
+
+// triggers returns the events in a workflow's on: key
+func triggers(root *yaml.Node) map[string]bool {
+	events := make(map[string]bool)
+	on := workflow.Value(root, "on")
+	if on == nil {
+		return events
+	}
+	switch on.Kind {
+	case yaml.ScalarNode:
+		events[on.Value] = true
+	case yaml.SequenceNode:
+		for _, item := range on.Content {
+			events[item.Value] = true
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(on.Content); i += 2 {
+			events[on.Content[i].Value] = true
+		}
+	}
+	return events
+}
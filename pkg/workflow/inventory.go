@@ -0,0 +1,131 @@
+package workflow
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuiltinSecret is provided to every run and never needs to be configured
+const BuiltinSecret = "GITHUB_TOKEN"
+
+// Reference is a use of a secret or configuration variable
+type Reference struct {
+	Name string
+	Line int // First line the name is referenced on, 1-based
+}
+
+// Inventory lists the configuration a workflow depends on
+type Inventory struct {
+	Path         string
+	Env          []string    // Names declared under env: at any level
+	Secrets      []Reference // secrets.NAME references
+	Vars         []Reference // vars.NAME references
+	Environments []string    // Deployment environments used by jobs
+}
+
+var (
+	secretRef = regexp.MustCompile(`\bsecrets(?:\.([A-Za-z_][A-Za-z0-9_]*)|\[\s*['"]([^'"]+)['"]\s*\])`)
+	varRef    = regexp.MustCompile(`\bvars(?:\.([A-Za-z_][A-Za-z0-9_]*)|\[\s*['"]([^'"]+)['"]\s*\])`)
+)
+
+// Inspect builds the inventory of a workflow file's content
+func Inspect(path, content string) (*Inventory, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "inspect_workflow", "invalid YAML", err).WithPath(path)
+	}
+
+	inv := &Inventory{
+		Path:    path,
+		Secrets: references(secretRef, content),
+		Vars:    references(varRef, content),
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return inv, nil
+	}
+	root := doc.Content[0]
+
+	env := make(map[string]bool)
+	environments := make(map[string]bool)
+	collectEnv(root, env)
+	if jobs := Value(root, "jobs"); jobs != nil && jobs.Kind == yaml.MappingNode {
+		for i := 1; i < len(jobs.Content); i += 2 {
+			job := jobs.Content[i]
+			collectEnv(job, env)
+			if name := environmentName(Value(job, "environment")); name != "" {
+				environments[name] = true
+			}
+			if steps := Value(job, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+				for _, step := range steps.Content {
+					collectEnv(step, env)
+				}
+			}
+		}
+	}
+	inv.Env = sortedKeys(env)
+	inv.Environments = sortedKeys(environments)
+	return inv, nil
+}
+
+// references finds every name matched by re, keeping the first line of each
+func references(re *regexp.Regexp, content string) []Reference {
+	seen := make(map[string]bool)
+	var refs []Reference
+	for i, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, m := range re.FindAllStringSubmatch(line, -1) {
+			name := m[1]
+			if name == "" {
+				name = m[2]
+			}
+			if !seen[name] {
+				seen[name] = true
+				refs = append(refs, Reference{Name: name, Line: i + 1})
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// collectEnv adds the names declared in node's env: mapping
+func collectEnv(node *yaml.Node, env map[string]bool) {
+	block := Value(node, "env")
+	if block == nil || block.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i < len(block.Content); i += 2 {
+		env[block.Content[i].Value] = true
+	}
+}
+
+// environmentName reads a job's environment, given as a name or a mapping
+func environmentName(node *yaml.Node) string {
+	if node == nil {
+		return ""
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	if name := Value(node, "name"); name != nil && name.Kind == yaml.ScalarNode {
+		return name.Value
+	}
+	return ""
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,43 @@
+package workflow
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// MatrixAxes returns the axes of the matrix of each job that has one, e.g.
+// [os node-version], in the order GitHub lists their values in job names.
+// Jobs are keyed both by ID and by name:. Content that is not a valid
+// workflow has none.
+func MatrixAxes(content string) map[string][]string {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	jobs := Value(doc.Content[0], "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	axes := make(map[string][]string)
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		job := jobs.Content[i+1]
+		matrix := Value(Value(job, "strategy"), "matrix")
+		if matrix == nil || matrix.Kind != yaml.MappingNode {
+			continue
+		}
+		var keys []string
+		for j := 0; j+1 < len(matrix.Content); j += 2 {
+			if key := matrix.Content[j].Value; key != "include" && key != "exclude" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		axes[jobs.Content[i].Value] = keys
+		if name := Value(job, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			axes[name.Value] = keys
+		}
+	}
+	return axes
+}
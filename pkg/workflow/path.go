@@ -0,0 +1,163 @@
+package workflow
+
+import (
+	"path"
+	"strings"
+)
+
+// Dir is the repository directory GitHub Actions loads workflows from
+const Dir = ".github/workflows"
+
+// ActionsDir is the repository directory local actions conventionally
+// live in
+const ActionsDir = ".github/actions"
+
+// Resolve maps a workflow path or file name onto an existing workflow file.
+// files are names inside Dir as returned by the Contents API, and the
+// repository paths of local actions' action.yml. Names are compared exactly
+// first, then case-insensitively, then ignoring the .yml/.yaml distinction.
+// An action is matched by the path of its action.yml or of its directory.
+// It returns the existing file's exact path and whether a match was found;
+// without a match the path is returned unchanged.
+func Resolve(p string, files []string) (string, bool) {
+	if action, ok := actionPath(p); ok {
+		return resolveAction(p, action, files)
+	}
+	name := path.Base(strings.ReplaceAll(p, "\\", "/"))
+
+	matchers := []func(file string) bool{
+		func(file string) bool { return file == name },
+		func(file string) bool { return strings.EqualFold(file, name) },
+		func(file string) bool { return strings.EqualFold(stem(file), stem(name)) },
+	}
+	for _, matches := range matchers {
+		for _, file := range files {
+			if !strings.Contains(file, "/") && matches(file) {
+				return Dir + "/" + file, true
+			}
+		}
+	}
+	return p, false
+}
+
+// resolveAction maps the normalized path of an action.yml onto the action
+// files among files
+func resolveAction(p, action string, files []string) (string, bool) {
+	matchers := []func(file string) bool{
+		func(file string) bool { return file == action },
+		func(file string) bool { return strings.EqualFold(file, action) },
+		func(file string) bool { return strings.EqualFold(stem(file), stem(action)) },
+	}
+	for _, matches := range matchers {
+		for _, file := range files {
+			if IsAction(file) && matches(file) {
+				return file, true
+			}
+		}
+	}
+	return p, false
+}
+
+// IsAction reports whether a path is the metadata file of an action,
+// action.yml or action.yaml
+func IsAction(p string) bool {
+	name := strings.ToLower(path.Base(p))
+	return name == "action.yml" || name == "action.yaml"
+}
+
+// IsWorkflowFile reports whether a path is a workflow or the metadata of an
+// action, rather than another file of the repository such as a
+// package.json, a Dockerfile or a source file
+func IsWorkflowFile(p string) bool {
+	p = path.Clean(strings.ReplaceAll(p, "\\", "/"))
+	if IsAction(p) {
+		return true
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case ".yml", ".yaml":
+		return strings.HasPrefix(p, Dir+"/") || strings.Contains(p, "/"+Dir+"/")
+	}
+	return false
+}
+
+// FilePath returns the repository path of an entry of a workflow file
+// listing: workflows are named inside Dir, actions by their path
+func FilePath(file string) string {
+	if strings.Contains(file, "/") {
+		return file
+	}
+	return Dir + "/" + file
+}
+
+// actionPath normalizes a reference to a local action to the path of its
+// action.yml. The action is named by that path, or by its directory as
+// uses: names it ("./ci/setup") or inside ActionsDir.
+func actionPath(p string) (string, bool) {
+	p = strings.ReplaceAll(p, "\\", "/")
+	local := strings.HasPrefix(p, "./")
+	p = strings.Trim(strings.TrimPrefix(p, "./"), "/")
+	switch {
+	case IsAction(p):
+		return p, true
+	case stem(p) == p && (local && p != "" || strings.HasPrefix(p, ActionsDir+"/")):
+		return p + "/action.yml", true
+	}
+	return "", false
+}
+
+// stem strips a YAML extension from a file name
+func stem(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".yml", ".yaml"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// Closest returns the path of the existing workflow file whose name is
+// nearest to p by edit distance (case and extension insensitive), and that
+// distance. An action is compared with the paths of the action files. It
+// returns "" and -1 when there is no file to compare with.
+func Closest(p string, files []string) (string, int) {
+	action, isAction := actionPath(p)
+	name := strings.ToLower(stem(path.Base(strings.ReplaceAll(p, "\\", "/"))))
+	if isAction {
+		name = strings.ToLower(stem(action))
+	}
+
+	best, bestDistance := "", -1
+	for _, file := range files {
+		if IsAction(file) != isAction {
+			continue
+		}
+		d := levenshtein(name, strings.ToLower(stem(file)))
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = FilePath(file), d
+		}
+	}
+	return best, bestDistance
+}
+
+// levenshtein computes the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,126 @@
+package workflow
+
+import (
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ActionRef is a uses: of an action or reusable workflow of another
+// repository, "owner/repo[/path]@ref"
+type ActionRef struct {
+	Line       int
+	Uses       string // The reference as written
+	Repository string // owner/repo
+	Ref        string // Tag, branch or commit SHA
+	Comment    string // The line's comment, without "#", e.g. the tag a SHA was pinned from
+}
+
+// Pinned reports whether the reference is an immutable commit SHA
+func (r ActionRef) Pinned() bool {
+	return shaRe.MatchString(r.Ref)
+}
+
+var (
+	shaRe       = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	actionRefRe = regexp.MustCompile(`^([\w.-]+/[\w.-]+)(?:/[^@\s]*)?@(\S+)$`)
+)
+
+// ActionRefs returns the uses: of the steps and jobs of a workflow, or of
+// the steps of a composite action, that run code of another repository.
+// Local and docker:// references are left out. Content that is not valid
+// YAML has none.
+func ActionRefs(content string) []ActionRef {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+
+	var uses []*yaml.Node
+	steps := func(node *yaml.Node) {
+		if node == nil || node.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, step := range node.Content {
+			uses = append(uses, Value(step, "uses"))
+		}
+	}
+	if jobs := Value(root, "jobs"); jobs != nil && jobs.Kind == yaml.MappingNode {
+		for i := 1; i < len(jobs.Content); i += 2 {
+			uses = append(uses, Value(jobs.Content[i], "uses"))
+			steps(Value(jobs.Content[i], "steps"))
+		}
+	}
+	steps(Value(Value(root, "runs"), "steps"))
+
+	var refs []ActionRef
+	for _, node := range uses {
+		if node == nil || node.Kind != yaml.ScalarNode {
+			continue
+		}
+		m := actionRefRe.FindStringSubmatch(node.Value)
+		if m == nil {
+			continue
+		}
+		refs = append(refs, ActionRef{
+			Line:       node.Line,
+			Uses:       node.Value,
+			Repository: m[1],
+			Ref:        m[2],
+			Comment:    strings.TrimSpace(strings.TrimPrefix(node.LineComment, "#")),
+		})
+	}
+	return refs
+}
+
+// Pin is the commit to pin a reference to
+type Pin struct {
+	ActionRef
+	SHA string
+}
+
+// PinRefs rewrites references to the commits of their pins, noting the
+// tag or branch they were pinned from in a comment ("@<sha> # v4"), which
+// keeps the version readable and lets Dependabot update the pin. A comment
+// the line had is kept after the tag.
+func PinRefs(content string, pins []Pin) string {
+	lines := strings.Split(content, "\n")
+	for _, pin := range pins {
+		i := pin.Line - 1
+		if i < 0 || i >= len(lines) {
+			continue
+		}
+		line := lines[i]
+		start := strings.Index(line, pin.Uses)
+		if start < 0 {
+			continue
+		}
+		end := start + len(pin.Uses)
+		pinned := strings.TrimSuffix(pin.Uses, pin.Ref) + pin.SHA
+
+		// In flow style, e.g. "- {uses: ..., with: ...}", the comment goes
+		// at the end of the line if it has none
+		rest := line[end:]
+		if trimmed := strings.TrimSpace(strings.TrimLeft(rest, `"'`)); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			lines[i] = line[:start] + pinned + rest
+			if !strings.Contains(rest, "#") {
+				lines[i] += " # " + pin.Ref
+			}
+			continue
+		}
+
+		// A closing quote stays with the value, the comment is replaced
+		quote := ""
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote, rest = rest[:1], rest[1:]
+		}
+		comment := "# " + pin.Ref
+		if kept := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "#")); kept != "" && kept != pin.Ref {
+			comment += " " + kept
+		}
+		lines[i] = line[:start] + pinned + quote + " " + comment
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,71 @@
+package workflow
+
+import (
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Remote is a workflow file of another repository, as a job's uses: names
+// it: "owner/repo/.github/workflows/build.yml@v1"
+type Remote struct {
+	Repository string // owner/repo
+	Path       string // Path in the repository, e.g. .github/workflows/build.yml
+	Ref        string // Branch, tag or SHA
+}
+
+// String returns the reference in uses: form
+func (r Remote) String() string {
+	return r.Repository + "/" + r.Path + "@" + r.Ref
+}
+
+var remoteRe = regexp.MustCompile(`^([\w.-]+/[\w.-]+)/(` + regexp.QuoteMeta(Dir) + `/[^@\s]+\.ya?ml)@(\S+)$`)
+
+// ParseRemote parses a reference to a workflow of another repository. Local
+// references (./.github/workflows/...) are not remote.
+func ParseRemote(ref string) (Remote, bool) {
+	m := remoteRe.FindStringSubmatch(ref)
+	if m == nil {
+		return Remote{}, false
+	}
+	return Remote{Repository: m[1], Path: m[2], Ref: m[3]}, true
+}
+
+// RemoteCall is a job that runs a reusable workflow of another repository
+type RemoteCall struct {
+	Job    string // Job ID
+	Name   string // The job's name:, if it has one
+	Remote Remote
+}
+
+// RemoteCalls returns the jobs of a workflow that call a reusable workflow
+// of another repository. Content that is not a valid workflow has none.
+func RemoteCalls(content string) []RemoteCall {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	jobs := Value(doc.Content[0], "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var calls []RemoteCall
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		job := jobs.Content[i+1]
+		uses := Value(job, "uses")
+		if uses == nil || uses.Kind != yaml.ScalarNode {
+			continue
+		}
+		remote, ok := ParseRemote(uses.Value)
+		if !ok {
+			continue
+		}
+		call := RemoteCall{Job: jobs.Content[i].Value, Remote: remote}
+		if name := Value(job, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			call.Name = name.Value
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
@@ -0,0 +1,96 @@
+package workflow
+
+import (
+	"strconv"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Script is the body of a run: step
+type Script struct {
+	Job   string
+	Step  string // Step name, or its 1-based position when unnamed
+	Shell string // Effective shell from the step or defaults, "" for the runner default
+	Body  string
+	Line  int // Workflow line of the script's first line, 1-based
+}
+
+// Scripts extracts the run: scripts of every job in a workflow
+func Scripts(path, content string) ([]Script, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "extract_scripts", "invalid YAML", err).WithPath(path)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	workflowShell := defaultShell(root)
+
+	jobs := Value(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var scripts []Script
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i].Value, jobs.Content[i+1]
+		jobShell := defaultShell(job)
+		if jobShell == "" {
+			jobShell = workflowShell
+		}
+
+		steps := Value(job, "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for n, step := range steps.Content {
+			run := Value(step, "run")
+			if run == nil || run.Kind != yaml.ScalarNode {
+				continue
+			}
+
+			script := Script{
+				Job:   id,
+				Step:  strconv.Itoa(n + 1),
+				Shell: jobShell,
+				Body:  run.Value,
+				Line:  run.Line,
+			}
+			if name := Value(step, "name"); name != nil && name.Value != "" {
+				script.Step = name.Value
+			}
+			if shell := Value(step, "shell"); shell != nil && shell.Value != "" {
+				script.Shell = shell.Value
+			}
+			// Block scalars start on the line after the | or > indicator
+			if run.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+				script.Line++
+			}
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts, nil
+}
+
+// IsPOSIXShell reports whether a script runs in bash or sh. Scripts without
+// an explicit shell are assumed to, since that is the default on Linux and
+// macOS runners.
+func (s Script) IsPOSIXShell() bool {
+	fields := strings.Fields(s.Shell)
+	if len(fields) == 0 {
+		return true
+	}
+	return fields[0] == "bash" || fields[0] == "sh"
+}
+
+// defaultShell reads defaults.run.shell from a workflow or job
+func defaultShell(node *yaml.Node) string {
+	if shell := Value(Value(Value(node, "defaults"), "run"), "shell"); shell != nil {
+		return shell.Value
+	}
+	return ""
+}
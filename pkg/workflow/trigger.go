@@ -0,0 +1,324 @@
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gh-sentinel/internal/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Trigger outcomes
+const (
+	TriggerRuns    = "runs"
+	TriggerSkipped = "skipped"
+	TriggerDepends = "depends" // Needs information the event did not give
+)
+
+// TriggerEvent is a hypothetical event to test a workflow's on: filters
+// against
+type TriggerEvent struct {
+	Name   string   // Event name, e.g. push or pull_request
+	Branch string   // Pushed branch, or the base branch of a pull request
+	Tag    string   // Pushed tag
+	Type   string   // Activity type, e.g. opened
+	Paths  []string // Changed files, nil when unknown
+}
+
+// TriggerCheck is the outcome of one filter
+type TriggerCheck struct {
+	Filter  string `json:"filter"` // e.g. "branches" or "paths-ignore"
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason"`
+}
+
+// TriggerResult explains whether a workflow runs for an event
+type TriggerResult struct {
+	Outcome  string         `json:"outcome"`
+	Triggers []string       `json:"triggers"` // Events in the on: key
+	Checks   []TriggerCheck `json:"checks"`
+}
+
+// defaultPullRequestTypes are the activity types pull_request events run on
+// when types: is not set
+var defaultPullRequestTypes = []string{"opened", "synchronize", "reopened"}
+
+// EvaluateTrigger evaluates a workflow's on: filters (branches, tags,
+// paths and types, with their -ignore variants) against an event
+func EvaluateTrigger(path, content string, ev TriggerEvent) (*TriggerResult, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, errors.New(errors.ErrTypeValidation, "evaluate_trigger", "invalid YAML", err).WithPath(path)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.ValidationError("evaluate_trigger", "workflow is not a mapping").WithPath(path)
+	}
+
+	triggers, configs := triggerConfigs(Value(doc.Content[0], "on"))
+	result := &TriggerResult{Triggers: triggers}
+	add := func(filter, outcome, format string, args ...interface{}) {
+		result.Checks = append(result.Checks, TriggerCheck{filter, outcome, fmt.Sprintf(format, args...)})
+	}
+
+	config, ok := configs[ev.Name]
+	if !ok {
+		add("on", TriggerSkipped, "the workflow is not triggered by %s events (it runs on: %s)", ev.Name, strings.Join(triggers, ", "))
+		result.Outcome = TriggerSkipped
+		return result, nil
+	}
+	add("on", TriggerRuns, "the workflow is triggered by %s events", ev.Name)
+
+	switch ev.Name {
+	case "push":
+		checkPush(config, ev, add)
+	case "pull_request", "pull_request_target":
+		checkTypes(config, ev, defaultPullRequestTypes, add)
+		checkRefs(config, "branches", ev.Branch, "base branch", add)
+		checkPaths(config, ev, add)
+	case "workflow_run":
+		checkTypes(config, ev, nil, add)
+		checkRefs(config, "branches", ev.Branch, "branch of the triggering run", add)
+	case "schedule":
+		add("schedule", TriggerRuns, "scheduled runs use the workflow file on the default branch only")
+	case "workflow_dispatch":
+		add("workflow_dispatch", TriggerRuns, "runs only when started manually, from a branch that contains the workflow file")
+	default:
+		checkTypes(config, ev, nil, add)
+	}
+
+	result.Outcome = TriggerRuns
+	for _, check := range result.Checks {
+		switch check.Outcome {
+		case TriggerSkipped:
+			result.Outcome = TriggerSkipped
+			return result, nil
+		case TriggerDepends:
+			result.Outcome = TriggerDepends
+		}
+	}
+	return result, nil
+}
+
+// checkPush applies the branch, tag and path filters of a push trigger.
+// Pushes are filtered by branch or by tag, and only branch filters make tag
+// pushes run and vice versa.
+func checkPush(config *yaml.Node, ev TriggerEvent, add func(string, string, string, ...interface{})) {
+	hasBranches := Value(config, "branches") != nil || Value(config, "branches-ignore") != nil
+	hasTags := Value(config, "tags") != nil || Value(config, "tags-ignore") != nil
+
+	if ev.Tag != "" {
+		if hasBranches && !hasTags {
+			add("tags", TriggerSkipped, "only branches are filtered, so pushes of tags do not run the workflow")
+			return
+		}
+		checkRefs(config, "tags", ev.Tag, "tag", add)
+		add("paths", TriggerRuns, "path filters are not evaluated for pushes of tags")
+		return
+	}
+
+	if hasTags && !hasBranches {
+		add("branches", TriggerSkipped, "only tags are filtered, so pushes to branches do not run the workflow")
+		return
+	}
+	checkRefs(config, "branches", ev.Branch, "branch", add)
+	checkPaths(config, ev, add)
+}
+
+// checkRefs applies a branches or tags filter and its -ignore variant
+func checkRefs(config *yaml.Node, filter, ref, what string, add func(string, string, string, ...interface{})) {
+	include, exclude := patterns(Value(config, filter)), patterns(Value(config, filter+"-ignore"))
+	if include == nil && exclude == nil {
+		return
+	}
+	if ref == "" {
+		add(filter, TriggerDepends, "runs only for some %ses; pass the %s to check", what, what)
+		return
+	}
+
+	if include != nil {
+		if matchFilters(include, ref) {
+			add(filter, TriggerRuns, "%s %s matches %s: %s", what, ref, filter, strings.Join(include, ", "))
+		} else {
+			add(filter, TriggerSkipped, "%s %s does not match %s: %s", what, ref, filter, strings.Join(include, ", "))
+		}
+	}
+	if exclude != nil {
+		if matchFilters(exclude, ref) {
+			add(filter+"-ignore", TriggerSkipped, "%s %s matches %s-ignore: %s", what, ref, filter, strings.Join(exclude, ", "))
+		} else {
+			add(filter+"-ignore", TriggerRuns, "%s %s is not in %s-ignore", what, ref, filter)
+		}
+	}
+}
+
+// checkPaths applies paths and paths-ignore to the changed files. The
+// workflow runs when any changed file passes the filter.
+func checkPaths(config *yaml.Node, ev TriggerEvent, add func(string, string, string, ...interface{})) {
+	include, exclude := patterns(Value(config, "paths")), patterns(Value(config, "paths-ignore"))
+	if include == nil && exclude == nil {
+		return
+	}
+	if ev.Paths == nil {
+		add("paths", TriggerDepends, "runs only when the changed files match the path filters; pass the changed paths to check")
+		return
+	}
+
+	if include != nil {
+		var matched []string
+		for _, file := range ev.Paths {
+			if matchFilters(include, file) {
+				matched = append(matched, file)
+			}
+		}
+		if len(matched) == 0 {
+			add("paths", TriggerSkipped, "no changed file matches paths: %s", strings.Join(include, ", "))
+			return
+		}
+		add("paths", TriggerRuns, "%s matches paths", strings.Join(matched, ", "))
+	}
+	if exclude != nil {
+		for _, file := range ev.Paths {
+			if !matchFilters(exclude, file) {
+				add("paths-ignore", TriggerRuns, "%s is not in paths-ignore", file)
+				return
+			}
+		}
+		add("paths-ignore", TriggerSkipped, "every changed file matches paths-ignore: %s", strings.Join(exclude, ", "))
+	}
+}
+
+// checkTypes applies the types filter, or the event's default types
+func checkTypes(config *yaml.Node, ev TriggerEvent, defaults []string, add func(string, string, string, ...interface{})) {
+	types := patterns(Value(config, "types"))
+	configured := types != nil
+	if !configured {
+		types = defaults
+	}
+	if types == nil {
+		return
+	}
+	if ev.Type == "" {
+		add("types", TriggerDepends, "runs only for activity types %s; pass the type to check", strings.Join(types, ", "))
+		return
+	}
+
+	for _, t := range types {
+		if t == ev.Type {
+			add("types", TriggerRuns, "activity type %s is in types", ev.Type)
+			return
+		}
+	}
+	if configured {
+		add("types", TriggerSkipped, "activity type %s is not in types: %s", ev.Type, strings.Join(types, ", "))
+	} else {
+		add("types", TriggerSkipped, "without types: %s only runs for %s", ev.Name, strings.Join(types, ", "))
+	}
+}
+
+// triggerConfigs returns the events in an on: key in order, and each
+// event's configuration (nil when it has none)
+func triggerConfigs(on *yaml.Node) ([]string, map[string]*yaml.Node) {
+	var names []string
+	configs := make(map[string]*yaml.Node)
+	if on == nil {
+		return names, configs
+	}
+	switch on.Kind {
+	case yaml.ScalarNode:
+		names = append(names, on.Value)
+		configs[on.Value] = nil
+	case yaml.SequenceNode:
+		for _, item := range on.Content {
+			names = append(names, item.Value)
+			configs[item.Value] = nil
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(on.Content); i += 2 {
+			names = append(names, on.Content[i].Value)
+			configs[on.Content[i].Value] = on.Content[i+1]
+		}
+	}
+	return names, configs
+}
+
+// patterns reads a filter list, which may also be a single string. It
+// returns nil when the filter is not set.
+func patterns(node *yaml.Node) []string {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}
+	case yaml.SequenceNode:
+		list := []string{}
+		for _, item := range node.Content {
+			list = append(list, item.Value)
+		}
+		return list
+	}
+	return nil
+}
+
+// matchFilters applies filter patterns in order: a matching pattern
+// includes the value and a matching !pattern excludes it again
+func matchFilters(filters []string, s string) bool {
+	included := false
+	for _, filter := range filters {
+		negated := strings.HasPrefix(filter, "!")
+		if MatchFilter(strings.TrimPrefix(filter, "!"), s) {
+			included = !negated
+		}
+	}
+	return included
+}
+
+// MatchFilter matches a branch, tag or path against a GitHub Actions filter
+// pattern: * matches anything but /, ** matches anything, ? and + repeat the
+// previous character zero-or-one and one-or-more times, [] is a character
+// class and \ escapes
+func MatchFilter(pattern, s string) bool {
+	re, err := regexp.Compile(filterRegexp(pattern))
+	if err != nil {
+		return pattern == s
+	}
+	return re.MatchString(s)
+}
+
+func filterRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?' || c == '+':
+			b.WriteByte(c)
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+			b.WriteString(pattern[i : i+end+1])
+			i += end
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
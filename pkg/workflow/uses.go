@@ -0,0 +1,78 @@
+package workflow
+
+import (
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LocalUse is a job that calls a reusable workflow of the same repository,
+// or a step that runs an action of it: "uses: ./.github/actions/setup"
+type LocalUse struct {
+	Job      string // Job ID
+	Name     string // The job's name:, if it has one
+	Step     string // The step's name:, or "Run <uses>" as GitHub names it otherwise; empty for a job
+	Uses     string // The reference as written
+	Path     string // Repository path of the workflow, or of the action's action.yml
+	Workflow bool   // A reusable workflow called by the job, rather than an action
+}
+
+// LocalUses returns the jobs and steps of a workflow that use a workflow
+// or an action of the same repository. Actions are assumed to keep their
+// metadata in action.yml; Resolve also matches action.yaml. Content that is
+// not a valid workflow has none.
+func LocalUses(content string) []LocalUse {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	jobs := Value(doc.Content[0], "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var uses []LocalUse
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		job := jobs.Content[i+1]
+		base := LocalUse{Job: jobs.Content[i].Value}
+		if name := Value(job, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			base.Name = name.Value
+		}
+
+		if ref, ok := localRef(Value(job, "uses")); ok {
+			use := base
+			use.Uses, use.Path, use.Workflow = ref, path.Clean(strings.TrimPrefix(ref, "./")), true
+			uses = append(uses, use)
+			continue
+		}
+
+		steps := Value(job, "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, step := range steps.Content {
+			ref, ok := localRef(Value(step, "uses"))
+			if !ok {
+				continue
+			}
+			use := base
+			use.Uses = ref
+			use.Path, _ = actionPath(ref)
+			use.Step = "Run " + ref
+			if name := Value(step, "name"); name != nil && name.Kind == yaml.ScalarNode {
+				use.Step = name.Value
+			}
+			uses = append(uses, use)
+		}
+	}
+	return uses
+}
+
+// localRef returns a uses: value that refers to the same repository
+func localRef(node *yaml.Node) (string, bool) {
+	if node == nil || node.Kind != yaml.ScalarNode || !strings.HasPrefix(node.Value, "./") {
+		return "", false
+	}
+	return node.Value, true
+}
@@ -0,0 +1,39 @@
+package workflow
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Entry returns the key and value nodes for key in a mapping node, or nils
+// when node is not a mapping or lacks the key
+func Entry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// Value returns the value for key in a mapping node
+func Value(node *yaml.Node, key string) *yaml.Node {
+	_, v := Entry(node, key)
+	return v
+}
+
+// ScalarLine returns the workflow line of a byte offset in a scalar
+func ScalarLine(node *yaml.Node, offset int) int {
+	line := node.Line
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		line++
+	}
+	if offset > len(node.Value) {
+		offset = len(node.Value)
+	}
+	return line + strings.Count(node.Value[:offset], "\n")
+}